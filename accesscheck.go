@@ -0,0 +1,115 @@
+package filesapi
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CapabilityResult is the outcome of a single access probe
+type CapabilityResult struct {
+
+	//the probe succeeded
+	Allowed bool
+
+	//the probe could not be made (i.e. there was nothing to read and writes were denied)
+	Skipped bool
+
+	//the error returned by the probe
+	Err error
+}
+
+// AccessReport lists the operations a store allows beneath a prefix
+type AccessReport struct {
+	Prefix  string
+	Results map[StoreOperation]CapabilityResult
+}
+
+// Allowed reports whether every operation was allowed
+func (ar *AccessReport) Allowed(operations ...StoreOperation) bool {
+	for _, op := range operations {
+		if !ar.Results[op].Allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// Require returns a descriptive error listing the required operations that were not allowed
+func (ar *AccessReport) Require(operations ...StoreOperation) error {
+	missing := []string{}
+	for _, op := range operations {
+		result := ar.Results[op]
+		switch {
+		case result.Allowed:
+			continue
+		case result.Err != nil:
+			missing = append(missing, fmt.Sprintf("%s (%s)", op, result.Err))
+		default:
+			missing = append(missing, fmt.Sprintf("%s (not verified)", op))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("insufficient access to %s: %s", ar.Prefix, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// CheckAccess probes list, write, read, and delete access beneath prefix with harmless
+// operations so deployments can fail fast with a clear message.  Writes and deletes
+// are probed with a small temporary object that is removed before returning.
+// Reads are probed with the temporary object, or with an existing object when writes are denied
+func CheckAccess(fs FileStore, prefix string) *AccessReport {
+	report := &AccessReport{Prefix: prefix, Results: map[StoreOperation]CapabilityResult{}}
+	dir := strings.TrimSuffix(prefix, "/") + "/"
+
+	listing, err := fs.ListDir(ListDirInput{Path: PathConfig{Path: dir}, Size: 10})
+	report.Results[StoreList] = CapabilityResult{Allowed: err == nil, Err: err}
+
+	probe := dir + ".filesapi-access-check-" + IdGenerator(nil).NewId()
+	_, err = fs.PutObject(PutObjectInput{
+		Source: ObjectSource{Data: []byte("filesapi access check")},
+		Dest:   PathConfig{Path: probe},
+	})
+	report.Results[StoreWrite] = CapabilityResult{Allowed: err == nil, Err: err}
+	written := err == nil
+
+	readPath := ""
+	if written {
+		readPath = probe
+	} else if listing != nil {
+		for _, obj := range *listing {
+			if !obj.IsDir {
+				readPath = listingPath(dir, obj)
+				break
+			}
+		}
+	}
+	if readPath == "" {
+		report.Results[StoreRead] = CapabilityResult{Skipped: true}
+	} else {
+		report.Results[StoreRead] = probeRead(fs, readPath)
+	}
+
+	if written {
+		err = firstError(fs.DeleteObjects(DeleteObjectInput{Paths: PathConfig{Paths: []string{probe}}}))
+		report.Results[StoreDelete] = CapabilityResult{Allowed: err == nil, Err: err}
+	} else {
+		//deletes are never probed against existing data
+		report.Results[StoreDelete] = CapabilityResult{Skipped: true}
+	}
+	return report
+}
+
+func probeRead(fs FileStore, path string) CapabilityResult {
+	reader, err := fs.GetObject(GetObjectInput{Path: PathConfig{Path: path}})
+	if err != nil {
+		return CapabilityResult{Err: err}
+	}
+	defer reader.Close()
+	buf := make([]byte, 1)
+	if _, err = reader.Read(buf); err != nil && err != io.EOF {
+		return CapabilityResult{Err: err}
+	}
+	return CapabilityResult{Allowed: true}
+}
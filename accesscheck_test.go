@@ -0,0 +1,50 @@
+package filesapi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+type readOnlyFS struct {
+	*BlockFS
+}
+
+func (r *readOnlyFS) PutObject(input PutObjectInput) (*FileOperationOutput, error) {
+	return nil, errors.New("AccessDenied")
+}
+
+func TestCheckAccess(t *testing.T) {
+	dir := t.TempDir()
+	store := &BlockFS{Config: BlockFSConfig{ChunkSize: 100}}
+	_, err := store.PutObject(PutObjectInput{
+		Source: ObjectSource{Data: []byte(testObjectString)},
+		Dest:   PathConfig{Path: dir + "/existing.txt"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := CheckAccess(store, dir)
+	if err = report.Require(StoreList, StoreRead, StoreWrite, StoreDelete); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatal("expected the probe object to be removed")
+	}
+
+	report = CheckAccess(&readOnlyFS{store}, dir)
+	if !report.Allowed(StoreList, StoreRead) {
+		t.Fatalf("expected list and read access, got %+v", report.Results)
+	}
+	if !report.Results[StoreDelete].Skipped {
+		t.Fatal("expected deletes to be skipped when writes are denied")
+	}
+	err = report.Require(StoreList, StoreWrite, StoreDelete)
+	expected := fmt.Sprintf("insufficient access to %s: write (AccessDenied), delete (not verified)", dir)
+	if err == nil || err.Error() != expected {
+		t.Fatalf("expected a missing write permission, got %v", err)
+	}
+}
@@ -0,0 +1,364 @@
+// Package backup stores deduplicated snapshots of a filesapi.FileStore prefix in
+// another store (the repository).
+//
+// Objects are split into content defined chunks, and each chunk is stored once in the
+// repository, named by its sha256 hash.  A snapshot records the chunk list of every file,
+// so periodic backups of mostly unchanged model directories only store the changed chunks.
+// Files whose size and modification time match the parent snapshot are not read at all.
+//
+// Repository layout, beneath the repository path:
+//
+//	chunks/<first two hash characters>/<sha256 hash>
+//	snapshots/<snapshot id>.json
+package backup
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/usace/filesapi"
+)
+
+const snapshotIdFormat string = "20060102T150405.000Z"
+
+// ErrChunkCorrupt is returned by Restore when a chunk does not match its hash
+var ErrChunkCorrupt = errors.New("backup chunk is corrupt")
+
+type Chunk struct {
+	Hash string
+	Size int64
+}
+
+// FileManifest is the chunk list of a backed up file
+type FileManifest struct {
+
+	//path relative to the backup root
+	Path    string
+	Size    int64
+	ModTime time.Time
+	Chunks  []Chunk
+}
+
+type BackupStats struct {
+	Files int
+	Bytes int64
+
+	//chunks referenced by the snapshot, and those the repository did not already hold
+	Chunks    int
+	NewChunks int
+	NewBytes  int64
+
+	//files whose chunk lists were taken from the parent snapshot without reading them
+	UnchangedFiles int
+}
+
+type Snapshot struct {
+	ID      string
+	Parent  string `json:",omitempty"`
+	Created time.Time
+	Source  string
+	Root    string
+	Files   []FileManifest
+	Stats   BackupStats
+}
+
+type BackupInput struct {
+	Source filesapi.FileStore
+	Root   filesapi.PathConfig
+
+	Repository     filesapi.FileStore
+	RepositoryPath string
+
+	//optional snapshot id.  Defaults to the creation time
+	ID string
+
+	//optional id of a previous snapshot of the same root.  Files with the same size
+	//and modification time reuse its chunk lists
+	Parent string
+
+	Chunking ChunkingOptions
+
+	//optional progress function.  Value is the source path of the file
+	Progress filesapi.ProgressFunction
+
+	//optional time source for the snapshot creation time
+	Clock filesapi.Clock
+}
+
+// Backup stores a snapshot of every object beneath Root in the repository
+func Backup(input BackupInput) (*Snapshot, error) {
+	if input.Source == nil || input.Repository == nil {
+		return nil, errors.New("backup requires a source and repository store")
+	}
+	repo := repository{input.Repository, input.RepositoryPath}
+	snapshot := &Snapshot{
+		ID:      input.ID,
+		Parent:  input.Parent,
+		Created: input.Clock.Now().UTC(),
+		Source:  input.Source.ResourceName(),
+		Root:    input.Root.Path,
+		Files:   []FileManifest{},
+	}
+	if snapshot.ID == "" {
+		snapshot.ID = snapshot.Created.Format(snapshotIdFormat)
+	}
+
+	//chunks known to be stored, so they are not checked or written again
+	stored := map[string]bool{}
+	previous := map[string]FileManifest{}
+	if input.Parent != "" {
+		parent, err := repo.snapshot(input.Parent)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range parent.Files {
+			previous[f.Path] = f
+			for _, c := range f.Chunks {
+				stored[c.Hash] = true
+			}
+		}
+	}
+
+	type file struct {
+		path string
+		info os.FileInfo
+	}
+	files := []file{}
+	err := input.Source.Walk(filesapi.WalkInput{Path: input.Root}, func(p string, info os.FileInfo) error {
+		if !info.IsDir() {
+			files = append(files, file{p, info})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	root := strings.Trim(input.Root.Path, "/")
+	for i, f := range files {
+		rel := strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(f.path, "/"), root), "/")
+		manifest := FileManifest{Path: rel, Size: f.info.Size(), ModTime: f.info.ModTime().UTC()}
+		if prev, ok := previous[rel]; ok && prev.Size == manifest.Size && prev.ModTime.Equal(manifest.ModTime) {
+			manifest.Chunks = prev.Chunks
+			snapshot.Stats.UnchangedFiles++
+		} else {
+			manifest.Chunks, err = repo.storeFile(input.Source, f.path, input.Chunking, stored, &snapshot.Stats)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to back up %s: %w", f.path, err)
+			}
+		}
+		snapshot.Files = append(snapshot.Files, manifest)
+		snapshot.Stats.Files++
+		snapshot.Stats.Bytes += manifest.Size
+		snapshot.Stats.Chunks += len(manifest.Chunks)
+		if input.Progress != nil {
+			if err := input.Progress(filesapi.ProgressData{Index: i, Max: len(files), Value: f.path}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	body, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	_, err = input.Repository.PutObject(filesapi.PutObjectInput{
+		Source:      filesapi.ObjectSource{Data: body},
+		Dest:        filesapi.PathConfig{Path: repo.snapshotPath(snapshot.ID)},
+		ContentType: "application/json",
+	})
+	return snapshot, err
+}
+
+type RestoreInput struct {
+	Repository     filesapi.FileStore
+	RepositoryPath string
+	SnapshotID     string
+
+	Dest     filesapi.FileStore
+	DestRoot string
+
+	//optional filter selecting the snapshot relative paths to restore.  Defaults to every file
+	Include func(path string) bool
+
+	//optional progress function.  Value is the destination path of the file
+	Progress filesapi.ProgressFunction
+}
+
+// Restore writes the files of a snapshot beneath DestRoot.  Every chunk is verified
+// against its hash, and a corrupt chunk fails the restore with ErrChunkCorrupt
+func Restore(input RestoreInput) (*Snapshot, error) {
+	if input.Repository == nil || input.Dest == nil {
+		return nil, errors.New("restore requires a repository and destination store")
+	}
+	repo := repository{input.Repository, input.RepositoryPath}
+	snapshot, err := repo.snapshot(input.SnapshotID)
+	if err != nil {
+		return nil, err
+	}
+	destRoot := strings.TrimSuffix(input.DestRoot, "/")
+	for i, f := range snapshot.Files {
+		if input.Include != nil && !input.Include(f.Path) {
+			continue
+		}
+		dest := destRoot + "/" + f.Path
+		size := f.Size
+		_, err := input.Dest.PutObject(filesapi.PutObjectInput{
+			Source:   filesapi.ObjectSource{Reader: &chunkReader{repo: repo, chunks: f.Chunks}, ContentLength: &size},
+			Dest:     filesapi.PathConfig{Path: dest},
+			Mutipart: size > int64(defaultMaxChunkSize),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to restore %s: %w", f.Path, err)
+		}
+		if input.Progress != nil {
+			if err := input.Progress(filesapi.ProgressData{Index: i, Max: len(snapshot.Files), Value: dest}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return snapshot, nil
+}
+
+// LoadSnapshot reads a snapshot from a repository
+func LoadSnapshot(store filesapi.FileStore, repositoryPath string, id string) (*Snapshot, error) {
+	return repository{store, repositoryPath}.snapshot(id)
+}
+
+// Snapshots returns the ids of the snapshots in a repository, oldest first for default ids
+func Snapshots(store filesapi.FileStore, repositoryPath string) ([]string, error) {
+	repo := repository{store, repositoryPath}
+	ids := []string{}
+	err := store.Walk(filesapi.WalkInput{Path: filesapi.PathConfig{Path: repo.path("snapshots") + "/"}}, func(p string, info os.FileInfo) error {
+		if !info.IsDir() && strings.HasSuffix(p, ".json") {
+			ids = append(ids, strings.TrimSuffix(path.Base(p), ".json"))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+type repository struct {
+	store filesapi.FileStore
+	root  string
+}
+
+func (r repository) path(elem ...string) string {
+	return path.Join(append([]string{"/", r.root}, elem...)...)
+}
+
+func (r repository) chunkPath(hash string) string {
+	return r.path("chunks", hash[:2], hash)
+}
+
+func (r repository) snapshotPath(id string) string {
+	return r.path("snapshots", id+".json")
+}
+
+func (r repository) snapshot(id string) (*Snapshot, error) {
+	reader, err := r.store.GetObject(filesapi.GetObjectInput{Path: filesapi.PathConfig{Path: r.snapshotPath(id)}})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read snapshot %s: %w", id, err)
+	}
+	defer reader.Close()
+	snapshot := &Snapshot{}
+	if err := json.NewDecoder(reader).Decode(snapshot); err != nil {
+		return nil, fmt.Errorf("Unable to read snapshot %s: %w", id, err)
+	}
+	return snapshot, nil
+}
+
+// chunks a source object, writing the chunks the repository does not hold
+func (r repository) storeFile(source filesapi.FileStore, p string, options ChunkingOptions, stored map[string]bool, stats *BackupStats) ([]Chunk, error) {
+	reader, err := source.GetObject(filesapi.GetObjectInput{Path: filesapi.PathConfig{Path: p}})
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	chunks := []Chunk{}
+	c := newChunker(reader, options)
+	for {
+		data, err := c.next()
+		if err == io.EOF {
+			return chunks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		chunks = append(chunks, Chunk{Hash: hash, Size: int64(len(data))})
+		if stored[hash] {
+			continue
+		}
+		exists, err := filesapi.ObjectExists(r.store, r.chunkPath(hash))
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			_, err = r.store.PutObject(filesapi.PutObjectInput{
+				Source: filesapi.ObjectSource{Data: data},
+				Dest:   filesapi.PathConfig{Path: r.chunkPath(hash)},
+			})
+			if err != nil {
+				return nil, err
+			}
+			stats.NewChunks++
+			stats.NewBytes += int64(len(data))
+		}
+		stored[hash] = true
+	}
+}
+
+// reads a file from its chunks, verifying each chunk before it is returned
+type chunkReader struct {
+	repo    repository
+	chunks  []Chunk
+	current *bytes.Reader
+}
+
+func (cr *chunkReader) Read(p []byte) (int, error) {
+	for cr.current == nil || cr.current.Len() == 0 {
+		if len(cr.chunks) == 0 {
+			return 0, io.EOF
+		}
+		data, err := cr.repo.chunk(cr.chunks[0])
+		if err != nil {
+			return 0, err
+		}
+		cr.chunks = cr.chunks[1:]
+		cr.current = bytes.NewReader(data)
+	}
+	return cr.current.Read(p)
+}
+
+func (r repository) chunk(c Chunk) ([]byte, error) {
+	reader, err := r.store.GetObject(filesapi.GetObjectInput{Path: filesapi.PathConfig{Path: r.chunkPath(c.Hash)}})
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	if int64(len(data)) != c.Size || hex.EncodeToString(sum[:]) != c.Hash {
+		return nil, fmt.Errorf("%w: %s", ErrChunkCorrupt, c.Hash)
+	}
+	return data, nil
+}
@@ -0,0 +1,98 @@
+package backup
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/usace/filesapi"
+)
+
+func TestBackupRestore(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := t.TempDir()
+	repo := t.TempDir()
+	model := make([]byte, 256*1024)
+	rand.New(rand.NewSource(7)).Read(model)
+	os.MkdirAll(filepath.Join(src, "inputs"), 0755)
+	os.WriteFile(filepath.Join(src, "model.hdf"), model, 0644)
+	os.WriteFile(filepath.Join(src, "inputs", "flows.csv"), []byte("time,flow\n0,100\n"), 0644)
+	os.WriteFile(filepath.Join(src, "empty.txt"), []byte{}, 0644)
+
+	options := ChunkingOptions{MinSize: 1024, AvgSize: 4096, MaxSize: 16384}
+	first, err := Backup(BackupInput{
+		Source:         store,
+		Root:           filesapi.PathConfig{Path: src},
+		Repository:     store,
+		RepositoryPath: repo,
+		ID:             "first",
+		Chunking:       options,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Stats.Files != 3 || first.Stats.NewChunks == 0 || first.Stats.NewBytes != first.Stats.Bytes {
+		t.Fatalf("unexpected first backup stats %+v", first.Stats)
+	}
+
+	//change a few bytes of the model and back up again
+	copy(model[100000:], []byte("changed"))
+	os.WriteFile(filepath.Join(src, "model.hdf"), model, 0644)
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(filepath.Join(src, "model.hdf"), later, later)
+	second, err := Backup(BackupInput{
+		Source:         store,
+		Root:           filesapi.PathConfig{Path: src},
+		Repository:     store,
+		RepositoryPath: repo,
+		ID:             "second",
+		Parent:         "first",
+		Chunking:       options,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Stats.UnchangedFiles != 2 {
+		t.Fatalf("expected 2 unchanged files, got %+v", second.Stats)
+	}
+	if second.Stats.NewBytes == 0 || second.Stats.NewBytes > 3*int64(options.MaxSize) {
+		t.Fatalf("expected only the changed chunks to be stored, got %+v", second.Stats)
+	}
+
+	ids, err := Snapshots(store, repo)
+	if err != nil || len(ids) != 2 || ids[0] != "first" {
+		t.Fatalf("unexpected snapshots %v: %v", ids, err)
+	}
+
+	dest := t.TempDir()
+	if _, err := Restore(RestoreInput{Repository: store, RepositoryPath: repo, SnapshotID: "second", Dest: store, DestRoot: dest}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"model.hdf", "inputs/flows.csv", "empty.txt"} {
+		want, _ := os.ReadFile(filepath.Join(src, name))
+		got, err := os.ReadFile(filepath.Join(dest, name))
+		if err != nil || !bytes.Equal(got, want) {
+			t.Fatalf("restored %s does not match the source: %v", name, err)
+		}
+	}
+
+	//corrupt a chunk and restore again
+	var chunk Chunk
+	for _, f := range first.Files {
+		if f.Path == "model.hdf" {
+			chunk = f.Chunks[0]
+		}
+	}
+	os.WriteFile(filepath.Join(repo, "chunks", chunk.Hash[:2], chunk.Hash), []byte("corrupt"), 0644)
+	_, err = Restore(RestoreInput{Repository: store, RepositoryPath: repo, SnapshotID: "first", Dest: store, DestRoot: t.TempDir()})
+	if !errors.Is(err, ErrChunkCorrupt) {
+		t.Fatalf("expected ErrChunkCorrupt, got %v", err)
+	}
+}
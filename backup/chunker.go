@@ -0,0 +1,105 @@
+package backup
+
+import (
+	"bufio"
+	"io"
+	"math/bits"
+)
+
+const (
+	defaultMinChunkSize int = 512 * 1024
+	defaultAvgChunkSize int = 1024 * 1024
+	defaultMaxChunkSize int = 4 * 1024 * 1024
+)
+
+// ChunkingOptions sizes content defined chunks.  Zero values use the package defaults
+// (512KiB minimum, 1MiB average, 4MiB maximum).  Changing the options between backups
+// moves the chunk boundaries, so unchanged data is stored again
+type ChunkingOptions struct {
+	MinSize int
+	AvgSize int
+	MaxSize int
+}
+
+func (co ChunkingOptions) withDefaults() ChunkingOptions {
+	if co.MinSize <= 0 {
+		co.MinSize = defaultMinChunkSize
+	}
+	if co.AvgSize <= 0 {
+		co.AvgSize = defaultAvgChunkSize
+	}
+	if co.MaxSize <= 0 {
+		co.MaxSize = defaultMaxChunkSize
+	}
+	if co.AvgSize < co.MinSize {
+		co.AvgSize = co.MinSize
+	}
+	if co.MaxSize < co.AvgSize {
+		co.MaxSize = co.AvgSize
+	}
+	return co
+}
+
+// gear hash values for each byte.  Generated with splitmix64 so chunk boundaries,
+// and therefore deduplication, are stable across releases
+var gear = func() [256]uint64 {
+	var table [256]uint64
+	state := uint64(0x5ca1ab1e)
+	for i := range table {
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+// chunker splits a stream at content defined boundaries using a gear rolling hash,
+// so an insertion or deletion only changes the chunks around it
+type chunker struct {
+	reader  *bufio.Reader
+	options ChunkingOptions
+	mask    uint64
+	buf     []byte
+}
+
+func newChunker(r io.Reader, options ChunkingOptions) *chunker {
+	options = options.withDefaults()
+	//past the minimum size a boundary is cut when the top hash bits are zero.  The number of
+	//bits makes the expected chunk size the average size
+	maskBits := 0
+	if spread := options.AvgSize - options.MinSize; spread > 1 {
+		maskBits = bits.Len(uint(spread)) - 1
+	}
+	return &chunker{
+		reader:  bufio.NewReaderSize(r, 64*1024),
+		options: options,
+		mask:    ^uint64(0) << (64 - maskBits),
+		buf:     make([]byte, 0, options.MaxSize),
+	}
+}
+
+// next returns the next chunk, or io.EOF after the last chunk.
+// The chunk is only valid until the following call
+func (c *chunker) next() ([]byte, error) {
+	c.buf = c.buf[:0]
+	var hash uint64
+	for {
+		b, err := c.reader.ReadByte()
+		if err == io.EOF {
+			if len(c.buf) == 0 {
+				return nil, io.EOF
+			}
+			return c.buf, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		c.buf = append(c.buf, b)
+		hash = (hash << 1) + gear[b]
+		if len(c.buf) >= c.options.MaxSize || (len(c.buf) >= c.options.MinSize && hash&c.mask == 0) {
+			return c.buf, nil
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+func chunkHashes(t *testing.T, data []byte, options ChunkingOptions) ([]string, []int) {
+	c := newChunker(bytes.NewReader(data), options)
+	hashes := []string{}
+	sizes := []int{}
+	for {
+		chunk, err := c.next()
+		if err == io.EOF {
+			return hashes, sizes
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		sum := sha256.Sum256(chunk)
+		hashes = append(hashes, hex.EncodeToString(sum[:]))
+		sizes = append(sizes, len(chunk))
+	}
+}
+
+func TestChunker(t *testing.T) {
+	options := ChunkingOptions{MinSize: 1024, AvgSize: 4096, MaxSize: 16384}
+	data := make([]byte, 512*1024)
+	rand.New(rand.NewSource(1)).Read(data)
+
+	hashes, sizes := chunkHashes(t, data, options)
+	total := 0
+	for i, size := range sizes {
+		total += size
+		if size > options.MaxSize || (size < options.MinSize && i != len(sizes)-1) {
+			t.Fatalf("chunk %d size %d is outside the chunking bounds", i, size)
+		}
+	}
+	if total != len(data) {
+		t.Fatalf("chunks cover %d of %d bytes", total, len(data))
+	}
+	if avg := total / len(sizes); avg < 2048 || avg > 8192 {
+		t.Fatalf("average chunk size %d is far from %d", avg, options.AvgSize)
+	}
+
+	//an insertion only changes the chunks around it
+	edited := append(append(append([]byte{}, data[:200000]...), []byte("inserted")...), data[200000:]...)
+	editedHashes, _ := chunkHashes(t, edited, options)
+	known := map[string]bool{}
+	for _, h := range hashes {
+		known[h] = true
+	}
+	changed := 0
+	for _, h := range editedHashes {
+		if !known[h] {
+			changed++
+		}
+	}
+	if changed > 3 {
+		t.Fatalf("expected an insertion to change at most 3 chunks, changed %d of %d", changed, len(editedHashes))
+	}
+}
@@ -0,0 +1,211 @@
+package filesapi
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sync"
+)
+
+// size of the object blocks read and cached by archive stores
+const archiveBlockSize int64 = 1024 * 1024
+
+// number of object blocks cached by each archive store
+const archiveCachedBlocks int = 8
+
+// ObjectReadSeeker is an io.ReadSeeker and io.ReaderAt over a store object, suitable for
+// http.ServeContent and archive readers.  Seeking is free: the object is read with a ranged
+// GetObject starting at the current offset the first time Read is called after a seek, so
+// serving a byte range only transfers that range
+type ObjectReadSeeker struct {
+	store FileStore
+	path  PathConfig
+	info  fs.FileInfo
+
+	offset int64
+
+	//open object body and the offset it is positioned at
+	body       io.ReadCloser
+	bodyOffset int64
+}
+
+// NewObjectReadSeeker looks up the size of an object and returns a ReadSeeker over it.
+// The caller is responsible for closing it
+func NewObjectReadSeeker(store FileStore, path PathConfig) (*ObjectReadSeeker, error) {
+	info, err := store.GetObjectInfo(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &FileNotFoundError{path.Path}
+	}
+	return &ObjectReadSeeker{store: store, path: path, info: info}, nil
+}
+
+// file info of the object when the ReadSeeker was created
+func (ors *ObjectReadSeeker) Info() fs.FileInfo {
+	return ors.info
+}
+
+// size of the object when the ReadSeeker was created
+func (ors *ObjectReadSeeker) Size() int64 {
+	return ors.info.Size()
+}
+
+func (ors *ObjectReadSeeker) Read(p []byte) (int, error) {
+	if ors.offset >= ors.info.Size() {
+		return 0, io.EOF
+	}
+	if ors.body != nil && ors.bodyOffset != ors.offset {
+		ors.body.Close()
+		ors.body = nil
+	}
+	if ors.body == nil {
+		input := GetObjectInput{Path: ors.path}
+		if ors.offset > 0 {
+			input.Range = fmt.Sprintf("bytes=%d-%d", ors.offset, ors.info.Size()-1)
+		}
+		body, err := ors.store.GetObject(input)
+		if err != nil {
+			return 0, err
+		}
+		ors.body = body
+		ors.bodyOffset = ors.offset
+	}
+	n, err := ors.body.Read(p)
+	ors.offset += int64(n)
+	ors.bodyOffset = ors.offset
+	return n, err
+}
+
+// ReadAt reads len(p) bytes at an offset with a ranged GetObject.  It does not change the
+// offset used by Read and Seek, and is safe for concurrent use
+func (ors *ObjectReadSeeker) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if off >= ors.info.Size() {
+		return 0, io.EOF
+	}
+	end := off + int64(len(p)) - 1
+	if end >= ors.info.Size() {
+		end = ors.info.Size() - 1
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	body, err := ors.store.GetObject(GetObjectInput{
+		Path:  ors.path,
+		Range: fmt.Sprintf("bytes=%d-%d", off, end),
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	n, err := io.ReadFull(body, p[:end-off+1])
+	if err == nil && n < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (ors *ObjectReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += ors.offset
+	case io.SeekEnd:
+		offset += ors.info.Size()
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	ors.offset = offset
+	return offset, nil
+}
+
+func (ors *ObjectReadSeeker) Close() error {
+	if ors.body == nil {
+		return nil
+	}
+	err := ors.body.Close()
+	ors.body = nil
+	return err
+}
+
+// ObjectETag returns a quoted entity tag for an object, used as an http validator so clients
+// can resume downloads with If-Range.  S3 objects and HTTPFS objects use the tag reported by
+// the store.  Other objects use a tag derived from the size and modification time, which
+// changes whenever the object is rewritten
+func ObjectETag(info fs.FileInfo) string {
+	switch fi := info.(type) {
+	case *S3AttributesFileInfo:
+		if etag := attributesEtag(fi); etag != "" {
+			return `"` + etag + `"`
+		}
+		return ""
+	case *storeFileInfo:
+		return fi.etag
+	}
+	return fmt.Sprintf(`"%x-%x"`, info.Size(), info.ModTime().UnixNano())
+}
+
+// reads an object in archiveBlockSize blocks, caching the most recently read blocks.
+// archive readers make many small reads, which would otherwise each be a request
+type blockReaderAt struct {
+	reader *ObjectReadSeeker
+
+	mu     sync.Mutex
+	blocks map[int64][]byte
+
+	//cached block numbers, least recently used first
+	order []int64
+}
+
+func newBlockReaderAt(reader *ObjectReadSeeker) *blockReaderAt {
+	return &blockReaderAt{reader: reader, blocks: map[int64][]byte{}}
+}
+
+func (br *blockReaderAt) block(n int64) ([]byte, error) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	for i, cached := range br.order {
+		if cached == n {
+			br.order = append(append(br.order[:i:i], br.order[i+1:]...), n)
+			return br.blocks[n], nil
+		}
+	}
+	data := make([]byte, archiveBlockSize)
+	read, err := br.reader.ReadAt(data, n*archiveBlockSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	data = data[:read]
+	if len(br.order) == archiveCachedBlocks {
+		delete(br.blocks, br.order[0])
+		br.order = br.order[1:]
+	}
+	br.blocks[n] = data
+	br.order = append(br.order, n)
+	return data, nil
+}
+
+func (br *blockReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		data, err := br.block(pos / archiveBlockSize)
+		if err != nil {
+			return n, err
+		}
+		start := pos % archiveBlockSize
+		if start >= int64(len(data)) {
+			return n, io.EOF
+		}
+		n += copy(p[n:], data[start:])
+	}
+	return n, nil
+}
@@ -0,0 +1,92 @@
+package filesapi
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestObjectReadSeeker(t *testing.T) {
+	dir := t.TempDir()
+	store := &BlockFS{}
+	path := PathConfig{Path: dir + "/object.txt"}
+	_, err := store.PutObject(PutObjectInput{Source: ObjectSource{Data: []byte(testObjectString)}, Dest: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := NewObjectReadSeeker(store, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	size, err := reader.Seek(0, io.SeekEnd)
+	if err != nil || size != int64(len(testObjectString)) {
+		t.Fatalf("expected size %d, got %d %v", len(testObjectString), size, err)
+	}
+	if _, err = reader.Seek(6, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 5)
+	if _, err = io.ReadFull(reader, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != testObjectString[6:11] {
+		t.Fatalf("unexpected read after seek: %q", buf)
+	}
+	if _, err = reader.Seek(-5, io.SeekCurrent); err != nil {
+		t.Fatal(err)
+	}
+	rest, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rest) != testObjectString[6:] {
+		t.Fatalf("unexpected read after a relative seek: %q", rest)
+	}
+
+	etag := ObjectETag(reader.Info())
+	info, _ := store.GetObjectInfo(path)
+	if etag == "" || etag != ObjectETag(info) {
+		t.Fatalf("expected a stable etag, got %s and %s", etag, ObjectETag(info))
+	}
+	if _, err = NewObjectReadSeeker(store, PathConfig{Path: dir}); err == nil {
+		t.Fatal("expected a directory to be rejected")
+	}
+}
+
+func TestBlockReaderAt(t *testing.T) {
+	store := &countingFS{BlockFS: &BlockFS{}}
+	path := PathConfig{Path: t.TempDir() + "/archive.bin"}
+	data := make([]byte, archiveBlockSize*2+10)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	if _, err := store.PutObject(PutObjectInput{Source: ObjectSource{Data: data}, Dest: path}); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := NewObjectReadSeeker(store, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	br := newBlockReaderAt(reader)
+
+	//reads spanning a block boundary read both blocks, and repeated reads are cached
+	buf := make([]byte, 20)
+	off := archiveBlockSize - 10
+	for i := 0; i < 2; i++ {
+		if n, err := br.ReadAt(buf, off); err != nil || n != 20 || !bytes.Equal(buf, data[off:off+20]) {
+			t.Fatalf("unexpected read of %d bytes across blocks: %v", n, err)
+		}
+	}
+	if store.gets != 2 {
+		t.Fatalf("expected 2 block reads, got %d", store.gets)
+	}
+
+	//reads past the end are short
+	n, err := br.ReadAt(buf, int64(len(data))-5)
+	if n != 5 || err != io.EOF || !bytes.Equal(buf[:n], data[len(data)-5:]) {
+		t.Fatalf("expected a short read at the end of the object, got %d bytes: %v", n, err)
+	}
+}
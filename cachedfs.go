@@ -0,0 +1,184 @@
+package filesapi
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultListingCacheTTL time.Duration = 30 * time.Second
+
+type cacheEntry struct {
+	path    string
+	value   any
+	expires time.Time
+}
+
+// CachedListingFS is a FileStore decorator that memoizes ListDir and GetObjectInfo
+// results for a TTL.  Writes and deletes made through the wrapper invalidate the
+// cached results for the written path and its parent directories.  Changes made
+// by other clients of the underlying store are visible once the TTL expires
+type CachedListingFS struct {
+	FileStore
+
+	//how long results are cached.  Defaults to defaultListingCacheTTL
+	TTL time.Duration
+
+	//optional duration that GetObjectInfo FileNotFoundErrors are cached.
+	//a short negative TTL protects the backend from hot existence check loops
+	//(i.e. polling for a .done marker).  Zero disables negative caching
+	NegativeTTL time.Duration
+
+	//optional time source
+	Clock Clock
+
+	mu       sync.Mutex
+	listings map[string]cacheEntry
+	infos    map[string]cacheEntry
+}
+
+func NewCachedListingFS(store FileStore, ttl time.Duration) *CachedListingFS {
+	return &CachedListingFS{
+		FileStore: store,
+		TTL:       ttl,
+	}
+}
+
+func (c *CachedListingFS) ListDir(input ListDirInput) (*[]FileStoreResultObject, error) {
+	key := fmt.Sprintf("%s|%d|%d|%s", input.Path.Path, input.Page, input.Size, input.Filter)
+	if value, ok := c.get(c.listings, key); ok {
+		return copyListing(value.(*[]FileStoreResultObject)), nil
+	}
+	listing, err := c.FileStore.ListDir(input)
+	if err != nil {
+		return listing, err
+	}
+	c.put(&c.listings, key, input.Path.Path, copyListing(listing), c.TTL)
+	return listing, nil
+}
+
+func (c *CachedListingFS) GetObjectInfo(path PathConfig) (fs.FileInfo, error) {
+	if value, ok := c.get(c.infos, path.Path); ok {
+		if err, notFound := value.(*FileNotFoundError); notFound {
+			return nil, err
+		}
+		return value.(fs.FileInfo), nil
+	}
+	info, err := c.FileStore.GetObjectInfo(path)
+	var notFound *FileNotFoundError
+	if c.NegativeTTL > 0 && errors.As(err, &notFound) {
+		c.put(&c.infos, path.Path, path.Path, notFound, c.NegativeTTL)
+		return info, err
+	}
+	if err != nil {
+		return info, err
+	}
+	c.put(&c.infos, path.Path, path.Path, info, c.TTL)
+	return info, nil
+}
+
+func (c *CachedListingFS) PutObject(input PutObjectInput) (*FileOperationOutput, error) {
+	defer c.Invalidate(input.Dest.Path)
+	return c.FileStore.PutObject(input)
+}
+
+func (c *CachedListingFS) CopyObject(input CopyObjectInput) error {
+	defer func() {
+		for _, path := range input.Dest.All() {
+			c.Invalidate(path)
+		}
+	}()
+	return c.FileStore.CopyObject(input)
+}
+
+func (c *CachedListingFS) MoveObject(input MoveObjectInput) error {
+	defer func() {
+		for _, path := range append(input.Src.All(), input.Dest.All()...) {
+			c.Invalidate(path)
+		}
+	}()
+	return c.FileStore.MoveObject(input)
+}
+
+func (c *CachedListingFS) InitializeObjectUpload(u UploadConfig) (UploadResult, error) {
+	defer c.Invalidate(u.ObjectPath)
+	return c.FileStore.InitializeObjectUpload(u)
+}
+
+func (c *CachedListingFS) CompleteObjectUpload(u CompletedObjectUploadConfig) error {
+	defer c.Invalidate(u.ObjectPath)
+	return c.FileStore.CompleteObjectUpload(u)
+}
+
+func (c *CachedListingFS) AbortObjectUpload(u UploadConfig) error {
+	defer c.Invalidate(u.ObjectPath)
+	return c.FileStore.AbortObjectUpload(u)
+}
+
+func (c *CachedListingFS) DeleteObjects(input DeleteObjectInput) []error {
+	defer func() {
+		for _, path := range input.Paths.All() {
+			c.Invalidate(path)
+		}
+	}()
+	return c.FileStore.DeleteObjects(input)
+}
+
+// Invalidate removes cached results for a path, everything beneath it, and its parent directories
+func (c *CachedListingFS) Invalidate(path string) {
+	target := strings.Trim(path, "/")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, cache := range []map[string]cacheEntry{c.listings, c.infos} {
+		for key, entry := range cache {
+			cached := strings.Trim(entry.path, "/")
+			if target == "" || cached == target || cached == "" ||
+				strings.HasPrefix(cached, target+"/") || strings.HasPrefix(target, cached+"/") {
+				delete(cache, key)
+			}
+		}
+	}
+}
+
+// Purge removes all cached results
+func (c *CachedListingFS) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listings = nil
+	c.infos = nil
+}
+
+func (c *CachedListingFS) get(cache map[string]cacheEntry, key string) (any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := cache[key]
+	if !ok || !c.Clock.Now().Before(entry.expires) {
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (c *CachedListingFS) put(cache *map[string]cacheEntry, key string, path string, value any, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = defaultListingCacheTTL
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if *cache == nil {
+		*cache = map[string]cacheEntry{}
+	}
+	(*cache)[key] = cacheEntry{path: path, value: value, expires: c.Clock.Now().Add(ttl)}
+}
+
+// listings are copied in and out of the cache so callers cannot modify cached results
+func copyListing(listing *[]FileStoreResultObject) *[]FileStoreResultObject {
+	if listing == nil {
+		return nil
+	}
+	copied := make([]FileStoreResultObject, len(*listing))
+	copy(copied, *listing)
+	return &copied
+}
@@ -0,0 +1,118 @@
+package filesapi
+
+import (
+	"io"
+	"io/fs"
+	"testing"
+	"time"
+)
+
+type countingFS struct {
+	*BlockFS
+	lists int
+	infos int
+	gets  int
+}
+
+func (c *countingFS) ListDir(input ListDirInput) (*[]FileStoreResultObject, error) {
+	c.lists++
+	return c.BlockFS.ListDir(input)
+}
+
+func (c *countingFS) GetObjectInfo(path PathConfig) (fs.FileInfo, error) {
+	c.infos++
+	return c.BlockFS.GetObjectInfo(path)
+}
+
+func (c *countingFS) GetObject(goi GetObjectInput) (io.ReadCloser, error) {
+	c.gets++
+	return c.BlockFS.GetObject(goi)
+}
+
+func TestCachedListingFS(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	backend := &countingFS{BlockFS: &BlockFS{Config: BlockFSConfig{ChunkSize: 100}}}
+	store := NewCachedListingFS(backend, time.Minute)
+	store.Clock = func() time.Time { return now }
+
+	put := func(name string) {
+		_, err := store.PutObject(PutObjectInput{
+			Source: ObjectSource{Data: []byte(testObjectString)},
+			Dest:   PathConfig{Path: dir + "/data/" + name},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	put("a.txt")
+
+	list := func() int {
+		listing, err := store.ListDir(ListDirInput{Path: PathConfig{Path: dir + "/data/"}, Size: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(*listing)
+	}
+	list()
+	if n := list(); n != 1 || backend.lists != 1 {
+		t.Fatalf("expected a cached listing, got %d entries after %d backend calls", n, backend.lists)
+	}
+
+	put("b.txt")
+	if n := list(); n != 2 || backend.lists != 2 {
+		t.Fatalf("expected the write to invalidate the listing, got %d entries after %d backend calls", n, backend.lists)
+	}
+
+	store.GetObjectInfo(PathConfig{Path: dir + "/data/a.txt"})
+	store.GetObjectInfo(PathConfig{Path: dir + "/data/a.txt"})
+	if backend.infos != 1 {
+		t.Fatalf("expected cached object info, got %d backend calls", backend.infos)
+	}
+
+	store.DeleteObjects(DeleteObjectInput{Paths: PathConfig{Paths: []string{dir + "/data/a.txt"}}})
+	if _, err := store.GetObjectInfo(PathConfig{Path: dir + "/data/a.txt"}); err == nil {
+		t.Fatal("expected the delete to invalidate the cached object info")
+	}
+
+	list()
+	list()
+	now = now.Add(2 * time.Minute)
+	list()
+	if backend.lists != 4 {
+		t.Fatalf("expected the listing to expire, got %d backend calls", backend.lists)
+	}
+}
+
+func TestCachedListingFSNegativeLookups(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	backend := &countingFS{BlockFS: &BlockFS{Config: BlockFSConfig{ChunkSize: 100}}}
+	store := NewCachedListingFS(backend, time.Minute)
+	store.NegativeTTL = time.Second
+	store.Clock = func() time.Time { return now }
+	marker := PathConfig{Path: dir + "/run/.done"}
+
+	for i := 0; i < 10; i++ {
+		if FileExists(store, marker.Path) {
+			t.Fatal("marker should not exist")
+		}
+	}
+	if backend.infos != 1 {
+		t.Fatalf("expected the missing marker to be cached, got %d backend calls", backend.infos)
+	}
+
+	now = now.Add(2 * time.Second)
+	FileExists(store, marker.Path)
+	if backend.infos != 2 {
+		t.Fatalf("expected the negative lookup to expire, got %d backend calls", backend.infos)
+	}
+
+	_, err := store.PutObject(PutObjectInput{Source: ObjectSource{Data: []byte("done")}, Dest: marker})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !FileExists(store, marker.Path) {
+		t.Fatal("expected the write to clear the negative lookup")
+	}
+}
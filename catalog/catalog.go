@@ -0,0 +1,216 @@
+// Package catalog exports the contents of a filesapi.FileStore prefix as a
+// STAC (https://stacspec.org) item collection so published datasets can be
+// registered with data catalogs.
+//
+// Each matching object becomes a STAC item with a single "data" asset carrying the
+// file extension size and checksum fields.  Spatial and temporal properties come
+// from an optional SidecarFunction, typically reading a .json/.xml sidecar or raster header.
+package catalog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/usace/filesapi"
+)
+
+const (
+	StacVersion          string = "1.0.0"
+	fileExtensionSchema  string = "https://stac-extensions.github.io/file/v2.1.0/schema.json"
+	sha256MultihashCode  string = "1220"
+	defaultAssetMimeType string = "application/octet-stream"
+)
+
+// SpatialMetadata describes the footprint and time of an object
+type SpatialMetadata struct {
+
+	//[west, south, east, north]
+	BBox []float64
+
+	//optional GeoJSON geometry.  Derived from BBox when omitted
+	Geometry any
+
+	Datetime time.Time
+
+	//additional STAC item properties
+	Properties map[string]any
+}
+
+// SidecarFunction returns the spatial metadata for an object.
+// Returning nil metadata skips the object
+type SidecarFunction func(store filesapi.FileStore, path string) (*SpatialMetadata, error)
+
+type ExportInput struct {
+	Store filesapi.FileStore
+	Root  filesapi.PathConfig
+
+	//optional pattern selecting the objects to export.  Defaults to every object
+	Pattern *regexp.Regexp
+
+	//computes sha256 checksums by streaming each object
+	Checksum bool
+
+	//optional metadata source for each object
+	Sidecar SidecarFunction
+
+	//optional base url prefixed to store paths for asset hrefs
+	BaseUrl string
+
+	//optional progress and cancellation
+	Progress filesapi.ProgressFunction
+}
+
+type Asset struct {
+	Href     string   `json:"href"`
+	Type     string   `json:"type,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+	Size     int64    `json:"file:size"`
+	Checksum string   `json:"file:checksum,omitempty"`
+}
+
+type Item struct {
+	Type           string           `json:"type"`
+	StacVersion    string           `json:"stac_version"`
+	StacExtensions []string         `json:"stac_extensions"`
+	Id             string           `json:"id"`
+	BBox           []float64        `json:"bbox,omitempty"`
+	Geometry       any              `json:"geometry"`
+	Properties     map[string]any   `json:"properties"`
+	Assets         map[string]Asset `json:"assets"`
+	Links          []any            `json:"links"`
+}
+
+type ItemCollection struct {
+	Type     string `json:"type"`
+	Features []Item `json:"features"`
+}
+
+// Export walks the root prefix and builds a STAC item collection
+func Export(input ExportInput) (*ItemCollection, error) {
+	if input.Store == nil {
+		return nil, errors.New("catalog export requires a store")
+	}
+	collection := &ItemCollection{Type: "FeatureCollection", Features: []Item{}}
+	count := 0
+	err := input.Store.Walk(filesapi.WalkInput{Path: input.Root}, func(p string, info os.FileInfo) error {
+		if info.IsDir() || (input.Pattern != nil && !input.Pattern.MatchString(p)) {
+			return nil
+		}
+		item, err := buildItem(input, p, info)
+		if err != nil {
+			return err
+		}
+		if item != nil {
+			collection.Features = append(collection.Features, *item)
+		}
+		count++
+		if input.Progress != nil {
+			return input.Progress(filesapi.ProgressData{Index: count, Max: -1, Value: p})
+		}
+		return nil
+	})
+	return collection, err
+}
+
+func (ic *ItemCollection) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(ic)
+}
+
+func buildItem(input ExportInput, p string, info os.FileInfo) (*Item, error) {
+	metadata := &SpatialMetadata{}
+	if input.Sidecar != nil {
+		var err error
+		metadata, err = input.Sidecar(input.Store, p)
+		if err != nil || metadata == nil {
+			return nil, err
+		}
+	}
+	datetime := metadata.Datetime
+	if datetime.IsZero() {
+		datetime = info.ModTime()
+	}
+	properties := map[string]any{}
+	for k, v := range metadata.Properties {
+		properties[k] = v
+	}
+	properties["datetime"] = datetime.UTC().Format(time.RFC3339)
+
+	asset := Asset{
+		Href:  strings.TrimSuffix(input.BaseUrl, "/") + "/" + strings.TrimPrefix(p, "/"),
+		Type:  assetType(p),
+		Roles: []string{"data"},
+		Size:  info.Size(),
+	}
+	if input.BaseUrl == "" {
+		asset.Href = p
+	}
+	if input.Checksum {
+		checksum, err := sha256Multihash(input.Store, p)
+		if err != nil {
+			return nil, err
+		}
+		asset.Checksum = checksum
+	}
+	geometry := metadata.Geometry
+	if geometry == nil && len(metadata.BBox) == 4 {
+		geometry = bboxPolygon(metadata.BBox)
+	}
+	return &Item{
+		Type:           "Feature",
+		StacVersion:    StacVersion,
+		StacExtensions: []string{fileExtensionSchema},
+		Id:             strings.Trim(strings.ReplaceAll(p, "/", "-"), "-"),
+		BBox:           metadata.BBox,
+		Geometry:       geometry,
+		Properties:     properties,
+		Assets:         map[string]Asset{"data": asset},
+		Links:          []any{},
+	}, nil
+}
+
+func assetType(p string) string {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".tif", ".tiff":
+		return "image/tiff; application=geotiff"
+	case ".geojson":
+		return "application/geo+json"
+	}
+	if t := mime.TypeByExtension(path.Ext(p)); t != "" {
+		return t
+	}
+	return defaultAssetMimeType
+}
+
+func bboxPolygon(b []float64) map[string]any {
+	return map[string]any{
+		"type": "Polygon",
+		"coordinates": [][][]float64{{
+			{b[0], b[1]}, {b[2], b[1]}, {b[2], b[3]}, {b[0], b[3]}, {b[0], b[1]},
+		}},
+	}
+}
+
+// stac file:checksum values are multihash encoded
+func sha256Multihash(store filesapi.FileStore, p string) (string, error) {
+	reader, err := store.GetObject(filesapi.GetObjectInput{Path: filesapi.PathConfig{Path: p}})
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, reader); err != nil {
+		return "", err
+	}
+	return sha256MultihashCode + hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,57 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/usace/filesapi"
+)
+
+func TestExport(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "depth.tif"), []byte("raster"), 0644)
+	os.WriteFile(filepath.Join(dir, "depth.tif.json"), []byte(`{"bbox":[-90.1,29.9,-89.9,30.1]}`), 0644)
+	os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("notes"), 0644)
+
+	collection, err := Export(ExportInput{
+		Store:    store,
+		Root:     filesapi.PathConfig{Path: dir},
+		Pattern:  regexp.MustCompile(`\.tif$`),
+		Checksum: true,
+		Sidecar: func(store filesapi.FileStore, path string) (*SpatialMetadata, error) {
+			reader, err := store.GetObject(filesapi.GetObjectInput{Path: filesapi.PathConfig{Path: path + ".json"}})
+			if err != nil {
+				return nil, err
+			}
+			defer reader.Close()
+			metadata := &SpatialMetadata{}
+			return metadata, json.NewDecoder(reader).Decode(metadata)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collection.Features) != 1 {
+		t.Fatalf("expected a single item, got %d", len(collection.Features))
+	}
+	item := collection.Features[0]
+	if len(item.BBox) != 4 || item.Geometry == nil || item.Assets["data"].Size != 6 {
+		t.Fatalf("unexpected item: %v", item)
+	}
+	if !strings.HasPrefix(item.Assets["data"].Checksum, sha256MultihashCode) {
+		t.Fatalf("expected a sha256 multihash checksum, got %s", item.Assets["data"].Checksum)
+	}
+	var buf bytes.Buffer
+	if err = collection.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,84 @@
+package filesapi
+
+import (
+	"errors"
+	"fmt"
+)
+
+// maximum size of a single part in an S3 multipart upload
+const max_upload_part_size int64 = 5 * 1024 * 1024 * 1024
+
+// ErrInvalidChunkPlan is wrapped by the errors of chunk plans and chunks that break the S3 multipart limits
+var ErrInvalidChunkPlan = errors.New("invalid chunk plan")
+
+// ChunkRange is the byte range of a single chunk.  Chunk ids are 0 referenced, as in UploadConfig
+type ChunkRange struct {
+	ChunkId int32 `json:"chunkId"`
+	Offset  int64 `json:"offset"`
+	Size    int64 `json:"size"`
+}
+
+// ChunkPlan splits an object into the chunks of a multipart upload
+type ChunkPlan struct {
+	Size     int64        `json:"size"`
+	PartSize int64        `json:"partSize"`
+	Count    int          `json:"count"`
+	Chunks   []ChunkRange `json:"chunks"`
+}
+
+// PlanChunks splits an object of size bytes into chunks of partSize, with a shorter last chunk.
+// Empty objects are planned as a single empty chunk.  A partSize of 0 selects the default chunk
+// size, grown when needed to stay within max_upload_parts chunks.
+//
+// Plans are validated against the S3 limits: at most max_upload_parts chunks, and chunks of at
+// least min_multipart_part_size (except the last) and at most max_upload_part_size.  Browser
+// clients and the upload handlers share the plan, so both agree on the chunk boundaries
+func PlanChunks(size int64, partSize int64) (*ChunkPlan, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("%w: negative object size %d", ErrInvalidChunkPlan, size)
+	}
+	if partSize < 0 {
+		return nil, fmt.Errorf("%w: negative part size %d", ErrInvalidChunkPlan, partSize)
+	}
+	if partSize == 0 {
+		partSize = defaultChunkSize
+		if size > partSize*max_upload_parts {
+			partSize = (size + max_upload_parts - 1) / max_upload_parts
+		}
+	}
+	count := 1
+	if size > partSize {
+		count64 := (size + partSize - 1) / partSize
+		if count64 > max_upload_parts {
+			return nil, fmt.Errorf("%w: %d parts of %d bytes exceed the limit of %d parts", ErrInvalidChunkPlan, count64, partSize, max_upload_parts)
+		}
+		count = int(count64)
+	}
+	if count > 1 && partSize < min_multipart_part_size {
+		return nil, fmt.Errorf("%w: part size %d is less than the minimum of %d bytes", ErrInvalidChunkPlan, partSize, min_multipart_part_size)
+	}
+	if partSize > max_upload_part_size && size > max_upload_part_size {
+		return nil, fmt.Errorf("%w: part size %d exceeds the maximum of %d bytes", ErrInvalidChunkPlan, partSize, max_upload_part_size)
+	}
+	plan := &ChunkPlan{Size: size, PartSize: partSize, Count: count, Chunks: make([]ChunkRange, count)}
+	for i := range plan.Chunks {
+		offset := int64(i) * partSize
+		chunkSize := partSize
+		if offset+chunkSize > size {
+			chunkSize = size - offset
+		}
+		plan.Chunks[i] = ChunkRange{ChunkId: int32(i), Offset: offset, Size: chunkSize}
+	}
+	return plan, nil
+}
+
+// ValidateChunk checks that a chunk written to an upload matches the plan
+func (cp *ChunkPlan) ValidateChunk(chunkId int32, size int64) error {
+	if chunkId < 0 || int(chunkId) >= cp.Count {
+		return fmt.Errorf("%w: chunk %d is outside the %d planned chunks", ErrInvalidChunkPlan, chunkId, cp.Count)
+	}
+	if expected := cp.Chunks[chunkId].Size; size != expected {
+		return fmt.Errorf("%w: chunk %d has %d bytes, expected %d", ErrInvalidChunkPlan, chunkId, size, expected)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package filesapi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPlanChunks(t *testing.T) {
+	partSize := int64(min_multipart_part_size)
+	plan, err := PlanChunks(2*partSize+10, partSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Count != 3 || plan.Chunks[2].Offset != 2*partSize || plan.Chunks[2].Size != 10 {
+		t.Fatalf("unexpected plan %+v", plan.Chunks)
+	}
+	if err = plan.ValidateChunk(1, partSize); err != nil {
+		t.Fatal(err)
+	}
+	if err = plan.ValidateChunk(2, partSize); !errors.Is(err, ErrInvalidChunkPlan) {
+		t.Fatalf("expected a mismatched last chunk to be rejected, got %v", err)
+	}
+	if err = plan.ValidateChunk(3, 10); !errors.Is(err, ErrInvalidChunkPlan) {
+		t.Fatalf("expected an unplanned chunk to be rejected, got %v", err)
+	}
+
+	if plan, err = PlanChunks(0, 0); err != nil || plan.Count != 1 || plan.Chunks[0].Size != 0 {
+		t.Fatalf("expected a single empty chunk, got %v: %v", plan, err)
+	}
+	if _, err = PlanChunks(100, 10); !errors.Is(err, ErrInvalidChunkPlan) {
+		t.Fatalf("expected small parts of multipart objects to be rejected, got %v", err)
+	}
+	if plan, err = PlanChunks(10, 100); err != nil || plan.Count != 1 {
+		t.Fatalf("expected objects smaller than a part to be a single chunk, got %v: %v", plan, err)
+	}
+
+	large := defaultChunkSize*max_upload_parts + 1
+	if plan, err = PlanChunks(large, 0); err != nil || plan.Count > max_upload_parts {
+		t.Fatalf("expected the default part size to grow within the part limit: %v", err)
+	}
+	if _, err = PlanChunks(large, defaultChunkSize); !errors.Is(err, ErrInvalidChunkPlan) {
+		t.Fatalf("expected plans exceeding the part limit to be rejected, got %v", err)
+	}
+	if _, err = PlanChunks(2*partSize, partSize-1); !errors.Is(err, ErrInvalidChunkPlan) {
+		t.Fatalf("expected parts below the minimum to be rejected, got %v", err)
+	}
+	if _, err = PlanChunks(-1, 0); !errors.Is(err, ErrInvalidChunkPlan) {
+		t.Fatal("expected negative sizes to be rejected")
+	}
+}
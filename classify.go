@@ -0,0 +1,180 @@
+package filesapi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// number of leading bytes of an object handed to a Classifier (the length sniffed by http.DetectContentType)
+const sniffLength int = 512
+
+// ErrClassificationRefused is returned by a ClassifiedFS when its Classifier refuses a write
+var ErrClassificationRefused = errors.New("write refused by classification policy")
+
+type ClassifyInput struct {
+
+	//destination path of the write
+	Path string
+
+	//leading bytes of the object.  Nil for multipart upload sessions, which are classified before any data is written
+	Head []byte
+
+	//content type of the put, or the type sniffed from Head
+	ContentType string
+}
+
+// Classification is stored with the object.  The values replace any tags or metadata
+// of the same name set by the caller
+type Classification struct {
+	Tags     map[string]string
+	Metadata map[string]string
+}
+
+func (c *Classification) empty() bool {
+	return c == nil || (len(c.Tags) == 0 && len(c.Metadata) == 0)
+}
+
+// Classifier assigns the tags and metadata of an object (i.e. CUI markings) from its path and
+// content.  Returning an error refuses the write.  A nil Classification writes the object unmarked
+type Classifier func(input ClassifyInput) (*Classification, error)
+
+// ClassifiedFS is a FileStore decorator enforcing an information handling policy.  Every put is
+// classified before it is written, and the classification is stored as object tags and metadata.
+// Block file stores ignore tags and metadata, so policies for them can only refuse writes.
+// Multipart upload sessions cannot carry tags or metadata, and are refused unless the Classifier
+// leaves them unmarked.  Copies keep the classification of the source object
+type ClassifiedFS struct {
+	FileStore
+	Classifier Classifier
+}
+
+func NewClassifiedFS(store FileStore, classifier Classifier) *ClassifiedFS {
+	return &ClassifiedFS{
+		FileStore:  store,
+		Classifier: classifier,
+	}
+}
+
+func (cfs *ClassifiedFS) classify(input ClassifyInput) (*Classification, error) {
+	classification, err := cfs.Classifier(input)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrClassificationRefused, input.Path, err)
+	}
+	return classification, nil
+}
+
+func (cfs *ClassifiedFS) PutObject(input PutObjectInput) (*FileOperationOutput, error) {
+	head, err := sniffSource(&input.Source)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the Source for classification: %s\n", err)
+	}
+	contentType := input.ContentType
+	if contentType == "" {
+		contentType = http.DetectContentType(head)
+	}
+	classification, err := cfs.classify(ClassifyInput{Path: input.Dest.Path, Head: head, ContentType: contentType})
+	if err != nil {
+		return nil, err
+	}
+	if !classification.empty() {
+		input.Options = classification.apply(input.Options)
+	}
+	return cfs.FileStore.PutObject(input)
+}
+
+func (cfs *ClassifiedFS) InitializeObjectUpload(u UploadConfig) (UploadResult, error) {
+	classification, err := cfs.classify(ClassifyInput{Path: u.ObjectPath})
+	if err != nil {
+		return UploadResult{}, err
+	}
+	if !classification.empty() {
+		return UploadResult{}, fmt.Errorf("%w: %s must be classified, and multipart upload sessions cannot store a classification", ErrClassificationRefused, u.ObjectPath)
+	}
+	return cfs.FileStore.InitializeObjectUpload(u)
+}
+
+// returns a copy of the call options with the classification merged in
+func (c *Classification) apply(options *CallOptions) *CallOptions {
+	merged := CallOptions{}
+	if options != nil {
+		merged = *options
+	}
+	merged.Tags = mergeClassification(merged.Tags, c.Tags)
+	merged.Metadata = mergeClassification(merged.Metadata, c.Metadata)
+	return &merged
+}
+
+func mergeClassification(values map[string]string, classification map[string]string) map[string]string {
+	if len(classification) == 0 {
+		return values
+	}
+	merged := make(map[string]string, len(values)+len(classification))
+	for k, v := range values {
+		merged[k] = v
+	}
+	for k, v := range classification {
+		merged[k] = v
+	}
+	return merged
+}
+
+// reads the leading bytes of a source without consuming it.  Plain Readers that cannot
+// seek are replaced with a reader replaying the sniffed bytes
+func sniffSource(src *ObjectSource) ([]byte, error) {
+	if src.Data != nil {
+		if len(src.Data) > sniffLength {
+			return src.Data[:sniffLength], nil
+		}
+		return src.Data, nil
+	}
+	head := make([]byte, sniffLength)
+	if src.Reader != nil {
+		if br, ok := src.Reader.(*bytes.Reader); ok && src.ContentLength == nil {
+			cl := br.Size()
+			src.ContentLength = &cl
+		}
+		if rs, ok := src.Reader.(io.ReadSeeker); ok {
+			pos, err := rs.Seek(0, io.SeekCurrent)
+			if err != nil {
+				return nil, err
+			}
+			n, err := io.ReadFull(rs, head)
+			if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+				return nil, err
+			}
+			_, err = rs.Seek(pos, io.SeekStart)
+			return head[:n], err
+		}
+		n, err := io.ReadFull(src.Reader, head)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		head = head[:n]
+		src.Reader = io.MultiReader(bytes.NewReader(head), src.Reader)
+		return head, nil
+	}
+	if src.ReaderAt != nil {
+		if src.ContentLength != nil && *src.ContentLength < int64(sniffLength) {
+			head = head[:*src.ContentLength]
+		}
+		n, err := src.ReaderAt.ReadAt(head, 0)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		return head[:n], nil
+	}
+	//remaining sources are replayable, so the sniffed reader is discarded
+	reader, err := src.GetReader()
+	if err != nil {
+		return nil, err
+	}
+	defer src.closeReader(reader)
+	n, err := io.ReadFull(reader, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return head[:n], nil
+}
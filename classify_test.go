@@ -0,0 +1,115 @@
+package filesapi
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestClassifiedFS(t *testing.T) {
+	var tagging url.Values
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			tagging, _ = url.ParseQuery(r.Header.Get("X-Amz-Tagging"))
+			data, _ := io.ReadAll(r.Body)
+			body = string(data)
+			w.Header().Set("ETag", `"etag"`)
+		}
+	}))
+	defer server.Close()
+	store, err := NewFileStore(MinioFSConfig{
+		S3FSConfig: S3FSConfig{
+			S3Region:    "us-east-1",
+			S3Bucket:    "bucket",
+			Credentials: S3FS_Static{S3Id: "id", S3Key: "key"},
+		},
+		HostAddress: server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var classified ClassifyInput
+	cfs := NewClassifiedFS(store, func(input ClassifyInput) (*Classification, error) {
+		classified = input
+		if strings.HasPrefix(input.Path, "/public/") {
+			if strings.Contains(string(input.Head), "CUI") {
+				return nil, errors.New("CUI may not be written to public paths")
+			}
+			return nil, nil
+		}
+		return &Classification{Tags: map[string]string{"classification": "CUI"}}, nil
+	})
+
+	//a seekable reader is rewound after sniffing
+	content := "CUI//SP-PRVCY report"
+	_, err = cfs.PutObject(PutObjectInput{
+		Source:  ObjectSource{Reader: strings.NewReader(content)},
+		Dest:    PathConfig{Path: "/data/report.txt"},
+		Options: &CallOptions{Tags: map[string]string{"project": "levee", "classification": "none"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != content || string(classified.Head) != content || !strings.HasPrefix(classified.ContentType, "text/plain") {
+		t.Fatalf("unexpected classified content %q, %+v", body, classified)
+	}
+	if tagging.Get("classification") != "CUI" || tagging.Get("project") != "levee" {
+		t.Fatalf("expected the classification tags to be stored, got %v", tagging)
+	}
+
+	body = ""
+	_, err = cfs.PutObject(PutObjectInput{Source: ObjectSource{Data: []byte(content)}, Dest: PathConfig{Path: "/public/report.txt"}})
+	if !errors.Is(err, ErrClassificationRefused) || body != "" {
+		t.Fatalf("expected the write to be refused before it was stored, got %v", err)
+	}
+	if _, err := cfs.PutObject(PutObjectInput{Source: ObjectSource{Data: []byte("open data")}, Dest: PathConfig{Path: "/public/open.txt"}}); err != nil {
+		t.Fatal(err)
+	}
+	if tagging.Get("classification") != "" {
+		t.Fatalf("expected unclassified objects to be unmarked, got %v", tagging)
+	}
+	if _, err := cfs.InitializeObjectUpload(UploadConfig{ObjectPath: "/data/large.bin"}); !errors.Is(err, ErrClassificationRefused) {
+		t.Fatalf("expected classified multipart uploads to be refused, got %v", err)
+	}
+}
+
+func TestSniffSource(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "sniff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(strings.Repeat("x", 1000))
+	file.Close()
+	sources := []ObjectSource{
+		{Filepath: PathConfig{Path: file.Name()}},
+		{Reader: io.MultiReader(strings.NewReader(strings.Repeat("x", 1000)))},
+		{ReaderAt: strings.NewReader(strings.Repeat("x", 1000)), ContentLength: func() *int64 { n := int64(10); return &n }()},
+	}
+	for i := range sources {
+		head, err := sniffSource(&sources[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 2 {
+			if len(head) != 10 {
+				t.Fatalf("expected the sniff to honor the content length, got %d bytes", len(head))
+			}
+			continue
+		}
+		reader, err := sources[i].GetReader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(reader)
+		sources[i].closeReader(reader)
+		if len(head) != sniffLength || len(data) != 1000 {
+			t.Fatalf("expected the sniff to leave source %d unconsumed, got %d and %d bytes", i, len(head), len(data))
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package filesapi
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrComplianceUnsupported is returned by NewFileStore when a store configured with
+// ComplianceMode cannot satisfy FIPS endpoint, TLS, or SigV4 signing requirements
+var ErrComplianceUnsupported = errors.New("store configuration does not satisfy compliance mode")
+
+// S3 regions with FIPS 140 validated endpoints
+var fipsRegions = map[string]bool{
+	"us-east-1":     true,
+	"us-east-2":     true,
+	"us-west-1":     true,
+	"us-west-2":     true,
+	"ca-central-1":  true,
+	"ca-west-1":     true,
+	"us-gov-east-1": true,
+	"us-gov-west-1": true,
+}
+
+// validateCompliance reports configurations that cannot meet ComplianceMode.
+// hostAddress is the custom endpoint of S3 compatible stores, which must use https
+// because FIPS endpoint selection does not apply to them
+func (sc *S3FSConfig) validateCompliance(hostAddress string) error {
+	if !sc.ComplianceMode {
+		return nil
+	}
+	if hostAddress != "" {
+		u, err := url.Parse(hostAddress)
+		if err != nil || !strings.EqualFold(u.Scheme, "https") {
+			return fmt.Errorf("%w: endpoint %s must use https", ErrComplianceUnsupported, hostAddress)
+		}
+	} else if !fipsRegions[sc.S3Region] && !sc.AutoDetectRegion {
+		return fmt.Errorf("%w: region %s has no FIPS endpoint", ErrComplianceUnsupported, sc.S3Region)
+	}
+	if _, ok := sc.Credentials.(S3FS_Anonymous); ok {
+		return fmt.Errorf("%w: anonymous credentials send unsigned requests", ErrComplianceUnsupported)
+	}
+	if sc.ClientOptions != nil && sc.ClientOptions.DisablePayloadSigning {
+		return fmt.Errorf("%w: DisablePayloadSigning sends unsigned payloads", ErrComplianceUnsupported)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package filesapi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestComplianceMode(t *testing.T) {
+	valid := S3FSConfig{
+		S3Region:       "us-gov-west-1",
+		S3Bucket:       "models",
+		Credentials:    S3FS_Attached{},
+		ComplianceMode: true,
+	}
+	if err := valid.validateCompliance(""); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := []struct {
+		name   string
+		config S3FSConfig
+		host   string
+	}{
+		{"non fips region", S3FSConfig{S3Region: "eu-west-1", Credentials: S3FS_Attached{}, ComplianceMode: true}, ""},
+		{"anonymous", S3FSConfig{S3Region: "us-east-1", Credentials: S3FS_Anonymous{}, ComplianceMode: true}, ""},
+		{"unsigned payloads", S3FSConfig{S3Region: "us-east-1", Credentials: S3FS_Attached{}, ComplianceMode: true, ClientOptions: &S3ClientOptions{DisablePayloadSigning: true}}, ""},
+		{"plain http endpoint", S3FSConfig{S3Region: "us-east-1", Credentials: S3FS_Static{}, ComplianceMode: true}, "http://minio:9000"},
+	}
+	for _, test := range invalid {
+		if err := test.config.validateCompliance(test.host); !errors.Is(err, ErrComplianceUnsupported) {
+			t.Fatalf("%s: expected a compliance error, got %v", test.name, err)
+		}
+	}
+
+	_, err := NewFileStore(S3FSConfig{S3Region: "eu-west-1", Credentials: S3FS_Attached{}, ComplianceMode: true})
+	if !errors.Is(err, ErrComplianceUnsupported) {
+		t.Fatalf("expected NewFileStore to reject the configuration, got %v", err)
+	}
+}
@@ -0,0 +1,271 @@
+package filesapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// minimum size of every part but the last in an S3 multipart upload
+const min_multipart_part_size = 5 * 1024 * 1024
+
+// Concatenator is implemented by stores that can assemble an object from existing objects
+// without routing the data through the client
+type Concatenator interface {
+	ConcatObjects(dest PathConfig, srcs []PathConfig) error
+}
+
+// ConcatObjects assembles dest from the srcs in order (i.e. to merge chunked exports).
+// Stores implementing Concatenator assemble the object server side.  Other stores
+// (including decorators) stream each source into a single put
+func ConcatObjects(store FileStore, dest PathConfig, srcs []PathConfig) error {
+	if c, ok := store.(Concatenator); ok {
+		err := c.ConcatObjects(dest, srcs)
+		if !errors.Is(err, errPartCopyUnsupported) {
+			return err
+		}
+	}
+	readers := make([]io.Reader, len(srcs))
+	for i, src := range srcs {
+		readers[i] = &lazyObjectReader{store: store, path: src}
+	}
+	_, err := store.PutObject(PutObjectInput{
+		Source:   ObjectSource{Reader: io.MultiReader(readers...)},
+		Dest:     dest,
+		Mutipart: true,
+	})
+	for _, r := range readers {
+		r.(*lazyObjectReader).close()
+	}
+	return err
+}
+
+// opens an object on first read so only one source is open at a time
+type lazyObjectReader struct {
+	store  FileStore
+	path   PathConfig
+	reader io.ReadCloser
+	done   bool
+}
+
+func (lr *lazyObjectReader) Read(p []byte) (int, error) {
+	if lr.done {
+		return 0, io.EOF
+	}
+	if lr.reader == nil {
+		reader, err := lr.store.GetObject(GetObjectInput{Path: lr.path})
+		if err != nil {
+			return 0, err
+		}
+		lr.reader = reader
+	}
+	n, err := lr.reader.Read(p)
+	if err == io.EOF {
+		lr.close()
+	}
+	return n, err
+}
+
+func (lr *lazyObjectReader) close() {
+	lr.done = true
+	if lr.reader != nil {
+		lr.reader.Close()
+		lr.reader = nil
+	}
+}
+
+// ConcatObjects appends each source to a temporary file that replaces dest once complete,
+// so dest may also be one of the sources
+func (b *BlockFS) ConcatObjects(dest PathConfig, srcs []PathConfig) error {
+	if err := os.MkdirAll(filepath.Dir(dest.Path), os.ModePerm); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest.Path), ".filesapi-concat-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	for _, src := range srcs {
+		if err = appendFile(tmp, src.Path); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	os.Chmod(tmp.Name(), 0755)
+	return os.Rename(tmp.Name(), dest.Path)
+}
+
+func appendFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &FileNotFoundError{path}
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// ConcatObjects assembles dest with a multipart upload.  Sources of at least 5MB are
+// copied server side with UploadPartCopy.  Smaller sources, and the tails of sources
+// that would leave a part under the 5MB minimum, are read and merged into uploaded parts
+func (s3fs *S3FS) ConcatObjects(dest PathConfig, srcs []PathConfig) error {
+	client, err := s3fs.client()
+	if err != nil {
+		return err
+	}
+	if s3fs.partCopy.unsupported() {
+		return errPartCopyUnsupported
+	}
+	sizes := make([]int64, len(srcs))
+	for i, src := range srcs {
+		info, err := s3fs.GetObjectInfo(src)
+		if err != nil {
+			return err
+		}
+		sizes[i] = info.Size()
+	}
+	destKey := strings.TrimPrefix(dest.Path, "/")
+	ctx := context.TODO()
+
+	createInput := &s3.CreateMultipartUploadInput{
+		Bucket: &s3fs.config.S3Bucket,
+		Key:    &destKey,
+	}
+	if err := s3fs.encryptMultipart(createInput); err != nil {
+		return err
+	}
+	createOutput, err := client.CreateMultipartUpload(ctx, createInput)
+	if err != nil {
+		return err
+	}
+	uploadId := createOutput.UploadId
+	abort := func(err error) error {
+		client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   &s3fs.config.S3Bucket,
+			Key:      &destKey,
+			UploadId: uploadId,
+		})
+		return err
+	}
+
+	parts := []types.CompletedPart{}
+	buf := new(bytes.Buffer)
+	uploadBuffer := func() error {
+		partNumber := int32(len(parts) + 1)
+		output, err := client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     &s3fs.config.S3Bucket,
+			Key:        &destKey,
+			UploadId:   uploadId,
+			PartNumber: &partNumber,
+			Body:       bytes.NewReader(buf.Bytes()),
+		})
+		if err != nil {
+			return fmt.Errorf("Error uploading part %d : %w", partNumber, err)
+		}
+		parts = append(parts, types.CompletedPart{ETag: output.ETag, PartNumber: &partNumber})
+		buf.Reset()
+		return nil
+	}
+
+	for i, src := range srcs {
+		srcKey := strings.TrimPrefix(src.Path, "/")
+		copySource := buildCopySource(s3fs.ResourceName(), srcKey)
+		last := i == len(srcs)-1
+		var offset int64
+		for offset < sizes[i] {
+			remaining := sizes[i] - offset
+			if buf.Len() > 0 || (remaining < min_multipart_part_size && !last) {
+				//fill the pending part up to the minimum part size
+				n := int64(min_multipart_part_size - buf.Len())
+				if n > remaining {
+					n = remaining
+				}
+				reader, err := s3fs.GetObject(GetObjectInput{
+					Path:  src,
+					Range: fmt.Sprintf("bytes=%d-%d", offset, offset+n-1),
+				})
+				if err != nil {
+					return abort(err)
+				}
+				_, err = io.Copy(buf, reader)
+				reader.Close()
+				if err != nil {
+					return abort(err)
+				}
+				offset += n
+				if buf.Len() >= min_multipart_part_size {
+					if err = uploadBuffer(); err != nil {
+						return abort(err)
+					}
+				}
+				continue
+			}
+			n := remaining
+			if n > max_put_object_copy_size {
+				n = max_put_object_copy_size
+			}
+			partNumber := int32(len(parts) + 1)
+			copyRange := fmt.Sprintf("bytes=%d-%d", offset, offset+n-1)
+			output, err := client.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+				Bucket:          &s3fs.config.S3Bucket,
+				Key:             &destKey,
+				UploadId:        uploadId,
+				PartNumber:      &partNumber,
+				CopySource:      &copySource,
+				CopySourceRange: &copyRange,
+			})
+			if err != nil {
+				if isNotImplementedError(err) {
+					s3fs.partCopy.set(false)
+					return abort(fmt.Errorf("%w: %s", errPartCopyUnsupported, err))
+				}
+				return abort(fmt.Errorf("Error copying part %d : %w", partNumber, err))
+			}
+			parts = append(parts, types.CompletedPart{ETag: output.CopyPartResult.ETag, PartNumber: &partNumber})
+			offset += n
+		}
+	}
+	if buf.Len() > 0 {
+		if err = uploadBuffer(); err != nil {
+			return abort(err)
+		}
+	}
+	if len(parts) == 0 {
+		//every source was empty
+		abort(nil)
+		input := &s3.PutObjectInput{
+			Bucket: &s3fs.config.S3Bucket,
+			Key:    &destKey,
+			Body:   bytes.NewReader(nil),
+		}
+		if err = s3fs.encryptPut(input); err != nil {
+			return err
+		}
+		_, err = client.PutObject(ctx, input)
+		return err
+	}
+	_, err = client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          &s3fs.config.S3Bucket,
+		Key:             &destKey,
+		UploadId:        uploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return abort(fmt.Errorf("Error completing upload: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package filesapi
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestConcatObjects(t *testing.T) {
+	dir := t.TempDir()
+	block := &BlockFS{Config: BlockFSConfig{ChunkSize: 100}}
+	srcs := []PathConfig{}
+	for _, part := range []string{"part-0", "part-1", "part-2"} {
+		path := PathConfig{Path: dir + "/export/" + part}
+		_, err := block.PutObject(PutObjectInput{
+			Source: ObjectSource{Data: []byte(part + ";")},
+			Dest:   path,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		srcs = append(srcs, path)
+	}
+	expected := "part-0;part-1;part-2;"
+
+	//server side assembly.  The destination may also be a source
+	if err := ConcatObjects(block, srcs[0], srcs); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(srcs[0].Path)
+	if err != nil || string(data) != expected {
+		t.Fatalf("unexpected concatenated object %q %v", data, err)
+	}
+
+	//streamed assembly through a decorator
+	if err = os.WriteFile(srcs[0].Path, []byte("part-0;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dest := PathConfig{Path: dir + "/merged/export.csv"}
+	if err = ConcatObjects(NewCachedListingFS(block, time.Minute), dest, srcs); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(dest.Path)
+	if err != nil || string(data) != expected {
+		t.Fatalf("unexpected concatenated object %q %v", data, err)
+	}
+
+	if err = ConcatObjects(block, dest, []PathConfig{{Path: dir + "/missing"}}); err == nil {
+		t.Fatal("expected an error for a missing source")
+	}
+}
@@ -0,0 +1,120 @@
+package filesapi
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// exercises each decorator from many goroutines.  Run with -race to check the
+// concurrency claim on FileStore
+func TestDecoratorsConcurrentUse(t *testing.T) {
+	var published int64
+	bus := NewEventBus()
+	bus.Subscribe(func(e Event) {
+		atomic.AddInt64(&published, 1)
+	})
+	decorators := []struct {
+		name     string
+		decorate func(base FileStore, dir string) FileStore
+	}{
+		{"prefix", func(base FileStore, dir string) FileStore {
+			return base
+		}},
+		{"cached", func(base FileStore, dir string) FileStore {
+			return NewCachedListingFS(base, time.Minute)
+		}},
+		{"retry", func(base FileStore, dir string) FileStore {
+			return NewRetryFS(base, RetryPolicy{MaxAttempts: 3, MaxBackoff: 0.01, R: 2})
+		}},
+		{"protected", func(base FileStore, dir string) FileStore {
+			return NewProtectedFS(base, "/published")
+		}},
+		{"events", func(base FileStore, dir string) FileStore {
+			return NewEventFS(base, bus)
+		}},
+		{"classified", func(base FileStore, dir string) FileStore {
+			return NewClassifiedFS(base, func(input ClassifyInput) (*Classification, error) {
+				return nil, nil
+			})
+		}},
+		{"migration", func(base FileStore, dir string) FileStore {
+			return NewMigrationFS(base, NewPrefixFS(&BlockFS{}, filepath.Join(dir, "new")))
+		}},
+		{"scheduled", func(base FileStore, dir string) FileStore {
+			return NewScheduledFS(base, NewTransferScheduler(TransferSchedulerConfig{MaxConcurrent: 2}))
+		}},
+		{"stacked", func(base FileStore, dir string) FileStore {
+			store := NewProtectedFS(NewEventFS(NewRetryFS(base, RetryPolicy{MaxAttempts: 3, MaxBackoff: 0.01, R: 2}), bus), "/published")
+			return NewCachedListingFS(store, time.Minute)
+		}},
+	}
+	for _, decorator := range decorators {
+		t.Run(decorator.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store := decorator.decorate(NewPrefixFS(&BlockFS{}, filepath.Join(dir, "old")), dir)
+			var wg sync.WaitGroup
+			for w := 0; w < 8; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					if err := concurrentWorker(store, w); err != nil {
+						t.Error(err)
+					}
+				}(w)
+			}
+			wg.Wait()
+		})
+	}
+	if atomic.LoadInt64(&published) == 0 {
+		t.Fatal("expected the event decorators to publish events")
+	}
+}
+
+// writes, reads, lists, copies, and deletes objects beneath a directory owned by the worker
+func concurrentWorker(store FileStore, w int) error {
+	for i := 0; i < 10; i++ {
+		src := fmt.Sprintf("/w%d/f%d.txt", w, i)
+		dest := fmt.Sprintf("/w%d/copy%d.txt", w, i)
+		_, err := store.PutObject(PutObjectInput{
+			Source: ObjectSource{Data: []byte(testObjectString)},
+			Dest:   PathConfig{Path: src},
+		})
+		if err != nil {
+			return err
+		}
+		reader, err := store.GetObject(GetObjectInput{Path: PathConfig{Path: src}})
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+		if string(data) != testObjectString {
+			return fmt.Errorf("unexpected contents of %s: %q", src, data)
+		}
+		info, err := store.GetObjectInfo(PathConfig{Path: src})
+		if err != nil {
+			return err
+		}
+		if info.Size() != int64(len(testObjectString)) {
+			return fmt.Errorf("unexpected size of %s: %d", src, info.Size())
+		}
+		if _, err = store.ListDir(ListDirInput{Path: PathConfig{Path: fmt.Sprintf("/w%d/", w)}}); err != nil {
+			return err
+		}
+		if err = store.CopyObject(CopyObjectInput{Src: PathConfig{Path: src}, Dest: PathConfig{Path: dest}}); err != nil {
+			return err
+		}
+		if errs := store.DeleteObjects(DeleteObjectInput{Paths: PathConfig{Path: dest}}); len(errs) > 0 {
+			return errs[0]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package filesapi
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var ErrInvalidConfig = errors.New("invalid store configuration")
+
+// ConfigError identifies the store configuration field that failed validation.
+// It matches ErrInvalidConfig with errors.Is
+type ConfigError struct {
+	Field   string
+	Message string
+}
+
+func (ce *ConfigError) Error() string {
+	return fmt.Sprintf("%s: %s %s", ErrInvalidConfig, ce.Field, ce.Message)
+}
+
+func (ce *ConfigError) Is(target error) bool {
+	return target == ErrInvalidConfig
+}
+
+func configError(field string, format string, args ...any) error {
+	return &ConfigError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+// S3 bucket naming rules: 3-63 lowercase letters, numbers, dots and hyphens,
+// beginning and ending with a letter or number
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// AWS region names (i.e. us-east-1, us-gov-west-1, ap-southeast-2)
+var regionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+$`)
+
+// maximum keys S3 returns in a single list response
+const maxListKeys int32 = 1000
+
+func validateBucketName(bucket string) error {
+	switch {
+	case bucket == "":
+		return configError("S3Bucket", "is required")
+	case !bucketNamePattern.MatchString(bucket):
+		return configError("S3Bucket", "%q must be 3-63 lowercase letters, numbers, dots or hyphens, beginning and ending with a letter or number", bucket)
+	case strings.Contains(bucket, ".."):
+		return configError("S3Bucket", "%q must not contain adjacent dots", bucket)
+	case net.ParseIP(bucket) != nil:
+		return configError("S3Bucket", "%q must not be formatted as an IP address", bucket)
+	}
+	return nil
+}
+
+func validateCredentials(field string, credentials any) error {
+	switch cred := credentials.(type) {
+	case nil:
+		return configError(field, "is required")
+	case S3FS_Static:
+		if cred.S3Id == "" {
+			return configError(field, "static credentials require an S3Id")
+		}
+		if cred.S3Key == "" {
+			return configError(field, "static credentials require an S3Key")
+		}
+	case S3FS_Attached, S3FS_Anonymous:
+	case S3FS_Role:
+		return configError(field, "assumed role credentials are not supported")
+	case S3FS_CredentialChain:
+		if len(cred) == 0 {
+			return configError(field, "credential chain is empty")
+		}
+		for i, c := range cred {
+			switch c.(type) {
+			case S3FS_Static, S3FS_Attached:
+				if err := validateCredentials(fmt.Sprintf("%s[%d]", field, i), c); err != nil {
+					return err
+				}
+			default:
+				return configError(fmt.Sprintf("%s[%d]", field, i), "has unsupported credential type %T", c)
+			}
+		}
+	default:
+		return configError(field, "has unsupported credential type %T", credentials)
+	}
+	return nil
+}
+
+// validates an http(s) endpoint url
+func validateEndpoint(field string, endpoint string) error {
+	if endpoint == "" {
+		return configError(field, "is required")
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return configError(field, "%q is not a valid url: %s", endpoint, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return configError(field, "%q must be an http or https url", endpoint)
+	}
+	if u.Host == "" {
+		return configError(field, "%q has no host", endpoint)
+	}
+	return nil
+}
+
+// checks the fields of an S3 configuration.  Minio stores accept any non empty region name
+func (sc *S3FSConfig) validate(minio bool) error {
+	if err := validateBucketName(sc.S3Bucket); err != nil {
+		return err
+	}
+	if minio {
+		if strings.TrimSpace(sc.S3Region) == "" {
+			return configError("S3Region", "is required")
+		}
+	} else if sc.S3Region != "" && !regionPattern.MatchString(sc.S3Region) {
+		return configError("S3Region", "%q is not a valid AWS region", sc.S3Region)
+	}
+	if sc.Delimiter != "" {
+		for _, r := range sc.Delimiter {
+			if !unicode.IsPrint(r) {
+				return configError("Delimiter", "%q must not contain non printable characters", sc.Delimiter)
+			}
+		}
+	}
+	if sc.MaxKeys < 0 || sc.MaxKeys > maxListKeys {
+		return configError("MaxKeys", "%d must be between 0 (the default) and %d", sc.MaxKeys, maxListKeys)
+	}
+	if sc.ListConcurrency < 0 {
+		return configError("ListConcurrency", "%d must not be negative", sc.ListConcurrency)
+	}
+	return validateCredentials("Credentials", sc.Credentials)
+}
+
+func (mc *MinioFSConfig) validate() error {
+	if err := validateEndpoint("HostAddress", mc.HostAddress); err != nil {
+		return err
+	}
+	if err := mc.S3FSConfig.validate(true); err != nil {
+		return err
+	}
+	if _, ok := mc.Credentials.(S3FS_Static); !ok {
+		return configError("Credentials", "Minio stores require static credentials, got %T", mc.Credentials)
+	}
+	return nil
+}
+
+func (bc *BlockFSConfig) validate() error {
+	if bc.ChunkSize < 0 {
+		return configError("ChunkSize", "%d must not be negative", bc.ChunkSize)
+	}
+	return nil
+}
+
+func (hc *HTTPFSConfig) validate() error {
+	return validateEndpoint("BaseUrl", hc.BaseUrl)
+}
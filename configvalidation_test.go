@@ -0,0 +1,60 @@
+package filesapi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConfigValidation(t *testing.T) {
+	static := S3FS_Static{S3Id: "id", S3Key: "key"}
+	minio := func(host string, creds any) MinioFSConfig {
+		return MinioFSConfig{HostAddress: host, S3FSConfig: S3FSConfig{S3Region: "us-east-1", S3Bucket: "models", Credentials: creds}}
+	}
+
+	valid := []any{
+		S3FSConfig{S3Region: "us-gov-west-1", S3Bucket: "models", Credentials: S3FS_Attached{}},
+		S3FSConfig{S3Region: "us-east-1", S3Bucket: "model.runs-2", Credentials: S3FS_CredentialChain{static, S3FS_Attached{}}},
+		minio("http://localhost:9000", static),
+		BlockFSConfig{},
+	}
+	for _, config := range valid {
+		if _, err := NewFileStore(config); err != nil {
+			t.Fatalf("expected %T to be valid, got %v", config, err)
+		}
+	}
+
+	invalid := []struct {
+		name   string
+		config any
+		field  string
+	}{
+		{"missing bucket", S3FSConfig{S3Region: "us-east-1", Credentials: S3FS_Attached{}}, "S3Bucket"},
+		{"uppercase bucket", S3FSConfig{S3Region: "us-east-1", S3Bucket: "Models", Credentials: S3FS_Attached{}}, "S3Bucket"},
+		{"ip bucket", S3FSConfig{S3Region: "us-east-1", S3Bucket: "192.168.1.10", Credentials: S3FS_Attached{}}, "S3Bucket"},
+		{"bad region", S3FSConfig{S3Region: "east", S3Bucket: "models", Credentials: S3FS_Attached{}}, "S3Region"},
+		{"bad delimiter", S3FSConfig{S3Region: "us-east-1", S3Bucket: "models", Delimiter: "\n", Credentials: S3FS_Attached{}}, "Delimiter"},
+		{"bad max keys", S3FSConfig{S3Region: "us-east-1", S3Bucket: "models", MaxKeys: 5000, Credentials: S3FS_Attached{}}, "MaxKeys"},
+		{"missing credentials", S3FSConfig{S3Region: "us-east-1", S3Bucket: "models"}, "Credentials"},
+		{"incomplete static credentials", S3FSConfig{S3Region: "us-east-1", S3Bucket: "models", Credentials: S3FS_Static{S3Id: "id"}}, "Credentials"},
+		{"bad chain", S3FSConfig{S3Region: "us-east-1", S3Bucket: "models", Credentials: S3FS_CredentialChain{S3FS_Anonymous{}}}, "Credentials[0]"},
+		{"minio scheme", minio("ftp://minio:9000", static), "HostAddress"},
+		{"minio unparsable host", minio("::bad", static), "HostAddress"},
+		{"minio attached credentials", minio("http://minio:9000", S3FS_Attached{}), "Credentials"},
+		{"negative chunk size", BlockFSConfig{ChunkSize: -1}, "ChunkSize"},
+		{"missing base url", HTTPFSConfig{}, "BaseUrl"},
+	}
+	for _, test := range invalid {
+		_, err := NewFileStore(test.config)
+		if !errors.Is(err, ErrInvalidConfig) {
+			t.Fatalf("%s: expected an invalid config error, got %v", test.name, err)
+		}
+		var configErr *ConfigError
+		if !errors.As(err, &configErr) || configErr.Field != test.field {
+			t.Fatalf("%s: expected an error for field %s, got %v", test.name, test.field, err)
+		}
+	}
+
+	if _, err := NewFileStore(struct{}{}); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("expected an unsupported configuration type to be invalid, got %v", err)
+	}
+}
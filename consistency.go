@@ -0,0 +1,66 @@
+package filesapi
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"time"
+)
+
+const (
+	waitInitialBackoff time.Duration = 100 * time.Millisecond
+	waitMaxBackoff     time.Duration = 5 * time.Second
+)
+
+// ErrWaitTimeout is returned when an object does not reach the expected state before the timeout
+var ErrWaitTimeout = errors.New("timed out waiting for object")
+
+// WaitForObject polls with backoff until the object is visible or the timeout elapses.
+// Use it after writes to S3-compatible appliances that are only eventually consistent.
+// Errors other than FileNotFoundError are returned immediately
+func WaitForObject(store FileStore, path PathConfig, timeout time.Duration) (fs.FileInfo, error) {
+	var info fs.FileInfo
+	err := waitFor(path.Path, timeout, func() (bool, error) {
+		var err error
+		info, err = store.GetObjectInfo(path)
+		if errors.As(err, &fileNotFoundError) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+	return info, err
+}
+
+// WaitForDelete polls with backoff until the object is no longer visible or the timeout elapses.
+// Errors other than FileNotFoundError are returned immediately
+func WaitForDelete(store FileStore, path PathConfig, timeout time.Duration) error {
+	return waitFor(path.Path, timeout, func() (bool, error) {
+		_, err := store.GetObjectInfo(path)
+		if errors.As(err, &fileNotFoundError) {
+			return true, nil
+		}
+		return false, err
+	})
+}
+
+// calls check with exponential backoff and jitter until it reports done, fails, or the timeout elapses
+func waitFor(path string, timeout time.Duration, check func() (bool, error)) error {
+	deadline := time.Now().Add(timeout)
+	for attempt := 0; ; attempt++ {
+		done, err := check()
+		if err != nil || done {
+			return err
+		}
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("%w: %s", ErrWaitTimeout, path)
+		}
+		backoff := math.Min(float64(waitInitialBackoff)*math.Pow(2, float64(attempt)), float64(waitMaxBackoff))
+		delay := time.Duration((0.5 + jitter()/2) * backoff)
+		if delay > remaining {
+			delay = remaining
+		}
+		time.Sleep(delay)
+	}
+}
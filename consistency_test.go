@@ -0,0 +1,40 @@
+package filesapi
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitForObject(t *testing.T) {
+	store := &BlockFS{Config: BlockFSConfig{ChunkSize: 100}}
+	path := PathConfig{Path: t.TempDir() + "/eventual.txt"}
+
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		store.PutObject(PutObjectInput{
+			Source: ObjectSource{Data: []byte(testObjectString)},
+			Dest:   path,
+		})
+	}()
+	info, err := WaitForObject(store, path, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info == nil {
+		t.Fatal("expected object info")
+	}
+
+	go func() {
+		time.Sleep(150 * time.Millisecond)
+		store.DeleteObjects(DeleteObjectInput{Paths: PathConfig{Paths: []string{path.Path}}})
+	}()
+	if err = WaitForDelete(store, path, 5*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = WaitForObject(store, path, 200*time.Millisecond)
+	if !errors.Is(err, ErrWaitTimeout) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+}
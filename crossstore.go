@@ -0,0 +1,186 @@
+package filesapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// lifetime of the presigned source urls of PresignedSource copies
+const defaultCrossStoreUrlTTL time.Duration = 15 * time.Minute
+
+// ObjectPresigner is implemented by stores able to presign download urls (i.e. S3FS)
+type ObjectPresigner interface {
+	PresignGetObject(input PresignGetInput) (string, error)
+}
+
+type CrossStoreCopyInput struct {
+
+	//source store and object, using the source account credentials
+	Src     FileStore
+	SrcPath PathConfig
+
+	//destination store and object, using the destination account credentials
+	Dest     FileStore
+	DestPath PathConfig
+
+	//read the source through a presigned url instead of the source client.  The url is
+	//fetched with a plain http client, so the read can run in a process or network that
+	//only the destination credentials are available to.  Requires a Src implementing ObjectPresigner
+	PresignedSource bool
+
+	//lifetime of the presigned source url.  Defaults to defaultCrossStoreUrlTTL.
+	//The url must remain valid until the whole object has been read
+	UrlTTL time.Duration
+
+	//optional http client for presigned reads.  Defaults to http.DefaultClient
+	HTTPClient *http.Client
+
+	//optional Content-Type, Content-Encoding and Cache-Control stored with the copy
+	ContentType     string
+	ContentEncoding string
+	CacheControl    string
+
+	//compare the size of the copy with the source once it completes.  A mismatch returns a *CopyMismatchError
+	Verify bool
+
+	//reports the copied object
+	Progress ProgressFunction
+
+	//optional per call overrides of the destination put
+	Options *CallOptions
+}
+
+// CopyAcrossStores copies an object between stores with different credentials (i.e. ingesting
+// partner agency data from a bucket in another account).  Server side copies would require a
+// single identity able to read the source and write the destination, so the object is read
+// with the source store, or its presigned url, and written with a multipart put by the
+// destination store.  Memory is bounded by the destination upload part size, regardless of
+// object size
+func CopyAcrossStores(input CrossStoreCopyInput) (*FileOperationOutput, error) {
+	if input.Src == nil || input.Dest == nil {
+		return nil, errors.New("cross store copies require a source and destination store")
+	}
+	var reader io.ReadCloser
+	var size int64 = -1
+	var err error
+	if input.PresignedSource {
+		reader, size, err = presignedSource(input)
+	} else {
+		reader, err = input.Src.GetObject(GetObjectInput{Path: input.SrcPath})
+		if err == nil && input.Verify {
+			info, infoErr := input.Src.GetObjectInfo(input.SrcPath)
+			if infoErr != nil {
+				reader.Close()
+				return nil, infoErr
+			}
+			size = info.Size()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	counter := &countingReader{reader: reader}
+	put := PutObjectInput{
+		Source:          ObjectSource{Reader: counter},
+		Dest:            input.DestPath,
+		Mutipart:        true,
+		ContentType:     input.ContentType,
+		ContentEncoding: input.ContentEncoding,
+		CacheControl:    input.CacheControl,
+		Options:         input.Options,
+	}
+	output, err := input.Dest.PutObject(put)
+	if err != nil {
+		return nil, err
+	}
+	if input.Verify {
+		if err = verifyCrossStoreCopy(input, size, counter.n); err != nil {
+			return nil, err
+		}
+	}
+	err = reportProgress(input.Progress, ProgressData{
+		Index: 0,
+		Max:   1,
+		Value: input.DestPath.Path,
+	})
+	return output, err
+}
+
+// opens a presigned url of the source.  The size is the Content-Length of the response, or -1
+func presignedSource(input CrossStoreCopyInput) (io.ReadCloser, int64, error) {
+	presigner, ok := input.Src.(ObjectPresigner)
+	if !ok {
+		return nil, -1, errors.New("presigned cross store copies require a source store implementing ObjectPresigner")
+	}
+	ttl := input.UrlTTL
+	if ttl <= 0 {
+		ttl = defaultCrossStoreUrlTTL
+	}
+	url, err := presigner.PresignGetObject(PresignGetInput{Path: input.SrcPath, Expires: ttl})
+	if err != nil {
+		return nil, -1, err
+	}
+	client := input.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, url, nil)
+	if err != nil {
+		return nil, -1, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, -1, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, resp.ContentLength, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, -1, &FileNotFoundError{input.SrcPath.Path}
+	default:
+		resp.Body.Close()
+		return nil, -1, fmt.Errorf("Unable to read the presigned source %s: %s\n", input.SrcPath.Path, resp.Status)
+	}
+}
+
+// compares the bytes read and the size of the copy with the source size, when it is known
+func verifyCrossStoreCopy(input CrossStoreCopyInput, srcSize int64, read int64) error {
+	if srcSize < 0 {
+		srcSize = read
+	}
+	info, err := input.Dest.GetObjectInfo(input.DestPath)
+	if err != nil {
+		return err
+	}
+	for _, actual := range []int64{read, info.Size()} {
+		if actual != srcSize {
+			return &CopyMismatchError{
+				Src:      input.SrcPath.Path,
+				Dest:     input.DestPath.Path,
+				Property: "size",
+				Expected: strconv.FormatInt(srcSize, 10),
+				Actual:   strconv.FormatInt(actual, 10),
+			}
+		}
+	}
+	return nil
+}
+
+// counts the bytes read from a source
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.reader.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,89 @@
+package filesapi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCopyAcrossStores(t *testing.T) {
+	content := "partner agency data"
+	var credentials []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		credentials = append(credentials, r.Header.Get("Authorization")+r.URL.Query().Get("X-Amz-Credential"))
+		if r.URL.Path != "/bucket/data/a.txt" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Has("attributes") {
+			w.Write([]byte("<GetObjectAttributesResponse><ObjectSize>19</ObjectSize></GetObjectAttributesResponse>"))
+			return
+		}
+		w.Header().Set("Content-Length", "19")
+		if r.Method == http.MethodGet {
+			w.Write([]byte(content))
+		}
+	}))
+	defer server.Close()
+	src, err := NewFileStore(MinioFSConfig{
+		S3FSConfig: S3FSConfig{
+			S3Region:    "us-east-1",
+			S3Bucket:    "bucket",
+			Credentials: S3FS_Static{S3Id: "partner", S3Key: "key"},
+		},
+		HostAddress: server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest, err := NewFileStore(BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+
+	for i, presigned := range []bool{false, true} {
+		credentials = nil
+		destPath := filepath.Join(root, "ingest", []string{"streamed.txt", "presigned.txt"}[i])
+		_, err := CopyAcrossStores(CrossStoreCopyInput{
+			Src:             src,
+			SrcPath:         PathConfig{Path: "/data/a.txt"},
+			Dest:            dest,
+			DestPath:        PathConfig{Path: destPath},
+			PresignedSource: presigned,
+			Verify:          true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := os.ReadFile(destPath)
+		if string(data) != content {
+			t.Fatalf("expected the copied content, got %q", data)
+		}
+		for _, c := range credentials {
+			if !strings.Contains(c, "partner/") {
+				t.Fatalf("expected the source to be read with the source credentials, got %q", c)
+			}
+		}
+	}
+
+	_, err = CopyAcrossStores(CrossStoreCopyInput{
+		Src:             src,
+		SrcPath:         PathConfig{Path: "/data/missing.txt"},
+		Dest:            dest,
+		DestPath:        PathConfig{Path: filepath.Join(root, "missing.txt")},
+		PresignedSource: true,
+	})
+	var fnf *FileNotFoundError
+	if !errors.As(err, &fnf) {
+		t.Fatalf("expected a missing presigned source to be not found, got %v", err)
+	}
+	_, err = CopyAcrossStores(CrossStoreCopyInput{Src: dest, SrcPath: PathConfig{Path: filepath.Join(root, "ingest", "streamed.txt")}, Dest: dest, DestPath: PathConfig{Path: filepath.Join(root, "b.txt")}, PresignedSource: true})
+	if err == nil {
+		t.Fatal("expected presigned copies to require an ObjectPresigner source")
+	}
+}
@@ -0,0 +1,111 @@
+package filesapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// default number of affected paths included in a DeletePlan sample
+const defaultDeletePlanSample int = 10
+
+var ErrDeletePlanChanged = errors.New("objects changed since the delete was planned")
+
+type DeletePlanInput struct {
+	Store FileStore
+
+	//objects or directories to delete
+	Paths []string
+
+	//maximum number of affected paths in the plan sample.  Defaults to defaultDeletePlanSample
+	SampleSize int
+}
+
+// DeletePlan describes what a delete will remove so an application can ask for confirmation
+type DeletePlan struct {
+	Paths   []string `json:"paths"`
+	Objects int64    `json:"objects"`
+	Bytes   int64    `json:"bytes"`
+
+	//the first affected paths, up to SampleSize
+	Sample []string `json:"sample"`
+
+	//opaque token required by ExecuteDelete.  It fingerprints every affected path and size,
+	//so a delete fails if the objects change after they are planned
+	Token string `json:"token"`
+}
+
+// PlanDelete walks the paths that a delete would remove and summarizes them.  Paths holding
+// an object plan that object, other paths plan everything beneath them, as DeleteObjects does
+func PlanDelete(input DeletePlanInput) (*DeletePlan, error) {
+	sampleSize := input.SampleSize
+	if sampleSize <= 0 {
+		sampleSize = defaultDeletePlanSample
+	}
+	plan := &DeletePlan{
+		Paths:  input.Paths,
+		Sample: []string{},
+	}
+	h := sha256.New()
+	for _, p := range input.Paths {
+		fmt.Fprintf(h, "%s\n", p)
+	}
+	add := func(path string, size int64) {
+		plan.Objects++
+		plan.Bytes += size
+		if len(plan.Sample) < sampleSize {
+			plan.Sample = append(plan.Sample, path)
+		}
+		fmt.Fprintf(h, "%s\t%d\n", path, size)
+	}
+	for _, p := range input.Paths {
+		info, err := input.Store.GetObjectInfo(PathConfig{Path: p})
+		if err != nil && !errors.As(err, &fileNotFoundError) {
+			return nil, err
+		}
+		if err == nil && !info.IsDir() {
+			add(p, info.Size())
+			continue
+		}
+		err = WalkEntries(input.Store, WalkInput{Path: PathConfig{Path: p}}, func(entry WalkEntry) error {
+			if !entry.IsDir() {
+				add(entry.Path, entry.Info.Size())
+			}
+			return nil
+		})
+		//missing paths have nothing to delete
+		if err != nil && !errors.As(err, &fileNotFoundError) && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	plan.Token = hex.EncodeToString(h.Sum(nil))
+	return plan, nil
+}
+
+type ExecuteDeleteInput struct {
+	Store FileStore
+	Paths []string
+
+	//token from the DeletePlan the user confirmed
+	Token    string
+	Progress ProgressFunction
+}
+
+// ExecuteDelete deletes the planned paths once the confirmation token is checked against a
+// new plan.  Returns ErrDeletePlanChanged, without deleting anything, when the token is
+// missing or the affected objects have changed since the plan was made
+func ExecuteDelete(input ExecuteDeleteInput) []error {
+	plan, err := PlanDelete(DeletePlanInput{Store: input.Store, Paths: input.Paths})
+	if err != nil {
+		return []error{err}
+	}
+	if input.Token == "" || plan.Token != input.Token {
+		return []error{ErrDeletePlanChanged}
+	}
+	return input.Store.DeleteObjects(DeleteObjectInput{
+		Paths:    PathConfig{Paths: input.Paths},
+		Progress: input.Progress,
+	})
+}
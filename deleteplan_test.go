@@ -0,0 +1,61 @@
+package filesapi
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlanDelete(t *testing.T) {
+	dir := t.TempDir()
+	store := &BlockFS{}
+	files := map[string]string{
+		"runs/a/out.dss": "0123456789",
+		"runs/b/out.dss": "01234",
+		"keep.txt":       "keep",
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths := []string{filepath.Join(dir, "runs"), filepath.Join(dir, "keep.txt")}
+	plan, err := PlanDelete(DeletePlanInput{Store: store, Paths: paths, SampleSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Objects != 3 || plan.Bytes != 19 || len(plan.Sample) != 2 || plan.Token == "" {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+
+	//a changed object invalidates the token
+	if err = os.WriteFile(filepath.Join(dir, "runs/c.txt"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	errs := ExecuteDelete(ExecuteDeleteInput{Store: store, Paths: paths, Token: plan.Token})
+	if len(errs) != 1 || !errors.Is(errs[0], ErrDeletePlanChanged) {
+		t.Fatalf("expected a changed plan error, got %v", errs)
+	}
+	if !FileExists(store, filepath.Join(dir, "keep.txt")) {
+		t.Fatal("expected nothing to be deleted")
+	}
+	if errs = ExecuteDelete(ExecuteDeleteInput{Store: store, Paths: paths}); !errors.Is(firstError(errs), ErrDeletePlanChanged) {
+		t.Fatal("expected a delete without a token to be refused")
+	}
+
+	plan, err = PlanDelete(DeletePlanInput{Store: store, Paths: paths})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = firstError(ExecuteDelete(ExecuteDeleteInput{Store: store, Paths: paths, Token: plan.Token})); err != nil {
+		t.Fatal(err)
+	}
+	if FileExists(store, filepath.Join(dir, "runs")) || FileExists(store, filepath.Join(dir, "keep.txt")) {
+		t.Fatal("expected the planned paths to be deleted")
+	}
+}
@@ -0,0 +1,139 @@
+package filesapi
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const defaultDownloadPartSize int64 = 8 * 1024 * 1024
+
+type DownloadOptions struct {
+
+	//resume a partially written local file with ranged reads rather than starting over.
+	//the local file is discarded if the remote object was modified after the local file was last written,
+	//or if the store does not report when the object was modified (a zero ModTime)
+	Resume bool
+
+	//size in bytes of each ranged read.  Defaults to defaultDownloadPartSize
+	PartSize int64
+
+	//optional hex encoded hash of the object.  When provided the completed
+	//local file is verified against the hash and is removed if it does not match
+	ExpectedHash string
+
+	//algorithm used to compute ExpectedHash.  Defaults to SHA256
+	HashAlgorithm HashAlgorithm
+
+	//optional progress function.  Value is the number of bytes written to the local file
+	Progress ProgressFunction
+}
+
+type DownloadOutput struct {
+
+	//total size of the local file
+	Size int64
+
+	//number of bytes already present in the local file when the download was resumed
+	ResumedFrom int64
+
+	//hex encoded hash of the local file using the requested HashAlgorithm
+	Hash string
+}
+
+// DownloadObject copies an object to a local file using ranged reads.
+// Interrupted downloads can be continued by calling DownloadObject
+// again with Resume enabled.  The final local file size is always checked
+// against the object size, and its hash is checked when ExpectedHash is provided
+func DownloadObject(store FileStore, path PathConfig, localFile string, opts DownloadOptions) (*DownloadOutput, error) {
+	info, err := store.GetObjectInfo(path)
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	partSize := opts.PartSize
+	if partSize <= 0 {
+		partSize = defaultDownloadPartSize
+	}
+	h, err := opts.HashAlgorithm.New()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(localFile), os.ModePerm); err != nil {
+		return nil, err
+	}
+	var offset int64
+	if opts.Resume {
+		if local, err := os.Stat(localFile); err == nil {
+			modified := info.ModTime()
+			if local.Size() <= size && !modified.IsZero() && !modified.After(local.ModTime()) {
+				offset = local.Size()
+			}
+		}
+	}
+	flags := os.O_CREATE | os.O_RDWR
+	if offset == 0 {
+		flags |= os.O_TRUNC
+	}
+	f, err := os.OpenFile(localFile, flags, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	//hash the previously downloaded bytes before appending
+	if _, err = io.CopyN(h, f, offset); err != nil {
+		return nil, fmt.Errorf("Unable to read partial download %s: %s\n", localFile, err)
+	}
+	output := &DownloadOutput{Size: size, ResumedFrom: offset}
+	writer := io.MultiWriter(f, h)
+	parts := int((size - offset + partSize - 1) / partSize)
+	for i := 0; offset < size; i++ {
+		end := offset + partSize - 1
+		if end >= size {
+			end = size - 1
+		}
+		expected := end - offset + 1
+		reader, err := store.GetObject(GetObjectInput{
+			Path:  path,
+			Range: fmt.Sprintf("bytes=%d-%d", offset, end),
+		})
+		if err != nil {
+			return output, err
+		}
+		n, err := io.Copy(writer, reader)
+		reader.Close()
+		offset += n
+		if err != nil {
+			return output, err
+		}
+		if n != expected {
+			return output, fmt.Errorf("Short read downloading %s: expected %d bytes, got %d\n", path.Path, expected, n)
+		}
+		if err = reportProgress(opts.Progress, ProgressData{Index: i, Max: parts, Value: offset}); err != nil {
+			return output, err
+		}
+	}
+
+	if err = f.Sync(); err != nil {
+		return output, err
+	}
+	local, err := f.Stat()
+	if err != nil {
+		return output, err
+	}
+	if local.Size() != size {
+		return output, fmt.Errorf("Downloaded file %s is %d bytes, expected %d\n", localFile, local.Size(), size)
+	}
+	output.Hash = hex.EncodeToString(h.Sum(nil))
+	if opts.ExpectedHash != "" && !strings.EqualFold(output.Hash, opts.ExpectedHash) {
+		f.Close()
+		os.Remove(localFile)
+		return output, &HashMismatchError{Path: localFile, Expected: opts.ExpectedHash, Actual: output.Hash}
+	}
+	return output, nil
+}
@@ -0,0 +1,139 @@
+package filesapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestDownloadObjectResume(t *testing.T) {
+	store := &BlockFS{Config: BlockFSConfig{ChunkSize: 100}}
+	dir := t.TempDir()
+	remote := dir + "/remote/terrain.bin"
+	local := dir + "/local/terrain.bin"
+
+	data := make([]byte, 2500)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	_, err := store.PutObject(PutObjectInput{
+		Source: ObjectSource{Data: data},
+		Dest:   PathConfig{Path: remote},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	expected := hex.EncodeToString(sum[:])
+
+	//simulate an interrupted download
+	if err = os.MkdirAll(dir+"/local", os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(local, data[:1000], 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := DownloadObject(store, PathConfig{Path: remote}, local, DownloadOptions{
+		Resume:       true,
+		PartSize:     700,
+		ExpectedHash: expected,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.ResumedFrom != 1000 {
+		t.Fatalf("expected download to resume from 1000, got %d", output.ResumedFrom)
+	}
+	downloaded, err := os.ReadFile(local)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(downloaded, data) {
+		t.Fatal("downloaded file does not match the remote object")
+	}
+
+	_, err = DownloadObject(store, PathConfig{Path: remote}, local, DownloadOptions{ExpectedHash: "00"})
+	var mismatch *HashMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected a hash mismatch error, got %v", err)
+	}
+	if _, err = os.Stat(local); !os.IsNotExist(err) {
+		t.Fatal("expected the mismatched local file to be removed")
+	}
+}
+
+// reports BlockFS objects with S3 attributes, as S3FS does
+type s3InfoFS struct {
+	*BlockFS
+	lastModified *time.Time
+}
+
+func (s *s3InfoFS) GetObjectInfo(path PathConfig) (fs.FileInfo, error) {
+	info, err := s.BlockFS.GetObjectInfo(path)
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	return &S3AttributesFileInfo{
+		name: info.Name(),
+		GetObjectAttributesOutput: &s3.GetObjectAttributesOutput{
+			ObjectSize:   &size,
+			LastModified: s.lastModified,
+		},
+	}, nil
+}
+
+func TestDownloadObjectResumeS3(t *testing.T) {
+	dir := t.TempDir()
+	remote := dir + "/remote/terrain.bin"
+	local := dir + "/local/terrain.bin"
+	data := bytes.Repeat([]byte("0123456789"), 250)
+	blockFS := &BlockFS{Config: BlockFSConfig{ChunkSize: 100}}
+	if _, err := blockFS.PutObject(PutObjectInput{Source: ObjectSource{Data: data}, Dest: PathConfig{Path: remote}}); err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(dir+"/local", os.ModePerm)
+
+	written := time.Now().Add(-time.Minute)
+	before := written.Add(-time.Hour)
+	after := written.Add(time.Hour)
+	for _, test := range []struct {
+		lastModified *time.Time
+		resumedFrom  int64
+	}{
+		{&before, 1000},
+		{&after, 0},
+		{nil, 0},
+	} {
+		if err := os.WriteFile(local, data[:1000], 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(local, written, written)
+		store := &s3InfoFS{BlockFS: blockFS, lastModified: test.lastModified}
+		info, err := store.GetObjectInfo(PathConfig{Path: remote})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.lastModified == nil && !info.ModTime().IsZero() || test.lastModified != nil && !info.ModTime().Equal(*test.lastModified) {
+			t.Fatalf("unexpected modification time %v", info.ModTime())
+		}
+		output, err := DownloadObject(store, PathConfig{Path: remote}, local, DownloadOptions{Resume: true, PartSize: 300})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if output.ResumedFrom != test.resumedFrom {
+			t.Fatalf("expected the download to resume from %d, got %d", test.resumedFrom, output.ResumedFrom)
+		}
+		if got, _ := os.ReadFile(local); !bytes.Equal(got, data) {
+			t.Fatal("downloaded file does not match the object")
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package filesapi
+
+import (
+	"errors"
+	"os"
+)
+
+const bytesPerGB float64 = 1024 * 1024 * 1024
+
+// TransferPricing holds per request and per GB costs (USD) used to estimate transfers
+type TransferPricing struct {
+
+	//PUT, COPY, POST, and LIST requests per 1000
+	PutPer1000  float64
+	ListPer1000 float64
+
+	//GET and all other requests per 1000
+	GetPer1000 float64
+
+	//data transfer out per GB
+	EgressPerGB float64
+}
+
+// approximate S3 Standard pricing for us-east-1
+var DefaultS3Pricing = TransferPricing{
+	PutPer1000:  0.005,
+	ListPer1000: 0.005,
+	GetPer1000:  0.0004,
+	EgressPerGB: 0.09,
+}
+
+type EstimateInput struct {
+	Src    FileStore
+	Dst    FileStore
+	Prefix PathConfig
+
+	//objects larger than the part size are counted as multipart uploads.
+	//defaults to the 10MB default chunk size
+	PartSize int64
+
+	//include data transfer out costs.  Set when the destination is outside
+	//the source region (i.e. downloads to on-prem storage)
+	Egress bool
+
+	//optional pricing.  Defaults to DefaultS3Pricing
+	Pricing *TransferPricing
+}
+
+type TransferEstimate struct {
+	Objects int64
+	Bytes   int64
+
+	ListRequests int64
+	GetRequests  int64
+	PutRequests  int64
+
+	RequestCost float64
+	EgressCost  float64
+	TotalCost   float64
+}
+
+// EstimateTransfer walks the source prefix and estimates the requests and cost of
+// copying it to the destination.  Copies within a single S3 store are counted as
+// server side copies with no GET requests or egress
+func EstimateTransfer(input EstimateInput) (TransferEstimate, error) {
+	estimate := TransferEstimate{}
+	if input.Src == nil || input.Dst == nil {
+		return estimate, errors.New("transfer estimates require a source and destination store")
+	}
+	partSize := input.PartSize
+	if partSize <= 0 {
+		partSize = defaultChunkSize
+	}
+	pricing := DefaultS3Pricing
+	if input.Pricing != nil {
+		pricing = *input.Pricing
+	}
+	serverSideCopy := input.Src == input.Dst
+
+	err := input.Src.Walk(WalkInput{Path: input.Prefix}, func(path string, file os.FileInfo) error {
+		if file.IsDir() {
+			return nil
+		}
+		estimate.Objects++
+		estimate.Bytes += file.Size()
+		if file.Size() <= partSize {
+			estimate.PutRequests++
+			if !serverSideCopy {
+				estimate.GetRequests++
+			}
+			return nil
+		}
+		//create, upload each part, and complete
+		parts := (file.Size() + partSize - 1) / partSize
+		estimate.PutRequests += parts + 2
+		if !serverSideCopy {
+			estimate.GetRequests += parts
+		}
+		return nil
+	})
+	if err != nil {
+		return estimate, err
+	}
+	estimate.ListRequests = (estimate.Objects + int64(DEFAULTMAXKEYS) - 1) / int64(DEFAULTMAXKEYS)
+	if estimate.ListRequests == 0 {
+		estimate.ListRequests = 1
+	}
+
+	if _, ok := input.Src.(*S3FS); ok || serverSideCopy {
+		estimate.RequestCost += float64(estimate.ListRequests) / 1000 * pricing.ListPer1000
+		estimate.RequestCost += float64(estimate.GetRequests) / 1000 * pricing.GetPer1000
+		if input.Egress && !serverSideCopy {
+			estimate.EgressCost = float64(estimate.Bytes) / bytesPerGB * pricing.EgressPerGB
+		}
+	}
+	if _, ok := input.Dst.(*S3FS); ok {
+		estimate.RequestCost += float64(estimate.PutRequests) / 1000 * pricing.PutPer1000
+	}
+	estimate.TotalCost = estimate.RequestCost + estimate.EgressCost
+	return estimate, nil
+}
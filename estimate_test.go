@@ -0,0 +1,38 @@
+package filesapi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEstimateTransfer(t *testing.T) {
+	fs, err := NewFileStore(BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "small.txt"), make([]byte, 10), 0644)
+	os.WriteFile(filepath.Join(dir, "large.bin"), make([]byte, 25), 0644)
+	dest, _ := NewFileStore(BlockFSConfig{})
+
+	estimate, err := EstimateTransfer(EstimateInput{
+		Src:      fs,
+		Dst:      dest,
+		Prefix:   PathConfig{Path: dir},
+		PartSize: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if estimate.Objects != 2 || estimate.Bytes != 35 {
+		t.Fatalf("expected 2 objects and 35 bytes, got %d and %d", estimate.Objects, estimate.Bytes)
+	}
+	//small: 1 put, 1 get.  large: 3 parts + create + complete, 3 gets
+	if estimate.PutRequests != 6 || estimate.GetRequests != 4 || estimate.ListRequests != 1 {
+		t.Fatalf("unexpected request counts: %+v", estimate)
+	}
+	if estimate.TotalCost != 0 {
+		t.Fatalf("expected no cost between block stores, got %f", estimate.TotalCost)
+	}
+}
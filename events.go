@@ -0,0 +1,202 @@
+package filesapi
+
+import (
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	ObjectCreated EventType = "ObjectCreated"
+	ObjectDeleted EventType = "ObjectDeleted"
+	ObjectCopied  EventType = "ObjectCopied"
+	ObjectMoved   EventType = "ObjectMoved"
+)
+
+type Event struct {
+	Type EventType
+
+	//path of the created, deleted, or copy or move destination object
+	Path string
+
+	//source path for copied and moved objects
+	SrcPath string
+
+	//object size in bytes.  -1 when unknown
+	Size int64
+
+	//optional identity of the caller that made the change
+	Principal string
+
+	//resource name of the store that changed
+	Store string
+
+	Time time.Time
+}
+
+type EventHandler func(e Event)
+
+type subscription struct {
+	handler EventHandler
+	types   map[EventType]bool
+}
+
+// EventBus is a lightweight in process publisher for store change events.
+// Handlers are called synchronously in the publishing goroutine, so long running
+// handlers should hand work off to their own goroutine.
+// EventBus is safe for concurrent use
+type EventBus struct {
+	mu            sync.RWMutex
+	next          int
+	subscriptions map[int]subscription
+
+	//optional time source for event timestamps
+	Clock Clock
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscriptions: map[int]subscription{},
+	}
+}
+
+// Subscribe registers a handler for the given event types, or for all
+// events if no types are provided.  The returned function unsubscribes the handler
+func (eb *EventBus) Subscribe(handler EventHandler, types ...EventType) func() {
+	sub := subscription{handler: handler}
+	if len(types) > 0 {
+		sub.types = map[EventType]bool{}
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+	eb.mu.Lock()
+	id := eb.next
+	eb.next++
+	eb.subscriptions[id] = sub
+	eb.mu.Unlock()
+	return func() {
+		eb.mu.Lock()
+		delete(eb.subscriptions, id)
+		eb.mu.Unlock()
+	}
+}
+
+func (eb *EventBus) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = eb.Clock.Now()
+	}
+	eb.mu.RLock()
+	handlers := make([]EventHandler, 0, len(eb.subscriptions))
+	for _, sub := range eb.subscriptions {
+		if sub.types == nil || sub.types[e.Type] {
+			handlers = append(handlers, sub.handler)
+		}
+	}
+	eb.mu.RUnlock()
+	for _, handler := range handlers {
+		handler(e)
+	}
+}
+
+// EventFS is a FileStore decorator that publishes change events
+// for successful writes, copies, and deletes to an EventBus
+type EventFS struct {
+	FileStore
+	Bus *EventBus
+
+	//optional identity attached to published events
+	Principal string
+}
+
+func NewEventFS(store FileStore, bus *EventBus) *EventFS {
+	return &EventFS{
+		FileStore: store,
+		Bus:       bus,
+	}
+}
+
+// WithPrincipal returns a copy of the store that attaches principal to its events.
+// Useful for tagging events with the user of an individual request
+func (efs *EventFS) WithPrincipal(principal string) *EventFS {
+	return &EventFS{
+		FileStore: efs.FileStore,
+		Bus:       efs.Bus,
+		Principal: principal,
+	}
+}
+
+func (efs *EventFS) PutObject(input PutObjectInput) (*FileOperationOutput, error) {
+	output, err := efs.FileStore.PutObject(input)
+	if err == nil {
+		size := int64(-1)
+		if input.Source.ContentLength != nil {
+			size = *input.Source.ContentLength
+		} else if input.Source.Data != nil {
+			size = int64(len(input.Source.Data))
+		}
+		efs.publish(ObjectCreated, input.Dest.Path, "", size)
+	}
+	return output, err
+}
+
+func (efs *EventFS) CompleteObjectUpload(u CompletedObjectUploadConfig) error {
+	err := efs.FileStore.CompleteObjectUpload(u)
+	if err == nil {
+		efs.publish(ObjectCreated, u.ObjectPath, "", efs.size(u.ObjectPath))
+	}
+	return err
+}
+
+func (efs *EventFS) CopyObject(input CopyObjectInput) error {
+	err := efs.FileStore.CopyObject(input)
+	if err == nil {
+		srcs := input.Src.All()
+		for i, dest := range input.Dest.All() {
+			efs.publish(ObjectCopied, dest, srcs[i], efs.size(dest))
+		}
+	}
+	return err
+}
+
+func (efs *EventFS) MoveObject(input MoveObjectInput) error {
+	err := efs.FileStore.MoveObject(input)
+	if err == nil {
+		srcs := input.Src.All()
+		for i, dest := range input.Dest.All() {
+			efs.publish(ObjectMoved, dest, srcs[i], efs.size(dest))
+		}
+	}
+	return err
+}
+
+// publishes a delete event for each requested path when the delete succeeds
+func (efs *EventFS) DeleteObjects(input DeleteObjectInput) []error {
+	errs := efs.FileStore.DeleteObjects(input)
+	if firstError(errs) == nil {
+		for _, p := range input.Paths.All() {
+			efs.publish(ObjectDeleted, p, "", -1)
+		}
+	}
+	return errs
+}
+
+func (efs *EventFS) publish(eventType EventType, path string, srcPath string, size int64) {
+	efs.Bus.Publish(Event{
+		Type:      eventType,
+		Path:      path,
+		SrcPath:   srcPath,
+		Size:      size,
+		Principal: efs.Principal,
+		Store:     efs.ResourceName(),
+	})
+}
+
+func (efs *EventFS) size(path string) int64 {
+	info, err := efs.FileStore.GetObjectInfo(PathConfig{Path: path})
+	if err != nil {
+		return -1
+	}
+	return info.Size()
+}
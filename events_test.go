@@ -0,0 +1,46 @@
+package filesapi
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestEventFS(t *testing.T) {
+	store, err := NewFileStore(BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := NewEventBus()
+	created := []Event{}
+	all := 0
+	bus.Subscribe(func(e Event) {
+		created = append(created, e)
+	}, ObjectCreated)
+	unsubscribe := bus.Subscribe(func(e Event) {
+		all++
+	})
+
+	dir := t.TempDir()
+	efs := NewEventFS(store, bus).WithPrincipal("tester")
+	src := filepath.Join(dir, "src.txt")
+	_, err = efs.PutObject(PutObjectInput{
+		Source: ObjectSource{Data: []byte(testObjectString)},
+		Dest:   PathConfig{Path: src},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = efs.CopyObject(CopyObjectInput{Src: PathConfig{Path: src}, Dest: PathConfig{Path: filepath.Join(dir, "dest.txt")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsubscribe()
+	efs.DeleteObjects(DeleteObjectInput{Paths: PathConfig{Paths: []string{src}}})
+
+	if len(created) != 1 || created[0].Path != src || created[0].Size != int64(len(testObjectString)) || created[0].Principal != "tester" {
+		t.Fatalf("unexpected created events: %v", created)
+	}
+	if all != 2 {
+		t.Fatalf("expected 2 events before unsubscribing, got %d", all)
+	}
+}
@@ -0,0 +1,194 @@
+package filesapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"syscall"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+var errStopScan = errors.New("directory scan stopped")
+
+// ExistenceChecker is implemented by stores that check for objects directly (i.e. with an
+// S3 HEAD request) rather than by decoding the FileNotFoundError of GetObjectInfo.
+// Exists has the semantics of ObjectExists, which uses it when the store implements it
+type ExistenceChecker interface {
+	Exists(path PathConfig) (bool, error)
+}
+
+// Exists reports whether a file exists at path.  Directories are not objects
+func (b *BlockFS) Exists(path PathConfig) (bool, error) {
+	info, err := os.Stat(path.Path)
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, syscall.ENOTDIR) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !info.IsDir(), nil
+}
+
+// Exists reports whether an object exists at the key with a HEAD request.  Folder markers are objects
+func (s3fs *S3FS) Exists(path PathConfig) (bool, error) {
+	client, err := s3fs.client()
+	if err != nil {
+		return false, err
+	}
+	key := strings.TrimPrefix(path.Path, "/")
+	_, err = client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+		Bucket: &s3fs.config.S3Bucket,
+		Key:    &key,
+	})
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) || errors.As(err, &noSuchKey) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// FileExists reports whether anything exists at path.  Errors other than not found
+// are reported as existing.  Use ObjectExists to handle errors separately
+func FileExists(fs FileStore, path string) bool {
+	_, err := fs.GetObjectInfo(PathConfig{Path: path})
+	return !errors.As(err, &fileNotFoundError)
+}
+
+// ObjectExists reports whether a file or object exists at path.
+// Directories are not objects.  The error is only set when the store could not be checked
+func ObjectExists(store FileStore, path string) (bool, error) {
+	if checker, ok := store.(ExistenceChecker); ok {
+		return checker.Exists(PathConfig{Path: path})
+	}
+	info, err := store.GetObjectInfo(PathConfig{Path: path})
+	if errors.As(err, &fileNotFoundError) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !info.IsDir(), nil
+}
+
+// DirExists reports whether a directory exists at path.  Object store prefixes exist
+// when any key, including a folder marker, is found beneath them.  Only the first
+// listing page is read.  The error is only set when the store could not be checked
+func DirExists(store FileStore, path string) (bool, error) {
+	exists, _, err := scanDir(store, path)
+	return exists, err
+}
+
+// IsEmptyDir reports whether path is a directory with nothing beneath it.  On object
+// stores a prefix holding only its folder marker is empty.  Missing directories are
+// not empty directories.  The error is only set when the store could not be checked
+func IsEmptyDir(store FileStore, path string) (bool, error) {
+	exists, empty, err := scanDir(store, path)
+	return exists && empty, err
+}
+
+// EnsureDir creates a directory if it does not exist.  Block file stores create the
+// directory and its parents.  Object stores write a folder marker (a key ending in /)
+// so the empty prefix is visible to DirExists and directory listings
+func EnsureDir(store FileStore, path string) error {
+	exists, err := DirExists(store, path)
+	if err != nil || exists {
+		return err
+	}
+	_, err = store.PutObject(PutObjectInput{
+		//empty byte slice sources create directories in block file stores
+		Source: ObjectSource{Data: []byte{}},
+		Dest:   PathConfig{Path: strings.TrimSuffix(path, "/") + "/"},
+	})
+	return err
+}
+
+// ListFiles returns the store paths, with a leading slash, of the objects beneath a directory.
+// Directories and folder markers are left out
+func ListFiles(store FileStore, dir string) ([]string, error) {
+	files := []string{}
+	dir = strings.TrimSuffix(dir, "/") + "/"
+	err := store.Walk(WalkInput{Path: PathConfig{Path: dir}}, func(p string, info os.FileInfo) error {
+		if !info.IsDir() && !strings.HasSuffix(p, "/") {
+			files = append(files, "/"+strings.TrimPrefix(p, "/"))
+		}
+		return nil
+	})
+	return files, err
+}
+
+// RemoveDir deletes every object beneath a directory, then the directory itself in block
+// file stores or its folder marker in object stores.  A missing directory or folder marker
+// is not an error.  The objects are deleted with a single DeleteObjects call, which S3 stores
+// send in batches
+func RemoveDir(store FileStore, dir string) error {
+	dir = strings.TrimSuffix(dir, "/")
+	files, err := ListFiles(store, dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(files) > 0 {
+		if err = firstError(store.DeleteObjects(DeleteObjectInput{Paths: PathConfig{Paths: files}})); err != nil {
+			return err
+		}
+	}
+	err = firstError(store.DeleteObjects(DeleteObjectInput{Paths: PathConfig{Path: dir + "/"}}))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// EnsurePath creates the parent directory of an object path if it does not exist, for
+// writers that expect it (i.e. tools writing through a mounted store)
+func EnsurePath(store FileStore, objectPath string) error {
+	return EnsureDir(store, path.Dir(objectPath))
+}
+
+// Touch creates an empty object at path if no object exists there (i.e. _SUCCESS and lock
+// markers).  Existing objects are left unchanged, including their modification time.
+// The put is conditional, so concurrent touches create the object once
+func Touch(store FileStore, path string) error {
+	var empty int64
+	_, err := store.PutObject(PutObjectInput{
+		//a ReaderAt source, since empty byte slice sources create directories in block file stores
+		Source:      ObjectSource{ReaderAt: bytes.NewReader(nil), ContentLength: &empty},
+		Dest:        PathConfig{Path: path},
+		IfNoneMatch: true,
+	})
+	if errors.Is(err, ErrObjectExists) {
+		return nil
+	}
+	return err
+}
+
+// walks a directory until the first entry beneath it
+func scanDir(store FileStore, path string) (exists bool, empty bool, err error) {
+	empty = true
+	err = WalkEntries(store, WalkInput{Path: PathConfig{Path: strings.TrimSuffix(path, "/") + "/"}}, func(entry WalkEntry) error {
+		if entry.RelativePath == "" {
+			//the directory itself in block file stores, or its folder marker in object stores
+			exists = true
+			return nil
+		}
+		exists = true
+		empty = false
+		return errStopScan
+	})
+	switch {
+	case errors.Is(err, errStopScan):
+		err = nil
+	case errors.Is(err, fs.ErrNotExist), errors.Is(err, syscall.ENOTDIR):
+		//missing block file directories, or files in place of them
+		err = nil
+	}
+	return exists, empty, err
+}
@@ -0,0 +1,177 @@
+package filesapi
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExistsBlockFS(t *testing.T) {
+	dir := t.TempDir()
+	store := &BlockFS{}
+	file := filepath.Join(dir, "full", "a.txt")
+	if err := os.MkdirAll(filepath.Join(dir, "empty"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(testObjectString), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path                    string
+		object, exists, isEmpty bool
+	}{
+		{file, true, false, false},
+		{filepath.Join(dir, "full"), false, true, false},
+		{filepath.Join(dir, "empty") + "/", false, true, true},
+		{filepath.Join(dir, "missing"), false, false, false},
+	}
+	for _, test := range tests {
+		object, err := ObjectExists(store, test.path)
+		if err != nil || object != test.object {
+			t.Fatalf("ObjectExists(%s) = %t %v", test.path, object, err)
+		}
+		exists, err := DirExists(store, test.path)
+		if err != nil || exists != test.exists {
+			t.Fatalf("DirExists(%s) = %t %v", test.path, exists, err)
+		}
+		empty, err := IsEmptyDir(store, test.path)
+		if err != nil || empty != test.isEmpty {
+			t.Fatalf("IsEmptyDir(%s) = %t %v", test.path, empty, err)
+		}
+	}
+
+	created := filepath.Join(dir, "created", "nested")
+	if err := EnsureDir(store, created); err != nil {
+		t.Fatal(err)
+	}
+	if empty, err := IsEmptyDir(store, created); err != nil || !empty {
+		t.Fatalf("expected an empty directory to be created: %v", err)
+	}
+	if err := EnsureDir(store, file); err == nil {
+		t.Fatal("expected an error creating a directory in place of a file")
+	}
+
+	marker := filepath.Join(dir, "touched", "_SUCCESS")
+	if err := EnsurePath(store, marker); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := DirExists(store, filepath.Dir(marker)); err != nil || !exists {
+		t.Fatalf("expected the parent directory to be created: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := Touch(store, marker); err != nil {
+			t.Fatal(err)
+		}
+		if info, err := os.Stat(marker); err != nil || info.IsDir() || info.Size() != 0 {
+			t.Fatalf("expected an empty object to be touched: %v", err)
+		}
+	}
+	if err := Touch(store, file); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != testObjectString {
+		t.Fatal("expected touching an existing object to leave it unchanged")
+	}
+}
+
+func TestRemoveDir(t *testing.T) {
+	dir := t.TempDir()
+	store := &BlockFS{}
+	for _, p := range []string{"runs/a.txt", "runs/nested/b.txt", "keep.txt"} {
+		fp := filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(fp), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fp, []byte(testObjectString), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runs := filepath.Join(dir, "runs")
+	files, err := ListFiles(store, runs+"/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{filepath.Join(runs, "a.txt"), filepath.Join(runs, "nested", "b.txt")}
+	if len(files) != 2 || files[0] != expected[0] || files[1] != expected[1] {
+		t.Fatalf("expected the files beneath the directory %v, got %v", expected, files)
+	}
+	if err = RemoveDir(store, runs); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(runs); !os.IsNotExist(err) {
+		t.Fatalf("expected the directory to be removed: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "keep.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err = RemoveDir(store, runs); err != nil {
+		t.Fatalf("expected removing a missing directory to succeed: %v", err)
+	}
+}
+
+func TestExistsS3(t *testing.T) {
+	backend := &deleteServer{keys: map[string]bool{
+		"data/empty/":      true,
+		"data/full/a.txt":  true,
+		"data/fuller.txt":  true,
+		"data/nested/x/y/": true,
+	}}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+	store, err := NewFileStore(MinioFSConfig{
+		S3FSConfig: S3FSConfig{
+			S3Region:    "us-east-1",
+			S3Bucket:    "bucket",
+			Credentials: S3FS_Static{S3Id: "id", S3Key: "key"},
+		},
+		HostAddress: server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path                    string
+		object, exists, isEmpty bool
+	}{
+		{"/data/full/a.txt", true, false, false},
+		{"/data/full", false, true, false},
+		{"/data/empty", false, true, true},
+		{"/data/nested", false, true, false},
+		//prefixes are matched on whole path elements
+		{"/data/ful", false, false, false},
+	}
+	for _, test := range tests {
+		object, err := ObjectExists(store, test.path)
+		if err != nil || object != test.object {
+			t.Fatalf("ObjectExists(%s) = %t %v", test.path, object, err)
+		}
+		exists, err := DirExists(store, test.path)
+		if err != nil || exists != test.exists {
+			t.Fatalf("DirExists(%s) = %t %v", test.path, exists, err)
+		}
+		empty, err := IsEmptyDir(store, test.path)
+		if err != nil || empty != test.isEmpty {
+			t.Fatalf("IsEmptyDir(%s) = %t %v", test.path, empty, err)
+		}
+	}
+	if err = EnsureDir(store, "/data/created"); err != nil {
+		t.Fatal(err)
+	}
+	if !backend.keys["data/created/"] {
+		t.Fatal("expected a folder marker to be written")
+	}
+	if empty, err := IsEmptyDir(store, "/data/created"); err != nil || !empty {
+		t.Fatalf("expected the created prefix to be empty: %v", err)
+	}
+	if err = Touch(store, "/data/created/_SUCCESS"); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := ObjectExists(store, "/data/created/_SUCCESS"); err != nil || !exists {
+		t.Fatalf("expected the touched object to exist: %v", err)
+	}
+	var _ ExistenceChecker = store.(*S3FS)
+}
@@ -1,23 +1,26 @@
 package filesapi
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"io/fs"
+	"net/http"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
 )
 
 type PATHTYPE int
@@ -34,10 +37,36 @@ const (
 
 var defaultChunkSize int64 = 10 * 1024 * 1024
 
+// Clock returns the current time.  A nil Clock uses time.Now
+type Clock func() time.Time
+
+func (c Clock) Now() time.Time {
+	if c == nil {
+		return time.Now()
+	}
+	return c()
+}
+
+// IdGenerator returns a new unique identifier.  A nil IdGenerator uses random UUIDs
+type IdGenerator func() string
+
+func (g IdGenerator) NewId() string {
+	if g == nil {
+		return uuid.New().String()
+	}
+	return g()
+}
+
 type FileNotFoundError struct {
 	path string
 }
 
+// NewFileNotFoundError returns the error reported for missing objects, for stores
+// implemented outside this package (i.e. registered stores and remote clients)
+func NewFileNotFoundError(path string) *FileNotFoundError {
+	return &FileNotFoundError{path}
+}
+
 func (f *FileNotFoundError) Error() string {
 	return fmt.Sprintf("File Not Found: %s\n", f.path)
 }
@@ -45,7 +74,8 @@ func (f *FileNotFoundError) Error() string {
 // Path config a PATH or array of PATHS
 // representing a resource.  Array of PATHS
 // is used to support multi-file resources
-// such as geospatial shape files
+// such as geospatial shape files.
+// GetObjectInfo, CopyObject and DeleteObjects operate on every path (see All)
 type PathConfig struct {
 	Path  string
 	Paths []string
@@ -58,6 +88,20 @@ func (pc PathConfig) IsNil() bool {
 	return false
 }
 
+// All returns Path followed by Paths, skipping empty paths and repeats of Path
+func (pc PathConfig) All() []string {
+	all := make([]string, 0, len(pc.Paths)+1)
+	if pc.Path != "" {
+		all = append(all, pc.Path)
+	}
+	for _, p := range pc.Paths {
+		if p != "" && p != pc.Path {
+			all = append(all, p)
+		}
+	}
+	return all
+}
+
 type FileOperationOutput struct {
 
 	//AWS Etag for S3 results.  MD5 hash for file system operations
@@ -88,6 +132,11 @@ type UploadConfig struct {
 
 	//chunk data
 	Data []byte
+
+	//record SHA256 checksums for each part, so the completed upload can be verified against
+	//PartHashes without reading it back.  Set when initializing the upload and writing each chunk.
+	//S3 uploads initialized with checksums must be completed with PartHashes or ExpectedHash
+	Checksums bool
 }
 
 type CompletedObjectUploadConfig struct {
@@ -100,6 +149,48 @@ type CompletedObjectUploadConfig struct {
 
 	//ETags for uploaded parts
 	ChunkUploadIds []string
+
+	//optional hex encoded hash of the full assembled object.
+	//when provided the completed object is verified against the hash
+	//and is deleted if it does not match
+	ExpectedHash string
+
+	//algorithm used to compute ExpectedHash.  Defaults to SHA256
+	HashAlgorithm HashAlgorithm
+
+	//optional hex encoded SHA256 hashes of each part, in part order.  S3 stores verify the
+	//completed object against the part checksums recorded by S3 for uploads initialized with
+	//Checksums, without reading the object back.  Objects without part checksums, and other
+	//stores, are verified against ExpectedHash
+	PartHashes []string
+}
+
+type HashAlgorithm string
+
+const (
+	MD5    HashAlgorithm = "md5"
+	SHA256 HashAlgorithm = "sha256"
+)
+
+func (ha HashAlgorithm) New() (hash.Hash, error) {
+	switch ha {
+	case MD5:
+		return md5.New(), nil
+	case SHA256, "":
+		return sha256.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported hash algorithm: %s", ha)
+	}
+}
+
+type HashMismatchError struct {
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (h *HashMismatchError) Error() string {
+	return fmt.Sprintf("Hash mismatch for %s: expected %s, got %s\n", h.Path, h.Expected, h.Actual)
 }
 
 type UploadResult struct {
@@ -108,8 +199,27 @@ type UploadResult struct {
 	IsComplete bool   `json:"isComplete"`
 }
 
+type UploadPart struct {
+	ChunkId int32  `json:"chunkId"`
+	Size    int64  `json:"size"`
+	ETag    string `json:"etag"`
+
+	//hex encoded SHA256 of the part, when recorded by the store
+	Checksum string `json:"checksum,omitempty"`
+}
+
+// FileVisitFunction receives the path as reported by each backend.
+// Use WalkEntries for store relative paths and depth
 type FileVisitFunction func(path string, file os.FileInfo) error
-type ProgressFunction func(pd ProgressData)
+
+// ProgressFunction is called by long running operations (Walk, CopyObject, DeleteObjects)
+// as they advance.  Returning a non-nil error aborts the operation and the error
+// is returned to the caller.
+type ProgressFunction func(pd ProgressData) error
+
+// ErrOperationAborted is returned by ContextProgress when the context
+// is done before the operation completes
+var ErrOperationAborted = errors.New("operation aborted")
 
 type ProgressData struct {
 	Index int
@@ -117,6 +227,38 @@ type ProgressData struct {
 	Value any
 }
 
+// CallOptions override store settings for a single call.  A nil CallOptions uses the store settings
+type CallOptions struct {
+
+	//timeout for the call.  For GetObject the timeout also covers reading the returned body.
+	//Honored by the S3 GetObject, PutObject, ListDir, CopyObject, MoveObject and DeleteObjects
+	//calls.  GetObjectInfo and Walk take no call options and are not bounded
+	Timeout time.Duration
+
+	//retry policy for the call.  Replaces the Policy of a RetryFS
+	RetryPolicy *RetryPolicy
+
+	//S3 storage class for puts (i.e. STANDARD_IA, GLACIER_IR).  Ignored by block file stores
+	StorageClass string
+
+	//user metadata stored with puts.  Ignored by block file stores
+	Metadata map[string]string
+
+	//object tags stored with puts.  Ignored by block file stores
+	Tags map[string]string
+
+	//allow the call to delete or overwrite objects beneath the prefixes of a ProtectedFS
+	OverrideProtection bool
+}
+
+// returns the context for a call.  The cancel function must always be called
+func (co *CallOptions) context() (context.Context, context.CancelFunc) {
+	if co == nil || co.Timeout <= 0 {
+		return context.WithCancel(context.TODO())
+	}
+	return context.WithTimeout(context.TODO(), co.Timeout)
+}
+
 type GetObjectInput struct {
 
 	//Path to resource
@@ -126,6 +268,14 @@ type GetObjectInput struct {
 	// https://www.rfc-editor.org/rfc/rfc9110.html#name-range
 	//Note: Does not support multiple ranges in a single request
 	Range string
+
+	//transparently gunzip objects stored with a gzip content encoding.
+	//S3 objects are identified by their ContentEncoding, block files by the gzip header.
+	//Ignored for range requests
+	Decompress bool
+
+	//optional per call overrides
+	Options *CallOptions
 }
 
 type PutObjectInput struct {
@@ -133,6 +283,46 @@ type PutObjectInput struct {
 	Dest     PathConfig
 	Mutipart bool
 	PartSize int
+
+	//optional Content-Encoding stored with the object (i.e. "gzip").
+	//the source must already be encoded.  Ignored by block file stores
+	ContentEncoding string
+
+	//optional Content-Type and Cache-Control stored with the object.  Ignored by block file stores
+	ContentType  string
+	CacheControl string
+
+	//only write the object if it does not already exist (If-None-Match: *).
+	//returns an error wrapping ErrObjectExists when it does.  Not supported for multipart puts
+	IfNoneMatch bool
+
+	//optional per call overrides
+	Options *CallOptions
+}
+
+// ErrObjectExists is returned by conditional puts when the destination already exists
+var ErrObjectExists = errors.New("object already exists")
+
+const gzipEncoding string = "gzip"
+
+// gzipReadCloser closes both the gzip reader and the underlying object reader
+type gzipReadCloser struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (g *gzipReadCloser) Close() error {
+	g.Reader.Close()
+	return g.body.Close()
+}
+
+func newGzipReadCloser(body io.ReadCloser) (io.ReadCloser, error) {
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	return &gzipReadCloser{gz, body}, nil
 }
 
 type Range struct {
@@ -146,7 +336,7 @@ type ObjectSource struct {
 	//optional content length.  Will be determined automatically for byte slice sources (i.e. Data)
 	ContentLength *int64
 
-	//One of the next three sources must be provided
+	//One of the next five sources must be provided
 	//an existing io.ReadCloser
 	Reader io.Reader
 
@@ -155,6 +345,13 @@ type ObjectSource struct {
 
 	//a file path to a resource
 	Filepath PathConfig
+
+	//a factory function returning a new reader positioned at the start of the source.
+	//it is called again for each retry of a failed write
+	Open func() (io.ReadCloser, error)
+
+	//a random access source.  Requires ContentLength
+	ReaderAt io.ReaderAt
 }
 
 func (obs *ObjectSource) GetReader() (io.Reader, error) {
@@ -165,6 +362,15 @@ func (obs *ObjectSource) GetReader() (io.Reader, error) {
 		}
 		return obs.Reader, nil
 	}
+	if obs.Open != nil {
+		return obs.Open()
+	}
+	if obs.ReaderAt != nil {
+		if obs.ContentLength == nil {
+			return nil, errors.New("invalid objectsource configuration: ReaderAt sources require a ContentLength")
+		}
+		return io.NewSectionReader(obs.ReaderAt, 0, *obs.ContentLength), nil
+	}
 	if obs.Filepath.Path != "" {
 		return os.Open(obs.Filepath.Path)
 	}
@@ -176,20 +382,122 @@ func (obs *ObjectSource) GetReader() (io.Reader, error) {
 	return nil, errors.New("invalid objectsource configuration")
 }
 
+// Replayable reports whether the source can be read more than once,
+// which is required to safely retry a failed write.
+// Every source other than a plain Reader is replayable
+func (obs *ObjectSource) Replayable() bool {
+	if obs.Reader != nil {
+		return false
+	}
+	return obs.Open != nil || obs.ReaderAt != nil || obs.Data != nil || obs.Filepath.Path != ""
+}
+
+// closes a reader returned by GetReader if it was opened by the ObjectSource.
+// Caller supplied Readers are left open
+func (obs *ObjectSource) closeReader(reader io.Reader) error {
+	if obs.Reader != nil {
+		return nil
+	}
+	if c, ok := reader.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// ContextProgress wraps a ProgressFunction so that the operation is aborted
+// once ctx is cancelled or times out.  pf is optional.
+func ContextProgress(ctx context.Context, pf ProgressFunction) ProgressFunction {
+	return func(pd ProgressData) error {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("%w: %s", ErrOperationAborted, err)
+		}
+		if pf != nil {
+			return pf(pd)
+		}
+		return nil
+	}
+}
+
+// reports progress if a progress function is provided
+func reportProgress(pf ProgressFunction, pd ProgressData) error {
+	if pf == nil {
+		return nil
+	}
+	return pf(pd)
+}
+
 type DeleteObjectInput struct {
 	Paths    PathConfig
 	Progress ProgressFunction
+
+	//optional per call overrides.  Only the timeout and OverrideProtection apply to deletes
+	Options *CallOptions
 }
 
 type WalkInput struct {
 	Path     PathConfig
 	Progress ProgressFunction
+
+	//optional checkpoint token used to resume an interrupted walk.
+	//set to the last path handed to the FileVisitFunction in a previous walk
+	//and traversal resumes with the object immediately after it.
+	StartAfter string
 }
 
 type CopyObjectInput struct {
-	Src      PathConfig
-	Dest     PathConfig
+	Src  PathConfig
+	Dest PathConfig
+
+	//reports each copied part.  Single request copies report a single part
+	Progress ProgressFunction
+
+	//compare the copy against the source once it completes.  Sizes are always compared,
+	//S3 ETags when the copy preserves them, and block file MD5s.  A mismatch returns a *CopyMismatchError
+	Verify bool
+
+	//optional per call overrides.  Only the timeout and OverrideProtection apply to copies
+	Options *CallOptions
+}
+
+// CopyMismatchError is returned by verified copies when the destination does not match the source
+type CopyMismatchError struct {
+	Src  string
+	Dest string
+
+	//compared property, i.e. size or etag
+	Property string
+	Expected string
+	Actual   string
+}
+
+func (c *CopyMismatchError) Error() string {
+	return fmt.Sprintf("Copy of %s to %s failed verification: expected %s %s, got %s\n", c.Src, c.Dest, c.Property, c.Expected, c.Actual)
+}
+
+type MoveObjectInput struct {
+	Src  PathConfig
+	Dest PathConfig
+
+	//reports each copied part of moves made with a copy.  Renames report a single part
 	Progress ProgressFunction
+
+	//compare moves made with a copy against the source before the source is deleted.
+	//A mismatch returns a *CopyMismatchError and keeps the source
+	Verify bool
+
+	//optional per call overrides.  Only the timeout and OverrideProtection apply to moves
+	Options *CallOptions
+}
+
+// the copy made by stores that move objects with a copy and delete
+func (moi MoveObjectInput) copyInput() CopyObjectInput {
+	return CopyObjectInput{
+		Src:      moi.Src,
+		Dest:     moi.Dest,
+		Progress: moi.Progress,
+		Verify:   moi.Verify,
+		Options:  moi.Options,
+	}
 }
 
 type ListDirInput struct {
@@ -197,8 +505,14 @@ type ListDirInput struct {
 	Page   int
 	Size   int32
 	Filter string
+
+	//optional per call overrides
+	Options *CallOptions
 }
 
+// FileStore is implemented by each storage backend.  All implementations in this
+// package, and the decorators wrapping them, are safe for concurrent use by multiple
+// goroutines.  Operations keep their state on the call stack rather than on the store
 type FileStore interface {
 
 	//requests a slice of resources at a store directory
@@ -226,6 +540,10 @@ type FileStore interface {
 	//copy an object in a filestore
 	CopyObject(input CopyObjectInput) error
 
+	//move (rename) an object in a filestore.
+	//the source is only removed once the destination is written
+	MoveObject(input MoveObjectInput) error
+
 	//initialize a multipart upload sessions
 	InitializeObjectUpload(UploadConfig) (UploadResult, error)
 
@@ -235,6 +553,14 @@ type FileStore interface {
 	//complete a multipart upload session
 	CompleteObjectUpload(CompletedObjectUploadConfig) error
 
+	//aborts a multipart upload session and discards any written chunks.
+	//requires the UploadId and ObjectPath
+	AbortObjectUpload(UploadConfig) error
+
+	//lists the chunks already written to a multipart upload session.
+	//requires the UploadId and ObjectPath
+	ListUploadParts(UploadConfig) ([]UploadPart, error)
+
 	//recursively deletes objects matching the path pattern
 	DeleteObjects(DeleteObjectInput) []error
 
@@ -247,14 +573,15 @@ func NewFileStore(fsconfig any) (FileStore, error) {
 	switch scType := fsconfig.(type) {
 	case BlockFSConfig:
 		config := fsconfig.(BlockFSConfig)
+		if err := config.validate(); err != nil {
+			return nil, err
+		}
 		if config.ChunkSize == 0 {
 			config.ChunkSize = defaultChunkSize
 		}
-		fs := BlockFS{fsconfig.(BlockFSConfig)}
+		fs := BlockFS{Config: config}
 		return &fs, nil
 	case S3FSConfig:
-		var cfg aws.Config
-		var err error
 		maxKeys := DEFAULTMAXKEYS
 		if scType.MaxKeys > 0 {
 			maxKeys = scType.MaxKeys
@@ -263,51 +590,30 @@ func NewFileStore(fsconfig any) (FileStore, error) {
 		if scType.Delimiter != "" {
 			delimiter = scType.Delimiter
 		}
-		loadOptions := []func(*config.LoadOptions) error{}
-		if scType.AwsOptions != nil {
-			loadOptions = append(loadOptions, scType.AwsOptions...)
+		if err := scType.validateAwsOptions(false); err != nil {
+			return nil, err
 		}
-		loadOptions = append(loadOptions, config.WithRegion(scType.S3Region))
-		/////AWS RETRY OPTION
-		/*
-			loadOptions = append(loadOptions, config.WithRetryer(func() aws.Retryer {
-				return retry.AddWithMaxBackoffDelay(retry.NewStandard(), time.Second*5)
-			}))
-		*/
-		////
-		switch cred := scType.Credentials.(type) {
-		case S3FS_Static:
-			loadOptions = append(loadOptions, config.WithCredentialsProvider(
-				credentials.NewStaticCredentialsProvider(cred.S3Id, cred.S3Key, ""),
-			))
-		case S3FS_Attached:
-			//if attached credentials are used and cred.Profile=="", then the AWS default credential chain is invoked
-			//otherwise add the profile.
-			if cred.Profile != "" {
-				loadOptions = append(loadOptions, config.WithSharedConfigProfile(cred.Profile))
-			}
-		case S3FS_Role:
-			return nil, errors.New("Assumed rules are not supported")
-
-		default:
-			return nil, errors.New("Invalid S3 Credentials")
+		if err := scType.validateCompliance(""); err != nil {
+			return nil, err
 		}
-
-		cfg, err = config.LoadDefaultConfig(
-			context.TODO(),
-			loadOptions...,
-		)
-
-		if err != nil {
+		if err := scType.validateKMSKeys(); err != nil {
+			return nil, err
+		}
+		if err := scType.validate(false); err != nil {
 			return nil, err
 		}
-
-		s3Client := s3.NewFromConfig(cfg)
 		fs := S3FS{
-			s3client:  s3Client,
+			conn:      &s3Connection{connect: scType.newClient},
 			config:    &scType,
 			delimiter: delimiter,
 			maxKeys:   maxKeys,
+			lister:    newListThrottle(scType.ListConcurrency),
+			partCopy:  &partCopySupport{},
+		}
+		if !scType.LazyConnect {
+			if err := fs.Connect(); err != nil {
+				return nil, err
+			}
 		}
 		return &fs, nil
 
@@ -320,49 +626,49 @@ func NewFileStore(fsconfig any) (FileStore, error) {
 		if scType.Delimiter != "" {
 			delimiter = scType.Delimiter
 		}
-		loadOptions := []func(*config.LoadOptions) error{}
-		if scType.AwsOptions != nil {
-			loadOptions = append(loadOptions, scType.AwsOptions...)
+		if err := scType.validateAwsOptions(true); err != nil {
+			return nil, err
 		}
-		loadOptions = append(loadOptions, config.WithRegion(scType.S3Region))
-
-		resolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...any) (aws.Endpoint, error) {
-			return aws.Endpoint{
-				PartitionID:       "aws",
-				URL:               scType.HostAddress,
-				SigningRegion:     scType.S3Region,
-				HostnameImmutable: true,
-			}, nil
-		})
-
-		var creds S3FS_Static
-		var ok bool
-		if creds, ok = scType.Credentials.(S3FS_Static); !ok {
-			return nil, errors.New("Minio Configure requires static credentials")
+		if err := scType.validateCompliance(scType.HostAddress); err != nil {
+			return nil, err
 		}
-
-		loadOptions = append(
-			loadOptions, config.WithRegion(scType.S3Region),
-			config.WithEndpointResolverWithOptions(resolver),
-			config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(creds.S3Id, creds.S3Key, "")),
-		)
-
-		cfg, err := config.LoadDefaultConfig(context.Background(), loadOptions...)
-		if err != nil {
+		if err := scType.validateKMSKeys(); err != nil {
+			return nil, err
+		}
+		if err := scType.validate(); err != nil {
 			return nil, err
 		}
-		s3Client := s3.NewFromConfig(cfg)
 		s3Type := S3FSConfig(scType.S3FSConfig)
 		fs := S3FS{
-			s3client:  s3Client,
+			conn:      &s3Connection{connect: scType.newClient},
 			config:    &s3Type,
 			delimiter: delimiter,
 			maxKeys:   maxKeys,
+			lister:    newListThrottle(s3Type.ListConcurrency),
+			partCopy:  &partCopySupport{},
+		}
+		if !scType.LazyConnect {
+			if err := fs.Connect(); err != nil {
+				return nil, err
+			}
 		}
 		return &fs, nil
 
+	case HTTPFSConfig:
+		if err := scType.validate(); err != nil {
+			return nil, err
+		}
+		client := scType.Client
+		if client == nil {
+			client = http.DefaultClient
+		}
+		return &HTTPFS{config: scType, client: client}, nil
+
+	case SchemeConfig:
+		return newRegisteredFileStore(scType)
+
 	default:
-		return nil, errors.New(fmt.Sprintf("Invalid File System System Type Configuration: %v", scType))
+		return nil, fmt.Errorf("%w: unsupported store configuration type %T", ErrInvalidConfig, scType)
 	}
 }
 
@@ -403,6 +709,47 @@ func buildUrl(urlparts []string, pathType PATHTYPE) string {
 	return sanitizePath(b.String())
 }
 
+// streams an object from the store and compares its hash to the expected hex encoded hash
+func verifyObjectHash(fs FileStore, path string, algorithm HashAlgorithm, expected string) error {
+	h, err := algorithm.New()
+	if err != nil {
+		return err
+	}
+	reader, err := fs.GetObject(GetObjectInput{Path: PathConfig{Path: path}})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	if _, err = io.Copy(h, reader); err != nil {
+		return err
+	}
+	actual := hex.EncodeToString(h.Sum(nil))
+	if !strings.EqualFold(actual, expected) {
+		return &HashMismatchError{Path: path, Expected: expected, Actual: actual}
+	}
+	return nil
+}
+
+// wraps a reader in a gzip reader if the content starts with the gzip header
+func maybeGunzip(body io.ReadCloser) (io.ReadCloser, error) {
+	br := bufio.NewReader(body)
+	magic, err := br.Peek(2)
+	if err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+		return &readCloser{br, body}, nil
+	}
+	return newGzipReadCloser(&readCloser{br, body})
+}
+
+// pairs a reader with the closer of the resource it reads from
+type readCloser struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (rc *readCloser) Close() error {
+	return rc.closer.Close()
+}
+
 func getFileMd5(f *os.File) (string, error) {
 	h := md5.New()
 	if _, err := io.Copy(h, f); err != nil {
@@ -0,0 +1,360 @@
+// Package filestoretest provides a conformance suite for filesapi.FileStore implementations.
+// New backends and decorators run it from their own tests to show they keep the semantics
+// the rest of the module relies on:
+//
+//	func TestConformance(t *testing.T) {
+//		filestoretest.RunFileStoreConformance(t, func(t *testing.T) (filesapi.FileStore, string) {
+//			return NewMyStore(...), "/conformance"
+//		})
+//	}
+//
+// Object sizes and read ranges are drawn from a seeded source, which is logged, so a
+// failure can be reproduced by running the suite with the same seed (see Seed)
+package filestoretest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/usace/filesapi"
+)
+
+// chunk size of the multipart assembly test.  It matches the default ChunkSize of block file
+// stores, which write chunks at their offset, and exceeds the S3 minimum part size
+const chunkSize int = 10 * 1024 * 1024
+
+// Seed of the random object sizes and ranges.  Zero uses the current time
+var Seed int64
+
+// Factory returns a new, empty store for a test, and the root path the test writes beneath
+// (i.e. a t.TempDir() for block file stores, or a unique prefix for S3 stores).
+// Register any cleanup of the store with t.Cleanup
+type Factory func(t *testing.T) (store filesapi.FileStore, root string)
+
+// RunFileStoreConformance runs the conformance tests as subtests of t.  Each subtest
+// calls newStore for its own store
+func RunFileStoreConformance(t *testing.T, newStore Factory) {
+	seed := Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	t.Logf("conformance seed %d", seed)
+	tests := []struct {
+		name string
+		test func(t *testing.T, store filesapi.FileStore, root string, r *rand.Rand)
+	}{
+		{"PutGet", testPutGet},
+		{"RangeReads", testRangeReads},
+		{"NotFound", testNotFound},
+		{"ConditionalPut", testConditionalPut},
+		{"MultipartAssembly", testMultipartAssembly},
+		{"CopyMove", testCopyMove},
+		{"ListDir", testListDir},
+		{"WalkCompleteness", testWalkCompleteness},
+		{"Delete", testDelete},
+	}
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			store, root := newStore(t)
+			tc.test(t, store, root, rand.New(rand.NewSource(seed)))
+		})
+	}
+}
+
+// returns random bytes of a size between 1 and max
+func randomData(r *rand.Rand, max int) []byte {
+	data := make([]byte, 1+r.Intn(max))
+	r.Read(data)
+	return data
+}
+
+func put(t *testing.T, store filesapi.FileStore, p string, data []byte) {
+	t.Helper()
+	_, err := store.PutObject(filesapi.PutObjectInput{
+		Source: filesapi.ObjectSource{Data: data},
+		Dest:   filesapi.PathConfig{Path: p},
+	})
+	if err != nil {
+		t.Fatalf("put %s: %v", p, err)
+	}
+}
+
+// reads an object, or a range of it
+func read(store filesapi.FileStore, p string, byteRange string) ([]byte, error) {
+	reader, err := store.GetObject(filesapi.GetObjectInput{
+		Path:  filesapi.PathConfig{Path: p},
+		Range: byteRange,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+func expectContents(t *testing.T, store filesapi.FileStore, p string, expected []byte) {
+	t.Helper()
+	data, err := read(store, p, "")
+	if err != nil {
+		t.Fatalf("get %s: %v", p, err)
+	}
+	if !bytes.Equal(data, expected) {
+		t.Fatalf("%s has %d bytes that differ from the %d expected", p, len(data), len(expected))
+	}
+}
+
+func expectNotFound(t *testing.T, store filesapi.FileStore, p string) {
+	t.Helper()
+	var fnf *filesapi.FileNotFoundError
+	if _, err := store.GetObjectInfo(filesapi.PathConfig{Path: p}); !errors.As(err, &fnf) {
+		t.Fatalf("expected GetObjectInfo of %s to return a *FileNotFoundError, got %v", p, err)
+	}
+}
+
+// store paths reported by Walk, relative to the root.  S3 keys are reported without a leading slash
+func relativePath(root string, p string) string {
+	rel := strings.TrimPrefix(strings.TrimPrefix(p, "/"), strings.Trim(root, "/"))
+	return strings.TrimPrefix(rel, "/")
+}
+
+func testPutGet(t *testing.T, store filesapi.FileStore, root string, r *rand.Rand) {
+	for i := 0; i < 5; i++ {
+		p := path.Join(root, fmt.Sprintf("putget/object-%d.bin", i))
+		data := randomData(r, 64*1024)
+		put(t, store, p, data)
+		expectContents(t, store, p, data)
+		info, err := store.GetObjectInfo(filesapi.PathConfig{Path: p})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() != int64(len(data)) || info.Name() != path.Base(p) || info.IsDir() {
+			t.Fatalf("unexpected info for %s: name %s, size %d, dir %v", p, info.Name(), info.Size(), info.IsDir())
+		}
+	}
+	//puts replace existing objects
+	p := path.Join(root, "putget/object-0.bin")
+	data := randomData(r, 1024)
+	put(t, store, p, data)
+	expectContents(t, store, p, data)
+}
+
+func testRangeReads(t *testing.T, store filesapi.FileStore, root string, r *rand.Rand) {
+	p := path.Join(root, "ranges/object.bin")
+	data := randomData(r, 256*1024)
+	put(t, store, p, data)
+	ranges := [][2]int{{0, 0}, {0, len(data) - 1}, {len(data) - 1, len(data) - 1}}
+	for i := 0; i < 20; i++ {
+		start := r.Intn(len(data))
+		ranges = append(ranges, [2]int{start, start + r.Intn(len(data)-start)})
+	}
+	for _, rg := range ranges {
+		byteRange := fmt.Sprintf("bytes=%d-%d", rg[0], rg[1])
+		got, err := read(store, p, byteRange)
+		if err != nil {
+			t.Fatalf("get %s %s: %v", p, byteRange, err)
+		}
+		if !bytes.Equal(got, data[rg[0]:rg[1]+1]) {
+			t.Fatalf("range %s of %d bytes returned %d unexpected bytes", byteRange, len(data), len(got))
+		}
+	}
+}
+
+func testNotFound(t *testing.T, store filesapi.FileStore, root string, r *rand.Rand) {
+	p := path.Join(root, "missing/object.bin")
+	expectNotFound(t, store, p)
+	var fnf *filesapi.FileNotFoundError
+	reader, err := store.GetObject(filesapi.GetObjectInput{Path: filesapi.PathConfig{Path: p}})
+	if err == nil {
+		//stores may report a missing object on the first read
+		_, err = io.ReadAll(reader)
+		reader.Close()
+	}
+	if !errors.As(err, &fnf) {
+		t.Fatalf("expected GetObject of a missing object to return a *FileNotFoundError, got %v", err)
+	}
+}
+
+func testConditionalPut(t *testing.T, store filesapi.FileStore, root string, r *rand.Rand) {
+	p := path.Join(root, "conditional/object.bin")
+	data := randomData(r, 1024)
+	conditional := func(data []byte) error {
+		_, err := store.PutObject(filesapi.PutObjectInput{
+			Source:      filesapi.ObjectSource{Data: data},
+			Dest:        filesapi.PathConfig{Path: p},
+			IfNoneMatch: true,
+		})
+		return err
+	}
+	if err := conditional(data); err != nil {
+		t.Fatalf("conditional put of a new object: %v", err)
+	}
+	if err := conditional(randomData(r, 1024)); !errors.Is(err, filesapi.ErrObjectExists) {
+		t.Fatalf("expected a conditional put of an existing object to return ErrObjectExists, got %v", err)
+	}
+	expectContents(t, store, p, data)
+}
+
+// writes the chunks of an upload out of order, and checks they are assembled in chunk order
+func testMultipartAssembly(t *testing.T, store filesapi.FileStore, root string, r *rand.Rand) {
+	p := path.Join(root, "multipart/object.bin")
+	data := make([]byte, chunkSize+1+r.Intn(1024))
+	r.Read(data)
+	upload, err := store.InitializeObjectUpload(filesapi.UploadConfig{ObjectPath: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := [][]byte{data[:chunkSize], data[chunkSize:]}
+	ids := make([]string, len(chunks))
+	for i := len(chunks) - 1; i >= 0; i-- {
+		result, err := store.WriteChunk(filesapi.UploadConfig{
+			ObjectPath: p,
+			ChunkId:    int32(i),
+			UploadId:   upload.ID,
+			Data:       chunks[i],
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = result.ID
+	}
+	err = store.CompleteObjectUpload(filesapi.CompletedObjectUploadConfig{
+		UploadId:       upload.ID,
+		ObjectPath:     p,
+		ChunkUploadIds: ids,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectContents(t, store, p, data)
+
+	//multipart puts of unknown length
+	p = path.Join(root, "multipart/streamed.bin")
+	_, err = store.PutObject(filesapi.PutObjectInput{
+		Source:   filesapi.ObjectSource{Reader: io.MultiReader(bytes.NewReader(data))},
+		Dest:     filesapi.PathConfig{Path: p},
+		Mutipart: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectContents(t, store, p, data)
+}
+
+func testCopyMove(t *testing.T, store filesapi.FileStore, root string, r *rand.Rand) {
+	src := path.Join(root, "copy/src.bin")
+	data := randomData(r, 64*1024)
+	put(t, store, src, data)
+	copied := path.Join(root, "copy/nested/copied.bin")
+	err := store.CopyObject(filesapi.CopyObjectInput{
+		Src:    filesapi.PathConfig{Path: src},
+		Dest:   filesapi.PathConfig{Path: copied},
+		Verify: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectContents(t, store, copied, data)
+	expectContents(t, store, src, data)
+
+	moved := path.Join(root, "copy/moved.bin")
+	err = store.MoveObject(filesapi.MoveObjectInput{
+		Src:  filesapi.PathConfig{Path: src},
+		Dest: filesapi.PathConfig{Path: moved},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectContents(t, store, moved, data)
+	expectNotFound(t, store, src)
+}
+
+func testListDir(t *testing.T, store filesapi.FileStore, root string, r *rand.Rand) {
+	dir := path.Join(root, "list")
+	put(t, store, path.Join(dir, "a.bin"), randomData(r, 1024))
+	put(t, store, path.Join(dir, "b.bin"), randomData(r, 1024))
+	put(t, store, path.Join(dir, "sub/c.bin"), randomData(r, 1024))
+	results, err := store.ListDir(filesapi.ListDirInput{Path: filesapi.PathConfig{Path: dir + "/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, result := range *results {
+		name := strings.TrimSuffix(result.Name, "/")
+		if result.IsDir {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "a.bin,b.bin,sub/" {
+		t.Fatalf("expected the objects and subdirectory of %s to be listed, got %v", dir, names)
+	}
+}
+
+// walks a random tree and checks every object is visited exactly once
+func testWalkCompleteness(t *testing.T, store filesapi.FileStore, root string, r *rand.Rand) {
+	expected := map[string]int64{}
+	dirs := []string{"walk"}
+	for i := 0; i < 40; i++ {
+		dir := dirs[r.Intn(len(dirs))]
+		if r.Intn(3) == 0 {
+			dir = path.Join(dir, fmt.Sprintf("dir-%d", i))
+			dirs = append(dirs, dir)
+		}
+		rel := path.Join(dir, fmt.Sprintf("object-%d.bin", i))
+		data := randomData(r, 512)
+		put(t, store, path.Join(root, rel), data)
+		expected[rel] = int64(len(data))
+	}
+	visited := map[string]int{}
+	err := store.Walk(filesapi.WalkInput{Path: filesapi.PathConfig{Path: path.Join(root, "walk")}}, func(p string, info fs.FileInfo) error {
+		if info.IsDir() {
+			return nil
+		}
+		rel := relativePath(root, p)
+		visited[rel]++
+		if size, ok := expected[rel]; ok && info.Size() != size {
+			return fmt.Errorf("walk reported %d bytes for %s, expected %d", info.Size(), rel, size)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rel := range expected {
+		if visited[rel] != 1 {
+			t.Fatalf("expected the walk to visit %s once, visited %d times", rel, visited[rel])
+		}
+	}
+	if len(visited) != len(expected) {
+		t.Fatalf("expected the walk to visit %d objects, visited %d", len(expected), len(visited))
+	}
+}
+
+func testDelete(t *testing.T, store filesapi.FileStore, root string, r *rand.Rand) {
+	single := path.Join(root, "delete/single.bin")
+	put(t, store, single, randomData(r, 1024))
+	dir := path.Join(root, "delete/dir")
+	for i := 0; i < 3; i++ {
+		put(t, store, path.Join(dir, fmt.Sprintf("nested/object-%d.bin", i)), randomData(r, 1024))
+	}
+	for _, p := range []string{single, dir} {
+		for _, err := range store.DeleteObjects(filesapi.DeleteObjectInput{Paths: filesapi.PathConfig{Path: p}}) {
+			if err != nil {
+				t.Fatalf("delete %s: %v", p, err)
+			}
+		}
+	}
+	expectNotFound(t, store, single)
+	expectNotFound(t, store, path.Join(dir, "nested/object-0.bin"))
+}
@@ -0,0 +1,27 @@
+package filestoretest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/usace/filesapi"
+)
+
+func newBlockFS(t *testing.T) (filesapi.FileStore, string) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, t.TempDir()
+}
+
+func TestBlockFSConformance(t *testing.T) {
+	RunFileStoreConformance(t, newBlockFS)
+}
+
+func TestDecoratorConformance(t *testing.T) {
+	RunFileStoreConformance(t, func(t *testing.T) (filesapi.FileStore, string) {
+		store, root := newBlockFS(t)
+		return filesapi.NewCachedListingFS(filesapi.NewPrefixFS(store, root), time.Minute), "/data"
+	})
+}
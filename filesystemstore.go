@@ -1,7 +1,7 @@
 package filesapi
 
 import (
-	"bytes"
+	"crypto/md5"
 	"errors"
 	"fmt"
 	"io"
@@ -9,10 +9,11 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
-
-	"github.com/google/uuid"
+	"syscall"
 )
 
 var pathError *fs.PathError
@@ -21,13 +22,31 @@ var pathError *fs.PathError
 // as of now I don't actually need any config properties
 type BlockFSConfig struct {
 	ChunkSize int64
+
+	//optional time and upload id sources for deterministic tests and replays
+	Clock       Clock
+	IdGenerator IdGenerator
 }
 
 type BlockFS struct {
 	Config BlockFSConfig
+
+	//open upload sessions keyed by upload id.
+	//session state is kept in memory and does not survive a restart
+	mu      sync.Mutex
+	uploads map[string]*blockUpload
+}
+
+// an open upload session: the file created for the session and the chunks written to it
+type blockUpload struct {
+	path  string
+	parts map[int32]UploadPart
 }
 
 func (b *BlockFS) GetObjectInfo(path PathConfig) (fs.FileInfo, error) {
+	if all := path.All(); len(all) > 1 {
+		return resourceInfo(all, b.GetObjectInfo)
+	}
 	file, err := os.Stat(path.Path)
 	if errors.As(err, &pathError) {
 		err = &FileNotFoundError{path.Path}
@@ -87,17 +106,26 @@ func (b *BlockFS) GetObject(goi GetObjectInput) (io.ReadCloser, error) {
 	reader, err := os.Open(goi.Path.Path)
 	if goi.Range == "" || err != nil {
 		if errors.As(err, &pathError) {
-			err = &FileNotFoundError{goi.Path.Path}
+			return nil, &FileNotFoundError{goi.Path.Path}
+		}
+		if err == nil && goi.Decompress {
+			return maybeGunzip(reader)
 		}
 		return reader, err
 	}
 	readRange, err := parseRange(goi.Range)
 	if err != nil {
+		reader.Close()
 		return nil, err
 	}
-	buf := make([]byte, readRange.End-readRange.Start)
-	_, err = reader.ReadAt(buf, readRange.Start) //@TODO not sure if I should check the # of bytes read and compare to range
-	return io.NopCloser(bytes.NewReader(buf)), nil
+	if readRange.End < readRange.Start {
+		reader.Close()
+		return nil, fmt.Errorf("invalid range: %s", goi.Range)
+	}
+	//rfc9110 ranges are inclusive of the last byte.
+	//ranges are streamed from the file rather than buffered, so large ranges can be served
+	section := io.NewSectionReader(reader, readRange.Start, readRange.End-readRange.Start+1)
+	return &readCloser{section, reader}, nil
 }
 func (b *BlockFS) PutObject(poi PutObjectInput) (*FileOperationOutput, error) {
 	foo := FileOperationOutput{}
@@ -109,21 +137,23 @@ func (b *BlockFS) PutObject(poi PutObjectInput) (*FileOperationOutput, error) {
 	case poi.Source.Data != nil && len(poi.Source.Data) == 0:
 		err = os.MkdirAll(filepath.Dir(poi.Dest.Path), os.ModePerm)
 		return &foo, err
-	case poi.Source.Data != nil:
-		src = bytes.NewReader(poi.Source.Data)
-	case poi.Source.Filepath.Path != "":
-		f, err := os.OpenFile(poi.Source.Filepath.Path, os.O_RDONLY, os.ModePerm)
+	default:
+		src, err = poi.Source.GetReader()
 		if err != nil {
 			return nil, err
 		}
-		defer f.Close()
-		src = f
-	case poi.Source.Reader != nil:
-		src = poi.Source.Reader
+		defer poi.Source.closeReader(src)
 	}
 
 	//opena and write to the destination
-	f, err := os.OpenFile(poi.Dest.Path, os.O_RDWR|os.O_CREATE, os.ModePerm)
+	err = os.MkdirAll(filepath.Dir(poi.Dest.Path), os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+	if poi.IfNoneMatch {
+		return putIfNoneMatch(poi.Dest.Path, src)
+	}
+	f, err := os.OpenFile(poi.Dest.Path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, os.ModePerm)
 	if err != nil {
 		return nil, err
 	}
@@ -143,39 +173,170 @@ func (b *BlockFS) PutObject(poi PutObjectInput) (*FileOperationOutput, error) {
 }
 
 func (b *BlockFS) CopyObject(coi CopyObjectInput) error {
+	if len(coi.Src.All()) > 1 || len(coi.Dest.All()) > 1 {
+		return copyEach(coi, b.CopyObject)
+	}
 	src, err := os.Open(coi.Src.Path)
 	if err != nil {
 		return err
 	}
 	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
 
+	err = os.MkdirAll(filepath.Dir(coi.Dest.Path), os.ModePerm)
+	if err != nil {
+		return err
+	}
 	dest, err := os.Create(coi.Dest.Path)
 	if err != nil {
 		return err
 	}
 	defer dest.Close()
 
-	_, err = io.Copy(dest, src)
-	return err
+	chunkSize := b.Config.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+	parts := int((info.Size() + chunkSize - 1) / chunkSize)
+	h := md5.New()
+	for part := 0; part == 0 || part < parts; part++ {
+		_, err = io.CopyN(io.MultiWriter(dest, h), src, chunkSize)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		err = reportProgress(coi.Progress, ProgressData{
+			Index: part,
+			Max:   parts,
+			Value: coi.Dest.Path,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if !coi.Verify {
+		return nil
+	}
+	if _, err = dest.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	destMd5, err := getFileMd5(dest)
+	if err != nil {
+		return err
+	}
+	if srcMd5 := fmt.Sprintf("%x", h.Sum(nil)); srcMd5 != destMd5 {
+		return &CopyMismatchError{Src: coi.Src.Path, Dest: coi.Dest.Path, Property: "md5", Expected: srcMd5, Actual: destMd5}
+	}
+	return nil
+}
+
+// MoveObject renames the file, creating the destination directory.  Moves across file
+// systems, which cannot be renamed, are copied and the source removed
+func (b *BlockFS) MoveObject(moi MoveObjectInput) error {
+	if len(moi.Src.All()) > 1 || len(moi.Dest.All()) > 1 {
+		return moveEach(moi, b.MoveObject)
+	}
+	if filepath.Clean(moi.Src.Path) == filepath.Clean(moi.Dest.Path) {
+		return nil
+	}
+	if _, err := os.Stat(moi.Src.Path); err != nil {
+		return err
+	}
+	err := os.MkdirAll(filepath.Dir(moi.Dest.Path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(moi.Src.Path, moi.Dest.Path)
+	if errors.Is(err, syscall.EXDEV) {
+		if err = b.CopyObject(moi.copyInput()); err != nil {
+			return err
+		}
+		return os.Remove(moi.Src.Path)
+	}
+	if err != nil {
+		return err
+	}
+	return reportProgress(moi.Progress, ProgressData{
+		Index: 0,
+		Max:   1,
+		Value: moi.Dest.Path,
+	})
 }
 
 func (b *BlockFS) DeleteObjects(doi DeleteObjectInput) []error {
-	var err error
-	for i, p := range doi.Paths.Paths {
+	errs := []error{}
+	paths := doi.Paths.All()
+	for i, p := range paths {
+		var err error
 		if isDir(p) {
 			err = os.RemoveAll(p)
 		} else {
 			err = os.Remove(p)
 		}
-		if doi.Progress != nil {
-			doi.Progress(ProgressData{
-				Index: i,
-				Max:   -1,
-				Value: p,
-			})
+		if err != nil {
+			errs = append(errs, err)
+		}
+		perr := reportProgress(doi.Progress, ProgressData{
+			Index: i,
+			Max:   len(paths),
+			Value: p,
+		})
+		if perr != nil {
+			return append(errs, perr)
 		}
 	}
-	return []error{err}
+	return errs
+}
+
+// writes src to a temporary file and hard links it into place, so the object
+// only becomes visible once it is complete and an existing object is never replaced.
+// file systems without hard links (i.e. exFAT) fall back to an exclusive create
+func putIfNoneMatch(path string, src io.Reader) (*FileOperationOutput, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".filesapi-put-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	h := md5.New()
+	_, err = io.Copy(io.MultiWriter(tmp, h), src)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	os.Chmod(tmp.Name(), 0755)
+
+	err = os.Link(tmp.Name(), path)
+	if err != nil && !errors.Is(err, os.ErrExist) {
+		err = copyExclusive(tmp.Name(), path)
+	}
+	if errors.Is(err, os.ErrExist) {
+		return nil, fmt.Errorf("%w: %s", ErrObjectExists, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &FileOperationOutput{ETag: fmt.Sprintf("%x", h.Sum(nil))}, nil
+}
+
+func copyExclusive(src string, dest string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
 }
 
 func (b *BlockFS) InitializeObjectUpload(u UploadConfig) (UploadResult, error) {
@@ -187,39 +348,141 @@ func (b *BlockFS) InitializeObjectUpload(u UploadConfig) (UploadResult, error) {
 		return result, err
 	}
 	_ = f.Close()
-	result.ID = uuid.New().String()
+	result.ID = b.Config.IdGenerator.NewId()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.uploads == nil {
+		b.uploads = map[string]*blockUpload{}
+	}
+	b.uploads[result.ID] = &blockUpload{path: u.ObjectPath, parts: map[int32]UploadPart{}}
 	return result, nil
 }
 
 func (b *BlockFS) WriteChunk(u UploadConfig) (UploadResult, error) {
 	result := UploadResult{}
-	//var err error
-	mutex := &sync.Mutex{}
-	mutex.Lock()
-	defer mutex.Unlock()
 	f, err := os.OpenFile(u.ObjectPath, os.O_WRONLY|os.O_CREATE, 0644) //@TODO incomplete
 	if err != nil {
 		return result, err
 	}
 	defer f.Close()
 	_, err = f.WriteAt(u.Data, (int64(u.ChunkId) * b.Config.ChunkSize))
+	if err != nil {
+		return result, err
+	}
 	result.WriteSize = len(u.Data)
-	return result, err
+	result.ID = fmt.Sprintf("%x", md5.Sum(u.Data))
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if upload, ok := b.uploads[u.UploadId]; ok {
+		upload.parts[u.ChunkId] = UploadPart{
+			ChunkId: u.ChunkId,
+			Size:    int64(len(u.Data)),
+			ETag:    result.ID,
+		}
+	}
+	return result, nil
+}
+
+// AbortObjectUpload removes the file created for an open upload session.  Unknown or
+// completed upload ids are an error, so a stale abort cannot delete a finished object
+func (b *BlockFS) AbortObjectUpload(u UploadConfig) error {
+	b.mu.Lock()
+	upload, ok := b.uploads[u.UploadId]
+	delete(b.uploads, u.UploadId)
+	b.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("upload %s not found", u.UploadId)
+	}
+	err := os.Remove(upload.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (b *BlockFS) ListUploadParts(u UploadConfig) ([]UploadPart, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	upload, ok := b.uploads[u.UploadId]
+	if !ok {
+		return nil, fmt.Errorf("upload %s not found", u.UploadId)
+	}
+	result := make([]UploadPart, 0, len(upload.parts))
+	for _, part := range upload.parts {
+		result = append(result, part)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ChunkId < result[j].ChunkId
+	})
+	return result, nil
 }
 
 func (b *BlockFS) CompleteObjectUpload(u CompletedObjectUploadConfig) error {
-	//return md5 hash for file
-	return nil
+	b.mu.Lock()
+	delete(b.uploads, u.UploadId)
+	b.mu.Unlock()
+	if u.ExpectedHash == "" {
+		return nil
+	}
+	err := verifyObjectHash(b, u.ObjectPath, u.HashAlgorithm, u.ExpectedHash)
+	var mismatch *HashMismatchError
+	if errors.As(err, &mismatch) {
+		os.Remove(u.ObjectPath)
+	}
+	return err
 }
 
 func (b *BlockFS) Walk(input WalkInput, vistorFunction FileVisitFunction) error {
+	count := 0
 	err := filepath.Walk(input.Path.Path,
 		func(path string, fileinfo os.FileInfo, err error) error {
 			if err != nil {
 				return err
 			}
+			if input.StartAfter != "" {
+				c := walkOrderCompare(path, input.StartAfter)
+				//skip directories that were completely processed before the checkpoint.
+				//a checkpoint directory was visited but its entries were not, so it is descended
+				if c < 0 && fileinfo.IsDir() && !isPathAncestor(path, input.StartAfter) {
+					return filepath.SkipDir
+				}
+				if c <= 0 {
+					return nil
+				}
+			}
 			err = vistorFunction(path, fileinfo)
+			if err != nil {
+				return err
+			}
+			err = reportProgress(input.Progress, ProgressData{
+				Index: count,
+				Max:   -1,
+				Value: fileinfo,
+			})
+			count++
 			return err
 		})
 	return err
 }
+
+// compares two paths in the order filepath.Walk visits them.
+// Walk visits entries of a directory in lexical order, so paths
+// are compared element by element rather than as whole strings
+func walkOrderCompare(a string, b string) int {
+	ap := strings.Split(filepath.Clean(a), string(filepath.Separator))
+	bp := strings.Split(filepath.Clean(b), string(filepath.Separator))
+	for i := 0; i < len(ap) && i < len(bp); i++ {
+		if c := strings.Compare(ap[i], bp[i]); c != 0 {
+			return c
+		}
+	}
+	return len(ap) - len(bp)
+}
+
+func isPathAncestor(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != "." && !strings.HasPrefix(rel, "..")
+}
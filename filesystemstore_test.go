@@ -1,10 +1,16 @@
 package filesapi
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -89,6 +95,38 @@ func TestFssGetObject(t *testing.T) {
 	fmt.Println(buf.String())
 }
 
+func TestFssGetObjectRange(t *testing.T) {
+	fs, err := NewFileStore(BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	object := filepath.Join(t.TempDir(), "range.txt")
+	os.WriteFile(object, []byte("0123456789"), 0644)
+	for _, test := range []struct {
+		input    string
+		expected string
+	}{
+		{"bytes=0-0", "0"},
+		{"bytes=2-5", "2345"},
+		{"bytes=7-9", "789"},
+		{"bytes=7-20", "789"},
+		{"bytes=12-20", ""},
+	} {
+		reader, err := fs.GetObject(GetObjectInput{Path: PathConfig{Path: object}, Range: test.input})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(reader)
+		reader.Close()
+		if string(data) != test.expected {
+			t.Fatalf("expected range %s to read %q, got %q", test.input, test.expected, data)
+		}
+	}
+	if _, err = fs.GetObject(GetObjectInput{Path: PathConfig{Path: object}, Range: "bytes=5-2"}); err == nil {
+		t.Fatal("expected an inverted range to be rejected")
+	}
+}
+
 func TestFssPutObjectByteSlice(t *testing.T) {
 	config := BlockFSConfig{}
 	fs, err := NewFileStore(config)
@@ -174,6 +212,133 @@ func TestFssCopyObject(t *testing.T) {
 	}
 }
 
+func TestFssMoveObject(t *testing.T) {
+	fs, err := NewFileStore(BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	src := filepath.Join(root, "a.txt")
+	dest := filepath.Join(root, "moved", "b.txt")
+	os.WriteFile(src, []byte("HELLO WORLD"), 0644)
+	var progress []ProgressData
+	err = fs.MoveObject(MoveObjectInput{
+		Src:  PathConfig{Path: src},
+		Dest: PathConfig{Path: dest},
+		Progress: func(pd ProgressData) error {
+			progress = append(progress, pd)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Fatal("expected the source to be removed")
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "HELLO WORLD" || len(progress) != 1 {
+		t.Fatalf("unexpected move of %q with progress %v", data, progress)
+	}
+	//moving an object onto itself leaves it in place
+	if err := fs.MoveObject(MoveObjectInput{Src: PathConfig{Path: dest}, Dest: PathConfig{Path: dest}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dest); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.MoveObject(MoveObjectInput{Src: PathConfig{Path: src}, Dest: PathConfig{Path: dest}}); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected a missing source to fail, got %v", err)
+	}
+}
+
+func TestFssCopyObjectProgress(t *testing.T) {
+	fs := &BlockFS{Config: BlockFSConfig{ChunkSize: 10}}
+	dir := t.TempDir()
+	srcpath := PathConfig{Path: dir + "/src.txt"}
+	destpath := PathConfig{Path: dir + "/copies/dest.txt"}
+	data := []byte(strings.Repeat("0123456789", 3) + "tail")
+	if err := os.WriteFile(srcpath.Path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	parts := []ProgressData{}
+	err := fs.CopyObject(CopyObjectInput{
+		Src:  srcpath,
+		Dest: destpath,
+		Progress: func(pd ProgressData) error {
+			parts = append(parts, pd)
+			return nil
+		},
+		Verify: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parts) != 4 || parts[3].Index != 3 || parts[3].Max != 4 {
+		t.Fatalf("expected progress for 4 parts, got %v", parts)
+	}
+	copied, err := os.ReadFile(destpath.Path)
+	if err != nil || !bytes.Equal(copied, data) {
+		t.Fatalf("unexpected copy %q %v", copied, err)
+	}
+
+	//aborting from the progress function stops the copy
+	err = fs.CopyObject(CopyObjectInput{
+		Src:  srcpath,
+		Dest: destpath,
+		Progress: func(pd ProgressData) error {
+			return ErrOperationAborted
+		},
+	})
+	if !errors.Is(err, ErrOperationAborted) {
+		t.Fatalf("expected an aborted copy, got %v", err)
+	}
+}
+
+func TestFssMultiPathResource(t *testing.T) {
+	fs := &BlockFS{}
+	dir := t.TempDir()
+	src := PathConfig{Path: dir + "/levees.shp", Paths: []string{dir + "/levees.shp", dir + "/levees.shx", dir + "/levees.dbf"}}
+	for i, p := range src.All() {
+		if err := os.WriteFile(p, bytes.Repeat([]byte("x"), i+1), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if all := src.All(); len(all) != 3 {
+		t.Fatalf("expected the repeated primary path to be skipped, got %v", all)
+	}
+	info, err := fs.GetObjectInfo(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name() != "levees.shp" || info.Size() != 6 {
+		t.Fatalf("expected the combined resource size, got %s %d", info.Name(), info.Size())
+	}
+
+	dest := PathConfig{Paths: []string{dir + "/copy/levees.shp", dir + "/copy/levees.shx", dir + "/copy/levees.dbf"}}
+	if err = fs.CopyObject(CopyObjectInput{Src: src, Dest: dest}); err != nil {
+		t.Fatal(err)
+	}
+	if info, err = fs.GetObjectInfo(dest); err != nil || info.Size() != 6 {
+		t.Fatalf("expected every file to be copied: %v", err)
+	}
+	err = fs.CopyObject(CopyObjectInput{Src: src, Dest: PathConfig{Path: dir + "/copy/levees.zip"}})
+	if err == nil {
+		t.Fatal("expected an error copying 3 paths to 1")
+	}
+
+	//both Path and Paths are deleted, and every failure is returned
+	errs := fs.DeleteObjects(DeleteObjectInput{Paths: PathConfig{Path: dir + "/copy/levees.shp", Paths: []string{dir + "/copy/levees.shx", dir + "/missing"}}})
+	if len(errs) != 1 || !errors.Is(errs[0], os.ErrNotExist) {
+		t.Fatalf("expected a single missing file error, got %v", errs)
+	}
+	if _, err = fs.GetObjectInfo(PathConfig{Path: dir + "/copy/levees.shp"}); err == nil {
+		t.Fatal("expected Path to be deleted")
+	}
+	if _, err = fs.GetObjectInfo(dest); err == nil {
+		t.Fatal("expected an error for a partially deleted resource")
+	}
+}
+
 func TestFssDeleteObjects(t *testing.T) {
 	config := BlockFSConfig{}
 	fs, err := NewFileStore(config)
@@ -217,6 +382,219 @@ func TestFssWalk(t *testing.T) {
 	}
 }
 
+func TestFssWalkProgressAbort(t *testing.T) {
+	config := BlockFSConfig{}
+	fs, err := NewFileStore(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for i := 0; i < 5; i++ {
+		err = os.WriteFile(fmt.Sprintf("%s/file%d.txt", dir, i), []byte(testObjectString), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	count := 0
+	wi := WalkInput{
+		Path: PathConfig{Path: dir},
+		Progress: ContextProgress(ctx, func(pd ProgressData) error {
+			if pd.Index == 2 {
+				cancel()
+			}
+			return nil
+		}),
+	}
+	err = fs.Walk(wi, func(path string, fileinfo os.FileInfo) error {
+		count++
+		return nil
+	})
+	if !errors.Is(err, ErrOperationAborted) {
+		t.Fatalf("expected an aborted walk, got %v", err)
+	}
+	if count != 4 {
+		t.Fatalf("expected walk to stop after 4 visits, got %d", count)
+	}
+}
+
+func TestFssWalkResume(t *testing.T) {
+	config := BlockFSConfig{}
+	fs, err := NewFileStore(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, p := range []string{"a/1.txt", "a/2.txt", "a.txt", "b/c/3.txt", "b/4.txt"} {
+		fp := filepath.Join(dir, p)
+		if err = os.MkdirAll(filepath.Dir(fp), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(fp, []byte(testObjectString), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	visited := []string{}
+	wi := WalkInput{
+		Path:       PathConfig{Path: dir},
+		StartAfter: filepath.Join(dir, "a.txt"),
+	}
+	err = fs.Walk(wi, func(path string, fileinfo os.FileInfo) error {
+		if !fileinfo.IsDir() {
+			visited = append(visited, strings.TrimPrefix(path, dir))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "[/b/4.txt /b/c/3.txt]"
+	if fmt.Sprint(visited) != expected {
+		t.Fatalf("expected resumed walk to visit %s, got %v", expected, visited)
+	}
+
+	//a directory checkpoint was visited, but none of its entries were
+	visited = []string{}
+	wi.StartAfter = filepath.Join(dir, "a")
+	err = fs.Walk(wi, func(path string, fileinfo os.FileInfo) error {
+		visited = append(visited, strings.TrimPrefix(path, dir))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "[/a/1.txt /a/2.txt /a.txt /b /b/4.txt /b/c /b/c/3.txt]"
+	if fmt.Sprint(visited) != expected {
+		t.Fatalf("expected walk resumed from a directory to visit %s, got %v", expected, visited)
+	}
+}
+
+func TestFssVerifiedChunkedUpload(t *testing.T) {
+	fs, err := NewFileStore(BlockFSConfig{ChunkSize: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest := filepath.Join(t.TempDir(), "upload.txt")
+	data := []byte(testObjectString)
+	sum := sha256.Sum256(data)
+
+	for i, expected := range []string{hex.EncodeToString(sum[:]), "bad"} {
+		upload, err := fs.InitializeObjectUpload(UploadConfig{ObjectPath: dest})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for chunk := 0; chunk*5 < len(data); chunk++ {
+			end := (chunk + 1) * 5
+			if end > len(data) {
+				end = len(data)
+			}
+			_, err = fs.WriteChunk(UploadConfig{
+				ObjectPath: dest,
+				ChunkId:    int32(chunk),
+				UploadId:   upload.ID,
+				Data:       data[chunk*5 : end],
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		parts, err := fs.ListUploadParts(UploadConfig{ObjectPath: dest, UploadId: upload.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(parts) != 3 || parts[2].ChunkId != 2 || parts[2].Size != 1 {
+			t.Fatalf("unexpected upload parts: %v", parts)
+		}
+		err = fs.CompleteObjectUpload(CompletedObjectUploadConfig{
+			UploadId:     upload.ID,
+			ObjectPath:   dest,
+			ExpectedHash: expected,
+		})
+		var mismatch *HashMismatchError
+		switch {
+		case i == 0 && err != nil:
+			t.Fatal(err)
+		case i == 1 && !errors.As(err, &mismatch):
+			t.Fatalf("expected a hash mismatch error, got %v", err)
+		case i == 1 && FileExists(fs, dest):
+			t.Fatal("expected the corrupt upload to be deleted")
+		}
+	}
+}
+
+func TestFssAbortUpload(t *testing.T) {
+	fs, err := NewFileStore(BlockFSConfig{ChunkSize: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	finished := filepath.Join(dir, "finished.txt")
+	os.WriteFile(finished, []byte(testObjectString), 0644)
+
+	//unknown and completed upload ids must not remove anything
+	if err = fs.AbortObjectUpload(UploadConfig{ObjectPath: finished, UploadId: "missing"}); err == nil {
+		t.Fatal("expected aborting an unknown upload to fail")
+	}
+	upload, err := fs.InitializeObjectUpload(UploadConfig{ObjectPath: filepath.Join(dir, "done.txt")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = fs.CompleteObjectUpload(CompletedObjectUploadConfig{UploadId: upload.ID, ObjectPath: filepath.Join(dir, "done.txt")}); err != nil {
+		t.Fatal(err)
+	}
+	if err = fs.AbortObjectUpload(UploadConfig{ObjectPath: finished, UploadId: upload.ID}); err == nil {
+		t.Fatal("expected aborting a completed upload to fail")
+	}
+	if !FileExists(fs, finished) || !FileExists(fs, filepath.Join(dir, "done.txt")) {
+		t.Fatal("expected finished objects to survive stale aborts")
+	}
+
+	//aborts remove the file created for the session, not the path supplied with the abort
+	partial := filepath.Join(dir, "partial.txt")
+	upload, err = fs.InitializeObjectUpload(UploadConfig{ObjectPath: partial})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = fs.AbortObjectUpload(UploadConfig{ObjectPath: finished, UploadId: upload.ID}); err != nil {
+		t.Fatal(err)
+	}
+	if FileExists(fs, partial) || !FileExists(fs, finished) {
+		t.Fatal("expected only the session file to be removed")
+	}
+}
+
+func TestFssGetObjectDecompress(t *testing.T) {
+	fs, err := NewFileStore(BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(testObjectString))
+	gz.Close()
+	os.WriteFile(filepath.Join(dir, "test.json.gz"), buf.Bytes(), 0644)
+	os.WriteFile(filepath.Join(dir, "test.json"), []byte(testObjectString), 0644)
+
+	for _, name := range []string{"test.json.gz", "test.json"} {
+		reader, err := fs.GetObject(GetObjectInput{
+			Path:       PathConfig{Path: filepath.Join(dir, name)},
+			Decompress: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != testObjectString {
+			t.Fatalf("expected %s from %s, got %s", testObjectString, name, data)
+		}
+	}
+}
+
 /*
 //initialize a multipart upload sessions
 	InitializeObjectUpload(UploadConfig) (UploadResult, error)
@@ -0,0 +1,275 @@
+// Package fixtures populates a filesapi.FileStore with a synthetic directory tree, so listings,
+// walks, and syncs can be load tested at realistic scales.
+//
+// Trees are well known: the paths, sizes, and contents of every object are derived from
+// Config.Seed, so the same configuration always produces the same tree and a Manifest of the
+// tree can be computed without writing it (see Plan).  Each directory of the tree holds
+// FilesPerDir objects and Fanout subdirectories, down to Depth levels.  Text objects hold
+// printable lines (.txt), and binary objects poorly compressible bytes (.bin)
+package fixtures
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"path"
+	"sync"
+
+	"github.com/usace/filesapi"
+)
+
+const (
+	defaultDepth        int     = 3
+	defaultFanout       int     = 4
+	defaultFilesPerDir  int     = 10
+	defaultMinSize      int64   = 1024
+	defaultMaxSize      int64   = 1024 * 1024
+	defaultTextFraction float64 = 0.5
+	defaultConcurrency  int     = 8
+	defaultPartSize     int     = 8 * 1024 * 1024
+
+	//length of the lines of text objects, including the newline
+	textLineLength int64 = 64
+)
+
+// SizeDistribution returns the size of the next object from a seeded source
+type SizeDistribution func(r *rand.Rand) int64
+
+// UniformSizes draws sizes uniformly between min and max bytes
+func UniformSizes(min int64, max int64) SizeDistribution {
+	return func(r *rand.Rand) int64 {
+		if max <= min {
+			return min
+		}
+		return min + r.Int63n(max-min+1)
+	}
+}
+
+// LogUniformSizes draws sizes uniformly in log space between min and max bytes, so most objects
+// are small and a few are large, as in real data sets.  min must be at least 1
+func LogUniformSizes(min int64, max int64) SizeDistribution {
+	return func(r *rand.Rand) int64 {
+		if max <= min {
+			return min
+		}
+		lo, hi := math.Log(float64(min)), math.Log(float64(max))
+		return int64(math.Exp(lo + r.Float64()*(hi-lo)))
+	}
+}
+
+// Config describes the generated tree.  Zero values use the package defaults
+type Config struct {
+	Store filesapi.FileStore
+
+	//path the tree is written beneath.  Use a prefix that holds no other data
+	Prefix string
+
+	//levels of subdirectories beneath the prefix, and subdirectories in each directory.
+	//Use a negative depth for a single flat directory
+	Depth  int
+	Fanout int
+
+	//objects in each directory, including the prefix
+	FilesPerDir int
+
+	//object sizes.  Defaults to LogUniformSizes between defaultMinSize and defaultMaxSize
+	Sizes SizeDistribution
+
+	//fraction of the objects written as text, between 0 and 1.  Defaults to
+	//defaultTextFraction.  Use a negative fraction for binary objects only
+	TextFraction float64
+
+	//seed of the tree.  Trees with the same seed and configuration are identical
+	Seed int64
+
+	//concurrent puts
+	Concurrency int
+
+	//objects larger than the part size are written with multipart uploads
+	PartSize int
+
+	//reports each written object
+	Progress filesapi.ProgressFunction
+}
+
+func (c Config) withDefaults() Config {
+	if c.Depth < 0 {
+		c.Depth = 0
+	} else if c.Depth == 0 {
+		c.Depth = defaultDepth
+	}
+	if c.Fanout <= 0 {
+		c.Fanout = defaultFanout
+	}
+	if c.FilesPerDir <= 0 {
+		c.FilesPerDir = defaultFilesPerDir
+	}
+	if c.Sizes == nil {
+		c.Sizes = LogUniformSizes(defaultMinSize, defaultMaxSize)
+	}
+	if c.TextFraction == 0 {
+		c.TextFraction = defaultTextFraction
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = defaultConcurrency
+	}
+	if c.PartSize <= 0 {
+		c.PartSize = defaultPartSize
+	}
+	return c
+}
+
+// Object is a single object of a tree
+type Object struct {
+	Path string
+	Size int64
+	Text bool
+
+	//seed of the object contents
+	seed uint64
+}
+
+// Manifest lists the directories and objects of a tree, in the order they are generated
+type Manifest struct {
+	Dirs    []string
+	Objects []Object
+	Bytes   int64
+}
+
+// Plan returns the manifest of the tree a configuration generates, without writing it
+func Plan(config Config) *Manifest {
+	config = config.withDefaults()
+	r := rand.New(rand.NewSource(config.Seed))
+	manifest := &Manifest{}
+	var plan func(dir string, depth int)
+	plan = func(dir string, depth int) {
+		manifest.Dirs = append(manifest.Dirs, dir)
+		for i := 0; i < config.FilesPerDir; i++ {
+			object := Object{
+				Size: config.Sizes(r),
+				Text: r.Float64() < config.TextFraction,
+				seed: r.Uint64(),
+			}
+			ext := ".bin"
+			if object.Text {
+				ext = ".txt"
+			}
+			object.Path = path.Join(dir, fmt.Sprintf("file-%04d%s", i, ext))
+			manifest.Objects = append(manifest.Objects, object)
+			manifest.Bytes += object.Size
+		}
+		if depth == config.Depth {
+			return
+		}
+		for i := 0; i < config.Fanout; i++ {
+			plan(path.Join(dir, fmt.Sprintf("dir-%03d", i)), depth+1)
+		}
+	}
+	plan(path.Clean("/"+config.Prefix), 0)
+	return manifest
+}
+
+// Generate writes the tree of a configuration to the store and returns its manifest.
+// The first failed put stops the remaining writes and is returned
+func Generate(config Config) (*Manifest, error) {
+	if config.Store == nil {
+		return nil, errors.New("fixtures require a store")
+	}
+	config = config.withDefaults()
+	manifest := Plan(config)
+
+	objects := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	written := 0
+	for w := 0; w < config.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range objects {
+				err := put(config, manifest.Objects[i])
+				mu.Lock()
+				if err == nil && config.Progress != nil {
+					err = config.Progress(filesapi.ProgressData{
+						Index: written,
+						Max:   len(manifest.Objects),
+						Value: manifest.Objects[i].Path,
+					})
+				}
+				written++
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range manifest.Objects {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		objects <- i
+	}
+	close(objects)
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return manifest, nil
+}
+
+func put(config Config, object Object) error {
+	size := object.Size
+	_, err := config.Store.PutObject(filesapi.PutObjectInput{
+		Source: filesapi.ObjectSource{
+			ReaderAt:      Content(object),
+			ContentLength: &size,
+		},
+		Dest:     filesapi.PathConfig{Path: object.Path},
+		Mutipart: size > int64(config.PartSize),
+		PartSize: config.PartSize,
+	})
+	if err != nil {
+		return fmt.Errorf("Unable to write fixture %s: %w", object.Path, err)
+	}
+	return nil
+}
+
+// Content returns the contents of an object, so reads of a generated tree can be verified
+// without holding the objects in memory
+func Content(object Object) io.ReaderAt {
+	return &contentReaderAt{seed: object.seed, text: object.Text}
+}
+
+// an endless deterministic byte source.  Text sources are printable lines
+type contentReaderAt struct {
+	seed uint64
+	text bool
+}
+
+const textAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789 ,"
+
+func (cr *contentReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	for i := range p {
+		pos := off + int64(i)
+		x := cr.seed ^ uint64(pos)
+		x ^= x >> 31
+		x *= 0x9e3779b97f4a7c15
+		x ^= x >> 29
+		switch {
+		case !cr.text:
+			p[i] = byte(x >> 56)
+		case pos%textLineLength == textLineLength-1:
+			p[i] = '\n'
+		default:
+			p[i] = textAlphabet[(x>>32)%uint64(len(textAlphabet))]
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,94 @@
+package fixtures
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/usace/filesapi"
+)
+
+func TestGenerate(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := Config{
+		Store:        store,
+		Prefix:       t.TempDir(),
+		Depth:        2,
+		Fanout:       2,
+		FilesPerDir:  3,
+		Sizes:        UniformSizes(0, 4096),
+		TextFraction: 0.5,
+		Seed:         42,
+		Concurrency:  4,
+	}
+	manifest, err := Generate(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Dirs) != 7 || len(manifest.Objects) != 21 {
+		t.Fatalf("expected 7 directories and 21 objects, got %d and %d", len(manifest.Dirs), len(manifest.Objects))
+	}
+	text := 0
+	for _, object := range manifest.Objects {
+		data, err := os.ReadFile(object.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, _ := io.ReadAll(io.NewSectionReader(Content(object), 0, object.Size))
+		if !bytes.Equal(data, expected) {
+			t.Fatalf("unexpected contents of %s", object.Path)
+		}
+		if object.Text {
+			text++
+			if bytes.IndexFunc(data, func(r rune) bool { return r > 127 }) >= 0 {
+				t.Fatalf("expected printable text in %s", object.Path)
+			}
+		}
+	}
+	if text == 0 || text == len(manifest.Objects) {
+		t.Fatalf("expected a mix of text and binary objects, got %d text objects", text)
+	}
+
+	visited := 0
+	err = store.Walk(filesapi.WalkInput{Path: filesapi.PathConfig{Path: config.Prefix}}, func(path string, file os.FileInfo) error {
+		if !file.IsDir() {
+			visited++
+		}
+		return nil
+	})
+	if err != nil || visited != len(manifest.Objects) {
+		t.Fatalf("expected a walk to visit %d objects, got %d: %v", len(manifest.Objects), visited, err)
+	}
+
+	again := Plan(config)
+	if again.Bytes != manifest.Bytes || again.Objects[20].Path != manifest.Objects[20].Path {
+		t.Fatal("expected trees with the same seed to be identical")
+	}
+	config.Seed = 7
+	if Plan(config).Bytes == manifest.Bytes {
+		t.Fatal("expected trees with different seeds to differ")
+	}
+}
+
+func TestLogUniformSizes(t *testing.T) {
+	manifest := Plan(Config{Depth: -1, FilesPerDir: 1000, Sizes: LogUniformSizes(1, 1024*1024)})
+	small := 0
+	for _, object := range manifest.Objects {
+		if object.Size < 1 || object.Size > 1024*1024 {
+			t.Fatalf("size %d outside the distribution", object.Size)
+		}
+		if object.Size < 1024 {
+			small++
+		}
+	}
+	if small < 400 {
+		t.Fatalf("expected about half the objects to be under 1KB, got %d of 1000", small)
+	}
+	if len(manifest.Dirs) != 1 {
+		t.Fatalf("expected a negative depth to plan a single directory, got %d", len(manifest.Dirs))
+	}
+}
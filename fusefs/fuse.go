@@ -0,0 +1,195 @@
+//go:build linux || freebsd
+
+package fusefs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"syscall"
+
+	"bazil.org/fuse"
+	bazilfs "bazil.org/fuse/fs"
+)
+
+// Mount mounts a store at mountpoint and serves it until the file system is unmounted
+// (see Unmount).  Changes not flushed by then are lost
+func Mount(mountpoint string, config Config) error {
+	m, err := newMount(config)
+	if err != nil {
+		return err
+	}
+	options := []fuse.MountOption{fuse.FSName(config.Store.ResourceName()), fuse.Subtype("filesapi")}
+	if config.ReadOnly {
+		options = append(options, fuse.ReadOnly())
+	}
+	conn, err := fuse.Mount(mountpoint, options...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return bazilfs.Serve(conn, fileSystem{m})
+}
+
+// Unmount unmounts a mounted store, ending Mount
+func Unmount(mountpoint string) error {
+	return fuse.Unmount(mountpoint)
+}
+
+type fileSystem struct {
+	m *mount
+}
+
+func (fsys fileSystem) Root() (bazilfs.Node, error) {
+	return &node{m: fsys.m, path: "/"}, nil
+}
+
+type node struct {
+	m    *mount
+	path string
+}
+
+func (n *node) Attr(ctx context.Context, a *fuse.Attr) error {
+	info, err := n.m.stat(n.path)
+	if err != nil {
+		return fuseError(err)
+	}
+	a.Size = uint64(info.Size())
+	a.Mtime = info.ModTime()
+	if info.IsDir() {
+		a.Mode = os.ModeDir | 0755
+	} else {
+		a.Mode = 0644
+	}
+	if n.m.config.ReadOnly {
+		a.Mode &^= 0222
+	}
+	return nil
+}
+
+func (n *node) Lookup(ctx context.Context, name string) (bazilfs.Node, error) {
+	p := path.Join(n.path, name)
+	if _, err := n.m.stat(p); err != nil {
+		return nil, fuseError(err)
+	}
+	return &node{m: n.m, path: p}, nil
+}
+
+func (n *node) ReadDirAll(ctx context.Context) ([]fuse.Dirent, error) {
+	entries, err := n.m.readDir(n.path)
+	if err != nil {
+		return nil, fuseError(err)
+	}
+	dirents := make([]fuse.Dirent, 0, len(entries))
+	for _, e := range entries {
+		dirent := fuse.Dirent{Name: e.Name(), Type: fuse.DT_File}
+		if e.IsDir() {
+			dirent.Type = fuse.DT_Dir
+		}
+		dirents = append(dirents, dirent)
+	}
+	return dirents, nil
+}
+
+func (n *node) Open(ctx context.Context, req *fuse.OpenRequest, resp *fuse.OpenResponse) (bazilfs.Handle, error) {
+	if req.Dir {
+		return n, nil
+	}
+	h, err := n.m.open(n.path, !req.Flags.IsReadOnly(), req.Flags&fuse.OpenTruncate != 0)
+	if err != nil {
+		return nil, fuseError(err)
+	}
+	return &fileHandle{h}, nil
+}
+
+func (n *node) Create(ctx context.Context, req *fuse.CreateRequest, resp *fuse.CreateResponse) (bazilfs.Node, bazilfs.Handle, error) {
+	p := path.Join(n.path, req.Name)
+	h, err := n.m.create(p, req.Flags&fuse.OpenExclusive != 0)
+	if err != nil {
+		return nil, nil, fuseError(err)
+	}
+	return &node{m: n.m, path: p}, &fileHandle{h}, nil
+}
+
+func (n *node) Mkdir(ctx context.Context, req *fuse.MkdirRequest) (bazilfs.Node, error) {
+	p := path.Join(n.path, req.Name)
+	if err := n.m.mkdir(p); err != nil {
+		return nil, fuseError(err)
+	}
+	return &node{m: n.m, path: p}, nil
+}
+
+func (n *node) Remove(ctx context.Context, req *fuse.RemoveRequest) error {
+	return fuseError(n.m.remove(path.Join(n.path, req.Name), req.Dir))
+}
+
+func (n *node) Rename(ctx context.Context, req *fuse.RenameRequest, newDir bazilfs.Node) error {
+	target, ok := newDir.(*node)
+	if !ok {
+		return fuse.EIO
+	}
+	return fuseError(n.m.rename(path.Join(n.path, req.OldName), path.Join(target.path, req.NewName)))
+}
+
+func (n *node) Setattr(ctx context.Context, req *fuse.SetattrRequest, resp *fuse.SetattrResponse) error {
+	//modes and times are managed by the store
+	if req.Valid.Size() {
+		if err := n.m.truncate(n.path, int64(req.Size)); err != nil {
+			return fuseError(err)
+		}
+	}
+	return n.Attr(ctx, &resp.Attr)
+}
+
+type fileHandle struct {
+	h *handle
+}
+
+func (fh *fileHandle) Read(ctx context.Context, req *fuse.ReadRequest, resp *fuse.ReadResponse) error {
+	buf := make([]byte, req.Size)
+	n, err := fh.h.readAt(buf, req.Offset)
+	if err != nil && err != io.EOF {
+		return fuseError(err)
+	}
+	resp.Data = buf[:n]
+	return nil
+}
+
+func (fh *fileHandle) Write(ctx context.Context, req *fuse.WriteRequest, resp *fuse.WriteResponse) error {
+	n, err := fh.h.writeAt(req.Data, req.Offset)
+	resp.Size = n
+	return fuseError(err)
+}
+
+func (fh *fileHandle) Flush(ctx context.Context, req *fuse.FlushRequest) error {
+	return fuseError(fh.h.flush())
+}
+
+func (fh *fileHandle) Fsync(ctx context.Context, req *fuse.FsyncRequest) error {
+	return fuseError(fh.h.flush())
+}
+
+func (fh *fileHandle) Release(ctx context.Context, req *fuse.ReleaseRequest) error {
+	return fuseError(fh.h.release())
+}
+
+func fuseError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, fs.ErrNotExist):
+		return fuse.ENOENT
+	case errors.Is(err, fs.ErrExist):
+		return fuse.EEXIST
+	case errors.Is(err, fs.ErrPermission):
+		return fuse.EPERM
+	case errors.Is(err, errNotEmpty):
+		return fuse.Errno(syscall.ENOTEMPTY)
+	case errors.Is(err, errIsDirectory):
+		return fuse.Errno(syscall.EISDIR)
+	}
+	return err
+}
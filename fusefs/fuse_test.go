@@ -0,0 +1,72 @@
+//go:build linux || freebsd
+
+package fusefs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"bazil.org/fuse"
+	"github.com/usace/filesapi"
+)
+
+func TestFuseNodes(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	m, err := newMount(Config{Store: store, Root: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	rootNode, err := fileSystem{m}.Root()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := rootNode.(*node)
+
+	created, handle, err := dir.Create(ctx, &fuse.CreateRequest{Name: "a.txt", Flags: fuse.OpenReadWrite | fuse.OpenExclusive}, &fuse.CreateResponse{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fh := handle.(*fileHandle)
+	written := &fuse.WriteResponse{}
+	if err = fh.Write(ctx, &fuse.WriteRequest{Data: []byte("HELLO WORLD")}, written); err != nil || written.Size != 11 {
+		t.Fatalf("unexpected write of %d bytes: %v", written.Size, err)
+	}
+	if err = fh.Flush(ctx, &fuse.FlushRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = fh.Release(ctx, &fuse.ReleaseRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "a.txt")); string(data) != "HELLO WORLD" {
+		t.Fatalf("expected the flushed file to be written back, got %q", data)
+	}
+
+	attr := fuse.Attr{}
+	if err = created.(*node).Attr(ctx, &attr); err != nil || attr.Size != 11 || attr.Mode != 0644 {
+		t.Fatalf("unexpected attributes %v: %v", attr, err)
+	}
+	dirents, err := dir.ReadDirAll(ctx)
+	if err != nil || len(dirents) != 1 || dirents[0].Name != "a.txt" || dirents[0].Type != fuse.DT_File {
+		t.Fatalf("unexpected directory entries %v: %v", dirents, err)
+	}
+	if _, err = dir.Lookup(ctx, "missing.txt"); err != fuse.ENOENT {
+		t.Fatalf("expected ENOENT looking up a missing file, got %v", err)
+	}
+
+	opened, err := created.(*node).Open(ctx, &fuse.OpenRequest{Flags: fuse.OpenReadOnly}, &fuse.OpenResponse{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := &fuse.ReadResponse{}
+	if err = opened.(*fileHandle).Read(ctx, &fuse.ReadRequest{Offset: 6, Size: 10}, read); err != nil || string(read.Data) != "WORLD" {
+		t.Fatalf("unexpected read %q: %v", read.Data, err)
+	}
+	opened.(*fileHandle).Release(ctx, &fuse.ReleaseRequest{})
+}
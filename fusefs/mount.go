@@ -0,0 +1,463 @@
+// Package fusefs mounts a filesapi.FileStore as a local file system, so modeling tools
+// that expect POSIX paths can read and write S3 data directly.
+//
+// Files are read with ranged requests.  Writes are staged in a temporary file and written
+// back to the store when the file is flushed (closed), with a multipart upload for large
+// files.  Until then the staged file is visible through the mount.  Directories are
+// created with folder markers in object stores.  Files are renamed with MoveObject, and
+// directory renames copy every object beneath the renamed path.
+//
+// Mounting uses bazil.org/fuse, which supports Linux and FreeBSD.  The package is its own
+// module, so applications that do not mount stores do not depend on it
+package fusefs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/usace/filesapi"
+)
+
+// files larger than this are written back with multipart uploads
+const multipartThreshold int64 = 64 * 1024 * 1024
+
+var (
+	errNotEmpty    = errors.New("directory not empty")
+	errIsDirectory = errors.New("is a directory")
+)
+
+type Config struct {
+
+	//store to mount
+	Store filesapi.FileStore
+
+	//store path mounted as the root directory
+	Root string
+
+	//mount read only
+	ReadOnly bool
+}
+
+// mount maps file system operations on client paths (i.e. /runs/out.dss) onto a store
+type mount struct {
+	config Config
+	fsys   *filesapi.IOFS
+
+	mu sync.Mutex
+	//open handles holding staged content, by client path
+	staged map[string]*handle
+}
+
+func newMount(config Config) (*mount, error) {
+	if config.Store == nil {
+		return nil, errors.New("fuse mount requires a store")
+	}
+	return &mount{
+		config: config,
+		fsys:   filesapi.NewIOFS(config.Store, config.Root),
+		staged: map[string]*handle{},
+	}, nil
+}
+
+func cleanPath(p string) string {
+	return path.Clean("/" + p)
+}
+
+// store path of a client path
+func (m *mount) storePath(p string) string {
+	return path.Join("/", m.config.Root, cleanPath(p))
+}
+
+// io/fs name of a client path
+func ioName(p string) string {
+	n := strings.Trim(cleanPath(p), "/")
+	if n == "" {
+		return "."
+	}
+	return n
+}
+
+func (m *mount) writable(op string, p string) error {
+	if m.config.ReadOnly {
+		return &fs.PathError{Op: op, Path: p, Err: fs.ErrPermission}
+	}
+	return nil
+}
+
+func (m *mount) stagedHandle(p string) *handle {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.staged[cleanPath(p)]
+}
+
+func (m *mount) stat(p string) (fs.FileInfo, error) {
+	if h := m.stagedHandle(p); h != nil {
+		return h.info()
+	}
+	return m.fsys.Stat(ioName(p))
+}
+
+// readDir lists a directory, including staged files that are not written back yet
+func (m *mount) readDir(p string) ([]fs.DirEntry, error) {
+	entries, err := m.fsys.ReadDir(ioName(p))
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]bool{}
+	for _, e := range entries {
+		names[e.Name()] = true
+	}
+	dir := cleanPath(p)
+	handles := []*handle{}
+	m.mu.Lock()
+	for sp, h := range m.staged {
+		if path.Dir(sp) == dir && !names[path.Base(sp)] {
+			handles = append(handles, h)
+		}
+	}
+	m.mu.Unlock()
+	for _, h := range handles {
+		if info, err := h.info(); err == nil {
+			entries = append(entries, fs.FileInfoToDirEntry(info))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (m *mount) mkdir(p string) error {
+	if err := m.writable("mkdir", p); err != nil {
+		return err
+	}
+	if _, err := m.stat(p); err == nil {
+		return &fs.PathError{Op: "mkdir", Path: p, Err: fs.ErrExist}
+	}
+	return filesapi.EnsureDir(m.config.Store, m.storePath(p))
+}
+
+// open opens an existing file.  Truncate discards its content
+func (m *mount) open(p string, write bool, truncate bool) (*handle, error) {
+	if write {
+		if err := m.writable("open", p); err != nil {
+			return nil, err
+		}
+	}
+	info, err := m.stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &fs.PathError{Op: "open", Path: p, Err: errIsDirectory}
+	}
+	h := &handle{m: m, path: cleanPath(p)}
+	if write && truncate {
+		if err := h.truncate(0); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// create opens an empty file, replacing an existing file unless exclusive is set
+func (m *mount) create(p string, exclusive bool) (*handle, error) {
+	if err := m.writable("create", p); err != nil {
+		return nil, err
+	}
+	info, err := m.stat(p)
+	switch {
+	case err == nil && (exclusive || info.IsDir()):
+		return nil, &fs.PathError{Op: "create", Path: p, Err: fs.ErrExist}
+	case err != nil && !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+	h := &handle{m: m, path: cleanPath(p)}
+	if err := h.truncate(0); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// truncate changes the size of a file.  Open handles write back the change when flushed
+func (m *mount) truncate(p string, size int64) error {
+	if h := m.stagedHandle(p); h != nil {
+		return h.truncate(size)
+	}
+	h, err := m.open(p, true, false)
+	if err != nil {
+		return err
+	}
+	defer h.release()
+	if err := h.truncate(size); err != nil {
+		return err
+	}
+	return h.flush()
+}
+
+func (m *mount) remove(p string, dir bool) error {
+	if err := m.writable("remove", p); err != nil {
+		return err
+	}
+	info, err := m.stat(p)
+	if err != nil {
+		return err
+	}
+	if !dir {
+		if info.IsDir() {
+			return &fs.PathError{Op: "remove", Path: p, Err: errIsDirectory}
+		}
+		return m.deleteObject(m.storePath(p))
+	}
+	if ioName(p) == "." {
+		return &fs.PathError{Op: "rmdir", Path: p, Err: fs.ErrPermission}
+	}
+	entries, err := m.readDir(p)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		//object store folder markers are listed as an entry of their own folder
+		if e.Name() != path.Base(p) || e.IsDir() {
+			return &fs.PathError{Op: "rmdir", Path: p, Err: errNotEmpty}
+		}
+	}
+	return filesapi.RemoveDir(m.config.Store, m.storePath(p))
+}
+
+// rename moves a file, or copies every object beneath a directory and removes the source.
+// An existing file target is replaced
+func (m *mount) rename(oldPath string, newPath string) error {
+	if err := m.writable("rename", oldPath); err != nil {
+		return err
+	}
+	info, err := m.stat(oldPath)
+	if err != nil {
+		return err
+	}
+	if ioName(oldPath) == "." || ioName(newPath) == "." {
+		return &fs.PathError{Op: "rename", Path: oldPath, Err: fs.ErrPermission}
+	}
+	if target, err := m.stat(newPath); err == nil && target.IsDir() {
+		return &fs.PathError{Op: "rename", Path: newPath, Err: fs.ErrExist}
+	}
+	src, dest := m.storePath(oldPath), m.storePath(newPath)
+	if !info.IsDir() {
+		return m.move(src, dest)
+	}
+	if strings.HasPrefix(dest+"/", src+"/") {
+		return &fs.PathError{Op: "rename", Path: newPath, Err: errors.New("cannot move a directory into itself")}
+	}
+	files, err := filesapi.ListFiles(m.config.Store, src)
+	if err != nil {
+		return err
+	}
+	if err := filesapi.EnsureDir(m.config.Store, dest); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := m.copy(f, dest+strings.TrimPrefix(f, src)); err != nil {
+			return err
+		}
+	}
+	return filesapi.RemoveDir(m.config.Store, src)
+}
+
+func (m *mount) copy(src string, dest string) error {
+	if err := filesapi.EnsureDir(m.config.Store, path.Dir(dest)); err != nil {
+		return err
+	}
+	return m.config.Store.CopyObject(filesapi.CopyObjectInput{
+		Src:  filesapi.PathConfig{Path: src},
+		Dest: filesapi.PathConfig{Path: dest},
+	})
+}
+
+func (m *mount) move(src string, dest string) error {
+	if err := filesapi.EnsureDir(m.config.Store, path.Dir(dest)); err != nil {
+		return err
+	}
+	return m.config.Store.MoveObject(filesapi.MoveObjectInput{
+		Src:  filesapi.PathConfig{Path: src},
+		Dest: filesapi.PathConfig{Path: dest},
+	})
+}
+
+func (m *mount) deleteObject(p string) error {
+	for _, err := range m.config.Store.DeleteObjects(filesapi.DeleteObjectInput{Paths: filesapi.PathConfig{Path: p}}) {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handle is an open file.  Reads go to the store until the first change, which stages
+// the content in a temporary file that is written back on flush
+type handle struct {
+	m    *mount
+	path string
+
+	mu     sync.Mutex
+	reader *filesapi.ObjectReadSeeker
+	tmp    *os.File
+	dirty  bool
+}
+
+// stage copies the object into a temporary file.  Caller holds h.mu
+func (h *handle) stage(load bool) error {
+	if h.tmp != nil {
+		return nil
+	}
+	tmp, err := os.CreateTemp("", "filesapi-fuse-*")
+	if err != nil {
+		return err
+	}
+	if load {
+		reader, err := h.m.config.Store.GetObject(filesapi.GetObjectInput{Path: filesapi.PathConfig{Path: h.m.storePath(h.path)}})
+		if err == nil {
+			_, err = io.Copy(tmp, reader)
+			reader.Close()
+		}
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+			return err
+		}
+	}
+	h.tmp = tmp
+	h.m.mu.Lock()
+	h.m.staged[h.path] = h
+	h.m.mu.Unlock()
+	return nil
+}
+
+func (h *handle) readAt(p []byte, off int64) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.tmp != nil {
+		return h.tmp.ReadAt(p, off)
+	}
+	if h.reader == nil {
+		reader, err := filesapi.NewObjectReadSeeker(h.m.config.Store, filesapi.PathConfig{Path: h.m.storePath(h.path)})
+		if err != nil {
+			return 0, err
+		}
+		h.reader = reader
+	}
+	return h.reader.ReadAt(p, off)
+}
+
+func (h *handle) writeAt(p []byte, off int64) (int, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.stage(true); err != nil {
+		return 0, err
+	}
+	h.dirty = true
+	return h.tmp.WriteAt(p, off)
+}
+
+func (h *handle) truncate(size int64) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.stage(size > 0); err != nil {
+		return err
+	}
+	h.dirty = true
+	return h.tmp.Truncate(size)
+}
+
+// flush writes staged changes back to the store
+func (h *handle) flush() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.dirty {
+		return nil
+	}
+	info, err := h.tmp.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+	_, err = h.m.config.Store.PutObject(filesapi.PutObjectInput{
+		Source:   filesapi.ObjectSource{Reader: io.NewSectionReader(h.tmp, 0, size), ContentLength: &size},
+		Dest:     filesapi.PathConfig{Path: h.m.storePath(h.path)},
+		Mutipart: size > multipartThreshold,
+	})
+	if err != nil {
+		return err
+	}
+	h.dirty = false
+	return nil
+}
+
+// release closes the handle, discarding changes that were not flushed
+func (h *handle) release() error {
+	h.m.mu.Lock()
+	if h.m.staged[h.path] == h {
+		delete(h.m.staged, h.path)
+	}
+	h.m.mu.Unlock()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.reader != nil {
+		h.reader.Close()
+		h.reader = nil
+	}
+	if h.tmp != nil {
+		h.tmp.Close()
+		os.Remove(h.tmp.Name())
+		h.tmp = nil
+	}
+	return nil
+}
+
+// file info of the staged content, or of the object before the first change
+func (h *handle) info() (fs.FileInfo, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.tmp == nil {
+		return h.m.fsys.Stat(ioName(h.path))
+	}
+	info, err := h.tmp.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return &stagedInfo{name: path.Base(h.path), size: info.Size(), modTime: info.ModTime()}, nil
+}
+
+type stagedInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (si *stagedInfo) Name() string {
+	return si.name
+}
+
+func (si *stagedInfo) Size() int64 {
+	return si.size
+}
+
+func (si *stagedInfo) Mode() fs.FileMode {
+	return 0644
+}
+
+func (si *stagedInfo) ModTime() time.Time {
+	return si.modTime
+}
+
+func (si *stagedInfo) IsDir() bool {
+	return false
+}
+
+func (si *stagedInfo) Sys() interface{} {
+	return nil
+}
@@ -0,0 +1,109 @@
+package fusefs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/usace/filesapi"
+)
+
+func TestMount(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	m, err := newMount(Config{Store: store, Root: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.mkdir("/runs"); err != nil {
+		t.Fatal(err)
+	}
+
+	//staged files are visible before they are written back
+	h, err := m.create("/runs/out.hdf", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.writeAt([]byte("header|"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.writeAt([]byte("data"), 7); err != nil {
+		t.Fatal(err)
+	}
+	info, err := m.stat("/runs/out.hdf")
+	if err != nil || info.Size() != 11 {
+		t.Fatalf("expected the staged file, got %v %v", info, err)
+	}
+	entries, err := m.readDir("/runs")
+	if err != nil || len(entries) != 1 || entries[0].Name() != "out.hdf" {
+		t.Fatalf("expected the staged file in the listing, got %v %v", entries, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "runs", "out.hdf")); !os.IsNotExist(err) {
+		t.Fatal("expected the file to be written back on flush")
+	}
+	if err := h.flush(); err != nil {
+		t.Fatal(err)
+	}
+	h.release()
+	data, err := os.ReadFile(filepath.Join(root, "runs", "out.hdf"))
+	if err != nil || string(data) != "header|data" {
+		t.Fatalf("unexpected written content %q %v", data, err)
+	}
+	if _, err := m.create("/runs/out.hdf", true); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("expected an exists error, got %v", err)
+	}
+
+	//partial update of an existing file
+	h, err = m.open("/runs/out.hdf", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 6)
+	if n, err := h.readAt(buf, 0); n != 6 || (err != nil && err != io.EOF) || string(buf) != "header" {
+		t.Fatalf("unexpected read %q %v", buf[:n], err)
+	}
+	if _, err := h.writeAt([]byte("DATA"), 7); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.flush(); err != nil {
+		t.Fatal(err)
+	}
+	h.release()
+	if err := m.truncate("/runs/out.hdf", 6); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(filepath.Join(root, "runs", "out.hdf"))
+	if string(data) != "header" {
+		t.Fatalf("unexpected truncated content %q", data)
+	}
+
+	if err := m.remove("/runs", true); !errors.Is(err, errNotEmpty) {
+		t.Fatalf("expected a not empty error, got %v", err)
+	}
+	if err := m.rename("/runs", "/archive"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.stat("/runs"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected the old directory to be removed, got %v", err)
+	}
+	if err := m.remove("/archive/out.hdf", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.remove("/archive", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "archive")); !os.IsNotExist(err) {
+		t.Fatalf("expected the directory to be removed, got %v", err)
+	}
+
+	readOnly, _ := newMount(Config{Store: store, Root: root, ReadOnly: true})
+	if _, err := readOnly.create("/new.txt", false); !errors.Is(err, fs.ErrPermission) {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package filesapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+type MultipartUploadInfo struct {
+	Path      string
+	UploadId  string
+	Initiated time.Time
+}
+
+// MultipartUploadLister is implemented by stores that can list in progress multipart uploads
+type MultipartUploadLister interface {
+	ListMultipartUploads(prefix PathConfig) ([]MultipartUploadInfo, error)
+}
+
+type GarbageReportInput struct {
+	Store  FileStore
+	Prefix PathConfig
+
+	//multipart uploads initiated more than UploadAge ago are reported as dangling.
+	//defaults to 24 hours
+	UploadAge time.Duration
+
+	//optional time source for upload age
+	Clock Clock
+}
+
+// GarbageReport is a cleanup plan for a store prefix.  Review it, then call Apply to carry it out
+type GarbageReport struct {
+	Prefix string
+
+	//zero byte S3 folder marker objects (keys ending in /) with no objects beneath them
+	FolderMarkers []string
+
+	//BlockFS directories with no files beneath them, deepest first
+	EmptyDirs []string
+
+	//multipart uploads older than the upload age
+	DanglingUploads []MultipartUploadInfo
+}
+
+func (gr *GarbageReport) Empty() bool {
+	return len(gr.FolderMarkers) == 0 && len(gr.EmptyDirs) == 0 && len(gr.DanglingUploads) == 0
+}
+
+func (gr *GarbageReport) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "garbage report for %s: %d folder markers, %d empty directories, %d dangling uploads\n",
+		gr.Prefix, len(gr.FolderMarkers), len(gr.EmptyDirs), len(gr.DanglingUploads))
+	for _, m := range gr.FolderMarkers {
+		fmt.Fprintf(&sb, "delete folder marker %s\n", m)
+	}
+	for _, d := range gr.EmptyDirs {
+		fmt.Fprintf(&sb, "remove empty directory %s\n", d)
+	}
+	for _, u := range gr.DanglingUploads {
+		fmt.Fprintf(&sb, "abort upload %s for %s initiated %s\n", u.UploadId, u.Path, u.Initiated.Format(time.RFC3339))
+	}
+	return sb.String()
+}
+
+// Apply deletes the reported folder markers and empty directories and aborts the dangling uploads.
+// Directories are removed with os.Remove so one that gained files since the report is left in place
+func (gr *GarbageReport) Apply(store FileStore) error {
+	errs := []error{}
+	if len(gr.FolderMarkers) > 0 {
+		if err := firstError(store.DeleteObjects(DeleteObjectInput{Paths: PathConfig{Paths: gr.FolderMarkers}})); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, dir := range gr.EmptyDirs {
+		if err := os.Remove(dir); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	for _, u := range gr.DanglingUploads {
+		if err := store.AbortObjectUpload(UploadConfig{ObjectPath: u.Path, UploadId: u.UploadId}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d cleanup actions failed. first error: %w", len(errs), errs[0])
+	}
+	return nil
+}
+
+// FindGarbage walks a store prefix for leftover folder markers, empty directories,
+// and dangling multipart uploads, producing a cleanup plan.  Nothing is modified
+func FindGarbage(input GarbageReportInput) (*GarbageReport, error) {
+	if input.UploadAge <= 0 {
+		input.UploadAge = 24 * time.Hour
+	}
+	report := &GarbageReport{
+		Prefix:          input.Prefix.Path,
+		FolderMarkers:   []string{},
+		EmptyDirs:       []string{},
+		DanglingUploads: []MultipartUploadInfo{},
+	}
+	keys := []string{}
+	markers := map[string]bool{}
+	dirs := []string{}
+	root := strings.TrimSuffix(input.Prefix.Path, "/")
+	err := input.Store.Walk(WalkInput{Path: input.Prefix}, func(path string, file os.FileInfo) error {
+		if file.IsDir() {
+			if strings.TrimSuffix(path, "/") != root {
+				dirs = append(dirs, path)
+			}
+			return nil
+		}
+		if strings.HasSuffix(path, "/") && file.Size() == 0 {
+			markers[path] = true
+		}
+		keys = append(keys, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(keys)
+
+	for i, key := range keys {
+		if markers[key] && (i+1 == len(keys) || !strings.HasPrefix(keys[i+1], key)) {
+			report.FolderMarkers = append(report.FolderMarkers, key)
+		}
+	}
+
+	//deepest first so removing each directory in order empties its parent
+	sort.Slice(dirs, func(i, j int) bool {
+		return strings.Count(dirs[i], string(os.PathSeparator)) > strings.Count(dirs[j], string(os.PathSeparator))
+	})
+	for _, dir := range dirs {
+		prefix := strings.TrimSuffix(dir, string(os.PathSeparator)) + string(os.PathSeparator)
+		i := sort.SearchStrings(keys, prefix)
+		if i == len(keys) || !strings.HasPrefix(keys[i], prefix) {
+			report.EmptyDirs = append(report.EmptyDirs, dir)
+		}
+	}
+
+	if lister, ok := input.Store.(MultipartUploadLister); ok {
+		uploads, err := lister.ListMultipartUploads(input.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		cutoff := input.Clock.Now().Add(-input.UploadAge)
+		for _, u := range uploads {
+			if u.Initiated.Before(cutoff) {
+				report.DanglingUploads = append(report.DanglingUploads, u)
+			}
+		}
+	}
+	return report, nil
+}
+
+func (s3fs *S3FS) ListMultipartUploads(prefix PathConfig) ([]MultipartUploadInfo, error) {
+	client, err := s3fs.client()
+	if err != nil {
+		return nil, err
+	}
+	s3Path := strings.TrimPrefix(prefix.Path, "/")
+	input := &s3.ListMultipartUploadsInput{
+		Bucket: &s3fs.config.S3Bucket,
+		Prefix: &s3Path,
+	}
+	uploads := []MultipartUploadInfo{}
+	for {
+		resp, err := client.ListMultipartUploads(context.TODO(), input)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range resp.Uploads {
+			info := MultipartUploadInfo{Path: "/" + *u.Key, UploadId: *u.UploadId}
+			if u.Initiated != nil {
+				info.Initiated = *u.Initiated
+			}
+			uploads = append(uploads, info)
+		}
+		if resp.IsTruncated == nil || !*resp.IsTruncated {
+			return uploads, nil
+		}
+		input.KeyMarker = resp.NextKeyMarker
+		input.UploadIdMarker = resp.NextUploadIdMarker
+	}
+}
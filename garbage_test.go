@@ -0,0 +1,117 @@
+package filesapi
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// lists a fixed set of s3 style keys and in progress uploads
+type markerFS struct {
+	*BlockFS
+	objects []types.Object
+	uploads []MultipartUploadInfo
+	aborted []string
+}
+
+func (m *markerFS) Walk(input WalkInput, visitor FileVisitFunction) error {
+	//like S3FS, visitor errors are ignored and progress errors end the walk
+	for i := range m.objects {
+		visitor("/"+*m.objects[i].Key, &S3FileInfo{&m.objects[i]})
+		if err := reportProgress(input.Progress, ProgressData{Index: i, Max: -1}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *markerFS) ListMultipartUploads(prefix PathConfig) ([]MultipartUploadInfo, error) {
+	return m.uploads, nil
+}
+
+func (m *markerFS) AbortObjectUpload(u UploadConfig) error {
+	m.aborted = append(m.aborted, u.UploadId)
+	return nil
+}
+
+func testS3Object(key string, size int64) types.Object {
+	return types.Object{Key: aws.String(key), Size: aws.Int64(size)}
+}
+
+func TestFindGarbageBlockFS(t *testing.T) {
+	dir := t.TempDir()
+	for _, d := range []string{"runs/empty/deeper", "runs/full/nested"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "runs/full/nested/output.dss"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	block := &BlockFS{}
+	report, err := FindGarbage(GarbageReportInput{Store: block, Prefix: PathConfig{Path: dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{filepath.Join(dir, "runs/empty/deeper"), filepath.Join(dir, "runs/empty")}
+	if len(report.EmptyDirs) != 2 || report.EmptyDirs[0] != expected[0] || report.EmptyDirs[1] != expected[1] {
+		t.Fatalf("unexpected empty directories %v", report.EmptyDirs)
+	}
+	if len(report.FolderMarkers) != 0 || len(report.DanglingUploads) != 0 {
+		t.Fatalf("unexpected block file garbage %v %v", report.FolderMarkers, report.DanglingUploads)
+	}
+	summary := fmt.Sprintf("garbage report for %s: 0 folder markers, 2 empty directories, 0 dangling uploads\n"+
+		"remove empty directory %s\nremove empty directory %s\n", dir, expected[0], expected[1])
+	if report.String() != summary {
+		t.Fatalf("unexpected report summary %q", report.String())
+	}
+	if err = report.Apply(block); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(expected[1]); !os.IsNotExist(err) {
+		t.Fatal("expected the empty directory to be removed")
+	}
+	if _, err = os.Stat(filepath.Join(dir, "runs/full/nested/output.dss")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindGarbageFolderMarkers(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	store := &markerFS{
+		BlockFS: &BlockFS{},
+		objects: []types.Object{
+			{Key: aws.String("project/"), Size: aws.Int64(0)},
+			{Key: aws.String("project/empty/"), Size: aws.Int64(0)},
+			{Key: aws.String("project/inputs/"), Size: aws.Int64(0)},
+			{Key: aws.String("project/inputs/flow.csv"), Size: aws.Int64(10)},
+		},
+		uploads: []MultipartUploadInfo{
+			{Path: "/project/big.tif", UploadId: "stale", Initiated: now.Add(-72 * time.Hour)},
+			{Path: "/project/new.tif", UploadId: "active", Initiated: now.Add(-time.Minute)},
+		},
+	}
+	report, err := FindGarbage(GarbageReportInput{
+		Store:  store,
+		Prefix: PathConfig{Path: "/project"},
+		Clock:  func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.FolderMarkers) != 1 || report.FolderMarkers[0] != "/project/empty/" {
+		t.Fatalf("unexpected folder markers %v", report.FolderMarkers)
+	}
+	if len(report.DanglingUploads) != 1 || report.DanglingUploads[0].UploadId != "stale" {
+		t.Fatalf("unexpected dangling uploads %v", report.DanglingUploads)
+	}
+	report.FolderMarkers = nil
+	if err = report.Apply(store); err != nil || len(store.aborted) != 1 {
+		t.Fatalf("expected the stale upload to be aborted: %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+package httpkit
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/usace/filesapi"
+)
+
+const (
+	defaultPageSize   int32 = 100
+	defaultPresignTTL int   = 1
+)
+
+// Presigner is implemented by stores able to create presigned download urls (i.e. S3FS)
+type Presigner interface {
+	GetPresignedUrl(path filesapi.PathConfig, days int) (string, error)
+}
+
+type BrowseHandlerConfig struct {
+
+	//store being browsed
+	Store filesapi.FileStore
+
+	//public url path the handler is mounted at
+	BasePath string
+
+	//store path that all client supplied paths are scoped beneath
+	Root string
+
+	//optional authorization hook
+	Authorize AuthorizeFunction
+
+	//default and maximum page sizes for listings
+	PageSize    int32
+	MaxPageSize int32
+
+	//maximum presigned url lifetime in days
+	MaxPresignDays int
+}
+
+type ObjectInfo struct {
+	Name     string    `json:"fileName"`
+	Path     string    `json:"filePath"`
+	Size     int64     `json:"size"`
+	IsDir    bool      `json:"isdir"`
+	Modified time.Time `json:"modified"`
+}
+
+type ListResponse struct {
+	Page    int                              `json:"page"`
+	Size    int32                            `json:"size"`
+	Objects []filesapi.FileStoreResultObject `json:"objects"`
+}
+
+type PresignResponse struct {
+	Url string `json:"url"`
+}
+
+type DeleteResponse struct {
+	Errors []string `json:"errors"`
+}
+
+// BrowseHandler exposes the file browsing operations of a store as JSON endpoints:
+//
+//	GET    {base}/list?path=&page=&size=&filter=   paged directory listing
+//	GET    {base}/info?path=                       object info
+//	GET    {base}/presign?path=&days=              presigned download url (stores implementing Presigner)
+//	DELETE {base}/objects?path=                    recursive delete
+//
+// The handler is a plain http.Handler so it can be mounted in any router,
+// including Echo (echo.WrapHandler) and Gin (gin.WrapH)
+type BrowseHandler struct {
+	config BrowseHandlerConfig
+}
+
+func NewBrowseHandler(config BrowseHandlerConfig) (*BrowseHandler, error) {
+	if config.Store == nil {
+		return nil, errors.New("browse handler requires a store")
+	}
+	if config.PageSize <= 0 {
+		config.PageSize = defaultPageSize
+	}
+	if config.MaxPageSize <= 0 {
+		config.MaxPageSize = filesapi.DEFAULTMAXKEYS
+	}
+	if config.MaxPresignDays <= 0 {
+		config.MaxPresignDays = defaultPresignTTL
+	}
+	return &BrowseHandler{config}, nil
+}
+
+func (bh *BrowseHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := routeSegments(bh.config.BasePath, r.URL.Path)
+	if len(segments) != 1 {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && segments[0] == "list":
+		bh.list(w, r)
+	case r.Method == http.MethodGet && segments[0] == "info":
+		bh.info(w, r)
+	case r.Method == http.MethodGet && segments[0] == "presign":
+		bh.presign(w, r)
+	case r.Method == http.MethodDelete && segments[0] == "objects":
+		bh.delete(w, r)
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+func (bh *BrowseHandler) authorize(r *http.Request, op Operation, path string) error {
+	if bh.config.Authorize == nil {
+		return nil
+	}
+	return bh.config.Authorize(r, op, path)
+}
+
+func (bh *BrowseHandler) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	path := scopedPath(bh.config.Root, q.Get("path")+"/")
+	if err := bh.authorize(r, OpList, path); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	page, _ := strconv.Atoi(q.Get("page"))
+	if page < 0 {
+		page = 0
+	}
+	size := bh.config.PageSize
+	if s, err := strconv.ParseInt(q.Get("size"), 10, 32); err == nil && s > 0 {
+		size = int32(s)
+	}
+	if size > bh.config.MaxPageSize {
+		size = bh.config.MaxPageSize
+	}
+	objects, err := bh.config.Store.ListDir(filesapi.ListDirInput{
+		Path:   filesapi.PathConfig{Path: path},
+		Page:   page,
+		Size:   size,
+		Filter: q.Get("filter"),
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ListResponse{Page: page, Size: size, Objects: *objects})
+}
+
+func (bh *BrowseHandler) info(w http.ResponseWriter, r *http.Request) {
+	path := scopedPath(bh.config.Root, r.URL.Query().Get("path"))
+	if err := bh.authorize(r, OpRead, path); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	fi, err := bh.config.Store.GetObjectInfo(filesapi.PathConfig{Path: path})
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ObjectInfo{
+		Name:     fi.Name(),
+		Path:     path,
+		Size:     fi.Size(),
+		IsDir:    fi.IsDir(),
+		Modified: fi.ModTime(),
+	})
+}
+
+func (bh *BrowseHandler) presign(w http.ResponseWriter, r *http.Request) {
+	presigner, ok := bh.config.Store.(Presigner)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, errors.New("store does not support presigned urls"))
+		return
+	}
+	q := r.URL.Query()
+	path := scopedPath(bh.config.Root, q.Get("path"))
+	if err := bh.authorize(r, OpRead, path); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	days, err := strconv.Atoi(q.Get("days"))
+	if err != nil || days <= 0 {
+		days = defaultPresignTTL
+	}
+	if days > bh.config.MaxPresignDays {
+		days = bh.config.MaxPresignDays
+	}
+	url, err := presigner.GetPresignedUrl(filesapi.PathConfig{Path: path}, days)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, PresignResponse{Url: url})
+}
+
+func (bh *BrowseHandler) delete(w http.ResponseWriter, r *http.Request) {
+	paths := []string{}
+	for _, p := range r.URL.Query()["path"] {
+		path := scopedPath(bh.config.Root, p)
+		if isRootPath(bh.config.Root, path) {
+			writeError(w, http.StatusBadRequest, errors.New("refusing to delete the root path"))
+			return
+		}
+		if err := bh.authorize(r, OpDelete, path); err != nil {
+			writeError(w, http.StatusForbidden, err)
+			return
+		}
+		paths = append(paths, path)
+	}
+	if len(paths) == 0 {
+		writeError(w, http.StatusBadRequest, errors.New("no paths to delete"))
+		return
+	}
+	errs := bh.config.Store.DeleteObjects(filesapi.DeleteObjectInput{
+		Paths: filesapi.PathConfig{Paths: paths},
+	})
+	response := DeleteResponse{Errors: []string{}}
+	for _, err := range errs {
+		if err != nil {
+			response.Errors = append(response.Errors, err.Error())
+		}
+	}
+	status := http.StatusOK
+	if len(response.Errors) > 0 {
+		status = http.StatusInternalServerError
+	}
+	writeJSON(w, status, response)
+}
+
+func statusFor(err error) int {
+	var fnf *filesapi.FileNotFoundError
+	if errors.As(err, &fnf) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
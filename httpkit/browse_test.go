@@ -0,0 +1,114 @@
+package httpkit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/usace/filesapi"
+)
+
+func TestBrowse(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "data"), os.ModePerm)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		os.WriteFile(filepath.Join(root, "data", name), []byte("HELLO WORLD"), 0644)
+	}
+	handler, err := NewBrowseHandler(BrowseHandlerConfig{Store: store, BasePath: "/browse", Root: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/browse/list?path=data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := ListResponse{}
+	json.NewDecoder(resp.Body).Decode(&list)
+	if len(list.Objects) != 2 {
+		t.Fatalf("expected 2 objects, got %v", list.Objects)
+	}
+
+	resp, err = http.Get(server.URL + "/browse/info?path=data/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := ObjectInfo{}
+	json.NewDecoder(resp.Body).Decode(&info)
+	if info.Size != 11 || info.IsDir {
+		t.Fatalf("unexpected object info: %v", info)
+	}
+
+	resp, err = http.Get(server.URL + "/browse/info?path=../data/missing.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing object, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(server.URL + "/browse/presign?path=data/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusNotImplemented {
+		t.Fatalf("expected 501 presigning from a block store, got %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/browse/objects?path=data/b.txt", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || filesapi.FileExists(store, filepath.Join(root, "data", "b.txt")) {
+		t.Fatalf("expected data/b.txt to be deleted, got %d", resp.StatusCode)
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, server.URL+"/browse/objects?path=/", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected root delete to be refused, got %d", resp.StatusCode)
+	}
+}
+
+func TestBrowseDeleteRoot(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "a.txt"), []byte("HELLO WORLD"), 0644)
+	handler, err := NewBrowseHandler(BrowseHandlerConfig{Store: store, BasePath: "/browse", Root: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	for _, p := range []string{"./", ".", "a/..", "//", "../..", "a/../"} {
+		req, _ := http.NewRequest(http.MethodDelete, server.URL+"/browse/objects?path="+url.QueryEscape(p), nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("expected deleting %q to be refused, got %d", p, resp.StatusCode)
+		}
+		if !filesapi.FileExists(store, filepath.Join(root, "a.txt")) {
+			t.Fatalf("deleting %q removed the root contents", p)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package httpkit
+
+import (
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/usace/filesapi"
+)
+
+// ServeObject serves a store object with byte range support.  The response carries the
+// object ETag and Last-Modified validators, so browsers can pause and resume downloads with
+// If-Range, and only the requested ranges are read from the store.  A non empty downloadName
+// saves the object as an attachment
+func ServeObject(w http.ResponseWriter, r *http.Request, store filesapi.FileStore, objectPath string, downloadName string) {
+	reader, err := filesapi.NewObjectReadSeeker(store, filesapi.PathConfig{Path: objectPath})
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	defer reader.Close()
+	if etag := filesapi.ObjectETag(reader.Info()); etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	if contentType := mime.TypeByExtension(path.Ext(objectPath)); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	if downloadName != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": downloadName}))
+	}
+	//the name is only used to sniff a content type when the extension has none
+	http.ServeContent(w, r, path.Base(objectPath), reader.Info().ModTime(), reader)
+}
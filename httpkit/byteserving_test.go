@@ -0,0 +1,58 @@
+package httpkit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/usace/filesapi"
+)
+
+func TestServeObjectResume(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	objectPath := filepath.Join(t.TempDir(), "a.txt")
+	os.WriteFile(objectPath, []byte("HELLO WORLD"), 0644)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ServeObject(w, r, store, objectPath, "")
+	}))
+	defer server.Close()
+
+	get := func(header map[string]string) (*http.Response, string) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp, string(body)
+	}
+
+	resp, body := get(nil)
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != http.StatusOK || body != "HELLO WORLD" || etag == "" {
+		t.Fatalf("unexpected download: %d %q etag %q", resp.StatusCode, body, etag)
+	}
+	if resp.Header.Get("Accept-Ranges") != "bytes" {
+		t.Fatal("expected range support to be advertised")
+	}
+
+	resp, body = get(map[string]string{"Range": "bytes=6-", "If-Range": etag})
+	if resp.StatusCode != http.StatusPartialContent || body != "WORLD" {
+		t.Fatalf("expected the download to resume, got %d %q", resp.StatusCode, body)
+	}
+
+	resp, body = get(map[string]string{"Range": "bytes=6-", "If-Range": `"stale"`})
+	if resp.StatusCode != http.StatusOK || body != "HELLO WORLD" {
+		t.Fatalf("expected a changed object to restart the download, got %d %q", resp.StatusCode, body)
+	}
+}
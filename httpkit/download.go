@@ -0,0 +1,102 @@
+package httpkit
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/usace/filesapi"
+)
+
+const defaultDownloadUrlTTL time.Duration = 5 * time.Minute
+
+// DownloadPresigner is implemented by stores able to presign short lived download urls (i.e. S3FS)
+type DownloadPresigner interface {
+	PresignGetObject(input filesapi.PresignGetInput) (string, error)
+}
+
+type DownloadHandlerConfig struct {
+
+	//store holding the downloads
+	Store filesapi.FileStore
+
+	//public url path the handler is mounted at
+	BasePath string
+
+	//store path that all client supplied paths are scoped beneath
+	Root string
+
+	//optional authentication and authorization hook, called with OpRead.
+	//return a StatusError with http.StatusUnauthorized to reject unauthenticated callers
+	Authorize AuthorizeFunction
+
+	//lifetime of presigned urls.  Defaults to defaultDownloadUrlTTL
+	UrlTTL time.Duration
+
+	//saves downloads as attachments named after the object
+	Attachment bool
+}
+
+// DownloadHandler serves secure download links:
+//
+//	GET {base}/{path}   authorize, then redirect or stream the object
+//
+// Stores implementing DownloadPresigner answer with a 302 redirect to a freshly presigned
+// url, so the data never passes through the service.  Missing objects are reported by the
+// store when the url is followed.  Other stores (i.e. BlockFS) serve the object with ServeObject,
+// so downloads can be resumed
+type DownloadHandler struct {
+	config DownloadHandlerConfig
+}
+
+func NewDownloadHandler(config DownloadHandlerConfig) (*DownloadHandler, error) {
+	if config.Store == nil {
+		return nil, errors.New("download handler requires a store")
+	}
+	if config.UrlTTL <= 0 {
+		config.UrlTTL = defaultDownloadUrlTTL
+	}
+	return &DownloadHandler{config}, nil
+}
+
+func (dh *DownloadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	segments := routeSegments(dh.config.BasePath, r.URL.Path)
+	if len(segments) == 0 {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+	objectPath := scopedPath(dh.config.Root, strings.Join(segments, "/"))
+	if dh.config.Authorize != nil {
+		if err := dh.config.Authorize(r, OpRead, objectPath); err != nil {
+			writeError(w, http.StatusForbidden, err)
+			return
+		}
+	}
+	downloadName := ""
+	if dh.config.Attachment {
+		downloadName = path.Base(objectPath)
+	}
+
+	//presigned urls are short lived, so neither response may be cached by shared caches
+	w.Header().Set("Cache-Control", "private, no-store")
+	if presigner, ok := dh.config.Store.(DownloadPresigner); ok {
+		url, err := presigner.PresignGetObject(filesapi.PresignGetInput{
+			Path:         filesapi.PathConfig{Path: objectPath},
+			Expires:      dh.config.UrlTTL,
+			DownloadName: downloadName,
+		})
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		http.Redirect(w, r, url, http.StatusFound)
+		return
+	}
+	ServeObject(w, r, dh.config.Store, objectPath, downloadName)
+}
@@ -0,0 +1,135 @@
+package httpkit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/usace/filesapi"
+)
+
+func TestDownloadStream(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "data"), os.ModePerm)
+	os.WriteFile(filepath.Join(root, "data", "a.txt"), []byte("HELLO WORLD"), 0644)
+	handler, err := NewDownloadHandler(DownloadHandlerConfig{
+		Store:      store,
+		BasePath:   "/download",
+		Root:       root,
+		Attachment: true,
+		Authorize: func(r *http.Request, op Operation, path string) error {
+			if r.Header.Get("Authorization") != "Bearer token" {
+				return &StatusError{Status: http.StatusUnauthorized, Message: "unauthorized"}
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	get := func(path string, header http.Header) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		req.Header.Set("Authorization", "Bearer token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp, err := http.Get(server.URL + "/download/data/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without credentials, got %d", resp.StatusCode)
+	}
+
+	resp = get("/download/data/a.txt", nil)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "HELLO WORLD" {
+		t.Fatalf("unexpected download: %d %q", resp.StatusCode, body)
+	}
+	if !strings.Contains(resp.Header.Get("Content-Disposition"), `filename=a.txt`) {
+		t.Fatalf("expected an attachment, got %q", resp.Header.Get("Content-Disposition"))
+	}
+
+	resp = get("/download/data/a.txt", http.Header{"Range": []string{"bytes=6-"}})
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent || string(body) != "WORLD" {
+		t.Fatalf("unexpected range download: %d %q", resp.StatusCode, body)
+	}
+
+	resp = get("/download/data/missing.txt", nil)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for a missing object, got %d", resp.StatusCode)
+	}
+}
+
+func TestDownloadRedirect(t *testing.T) {
+	s3server := httptest.NewServer(http.NotFoundHandler())
+	defer s3server.Close()
+	store, err := filesapi.NewFileStore(filesapi.MinioFSConfig{
+		S3FSConfig: filesapi.S3FSConfig{
+			S3Region:    "us-east-1",
+			S3Bucket:    "bucket",
+			Credentials: filesapi.S3FS_Static{S3Id: "id", S3Key: "key"},
+		},
+		HostAddress: s3server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := NewDownloadHandler(DownloadHandlerConfig{
+		Store:      store,
+		BasePath:   "/download",
+		Root:       "/data",
+		Attachment: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(server.URL + "/download/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected a redirect, got %d", resp.StatusCode)
+	}
+	location := resp.Header.Get("Location")
+	if !strings.HasPrefix(location, s3server.URL) || !strings.Contains(location, "/data/a.txt") {
+		t.Fatalf("unexpected redirect location %s", location)
+	}
+	if !strings.Contains(location, "X-Amz-Expires=300") || !strings.Contains(location, "response-content-disposition") {
+		t.Fatalf("expected a 5 minute attachment url, got %s", location)
+	}
+	if resp.Header.Get("Cache-Control") != "private, no-store" {
+		t.Fatalf("redirects must not be cached, got %q", resp.Header.Get("Cache-Control"))
+	}
+}
@@ -0,0 +1,83 @@
+package httpkit
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/usace/filesapi"
+)
+
+type FileHandlerConfig struct {
+
+	//store holding the files
+	Store filesapi.FileStore
+
+	//public url path the handler is mounted at
+	BasePath string
+
+	//store path that all client supplied paths are scoped beneath
+	Root string
+
+	//optional authentication and authorization hook, called with OpRead
+	Authorize AuthorizeFunction
+
+	//optional Cache-Control of served files (i.e. "public, max-age=3600")
+	CacheControl string
+
+	//optional object served for directory paths (i.e. index.html).
+	//Without it directory paths are not found
+	IndexName string
+}
+
+// FileHandler serves the objects of a store:
+//
+//	GET|HEAD {base}/{path}
+//
+// Responses are streamed from the store with ServeObject, so they carry the Content-Type
+// of the object extension and ETag and Last-Modified validators, and answer conditional
+// and Range requests by reading only the requested bytes from the store
+type FileHandler struct {
+	config FileHandlerConfig
+}
+
+func NewFileHandler(config FileHandlerConfig) (*FileHandler, error) {
+	if config.Store == nil {
+		return nil, errors.New("file handler requires a store")
+	}
+	return &FileHandler{config}, nil
+}
+
+func (fh *FileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	rel := strings.Join(routeSegments(fh.config.BasePath, r.URL.Path), "/")
+	if rel == "" || strings.HasSuffix(r.URL.Path, "/") {
+		if fh.config.IndexName == "" {
+			writeError(w, http.StatusNotFound, errors.New("not found"))
+			return
+		}
+		rel = strings.TrimPrefix(rel+"/"+fh.config.IndexName, "/")
+	}
+	objectPath := scopedPath(fh.config.Root, rel)
+	if fh.config.Authorize != nil {
+		if err := fh.config.Authorize(r, OpRead, objectPath); err != nil {
+			writeError(w, http.StatusForbidden, err)
+			return
+		}
+	}
+	if fh.config.CacheControl != "" {
+		w.Header().Set("Cache-Control", fh.config.CacheControl)
+	}
+	ServeObject(w, r, fh.config.Store, objectPath, "")
+}
+
+// FileSystem adapts the objects beneath a store root to an http.FileSystem for use with
+// http.FileServer, which adds directory listings.  http.FileServer does not send ETags;
+// use a FileHandler when clients revalidate or resume downloads with If-Range
+func FileSystem(store filesapi.FileStore, root string) http.FileSystem {
+	return http.FS(filesapi.NewIOFS(store, root))
+}
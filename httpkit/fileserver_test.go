@@ -0,0 +1,117 @@
+package httpkit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/usace/filesapi"
+)
+
+func TestFileHandler(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "site", "data"), os.ModePerm)
+	os.WriteFile(filepath.Join(root, "site", "index.html"), []byte("<html></html>"), 0644)
+	os.WriteFile(filepath.Join(root, "site", "data", "stage.csv"), []byte("time,stage\n0,12.5\n"), 0644)
+	handler, err := NewFileHandler(FileHandlerConfig{
+		Store:        store,
+		BasePath:     "/files",
+		Root:         root + "/site",
+		CacheControl: "public, max-age=60",
+		IndexName:    "index.html",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	get := func(method string, path string, header map[string]string) (*http.Response, string) {
+		req, _ := http.NewRequest(method, server.URL+path, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	resp, body := get(http.MethodGet, "/files/data/stage.csv", nil)
+	if resp.StatusCode != http.StatusOK || body != "time,stage\n0,12.5\n" {
+		t.Fatalf("unexpected response %d %q", resp.StatusCode, body)
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" || resp.Header.Get("Last-Modified") == "" || resp.Header.Get("Cache-Control") != "public, max-age=60" {
+		t.Fatalf("expected validators and caching headers, got %v", resp.Header)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/csv") {
+		t.Fatalf("expected a csv content type, got %s", resp.Header.Get("Content-Type"))
+	}
+
+	resp, body = get(http.MethodGet, "/files/data/stage.csv", map[string]string{"Range": "bytes=5-9"})
+	if resp.StatusCode != http.StatusPartialContent || body != "stage" {
+		t.Fatalf("unexpected range response %d %q", resp.StatusCode, body)
+	}
+	resp, _ = get(http.MethodGet, "/files/data/stage.csv", map[string]string{"If-None-Match": etag})
+	if resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("expected 304 for a matching etag, got %d", resp.StatusCode)
+	}
+
+	resp, body = get(http.MethodGet, "/files/", nil)
+	if resp.StatusCode != http.StatusOK || body != "<html></html>" {
+		t.Fatalf("expected the index object, got %d %q", resp.StatusCode, body)
+	}
+	for _, path := range []string{"/files/missing.csv", "/files/data/", "/files/../../etc/passwd"} {
+		if resp, _ := get(http.MethodGet, path, nil); resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d", path, resp.StatusCode)
+		}
+	}
+	if resp, _ := get(http.MethodPost, "/files/data/stage.csv", nil); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", resp.StatusCode)
+	}
+}
+
+func TestFileSystem(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "a.txt"), []byte("HELLO WORLD"), 0644)
+	server := httptest.NewServer(http.FileServer(FileSystem(store, root)))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/a.txt", nil)
+	req.Header.Set("Range", "bytes=6-")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent || string(body) != "WORLD" {
+		t.Fatalf("unexpected range response %d %q", resp.StatusCode, body)
+	}
+
+	resp, err = http.Get(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), "a.txt") {
+		t.Fatalf("expected a directory listing, got %q", body)
+	}
+}
@@ -0,0 +1,81 @@
+// Package httpkit provides ready-made net/http handlers for applications
+// exposing a filesapi.FileStore over http.
+package httpkit
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/usace/filesapi"
+)
+
+type Operation string
+
+const (
+	OpUpload Operation = "upload"
+	OpList   Operation = "list"
+	OpRead   Operation = "read"
+	OpDelete Operation = "delete"
+)
+
+// AuthorizeFunction is called before each operation with the store path it targets.
+// Returning an error rejects the request with a 403 (or the status of a StatusError)
+type AuthorizeFunction func(r *http.Request, op Operation, path string) error
+
+// StatusError lets hooks control the http status returned to the client
+type StatusError struct {
+	Status  int
+	Message string
+}
+
+func (se *StatusError) Error() string {
+	return se.Message
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	var se *StatusError
+	if errors.As(err, &se) {
+		status = se.Status
+	}
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+// scopes a client supplied path beneath a root store path.
+// the client path is cleaned as an absolute path before it is joined, so "." and ".."
+// segments can never resolve above the root.
+// scoped paths are always rooted, so Root should be an absolute directory for block file stores
+func scopedPath(root string, p string) string {
+	rel := path.Clean("/" + p)
+	pp := filesapi.PathParts{Parts: []string{root, rel}}
+	if strings.HasSuffix(p, "/") {
+		return pp.ToPath()
+	}
+	return pp.ToFilePath()
+}
+
+// reports whether a scoped path resolves to the root itself
+func isRootPath(root string, scoped string) bool {
+	return path.Clean(scoped) == path.Clean(scopedPath(root, "/"))
+}
+
+// splits a request path beneath the handler base path into its segments
+func routeSegments(base string, path string) []string {
+	rel := strings.Trim(strings.TrimPrefix(path, strings.TrimSuffix(base, "/")), "/")
+	if rel == "" {
+		return []string{}
+	}
+	return strings.Split(rel, "/")
+}
@@ -0,0 +1,128 @@
+package httpkit
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/usace/filesapi"
+)
+
+// upload session states
+const (
+	UploadStatusUploading  string = "uploading"
+	UploadStatusAssembling string = "assembling"
+	UploadStatusComplete   string = "complete"
+	UploadStatusFailed     string = "failed"
+)
+
+// ProgressStore persists upload progress so it can be polled by upload id, including from
+// other service instances when the store is shared
+type ProgressStore interface {
+
+	//returns the progress of an upload.  ok is false for unknown uploads
+	Get(uploadId string) (progress UploadProgress, ok bool, err error)
+
+	Put(progress UploadProgress) error
+
+	Delete(uploadId string) error
+}
+
+// MemoryProgressStore keeps upload progress in memory.  Progress does not survive a restart.
+// Expired progress is swept whenever progress is put, so abandoned uploads are not kept
+type MemoryProgressStore struct {
+	mu       sync.Mutex
+	progress map[string]UploadProgress
+}
+
+func NewMemoryProgressStore() *MemoryProgressStore {
+	return &MemoryProgressStore{progress: map[string]UploadProgress{}}
+}
+
+func (mps *MemoryProgressStore) Get(uploadId string) (UploadProgress, bool, error) {
+	mps.mu.Lock()
+	defer mps.mu.Unlock()
+	p, ok := mps.progress[uploadId]
+	return p, ok, nil
+}
+
+func (mps *MemoryProgressStore) Put(progress UploadProgress) error {
+	mps.mu.Lock()
+	defer mps.mu.Unlock()
+	now := time.Now()
+	for id, p := range mps.progress {
+		if !p.ExpiresAt.IsZero() && now.After(p.ExpiresAt) {
+			delete(mps.progress, id)
+		}
+	}
+	mps.progress[progress.UploadId] = progress
+	return nil
+}
+
+func (mps *MemoryProgressStore) Delete(uploadId string) error {
+	mps.mu.Lock()
+	defer mps.mu.Unlock()
+	delete(mps.progress, uploadId)
+	return nil
+}
+
+// FileStoreProgressStore keeps upload progress as json documents in a file store,
+// one document per upload at {Prefix}/{uploadId}.json.  Expired documents are deleted when
+// they are next looked up; use a lifecycle rule on the prefix to remove abandoned uploads
+type FileStoreProgressStore struct {
+	Store  filesapi.FileStore
+	Prefix string
+}
+
+func (fps *FileStoreProgressStore) path(uploadId string) filesapi.PathConfig {
+	pp := filesapi.PathParts{Parts: []string{fps.Prefix, uploadId + ".json"}}
+	return filesapi.PathConfig{Path: pp.ToFilePath()}
+}
+
+func (fps *FileStoreProgressStore) Get(uploadId string) (UploadProgress, bool, error) {
+	progress := UploadProgress{}
+	reader, err := fps.Store.GetObject(filesapi.GetObjectInput{Path: fps.path(uploadId)})
+	var fnf *filesapi.FileNotFoundError
+	if errors.As(err, &fnf) {
+		return progress, false, nil
+	}
+	if err != nil {
+		return progress, false, err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return progress, false, err
+	}
+	if err = json.Unmarshal(data, &progress); err != nil {
+		return progress, false, err
+	}
+	return progress, true, nil
+}
+
+func (fps *FileStoreProgressStore) Put(progress UploadProgress) error {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	_, err = fps.Store.PutObject(filesapi.PutObjectInput{
+		Source: filesapi.ObjectSource{Data: data},
+		Dest:   fps.path(progress.UploadId),
+	})
+	return err
+}
+
+func (fps *FileStoreProgressStore) Delete(uploadId string) error {
+	return firstError(fps.Store.DeleteObjects(filesapi.DeleteObjectInput{Paths: fps.path(uploadId)}))
+}
+
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
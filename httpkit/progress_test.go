@@ -0,0 +1,83 @@
+package httpkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/usace/filesapi"
+)
+
+func TestSharedProgressStore(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	progressStore := &FileStoreProgressStore{Store: store, Prefix: t.TempDir()}
+	newServer := func() *httptest.Server {
+		handler, err := NewUploadHandler(UploadHandlerConfig{
+			Store:         store,
+			BasePath:      "/uploads",
+			Root:          root,
+			ProgressStore: progressStore,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return httptest.NewServer(handler)
+	}
+	uploader := newServer()
+	defer uploader.Close()
+	poller := newServer()
+	defer poller.Close()
+
+	send := func(method string, url string, body []byte) *http.Response {
+		req, _ := http.NewRequest(method, url, bytes.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	poll := func(uploadId string) UploadProgress {
+		resp := send(http.MethodGet, poller.URL+"/uploads/"+uploadId, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200 polling progress, got %d", resp.StatusCode)
+		}
+		progress := UploadProgress{}
+		json.NewDecoder(resp.Body).Decode(&progress)
+		return progress
+	}
+
+	resp := send(http.MethodPost, uploader.URL+"/uploads", []byte(`{"path":"a.txt"}`))
+	upload := filesapi.UploadResult{}
+	json.NewDecoder(resp.Body).Decode(&upload)
+	if progress := poll(upload.ID); progress.Status != UploadStatusUploading {
+		t.Fatalf("expected an uploading status, got %v", progress)
+	}
+
+	resp = send(http.MethodPut, fmt.Sprintf("%s/uploads/%s/0?path=a.txt", uploader.URL, upload.ID), []byte("HELLO WORLD"))
+	chunk := filesapi.UploadResult{}
+	json.NewDecoder(resp.Body).Decode(&chunk)
+	if progress := poll(upload.ID); progress.ChunksWritten != 1 || progress.BytesWritten != 11 {
+		t.Fatalf("unexpected progress after a chunk: %v", progress)
+	}
+
+	complete, _ := json.Marshal(completeRequest{Path: "a.txt", ChunkUploadIds: []string{chunk.ID}})
+	resp = send(http.MethodPost, fmt.Sprintf("%s/uploads/%s/complete", uploader.URL, upload.ID), complete)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 from complete, got %d", resp.StatusCode)
+	}
+	if progress := poll(upload.ID); progress.Status != UploadStatusComplete || !progress.IsComplete {
+		t.Fatalf("expected a complete status, got %v", progress)
+	}
+
+	resp = send(http.MethodGet, poller.URL+"/uploads/missing", nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown upload, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,126 @@
+package httpkit
+
+import (
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/usace/filesapi"
+)
+
+type RestHandlerConfig struct {
+
+	//store exposed by the api
+	Store filesapi.FileStore
+
+	//public url path the handler is mounted at
+	BasePath string
+
+	//store path that all client supplied paths are scoped beneath
+	Root string
+
+	//optional authorization hook, shared by every endpoint
+	Authorize AuthorizeFunction
+
+	//default and maximum page sizes for listings
+	PageSize    int32
+	MaxPageSize int32
+
+	//maximum presigned url lifetime in days
+	MaxPresignDays int
+
+	//maximum accepted upload chunk size in bytes.  Defaults to 100MB
+	MaxChunkSize int64
+
+	//optional store for upload progress.  Defaults to a MemoryProgressStore
+	ProgressStore ProgressStore
+}
+
+// RestHandler is an embeddable REST api over a store, combining the BrowseHandler and
+// UploadHandler endpoints with downloads:
+//
+//	GET      {base}/list?path=&page=&size=&filter=   paged directory listing of FileStoreResultObjects
+//	GET      {base}/info?path=                       object info
+//	GET      {base}/presign?path=&days=              presigned download url (stores implementing Presigner)
+//	DELETE   {base}/objects?path=                    recursive delete
+//	GET|HEAD {base}/download?path=&attachment=true   object download, with Range support
+//	*        {base}/uploads/...                      chunked uploads (see UploadHandler)
+//
+// The handler is a plain http.Handler so it can be mounted in any router,
+// including chi (r.Mount), Echo (echo.WrapHandler) and Gin (gin.WrapH).
+// A RestClient uses the api as a FileStore
+type RestHandler struct {
+	config  RestHandlerConfig
+	browse  *BrowseHandler
+	uploads *UploadHandler
+}
+
+func NewRestHandler(config RestHandlerConfig) (*RestHandler, error) {
+	if config.Store == nil {
+		return nil, errors.New("rest handler requires a store")
+	}
+	base := strings.TrimSuffix(config.BasePath, "/")
+	browse, err := NewBrowseHandler(BrowseHandlerConfig{
+		Store:          config.Store,
+		BasePath:       base,
+		Root:           config.Root,
+		Authorize:      config.Authorize,
+		PageSize:       config.PageSize,
+		MaxPageSize:    config.MaxPageSize,
+		MaxPresignDays: config.MaxPresignDays,
+	})
+	if err != nil {
+		return nil, err
+	}
+	uploads, err := NewUploadHandler(UploadHandlerConfig{
+		Store:         config.Store,
+		BasePath:      base + "/uploads",
+		Root:          config.Root,
+		Authorize:     config.Authorize,
+		MaxChunkSize:  config.MaxChunkSize,
+		ProgressStore: config.ProgressStore,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &RestHandler{config: config, browse: browse, uploads: uploads}, nil
+}
+
+func (rh *RestHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := routeSegments(rh.config.BasePath, r.URL.Path)
+	switch {
+	case len(segments) > 0 && segments[0] == "uploads":
+		rh.uploads.ServeHTTP(w, r)
+	case len(segments) == 1 && segments[0] == "download":
+		rh.download(w, r)
+	default:
+		rh.browse.ServeHTTP(w, r)
+	}
+}
+
+// Uploads returns the upload handler, for reading the progress of upload sessions
+func (rh *RestHandler) Uploads() *UploadHandler {
+	return rh.uploads
+}
+
+func (rh *RestHandler) download(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+	q := r.URL.Query()
+	objectPath := scopedPath(rh.config.Root, q.Get("path"))
+	if rh.config.Authorize != nil {
+		if err := rh.config.Authorize(r, OpRead, objectPath); err != nil {
+			writeError(w, http.StatusForbidden, err)
+			return
+		}
+	}
+	downloadName := ""
+	if q.Get("attachment") == "true" {
+		downloadName = path.Base(objectPath)
+	}
+	ServeObject(w, r, rh.config.Store, objectPath, downloadName)
+}
@@ -0,0 +1,114 @@
+package httpkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/usace/filesapi"
+)
+
+func TestRestHandler(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	handler, err := NewRestHandler(RestHandlerConfig{Store: store, BasePath: "/api/", Root: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	send := func(method string, url string, body []byte) *http.Response {
+		req, _ := http.NewRequest(method, server.URL+url, bytes.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := send(http.MethodPost, "/api/uploads", []byte(`{"path":"data/a.txt"}`))
+	upload := filesapi.UploadResult{}
+	json.NewDecoder(resp.Body).Decode(&upload)
+	resp = send(http.MethodPut, fmt.Sprintf("/api/uploads/%s/0?path=data/a.txt", upload.ID), []byte("HELLO WORLD"))
+	chunk := filesapi.UploadResult{}
+	json.NewDecoder(resp.Body).Decode(&chunk)
+	complete, _ := json.Marshal(completeRequest{Path: "data/a.txt", ChunkUploadIds: []string{chunk.ID}})
+	if resp = send(http.MethodPost, fmt.Sprintf("/api/uploads/%s/complete", upload.ID), complete); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 from complete, got %d", resp.StatusCode)
+	}
+
+	resp = send(http.MethodGet, "/api/list?path=data", nil)
+	var list map[string]any
+	json.NewDecoder(resp.Body).Decode(&list)
+	objects, _ := list["objects"].([]any)
+	if len(objects) != 1 || objects[0].(map[string]any)["fileName"] != "a.txt" {
+		t.Fatalf("expected the uploaded object to be listed with the FileStoreResultObject json names, got %v", list)
+	}
+
+	resp = send(http.MethodGet, "/api/download?path=data/a.txt&attachment=true", nil)
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "HELLO WORLD" || resp.Header.Get("Content-Disposition") == "" {
+		t.Fatalf("unexpected download %d %q", resp.StatusCode, body)
+	}
+
+	if resp = send(http.MethodGet, "/api/presign?path=data/a.txt", nil); resp.StatusCode != http.StatusNotImplemented {
+		t.Fatalf("expected block file stores to refuse presigning, got %d", resp.StatusCode)
+	}
+	if resp = send(http.MethodDelete, "/api/objects?path=data/a.txt", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 from delete, got %d", resp.StatusCode)
+	}
+	if _, err := os.Stat(filepath.Join(root, "data", "a.txt")); !os.IsNotExist(err) {
+		t.Fatal("expected the object to be deleted")
+	}
+	if resp = send(http.MethodGet, "/api/download?path=data/a.txt", nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for a deleted object, got %d", resp.StatusCode)
+	}
+}
+
+func TestRestHandlerDeleteRoot(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "a.txt"), []byte("HELLO WORLD"), 0644)
+	handler, err := NewRestHandler(RestHandlerConfig{Store: store, BasePath: "/api", Root: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodDelete, server.URL+"/api/objects?path=./", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected deleting ./ to be refused, got %d", resp.StatusCode)
+	}
+
+	client, err := NewRestClient(RestClientConfig{BaseUrl: server.URL + "/api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{".", "./", "a/.."} {
+		errs := client.DeleteObjects(filesapi.DeleteObjectInput{Paths: filesapi.PathConfig{Path: p}})
+		if len(errs) == 0 || errs[0] == nil {
+			t.Fatalf("expected the client delete of %q to fail", p)
+		}
+	}
+	if !filesapi.FileExists(store, filepath.Join(root, "a.txt")) {
+		t.Fatal("expected the root contents to survive")
+	}
+}
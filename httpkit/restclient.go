@@ -0,0 +1,522 @@
+package httpkit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/usace/filesapi"
+)
+
+// chunk size of RestClient puts.  Matches the default ChunkSize of block file stores
+const defaultClientChunkSize int = 10 * 1024 * 1024
+
+// ErrUnsupported is returned by RestClient operations the REST api does not expose
+var ErrUnsupported = errors.New("operation not supported by the rest api")
+
+// RequestSigner authenticates a request to a RestHandler (i.e. adds a bearer token or
+// an HMAC signature the service Authorize hook verifies)
+type RequestSigner func(r *http.Request) error
+
+// BearerToken signs requests with the token returned by a token source, which is
+// called for every request so short lived tokens can be refreshed
+func BearerToken(token func() (string, error)) RequestSigner {
+	return func(r *http.Request) error {
+		t, err := token()
+		if err != nil {
+			return err
+		}
+		r.Header.Set("Authorization", "Bearer "+t)
+		return nil
+	}
+}
+
+type RestClientConfig struct {
+
+	//url the RestHandler is mounted at (i.e. https://files.example.com/api)
+	BaseUrl string
+
+	//optional http client.  Defaults to http.DefaultClient
+	Client *http.Client
+
+	//optional signer called for every request
+	Sign RequestSigner
+
+	//size of the chunks objects are uploaded in.  Defaults to defaultClientChunkSize.
+	//Must not exceed the MaxChunkSize of the service, must be at least 5MB for S3 stores,
+	//and must equal the ChunkSize of block file stores, which write chunks at their offset
+	ChunkSize int
+}
+
+// RestClient is a FileStore using a store served by a RestHandler.  The service holds the
+// store credentials, so desktop tools can operate on buckets with their own credentials
+// (checked by the service Authorize hook) and never receive AWS keys.
+//
+// Paths are relative to the Root of the service.  Objects are uploaded in chunks with the
+// upload endpoints, and copies and moves stream the object through the client, since the
+// api has no server side copy
+type RestClient struct {
+	config RestClientConfig
+	client *http.Client
+}
+
+func NewRestClient(config RestClientConfig) (*RestClient, error) {
+	if config.BaseUrl == "" {
+		return nil, errors.New("rest client requires a base url")
+	}
+	if _, err := url.Parse(config.BaseUrl); err != nil {
+		return nil, err
+	}
+	if config.ChunkSize <= 0 {
+		config.ChunkSize = defaultClientChunkSize
+	}
+	client := config.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &RestClient{config: config, client: client}, nil
+}
+
+// returns the context for a call, honoring the timeout of the call options.
+// The cancel function must always be called
+func callContext(options *filesapi.CallOptions) (context.Context, context.CancelFunc) {
+	if options == nil || options.Timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), options.Timeout)
+}
+
+// sends a signed request to an endpoint beneath the base url.  Error responses are
+// converted to store errors, and the body of successful responses must be closed
+func (rc *RestClient) do(ctx context.Context, method string, endpoint string, query url.Values, body io.Reader, header http.Header) (*http.Response, error) {
+	u := strings.TrimSuffix(rc.config.BaseUrl, "/") + "/" + endpoint
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if rc.config.Sign != nil {
+		if err = rc.config.Sign(req); err != nil {
+			return nil, err
+		}
+	}
+	resp, err := rc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+		return nil, responseError(query.Get("path"), resp)
+	}
+	return resp, nil
+}
+
+// sends a request and decodes the json response
+func (rc *RestClient) doJSON(ctx context.Context, method string, endpoint string, query url.Values, request any, response any) error {
+	var body io.Reader
+	header := http.Header{}
+	if request != nil {
+		data, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+		header.Set("Content-Type", "application/json")
+	}
+	resp, err := rc.do(ctx, method, endpoint, query, body, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if response == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
+
+// converts an error response of the service to a store error
+func responseError(p string, resp *http.Response) error {
+	message := resp.Status
+	er := errorResponse{}
+	if json.NewDecoder(resp.Body).Decode(&er) == nil && er.Error != "" {
+		message = er.Error
+	}
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return filesapi.NewFileNotFoundError(p)
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return fmt.Errorf("%w: %s", fs.ErrPermission, message)
+	}
+	return fmt.Errorf("%s failed: %s", p, message)
+}
+
+func (rc *RestClient) ResourceName() string {
+	return rc.config.BaseUrl
+}
+
+func (rc *RestClient) ListDir(input filesapi.ListDirInput) (*[]filesapi.FileStoreResultObject, error) {
+	ctx, cancel := callContext(input.Options)
+	defer cancel()
+	query := url.Values{"path": {input.Path.Path}, "page": {strconv.Itoa(input.Page)}}
+	if input.Size > 0 {
+		query.Set("size", strconv.Itoa(int(input.Size)))
+	}
+	if input.Filter != "" {
+		query.Set("filter", input.Filter)
+	}
+	list := ListResponse{}
+	if err := rc.doJSON(ctx, http.MethodGet, "list", query, nil, &list); err != nil {
+		return nil, err
+	}
+	return &list.Objects, nil
+}
+
+func (rc *RestClient) GetDir(path filesapi.PathConfig) (*[]filesapi.FileStoreResultObject, error) {
+	return rc.ListDir(filesapi.ListDirInput{Path: path})
+}
+
+func (rc *RestClient) GetObjectInfo(pc filesapi.PathConfig) (fs.FileInfo, error) {
+	info := ObjectInfo{}
+	if err := rc.doJSON(context.Background(), http.MethodGet, "info", url.Values{"path": {pc.Path}}, nil, &info); err != nil {
+		return nil, err
+	}
+	return &restFileInfo{info}, nil
+}
+
+func (rc *RestClient) GetObject(input filesapi.GetObjectInput) (io.ReadCloser, error) {
+	header := http.Header{}
+	if input.Range != "" {
+		header.Set("Range", input.Range)
+	}
+	//objects are returned as stored, so gzip encoded objects are only decoded on request
+	header.Set("Accept-Encoding", "identity")
+	ctx, cancel := callContext(input.Options)
+	resp, err := rc.do(ctx, http.MethodGet, "download", url.Values{"path": {input.Path.Path}}, nil, header)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	//the call timeout also covers reading the body
+	body := &cancelReadCloser{resp.Body, cancel}
+	if input.Decompress && input.Range == "" && strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			body.Close()
+			return nil, err
+		}
+		return &gzipReadCloser{gz, body}, nil
+	}
+	return body, nil
+}
+
+// gzipReadCloser closes both the gzip reader and the response body
+type gzipReadCloser struct {
+	*gzip.Reader
+	body io.ReadCloser
+}
+
+func (g *gzipReadCloser) Close() error {
+	g.Reader.Close()
+	return g.body.Close()
+}
+
+// PutObject uploads the source in chunks of ChunkSize.  IfNoneMatch puts check the
+// destination before uploading, so unlike S3 conditional puts they are not atomic
+func (rc *RestClient) PutObject(input filesapi.PutObjectInput) (*filesapi.FileOperationOutput, error) {
+	if input.IfNoneMatch {
+		_, err := rc.GetObjectInfo(input.Dest)
+		if err == nil {
+			return nil, fmt.Errorf("%w: %s", filesapi.ErrObjectExists, input.Dest.Path)
+		}
+		var fnf *filesapi.FileNotFoundError
+		if !errors.As(err, &fnf) {
+			return nil, err
+		}
+	}
+	reader, err := input.Source.GetReader()
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := reader.(io.Closer); ok && input.Source.Reader == nil {
+		defer closer.Close()
+	}
+	upload, err := rc.InitializeObjectUpload(filesapi.UploadConfig{ObjectPath: input.Dest.Path})
+	if err != nil {
+		return nil, err
+	}
+	chunkIds := []string{}
+	buf := make([]byte, rc.config.ChunkSize)
+	for chunk := int32(0); ; chunk++ {
+		n, rerr := io.ReadFull(reader, buf)
+		if rerr != nil && rerr != io.EOF && rerr != io.ErrUnexpectedEOF {
+			rc.AbortObjectUpload(filesapi.UploadConfig{ObjectPath: input.Dest.Path, UploadId: upload.ID})
+			return nil, rerr
+		}
+		//empty objects are uploaded as a single empty chunk
+		if n > 0 || chunk == 0 {
+			result, err := rc.WriteChunk(filesapi.UploadConfig{
+				ObjectPath: input.Dest.Path,
+				ChunkId:    chunk,
+				UploadId:   upload.ID,
+				Data:       buf[:n],
+			})
+			if err != nil {
+				rc.AbortObjectUpload(filesapi.UploadConfig{ObjectPath: input.Dest.Path, UploadId: upload.ID})
+				return nil, err
+			}
+			chunkIds = append(chunkIds, result.ID)
+		}
+		if rerr != nil {
+			break
+		}
+	}
+	err = rc.CompleteObjectUpload(filesapi.CompletedObjectUploadConfig{
+		UploadId:       upload.ID,
+		ObjectPath:     input.Dest.Path,
+		ChunkUploadIds: chunkIds,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &filesapi.FileOperationOutput{}, nil
+}
+
+// CopyObject streams the object through the client.  Verified copies compare the sizes
+// of the source and the copy
+func (rc *RestClient) CopyObject(input filesapi.CopyObjectInput) error {
+	reader, err := rc.GetObject(filesapi.GetObjectInput{Path: input.Src, Options: input.Options})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	_, err = rc.PutObject(filesapi.PutObjectInput{
+		Source:  filesapi.ObjectSource{Reader: reader},
+		Dest:    input.Dest,
+		Options: input.Options,
+	})
+	if err != nil {
+		return err
+	}
+	if input.Verify {
+		if err = rc.verifyCopy(input); err != nil {
+			return err
+		}
+	}
+	if input.Progress != nil {
+		return input.Progress(filesapi.ProgressData{Index: 0, Max: 1, Value: input.Dest.Path})
+	}
+	return nil
+}
+
+func (rc *RestClient) verifyCopy(input filesapi.CopyObjectInput) error {
+	src, err := rc.GetObjectInfo(input.Src)
+	if err != nil {
+		return err
+	}
+	dest, err := rc.GetObjectInfo(input.Dest)
+	if err != nil {
+		return err
+	}
+	if src.Size() != dest.Size() {
+		return &filesapi.CopyMismatchError{
+			Src:      input.Src.Path,
+			Dest:     input.Dest.Path,
+			Property: "size",
+			Expected: strconv.FormatInt(src.Size(), 10),
+			Actual:   strconv.FormatInt(dest.Size(), 10),
+		}
+	}
+	return nil
+}
+
+// MoveObject copies the object, then deletes the source
+func (rc *RestClient) MoveObject(input filesapi.MoveObjectInput) error {
+	if path.Clean(input.Src.Path) == path.Clean(input.Dest.Path) {
+		return nil
+	}
+	err := rc.CopyObject(filesapi.CopyObjectInput{
+		Src:      input.Src,
+		Dest:     input.Dest,
+		Progress: input.Progress,
+		Verify:   input.Verify,
+		Options:  input.Options,
+	})
+	if err != nil {
+		return err
+	}
+	for _, err := range rc.DeleteObjects(filesapi.DeleteObjectInput{Paths: input.Src, Options: input.Options}) {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rc *RestClient) InitializeObjectUpload(u filesapi.UploadConfig) (filesapi.UploadResult, error) {
+	result := filesapi.UploadResult{}
+	err := rc.doJSON(context.Background(), http.MethodPost, "uploads", url.Values{"path": {u.ObjectPath}}, initializeRequest{Path: u.ObjectPath}, &result)
+	return result, err
+}
+
+func (rc *RestClient) WriteChunk(u filesapi.UploadConfig) (filesapi.UploadResult, error) {
+	result := filesapi.UploadResult{}
+	endpoint := fmt.Sprintf("uploads/%s/%d", url.PathEscape(u.UploadId), u.ChunkId)
+	resp, err := rc.do(context.Background(), http.MethodPut, endpoint, url.Values{"path": {u.ObjectPath}}, bytes.NewReader(u.Data), nil)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	return result, err
+}
+
+func (rc *RestClient) CompleteObjectUpload(u filesapi.CompletedObjectUploadConfig) error {
+	endpoint := fmt.Sprintf("uploads/%s/complete", url.PathEscape(u.UploadId))
+	return rc.doJSON(context.Background(), http.MethodPost, endpoint, url.Values{"path": {u.ObjectPath}}, completeRequest{
+		Path:           u.ObjectPath,
+		ChunkUploadIds: u.ChunkUploadIds,
+		ExpectedHash:   u.ExpectedHash,
+		HashAlgorithm:  string(u.HashAlgorithm),
+	}, nil)
+}
+
+func (rc *RestClient) AbortObjectUpload(u filesapi.UploadConfig) error {
+	endpoint := "uploads/" + url.PathEscape(u.UploadId)
+	return rc.doJSON(context.Background(), http.MethodDelete, endpoint, url.Values{"path": {u.ObjectPath}}, nil, nil)
+}
+
+func (rc *RestClient) ListUploadParts(u filesapi.UploadConfig) ([]filesapi.UploadPart, error) {
+	return nil, ErrUnsupported
+}
+
+func (rc *RestClient) DeleteObjects(input filesapi.DeleteObjectInput) []error {
+	ctx, cancel := callContext(input.Options)
+	defer cancel()
+	resp, err := rc.do(ctx, http.MethodDelete, "objects", url.Values{"path": input.Paths.All()}, nil, nil)
+	if err != nil {
+		return []error{err}
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Walk lists the directories beneath a path, visiting objects in lexicographic order
+func (rc *RestClient) Walk(input filesapi.WalkInput, vistorFunction filesapi.FileVisitFunction) error {
+	index := 0
+	return rc.walkDir(strings.TrimSuffix(input.Path.Path, "/"), input, &index, vistorFunction)
+}
+
+func (rc *RestClient) walkDir(dir string, input filesapi.WalkInput, index *int, vistorFunction filesapi.FileVisitFunction) error {
+	entries := []filesapi.FileStoreResultObject{}
+	for page := 0; ; page++ {
+		results, err := rc.ListDir(filesapi.ListDirInput{Path: filesapi.PathConfig{Path: dir}, Page: page, Size: filesapi.DEFAULTMAXKEYS})
+		if err != nil {
+			return err
+		}
+		entries = append(entries, *results...)
+		if len(*results) < int(filesapi.DEFAULTMAXKEYS) {
+			break
+		}
+	}
+	//directories sort by their trailing slash, so objects are visited in path order
+	key := func(entry filesapi.FileStoreResultObject) string {
+		if entry.IsDir {
+			return entry.Name + "/"
+		}
+		return entry.Name
+	}
+	sort.Slice(entries, func(i, j int) bool { return key(entries[i]) < key(entries[j]) })
+	for _, entry := range entries {
+		p := dir + "/" + entry.Name
+		if entry.IsDir {
+			//every path beneath the directory sorts before a later checkpoint outside it
+			if input.StartAfter > p+"/" && !strings.HasPrefix(input.StartAfter, p+"/") {
+				continue
+			}
+			if err := rc.walkDir(p, input, index, vistorFunction); err != nil {
+				return err
+			}
+			continue
+		}
+		if input.StartAfter != "" && p <= input.StartAfter {
+			continue
+		}
+		if input.Progress != nil {
+			if err := input.Progress(filesapi.ProgressData{Index: *index, Max: -1, Value: p}); err != nil {
+				return err
+			}
+		}
+		*index++
+		size, _ := strconv.ParseInt(entry.Size, 10, 64)
+		info := &restFileInfo{ObjectInfo{Name: entry.Name, Path: p, Size: size, Modified: entry.Modified}}
+		if err := vistorFunction(p, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restFileInfo is the fs.FileInfo of objects reported by the info endpoint
+type restFileInfo struct {
+	info ObjectInfo
+}
+
+func (fi *restFileInfo) Name() string {
+	if fi.info.Name == "" {
+		return path.Base(fi.info.Path)
+	}
+	return fi.info.Name
+}
+
+func (fi *restFileInfo) Size() int64 {
+	return fi.info.Size
+}
+
+func (fi *restFileInfo) Mode() fs.FileMode {
+	if fi.info.IsDir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+func (fi *restFileInfo) ModTime() time.Time {
+	return fi.info.Modified
+}
+
+func (fi *restFileInfo) IsDir() bool {
+	return fi.info.IsDir
+}
+
+func (fi *restFileInfo) Sys() any {
+	return nil
+}
+
+// closes the response body, then cancels the request context
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelReadCloser) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+var _ filesapi.FileStore = (*RestClient)(nil)
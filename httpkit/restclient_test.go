@@ -0,0 +1,124 @@
+package httpkit
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/usace/filesapi"
+)
+
+func TestRestClient(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{ChunkSize: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	handler, err := NewRestHandler(RestHandlerConfig{
+		Store:    store,
+		BasePath: "/api",
+		Root:     root,
+		Authorize: func(r *http.Request, op Operation, path string) error {
+			if r.Header.Get("Authorization") != "Bearer secret" {
+				return &StatusError{Status: http.StatusUnauthorized, Message: "unauthorized"}
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	client, err := NewRestClient(RestClientConfig{
+		BaseUrl:   server.URL + "/api",
+		Sign:      BearerToken(func() (string, error) { return "secret", nil }),
+		ChunkSize: 4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var _ filesapi.FileStore = client
+
+	_, err = client.PutObject(filesapi.PutObjectInput{
+		Source: filesapi.ObjectSource{Data: []byte("HELLO WORLD")},
+		Dest:   filesapi.PathConfig{Path: "data/a.txt"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(root, "data", "a.txt")); string(data) != "HELLO WORLD" {
+		t.Fatalf("expected the chunks to be assembled, got %q", data)
+	}
+	info, err := client.GetObjectInfo(filesapi.PathConfig{Path: "data/a.txt"})
+	if err != nil || info.Size() != 11 || info.Name() != "a.txt" {
+		t.Fatalf("unexpected object info %v: %v", info, err)
+	}
+	reader, err := client.GetObject(filesapi.GetObjectInput{Path: filesapi.PathConfig{Path: "data/a.txt"}, Range: "bytes=6-10"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(data) != "WORLD" {
+		t.Fatalf("unexpected range %q", data)
+	}
+
+	_, err = client.PutObject(filesapi.PutObjectInput{
+		Source:      filesapi.ObjectSource{Data: []byte("AGAIN")},
+		Dest:        filesapi.PathConfig{Path: "data/a.txt"},
+		IfNoneMatch: true,
+	})
+	if !errors.Is(err, filesapi.ErrObjectExists) {
+		t.Fatalf("expected ErrObjectExists, got %v", err)
+	}
+	err = client.CopyObject(filesapi.CopyObjectInput{
+		Src:    filesapi.PathConfig{Path: "data/a.txt"},
+		Dest:   filesapi.PathConfig{Path: "data/nested/b.txt"},
+		Verify: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.MoveObject(filesapi.MoveObjectInput{
+		Src:  filesapi.PathConfig{Path: "data/a.txt"},
+		Dest: filesapi.PathConfig{Path: "data/c.txt"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fnf *filesapi.FileNotFoundError
+	if _, err = client.GetObjectInfo(filesapi.PathConfig{Path: "data/a.txt"}); !errors.As(err, &fnf) {
+		t.Fatalf("expected the moved source to be not found, got %v", err)
+	}
+
+	walked := []string{}
+	err = client.Walk(filesapi.WalkInput{Path: filesapi.PathConfig{Path: "data"}}, func(path string, file fs.FileInfo) error {
+		walked = append(walked, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(walked) != 2 || walked[0] != "data/c.txt" || walked[1] != "data/nested/b.txt" {
+		t.Fatalf("unexpected walk %v", walked)
+	}
+
+	if errs := client.DeleteObjects(filesapi.DeleteObjectInput{Paths: filesapi.PathConfig{Path: "data/nested"}}); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if _, err := os.Stat(filepath.Join(root, "data", "nested", "b.txt")); !os.IsNotExist(err) {
+		t.Fatal("expected the nested object to be deleted")
+	}
+
+	unsigned, _ := NewRestClient(RestClientConfig{BaseUrl: server.URL + "/api"})
+	if _, err = unsigned.GetObjectInfo(filesapi.PathConfig{Path: "data/c.txt"}); !errors.Is(err, fs.ErrPermission) {
+		t.Fatalf("expected unsigned requests to be refused, got %v", err)
+	}
+}
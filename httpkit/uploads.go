@@ -0,0 +1,453 @@
+package httpkit
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/usace/filesapi"
+)
+
+const (
+	defaultMaxChunkSize         int64         = 100 * 1024 * 1024
+	defaultProgressTTL          time.Duration = 24 * time.Hour
+	defaultCompletedProgressTTL time.Duration = time.Minute
+)
+
+var errUploadNotOpen = errors.New("upload is not open")
+
+type UploadHandlerConfig struct {
+
+	//store receiving the uploads
+	Store filesapi.FileStore
+
+	//public url path the handler is mounted at
+	BasePath string
+
+	//store path that all client supplied upload paths are scoped beneath
+	Root string
+
+	//optional authorization hook
+	Authorize AuthorizeFunction
+
+	//maximum accepted chunk size in bytes.  Defaults to 100MB
+	MaxChunkSize int64
+
+	//optional store for upload progress.  Defaults to a MemoryProgressStore.
+	//Use a shared store (i.e. FileStoreProgressStore) when uploads are served by several instances
+	ProgressStore ProgressStore
+
+	//open uploads without a chunk for longer than the ttl expire and are evicted when next
+	//looked up.  Defaults to 24 hours
+	ProgressTTL time.Duration
+
+	//completed and failed uploads are kept this long so clients can poll the final status.
+	//Defaults to 1 minute
+	CompletedProgressTTL time.Duration
+
+	//optional time source for tests
+	Clock filesapi.Clock
+}
+
+// UploadProgress records the chunks received for an upload session
+type UploadProgress struct {
+	UploadId      string `json:"uploadId"`
+	Path          string `json:"path"`
+	ChunksWritten int    `json:"chunksWritten"`
+	BytesWritten  int64  `json:"bytesWritten"`
+	IsComplete    bool   `json:"isComplete"`
+
+	//one of the UploadStatus states.  Assembling while the chunks are combined into the object
+	Status string `json:"status"`
+
+	//reason a failed upload could not be assembled
+	Error string `json:"error,omitempty"`
+
+	//object and part size of planned uploads, whose chunks are validated against the plan
+	Size     *int64 `json:"size,omitempty"`
+	PartSize int64  `json:"partSize,omitempty"`
+
+	//time the progress is evicted unless the upload makes progress
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type initializeRequest struct {
+	Path string `json:"path"`
+
+	//optional object size and part size.  When the size is provided the upload is planned
+	//with filesapi.PlanChunks, and chunks that do not match the plan are rejected
+	Size     *int64 `json:"size,omitempty"`
+	PartSize int64  `json:"partSize,omitempty"`
+}
+
+type initializeResponse struct {
+	filesapi.UploadResult
+	Plan *filesapi.ChunkPlan `json:"plan,omitempty"`
+}
+
+type completeRequest struct {
+	//ignored.  Uploads complete at the path they were initialized with
+	Path           string   `json:"path"`
+	ChunkUploadIds []string `json:"chunkUploadIds"`
+	ExpectedHash   string   `json:"expectedHash"`
+	HashAlgorithm  string   `json:"hashAlgorithm"`
+}
+
+// UploadHandler serves the chunked upload endpoints:
+//
+//	POST   {base}/                       initialize an upload. body: {"path":"...","size":n,"partSize":n}
+//	PUT    {base}/{uploadId}/{chunkId}   write a chunk. body: raw chunk bytes
+//	POST   {base}/{uploadId}/complete    complete an upload. body: {"chunkUploadIds":[...]}
+//	DELETE {base}/{uploadId}             abort an upload
+//	GET    {base}/{uploadId}             upload progress
+//
+// Uploads are bound to the path they were initialized with: chunks, completes, and aborts are
+// authorized and written against the path recorded for the upload id, and unknown, expired,
+// or closed uploads are not found.  Uploads initialized with a size answer with the chunk
+// plan, and chunks that do not match the plan are rejected.  Chunks carrying a Content-MD5
+// header are validated before they are written.  Progress is kept in the ProgressStore, so
+// clients can poll it while an upload is assembled
+type UploadHandler struct {
+	config UploadHandlerConfig
+
+	//serializes progress updates made by this handler
+	mu sync.Mutex
+}
+
+func NewUploadHandler(config UploadHandlerConfig) (*UploadHandler, error) {
+	if config.Store == nil {
+		return nil, errors.New("upload handler requires a store")
+	}
+	if config.MaxChunkSize <= 0 {
+		config.MaxChunkSize = defaultMaxChunkSize
+	}
+	if config.ProgressStore == nil {
+		config.ProgressStore = NewMemoryProgressStore()
+	}
+	if config.ProgressTTL <= 0 {
+		config.ProgressTTL = defaultProgressTTL
+	}
+	if config.CompletedProgressTTL <= 0 {
+		config.CompletedProgressTTL = defaultCompletedProgressTTL
+	}
+	return &UploadHandler{config: config}, nil
+}
+
+func (uh *UploadHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := routeSegments(uh.config.BasePath, r.URL.Path)
+	switch {
+	case r.Method == http.MethodPost && len(segments) == 0:
+		uh.initialize(w, r)
+	case r.Method == http.MethodPut && len(segments) == 2:
+		uh.writeChunk(w, r, segments[0], segments[1])
+	case r.Method == http.MethodPost && len(segments) == 2 && segments[1] == "complete":
+		uh.complete(w, r, segments[0])
+	case r.Method == http.MethodDelete && len(segments) == 1:
+		uh.abort(w, r, segments[0])
+	case r.Method == http.MethodGet && len(segments) == 1:
+		uh.status(w, r, segments[0])
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+// returns the progress of an upload session
+func (uh *UploadHandler) Progress(uploadId string) (UploadProgress, bool) {
+	p, ok, err := uh.lookup(uploadId)
+	if err != nil {
+		return UploadProgress{}, false
+	}
+	return p, ok
+}
+
+// returns the stored progress of an upload, evicting it once it has expired
+func (uh *UploadHandler) lookup(uploadId string) (UploadProgress, bool, error) {
+	p, ok, err := uh.config.ProgressStore.Get(uploadId)
+	if err != nil || !ok {
+		return p, false, err
+	}
+	if !p.ExpiresAt.IsZero() && !uh.config.Clock.Now().Before(p.ExpiresAt) {
+		return p, false, uh.config.ProgressStore.Delete(uploadId)
+	}
+	return p, true, nil
+}
+
+// returns the progress of an upload still accepting chunks.  Unknown, expired, and
+// completed uploads are errUploadNotOpen
+func (uh *UploadHandler) session(uploadId string) (UploadProgress, error) {
+	p, ok, err := uh.lookup(uploadId)
+	if err != nil {
+		return p, err
+	}
+	if !ok || p.Status != UploadStatusUploading {
+		return p, fmt.Errorf("upload %s: %w", uploadId, errUploadNotOpen)
+	}
+	return p, nil
+}
+
+// extends the expiration of an upload for its status
+func (uh *UploadHandler) expire(p *UploadProgress) {
+	ttl := uh.config.ProgressTTL
+	if p.Status == UploadStatusComplete || p.Status == UploadStatusFailed {
+		ttl = uh.config.CompletedProgressTTL
+	}
+	p.ExpiresAt = uh.config.Clock.Now().Add(ttl)
+}
+
+// applies an update to the stored progress of an upload.  Unknown uploads are ignored
+func (uh *UploadHandler) updateProgress(uploadId string, update func(p *UploadProgress)) error {
+	uh.mu.Lock()
+	defer uh.mu.Unlock()
+	p, ok, err := uh.lookup(uploadId)
+	if err != nil || !ok {
+		return err
+	}
+	update(&p)
+	uh.expire(&p)
+	return uh.config.ProgressStore.Put(p)
+}
+
+// writes the error of a session lookup
+func writeSessionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errUploadNotOpen) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err)
+}
+
+func (uh *UploadHandler) authorize(r *http.Request, path string) error {
+	if uh.config.Authorize == nil {
+		return nil
+	}
+	return uh.config.Authorize(r, OpUpload, path)
+}
+
+func (uh *UploadHandler) initialize(w http.ResponseWriter, r *http.Request) {
+	req := initializeRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Path == "" {
+		writeError(w, http.StatusBadRequest, errors.New("invalid initialize request"))
+		return
+	}
+	path := scopedPath(uh.config.Root, req.Path)
+	if err := uh.authorize(r, path); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	var plan *filesapi.ChunkPlan
+	if req.Size != nil {
+		var err error
+		if plan, err = filesapi.PlanChunks(*req.Size, req.PartSize); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if plan.PartSize > uh.config.MaxChunkSize && plan.Count > 1 {
+			writeError(w, http.StatusBadRequest, errors.New("part size exceeds the maximum chunk size"))
+			return
+		}
+	}
+	result, err := uh.config.Store.InitializeObjectUpload(filesapi.UploadConfig{ObjectPath: path})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	progress := UploadProgress{
+		UploadId: result.ID,
+		Path:     path,
+		Status:   UploadStatusUploading,
+	}
+	if plan != nil {
+		progress.Size = &plan.Size
+		progress.PartSize = plan.PartSize
+	}
+	uh.expire(&progress)
+	if err = uh.config.ProgressStore.Put(progress); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, initializeResponse{UploadResult: result, Plan: plan})
+}
+
+// validates a chunk against the plan of a planned upload
+func validateChunk(p UploadProgress, chunkId int32, size int64) error {
+	if p.Size == nil {
+		return nil
+	}
+	plan, err := filesapi.PlanChunks(*p.Size, p.PartSize)
+	if err != nil {
+		return err
+	}
+	return plan.ValidateChunk(chunkId, size)
+}
+
+func (uh *UploadHandler) writeChunk(w http.ResponseWriter, r *http.Request, uploadId string, chunk string) {
+	chunkId, err := strconv.ParseInt(chunk, 10, 32)
+	if err != nil || chunkId < 0 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid chunk id: %s", chunk))
+		return
+	}
+	p, err := uh.session(uploadId)
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+	if err = uh.authorize(r, p.Path); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	if r.ContentLength > uh.config.MaxChunkSize {
+		writeError(w, http.StatusRequestEntityTooLarge, errors.New("chunk exceeds the maximum chunk size"))
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, uh.config.MaxChunkSize+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if int64(len(data)) > uh.config.MaxChunkSize {
+		writeError(w, http.StatusRequestEntityTooLarge, errors.New("chunk exceeds the maximum chunk size"))
+		return
+	}
+	if r.ContentLength >= 0 && int64(len(data)) != r.ContentLength {
+		writeError(w, http.StatusBadRequest, errors.New("chunk length does not match Content-Length"))
+		return
+	}
+	if err = validateChunk(p, int32(chunkId), int64(len(data))); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, filesapi.ErrInvalidChunkPlan) {
+			status = http.StatusBadRequest
+		}
+		writeError(w, status, err)
+		return
+	}
+	if contentMd5 := r.Header.Get("Content-MD5"); contentMd5 != "" {
+		sum := md5.Sum(data)
+		expected, err := base64.StdEncoding.DecodeString(contentMd5)
+		if err != nil || !bytes.Equal(expected, sum[:]) {
+			writeError(w, http.StatusBadRequest, errors.New("chunk does not match Content-MD5"))
+			return
+		}
+	}
+	result, err := uh.config.Store.WriteChunk(filesapi.UploadConfig{
+		ObjectPath: p.Path,
+		ChunkId:    int32(chunkId),
+		UploadId:   uploadId,
+		Data:       data,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	err = uh.updateProgress(uploadId, func(p *UploadProgress) {
+		p.ChunksWritten++
+		p.BytesWritten += int64(result.WriteSize)
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (uh *UploadHandler) complete(w http.ResponseWriter, r *http.Request, uploadId string) {
+	req := completeRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid complete request"))
+		return
+	}
+	p, err := uh.session(uploadId)
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+	if err = uh.authorize(r, p.Path); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	err = uh.updateProgress(uploadId, func(p *UploadProgress) {
+		p.Status = UploadStatusAssembling
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	err = uh.config.Store.CompleteObjectUpload(filesapi.CompletedObjectUploadConfig{
+		UploadId:       uploadId,
+		ObjectPath:     p.Path,
+		ChunkUploadIds: req.ChunkUploadIds,
+		ExpectedHash:   req.ExpectedHash,
+		HashAlgorithm:  filesapi.HashAlgorithm(req.HashAlgorithm),
+	})
+	progressErr := uh.updateProgress(uploadId, func(p *UploadProgress) {
+		if err != nil {
+			p.Status = UploadStatusFailed
+			p.Error = err.Error()
+			return
+		}
+		p.Status = UploadStatusComplete
+		p.IsComplete = true
+	})
+	var mismatch *filesapi.HashMismatchError
+	if errors.As(err, &mismatch) {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if err == nil {
+		err = progressErr
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, filesapi.UploadResult{ID: uploadId, IsComplete: true})
+}
+
+func (uh *UploadHandler) abort(w http.ResponseWriter, r *http.Request, uploadId string) {
+	p, err := uh.session(uploadId)
+	if err != nil {
+		writeSessionError(w, err)
+		return
+	}
+	if err = uh.authorize(r, p.Path); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	err = uh.config.Store.AbortObjectUpload(filesapi.UploadConfig{
+		ObjectPath: p.Path,
+		UploadId:   uploadId,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if err = uh.config.ProgressStore.Delete(uploadId); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (uh *UploadHandler) status(w http.ResponseWriter, r *http.Request, uploadId string) {
+	p, ok, err := uh.lookup(uploadId)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("upload %s not found", uploadId))
+		return
+	}
+	if err := uh.authorize(r, p.Path); err != nil {
+		writeError(w, http.StatusForbidden, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, p)
+}
@@ -0,0 +1,238 @@
+package httpkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/usace/filesapi"
+)
+
+func TestChunkedUpload(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{ChunkSize: 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	handler, err := NewUploadHandler(UploadHandlerConfig{
+		Store:    store,
+		BasePath: "/uploads",
+		Root:     root,
+		Authorize: func(r *http.Request, op Operation, path string) error {
+			if r.Header.Get("Authorization") == "" {
+				return errors.New("missing credentials")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	send := func(method string, url string, body []byte) *http.Response {
+		req, _ := http.NewRequest(method, server.URL+url, bytes.NewReader(body))
+		req.Header.Set("Authorization", "test")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	resp, err := http.Post(server.URL+"/uploads", "application/json", bytes.NewReader([]byte(`{"path":"a/b.txt"}`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected an unauthorized request to be rejected, got %d", resp.StatusCode)
+	}
+
+	resp = send(http.MethodPost, "/uploads", []byte(`{"path":"a/b.txt"}`))
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201 from initialize, got %d", resp.StatusCode)
+	}
+	upload := filesapi.UploadResult{}
+	json.NewDecoder(resp.Body).Decode(&upload)
+
+	etags := []string{}
+	for i, chunk := range []string{"HELLO ", "WORLD"} {
+		resp = send(http.MethodPut, fmt.Sprintf("/uploads/%s/%d?path=a/b.txt", upload.ID, i), []byte(chunk))
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected 200 from chunk %d, got %d", i, resp.StatusCode)
+		}
+		result := filesapi.UploadResult{}
+		json.NewDecoder(resp.Body).Decode(&result)
+		etags = append(etags, result.ID)
+	}
+
+	complete, _ := json.Marshal(completeRequest{Path: "a/b.txt", ChunkUploadIds: etags})
+	resp = send(http.MethodPost, fmt.Sprintf("/uploads/%s/complete", upload.ID), complete)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 from complete, got %d", resp.StatusCode)
+	}
+
+	progress, ok := handler.Progress(upload.ID)
+	if !ok || progress.ChunksWritten != 2 || progress.BytesWritten != 11 || !progress.IsComplete {
+		t.Fatalf("unexpected upload progress: %v", progress)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "a/b.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "HELLO WORLD" {
+		t.Fatalf("expected HELLO WORLD, got %s", data)
+	}
+}
+
+func TestPlannedUpload(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := NewUploadHandler(UploadHandlerConfig{Store: store, BasePath: "/uploads", Root: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	send := func(method string, url string, body []byte) *http.Response {
+		req, _ := http.NewRequest(method, server.URL+url, bytes.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp := send(http.MethodPost, "/uploads", []byte(`{"path":"a.txt","size":20000000,"partSize":1024}`)); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected parts below the S3 minimum to be rejected, got %d", resp.StatusCode)
+	}
+	resp := send(http.MethodPost, "/uploads", []byte(`{"path":"a.txt","size":11}`))
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201 from initialize, got %d", resp.StatusCode)
+	}
+	upload := initializeResponse{}
+	json.NewDecoder(resp.Body).Decode(&upload)
+	if upload.Plan == nil || upload.Plan.Count != 1 || upload.Plan.Chunks[0].Size != 11 {
+		t.Fatalf("expected the chunk plan in the response, got %v", upload.Plan)
+	}
+	if resp = send(http.MethodPut, fmt.Sprintf("/uploads/%s/0?path=a.txt", upload.ID), []byte("HELLO")); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected a chunk shorter than planned to be rejected, got %d", resp.StatusCode)
+	}
+	if resp = send(http.MethodPut, fmt.Sprintf("/uploads/%s/1?path=a.txt", upload.ID), []byte("HELLO WORLD")); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected an unplanned chunk to be rejected, got %d", resp.StatusCode)
+	}
+	if resp = send(http.MethodPut, fmt.Sprintf("/uploads/%s/0?path=a.txt", upload.ID), []byte("HELLO WORLD")); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the planned chunk to be written, got %d", resp.StatusCode)
+	}
+}
+
+func TestUploadSessions(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "finished.txt"), []byte("HELLO WORLD"), 0644)
+	now := time.Now()
+	authorized := []string{}
+	handler, err := NewUploadHandler(UploadHandlerConfig{
+		Store:    store,
+		BasePath: "/uploads",
+		Root:     root,
+		Authorize: func(r *http.Request, op Operation, path string) error {
+			authorized = append(authorized, path)
+			return nil
+		},
+		ProgressTTL: time.Hour,
+		Clock:       func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	send := func(method string, url string, body []byte) *http.Response {
+		req, _ := http.NewRequest(method, server.URL+url, bytes.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	initialize := func(path string) string {
+		resp := send(http.MethodPost, "/uploads", []byte(`{"path":"`+path+`"}`))
+		upload := filesapi.UploadResult{}
+		json.NewDecoder(resp.Body).Decode(&upload)
+		return upload.ID
+	}
+	finished := func() bool {
+		return filesapi.FileExists(store, filepath.Join(root, "finished.txt"))
+	}
+
+	if resp := send(http.MethodPut, "/uploads/missing/0?path=finished.txt", []byte("HELLO")); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 writing to an unknown upload, got %d", resp.StatusCode)
+	}
+	if resp := send(http.MethodDelete, "/uploads/missing?path=finished.txt", nil); resp.StatusCode != http.StatusNotFound || !finished() {
+		t.Fatalf("expected 404 aborting an unknown upload, got %d", resp.StatusCode)
+	}
+
+	//chunks and aborts use the path the upload was initialized with
+	id := initialize("a.txt")
+	authorized = authorized[:0]
+	if resp := send(http.MethodPut, "/uploads/"+id+"/0?path=finished.txt", []byte("BYE")); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 writing a chunk, got %d", resp.StatusCode)
+	}
+	if resp := send(http.MethodDelete, "/uploads/"+id+"?path=finished.txt", nil); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204 aborting an upload, got %d", resp.StatusCode)
+	}
+	scoped := filepath.Join(root, "a.txt")
+	if len(authorized) != 2 || authorized[0] != scoped || authorized[1] != scoped {
+		t.Fatalf("expected the session path to be authorized, got %v", authorized)
+	}
+	if !finished() || filesapi.FileExists(store, scoped) {
+		t.Fatal("expected the abort to remove only the session object")
+	}
+	if resp := send(http.MethodGet, "/uploads/"+id, nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected aborted uploads to be evicted, got %d", resp.StatusCode)
+	}
+
+	//completed uploads are closed
+	id = initialize("b.txt")
+	send(http.MethodPut, "/uploads/"+id+"/0", []byte("HELLO"))
+	if resp := send(http.MethodPost, "/uploads/"+id+"/complete", []byte(`{"path":"finished.txt"}`)); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 completing an upload, got %d", resp.StatusCode)
+	}
+	if resp := send(http.MethodDelete, "/uploads/"+id+"?path=finished.txt", nil); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 aborting a completed upload, got %d", resp.StatusCode)
+	}
+	if resp := send(http.MethodPut, "/uploads/"+id+"/0", []byte("BYE")); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 writing to a completed upload, got %d", resp.StatusCode)
+	}
+	if !finished() || !filesapi.FileExists(store, filepath.Join(root, "b.txt")) {
+		t.Fatal("expected completed objects to survive")
+	}
+	now = now.Add(2 * time.Minute)
+	if _, ok := handler.Progress(id); ok {
+		t.Fatal("expected completed uploads to be evicted after the completed ttl")
+	}
+
+	//idle uploads expire
+	id = initialize("c.txt")
+	now = now.Add(2 * time.Hour)
+	if resp := send(http.MethodPut, "/uploads/"+id+"/0", []byte("HELLO")); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 writing to an expired upload, got %d", resp.StatusCode)
+	}
+	if _, ok := handler.Progress(id); ok {
+		t.Fatal("expected expired uploads to be evicted")
+	}
+}
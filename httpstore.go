@@ -0,0 +1,189 @@
+package filesapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// HTTPFSConfig configures a read only store over a base url (i.e. a dataset released with Publish
+// to a static website or CDN).  Objects are read with GET and HEAD requests.  Listing requires
+// an index at the base url, which is either a Publish manifest (signed or unsigned) or any
+// document with the same "files" array
+type HTTPFSConfig struct {
+	BaseUrl string
+
+	//name of the index object at the base url.  Defaults to defaultManifestName
+	IndexName string
+
+	//optional http client.  Defaults to http.DefaultClient
+	Client *http.Client
+
+	//optional headers added to every request (i.e. Authorization)
+	Headers map[string]string
+}
+
+// HTTPFS is a read only FileStore.  Store paths are resolved relative to the base url, and
+// every write operation returns ErrReadOnlyStore
+type HTTPFS struct {
+	readOnlyStore
+	config HTTPFSConfig
+	client *http.Client
+}
+
+func (hfs *HTTPFS) ResourceName() string {
+	return hfs.config.BaseUrl
+}
+
+// builds the url of a store path, escaping each path element
+func (hfs *HTTPFS) objectUrl(p string) string {
+	parts := strings.Split(strings.Trim(p, "/"), "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.TrimSuffix(hfs.config.BaseUrl, "/") + "/" + strings.Join(parts, "/")
+}
+
+func (hfs *HTTPFS) do(ctx context.Context, method string, p string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, hfs.objectUrl(p), nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range hfs.config.Headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	//objects are returned as stored, so gzip encoded objects are only decoded on request
+	req.Header.Set("Accept-Encoding", "identity")
+	resp, err := hfs.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, &FileNotFoundError{p}
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s failed: %s", method, p, resp.Status)
+	}
+	return resp, nil
+}
+
+func (hfs *HTTPFS) GetObject(goi GetObjectInput) (io.ReadCloser, error) {
+	header := http.Header{}
+	if goi.Range != "" {
+		header.Set("Range", goi.Range)
+	}
+	ctx, cancel := goi.Options.context()
+	resp, err := hfs.do(ctx, http.MethodGet, goi.Path.Path, header)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if goi.Range != "" && resp.StatusCode != http.StatusPartialContent {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("%s does not support range requests", hfs.config.BaseUrl)
+	}
+	//the call timeout also covers reading the body
+	body := &cancelReadCloser{resp.Body, cancel}
+	if goi.Decompress && goi.Range == "" && strings.EqualFold(resp.Header.Get("Content-Encoding"), gzipEncoding) {
+		return newGzipReadCloser(body)
+	}
+	return body, nil
+}
+
+// GetObjectInfo sends a HEAD request for the object.  Paths without an object are reported
+// as directories when the index lists files beneath them
+func (hfs *HTTPFS) GetObjectInfo(pc PathConfig) (fs.FileInfo, error) {
+	if all := pc.All(); len(all) > 1 {
+		return resourceInfo(all, hfs.GetObjectInfo)
+	}
+	resp, err := hfs.do(context.TODO(), http.MethodHead, pc.Path, nil)
+	if err == nil {
+		resp.Body.Close()
+		modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+		return &storeFileInfo{
+			name:    path.Base(pc.Path),
+			size:    resp.ContentLength,
+			modTime: modTime,
+			etag:    resp.Header.Get("ETag"),
+		}, nil
+	}
+	if !errors.As(err, &fileNotFoundError) {
+		return nil, err
+	}
+	index, indexErr := hfs.index()
+	if indexErr != nil {
+		return nil, err
+	}
+	return index.info(pc.Path)
+}
+
+// reads the store index
+func (hfs *HTTPFS) index() (entryIndex, error) {
+	indexName := hfs.config.IndexName
+	if indexName == "" {
+		indexName = defaultManifestName
+	}
+	resp, err := hfs.do(context.TODO(), http.MethodGet, indexName, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read the index of %s: %w", hfs.config.BaseUrl, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	signed := SignedManifest{}
+	if err = json.Unmarshal(data, &signed); err != nil {
+		return nil, err
+	}
+	if len(signed.Manifest) > 0 {
+		data = signed.Manifest
+	}
+	manifest := PublishManifest{}
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	entries := make([]storeEntry, len(manifest.Files))
+	for i, file := range manifest.Files {
+		entries[i] = storeEntry{
+			path: file.Path,
+			info: &storeFileInfo{name: path.Base(file.Path), size: file.Size, modTime: manifest.Created},
+		}
+	}
+	return newEntryIndex(entries), nil
+}
+
+// ListDir lists the index entries immediately beneath a directory
+func (hfs *HTTPFS) ListDir(input ListDirInput) (*[]FileStoreResultObject, error) {
+	index, err := hfs.index()
+	if err != nil {
+		return nil, err
+	}
+	return index.listDir(input), nil
+}
+
+func (hfs *HTTPFS) GetDir(path PathConfig) (*[]FileStoreResultObject, error) {
+	return hfs.ListDir(ListDirInput{Path: path})
+}
+
+// Walk visits the index entries beneath a path in lexicographic order
+func (hfs *HTTPFS) Walk(input WalkInput, vistorFunction FileVisitFunction) error {
+	index, err := hfs.index()
+	if err != nil {
+		return err
+	}
+	return index.walk(input, vistorFunction)
+}
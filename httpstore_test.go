@@ -0,0 +1,99 @@
+package filesapi
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestHTTPFS(t *testing.T) {
+	dir := t.TempDir()
+	src := &BlockFS{}
+	for _, name := range []string{"/src/a.txt", "/src/nested/b.txt", "/src/nested/c.txt"} {
+		_, err := src.PutObject(PutObjectInput{
+			Source: ObjectSource{Data: []byte(testObjectString)},
+			Dest:   PathConfig{Path: dir + name},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := Publish(PublishInput{
+		Src:        src,
+		SrcPrefix:  dir + "/src",
+		DestPrefix: dir + "/release",
+		Signer:     HMACSigner{Key: []byte("release-key")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//static hosts (i.e. S3 websites) have no directory objects
+	files := http.FileServer(http.Dir(dir + "/release"))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if info, err := os.Stat(dir + "/release" + r.URL.Path); err == nil && info.IsDir() {
+			http.NotFound(w, r)
+			return
+		}
+		files.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	store, err := NewFileStore(HTTPFSConfig{BaseUrl: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := store.GetObject(GetObjectInput{Path: PathConfig{Path: "/nested/b.txt"}, Range: "bytes=0-4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(data) != testObjectString[:5] {
+		t.Fatalf("unexpected range read %q", data)
+	}
+
+	info, err := store.GetObjectInfo(PathConfig{Path: "/a.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len(testObjectString)) || info.IsDir() {
+		t.Fatalf("unexpected object info: %d %t", info.Size(), info.IsDir())
+	}
+	info, err = store.GetObjectInfo(PathConfig{Path: "/nested"})
+	if err != nil || !info.IsDir() {
+		t.Fatalf("expected a directory, got %v %v", info, err)
+	}
+	_, err = store.GetObjectInfo(PathConfig{Path: "/missing.txt"})
+	if !errors.As(err, &fileNotFoundError) {
+		t.Fatalf("expected a FileNotFoundError, got %v", err)
+	}
+
+	list, err := store.ListDir(ListDirInput{Path: PathConfig{Path: "/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*list) != 2 || (*list)[0].Name != "a.txt" || !(*list)[1].IsDir {
+		t.Fatalf("unexpected listing: %v", *list)
+	}
+
+	walked := []string{}
+	err = store.Walk(WalkInput{Path: PathConfig{Path: "/nested"}}, func(path string, file os.FileInfo) error {
+		walked = append(walked, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(walked) != 2 || walked[0] != "/nested/b.txt" {
+		t.Fatalf("unexpected walk: %v", walked)
+	}
+
+	_, err = store.PutObject(PutObjectInput{Source: ObjectSource{Data: []byte("x")}, Dest: PathConfig{Path: "/x.txt"}})
+	if !errors.Is(err, ErrReadOnlyStore) {
+		t.Fatalf("expected ErrReadOnlyStore, got %v", err)
+	}
+}
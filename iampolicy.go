@@ -0,0 +1,141 @@
+package filesapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// StoreOperation is a class of FileStore operation used by an application
+type StoreOperation string
+
+const (
+	//GetObject, GetObjectInfo, and presigned downloads
+	StoreRead StoreOperation = "read"
+
+	//ListDir, Walk, and the listing utilities
+	StoreList StoreOperation = "list"
+
+	//PutObject and the chunked upload methods
+	StoreWrite StoreOperation = "write"
+
+	//DeleteObject and DeleteObjects
+	StoreDelete StoreOperation = "delete"
+
+	//CopyObject
+	StoreCopy StoreOperation = "copy"
+
+	//RestoreObject and RestoreStatus
+	StoreRestore StoreOperation = "restore"
+)
+
+var storeOperationActions = map[StoreOperation][]string{
+	StoreRead:    {"s3:GetObject", "s3:GetObjectAttributes"},
+	StoreWrite:   {"s3:PutObject", "s3:AbortMultipartUpload", "s3:ListMultipartUploadParts"},
+	StoreDelete:  {"s3:DeleteObject"},
+	StoreCopy:    {"s3:GetObject", "s3:PutObject", "s3:AbortMultipartUpload", "s3:ListMultipartUploadParts"},
+	StoreRestore: {"s3:RestoreObject", "s3:GetObject"},
+}
+
+type IAMPolicy struct {
+	Version   string         `json:"Version"`
+	Statement []IAMStatement `json:"Statement"`
+}
+
+type IAMStatement struct {
+	Sid       string                         `json:"Sid"`
+	Effect    string                         `json:"Effect"`
+	Action    []string                       `json:"Action"`
+	Resource  []string                       `json:"Resource"`
+	Condition map[string]map[string][]string `json:"Condition,omitempty"`
+}
+
+// JSON returns the indented policy document
+func (p *IAMPolicy) JSON() ([]byte, error) {
+	return json.MarshalIndent(p, "", "  ")
+}
+
+// GenerateIAMPolicy builds a least-privilege IAM policy for the bucket in config
+// that allows only the given operations.  Optional prefixes restrict object
+// access and listing to keys beneath them.  GovCloud and China regions use
+// their partition in resource ARNs
+func GenerateIAMPolicy(config S3FSConfig, operations []StoreOperation, prefixes ...string) (*IAMPolicy, error) {
+	if config.S3Bucket == "" {
+		return nil, errors.New("policy generation requires an S3Bucket")
+	}
+	if len(operations) == 0 {
+		return nil, errors.New("policy generation requires at least one operation")
+	}
+	bucketArn := fmt.Sprintf("arn:%s:s3:::%s", awsPartition(config.S3Region), config.S3Bucket)
+	patterns := []string{}
+	for _, prefix := range prefixes {
+		prefix = strings.TrimPrefix(prefix, "/")
+		if prefix != "" {
+			patterns = append(patterns, prefix+"*")
+		}
+	}
+
+	policy := &IAMPolicy{Version: "2012-10-17", Statement: []IAMStatement{}}
+	objectActions := map[string]bool{}
+	list := false
+	for _, op := range operations {
+		if op == StoreList {
+			list = true
+			continue
+		}
+		actions, ok := storeOperationActions[op]
+		if !ok {
+			return nil, fmt.Errorf("unknown store operation: %s", op)
+		}
+		for _, action := range actions {
+			objectActions[action] = true
+		}
+	}
+
+	if list {
+		statement := IAMStatement{
+			Sid:      "ListBucket",
+			Effect:   "Allow",
+			Action:   []string{"s3:ListBucket"},
+			Resource: []string{bucketArn},
+		}
+		if len(patterns) > 0 {
+			statement.Condition = map[string]map[string][]string{
+				"StringLike": {"s3:prefix": patterns},
+			}
+		}
+		policy.Statement = append(policy.Statement, statement)
+	}
+	if len(objectActions) > 0 {
+		statement := IAMStatement{
+			Sid:      "ObjectAccess",
+			Effect:   "Allow",
+			Action:   []string{},
+			Resource: []string{},
+		}
+		for action := range objectActions {
+			statement.Action = append(statement.Action, action)
+		}
+		sort.Strings(statement.Action)
+		if len(patterns) == 0 {
+			statement.Resource = append(statement.Resource, bucketArn+"/*")
+		}
+		for _, pattern := range patterns {
+			statement.Resource = append(statement.Resource, bucketArn+"/"+pattern)
+		}
+		policy.Statement = append(policy.Statement, statement)
+	}
+	return policy, nil
+}
+
+func awsPartition(region string) string {
+	switch {
+	case strings.HasPrefix(region, "us-gov-"):
+		return "aws-us-gov"
+	case strings.HasPrefix(region, "cn-"):
+		return "aws-cn"
+	}
+	return "aws"
+}
@@ -0,0 +1,69 @@
+package filesapi
+
+import (
+	"testing"
+)
+
+// read, list, and write access to a GovCloud bucket prefix.  Object access is scoped
+// to the prefix and nothing unrequested (i.e. s3:DeleteObject) is granted
+const expectedIAMPolicy = `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Sid": "ListBucket",
+      "Effect": "Allow",
+      "Action": [
+        "s3:ListBucket"
+      ],
+      "Resource": [
+        "arn:aws-us-gov:s3:::models"
+      ],
+      "Condition": {
+        "StringLike": {
+          "s3:prefix": [
+            "projects/ras*"
+          ]
+        }
+      }
+    },
+    {
+      "Sid": "ObjectAccess",
+      "Effect": "Allow",
+      "Action": [
+        "s3:AbortMultipartUpload",
+        "s3:GetObject",
+        "s3:GetObjectAttributes",
+        "s3:ListMultipartUploadParts",
+        "s3:PutObject"
+      ],
+      "Resource": [
+        "arn:aws-us-gov:s3:::models/projects/ras*"
+      ]
+    }
+  ]
+}`
+
+func TestGenerateIAMPolicy(t *testing.T) {
+	policy, err := GenerateIAMPolicy(
+		S3FSConfig{S3Bucket: "models", S3Region: "us-gov-west-1"},
+		[]StoreOperation{StoreRead, StoreList, StoreWrite},
+		"/projects/ras",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := policy.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(policy.Statement) != 2 {
+		t.Fatalf("expected list and object statements, got %d", len(policy.Statement))
+	}
+	if string(doc) != expectedIAMPolicy {
+		t.Fatalf("unexpected policy:\n%s", doc)
+	}
+
+	if _, err = GenerateIAMPolicy(S3FSConfig{S3Bucket: "models"}, []StoreOperation{"admin"}); err == nil {
+		t.Fatal("expected an error for an unknown operation")
+	}
+}
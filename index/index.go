@@ -0,0 +1,409 @@
+// Package index maintains an embedded, in process search index over a filesapi.FileStore.
+//
+// An Index is populated by walking a store and can be kept current by subscribing
+// it to a filesapi.EventBus.  It answers name and metadata queries and pages through
+// directories with millions of siblings without listing the backing store.
+// Names are indexed by trigram and metadata by key/value pair, so selective queries
+// only visit candidate entries, and prefix queries only visit the matching subtree.
+// Indexes are persisted with Save and Load, which use encoding/gob so the package
+// carries no database dependencies.
+package index
+
+import (
+	"encoding/gob"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/usace/filesapi"
+)
+
+type Entry struct {
+	Path     string
+	Name     string
+	Dir      string
+	Size     int64
+	Modified time.Time
+	IsDir    bool
+	Metadata map[string]string
+}
+
+type Query struct {
+
+	//case insensitive substring of the entry name
+	NameContains string
+
+	//optional regular expression matched against the full path
+	Pattern *regexp.Regexp
+
+	//restricts results to entries beneath a path prefix
+	Prefix string
+
+	//entries must carry all of the metadata key/value pairs
+	Metadata map[string]string
+
+	//include directory entries in results
+	IncludeDirs bool
+
+	//maximum number of results.  Zero returns all matches
+	Limit int
+}
+
+// MetadataFunction optionally derives metadata for an entry while indexing
+type MetadataFunction func(path string, info os.FileInfo) map[string]string
+
+// Index is safe for concurrent use
+type Index struct {
+	mu       sync.RWMutex
+	entries  map[string]*Entry
+	children map[string][]string            //sorted child paths keyed by directory
+	trigrams map[string]map[string]struct{} //path sets keyed by lower case name trigram
+	metadata map[string]map[string]struct{} //path sets keyed by metadata key/value pair
+
+	//optional metadata source used by Build and event updates
+	Metadata MetadataFunction
+}
+
+func New() *Index {
+	return &Index{
+		entries:  map[string]*Entry{},
+		children: map[string][]string{},
+		trigrams: map[string]map[string]struct{}{},
+		metadata: map[string]map[string]struct{}{},
+	}
+}
+
+// Build walks the store from root, adding every object to the index
+func (ix *Index) Build(store filesapi.FileStore, root filesapi.PathConfig) error {
+	return store.Walk(filesapi.WalkInput{Path: root}, func(p string, info os.FileInfo) error {
+		ix.putInfo(p, info)
+		return nil
+	})
+}
+
+// Subscribe keeps the index current with the change events published for store.
+// The returned function unsubscribes the index
+func (ix *Index) Subscribe(bus *filesapi.EventBus, store filesapi.FileStore) func() {
+	return bus.Subscribe(func(e filesapi.Event) {
+		if e.Store != store.ResourceName() {
+			return
+		}
+		switch e.Type {
+		case filesapi.ObjectCreated, filesapi.ObjectCopied:
+			info, err := store.GetObjectInfo(filesapi.PathConfig{Path: e.Path})
+			if err == nil {
+				ix.putInfo(e.Path, info)
+			}
+		case filesapi.ObjectMoved:
+			ix.Delete(e.SrcPath)
+			info, err := store.GetObjectInfo(filesapi.PathConfig{Path: e.Path})
+			if err == nil {
+				ix.putInfo(e.Path, info)
+			}
+		case filesapi.ObjectDeleted:
+			ix.Delete(e.Path)
+		}
+	})
+}
+
+func (ix *Index) putInfo(p string, info os.FileInfo) {
+	entry := Entry{
+		Path:     p,
+		Size:     info.Size(),
+		Modified: info.ModTime(),
+		IsDir:    info.IsDir(),
+	}
+	if ix.Metadata != nil {
+		entry.Metadata = ix.Metadata(p, info)
+	}
+	ix.Put(entry)
+}
+
+// Put adds or replaces an entry.  Parent directory entries are created as needed
+func (ix *Index) Put(entry Entry) {
+	entry.Path = normalize(entry.Path)
+	entry.Name = path.Base(entry.Path)
+	entry.Dir = parent(entry.Path)
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if existing, ok := ix.entries[entry.Path]; ok {
+		ix.unindex(existing)
+		*existing = entry
+		ix.index(existing)
+		return
+	}
+	ix.entries[entry.Path] = &entry
+	ix.index(&entry)
+	ix.addChild(entry.Dir, entry.Path)
+	//create any missing ancestor directories
+	for dir := entry.Dir; dir != parent(dir); dir = parent(dir) {
+		if _, ok := ix.entries[dir]; ok {
+			break
+		}
+		dirEntry := &Entry{Path: dir, Name: path.Base(dir), Dir: parent(dir), IsDir: true}
+		ix.entries[dir] = dirEntry
+		ix.index(dirEntry)
+		ix.addChild(parent(dir), dir)
+	}
+}
+
+// Delete removes an entry and everything beneath it
+func (ix *Index) Delete(p string) {
+	p = normalize(p)
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if _, ok := ix.entries[p]; !ok {
+		return
+	}
+	ix.removeChild(parent(p), p)
+	stack := []string{p}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		stack = append(stack, ix.children[current]...)
+		if e, ok := ix.entries[current]; ok {
+			ix.unindex(e)
+		}
+		delete(ix.children, current)
+		delete(ix.entries, current)
+	}
+}
+
+func (ix *Index) Get(p string) (Entry, bool) {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	e, ok := ix.entries[normalize(p)]
+	if !ok {
+		return Entry{}, false
+	}
+	return *e, true
+}
+
+func (ix *Index) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.entries)
+}
+
+// ListDir returns a page of the sorted children of dir
+func (ix *Index) ListDir(dir string, offset int, limit int) []Entry {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	children := ix.children[normalize(dir)]
+	if offset >= len(children) {
+		return []Entry{}
+	}
+	end := len(children)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	result := make([]Entry, 0, end-offset)
+	for _, c := range children[offset:end] {
+		result = append(result, *ix.entries[c])
+	}
+	return result
+}
+
+// Search returns the entries matching a query sorted by path
+func (ix *Index) Search(q Query) []Entry {
+	nameContains := strings.ToLower(q.NameContains)
+	prefix := ""
+	if q.Prefix != "" {
+		prefix = normalize(q.Prefix)
+	}
+	ix.mu.RLock()
+	result := []Entry{}
+	ix.candidates(q, prefix, nameContains, func(e *Entry) {
+		switch {
+		case e.IsDir && !q.IncludeDirs:
+		case prefix != "" && !strings.HasPrefix(e.Path, prefix):
+		case nameContains != "" && !strings.Contains(strings.ToLower(e.Name), nameContains):
+		case q.Pattern != nil && !q.Pattern.MatchString(e.Path):
+		case !hasMetadata(e.Metadata, q.Metadata):
+		default:
+			result = append(result, *e)
+		}
+	})
+	ix.mu.RUnlock()
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	if q.Limit > 0 && len(result) > q.Limit {
+		result = result[:q.Limit]
+	}
+	return result
+}
+
+// visits the entries that can match a query.  The smallest metadata or name trigram
+// path set is used when the query has one, then the subtree under the prefix, and
+// only unselective queries scan every entry.  Must be called with the read lock held
+func (ix *Index) candidates(q Query, prefix string, nameContains string, visit func(e *Entry)) {
+	var best map[string]struct{}
+	found := false
+	consider := func(set map[string]struct{}) {
+		if !found || len(set) < len(best) {
+			best = set
+			found = true
+		}
+	}
+	for k, v := range q.Metadata {
+		consider(ix.metadata[metadataKey(k, v)])
+	}
+	for t := range trigrams(nameContains) {
+		consider(ix.trigrams[t])
+	}
+	switch {
+	case found:
+		for p := range best {
+			visit(ix.entries[p])
+		}
+	case prefix != "":
+		//entries sharing the prefix are children of its parent, or beneath them
+		stack := []string{}
+		for _, c := range ix.children[parent(prefix)] {
+			if strings.HasPrefix(c, prefix) {
+				stack = append(stack, c)
+			}
+		}
+		for len(stack) > 0 {
+			current := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			visit(ix.entries[current])
+			stack = append(stack, ix.children[current]...)
+		}
+	default:
+		for _, e := range ix.entries {
+			visit(e)
+		}
+	}
+}
+
+// Save writes a snapshot of the index
+func (ix *Index) Save(w io.Writer) error {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	entries := make([]Entry, 0, len(ix.entries))
+	for _, e := range ix.entries {
+		entries = append(entries, *e)
+	}
+	return gob.NewEncoder(w).Encode(entries)
+}
+
+// Load replaces the index contents with a snapshot written by Save.  The snapshot is
+// indexed off to the side, so concurrent readers see either the old or the new contents
+func (ix *Index) Load(r io.Reader) error {
+	entries := []Entry{}
+	if err := gob.NewDecoder(r).Decode(&entries); err != nil {
+		return err
+	}
+	loaded := New()
+	for _, e := range entries {
+		loaded.Put(e)
+	}
+	ix.mu.Lock()
+	ix.entries = loaded.entries
+	ix.children = loaded.children
+	ix.trigrams = loaded.trigrams
+	ix.metadata = loaded.metadata
+	ix.mu.Unlock()
+	return nil
+}
+
+// adds an entry to the name and metadata path sets
+func (ix *Index) index(e *Entry) {
+	for t := range trigrams(strings.ToLower(e.Name)) {
+		addPath(ix.trigrams, t, e.Path)
+	}
+	for k, v := range e.Metadata {
+		addPath(ix.metadata, metadataKey(k, v), e.Path)
+	}
+}
+
+// removes an entry from the name and metadata path sets
+func (ix *Index) unindex(e *Entry) {
+	for t := range trigrams(strings.ToLower(e.Name)) {
+		removePath(ix.trigrams, t, e.Path)
+	}
+	for k, v := range e.Metadata {
+		removePath(ix.metadata, metadataKey(k, v), e.Path)
+	}
+}
+
+func addPath(sets map[string]map[string]struct{}, key string, p string) {
+	set, ok := sets[key]
+	if !ok {
+		set = map[string]struct{}{}
+		sets[key] = set
+	}
+	set[p] = struct{}{}
+}
+
+func removePath(sets map[string]map[string]struct{}, key string, p string) {
+	if set, ok := sets[key]; ok {
+		delete(set, p)
+		if len(set) == 0 {
+			delete(sets, key)
+		}
+	}
+}
+
+// returns the distinct three byte substrings of s.  Strings shorter than three
+// bytes have no trigrams and are matched by scanning
+func trigrams(s string) map[string]struct{} {
+	result := map[string]struct{}{}
+	for i := 0; i+3 <= len(s); i++ {
+		result[s[i:i+3]] = struct{}{}
+	}
+	return result
+}
+
+func metadataKey(k string, v string) string {
+	return k + "\x00" + v
+}
+
+func (ix *Index) addChild(dir string, p string) {
+	children := ix.children[dir]
+	i := sort.SearchStrings(children, p)
+	if i < len(children) && children[i] == p {
+		return
+	}
+	children = append(children, "")
+	copy(children[i+1:], children[i:])
+	children[i] = p
+	ix.children[dir] = children
+}
+
+func (ix *Index) removeChild(dir string, p string) {
+	children := ix.children[dir]
+	i := sort.SearchStrings(children, p)
+	if i < len(children) && children[i] == p {
+		ix.children[dir] = append(children[:i], children[i+1:]...)
+	}
+}
+
+func hasMetadata(metadata map[string]string, required map[string]string) bool {
+	for k, v := range required {
+		if metadata[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// index paths use forward slashes without trailing delimiters
+func normalize(p string) string {
+	p = strings.ReplaceAll(p, "\\", "/")
+	if p != "/" {
+		p = strings.TrimSuffix(p, "/")
+	}
+	return p
+}
+
+func parent(p string) string {
+	return path.Dir(p)
+}
@@ -0,0 +1,137 @@
+package index
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/usace/filesapi"
+)
+
+func TestIndex(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "runs"), os.ModePerm)
+	for i := 0; i < 25; i++ {
+		os.WriteFile(filepath.Join(dir, "runs", fmt.Sprintf("run%02d.dss", i)), []byte("data"), 0644)
+	}
+	os.WriteFile(filepath.Join(dir, "terrain.tif"), []byte("terrain"), 0644)
+
+	ix := New()
+	ix.Metadata = func(path string, info os.FileInfo) map[string]string {
+		return map[string]string{"ext": filepath.Ext(path)}
+	}
+	if err = ix.Build(store, filesapi.PathConfig{Path: dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	page := ix.ListDir(filepath.Join(dir, "runs"), 10, 5)
+	if len(page) != 5 || page[0].Name != "run10.dss" {
+		t.Fatalf("unexpected directory page: %v", page)
+	}
+	results := ix.Search(Query{Metadata: map[string]string{"ext": ".tif"}})
+	if len(results) != 1 || results[0].Name != "terrain.tif" {
+		t.Fatalf("unexpected metadata search results: %v", results)
+	}
+	results = ix.Search(Query{NameContains: "RUN1", Pattern: regexp.MustCompile(`\.dss$`)})
+	if len(results) != 10 {
+		t.Fatalf("expected 10 name matches, got %d", len(results))
+	}
+
+	bus := filesapi.NewEventBus()
+	unsubscribe := ix.Subscribe(bus, store)
+	defer unsubscribe()
+	efs := filesapi.NewEventFS(store, bus)
+	efs.DeleteObjects(filesapi.DeleteObjectInput{Paths: filesapi.PathConfig{Paths: []string{filepath.Join(dir, "runs")}}})
+	if len(ix.ListDir(filepath.Join(dir, "runs"), 0, 0)) != 0 {
+		t.Fatal("expected deleted directory to be removed from the index")
+	}
+
+	var buf bytes.Buffer
+	if err = ix.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	loaded := New()
+	if err = loaded.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != ix.Len() {
+		t.Fatalf("expected %d entries after loading, got %d", ix.Len(), loaded.Len())
+	}
+}
+
+func TestIndexEvents(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	ix := New()
+	ix.Metadata = func(path string, info os.FileInfo) map[string]string {
+		return map[string]string{"ext": filepath.Ext(path)}
+	}
+	bus := filesapi.NewEventBus()
+	defer ix.Subscribe(bus, store)()
+	efs := filesapi.NewEventFS(store, bus)
+
+	src := filepath.Join(dir, "inbox", "flow.csv")
+	dest := filepath.Join(dir, "archive", "flow-2024.csv")
+	_, err = efs.PutObject(filesapi.PutObjectInput{
+		Source: filesapi.ObjectSource{Data: []byte("data")},
+		Dest:   filesapi.PathConfig{Path: src},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = efs.MoveObject(filesapi.MoveObjectInput{Src: filesapi.PathConfig{Path: src}, Dest: filesapi.PathConfig{Path: dest}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := ix.Get(src); ok {
+		t.Fatal("expected the move source to be removed from the index")
+	}
+	if e, ok := ix.Get(dest); !ok || e.Size != 4 {
+		t.Fatalf("expected the move destination in the index, got %+v", e)
+	}
+
+	tests := []struct {
+		name     string
+		query    Query
+		expected []string
+	}{
+		{"name", Query{NameContains: "FLOW"}, []string{dest}},
+		{"short name", Query{NameContains: "fl"}, []string{dest}},
+		{"metadata", Query{Metadata: map[string]string{"ext": ".csv"}}, []string{dest}},
+		{"prefix", Query{Prefix: filepath.Join(dir, "arch")}, []string{dest}},
+		{"moved prefix", Query{Prefix: filepath.Join(dir, "inbox"), IncludeDirs: true}, []string{filepath.Join(dir, "inbox")}},
+		{"stale name", Query{NameContains: "flow.csv"}, []string{}},
+		{"missing metadata", Query{Metadata: map[string]string{"ext": ".dss"}}, []string{}},
+	}
+	for _, test := range tests {
+		results := ix.Search(test.query)
+		paths := []string{}
+		for _, r := range results {
+			paths = append(paths, r.Path)
+		}
+		if fmt.Sprint(paths) != fmt.Sprint(test.expected) {
+			t.Fatalf("%s: expected %v, got %v", test.name, test.expected, paths)
+		}
+	}
+
+	//a reloaded index answers the same indexed queries
+	var buf bytes.Buffer
+	if err = ix.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err = ix.Load(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if results := ix.Search(Query{NameContains: "flow", Metadata: map[string]string{"ext": ".csv"}}); len(results) != 1 || results[0].Path != dest {
+		t.Fatalf("unexpected search results after loading: %v", results)
+	}
+}
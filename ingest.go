@@ -0,0 +1,231 @@
+package filesapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultIngestStableFor    time.Duration = 10 * time.Second
+	defaultIngestPollInterval time.Duration = 5 * time.Second
+)
+
+// IngestAction determines what happens to a local file after it is uploaded
+type IngestAction int
+
+const (
+	//leave the local file in place.  It will not be uploaded again while the Ingester runs
+	IngestKeep IngestAction = iota
+
+	//delete the local file
+	IngestDelete
+
+	//move the local file into the ArchiveDir
+	IngestArchive
+)
+
+type IngestConfig struct {
+
+	//destination store
+	Store FileStore
+
+	//local "drop folder" that is watched for new files
+	WatchDir string
+
+	//destination prefix.  Files are written to DestPrefix + their path relative to WatchDir
+	DestPrefix string
+
+	//optional sharder used to spread uploads across hashed prefixes.
+	//when set, files are written to Sharder.ShardKey(path relative to WatchDir)
+	//and DestPrefix is ignored
+	Sharder *KeySharder
+
+	//files must be unchanged in size and modification time for StableFor
+	//before they are uploaded.  Defaults to defaultIngestStableFor
+	StableFor time.Duration
+
+	//interval between directory scans used by Run.  Defaults to defaultIngestPollInterval
+	PollInterval time.Duration
+
+	//action taken on the local file after a successful upload
+	Action IngestAction
+
+	//directory that uploaded files are moved into for IngestArchive.
+	//the relative path below WatchDir is preserved
+	ArchiveDir string
+
+	//optional journal.  Each upload attempt is written as a single line of JSON
+	Journal io.Writer
+
+	//optional time source
+	Clock Clock
+}
+
+// IngestRecord is a journal entry for a single upload attempt
+type IngestRecord struct {
+	Time      time.Time `json:"time"`
+	LocalPath string    `json:"localPath"`
+	DestPath  string    `json:"destPath"`
+	Size      int64     `json:"size"`
+	Error     string    `json:"error,omitempty"`
+}
+
+type ingestFileState struct {
+	size     int64
+	modTime  time.Time
+	since    time.Time
+	uploaded bool
+}
+
+// Ingester uploads files that appear in a local directory once they
+// are stable.  Failed uploads are journaled and retried on the next scan
+type Ingester struct {
+	config IngestConfig
+	mu     sync.Mutex
+	files  map[string]*ingestFileState
+}
+
+func NewIngester(config IngestConfig) (*Ingester, error) {
+	if config.Store == nil {
+		return nil, errors.New("ingester requires a Store")
+	}
+	if config.WatchDir == "" {
+		return nil, errors.New("ingester requires a WatchDir")
+	}
+	if config.Action == IngestArchive && config.ArchiveDir == "" {
+		return nil, errors.New("ingester archive action requires an ArchiveDir")
+	}
+	if config.StableFor <= 0 {
+		config.StableFor = defaultIngestStableFor
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = defaultIngestPollInterval
+	}
+	return &Ingester{
+		config: config,
+		files:  map[string]*ingestFileState{},
+	}, nil
+}
+
+// Run scans the watch directory every PollInterval until the context is cancelled
+func (ig *Ingester) Run(ctx context.Context) error {
+	ticker := time.NewTicker(ig.config.PollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := ig.Scan(); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan makes a single pass over the watch directory, uploading every stable file.
+// It returns the records for the upload attempts made during the pass.
+// Upload failures are reported in the records rather than the returned error
+func (ig *Ingester) Scan() ([]IngestRecord, error) {
+	ig.mu.Lock()
+	defer ig.mu.Unlock()
+	now := ig.config.Clock.Now()
+	records := []IngestRecord{}
+	present := map[string]bool{}
+	archiveDir, _ := filepath.Abs(ig.config.ArchiveDir)
+
+	err := filepath.WalkDir(ig.config.WatchDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if abs, _ := filepath.Abs(path); ig.config.ArchiveDir != "" && abs == archiveDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		present[path] = true
+		state, ok := ig.files[path]
+		if !ok || state.size != info.Size() || !state.modTime.Equal(info.ModTime()) {
+			ig.files[path] = &ingestFileState{size: info.Size(), modTime: info.ModTime(), since: now}
+			return nil
+		}
+		if state.uploaded || now.Sub(state.since) < ig.config.StableFor {
+			return nil
+		}
+		record := ig.ingest(path, info.Size(), now)
+		if record.Error == "" {
+			state.uploaded = true
+		}
+		records = append(records, record)
+		return nil
+	})
+
+	//forget files that were removed from the drop folder
+	for path := range ig.files {
+		if !present[path] {
+			delete(ig.files, path)
+		}
+	}
+	return records, err
+}
+
+func (ig *Ingester) ingest(path string, size int64, now time.Time) IngestRecord {
+	rel, err := filepath.Rel(ig.config.WatchDir, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+	record := IngestRecord{
+		Time:      now,
+		LocalPath: path,
+		DestPath:  strings.TrimSuffix(ig.config.DestPrefix, "/") + "/" + filepath.ToSlash(rel),
+		Size:      size,
+	}
+	if ig.config.Sharder != nil {
+		record.DestPath = ig.config.Sharder.ShardKey(filepath.ToSlash(rel))
+	}
+	err = ig.upload(path, rel, record.DestPath)
+	if err != nil {
+		record.Error = err.Error()
+	}
+	if ig.config.Journal != nil {
+		if jerr := json.NewEncoder(ig.config.Journal).Encode(record); jerr != nil && record.Error == "" {
+			record.Error = fmt.Sprintf("failed to journal upload: %s", jerr)
+		}
+	}
+	return record
+}
+
+func (ig *Ingester) upload(path string, rel string, dest string) error {
+	_, err := ig.config.Store.PutObject(PutObjectInput{
+		Source: ObjectSource{Filepath: PathConfig{Path: path}},
+		Dest:   PathConfig{Path: dest},
+	})
+	if err != nil {
+		return err
+	}
+	switch ig.config.Action {
+	case IngestDelete:
+		return os.Remove(path)
+	case IngestArchive:
+		archivePath := filepath.Join(ig.config.ArchiveDir, rel)
+		if err = os.MkdirAll(filepath.Dir(archivePath), os.ModePerm); err != nil {
+			return err
+		}
+		return os.Rename(path, archivePath)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package filesapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestIngesterArchive(t *testing.T) {
+	dir := t.TempDir()
+	watch := dir + "/drop"
+	archive := dir + "/drop/archive"
+	dest := dir + "/store"
+	if err := os.MkdirAll(watch+"/gage1", os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(watch+"/gage1/reading.csv", []byte("stage,1.2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	journal := new(bytes.Buffer)
+	ig, err := NewIngester(IngestConfig{
+		Store:      &BlockFS{Config: BlockFSConfig{ChunkSize: 100}},
+		WatchDir:   watch,
+		DestPrefix: dest,
+		StableFor:  30 * time.Second,
+		Action:     IngestArchive,
+		ArchiveDir: archive,
+		Journal:    journal,
+		Clock:      func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := ig.Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Fatal("expected newly discovered files to wait until they are stable")
+	}
+
+	now = now.Add(time.Minute)
+	records, err = ig.Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].Error != "" {
+		t.Fatalf("expected one successful upload, got %+v", records)
+	}
+	data, err := os.ReadFile(dest + "/gage1/reading.csv")
+	if err != nil || string(data) != "stage,1.2" {
+		t.Fatalf("uploaded file is missing or incorrect: %s", err)
+	}
+	if _, err = os.Stat(archive + "/gage1/reading.csv"); err != nil {
+		t.Fatal("expected the local file to be archived")
+	}
+
+	var record IngestRecord
+	if err = json.Unmarshal(journal.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.DestPath != dest+"/gage1/reading.csv" {
+		t.Fatalf("unexpected journal record %+v", record)
+	}
+
+	now = now.Add(time.Minute)
+	records, err = ig.Scan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 0 {
+		t.Fatal("archived files should not be ingested again")
+	}
+}
@@ -0,0 +1,248 @@
+package filesapi
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// IOFS adapts a FileStore to the io/fs interfaces (fs.FS, fs.ReadDirFS, fs.ReadFileFS,
+// fs.StatFS, and fs.GlobFS) so standard library consumers such as html/template,
+// fs.WalkDir, http.FS, and zip.Writer.AddFS can read directly from a store.
+// Names are resolved beneath a root path of the store (i.e. a BlockFS directory or an
+// S3 key prefix).  Object store directories are implied by the objects beneath them.
+// Files support Seek and ReadAt with ranged GetObject requests (see ObjectReadSeeker)
+type IOFS struct {
+	store FileStore
+	root  string
+}
+
+// NewIOFS returns an fs.FS over the objects beneath root.  Use "/" or "" for the store root
+func NewIOFS(store FileStore, root string) *IOFS {
+	return &IOFS{store: store, root: root}
+}
+
+// store path of an fs.FS name
+func (ifs *IOFS) storePath(name string) string {
+	return path.Join("/", ifs.root, name)
+}
+
+func (ifs *IOFS) Open(name string) (fs.File, error) {
+	info, err := ifs.stat("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &ioDir{fsys: ifs, name: name, info: info}, nil
+	}
+	object, err := NewObjectReadSeeker(ifs.store, PathConfig{Path: ifs.storePath(name)})
+	if err != nil {
+		return nil, ioPathError("open", name, err)
+	}
+	return &ioFile{ObjectReadSeeker: object, info: info}, nil
+}
+
+func (ifs *IOFS) Stat(name string) (fs.FileInfo, error) {
+	return ifs.stat("stat", name)
+}
+
+func (ifs *IOFS) stat(op string, name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &ioFileInfo{name: ".", isDir: true}, nil
+	}
+	info, err := ifs.store.GetObjectInfo(PathConfig{Path: ifs.storePath(name)})
+	if err == nil {
+		return &ioFileInfo{
+			name:    path.Base(name),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+			isDir:   info.IsDir(),
+		}, nil
+	}
+	if !isNotExist(err) {
+		return nil, ioPathError(op, name, err)
+	}
+	//object stores have no directory objects, so a name with objects beneath it is a directory
+	entries, lerr := ifs.list(name)
+	if lerr != nil || len(entries) == 0 {
+		return nil, ioPathError(op, name, err)
+	}
+	return &ioFileInfo{name: path.Base(name), isDir: true}, nil
+}
+
+// lists every entry of a directory, sorted by name
+func (ifs *IOFS) list(name string) ([]fs.DirEntry, error) {
+	results, err := ifs.store.ListDir(ListDirInput{
+		Path: PathConfig{Path: ifs.storePath(name) + "/"},
+		Size: math.MaxInt32,
+	})
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]fs.DirEntry, 0, len(*results))
+	for _, r := range *results {
+		if r.Name == "" || r.Name == "." || r.Name == "/" {
+			continue
+		}
+		size, _ := strconv.ParseInt(r.Size, 10, 64)
+		entries = append(entries, fs.FileInfoToDirEntry(&ioFileInfo{
+			name:    r.Name,
+			size:    size,
+			modTime: r.Modified,
+			isDir:   r.IsDir,
+		}))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (ifs *IOFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	entries, err := ifs.list(name)
+	if err != nil {
+		return nil, ioPathError("readdir", name, err)
+	}
+	if len(entries) == 0 && name != "." {
+		//distinguish an empty directory from a missing one or a file
+		info, err := ifs.stat("readdir", name)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+		}
+	}
+	return entries, nil
+}
+
+func (ifs *IOFS) ReadFile(name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readfile", Path: name, Err: fs.ErrInvalid}
+	}
+	reader, err := ifs.store.GetObject(GetObjectInput{Path: PathConfig{Path: ifs.storePath(name)}})
+	if err != nil {
+		return nil, ioPathError("readfile", name, err)
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// Glob returns the names matching a path.Match pattern.  Only the directories named by
+// the pattern are listed
+func (ifs *IOFS) Glob(pattern string) ([]string, error) {
+	//fs.Glob uses ReadDir and Stat when the file system does not implement Glob
+	return fs.Glob(struct{ fs.ReadDirFS }{ifs}, pattern)
+}
+
+func isNotExist(err error) bool {
+	var fnf *FileNotFoundError
+	return errors.As(err, &fnf) || errors.Is(err, fs.ErrNotExist)
+}
+
+func ioPathError(op string, name string, err error) error {
+	if isNotExist(err) {
+		err = fs.ErrNotExist
+	}
+	return &fs.PathError{Op: op, Path: name, Err: err}
+}
+
+// file info reported by IOFS.  Files are read only regular files, so consumers
+// requiring regular files (i.e. zip.Writer.AddFS) accept them
+type ioFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+func (ifi *ioFileInfo) Name() string {
+	return ifi.name
+}
+
+func (ifi *ioFileInfo) Size() int64 {
+	return ifi.size
+}
+
+func (ifi *ioFileInfo) Mode() fs.FileMode {
+	if ifi.isDir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+func (ifi *ioFileInfo) ModTime() time.Time {
+	return ifi.modTime
+}
+
+func (ifi *ioFileInfo) IsDir() bool {
+	return ifi.isDir
+}
+
+func (ifi *ioFileInfo) Sys() interface{} {
+	return nil
+}
+
+type ioFile struct {
+	*ObjectReadSeeker
+	info fs.FileInfo
+}
+
+func (f *ioFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// an open directory.  Entries are listed on the first ReadDir
+type ioDir struct {
+	fsys    *IOFS
+	name    string
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	listed  bool
+	offset  int
+}
+
+func (d *ioDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+func (d *ioDir) Read(p []byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *ioDir) Close() error {
+	return nil
+}
+
+func (d *ioDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !d.listed {
+		entries, err := d.fsys.list(d.name)
+		if err != nil {
+			return nil, ioPathError("readdir", d.name, err)
+		}
+		d.entries = entries
+		d.listed = true
+	}
+	remaining := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return remaining, nil
+	}
+	if len(remaining) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(remaining) {
+		n = len(remaining)
+	}
+	d.offset += n
+	return remaining[:n], nil
+}
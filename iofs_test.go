@@ -0,0 +1,91 @@
+package filesapi
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestIOFS(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "data", "nested"), 0755)
+	os.MkdirAll(filepath.Join(dir, "empty"), 0755)
+	os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("notes"), 0644)
+	os.WriteFile(filepath.Join(dir, "data", "a.csv"), []byte("x,y\n1,2\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "data", "nested", "b.csv"), []byte("x,y\n3,4\n"), 0644)
+
+	store, err := NewFileStore(BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := NewIOFS(store, dir)
+	if err := fstest.TestFS(fsys, "readme.txt", "data/a.csv", "data/nested/b.csv", "empty"); err != nil {
+		t.Fatal(err)
+	}
+
+	matches, err := fs.Glob(fsys, "data/*.csv")
+	if err != nil || len(matches) != 1 || matches[0] != "data/a.csv" {
+		t.Fatalf("unexpected glob matches %v: %v", matches, err)
+	}
+	if _, err := fsys.Open("missing.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected fs.ErrNotExist, got %v", err)
+	}
+	if _, err := fsys.Open("../escape"); !errors.Is(err, fs.ErrInvalid) {
+		t.Fatalf("expected fs.ErrInvalid, got %v", err)
+	}
+
+	//standard library consumers (i.e. zip.Writer.AddFS) require regular files
+	files := 0
+	err = fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			t.Fatalf("expected %s to be a regular file", name)
+		}
+		files++
+		return nil
+	})
+	if err != nil || files != 3 {
+		t.Fatalf("expected to walk 3 files, walked %d: %v", files, err)
+	}
+}
+
+func TestIOFSObjectStore(t *testing.T) {
+	//zip archives, like S3, have no directory entries
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	for name, data := range map[string]string{"readme.txt": "notes", "data/a.csv": "x,y\n1,2\n", "data/nested/b.csv": "x,y\n3,4\n"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(data))
+	}
+	zw.Close()
+	archive := PathConfig{Path: filepath.Join(t.TempDir(), "archive.zip")}
+	if err := os.WriteFile(archive.Path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	zfs, err := NewZipFS(&BlockFS{}, archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := NewIOFS(zfs, "/")
+	if err := fstest.TestFS(fsys, "readme.txt", "data/a.csv", "data/nested/b.csv"); err != nil {
+		t.Fatal(err)
+	}
+	info, err := fs.Stat(fsys, "data/nested")
+	if err != nil || !info.IsDir() {
+		t.Fatalf("expected an implied directory, got %v: %v", info, err)
+	}
+}
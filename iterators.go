@@ -0,0 +1,70 @@
+//go:build go1.23
+
+package filesapi
+
+import (
+	"errors"
+	"iter"
+)
+
+// ends a walk when a range loop breaks
+var errStopIteration = errors.New("iteration stopped")
+
+// All returns an iterator over every entry visited by walking the store from input.Path.
+// Breaking out of the range loop stops the underlying walk.  A walk error is yielded
+// as the final element with a zero WalkEntry
+func All(store FileStore, input WalkInput) iter.Seq2[WalkEntry, error] {
+	return walkSeq(store, input, func(entry WalkEntry) bool { return true })
+}
+
+// Files returns an iterator over the file entries of a walk
+func Files(store FileStore, input WalkInput) iter.Seq2[WalkEntry, error] {
+	return walkSeq(store, input, func(entry WalkEntry) bool { return !entry.IsDir() })
+}
+
+// Dirs returns an iterator over the directory entries of a walk.
+// Object stores without directory entries (i.e. S3) yield nothing
+func Dirs(store FileStore, input WalkInput) iter.Seq2[WalkEntry, error] {
+	return walkSeq(store, input, func(entry WalkEntry) bool { return entry.IsDir() })
+}
+
+// List returns an iterator over the results of ListDir
+func List(store FileStore, input ListDirInput) iter.Seq2[FileStoreResultObject, error] {
+	return func(yield func(FileStoreResultObject, error) bool) {
+		results, err := store.ListDir(input)
+		if err != nil {
+			yield(FileStoreResultObject{}, err)
+			return
+		}
+		for _, result := range *results {
+			if !yield(result, nil) {
+				return
+			}
+		}
+	}
+}
+
+func walkSeq(store FileStore, input WalkInput, include func(WalkEntry) bool) iter.Seq2[WalkEntry, error] {
+	return func(yield func(WalkEntry, error) bool) {
+		stopped := false
+		progress := input.Progress
+		//the progress function stops the walk on every backend, including those that
+		//ignore visitor errors
+		input.Progress = func(pd ProgressData) error {
+			if stopped {
+				return errStopIteration
+			}
+			return reportProgress(progress, pd)
+		}
+		err := WalkEntries(store, input, func(entry WalkEntry) error {
+			if stopped || !include(entry) {
+				return nil
+			}
+			stopped = !yield(entry, nil)
+			return nil
+		})
+		if err != nil && !errors.Is(err, errStopIteration) && !stopped {
+			yield(WalkEntry{}, err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+//go:build go1.23
+
+package filesapi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIterators(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "a/b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"a/1.txt", "a/2.txt", "a/b/3.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, f), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	block := &BlockFS{}
+	input := WalkInput{Path: PathConfig{Path: dir}}
+
+	files := []string{}
+	for entry, err := range Files(block, input) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, entry.RelativePath)
+	}
+	if len(files) != 3 || files[2] != "a/b/3.txt" {
+		t.Fatalf("unexpected files %v", files)
+	}
+
+	dirs := 0
+	for entry, err := range Dirs(block, input) {
+		if err != nil || !entry.IsDir() {
+			t.Fatalf("unexpected dir entry %+v %v", entry, err)
+		}
+		dirs++
+	}
+	if dirs != 3 {
+		t.Fatalf("expected 3 directories, got %d", dirs)
+	}
+
+	//breaking stops the walk, including on stores that ignore visitor errors
+	store := &markerFS{BlockFS: block}
+	store.objects = append(store.objects, testS3Object("a/1.txt", 4), testS3Object("a/2.txt", 4))
+	visited := 0
+	for range All(store, WalkInput{Path: PathConfig{Path: "a"}}) {
+		visited++
+		break
+	}
+	if visited != 1 {
+		t.Fatalf("expected the loop to break after one entry, got %d", visited)
+	}
+
+	for _, err := range All(block, WalkInput{Path: PathConfig{Path: filepath.Join(dir, "missing")}}) {
+		if err == nil {
+			t.Fatal("expected an error walking a missing directory")
+		}
+	}
+
+	listed := 0
+	for result, err := range List(block, ListDirInput{Path: PathConfig{Path: filepath.Join(dir, "a")}}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Name != "" {
+			listed++
+		}
+	}
+	if listed != 3 {
+		t.Fatalf("expected 3 listed entries, got %d", listed)
+	}
+}
@@ -0,0 +1,190 @@
+package filesapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// JobFunction runs one pass of a recurring job.  It should return promptly when the
+// context is cancelled
+type JobFunction func(ctx context.Context) error
+
+type Job struct {
+	//unique name reported with each run
+	Name string
+
+	//time between the end of a run and the start of the next
+	Interval time.Duration
+
+	//optional random delay, up to Jitter, added to each interval so instances of an
+	//application sharing a store do not run their maintenance at the same moment
+	Jitter time.Duration
+
+	//run once when the runner starts rather than waiting an interval
+	RunAtStart bool
+
+	//optional limit on the duration of each run
+	Timeout time.Duration
+
+	Run JobFunction
+}
+
+// JobRun is the outcome of a single run of a job
+type JobRun struct {
+	Job      string
+	Started  time.Time
+	Duration time.Duration
+	Err      error
+}
+
+type JobRunnerConfig struct {
+	Jobs []Job
+
+	//optional handler called after every run, i.e. for logging and metrics
+	OnRun func(JobRun)
+
+	//optional time source for run times
+	Clock Clock
+}
+
+// JobRunner hosts recurring maintenance jobs such as garbage cleanup, store eviction, and
+// reconciliation.  Each job runs on its own schedule, and a job never overlaps itself.
+// A failed or panicking run is reported to OnRun and the job runs again at its next interval
+type JobRunner struct {
+	config JobRunnerConfig
+	locks  map[string]*sync.Mutex
+}
+
+func NewJobRunner(config JobRunnerConfig) (*JobRunner, error) {
+	locks := map[string]*sync.Mutex{}
+	for _, job := range config.Jobs {
+		switch {
+		case job.Name == "":
+			return nil, errors.New("job runner jobs require a Name")
+		case locks[job.Name] != nil:
+			return nil, fmt.Errorf("duplicate job name %s", job.Name)
+		case job.Interval <= 0:
+			return nil, fmt.Errorf("job %s requires a positive Interval", job.Name)
+		case job.Run == nil:
+			return nil, fmt.Errorf("job %s requires a Run function", job.Name)
+		}
+		locks[job.Name] = &sync.Mutex{}
+	}
+	return &JobRunner{config: config, locks: locks}, nil
+}
+
+// Run runs every job on its schedule until the context is cancelled.  Runs in progress
+// are cancelled with the context, and Run returns once they end
+func (jr *JobRunner) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, job := range jr.config.Jobs {
+		wg.Add(1)
+		go func(job Job) {
+			defer wg.Done()
+			jr.schedule(ctx, job)
+		}(job)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// RunJob runs a job immediately, outside its schedule, waiting for a run in progress to end
+func (jr *JobRunner) RunJob(ctx context.Context, name string) (JobRun, error) {
+	for _, job := range jr.config.Jobs {
+		if job.Name == name {
+			return jr.run(ctx, job), nil
+		}
+	}
+	return JobRun{}, fmt.Errorf("unknown job %s", name)
+}
+
+func (jr *JobRunner) schedule(ctx context.Context, job Job) {
+	if job.RunAtStart && ctx.Err() == nil {
+		jr.run(ctx, job)
+	}
+	for {
+		timer := time.NewTimer(job.Interval + time.Duration(jitter()*float64(job.Jitter)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		jr.run(ctx, job)
+	}
+}
+
+func (jr *JobRunner) run(ctx context.Context, job Job) JobRun {
+	lock := jr.locks[job.Name]
+	lock.Lock()
+	defer lock.Unlock()
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+	run := JobRun{Job: job.Name, Started: jr.config.Clock.Now()}
+	run.Err = runJob(ctx, job)
+	run.Duration = jr.config.Clock.Now().Sub(run.Started)
+	if jr.config.OnRun != nil {
+		jr.config.OnRun(run)
+	}
+	return run
+}
+
+// runs a job function, converting a panic to an error so it does not stop the runner
+func runJob(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job %s panicked: %v", job.Name, r)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+// GarbageJob removes the folder markers, empty directories, and dangling multipart
+// uploads found by FindGarbage
+func GarbageJob(input GarbageReportInput, interval time.Duration) Job {
+	return Job{
+		Name:     "garbage",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			report, err := FindGarbage(input)
+			if err != nil || report.Empty() {
+				return err
+			}
+			return report.Apply(input.Store)
+		},
+	}
+}
+
+// EvictionJob evicts the idle and aged out stores of a StoreManager
+func EvictionJob(sm *StoreManager, interval time.Duration) Job {
+	return Job{
+		Name:     "store-eviction",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			sm.EvictExpired()
+			return nil
+		},
+	}
+}
+
+// ReconcileJob runs Reconcile, passing each report to the optional handler.
+// Unrepaired divergences are not errors; inspect the report
+func ReconcileJob(input ReconcileInput, interval time.Duration, handler func(*ReconcileReport)) Job {
+	return Job{
+		Name:     "reconcile",
+		Interval: interval,
+		Run: func(ctx context.Context) error {
+			report, err := Reconcile(input)
+			if handler != nil && report != nil {
+				handler(report)
+			}
+			return err
+		},
+	}
+}
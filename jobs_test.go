@@ -0,0 +1,125 @@
+package filesapi
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestJobRunner(t *testing.T) {
+	var mu sync.Mutex
+	runs := map[string][]JobRun{}
+	ctx, cancel := context.WithCancel(context.Background())
+	jr, err := NewJobRunner(JobRunnerConfig{
+		Jobs: []Job{
+			{
+				Name:       "sweep",
+				Interval:   5 * time.Millisecond,
+				Jitter:     time.Millisecond,
+				RunAtStart: true,
+				Run:        func(ctx context.Context) error { return nil },
+			},
+			{
+				Name:     "failing",
+				Interval: 5 * time.Millisecond,
+				Run:      func(ctx context.Context) error { return errors.New("store unavailable") },
+			},
+			{
+				Name:     "panicking",
+				Interval: 5 * time.Millisecond,
+				Run:      func(ctx context.Context) error { panic("bad state") },
+			},
+		},
+		OnRun: func(run JobRun) {
+			mu.Lock()
+			defer mu.Unlock()
+			runs[run.Job] = append(runs[run.Job], run)
+			if len(runs["sweep"]) >= 3 && len(runs["failing"]) >= 2 && len(runs["panicking"]) >= 2 {
+				cancel()
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() { done <- jr.Run(ctx) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected the runner to stop with the context, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("job runner did not stop")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if runs["sweep"][0].Err != nil || runs["failing"][0].Err == nil {
+		t.Fatalf("unexpected run errors %v %v", runs["sweep"][0].Err, runs["failing"][0].Err)
+	}
+	if err := runs["panicking"][0].Err; err == nil || !strings.Contains(err.Error(), "panicked") {
+		t.Fatalf("expected the panic to be reported, got %v", err)
+	}
+}
+
+func TestRunJob(t *testing.T) {
+	timedOut := Job{
+		Name:     "slow",
+		Interval: time.Hour,
+		Timeout:  10 * time.Millisecond,
+		Run: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	jr, err := NewJobRunner(JobRunnerConfig{Jobs: []Job{timedOut}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run, err := jr.RunJob(context.Background(), "slow")
+	if err != nil || !errors.Is(run.Err, context.DeadlineExceeded) {
+		t.Fatalf("expected the run to time out, got %v %v", run.Err, err)
+	}
+	if _, err := jr.RunJob(context.Background(), "missing"); err == nil {
+		t.Fatal("expected an unknown job error")
+	}
+
+	invalid := [][]Job{
+		{{Interval: time.Second, Run: timedOut.Run}},
+		{{Name: "a", Run: timedOut.Run}},
+		{{Name: "a", Interval: time.Second}},
+		{timedOut, timedOut},
+	}
+	for _, jobs := range invalid {
+		if _, err := NewJobRunner(JobRunnerConfig{Jobs: jobs}); err == nil {
+			t.Fatalf("expected %v to be rejected", jobs)
+		}
+	}
+}
+
+func TestGarbageJob(t *testing.T) {
+	store, err := NewFileStore(BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "empty", "nested"), os.ModePerm)
+	jr, err := NewJobRunner(JobRunnerConfig{Jobs: []Job{
+		GarbageJob(GarbageReportInput{Store: store, Prefix: PathConfig{Path: root}}, time.Hour),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run, err := jr.RunJob(context.Background(), "garbage")
+	if err != nil || run.Err != nil {
+		t.Fatalf("unexpected garbage job errors %v %v", run.Err, err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "empty")); !os.IsNotExist(err) {
+		t.Fatalf("expected the empty directories to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package filesapi
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"time"
+)
+
+// DefaultMarkerName is the conventional name of the completion marker written to a prefix
+const DefaultMarkerName string = "_SUCCESS"
+
+// MarkerPath returns the path of a completion marker in a prefix.
+// name defaults to DefaultMarkerName
+func MarkerPath(prefix string, name string) PathConfig {
+	if name == "" {
+		name = DefaultMarkerName
+	}
+	return PathConfig{Path: strings.TrimSuffix(prefix, "/") + "/" + name}
+}
+
+// WriteMarker signals that the data in a prefix is complete by writing a marker object
+// with an optional payload (i.e. a JSON summary of the run).  The marker is written
+// with a conditional put, so only one producer can signal completion; later writers
+// receive an error wrapping ErrObjectExists
+func WriteMarker(store FileStore, marker PathConfig, payload []byte) error {
+	source := ObjectSource{Data: payload}
+	if len(payload) == 0 {
+		//empty byte slice sources create directories in block file stores
+		source = ObjectSource{Reader: bytes.NewReader(nil)}
+	}
+	_, err := store.PutObject(PutObjectInput{
+		Source:      source,
+		Dest:        marker,
+		IfNoneMatch: true,
+	})
+	return err
+}
+
+// WaitForMarker waits until a completion marker is visible and returns its payload.
+// Returns an error wrapping ErrWaitTimeout if the marker does not appear before the timeout
+func WaitForMarker(store FileStore, marker PathConfig, timeout time.Duration) ([]byte, error) {
+	if _, err := WaitForObject(store, marker, timeout); err != nil {
+		return nil, err
+	}
+	reader, err := store.GetObject(GetObjectInput{Path: marker})
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
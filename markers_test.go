@@ -0,0 +1,39 @@
+package filesapi
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMarkers(t *testing.T) {
+	store := &BlockFS{Config: BlockFSConfig{ChunkSize: 100}}
+	marker := MarkerPath(t.TempDir()+"/run/", "")
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		if err := WriteMarker(store, marker, []byte(`{"files":3}`)); err != nil {
+			t.Error(err)
+		}
+	}()
+	payload, err := WaitForMarker(store, marker, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(payload) != `{"files":3}` {
+		t.Fatalf("unexpected marker payload %s", payload)
+	}
+
+	err = WriteMarker(store, marker, nil)
+	if !errors.Is(err, ErrObjectExists) {
+		t.Fatalf("expected a second marker write to fail, got %v", err)
+	}
+
+	empty := MarkerPath(t.TempDir(), ".done")
+	if err = WriteMarker(store, empty, nil); err != nil {
+		t.Fatal(err)
+	}
+	if payload, err = WaitForMarker(store, empty, time.Second); err != nil || len(payload) != 0 {
+		t.Fatalf("expected an empty marker, got %q %v", payload, err)
+	}
+}
@@ -0,0 +1,195 @@
+package filesapi
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"sync/atomic"
+)
+
+type MigrationReadMode int
+
+const (
+	//serve reads from the old store.  The new store is probed with GetObjectInfo
+	//to measure how many reads it could have served
+	MigrationReadOld MigrationReadMode = iota
+
+	//serve reads from the new store, falling back to the old store for objects not yet migrated
+	MigrationReadNewFirst
+)
+
+// MigrationStats counts reads served by each store during a migration
+type MigrationStats struct {
+	Reads int64
+
+	//reads the old store served
+	OldHits int64
+
+	//reads the new store served or, in MigrationReadOld mode, could have served
+	NewHits int64
+
+	//writes that succeeded on the old store but failed on the new store
+	ShadowWriteFailures int64
+}
+
+// NewHitRate is the fraction of reads the new store served or could have served.
+// A rate at or near 1.0 indicates the new store is ready for cutover
+func (ms MigrationStats) NewHitRate() float64 {
+	if ms.Reads == 0 {
+		return 0
+	}
+	return float64(ms.NewHits) / float64(ms.Reads)
+}
+
+// MigrationFS supports gradual migration between stores (i.e. on-prem BlockFS to S3).
+// The embedded FileStore is the old store and remains the system of record: writes,
+// copies, moves, and deletes go to the old store first and are then shadowed to the new store.
+// Reads follow ReadMode and are tallied in Stats to measure a cutover point.
+// Shadow write failures never fail the operation; they are counted and passed to OnShadowError
+type MigrationFS struct {
+	FileStore
+	New FileStore
+
+	ReadMode MigrationReadMode
+
+	//optional callback for failed shadow writes to the new store
+	OnShadowError func(path string, err error)
+
+	reads               int64
+	oldHits             int64
+	newHits             int64
+	shadowWriteFailures int64
+}
+
+func NewMigrationFS(old FileStore, newStore FileStore) *MigrationFS {
+	return &MigrationFS{
+		FileStore: old,
+		New:       newStore,
+	}
+}
+
+func (mfs *MigrationFS) Stats() MigrationStats {
+	return MigrationStats{
+		Reads:               atomic.LoadInt64(&mfs.reads),
+		OldHits:             atomic.LoadInt64(&mfs.oldHits),
+		NewHits:             atomic.LoadInt64(&mfs.newHits),
+		ShadowWriteFailures: atomic.LoadInt64(&mfs.shadowWriteFailures),
+	}
+}
+
+func (mfs *MigrationFS) GetObjectInfo(path PathConfig) (fs.FileInfo, error) {
+	if mfs.ReadMode == MigrationReadNewFirst {
+		info, err := mfs.New.GetObjectInfo(path)
+		if !errors.As(err, &fileNotFoundError) {
+			return info, err
+		}
+	}
+	return mfs.FileStore.GetObjectInfo(path)
+}
+
+func (mfs *MigrationFS) GetObject(input GetObjectInput) (io.ReadCloser, error) {
+	atomic.AddInt64(&mfs.reads, 1)
+	if mfs.ReadMode == MigrationReadNewFirst {
+		reader, err := mfs.New.GetObject(input)
+		if err == nil {
+			atomic.AddInt64(&mfs.newHits, 1)
+			return reader, nil
+		}
+		if !errors.As(err, &fileNotFoundError) {
+			return nil, err
+		}
+	} else if _, err := mfs.New.GetObjectInfo(input.Path); err == nil {
+		atomic.AddInt64(&mfs.newHits, 1)
+	}
+	reader, err := mfs.FileStore.GetObject(input)
+	if err == nil {
+		atomic.AddInt64(&mfs.oldHits, 1)
+	}
+	return reader, err
+}
+
+func (mfs *MigrationFS) PutObject(input PutObjectInput) (*FileOperationOutput, error) {
+	output, err := mfs.FileStore.PutObject(input)
+	if err != nil {
+		return output, err
+	}
+	if input.Source.Replayable() {
+		input.IfNoneMatch = false
+		_, err = mfs.New.PutObject(input)
+		mfs.shadowFailed(input.Dest.Path, err)
+	} else {
+		mfs.shadowCopy(input.Dest)
+	}
+	return output, nil
+}
+
+func (mfs *MigrationFS) CopyObject(input CopyObjectInput) error {
+	err := mfs.FileStore.CopyObject(input)
+	if err == nil {
+		mfs.shadowCopy(input.Dest)
+	}
+	return err
+}
+
+// MoveObject moves the object in both stores.  Objects not yet migrated are copied to
+// the destination in the new store
+func (mfs *MigrationFS) MoveObject(input MoveObjectInput) error {
+	err := mfs.FileStore.MoveObject(input)
+	if err != nil {
+		return err
+	}
+	input.Progress = nil
+	srcs := input.Src.All()
+	for i, dest := range input.Dest.All() {
+		move := input
+		move.Src = PathConfig{Path: srcs[i]}
+		move.Dest = PathConfig{Path: dest}
+		err := mfs.New.MoveObject(move)
+		if errors.As(err, &fileNotFoundError) || errors.Is(err, fs.ErrNotExist) {
+			mfs.shadowCopy(move.Dest)
+			continue
+		}
+		mfs.shadowFailed(dest, err)
+	}
+	return nil
+}
+
+func (mfs *MigrationFS) CompleteObjectUpload(u CompletedObjectUploadConfig) error {
+	err := mfs.FileStore.CompleteObjectUpload(u)
+	if err == nil {
+		mfs.shadowCopy(PathConfig{Path: u.ObjectPath})
+	}
+	return err
+}
+
+func (mfs *MigrationFS) DeleteObjects(input DeleteObjectInput) []error {
+	errs := mfs.FileStore.DeleteObjects(input)
+	if firstError(errs) == nil {
+		input.Progress = nil
+		for _, err := range mfs.New.DeleteObjects(input) {
+			//objects not yet migrated are missing from the new store
+			if err != nil && !errors.As(err, &fileNotFoundError) && !errors.Is(err, fs.ErrNotExist) {
+				mfs.shadowFailed(strings.Join(input.Paths.All(), ","), err)
+			}
+		}
+	}
+	return errs
+}
+
+// copies an object written to the old store into the new store
+func (mfs *MigrationFS) shadowCopy(path PathConfig) {
+	for _, p := range path.All() {
+		mfs.shadowFailed(p, streamObject(mfs.FileStore, mfs.New, PathConfig{Path: p}))
+	}
+}
+
+func (mfs *MigrationFS) shadowFailed(path string, err error) {
+	if err == nil {
+		return
+	}
+	atomic.AddInt64(&mfs.shadowWriteFailures, 1)
+	if mfs.OnShadowError != nil {
+		mfs.OnShadowError(path, err)
+	}
+}
@@ -0,0 +1,119 @@
+package filesapi
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// resolves object paths under a root directory so two BlockFS stores can hold the same paths
+type rootedFS struct {
+	*BlockFS
+	root string
+}
+
+func (r *rootedFS) path(p PathConfig) PathConfig {
+	rooted := PathConfig{}
+	if p.Path != "" {
+		rooted.Path = filepath.Join(r.root, p.Path)
+	}
+	for _, path := range p.Paths {
+		rooted.Paths = append(rooted.Paths, filepath.Join(r.root, path))
+	}
+	return rooted
+}
+
+func (r *rootedFS) GetObjectInfo(path PathConfig) (fs.FileInfo, error) {
+	return r.BlockFS.GetObjectInfo(r.path(path))
+}
+
+func (r *rootedFS) GetObject(input GetObjectInput) (io.ReadCloser, error) {
+	input.Path = r.path(input.Path)
+	return r.BlockFS.GetObject(input)
+}
+
+func (r *rootedFS) PutObject(input PutObjectInput) (*FileOperationOutput, error) {
+	input.Dest = r.path(input.Dest)
+	return r.BlockFS.PutObject(input)
+}
+
+func (r *rootedFS) DeleteObjects(input DeleteObjectInput) []error {
+	input.Paths = r.path(input.Paths)
+	return r.BlockFS.DeleteObjects(input)
+}
+
+func (r *rootedFS) Walk(input WalkInput, visitor FileVisitFunction) error {
+	input.Path = r.path(input.Path)
+	return r.BlockFS.Walk(input, func(path string, file os.FileInfo) error {
+		return visitor(strings.TrimPrefix(path, r.root), file)
+	})
+}
+
+func TestMigrationFS(t *testing.T) {
+	oldDir := t.TempDir()
+	newDir := t.TempDir()
+	legacy := filepath.Join(oldDir, "legacy.txt")
+	if err := os.WriteFile(legacy, []byte("legacy"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	old := &rootedFS{BlockFS: &BlockFS{}, root: oldDir}
+	newStore := &rootedFS{BlockFS: &BlockFS{}, root: newDir}
+	mfs := NewMigrationFS(old, newStore)
+	_, err := mfs.PutObject(PutObjectInput{Source: ObjectSource{Data: []byte("bytes")}, Dest: PathConfig{Path: "/bytes.txt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = mfs.PutObject(PutObjectInput{Source: ObjectSource{Reader: bytes.NewBufferString("stream")}, Dest: PathConfig{Path: "/stream.txt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"bytes.txt", "stream.txt"} {
+		if _, err = os.Stat(filepath.Join(newDir, name)); err != nil {
+			t.Fatalf("expected %s to be shadowed to the new store: %s", name, err)
+		}
+	}
+
+	for _, p := range []string{"/bytes.txt", "/legacy.txt"} {
+		reader, err := mfs.GetObject(GetObjectInput{Path: PathConfig{Path: p}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, reader)
+		reader.Close()
+	}
+	stats := mfs.Stats()
+	if stats.Reads != 2 || stats.OldHits != 2 || stats.NewHits != 1 || stats.NewHitRate() != 0.5 {
+		t.Fatalf("unexpected read-old stats %+v", stats)
+	}
+
+	mfs.ReadMode = MigrationReadNewFirst
+	reader, err := mfs.GetObject(GetObjectInput{Path: PathConfig{Path: "/legacy.txt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(data) != "legacy" {
+		t.Fatalf("expected fallback to the old store, got %q", data)
+	}
+	stats = mfs.Stats()
+	if stats.OldHits != 3 || stats.NewHits != 1 || stats.ShadowWriteFailures != 0 {
+		t.Fatalf("unexpected read-new-first stats %+v", stats)
+	}
+
+	errs := mfs.DeleteObjects(DeleteObjectInput{Paths: PathConfig{Paths: []string{"/bytes.txt", "/legacy.txt"}}})
+	if firstError(errs) != nil {
+		t.Fatal(errs)
+	}
+	if _, err = os.Stat(filepath.Join(newDir, "bytes.txt")); !os.IsNotExist(err) {
+		t.Fatal("expected the delete to be shadowed to the new store")
+	}
+	if mfs.Stats().ShadowWriteFailures != 0 {
+		t.Fatal("unmigrated objects should not count as shadow failures")
+	}
+}
@@ -0,0 +1,222 @@
+package filesapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	minioAdminPath     string = "/minio/admin/v3"
+	minioDefaultRegion string = "us-east-1"
+	QuotaTypeHard      string = "hard"
+)
+
+// BucketQuota is a Minio bucket size quota
+type BucketQuota struct {
+
+	//quota in bytes.  Zero removes the quota
+	Quota int64 `json:"quota"`
+
+	//quota type.  Minio only enforces hard quotas.  Defaults to QuotaTypeHard
+	Type string `json:"quotatype,omitempty"`
+}
+
+// BucketNotification routes bucket events to a target configured on the Minio server
+type BucketNotification struct {
+
+	//target ARN, i.e. arn:minio:sqs::primary:webhook
+	Arn string
+
+	//S3 event names, i.e. s3:ObjectCreated:*
+	Events []string
+
+	//optional key filters
+	Prefix string
+	Suffix string
+}
+
+// ObjectLockConfig is the default retention applied to new objects in an object lock bucket
+type ObjectLockConfig struct {
+
+	//GOVERNANCE or COMPLIANCE
+	Mode string
+	Days int32
+}
+
+// MinioAdmin provisions standalone Minio deployments: bucket creation with object locking,
+// bucket notifications, and quotas.  Notification and locking use the S3 API while quotas
+// use the Minio admin API, signed with the store's static credentials
+type MinioAdmin struct {
+	config     MinioFSConfig
+	store      *S3FS
+	httpClient *http.Client
+}
+
+func NewMinioAdmin(config MinioFSConfig) (*MinioAdmin, error) {
+	store, err := NewFileStore(config)
+	if err != nil {
+		return nil, err
+	}
+	return &MinioAdmin{
+		config:     config,
+		store:      store.(*S3FS),
+		httpClient: http.DefaultClient,
+	}, nil
+}
+
+// Store returns the S3FS for the admin's bucket
+func (ma *MinioAdmin) Store() *S3FS {
+	return ma.store
+}
+
+// CreateBucket creates a bucket.  Object locking can only be enabled when a bucket is created
+func (ma *MinioAdmin) CreateBucket(bucket string, objectLock bool) error {
+	client, err := ma.store.client()
+	if err != nil {
+		return err
+	}
+	input := &s3.CreateBucketInput{Bucket: &bucket}
+	if objectLock {
+		input.ObjectLockEnabledForBucket = aws.Bool(true)
+	}
+	_, err = client.CreateBucket(context.TODO(), input)
+	return err
+}
+
+// SetObjectLock sets the default retention for new objects in a bucket created with object locking
+func (ma *MinioAdmin) SetObjectLock(bucket string, lock ObjectLockConfig) error {
+	client, err := ma.store.client()
+	if err != nil {
+		return err
+	}
+	_, err = client.PutObjectLockConfiguration(context.TODO(), &s3.PutObjectLockConfigurationInput{
+		Bucket: &bucket,
+		ObjectLockConfiguration: &types.ObjectLockConfiguration{
+			ObjectLockEnabled: types.ObjectLockEnabledEnabled,
+			Rule: &types.ObjectLockRule{
+				DefaultRetention: &types.DefaultRetention{
+					Mode: types.ObjectLockRetentionMode(strings.ToUpper(lock.Mode)),
+					Days: &lock.Days,
+				},
+			},
+		},
+	})
+	return err
+}
+
+// SetBucketNotifications replaces the notification configuration of a bucket.
+// An empty list removes all notifications
+func (ma *MinioAdmin) SetBucketNotifications(bucket string, notifications []BucketNotification) error {
+	client, err := ma.store.client()
+	if err != nil {
+		return err
+	}
+	queues := []types.QueueConfiguration{}
+	for _, n := range notifications {
+		if n.Arn == "" || len(n.Events) == 0 {
+			return errors.New("bucket notifications require a target arn and at least one event")
+		}
+		queue := types.QueueConfiguration{QueueArn: aws.String(n.Arn)}
+		for _, e := range n.Events {
+			queue.Events = append(queue.Events, types.Event(e))
+		}
+		rules := []types.FilterRule{}
+		if n.Prefix != "" {
+			rules = append(rules, types.FilterRule{Name: types.FilterRuleNamePrefix, Value: aws.String(n.Prefix)})
+		}
+		if n.Suffix != "" {
+			rules = append(rules, types.FilterRule{Name: types.FilterRuleNameSuffix, Value: aws.String(n.Suffix)})
+		}
+		if len(rules) > 0 {
+			queue.Filter = &types.NotificationConfigurationFilter{Key: &types.S3KeyFilter{FilterRules: rules}}
+		}
+		queues = append(queues, queue)
+	}
+	_, err = client.PutBucketNotificationConfiguration(context.TODO(), &s3.PutBucketNotificationConfigurationInput{
+		Bucket:                    &bucket,
+		NotificationConfiguration: &types.NotificationConfiguration{QueueConfigurations: queues},
+	})
+	return err
+}
+
+// SetBucketQuota sets the size quota of a bucket through the Minio admin API
+func (ma *MinioAdmin) SetBucketQuota(bucket string, quota BucketQuota) error {
+	if quota.Type == "" {
+		quota.Type = QuotaTypeHard
+	}
+	data, err := json.Marshal(quota)
+	if err != nil {
+		return err
+	}
+	_, err = ma.adminRequest(http.MethodPut, "set-bucket-quota", bucket, data)
+	return err
+}
+
+// GetBucketQuota gets the size quota of a bucket through the Minio admin API
+func (ma *MinioAdmin) GetBucketQuota(bucket string) (BucketQuota, error) {
+	quota := BucketQuota{}
+	data, err := ma.adminRequest(http.MethodGet, "get-bucket-quota", bucket, nil)
+	if err != nil {
+		return quota, err
+	}
+	err = json.Unmarshal(data, &quota)
+	return quota, err
+}
+
+// sends a SigV4 signed request to the Minio admin API and returns the response body
+func (ma *MinioAdmin) adminRequest(method string, api string, bucket string, body []byte) ([]byte, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(ma.config.HostAddress, "/") + minioAdminPath + "/" + api)
+	if err != nil {
+		return nil, err
+	}
+	endpoint.RawQuery = url.Values{"bucket": []string{bucket}}.Encode()
+	req, err := http.NewRequest(method, endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	payloadHash := sha256.Sum256(body)
+	hexHash := hex.EncodeToString(payloadHash[:])
+	req.Header.Set("X-Amz-Content-Sha256", hexHash)
+
+	creds, _ := ma.config.Credentials.(S3FS_Static)
+	region := ma.config.S3Region
+	if region == "" {
+		region = minioDefaultRegion
+	}
+	err = v4.NewSigner().SignHTTP(context.TODO(), aws.Credentials{
+		AccessKeyID:     creds.S3Id,
+		SecretAccessKey: creds.S3Key,
+	}, req, hexHash, "s3", region, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := ma.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("minio admin %s failed with status %d: %s", api, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return data, nil
+}
@@ -0,0 +1,68 @@
+package filesapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMinioAdmin(t *testing.T) {
+	quotas := map[string]BucketQuota{}
+	notifications := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		bucket := r.URL.Query().Get("bucket")
+		switch {
+		case r.URL.Path == minioAdminPath+"/set-bucket-quota":
+			quota := BucketQuota{}
+			json.Unmarshal(body, &quota)
+			quotas[bucket] = quota
+		case r.URL.Path == minioAdminPath+"/get-bucket-quota":
+			json.NewEncoder(w).Encode(quotas[bucket])
+		case r.URL.Query().Has("notification"):
+			notifications = string(body)
+		}
+	}))
+	defer server.Close()
+
+	admin, err := NewMinioAdmin(MinioFSConfig{
+		S3FSConfig: S3FSConfig{
+			S3Region:    "us-east-1",
+			S3Bucket:    "model-runs",
+			Credentials: S3FS_Static{S3Id: "minio", S3Key: "minio123"},
+		},
+		HostAddress: server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = admin.SetBucketQuota("model-runs", BucketQuota{Quota: 1 << 30}); err != nil {
+		t.Fatal(err)
+	}
+	quota, err := admin.GetBucketQuota("model-runs")
+	if err != nil || quota.Quota != 1<<30 || quota.Type != QuotaTypeHard {
+		t.Fatalf("unexpected quota %+v %v", quota, err)
+	}
+
+	err = admin.SetBucketNotifications("model-runs", []BucketNotification{{
+		Arn:    "arn:minio:sqs::primary:webhook",
+		Events: []string{"s3:ObjectCreated:*"},
+		Suffix: ".dss",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(notifications, "arn:minio:sqs::primary:webhook") || !strings.Contains(notifications, ".dss") {
+		t.Fatalf("unexpected notification configuration %s", notifications)
+	}
+	if err = admin.SetBucketNotifications("model-runs", []BucketNotification{{Arn: "arn"}}); err == nil {
+		t.Fatal("expected an error for a notification without events")
+	}
+}
@@ -0,0 +1,156 @@
+package filesapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ErrObjectLocked matches errors for operations refused because an object version is
+// protected by S3 object lock retention or a legal hold (see ObjectLockedError)
+var ErrObjectLocked = errors.New("object is locked")
+
+// ObjectLockedError is an operation refused by object lock.  The lock state is read
+// when the error is created, and is left empty if it could not be read
+type ObjectLockedError struct {
+	Path      string
+	VersionId string
+
+	//retention mode (GOVERNANCE or COMPLIANCE) and the time the retention expires
+	Mode        string
+	RetainUntil time.Time
+
+	LegalHold bool
+
+	//error reported by the store
+	Err error
+}
+
+func (e *ObjectLockedError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "object %s", e.Path)
+	if e.VersionId != "" {
+		fmt.Fprintf(&sb, " (version %s)", e.VersionId)
+	}
+	sb.WriteString(" is locked")
+	if e.LegalHold {
+		sb.WriteString(" by a legal hold")
+	}
+	if !e.RetainUntil.IsZero() {
+		fmt.Fprintf(&sb, " with %s retention until %s", e.Mode, e.RetainUntil.Format(time.RFC3339))
+	}
+	if e.Err != nil {
+		fmt.Fprintf(&sb, ": %s", e.Err)
+	}
+	return sb.String()
+}
+
+func (e *ObjectLockedError) Is(target error) bool {
+	return target == ErrObjectLocked
+}
+
+func (e *ObjectLockedError) Unwrap() error {
+	return e.Err
+}
+
+// ObjectHold is the object lock state of an object
+type ObjectHold struct {
+	Path string
+
+	//retention mode (GOVERNANCE or COMPLIANCE) and the time the retention expires
+	Mode        string
+	RetainUntil time.Time
+
+	LegalHold bool
+}
+
+// Held reports whether the object cannot be deleted at a time
+func (oh ObjectHold) Held(now time.Time) bool {
+	return oh.LegalHold || oh.RetainUntil.After(now)
+}
+
+// ObjectHoldLister is implemented by stores that support object lock
+type ObjectHoldLister interface {
+	ListHeldObjects(prefix PathConfig) ([]ObjectHold, error)
+}
+
+// ListHeldObjects returns the objects under a prefix that are under a legal hold or
+// unexpired retention, so cleanup tools can report objects they will be unable to delete.
+// The lock state of each object is read with a HeadObject request
+func (s3fs *S3FS) ListHeldObjects(prefix PathConfig) ([]ObjectHold, error) {
+	holds := []ObjectHold{}
+	now := time.Now()
+	err := s3fs.Walk(WalkInput{Path: prefix}, func(p string, info os.FileInfo) error {
+		if info.IsDir() {
+			return nil
+		}
+		hold, err := s3fs.objectHold(context.TODO(), strings.TrimPrefix(p, "/"), "")
+		if err != nil {
+			return err
+		}
+		if hold.Held(now) {
+			holds = append(holds, hold)
+		}
+		return nil
+	})
+	return holds, err
+}
+
+// reads the lock state of an object version.  An empty version reads the current version
+func (s3fs *S3FS) objectHold(ctx context.Context, key string, versionId string) (ObjectHold, error) {
+	client, err := s3fs.client()
+	if err != nil {
+		return ObjectHold{}, err
+	}
+	input := &s3.HeadObjectInput{
+		Bucket: &s3fs.config.S3Bucket,
+		Key:    &key,
+	}
+	if versionId != "" {
+		input.VersionId = &versionId
+	}
+	out, err := client.HeadObject(ctx, input)
+	if err != nil {
+		return ObjectHold{}, err
+	}
+	hold := ObjectHold{
+		Path:      key,
+		Mode:      string(out.ObjectLockMode),
+		LegalHold: out.ObjectLockLegalHoldStatus == types.ObjectLockLegalHoldStatusOn,
+	}
+	if out.ObjectLockRetainUntilDate != nil {
+		hold.RetainUntil = *out.ObjectLockRetainUntilDate
+	}
+	return hold, nil
+}
+
+// reports whether an S3 error refused an operation because of object lock.  S3 and
+// Minio use generic codes for lock failures, so the message is checked as well
+func isObjectLockError(code string, message string) bool {
+	if code == "ObjectLocked" {
+		return true
+	}
+	if code != "AccessDenied" && code != "InvalidRequest" {
+		return false
+	}
+	m := strings.ToLower(message)
+	return strings.Contains(m, "object lock") || strings.Contains(m, "worm") ||
+		strings.Contains(m, "legal hold") || strings.Contains(m, "retention")
+}
+
+// describes an operation refused by object lock with the lock state of the object version
+func (s3fs *S3FS) objectLockedError(ctx context.Context, key string, versionId string, err error) *ObjectLockedError {
+	lockErr := &ObjectLockedError{Path: key, VersionId: versionId, Err: err}
+	if hold, herr := s3fs.objectHold(ctx, key, versionId); herr == nil {
+		lockErr.Mode = hold.Mode
+		lockErr.RetainUntil = hold.RetainUntil
+		lockErr.LegalHold = hold.LegalHold
+	}
+	return lockErr
+}
@@ -0,0 +1,80 @@
+package filesapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestObjectLock(t *testing.T) {
+	retainUntil := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	//data/held has compliance retention, data/hold has a legal hold, and data/expired has expired retention
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		switch {
+		case r.Method == http.MethodHead:
+			switch key {
+			case "data/held":
+				w.Header().Set("x-amz-object-lock-mode", "COMPLIANCE")
+				w.Header().Set("x-amz-object-lock-retain-until-date", retainUntil.Format(time.RFC3339))
+			case "data/hold":
+				w.Header().Set("x-amz-object-lock-legal-hold", "ON")
+			case "data/expired":
+				w.Header().Set("x-amz-object-lock-mode", "GOVERNANCE")
+				w.Header().Set("x-amz-object-lock-retain-until-date", time.Now().Add(-time.Hour).UTC().Format(time.RFC3339))
+			}
+		case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+			fmt.Fprint(w, "<ListBucketResult><Name>bucket</Name><KeyCount>4</KeyCount><IsTruncated>false</IsTruncated>")
+			for _, key := range []string{"data/expired", "data/free", "data/held", "data/hold"} {
+				fmt.Fprintf(w, "<Contents><Key>%s</Key><Size>1</Size></Contents>", key)
+			}
+			fmt.Fprint(w, "</ListBucketResult>")
+		case r.Method == http.MethodPost && r.URL.Query().Has("delete"):
+			fmt.Fprint(w, "<DeleteResult><Error><Key>data/held</Key><VersionId>v1</VersionId><Code>AccessDenied</Code>")
+			fmt.Fprint(w, "<Message>Access Denied because object protected by object lock.</Message></Error></DeleteResult>")
+		}
+	}))
+	defer server.Close()
+	store, err := NewFileStore(MinioFSConfig{
+		S3FSConfig: S3FSConfig{
+			S3Region:    "us-east-1",
+			S3Bucket:    "bucket",
+			Credentials: S3FS_Static{S3Id: "id", S3Key: "key"},
+		},
+		HostAddress: server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s3fs := store.(*S3FS)
+
+	report, err := s3fs.DeletePrefix(DeletePrefixInput{Prefix: PathConfig{Path: "/data/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := report.Errors()
+	if len(errs) != 1 || !errors.Is(errs[0], ErrObjectLocked) {
+		t.Fatalf("expected a locked object error, got %v", errs)
+	}
+	var lockErr *ObjectLockedError
+	if !errors.As(errs[0], &lockErr) || lockErr.Mode != "COMPLIANCE" || !lockErr.RetainUntil.Equal(retainUntil) || lockErr.VersionId != "v1" {
+		t.Fatalf("expected the retention of the locked object, got %+v", lockErr)
+	}
+
+	holds, err := s3fs.ListHeldObjects(PathConfig{Path: "/data/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holds) != 2 || holds[0].Path != "data/held" || holds[1].Path != "data/hold" || !holds[1].LegalHold {
+		t.Fatalf("unexpected held objects %+v", holds)
+	}
+	var _ ObjectHoldLister = s3fs
+
+	if isObjectLockError("AccessDenied", "denied") || !isObjectLockError("InvalidRequest", "Object is WORM protected and cannot be overwritten") {
+		t.Fatal("unexpected object lock error classification")
+	}
+}
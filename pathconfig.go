@@ -0,0 +1,96 @@
+package filesapi
+
+import (
+	"fmt"
+	"io/fs"
+	"time"
+)
+
+// ResourceInfo describes a multi-file resource.  Size is the total size of the
+// files, ModTime the latest modification, and Name the name of the first file
+type ResourceInfo struct {
+	Files []fs.FileInfo
+}
+
+func (ri *ResourceInfo) Name() string {
+	return ri.Files[0].Name()
+}
+
+func (ri *ResourceInfo) Size() int64 {
+	var size int64
+	for _, f := range ri.Files {
+		size += f.Size()
+	}
+	return size
+}
+
+func (ri *ResourceInfo) Mode() fs.FileMode {
+	return ri.Files[0].Mode()
+}
+
+func (ri *ResourceInfo) ModTime() time.Time {
+	modTime := ri.Files[0].ModTime()
+	for _, f := range ri.Files[1:] {
+		if f.ModTime().After(modTime) {
+			modTime = f.ModTime()
+		}
+	}
+	return modTime
+}
+
+func (ri *ResourceInfo) IsDir() bool {
+	return false
+}
+
+func (ri *ResourceInfo) Sys() any {
+	return nil
+}
+
+// returns a ResourceInfo for paths with more than one file.  Fails if any file is missing
+func resourceInfo(paths []string, stat func(PathConfig) (fs.FileInfo, error)) (fs.FileInfo, error) {
+	ri := &ResourceInfo{Files: make([]fs.FileInfo, len(paths))}
+	for i, p := range paths {
+		info, err := stat(PathConfig{Path: p})
+		if err != nil {
+			return nil, err
+		}
+		ri.Files[i] = info
+	}
+	return ri, nil
+}
+
+// copies each source path to the destination path at the same position
+func copyEach(coi CopyObjectInput, copy func(CopyObjectInput) error) error {
+	srcs := coi.Src.All()
+	dests := coi.Dest.All()
+	if len(srcs) != len(dests) {
+		return fmt.Errorf("Unable to copy %d source paths to %d destination paths", len(srcs), len(dests))
+	}
+	for i := range srcs {
+		input := coi
+		input.Src = PathConfig{Path: srcs[i]}
+		input.Dest = PathConfig{Path: dests[i]}
+		if err := copy(input); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// moves each source path to the destination path at the same position
+func moveEach(moi MoveObjectInput, move func(MoveObjectInput) error) error {
+	srcs := moi.Src.All()
+	dests := moi.Dest.All()
+	if len(srcs) != len(dests) {
+		return fmt.Errorf("Unable to move %d source paths to %d destination paths", len(srcs), len(dests))
+	}
+	for i := range srcs {
+		input := moi
+		input.Src = PathConfig{Path: srcs[i]}
+		input.Dest = PathConfig{Path: dests[i]}
+		if err := move(input); err != nil {
+			return err
+		}
+	}
+	return nil
+}
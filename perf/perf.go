@@ -0,0 +1,409 @@
+// Package perf runs standardized workloads against a filesapi.FileStore and reports
+// throughput and latency percentiles, so store configurations (i.e. Minio, S3, and
+// local block storage) can be compared on the same hardware before a deployment.
+//
+// Each workload writes beneath Config.Prefix and deletes what it wrote when it finishes.
+// Workloads:
+//   - small-object-churn puts, gets, and deletes many small objects concurrently
+//   - large-multipart writes and reads back large objects with multipart uploads
+//   - deep-listing builds a nested directory tree, then times ListDir of every directory and a Walk of the tree
+package perf
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/usace/filesapi"
+)
+
+type Workload string
+
+const (
+	SmallObjectChurn Workload = "small-object-churn"
+	LargeMultipart   Workload = "large-multipart"
+	DeepListing      Workload = "deep-listing"
+)
+
+// Workloads are the standard workloads, in the order Run executes them by default
+var Workloads = []Workload{SmallObjectChurn, LargeMultipart, DeepListing}
+
+const (
+	defaultConcurrency     int   = 8
+	defaultSmallObjects    int   = 200
+	defaultSmallObjectSize int   = 4 * 1024
+	defaultLargeObjects    int   = 2
+	defaultLargeObjectSize int64 = 64 * 1024 * 1024
+	defaultPartSize        int   = 8 * 1024 * 1024
+	defaultListingDepth    int   = 4
+	defaultListingFanout   int   = 3
+)
+
+// Config describes the store under test and the size of each workload.
+// Zero values use the package defaults
+type Config struct {
+	Store filesapi.FileStore
+
+	//label for the store in reports.  Defaults to the store resource name
+	Name string
+
+	//path the workloads write beneath.  Use a prefix that holds no other data;
+	//workloads delete the objects they create
+	Prefix string
+
+	//concurrent operations for the small object and listing workloads
+	Concurrency int
+
+	SmallObjects    int
+	SmallObjectSize int
+
+	LargeObjects    int
+	LargeObjectSize int64
+	PartSize        int
+
+	//depth and directory fanout of the deep listing tree.  Each leaf directory holds one object
+	ListingDepth  int
+	ListingFanout int
+}
+
+func (c Config) withDefaults() Config {
+	if c.Name == "" {
+		c.Name = c.Store.ResourceName()
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = defaultConcurrency
+	}
+	if c.SmallObjects <= 0 {
+		c.SmallObjects = defaultSmallObjects
+	}
+	if c.SmallObjectSize <= 0 {
+		c.SmallObjectSize = defaultSmallObjectSize
+	}
+	if c.LargeObjects <= 0 {
+		c.LargeObjects = defaultLargeObjects
+	}
+	if c.LargeObjectSize <= 0 {
+		c.LargeObjectSize = defaultLargeObjectSize
+	}
+	if c.PartSize <= 0 {
+		c.PartSize = defaultPartSize
+	}
+	if c.ListingDepth <= 0 {
+		c.ListingDepth = defaultListingDepth
+	}
+	if c.ListingFanout <= 0 {
+		c.ListingFanout = defaultListingFanout
+	}
+	return c
+}
+
+// Percentiles of the operation latencies of a result
+type Percentiles struct {
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+// Result measures one operation of a workload (i.e. the gets of small-object-churn)
+type Result struct {
+	Workload  Workload
+	Operation string
+
+	//operations attempted, and how many failed
+	Count  int
+	Errors int
+
+	//first operation error, if any
+	Err error
+
+	//bytes written or read by the operations
+	Bytes int64
+
+	//wall clock time of the operation phase
+	Elapsed time.Duration
+
+	Latency Percentiles
+}
+
+// OpsPerSecond is the operation throughput of a result
+func (r Result) OpsPerSecond() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Count) / r.Elapsed.Seconds()
+}
+
+// MBPerSecond is the data throughput of a result in MB (10^6 bytes) per second
+func (r Result) MBPerSecond() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) / 1e6 / r.Elapsed.Seconds()
+}
+
+type Report struct {
+	Store   string
+	Results []Result
+}
+
+// Run executes workloads against a store, defaulting to every standard workload.
+// Failed operations are counted in the results; Run returns an error when a
+// workload cannot be set up or the workload name is unknown
+func Run(config Config, workloads ...Workload) (*Report, error) {
+	if config.Store == nil {
+		return nil, errors.New("perf: a store is required")
+	}
+	config = config.withDefaults()
+	if len(workloads) == 0 {
+		workloads = Workloads
+	}
+	report := &Report{Store: config.Name}
+	for _, workload := range workloads {
+		var results []Result
+		var err error
+		switch workload {
+		case SmallObjectChurn:
+			results = smallObjectChurn(config)
+		case LargeMultipart:
+			results = largeMultipart(config)
+		case DeepListing:
+			results, err = deepListing(config)
+		default:
+			err = fmt.Errorf("perf: unknown workload %q", workload)
+		}
+		if err != nil {
+			return report, err
+		}
+		report.Results = append(report.Results, results...)
+	}
+	return report, nil
+}
+
+// WriteReports writes the results of one or more reports as a table, so the same
+// workloads run against different stores can be compared side by side
+func WriteReports(w io.Writer, reports ...*Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "store\tworkload\toperation\tcount\terrors\tops/s\tMB/s\tp50\tp90\tp99\tmax\t")
+	for _, report := range reports {
+		for _, r := range report.Results {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%d\t%.1f\t%.2f\t%s\t%s\t%s\t%s\t\n",
+				report.Store, r.Workload, r.Operation, r.Count, r.Errors,
+				r.OpsPerSecond(), r.MBPerSecond(),
+				round(r.Latency.P50), round(r.Latency.P90), round(r.Latency.P99), round(r.Latency.Max))
+		}
+	}
+	return tw.Flush()
+}
+
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	}
+	return d.Round(time.Microsecond)
+}
+
+// runs count operations with a fixed number of workers and measures each.
+// An operation returns the bytes it transferred
+func measure(workload Workload, operation string, count int, concurrency int, op func(i int) (int64, error)) Result {
+	result := Result{Workload: workload, Operation: operation, Count: count}
+	latencies := make([]time.Duration, count)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+	start := time.Now()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				opStart := time.Now()
+				n, err := op(i)
+				latencies[i] = time.Since(opStart)
+				mu.Lock()
+				result.Bytes += n
+				if err != nil {
+					result.Errors++
+					if result.Err == nil {
+						result.Err = err
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	result.Elapsed = time.Since(start)
+	result.Latency = percentiles(latencies)
+	return result
+}
+
+func percentiles(latencies []time.Duration) Percentiles {
+	if len(latencies) == 0 {
+		return Percentiles{}
+	}
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	at := func(p float64) time.Duration {
+		return sorted[int(p*float64(len(sorted)-1)+0.5)]
+	}
+	return Percentiles{P50: at(0.50), P90: at(0.90), P99: at(0.99), Max: sorted[len(sorted)-1]}
+}
+
+func objectPath(config Config, elem ...string) string {
+	return path.Join(append([]string{config.Prefix}, elem...)...)
+}
+
+func get(store filesapi.FileStore, p string) (int64, error) {
+	reader, err := store.GetObject(filesapi.GetObjectInput{Path: filesapi.PathConfig{Path: p}})
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+	return io.Copy(io.Discard, reader)
+}
+
+func remove(store filesapi.FileStore, paths ...string) error {
+	errs := store.DeleteObjects(filesapi.DeleteObjectInput{Paths: filesapi.PathConfig{Paths: paths}})
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+func smallObjectChurn(config Config) []Result {
+	data := pattern(config.SmallObjectSize)
+	paths := make([]string, config.SmallObjects)
+	for i := range paths {
+		paths[i] = objectPath(config, string(SmallObjectChurn), fmt.Sprintf("object-%06d.bin", i))
+	}
+	put := measure(SmallObjectChurn, "put", len(paths), config.Concurrency, func(i int) (int64, error) {
+		_, err := config.Store.PutObject(filesapi.PutObjectInput{
+			Source: filesapi.ObjectSource{Data: data},
+			Dest:   filesapi.PathConfig{Path: paths[i]},
+		})
+		if err != nil {
+			return 0, err
+		}
+		return int64(len(data)), nil
+	})
+	read := measure(SmallObjectChurn, "get", len(paths), config.Concurrency, func(i int) (int64, error) {
+		return get(config.Store, paths[i])
+	})
+	del := measure(SmallObjectChurn, "delete", len(paths), config.Concurrency, func(i int) (int64, error) {
+		return 0, remove(config.Store, paths[i])
+	})
+	return []Result{put, read, del}
+}
+
+func largeMultipart(config Config) []Result {
+	source := patternReaderAt{}
+	paths := make([]string, config.LargeObjects)
+	for i := range paths {
+		paths[i] = objectPath(config, string(LargeMultipart), fmt.Sprintf("object-%03d.bin", i))
+	}
+	//large transfers are run one at a time so each measures a single multipart upload
+	put := measure(LargeMultipart, "multipart put", len(paths), 1, func(i int) (int64, error) {
+		size := config.LargeObjectSize
+		_, err := config.Store.PutObject(filesapi.PutObjectInput{
+			Source:   filesapi.ObjectSource{ReaderAt: source, ContentLength: &size},
+			Dest:     filesapi.PathConfig{Path: paths[i]},
+			Mutipart: true,
+			PartSize: config.PartSize,
+		})
+		if err != nil {
+			return 0, err
+		}
+		return size, nil
+	})
+	read := measure(LargeMultipart, "get", len(paths), 1, func(i int) (int64, error) {
+		return get(config.Store, paths[i])
+	})
+	remove(config.Store, paths...)
+	return []Result{put, read}
+}
+
+func deepListing(config Config) ([]Result, error) {
+	root := objectPath(config, string(DeepListing))
+	dirs := []string{root}
+	level := []string{root}
+	for d := 0; d < config.ListingDepth; d++ {
+		next := []string{}
+		for _, parent := range level {
+			for f := 0; f < config.ListingFanout; f++ {
+				next = append(next, path.Join(parent, fmt.Sprintf("dir-%02d", f)))
+			}
+		}
+		dirs = append(dirs, next...)
+		level = next
+	}
+	objects := make([]string, len(level))
+	for i, leaf := range level {
+		objects[i] = path.Join(leaf, "object.bin")
+	}
+	data := pattern(1024)
+	setup := measure(DeepListing, "setup", len(objects), config.Concurrency, func(i int) (int64, error) {
+		_, err := config.Store.PutObject(filesapi.PutObjectInput{
+			Source: filesapi.ObjectSource{Data: data},
+			Dest:   filesapi.PathConfig{Path: objects[i]},
+		})
+		return 0, err
+	})
+	if setup.Err != nil {
+		remove(config.Store, objects...)
+		return nil, fmt.Errorf("perf: unable to create the listing tree: %w", setup.Err)
+	}
+	list := measure(DeepListing, "list dir", len(dirs), config.Concurrency, func(i int) (int64, error) {
+		_, err := config.Store.ListDir(filesapi.ListDirInput{Path: filesapi.PathConfig{Path: dirs[i] + "/"}})
+		return 0, err
+	})
+	walk := measure(DeepListing, "walk", 1, 1, func(i int) (int64, error) {
+		visited := 0
+		err := config.Store.Walk(filesapi.WalkInput{Path: filesapi.PathConfig{Path: root + "/"}}, func(path string, file os.FileInfo) error {
+			//file system stores also visit directories
+			if !file.IsDir() {
+				visited++
+			}
+			return nil
+		})
+		if err == nil && visited != len(objects) {
+			err = fmt.Errorf("walk visited %d of %d objects", visited, len(objects))
+		}
+		return 0, err
+	})
+	remove(config.Store, objects...)
+	return []Result{list, walk}, nil
+}
+
+// returns deterministic, poorly compressible data
+func pattern(size int) []byte {
+	data := make([]byte, size)
+	patternReaderAt{}.ReadAt(data, 0)
+	return data
+}
+
+// an endless deterministic byte source, so large objects are written without holding them in memory
+type patternReaderAt struct{}
+
+func (patternReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	for i := range p {
+		x := uint64(off + int64(i))
+		x ^= x >> 7
+		x *= 0x9e3779b97f4a7c15
+		p[i] = byte(x >> 56)
+	}
+	return len(p), nil
+}
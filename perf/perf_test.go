@@ -0,0 +1,93 @@
+package perf
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/usace/filesapi"
+)
+
+func TestRun(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	report, err := Run(Config{
+		Store:           store,
+		Name:            "local",
+		Prefix:          dir,
+		Concurrency:     4,
+		SmallObjects:    20,
+		SmallObjectSize: 512,
+		LargeObjects:    1,
+		LargeObjectSize: 3 * 1024 * 1024,
+		PartSize:        1024 * 1024,
+		ListingDepth:    2,
+		ListingFanout:   2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 7 {
+		t.Fatalf("expected 7 results, got %d", len(report.Results))
+	}
+	for _, r := range report.Results {
+		if r.Errors > 0 {
+			t.Fatalf("%s %s: %d errors, first: %v", r.Workload, r.Operation, r.Errors, r.Err)
+		}
+		if r.Latency.Max <= 0 || r.Latency.P50 > r.Latency.Max {
+			t.Fatalf("%s %s: unexpected latencies %+v", r.Workload, r.Operation, r.Latency)
+		}
+	}
+	if get := report.Results[4]; get.Operation != "get" || get.Bytes != 3*1024*1024 {
+		t.Fatalf("expected the large object to be read back, got %+v", get)
+	}
+
+	//workloads remove what they write
+	var remaining []string
+	filepathWalk(t, dir, &remaining)
+	if len(remaining) != 0 {
+		t.Fatalf("expected the workloads to clean up, found %v", remaining)
+	}
+
+	var table bytes.Buffer
+	if err := WriteReports(&table, report); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(table.String(), "local") || !strings.Contains(table.String(), "multipart put") {
+		t.Fatalf("unexpected report table:\n%s", table.String())
+	}
+
+	if _, err := Run(Config{Store: store, Prefix: dir}, "unknown"); err == nil {
+		t.Fatal("expected an unknown workload to fail")
+	}
+}
+
+func filepathWalk(t *testing.T, dir string, files *[]string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			filepathWalk(t, dir+"/"+e.Name(), files)
+		} else {
+			*files = append(*files, e.Name())
+		}
+	}
+}
+
+func TestPercentiles(t *testing.T) {
+	latencies := []time.Duration{}
+	for i := 100; i >= 1; i-- {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	p := percentiles(latencies)
+	if p.P50 != 51*time.Millisecond || p.P90 != 90*time.Millisecond || p.P99 != 99*time.Millisecond || p.Max != 100*time.Millisecond {
+		t.Fatalf("unexpected percentiles %+v", p)
+	}
+}
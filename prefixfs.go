@@ -0,0 +1,185 @@
+package filesapi
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// PrefixFS is a FileStore decorator that scopes every path beneath a prefix, so
+// "/report.csv" refers to "<prefix>/report.csv" in the wrapped store.  Paths are cleaned
+// before they are scoped, so ".." elements cannot reach outside the prefix.  Paths
+// returned by ListDir, GetDir, and Walk, and the names of S3 GetObjectInfo results, are
+// made relative to the prefix again.  An empty prefix leaves paths unscoped
+type PrefixFS struct {
+	FileStore
+	prefix string
+}
+
+func NewPrefixFS(store FileStore, prefix string) *PrefixFS {
+	return &PrefixFS{
+		FileStore: store,
+		prefix:    strings.Trim(prefix, "/"),
+	}
+}
+
+func (pfs *PrefixFS) Prefix() string {
+	return pfs.prefix
+}
+
+func (pfs *PrefixFS) scope(p string) string {
+	clean := path.Clean("/" + p)
+	if clean == "/" {
+		if pfs.prefix == "" {
+			return "/"
+		}
+		return "/" + pfs.prefix + "/"
+	}
+	if strings.HasSuffix(p, "/") {
+		clean += "/"
+	}
+	if pfs.prefix == "" {
+		return clean
+	}
+	return "/" + pfs.prefix + clean
+}
+
+func (pfs *PrefixFS) scopePaths(pc PathConfig) PathConfig {
+	scoped := PathConfig{}
+	if pc.Path != "" {
+		scoped.Path = pfs.scope(pc.Path)
+	}
+	for _, p := range pc.Paths {
+		scoped.Paths = append(scoped.Paths, pfs.scope(p))
+	}
+	return scoped
+}
+
+// strips the prefix from a path returned by the wrapped store, keeping its leading slash convention
+func (pfs *PrefixFS) unscope(p string) string {
+	if pfs.prefix == "" {
+		return p
+	}
+	lead := strings.HasPrefix(p, "/")
+	rel := strings.TrimPrefix(p, "/")
+	switch {
+	case rel == pfs.prefix || rel == pfs.prefix+"/":
+		rel = ""
+	case strings.HasPrefix(rel, pfs.prefix+"/"):
+		rel = rel[len(pfs.prefix)+1:]
+	default:
+		return p
+	}
+	if lead {
+		return "/" + rel
+	}
+	return rel
+}
+
+func (pfs *PrefixFS) unscopeResults(results *[]FileStoreResultObject, err error) (*[]FileStoreResultObject, error) {
+	if results != nil {
+		for i := range *results {
+			(*results)[i].Path = pfs.unscope((*results)[i].Path)
+		}
+	}
+	return results, err
+}
+
+// S3 object info is named by its full key, so the name is unscoped on a copy.  Block file
+// info is named by its base name and is returned as is
+func (pfs *PrefixFS) unscopeInfo(info fs.FileInfo) fs.FileInfo {
+	switch i := info.(type) {
+	case *S3AttributesFileInfo:
+		if i == nil {
+			return info
+		}
+		unscoped := *i
+		unscoped.name = pfs.unscope(i.name)
+		return &unscoped
+	case *ResourceInfo:
+		unscoped := &ResourceInfo{Files: make([]fs.FileInfo, len(i.Files))}
+		for j, f := range i.Files {
+			unscoped.Files[j] = pfs.unscopeInfo(f)
+		}
+		return unscoped
+	}
+	return info
+}
+
+func (pfs *PrefixFS) ListDir(input ListDirInput) (*[]FileStoreResultObject, error) {
+	input.Path = pfs.scopePaths(input.Path)
+	return pfs.unscopeResults(pfs.FileStore.ListDir(input))
+}
+
+func (pfs *PrefixFS) GetDir(p PathConfig) (*[]FileStoreResultObject, error) {
+	return pfs.unscopeResults(pfs.FileStore.GetDir(pfs.scopePaths(p)))
+}
+
+func (pfs *PrefixFS) GetObjectInfo(p PathConfig) (fs.FileInfo, error) {
+	info, err := pfs.FileStore.GetObjectInfo(pfs.scopePaths(p))
+	return pfs.unscopeInfo(info), err
+}
+
+func (pfs *PrefixFS) GetObject(input GetObjectInput) (io.ReadCloser, error) {
+	input.Path = pfs.scopePaths(input.Path)
+	return pfs.FileStore.GetObject(input)
+}
+
+func (pfs *PrefixFS) PutObject(input PutObjectInput) (*FileOperationOutput, error) {
+	input.Dest = pfs.scopePaths(input.Dest)
+	return pfs.FileStore.PutObject(input)
+}
+
+func (pfs *PrefixFS) CopyObject(input CopyObjectInput) error {
+	input.Src = pfs.scopePaths(input.Src)
+	input.Dest = pfs.scopePaths(input.Dest)
+	return pfs.FileStore.CopyObject(input)
+}
+
+func (pfs *PrefixFS) MoveObject(input MoveObjectInput) error {
+	input.Src = pfs.scopePaths(input.Src)
+	input.Dest = pfs.scopePaths(input.Dest)
+	return pfs.FileStore.MoveObject(input)
+}
+
+func (pfs *PrefixFS) InitializeObjectUpload(u UploadConfig) (UploadResult, error) {
+	u.ObjectPath = pfs.scope(u.ObjectPath)
+	return pfs.FileStore.InitializeObjectUpload(u)
+}
+
+func (pfs *PrefixFS) WriteChunk(u UploadConfig) (UploadResult, error) {
+	u.ObjectPath = pfs.scope(u.ObjectPath)
+	return pfs.FileStore.WriteChunk(u)
+}
+
+func (pfs *PrefixFS) CompleteObjectUpload(u CompletedObjectUploadConfig) error {
+	u.ObjectPath = pfs.scope(u.ObjectPath)
+	return pfs.FileStore.CompleteObjectUpload(u)
+}
+
+func (pfs *PrefixFS) AbortObjectUpload(u UploadConfig) error {
+	u.ObjectPath = pfs.scope(u.ObjectPath)
+	return pfs.FileStore.AbortObjectUpload(u)
+}
+
+func (pfs *PrefixFS) ListUploadParts(u UploadConfig) ([]UploadPart, error) {
+	u.ObjectPath = pfs.scope(u.ObjectPath)
+	return pfs.FileStore.ListUploadParts(u)
+}
+
+func (pfs *PrefixFS) DeleteObjects(input DeleteObjectInput) []error {
+	input.Paths = pfs.scopePaths(input.Paths)
+	return pfs.FileStore.DeleteObjects(input)
+}
+
+func (pfs *PrefixFS) Walk(input WalkInput, visitor FileVisitFunction) error {
+	input.Path = pfs.scopePaths(input.Path)
+	if input.StartAfter != "" {
+		input.StartAfter = pfs.scope(input.StartAfter)
+	}
+	return pfs.FileStore.Walk(input, func(p string, file os.FileInfo) error {
+		return visitor(pfs.unscope(p), file)
+	})
+}
@@ -0,0 +1,136 @@
+package filesapi
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestPrefixFS(t *testing.T) {
+	dir := t.TempDir()
+	tenant := NewPrefixFS(&BlockFS{}, filepath.Join(dir, "tenants/a"))
+	_, err := tenant.PutObject(PutObjectInput{
+		Source: ObjectSource{Data: []byte(testObjectString)},
+		Dest:   PathConfig{Path: "/reports/q1.csv"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "tenants/a/reports/q1.csv")); err != nil {
+		t.Fatal(err)
+	}
+
+	//parent references cannot escape the prefix
+	reader, err := tenant.GetObject(GetObjectInput{Path: PathConfig{Path: "/../../tenants/a/reports/q1.csv"}})
+	if err == nil {
+		reader.Close()
+		t.Fatal("expected the escaped path to resolve inside the prefix")
+	}
+	reader, err = tenant.GetObject(GetObjectInput{Path: PathConfig{Path: "/../reports/q1.csv"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(data) != testObjectString {
+		t.Fatalf("unexpected object %q", data)
+	}
+
+	walked := []string{}
+	err = tenant.Walk(WalkInput{Path: PathConfig{Path: "/"}}, func(path string, file os.FileInfo) error {
+		walked = append(walked, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(walked) != 3 || walked[2] != "/reports/q1.csv" {
+		t.Fatalf("unexpected walk paths %v", walked)
+	}
+
+	listing, err := tenant.ListDir(ListDirInput{Path: PathConfig{Path: "/reports/"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*listing) != 1 || listingPath((*listing)[0].Path, (*listing)[0]) != "/reports/q1.csv" {
+		t.Fatalf("unexpected listing %+v", *listing)
+	}
+}
+
+func TestS3FSWith(t *testing.T) {
+	base := &S3FS{config: &S3FSConfig{S3Bucket: "bucket"}, delimiter: DEFAULTDELIMITER, maxKeys: DEFAULTMAXKEYS}
+	clone := base.Clone(S3FSOptions{MaxKeys: 50})
+	if clone.maxKeys != 50 || clone.delimiter != DEFAULTDELIMITER || clone.config != base.config || base.maxKeys != DEFAULTMAXKEYS {
+		t.Fatalf("unexpected clone %+v", clone)
+	}
+	scoped, ok := base.With(S3FSOptions{Prefix: "/tenants/a/"}).(*PrefixFS)
+	if !ok || scoped.Prefix() != "tenants/a" || scoped.scope("report.csv") != "/tenants/a/report.csv" {
+		t.Fatalf("expected a store scoped to the prefix")
+	}
+}
+
+func TestPrefixFSScope(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		path     string
+		scoped   string
+		unscoped string
+	}{
+		{"tenants/a", "/", "/tenants/a/", "/"},
+		{"tenants/a", "reports/", "/tenants/a/reports/", "/reports/"},
+		{"tenants/a", "/../q1.csv", "/tenants/a/q1.csv", "/q1.csv"},
+		{"", "/", "/", "/"},
+		{"", "", "/", "/"},
+		{"/", "reports/", "/reports/", "/reports/"},
+		{"", "/../q1.csv", "/q1.csv", "/q1.csv"},
+	}
+	for _, test := range tests {
+		pfs := NewPrefixFS(&BlockFS{}, test.prefix)
+		scoped := pfs.scope(test.path)
+		if scoped != test.scoped {
+			t.Fatalf("expected %q under prefix %q to scope to %q, got %q", test.path, test.prefix, test.scoped, scoped)
+		}
+		if unscoped := pfs.unscope(scoped); unscoped != test.unscoped {
+			t.Fatalf("expected %q under prefix %q to unscope to %q, got %q", scoped, test.prefix, test.unscoped, unscoped)
+		}
+	}
+}
+
+// names object info by its key, as S3 stores do
+type keyInfoFS struct {
+	FileStore
+}
+
+func (k keyInfoFS) GetObjectInfo(p PathConfig) (fs.FileInfo, error) {
+	if all := p.All(); len(all) > 1 {
+		return resourceInfo(all, k.GetObjectInfo)
+	}
+	return &S3AttributesFileInfo{
+		name:                      strings.TrimPrefix(p.Path, "/"),
+		GetObjectAttributesOutput: &s3.GetObjectAttributesOutput{},
+	}, nil
+}
+
+func TestPrefixFSObjectInfo(t *testing.T) {
+	tenant := NewPrefixFS(keyInfoFS{}, "tenants/a")
+	info, err := tenant.GetObjectInfo(PathConfig{Path: "/reports/q1.csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := info.(*S3AttributesFileInfo); !ok || info.Name() != "reports/q1.csv" {
+		t.Fatalf("unexpected object info %T %q", info, info.Name())
+	}
+	info, err = tenant.GetObjectInfo(PathConfig{Paths: []string{"/q1.csv", "/q2.csv"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := info.(*ResourceInfo).Files
+	if files[0].Name() != "q1.csv" || files[1].Name() != "q2.csv" {
+		t.Fatalf("unexpected resource info names %q %q", files[0].Name(), files[1].Name())
+	}
+}
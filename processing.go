@@ -0,0 +1,138 @@
+package filesapi
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultProcessingWorkers   int = 2
+	defaultProcessingQueueSize int = 100
+)
+
+// ObjectProcessor runs after an object lands in a store, i.e. to generate
+// a thumbnail or metadata sidecar.  store is the ProcessingQueue store
+type ObjectProcessor func(store FileStore, e Event) error
+
+type ProcessingQueueConfig struct {
+
+	//store passed to processors for reading objects and writing sidecars.
+	//use the undecorated store rather than the EventFS publishing to the queue,
+	//otherwise sidecars matching a registered extension are processed again
+	Store FileStore
+
+	//number of concurrent processing workers.  Defaults to defaultProcessingWorkers
+	Workers int
+
+	//number of pending events buffered before publishers block.  Defaults to defaultProcessingQueueSize
+	QueueSize int
+
+	//optional callback for processor failures
+	OnError func(e Event, err error)
+}
+
+type processingTask struct {
+	event      Event
+	processors []ObjectProcessor
+}
+
+// ProcessingQueue runs registered processors for objects created or copied into a store.
+// Processors are keyed by file extension and fed from an EventBus, so processing happens
+// in background workers rather than in the goroutine that wrote the object.
+// ProcessingQueue is safe for concurrent use
+type ProcessingQueue struct {
+	config ProcessingQueueConfig
+	tasks  chan processingTask
+	wg     sync.WaitGroup
+
+	mu         sync.RWMutex
+	processors map[string][]ObjectProcessor
+	closed     bool
+}
+
+func NewProcessingQueue(config ProcessingQueueConfig) *ProcessingQueue {
+	if config.Workers <= 0 {
+		config.Workers = defaultProcessingWorkers
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaultProcessingQueueSize
+	}
+	pq := &ProcessingQueue{
+		config:     config,
+		tasks:      make(chan processingTask, config.QueueSize),
+		processors: map[string][]ObjectProcessor{},
+	}
+	for i := 0; i < config.Workers; i++ {
+		pq.wg.Add(1)
+		go pq.work()
+	}
+	return pq
+}
+
+// Register adds a processor for objects with any of the given extensions (i.e. ".png", ".tif").
+// Extensions are matched case insensitively
+func (pq *ProcessingQueue) Register(processor ObjectProcessor, extensions ...string) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+	for _, ext := range extensions {
+		ext = normalizeExtension(ext)
+		pq.processors[ext] = append(pq.processors[ext], processor)
+	}
+}
+
+// Subscribe feeds created and copied object events from bus into the queue.
+// The returned function unsubscribes the queue
+func (pq *ProcessingQueue) Subscribe(bus *EventBus) func() {
+	return bus.Subscribe(pq.Enqueue, ObjectCreated, ObjectCopied)
+}
+
+// Enqueue schedules the processors registered for the event object's extension.
+// Events without matching processors, and events received after Close, are ignored.
+// Enqueue blocks while the queue is full
+func (pq *ProcessingQueue) Enqueue(e Event) {
+	pq.mu.RLock()
+	defer pq.mu.RUnlock()
+	if pq.closed {
+		return
+	}
+	processors := pq.processors[normalizeExtension(filepath.Ext(e.Path))]
+	if len(processors) == 0 {
+		return
+	}
+	pq.tasks <- processingTask{
+		event:      e,
+		processors: append([]ObjectProcessor{}, processors...),
+	}
+}
+
+// Close stops accepting events and waits for queued events to finish processing
+func (pq *ProcessingQueue) Close() {
+	pq.mu.Lock()
+	if !pq.closed {
+		pq.closed = true
+		close(pq.tasks)
+	}
+	pq.mu.Unlock()
+	pq.wg.Wait()
+}
+
+func (pq *ProcessingQueue) work() {
+	defer pq.wg.Done()
+	for task := range pq.tasks {
+		for _, processor := range task.processors {
+			err := processor(pq.config.Store, task.event)
+			if err != nil && pq.config.OnError != nil {
+				pq.config.OnError(task.event, err)
+			}
+		}
+	}
+}
+
+func normalizeExtension(ext string) string {
+	ext = strings.ToLower(ext)
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
@@ -0,0 +1,71 @@
+package filesapi
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProcessingQueue(t *testing.T) {
+	dir := t.TempDir()
+	block := &BlockFS{}
+	bus := NewEventBus()
+	failures := make(chan error, 10)
+	queue := NewProcessingQueue(ProcessingQueueConfig{
+		Store: block,
+		OnError: func(e Event, err error) {
+			failures <- err
+		},
+	})
+	queue.Subscribe(bus)
+
+	//writes an upper case sidecar next to each text object
+	queue.Register(func(store FileStore, e Event) error {
+		reader, err := store.GetObject(GetObjectInput{Path: PathConfig{Path: e.Path}})
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+		_, err = store.PutObject(PutObjectInput{
+			Source: ObjectSource{Data: []byte(strings.ToUpper(string(data)))},
+			Dest:   PathConfig{Path: e.Path + ".preview"},
+		})
+		return err
+	}, "TXT")
+	queue.Register(func(store FileStore, e Event) error {
+		return errors.New("unreadable raster")
+	}, ".tif")
+
+	efs := NewEventFS(block, bus)
+	for _, name := range []string{"notes.txt", "dem.tif", "ignored.bin"} {
+		_, err := efs.PutObject(PutObjectInput{
+			Source: ObjectSource{Data: []byte("hello world")},
+			Dest:   PathConfig{Path: filepath.Join(dir, name)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	queue.Close()
+
+	data, err := os.ReadFile(filepath.Join(dir, "notes.txt.preview"))
+	if err != nil || string(data) != "HELLO WORLD" {
+		t.Fatalf("unexpected sidecar %q %v", data, err)
+	}
+	if len(failures) != 1 {
+		t.Fatalf("expected 1 processor failure, got %d", len(failures))
+	}
+	if _, err = os.Stat(filepath.Join(dir, "ignored.bin.preview")); !os.IsNotExist(err) {
+		t.Fatal("unexpected sidecar for an unregistered extension")
+	}
+
+	//events after close are ignored
+	queue.Enqueue(Event{Type: ObjectCreated, Path: filepath.Join(dir, "late.txt")})
+}
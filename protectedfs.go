@@ -0,0 +1,147 @@
+package filesapi
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ErrProtectedPath is returned by a ProtectedFS when a call would delete or overwrite
+// objects beneath a protected prefix
+var ErrProtectedPath = errors.New("path is protected")
+
+// ProtectedFS is a FileStore decorator that guards critical prefixes (i.e. published
+// record data).  New objects may be written beneath a protected prefix, but deletes and
+// overwrites are refused with ErrProtectedPath unless the call sets
+// CallOptions.OverrideProtection.  Moves of protected objects are refused because they
+// delete the source.  Deleting a parent of a protected prefix is refused, since deletes
+// are recursive.  Multipart uploads have no per call options, so they cannot overwrite
+// protected objects
+type ProtectedFS struct {
+	FileStore
+
+	//store paths of the protected prefixes
+	ProtectedPrefixes []string
+}
+
+func NewProtectedFS(store FileStore, prefixes ...string) *ProtectedFS {
+	return &ProtectedFS{
+		FileStore:         store,
+		ProtectedPrefixes: prefixes,
+	}
+}
+
+func protectedKey(p string) string {
+	return strings.Trim(path.Clean("/"+p), "/")
+}
+
+// returns the protected prefix containing a path
+func (pfs *ProtectedFS) protection(p string) (string, bool) {
+	key := protectedKey(p)
+	for _, prefix := range pfs.ProtectedPrefixes {
+		pk := protectedKey(prefix)
+		if pk == "" || key == pk || strings.HasPrefix(key, pk+"/") {
+			return prefix, true
+		}
+	}
+	return "", false
+}
+
+// returns the protected prefix affected by a recursive delete of a path
+func (pfs *ProtectedFS) deleteProtection(p string) (string, bool) {
+	if prefix, ok := pfs.protection(p); ok {
+		return prefix, true
+	}
+	key := protectedKey(p)
+	for _, prefix := range pfs.ProtectedPrefixes {
+		if key == "" || strings.HasPrefix(protectedKey(prefix), key+"/") {
+			return prefix, true
+		}
+	}
+	return "", false
+}
+
+// refuses writes replacing an existing protected object
+func (pfs *ProtectedFS) checkOverwrite(op string, p string) error {
+	prefix, ok := pfs.protection(p)
+	if !ok {
+		return nil
+	}
+	exists, err := ObjectExists(pfs.FileStore, p)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("%w: %s would overwrite %s beneath %s", ErrProtectedPath, op, p, prefix)
+	}
+	return nil
+}
+
+func overridesProtection(options *CallOptions) bool {
+	return options != nil && options.OverrideProtection
+}
+
+func (pfs *ProtectedFS) PutObject(input PutObjectInput) (*FileOperationOutput, error) {
+	if !input.IfNoneMatch && !overridesProtection(input.Options) {
+		if err := pfs.checkOverwrite("put", input.Dest.Path); err != nil {
+			return nil, err
+		}
+	}
+	return pfs.FileStore.PutObject(input)
+}
+
+func (pfs *ProtectedFS) CopyObject(input CopyObjectInput) error {
+	if !overridesProtection(input.Options) {
+		for _, dest := range input.Dest.All() {
+			if err := pfs.checkOverwrite("copy", dest); err != nil {
+				return err
+			}
+		}
+	}
+	return pfs.FileStore.CopyObject(input)
+}
+
+func (pfs *ProtectedFS) MoveObject(input MoveObjectInput) error {
+	if !overridesProtection(input.Options) {
+		for _, src := range input.Src.All() {
+			if prefix, ok := pfs.protection(src); ok {
+				return fmt.Errorf("%w: move would delete %s beneath %s", ErrProtectedPath, src, prefix)
+			}
+		}
+		for _, dest := range input.Dest.All() {
+			if err := pfs.checkOverwrite("move", dest); err != nil {
+				return err
+			}
+		}
+	}
+	return pfs.FileStore.MoveObject(input)
+}
+
+func (pfs *ProtectedFS) InitializeObjectUpload(u UploadConfig) (UploadResult, error) {
+	if err := pfs.checkOverwrite("upload", u.ObjectPath); err != nil {
+		return UploadResult{}, err
+	}
+	return pfs.FileStore.InitializeObjectUpload(u)
+}
+
+func (pfs *ProtectedFS) CompleteObjectUpload(u CompletedObjectUploadConfig) error {
+	//the object may have been written since the upload started
+	if err := pfs.checkOverwrite("upload", u.ObjectPath); err != nil {
+		return err
+	}
+	return pfs.FileStore.CompleteObjectUpload(u)
+}
+
+// DeleteObjects refuses the whole request when any path is protected, so a delete is
+// never partially applied
+func (pfs *ProtectedFS) DeleteObjects(input DeleteObjectInput) []error {
+	if !overridesProtection(input.Options) {
+		for _, p := range input.Paths.All() {
+			if prefix, ok := pfs.deleteProtection(p); ok {
+				return []error{fmt.Errorf("%w: delete of %s affects %s", ErrProtectedPath, p, prefix)}
+			}
+		}
+	}
+	return pfs.FileStore.DeleteObjects(input)
+}
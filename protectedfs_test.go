@@ -0,0 +1,85 @@
+package filesapi
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProtectedFS(t *testing.T) {
+	store, err := NewFileStore(BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "data", "published"), os.ModePerm)
+	os.WriteFile(filepath.Join(root, "data", "published", "record.json"), []byte("{}"), 0644)
+	os.WriteFile(filepath.Join(root, "data", "draft.json"), []byte("{}"), 0644)
+	pfs := NewProtectedFS(store, root+"/data/published")
+	record := root + "/data/published/record.json"
+
+	put := func(dest string, options *CallOptions) error {
+		_, err := pfs.PutObject(PutObjectInput{
+			Source:  ObjectSource{Data: []byte(`{"v":2}`)},
+			Dest:    PathConfig{Path: dest},
+			Options: options,
+		})
+		return err
+	}
+	if err := put(record, nil); !errors.Is(err, ErrProtectedPath) {
+		t.Fatalf("expected the overwrite to be refused, got %v", err)
+	}
+	if err := put(root+"/data/published/new.json", nil); err != nil {
+		t.Fatalf("expected new protected objects to be written, got %v", err)
+	}
+	if err := put(root+"/data/draft.json", nil); err != nil {
+		t.Fatal(err)
+	}
+	err = pfs.CopyObject(CopyObjectInput{Src: PathConfig{Path: root + "/data/draft.json"}, Dest: PathConfig{Path: record}})
+	if !errors.Is(err, ErrProtectedPath) {
+		t.Fatalf("expected the copy over a protected object to be refused, got %v", err)
+	}
+	if _, err := pfs.InitializeObjectUpload(UploadConfig{ObjectPath: record}); !errors.Is(err, ErrProtectedPath) {
+		t.Fatalf("expected the upload over a protected object to be refused, got %v", err)
+	}
+
+	move := func(src string, dest string) error {
+		return pfs.MoveObject(MoveObjectInput{Src: PathConfig{Path: src}, Dest: PathConfig{Path: dest}})
+	}
+	if err := move(record, root+"/data/record.json"); !errors.Is(err, ErrProtectedPath) {
+		t.Fatalf("expected the move of a protected object to be refused, got %v", err)
+	}
+	if err := move(root+"/data/published/new.json", record); !errors.Is(err, ErrProtectedPath) {
+		t.Fatalf("expected the move over a protected object to be refused, got %v", err)
+	}
+
+	for _, p := range []string{record, root + "/data", root + "/data/published/"} {
+		errs := pfs.DeleteObjects(DeleteObjectInput{Paths: PathConfig{Paths: []string{root + "/data/draft.json", p}}})
+		if !errors.Is(firstError(errs), ErrProtectedPath) {
+			t.Fatalf("expected the delete of %s to be refused, got %v", p, errs)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "data", "draft.json")); err != nil {
+		t.Fatal("expected a refused delete to delete nothing")
+	}
+	data, _ := os.ReadFile(filepath.Join(root, "data", "published", "record.json"))
+	if string(data) != "{}" {
+		t.Fatalf("expected the protected object to be unchanged, got %s", data)
+	}
+
+	override := &CallOptions{OverrideProtection: true}
+	if err := put(record, override); err != nil {
+		t.Fatal(err)
+	}
+	errs := pfs.DeleteObjects(DeleteObjectInput{Paths: PathConfig{Path: record}, Options: override})
+	if err := firstError(errs); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "data", "published", "record.json")); !os.IsNotExist(err) {
+		t.Fatal("expected the overridden delete to remove the object")
+	}
+	if err := firstError(pfs.DeleteObjects(DeleteObjectInput{Paths: PathConfig{Path: root + "/data/draft.json"}})); err != nil {
+		t.Fatal(err)
+	}
+}
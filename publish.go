@@ -0,0 +1,355 @@
+package filesapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const defaultManifestName string = "manifest.json"
+
+// Cache-Control of published manifests, which change with every release
+const defaultManifestCacheControl string = "no-cache"
+
+// text asset extensions gzip encoded by Publish
+var defaultGzipExtensions = []string{".csv", ".json", ".geojson", ".txt", ".html", ".htm", ".js", ".css", ".xml", ".svg", ".md"}
+
+// ManifestSigner signs and verifies publish manifests
+type ManifestSigner interface {
+
+	//name of the signature algorithm recorded in the manifest (i.e. "HMAC-SHA256")
+	Algorithm() string
+
+	Sign(data []byte) ([]byte, error)
+
+	Verify(data []byte, signature []byte) error
+}
+
+// HMACSigner signs manifests with an HMAC-SHA256 shared key
+type HMACSigner struct {
+	Key []byte
+}
+
+func (hs HMACSigner) Algorithm() string {
+	return "HMAC-SHA256"
+}
+
+func (hs HMACSigner) Sign(data []byte) ([]byte, error) {
+	return sign(data, hs.Key)
+}
+
+func (hs HMACSigner) Verify(data []byte, signature []byte) error {
+	expected, err := sign(data, hs.Key)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(expected, signature) {
+		return errors.New("invalid manifest signature")
+	}
+	return nil
+}
+
+type ManifestEntry struct {
+
+	//path relative to the published prefix
+	Path string `json:"path"`
+
+	//size and checksum of the stored (encoded) object
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+
+	ContentType     string `json:"contentType,omitempty"`
+	ContentEncoding string `json:"contentEncoding,omitempty"`
+}
+
+type PublishManifest struct {
+	Created time.Time       `json:"created"`
+	Source  string          `json:"source"`
+	Prefix  string          `json:"prefix"`
+	Files   []ManifestEntry `json:"files"`
+}
+
+// SignedManifest is the document written alongside published data.
+// The signature covers the exact bytes of Manifest
+type SignedManifest struct {
+	Manifest  json.RawMessage `json:"manifest"`
+	Algorithm string          `json:"algorithm"`
+	Signature string          `json:"signature"`
+}
+
+type PublishInput struct {
+
+	//store containing the data to publish
+	Src FileStore
+
+	//destination store.  Defaults to Src
+	Dst FileStore
+
+	//prefix to publish
+	SrcPrefix string
+
+	//prefix the data is published under
+	DestPrefix string
+
+	//optional manifest signer.  Without a signer the manifest is written unsigned
+	//as a plain index of the published files
+	Signer ManifestSigner
+
+	//name of the manifest object written under DestPrefix.  Defaults to defaultManifestName
+	ManifestName string
+
+	//optional progress function.  Value is the source path of the published object
+	Progress ProgressFunction
+
+	//optional Cache-Control of published objects (i.e. "public, max-age=86400").
+	//Ignored by block file stores
+	CacheControl string
+
+	//Cache-Control of the manifest.  Defaults to defaultManifestCacheControl
+	ManifestCacheControl string
+
+	//content types by file extension (i.e. ".geojson": "application/geo+json").  Other
+	//extensions use mime.TypeByExtension, falling back to application/octet-stream
+	ContentTypes map[string]string
+
+	//gzip encodes text assets, storing them with a gzip Content-Encoding
+	Gzip bool
+
+	//extensions encoded when Gzip is set.  Defaults to defaultGzipExtensions
+	GzipExtensions []string
+
+	//optional time source for the manifest creation time
+	Clock Clock
+}
+
+// Publish copies every object under SrcPrefix to DestPrefix with content types,
+// Cache-Control, and optional gzip encoding of text assets, and writes a manifest of the
+// published checksums and sizes.  Signed manifests let consumers verify the provenance
+// of released data with VerifyManifest
+func Publish(input PublishInput) (*PublishManifest, error) {
+	if input.Src == nil {
+		return nil, errors.New("publish requires a source store")
+	}
+	dst := input.Dst
+	if dst == nil {
+		dst = input.Src
+	}
+	manifestName := input.ManifestName
+	if manifestName == "" {
+		manifestName = defaultManifestName
+	}
+	srcPrefix := strings.TrimSuffix(strings.TrimPrefix(input.SrcPrefix, "/"), "/")
+	destPrefix := strings.TrimSuffix(input.DestPrefix, "/")
+
+	paths := []string{}
+	err := input.Src.Walk(WalkInput{Path: PathConfig{Path: input.SrcPrefix}}, func(path string, file os.FileInfo) error {
+		if !file.IsDir() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &PublishManifest{
+		Created: input.Clock.Now().UTC(),
+		Source:  input.Src.ResourceName(),
+		Prefix:  input.SrcPrefix,
+		Files:   []ManifestEntry{},
+	}
+	gzipExtensions := input.GzipExtensions
+	if gzipExtensions == nil {
+		gzipExtensions = defaultGzipExtensions
+	}
+	for i, path := range paths {
+		rel := strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(path, "/"), srcPrefix), "/")
+		ext := strings.ToLower(filepath.Ext(rel))
+		put := PutObjectInput{
+			Dest:         PathConfig{Path: destPrefix + "/" + rel},
+			Mutipart:     true,
+			ContentType:  publishContentType(ext, input.ContentTypes),
+			CacheControl: input.CacheControl,
+		}
+		if input.Gzip && containsString(gzipExtensions, ext) {
+			put.ContentEncoding = "gzip"
+		}
+		entry, err := publishObject(input.Src, dst, path, put)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to publish %s: %w", path, err)
+		}
+		entry.Path = rel
+		manifest.Files = append(manifest.Files, entry)
+		err = reportProgress(input.Progress, ProgressData{Index: i, Max: len(paths), Value: path})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	body, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	if input.Signer != nil {
+		signature, err := input.Signer.Sign(body)
+		if err != nil {
+			return nil, err
+		}
+		body, err = json.Marshal(SignedManifest{
+			Manifest:  body,
+			Algorithm: input.Signer.Algorithm(),
+			Signature: base64.StdEncoding.EncodeToString(signature),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	manifestCacheControl := input.ManifestCacheControl
+	if manifestCacheControl == "" {
+		manifestCacheControl = defaultManifestCacheControl
+	}
+	_, err = dst.PutObject(PutObjectInput{
+		Source:       ObjectSource{Data: body},
+		Dest:         PathConfig{Path: destPrefix + "/" + manifestName},
+		ContentType:  "application/json",
+		CacheControl: manifestCacheControl,
+	})
+	return manifest, err
+}
+
+// streams a single object to its destination, encoding and hashing it in transit
+func publishObject(src FileStore, dst FileStore, srcPath string, put PutObjectInput) (ManifestEntry, error) {
+	entry := ManifestEntry{
+		ContentType:     put.ContentType,
+		ContentEncoding: put.ContentEncoding,
+	}
+	reader, err := src.GetObject(GetObjectInput{Path: PathConfig{Path: srcPath}})
+	if err != nil {
+		return entry, err
+	}
+	defer reader.Close()
+	var body io.Reader = reader
+	if put.ContentEncoding == "gzip" {
+		pr, pw := io.Pipe()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			gz := gzip.NewWriter(pw)
+			_, err := io.Copy(gz, reader)
+			if err == nil {
+				err = gz.Close()
+			}
+			pw.CloseWithError(err)
+		}()
+		defer func() {
+			//unblocks the encoder if the put fails before reading everything
+			pr.Close()
+			<-done
+		}()
+		body = pr
+	}
+	h := sha256.New()
+	counter := &countingWriter{}
+	put.Source = ObjectSource{Reader: io.TeeReader(body, io.MultiWriter(h, counter))}
+	_, err = dst.PutObject(put)
+	if err != nil {
+		return entry, err
+	}
+	entry.Size = counter.n
+	entry.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return entry, nil
+}
+
+func publishContentType(ext string, contentTypes map[string]string) string {
+	if contentType, ok := contentTypes[ext]; ok {
+		return contentType
+	}
+	if contentType := mime.TypeByExtension(ext); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	cw.n += int64(len(p))
+	return len(p), nil
+}
+
+// VerifyManifest checks the signature of a published manifest and the size and
+// checksum of every object it lists.  Objects are resolved relative to the
+// directory containing the manifest
+func VerifyManifest(store FileStore, manifestPath string, signer ManifestSigner) (*PublishManifest, error) {
+	reader, err := store.GetObject(GetObjectInput{Path: PathConfig{Path: manifestPath}})
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return nil, err
+	}
+	signed := SignedManifest{}
+	if err = json.Unmarshal(data, &signed); err != nil {
+		return nil, err
+	}
+	if signed.Algorithm != signer.Algorithm() {
+		return nil, fmt.Errorf("manifest signed with %s, expected %s", signed.Algorithm, signer.Algorithm())
+	}
+	signature, err := base64.StdEncoding.DecodeString(signed.Signature)
+	if err != nil {
+		return nil, err
+	}
+	//manifests are signed in compact form
+	body := new(bytes.Buffer)
+	if err = json.Compact(body, signed.Manifest); err != nil {
+		return nil, err
+	}
+	if err = signer.Verify(body.Bytes(), signature); err != nil {
+		return nil, err
+	}
+	manifest := &PublishManifest{}
+	if err = json.Unmarshal(signed.Manifest, manifest); err != nil {
+		return nil, err
+	}
+
+	dir := manifestPath[:strings.LastIndex(manifestPath, "/")+1]
+	for _, entry := range manifest.Files {
+		path := dir + entry.Path
+		info, err := store.GetObjectInfo(PathConfig{Path: path})
+		if err != nil {
+			return manifest, err
+		}
+		if info.Size() != entry.Size {
+			return manifest, fmt.Errorf("size mismatch for %s: expected %d, got %d", path, entry.Size, info.Size())
+		}
+		if err = verifyObjectHash(store, path, SHA256, entry.SHA256); err != nil {
+			return manifest, err
+		}
+	}
+	return manifest, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
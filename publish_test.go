@@ -0,0 +1,156 @@
+package filesapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestPublishAndVerifyManifest(t *testing.T) {
+	dir := t.TempDir()
+	store := &BlockFS{Config: BlockFSConfig{ChunkSize: 100}}
+	for _, name := range []string{"/src/model/a.hdf", "/src/model/nested/b.hdf"} {
+		_, err := store.PutObject(PutObjectInput{
+			Source: ObjectSource{Data: []byte(testObjectString + name)},
+			Dest:   PathConfig{Path: dir + name},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	signer := HMACSigner{Key: []byte("release-key")}
+	manifest, err := Publish(PublishInput{
+		Src:        store,
+		SrcPrefix:  dir + "/src/model",
+		DestPrefix: dir + "/release/v1",
+		Signer:     signer,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Files) != 2 {
+		t.Fatalf("expected 2 published files, got %d", len(manifest.Files))
+	}
+
+	manifestPath := dir + "/release/v1/" + defaultManifestName
+	if _, err = VerifyManifest(store, manifestPath, signer); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = VerifyManifest(store, manifestPath, HMACSigner{Key: []byte("wrong-key")}); err == nil {
+		t.Fatal("expected verification with the wrong key to fail")
+	}
+
+	if err = os.WriteFile(dir+"/release/v1/nested/b.hdf", []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = VerifyManifest(store, manifestPath, signer); err == nil {
+		t.Fatal("expected verification of modified data to fail")
+	}
+}
+
+func TestPublishEncodingAndIndex(t *testing.T) {
+	dir := t.TempDir()
+	store := &BlockFS{}
+	csv := bytes.Repeat([]byte("station,stage\n"), 100)
+	for name, data := range map[string][]byte{"/src/gage.csv": csv, "/src/grid.tif": []byte("binary")} {
+		if err := os.MkdirAll(dir+"/src", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(dir+name, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifest, err := Publish(PublishInput{
+		Src:          store,
+		SrcPrefix:    dir + "/src",
+		DestPrefix:   dir + "/public",
+		Gzip:         true,
+		ContentTypes: map[string]string{".tif": "image/tiff"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := map[string]ManifestEntry{}
+	for _, entry := range manifest.Files {
+		entries[entry.Path] = entry
+	}
+	if entries["gage.csv"].ContentEncoding != "gzip" || entries["grid.tif"].ContentEncoding != "" || entries["grid.tif"].ContentType != "image/tiff" {
+		t.Fatalf("unexpected manifest entries %+v", manifest.Files)
+	}
+	reader, err := store.GetObject(GetObjectInput{Path: PathConfig{Path: dir + "/public/gage.csv"}, Decompress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil || !bytes.Equal(data, csv) {
+		t.Fatalf("unexpected decoded csv %v", err)
+	}
+	if info, _ := store.GetObjectInfo(PathConfig{Path: dir + "/public/gage.csv"}); info.Size() != entries["gage.csv"].Size || info.Size() >= int64(len(csv)) {
+		t.Fatal("expected the manifest to describe the smaller stored object")
+	}
+
+	//without a signer the manifest is a plain index
+	index, err := os.ReadFile(dir + "/public/" + defaultManifestName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsigned := PublishManifest{}
+	if err = json.Unmarshal(index, &unsigned); err != nil || len(unsigned.Files) != 2 {
+		t.Fatalf("expected an unsigned index of 2 files: %v", err)
+	}
+}
+
+func TestPublishHeadersS3(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(dir+"/stations.geojson", []byte(`{"type":"FeatureCollection"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	backend := &noPartCopyServer{objects: map[string][]byte{}, headers: map[string]http.Header{}}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+	public, err := NewFileStore(MinioFSConfig{
+		S3FSConfig: S3FSConfig{
+			S3Region:    "us-east-1",
+			S3Bucket:    "bucket",
+			Credentials: S3FS_Static{S3Id: "id", S3Key: "key"},
+		},
+		HostAddress: server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Publish(PublishInput{
+		Src:          &BlockFS{},
+		Dst:          public,
+		SrcPrefix:    dir,
+		DestPrefix:   "/datasets/stations",
+		CacheControl: "public, max-age=86400",
+		Gzip:         true,
+		ContentTypes: map[string]string{".geojson": "application/geo+json"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers := backend.headers["datasets/stations/stations.geojson"]
+	if headers.Get("Content-Type") != "application/geo+json" || headers.Get("Cache-Control") != "public, max-age=86400" || headers.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("unexpected object headers %v", headers)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(backend.objects["datasets/stations/stations.geojson"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(gz); string(data) != `{"type":"FeatureCollection"}` {
+		t.Fatalf("unexpected published object %s", data)
+	}
+	manifest := backend.headers["datasets/stations/"+defaultManifestName]
+	if manifest.Get("Cache-Control") != defaultManifestCacheControl || manifest.Get("Content-Type") != "application/json" {
+		t.Fatalf("unexpected manifest headers %v", manifest)
+	}
+}
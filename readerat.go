@@ -0,0 +1,70 @@
+package filesapi
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// ReadSeekCloserAt is a random access reader over an object, suitable for formats read
+// partially (i.e. zip, HDF5, and GeoTIFF).  The caller is responsible for closing it
+type ReadSeekCloserAt interface {
+	io.ReadSeekCloser
+	io.ReaderAt
+
+	//size of the object when the reader was opened
+	Size() int64
+}
+
+// ReaderAtOpener is implemented by stores with a native random access reader
+type ReaderAtOpener interface {
+	OpenReaderAt(path PathConfig) (ReadSeekCloserAt, error)
+}
+
+// OpenReaderAt opens a random access reader over an object, so only the bytes that are
+// read are transferred.  Stores implementing ReaderAtOpener open the reader themselves.
+// Other stores (including decorators) are read with ranged GetObject requests
+func OpenReaderAt(store FileStore, path PathConfig) (ReadSeekCloserAt, error) {
+	if ro, ok := store.(ReaderAtOpener); ok {
+		return ro.OpenReaderAt(path)
+	}
+	return NewObjectReadSeeker(store, path)
+}
+
+// OpenReaderAt opens the file.  Reads are served by the os.File without copies through a store
+func (b *BlockFS) OpenReaderAt(path PathConfig) (ReadSeekCloserAt, error) {
+	f, err := os.Open(path.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, &FileNotFoundError{path.Path}
+	}
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		f.Close()
+		return nil, &FileNotFoundError{path.Path}
+	}
+	return &fileReaderAt{File: f, size: info.Size()}, nil
+}
+
+// OpenReaderAt reads the object with ranged GET requests.  Each ReadAt is a single request,
+// so readers making many small reads should read in larger blocks
+func (s3fs *S3FS) OpenReaderAt(path PathConfig) (ReadSeekCloserAt, error) {
+	return NewObjectReadSeeker(s3fs, path)
+}
+
+// fileReaderAt is an open block file and its size
+type fileReaderAt struct {
+	*os.File
+	size int64
+}
+
+func (fr *fileReaderAt) Size() int64 {
+	return fr.size
+}
@@ -0,0 +1,105 @@
+package filesapi
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenReaderAtBlockFS(t *testing.T) {
+	store, err := NewFileStore(BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "data.zip")
+	f, _ := os.Create(archive)
+	zw := zip.NewWriter(f)
+	w, _ := zw.Create("a.csv")
+	io.WriteString(w, testObjectString)
+	zw.Close()
+	f.Close()
+
+	for _, s := range []FileStore{store, struct{ FileStore }{store}} {
+		reader, err := OpenReaderAt(s, PathConfig{Path: archive})
+		if err != nil {
+			t.Fatal(err)
+		}
+		zr, err := zip.NewReader(reader, reader.Size())
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry, _ := zr.File[0].Open()
+		data, _ := io.ReadAll(entry)
+		if string(data) != testObjectString {
+			t.Fatalf("unexpected zip entry %q", data)
+		}
+		reader.Close()
+	}
+
+	var fnf *FileNotFoundError
+	if _, err = OpenReaderAt(store, PathConfig{Path: filepath.Dir(archive)}); !errors.As(err, &fnf) {
+		t.Fatalf("expected directories to be not found, got %v", err)
+	}
+	if _, err = OpenReaderAt(store, PathConfig{Path: archive + ".missing"}); !errors.As(err, &fnf) {
+		t.Fatalf("expected missing files to be not found, got %v", err)
+	}
+}
+
+func TestOpenReaderAtS3(t *testing.T) {
+	object := "0123456789ABCDEFGHIJ"
+	ranges := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("attributes") {
+			fmt.Fprintf(w, "<GetObjectAttributesResponse><ObjectSize>%d</ObjectSize></GetObjectAttributesResponse>", len(object))
+			return
+		}
+		var start, end int
+		if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end); err != nil {
+			io.WriteString(w, object)
+			return
+		}
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(object)))
+		w.WriteHeader(http.StatusPartialContent)
+		io.WriteString(w, object[start:end+1])
+	}))
+	defer server.Close()
+	store, err := NewFileStore(MinioFSConfig{
+		S3FSConfig: S3FSConfig{
+			S3Region:    "us-east-1",
+			S3Bucket:    "bucket",
+			Credentials: S3FS_Static{S3Id: "id", S3Key: "key"},
+		},
+		HostAddress: server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := OpenReaderAt(store, PathConfig{Path: "/rasters/a.tif"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if reader.Size() != int64(len(object)) {
+		t.Fatalf("unexpected size %d", reader.Size())
+	}
+	buf := make([]byte, 4)
+	if n, err := reader.ReadAt(buf, 10); err != nil || string(buf[:n]) != "ABCD" {
+		t.Fatalf("unexpected ReadAt %q: %v", buf[:n], err)
+	}
+	reader.Seek(-3, io.SeekEnd)
+	rest, _ := io.ReadAll(reader)
+	if string(rest) != "HIJ" {
+		t.Fatalf("unexpected read after seek %q", rest)
+	}
+	if strings.Join(ranges, ",") != "bytes=10-13,bytes=17-19" {
+		t.Fatalf("expected only the read ranges to be requested, got %v", ranges)
+	}
+}
@@ -0,0 +1,201 @@
+package filesapi
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrReadOnlyStore = errors.New("store is read only")
+
+// readOnlyStore implements the write operations of read only stores
+// (i.e. HTTPFS and archive stores).  Every write returns ErrReadOnlyStore
+type readOnlyStore struct{}
+
+func (readOnlyStore) PutObject(poi PutObjectInput) (*FileOperationOutput, error) {
+	return nil, ErrReadOnlyStore
+}
+
+func (readOnlyStore) CopyObject(input CopyObjectInput) error {
+	return ErrReadOnlyStore
+}
+
+func (readOnlyStore) MoveObject(input MoveObjectInput) error {
+	return ErrReadOnlyStore
+}
+
+func (readOnlyStore) InitializeObjectUpload(u UploadConfig) (UploadResult, error) {
+	return UploadResult{}, ErrReadOnlyStore
+}
+
+func (readOnlyStore) WriteChunk(u UploadConfig) (UploadResult, error) {
+	return UploadResult{}, ErrReadOnlyStore
+}
+
+func (readOnlyStore) CompleteObjectUpload(u CompletedObjectUploadConfig) error {
+	return ErrReadOnlyStore
+}
+
+func (readOnlyStore) AbortObjectUpload(u UploadConfig) error {
+	return ErrReadOnlyStore
+}
+
+func (readOnlyStore) ListUploadParts(u UploadConfig) ([]UploadPart, error) {
+	return nil, ErrReadOnlyStore
+}
+
+func (readOnlyStore) DeleteObjects(doi DeleteObjectInput) []error {
+	return []error{ErrReadOnlyStore}
+}
+
+// file info reported by stores that are not backed by a file system or S3 (i.e. HTTPFS)
+type storeFileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	isDir   bool
+
+	//entity tag reported by the store, if any
+	etag string
+}
+
+func (sfi *storeFileInfo) Name() string {
+	return sfi.name
+}
+
+func (sfi *storeFileInfo) Size() int64 {
+	return sfi.size
+}
+
+func (sfi *storeFileInfo) Mode() os.FileMode {
+	if sfi.isDir {
+		return os.ModeDir
+	}
+	return os.ModeIrregular
+}
+
+func (sfi *storeFileInfo) ModTime() time.Time {
+	return sfi.modTime
+}
+
+func (sfi *storeFileInfo) IsDir() bool {
+	return sfi.isDir
+}
+
+func (sfi *storeFileInfo) Sys() interface{} {
+	return nil
+}
+
+// an object listed by a store index
+type storeEntry struct {
+
+	//path relative to the store root, without a leading slash
+	path string
+	info fs.FileInfo
+}
+
+// the objects of a read only store sorted by path.  Directories are implied by the object paths
+type entryIndex []storeEntry
+
+func newEntryIndex(entries []storeEntry) entryIndex {
+	for i := range entries {
+		entries[i].path = strings.TrimPrefix(entries[i].path, "/")
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].path < entries[j].path })
+	return entries
+}
+
+// prefix of the relative entry paths beneath a directory path
+func dirPrefix(dir string) string {
+	dir = strings.Trim(dir, "/")
+	if dir == "" {
+		return ""
+	}
+	return dir + "/"
+}
+
+// returns the position of the object at a path
+func (ei entryIndex) find(p string) (int, bool) {
+	name := strings.TrimPrefix(p, "/")
+	i := sort.Search(len(ei), func(i int) bool { return ei[i].path >= name })
+	return i, i < len(ei) && ei[i].path == name
+}
+
+// returns the info of an object, or of a directory containing objects
+func (ei entryIndex) info(p string) (fs.FileInfo, error) {
+	if i, ok := ei.find(p); ok {
+		return ei[i].info, nil
+	}
+	prefix := dirPrefix(p)
+	i := sort.Search(len(ei), func(i int) bool { return ei[i].path >= prefix })
+	if i < len(ei) && strings.HasPrefix(ei[i].path, prefix) {
+		return &storeFileInfo{name: path.Base(p), isDir: true}, nil
+	}
+	return nil, &FileNotFoundError{p}
+}
+
+// lists the objects and directories immediately beneath a directory
+func (ei entryIndex) listDir(input ListDirInput) *[]FileStoreResultObject {
+	prefix := dirPrefix(input.Path.Path)
+	result := []FileStoreResultObject{}
+	dirs := map[string]bool{}
+	for _, entry := range ei {
+		if !strings.HasPrefix(entry.path, prefix) {
+			continue
+		}
+		rel := strings.TrimPrefix(entry.path, prefix)
+		if i := strings.Index(rel, "/"); i >= 0 {
+			name := rel[:i]
+			if !dirs[name] {
+				dirs[name] = true
+				result = append(result, FileStoreResultObject{
+					ID:    len(result),
+					Name:  name,
+					Path:  input.Path.Path,
+					IsDir: true,
+				})
+			}
+			continue
+		}
+		result = append(result, FileStoreResultObject{
+			ID:       len(result),
+			Name:     rel,
+			Size:     strconv.FormatInt(entry.info.Size(), 10),
+			Path:     input.Path.Path,
+			Type:     filepath.Ext(rel),
+			Modified: entry.info.ModTime(),
+		})
+	}
+	return &result
+}
+
+// visits the objects beneath a path in lexicographic order
+func (ei entryIndex) walk(input WalkInput, vistorFunction FileVisitFunction) error {
+	prefix := dirPrefix(input.Path.Path)
+	startAfter := strings.TrimPrefix(input.StartAfter, "/")
+	count := 0
+	for _, entry := range ei {
+		if !strings.HasPrefix(entry.path, prefix) || (startAfter != "" && entry.path <= startAfter) {
+			continue
+		}
+		if err := vistorFunction("/"+entry.path, entry.info); err != nil {
+			return err
+		}
+		err := reportProgress(input.Progress, ProgressData{
+			Index: count,
+			Max:   len(ei),
+			Value: entry.info,
+		})
+		if err != nil {
+			return err
+		}
+		count++
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package filesapi
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestReadOnlyStore(t *testing.T) {
+	store := readOnlyStore{}
+	if _, err := store.PutObject(PutObjectInput{}); !errors.Is(err, ErrReadOnlyStore) {
+		t.Fatalf("expected puts to be refused, got %v", err)
+	}
+	if err := store.MoveObject(MoveObjectInput{}); !errors.Is(err, ErrReadOnlyStore) {
+		t.Fatalf("expected moves to be refused, got %v", err)
+	}
+	if _, err := store.InitializeObjectUpload(UploadConfig{}); !errors.Is(err, ErrReadOnlyStore) {
+		t.Fatalf("expected uploads to be refused, got %v", err)
+	}
+	if errs := store.DeleteObjects(DeleteObjectInput{}); len(errs) != 1 || !errors.Is(errs[0], ErrReadOnlyStore) {
+		t.Fatalf("expected deletes to be refused, got %v", errs)
+	}
+}
+
+func TestEntryIndex(t *testing.T) {
+	index := newEntryIndex([]storeEntry{
+		{path: "/b/c.txt", info: &storeFileInfo{name: "c.txt", size: 3}},
+		{path: "a.txt", info: &storeFileInfo{name: "a.txt", size: 1}},
+		{path: "b/d/e.txt", info: &storeFileInfo{name: "e.txt", size: 5}},
+	})
+
+	if info, err := index.info("/b/c.txt"); err != nil || info.Size() != 3 {
+		t.Fatalf("unexpected object info %v: %v", info, err)
+	}
+	if info, err := index.info("/b/d"); err != nil || !info.IsDir() {
+		t.Fatalf("expected an implied directory, got %v: %v", info, err)
+	}
+	var fnf *FileNotFoundError
+	if _, err := index.info("/b/missing"); !errors.As(err, &fnf) {
+		t.Fatalf("expected a missing path to be not found, got %v", err)
+	}
+
+	list := *index.listDir(ListDirInput{Path: PathConfig{Path: "/b"}})
+	if len(list) != 2 || list[0].Name != "c.txt" || list[0].Size != "3" || list[1].Name != "d" || !list[1].IsDir {
+		t.Fatalf("unexpected listing %v", list)
+	}
+
+	visited := []string{}
+	err := index.walk(WalkInput{Path: PathConfig{Path: "/b"}, StartAfter: "/b/c.txt"}, func(path string, file fs.FileInfo) error {
+		visited = append(visited, path)
+		return nil
+	})
+	if err != nil || len(visited) != 1 || visited[0] != "/b/d/e.txt" {
+		t.Fatalf("unexpected walk %v: %v", visited, err)
+	}
+}
@@ -0,0 +1,162 @@
+package filesapi
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+)
+
+type DivergenceType string
+
+const (
+	//object exists in the old store but not the new store
+	DivergenceMissing DivergenceType = "missing"
+
+	//object sizes differ between the stores
+	DivergenceSize DivergenceType = "size"
+
+	//object exists in the new store but not the old store
+	DivergenceExtra DivergenceType = "extra"
+)
+
+type Divergence struct {
+	Path    string
+	Type    DivergenceType
+	OldSize int64
+	NewSize int64
+
+	//true if the object was copied from the old store to repair the divergence
+	Repaired bool
+
+	//repair error
+	Err error
+}
+
+type ReconcileInput struct {
+	Old    FileStore
+	New    FileStore
+	Prefix PathConfig
+
+	//copy missing and mismatched objects from the old store into the new store.
+	//extra objects in the new store are reported but never deleted
+	Repair bool
+
+	Progress ProgressFunction
+}
+
+type ReconcileReport struct {
+	Checked     int64
+	Divergences []Divergence
+	Repaired    int
+	Failed      int
+}
+
+// Converged is true when no divergence remains unrepaired
+func (rr *ReconcileReport) Converged() bool {
+	return len(rr.Divergences) == rr.Repaired
+}
+
+// Reconcile diffs two stores under a prefix by path and size, optionally repairing the
+// new store from the old store.  Use it alongside MigrationFS to confirm the new store
+// holds everything in the old store before retiring it.  Both stores must use the same path layout
+func Reconcile(input ReconcileInput) (*ReconcileReport, error) {
+	if input.Old == nil || input.New == nil {
+		return nil, errors.New("reconcile requires an old and new store")
+	}
+	report := &ReconcileReport{Divergences: []Divergence{}}
+	newSizes := map[string]int64{}
+	err := input.New.Walk(WalkInput{Path: input.Prefix}, func(path string, file os.FileInfo) error {
+		if !file.IsDir() {
+			newSizes[path] = file.Size()
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	count := 0
+	err = input.Old.Walk(WalkInput{Path: input.Prefix}, func(path string, file os.FileInfo) error {
+		if file.IsDir() {
+			return nil
+		}
+		report.Checked++
+		newSize, ok := newSizes[path]
+		delete(newSizes, path)
+		var d *Divergence
+		switch {
+		case !ok:
+			d = &Divergence{Path: path, Type: DivergenceMissing, OldSize: file.Size(), NewSize: -1}
+		case newSize != file.Size():
+			d = &Divergence{Path: path, Type: DivergenceSize, OldSize: file.Size(), NewSize: newSize}
+		}
+		if d != nil {
+			if input.Repair {
+				d.Err = streamObject(input.Old, input.New, PathConfig{Path: path})
+				d.Repaired = d.Err == nil
+				if d.Repaired {
+					report.Repaired++
+				} else {
+					report.Failed++
+				}
+			}
+			report.Divergences = append(report.Divergences, *d)
+		}
+		err := reportProgress(input.Progress, ProgressData{
+			Index: count,
+			Max:   -1,
+			Value: path,
+		})
+		count++
+		return err
+	})
+	if err != nil {
+		return report, err
+	}
+	for path, size := range newSizes {
+		report.Divergences = append(report.Divergences, Divergence{Path: path, Type: DivergenceExtra, OldSize: -1, NewSize: size})
+	}
+	return report, nil
+}
+
+// ReconcileEvery runs Reconcile on a schedule until the context is cancelled,
+// passing each report to the handler
+func ReconcileEvery(ctx context.Context, input ReconcileInput, interval time.Duration, handler func(*ReconcileReport, error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		handler(Reconcile(input))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile diffs the old and new stores of the migration under prefix
+func (mfs *MigrationFS) Reconcile(prefix PathConfig, repair bool) (*ReconcileReport, error) {
+	return Reconcile(ReconcileInput{
+		Old:    mfs.FileStore,
+		New:    mfs.New,
+		Prefix: prefix,
+		Repair: repair,
+	})
+}
+
+// streams an object from one store into the same path in another
+func streamObject(src FileStore, dest FileStore, path PathConfig) error {
+	reader, err := src.GetObject(GetObjectInput{Path: path})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	_, err = dest.PutObject(PutObjectInput{
+		Source:   ObjectSource{Reader: reader},
+		Dest:     path,
+		Mutipart: true,
+	})
+	return err
+}
@@ -0,0 +1,58 @@
+package filesapi
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReconcile(t *testing.T) {
+	oldDir := t.TempDir()
+	newDir := t.TempDir()
+	files := map[string]string{
+		oldDir + "/runs/synced.dss":    "synced",
+		newDir + "/runs/synced.dss":    "synced",
+		oldDir + "/runs/missing.dss":   "missing",
+		oldDir + "/runs/truncated.dss": "truncated",
+		newDir + "/runs/truncated.dss": "trunc",
+		newDir + "/runs/extra.dss":     "extra",
+	}
+	for path, data := range files {
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mfs := NewMigrationFS(&rootedFS{BlockFS: &BlockFS{}, root: oldDir}, &rootedFS{BlockFS: &BlockFS{}, root: newDir})
+
+	report, err := mfs.Reconcile(PathConfig{Path: "/runs"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]DivergenceType{}
+	for _, d := range report.Divergences {
+		found[d.Path] = d.Type
+	}
+	if report.Checked != 3 || len(found) != 3 || found["/runs/missing.dss"] != DivergenceMissing ||
+		found["/runs/truncated.dss"] != DivergenceSize || found["/runs/extra.dss"] != DivergenceExtra {
+		t.Fatalf("unexpected divergences %+v", report.Divergences)
+	}
+
+	report, err = mfs.Reconcile(PathConfig{Path: "/runs"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Repaired != 2 || report.Failed != 0 || report.Converged() {
+		t.Fatalf("unexpected repair report %+v", report)
+	}
+	data, err := os.ReadFile(newDir + "/runs/truncated.dss")
+	if err != nil || string(data) != "truncated" {
+		t.Fatalf("expected the truncated object to be repaired: %q %v", data, err)
+	}
+
+	//only the extra object remains
+	report, err = mfs.Reconcile(PathConfig{Path: "/runs"}, true)
+	if err != nil || len(report.Divergences) != 1 || report.Divergences[0].Type != DivergenceExtra {
+		t.Fatalf("unexpected report after repair %+v %v", report, err)
+	}
+}
@@ -0,0 +1,85 @@
+package filesapi
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// FileStoreFactory creates a store from a configuration
+type FileStoreFactory func(cfg any) (FileStore, error)
+
+// SchemeConfig is implemented by the configuration types of registered backends.
+// NewFileStore passes configurations it does not recognize to the factory registered
+// for their scheme
+type SchemeConfig interface {
+	StoreScheme() string
+}
+
+// StoreURI is the configuration passed to a registered factory by NewFileStoreFromURI
+// for a URI with the factory scheme
+type StoreURI struct {
+	URL     *url.URL
+	Options StoreURIOptions
+}
+
+func (su StoreURI) StoreScheme() string {
+	return su.URL.Scheme
+}
+
+// schemes handled by NewFileStoreFromURI, which cannot be registered
+var builtinSchemes = map[string]bool{"s3": true, "minio": true, "file": true, "http": true, "https": true}
+
+var (
+	factoriesMu sync.RWMutex
+	factories   = map[string]FileStoreFactory{}
+)
+
+// RegisterFileStore makes a backend available to NewFileStore and NewFileStoreFromURI
+// under a URI scheme, so packages can contribute stores without changes to this package.
+// The factory receives either a SchemeConfig naming the scheme or, for URIs, a StoreURI.
+// Like database/sql.Register, it is intended to be called from an init function and
+// panics if the factory is nil or the scheme is built in or already registered
+func RegisterFileStore(scheme string, factory func(cfg any) (FileStore, error)) {
+	scheme = strings.ToLower(scheme)
+	if factory == nil {
+		panic("filesapi: RegisterFileStore factory is nil for scheme " + scheme)
+	}
+	if builtinSchemes[scheme] {
+		panic("filesapi: RegisterFileStore cannot replace built in scheme " + scheme)
+	}
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	if _, ok := factories[scheme]; ok {
+		panic("filesapi: RegisterFileStore called twice for scheme " + scheme)
+	}
+	factories[scheme] = factory
+}
+
+// RegisteredSchemes returns the schemes of the registered backends
+func RegisteredSchemes() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	schemes := make([]string, 0, len(factories))
+	for scheme := range factories {
+		schemes = append(schemes, scheme)
+	}
+	return schemes
+}
+
+func registeredFactory(scheme string) (FileStoreFactory, bool) {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	factory, ok := factories[strings.ToLower(scheme)]
+	return factory, ok
+}
+
+// creates a store with the factory registered for a configuration scheme
+func newRegisteredFileStore(cfg SchemeConfig) (FileStore, error) {
+	factory, ok := registeredFactory(cfg.StoreScheme())
+	if !ok {
+		return nil, fmt.Errorf("%w: no store is registered for scheme %q", ErrInvalidConfig, cfg.StoreScheme())
+	}
+	return factory(cfg)
+}
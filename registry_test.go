@@ -0,0 +1,68 @@
+package filesapi
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// removes a backend registered by a test, so the test can run more than once in a process
+func unregisterFileStore(scheme string) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	delete(factories, strings.ToLower(scheme))
+}
+
+type memoryTestConfig struct {
+	root string
+}
+
+func (memoryTestConfig) StoreScheme() string {
+	return "memtest"
+}
+
+func TestRegisterFileStore(t *testing.T) {
+	var received []any
+	RegisterFileStore("memtest", func(cfg any) (FileStore, error) {
+		received = append(received, cfg)
+		return NewFileStore(BlockFSConfig{})
+	})
+	t.Cleanup(func() { unregisterFileStore("memtest") })
+
+	if _, err := NewFileStore(memoryTestConfig{root: "/data"}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg, ok := received[0].(memoryTestConfig); !ok || cfg.root != "/data" {
+		t.Fatalf("expected the factory to receive the configuration, got %+v", received[0])
+	}
+
+	if _, err := NewFileStoreFromURI("memtest://host/path", StoreURIOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if uri, ok := received[1].(StoreURI); !ok || uri.URL.Host != "host" || uri.URL.Path != "/path" {
+		t.Fatalf("expected the factory to receive the uri, got %+v", received[1])
+	}
+
+	found := false
+	for _, scheme := range RegisteredSchemes() {
+		found = found || scheme == "memtest"
+	}
+	if !found {
+		t.Fatal("expected memtest to be a registered scheme")
+	}
+
+	if _, err := NewFileStoreFromURI("unregistered://host", StoreURIOptions{}); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("expected an unregistered scheme to be rejected, got %v", err)
+	}
+
+	for _, scheme := range []string{"memtest", "s3"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("expected registering %s to panic", scheme)
+				}
+			}()
+			RegisterFileStore(scheme, func(cfg any) (FileStore, error) { return nil, nil })
+		}()
+	}
+}
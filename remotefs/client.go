@@ -0,0 +1,302 @@
+package remotefs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/usace/filesapi"
+	"github.com/usace/filesapi/remotefs/remotepb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrUnsupported is returned by the multipart upload methods of a Client.
+// PutObject streams objects of any size
+var ErrUnsupported = errors.New("operation not supported by remote stores")
+
+// Client is a FileStore using a store served by a remote Server
+type Client struct {
+	rpc remotepb.FileStoreClient
+}
+
+// NewClient returns a FileStore using the store served on a connection (i.e. a *grpc.ClientConn)
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{rpc: remotepb.NewFileStoreClient(conn)}
+}
+
+// returns the context for a call, honoring the timeout of the call options.
+// The cancel function must always be called
+func callContext(options *filesapi.CallOptions) (context.Context, context.CancelFunc) {
+	if options == nil || options.Timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), options.Timeout)
+}
+
+func (c *Client) ListDir(input filesapi.ListDirInput) (*[]filesapi.FileStoreResultObject, error) {
+	ctx, cancel := callContext(input.Options)
+	defer cancel()
+	resp, err := c.rpc.ListDir(ctx, &remotepb.ListDirRequest{
+		Path:   input.Path.Path,
+		Page:   int32(input.Page),
+		Size:   input.Size,
+		Filter: input.Filter,
+	})
+	if err != nil {
+		return nil, storeError(input.Path.Path, err)
+	}
+	results := make([]filesapi.FileStoreResultObject, 0, len(resp.GetEntries()))
+	for _, e := range resp.GetEntries() {
+		results = append(results, filesapi.FileStoreResultObject{
+			ID:         int(e.GetId()),
+			Name:       e.GetName(),
+			Size:       e.GetSize(),
+			Path:       e.GetPath(),
+			Type:       e.GetType(),
+			IsDir:      e.GetIsDir(),
+			Modified:   fromUnixNano(e.GetModifiedUnixNano()),
+			ModifiedBy: e.GetModifiedBy(),
+		})
+	}
+	return &results, nil
+}
+
+func (c *Client) GetDir(path filesapi.PathConfig) (*[]filesapi.FileStoreResultObject, error) {
+	return c.ListDir(filesapi.ListDirInput{Path: path})
+}
+
+func (c *Client) GetObjectInfo(path filesapi.PathConfig) (fs.FileInfo, error) {
+	info, err := c.rpc.GetObjectInfo(context.Background(), &remotepb.PathRequest{Path: path.Path})
+	if err != nil {
+		return nil, storeError(path.Path, err)
+	}
+	return newFileInfo(info), nil
+}
+
+// GetObject streams the object from the server.  Errors opening the object are
+// returned by GetObject, rather than the first Read
+func (c *Client) GetObject(input filesapi.GetObjectInput) (io.ReadCloser, error) {
+	ctx, cancel := callContext(input.Options)
+	stream, err := c.rpc.GetObject(ctx, &remotepb.GetObjectRequest{Path: input.Path.Path, Range: input.Range})
+	if err != nil {
+		cancel()
+		return nil, storeError(input.Path.Path, err)
+	}
+	reader := &objectReader{stream: stream, cancel: cancel, path: input.Path.Path}
+	chunk, err := stream.Recv()
+	switch {
+	case err == io.EOF:
+		reader.err = io.EOF
+	case err != nil:
+		cancel()
+		return nil, storeError(input.Path.Path, err)
+	default:
+		reader.buf = chunk.GetData()
+	}
+	return reader, nil
+}
+
+// reads the chunks of a GetObject stream
+type objectReader struct {
+	stream remotepb.FileStore_GetObjectClient
+	cancel context.CancelFunc
+	path   string
+	buf    []byte
+	err    error
+}
+
+func (or *objectReader) Read(p []byte) (int, error) {
+	for len(or.buf) == 0 {
+		if or.err != nil {
+			return 0, or.err
+		}
+		chunk, err := or.stream.Recv()
+		if err != nil {
+			if err != io.EOF {
+				err = storeError(or.path, err)
+			}
+			or.err = err
+			return 0, err
+		}
+		or.buf = chunk.GetData()
+	}
+	n := copy(p, or.buf)
+	or.buf = or.buf[n:]
+	return n, nil
+}
+
+func (or *objectReader) Close() error {
+	or.cancel()
+	return nil
+}
+
+func (c *Client) ResourceName() string {
+	resp, err := c.rpc.ResourceName(context.Background(), &remotepb.ResourceNameRequest{})
+	if err != nil {
+		return ""
+	}
+	return resp.GetName()
+}
+
+func (c *Client) PutObject(input filesapi.PutObjectInput) (*filesapi.FileOperationOutput, error) {
+	reader, err := input.Source.GetReader()
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := reader.(io.Closer); ok && input.Source.Reader == nil {
+		defer closer.Close()
+	}
+	ctx, cancel := callContext(input.Options)
+	defer cancel()
+	stream, err := c.rpc.PutObject(ctx)
+	if err != nil {
+		return nil, storeError(input.Dest.Path, err)
+	}
+	header := &remotepb.PutObjectHeader{
+		Path:            input.Dest.Path,
+		ContentLength:   -1,
+		ContentType:     input.ContentType,
+		ContentEncoding: input.ContentEncoding,
+		CacheControl:    input.CacheControl,
+		IfNoneMatch:     input.IfNoneMatch,
+	}
+	if input.Source.ContentLength != nil {
+		header.ContentLength = *input.Source.ContentLength
+	}
+	if err := stream.Send(&remotepb.PutObjectRequest{Msg: &remotepb.PutObjectRequest_Header{Header: header}}); err != nil {
+		return nil, putError(input.Dest.Path, stream, err)
+	}
+	buf := make([]byte, chunkSize)
+	for {
+		n, rerr := io.ReadFull(reader, buf)
+		if n > 0 {
+			data := &remotepb.PutObjectRequest_Data{Data: buf[:n]}
+			if err := stream.Send(&remotepb.PutObjectRequest{Msg: data}); err != nil {
+				return nil, putError(input.Dest.Path, stream, err)
+			}
+		}
+		if rerr == io.EOF || rerr == io.ErrUnexpectedEOF {
+			break
+		}
+		if rerr != nil {
+			return nil, rerr
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return nil, storeError(input.Dest.Path, err)
+	}
+	return &filesapi.FileOperationOutput{ETag: resp.GetEtag()}, nil
+}
+
+// a failed Send returns io.EOF when the server ended the stream, and the server error from CloseAndRecv
+func putError(path string, stream remotepb.FileStore_PutObjectClient, err error) error {
+	if err == io.EOF {
+		_, err = stream.CloseAndRecv()
+	}
+	return storeError(path, err)
+}
+
+func (c *Client) CopyObject(input filesapi.CopyObjectInput) error {
+	ctx, cancel := callContext(input.Options)
+	defer cancel()
+	_, err := c.rpc.CopyObject(ctx, &remotepb.CopyObjectRequest{Src: input.Src.Path, Dest: input.Dest.Path})
+	return storeError(input.Src.Path, err)
+}
+
+func (c *Client) MoveObject(input filesapi.MoveObjectInput) error {
+	ctx, cancel := callContext(input.Options)
+	defer cancel()
+	_, err := c.rpc.MoveObject(ctx, &remotepb.MoveObjectRequest{Src: input.Src.Path, Dest: input.Dest.Path})
+	return storeError(input.Src.Path, err)
+}
+
+func (c *Client) InitializeObjectUpload(u filesapi.UploadConfig) (filesapi.UploadResult, error) {
+	return filesapi.UploadResult{}, ErrUnsupported
+}
+
+func (c *Client) WriteChunk(u filesapi.UploadConfig) (filesapi.UploadResult, error) {
+	return filesapi.UploadResult{}, ErrUnsupported
+}
+
+func (c *Client) CompleteObjectUpload(u filesapi.CompletedObjectUploadConfig) error {
+	return ErrUnsupported
+}
+
+func (c *Client) AbortObjectUpload(u filesapi.UploadConfig) error {
+	return ErrUnsupported
+}
+
+func (c *Client) ListUploadParts(u filesapi.UploadConfig) ([]filesapi.UploadPart, error) {
+	return nil, ErrUnsupported
+}
+
+func (c *Client) DeleteObjects(input filesapi.DeleteObjectInput) []error {
+	ctx, cancel := callContext(input.Options)
+	defer cancel()
+	resp, err := c.rpc.DeleteObjects(ctx, &remotepb.DeleteObjectsRequest{Paths: input.Paths.All()})
+	if err != nil {
+		return []error{storeError("", err)}
+	}
+	var errs []error
+	for _, msg := range resp.GetErrors() {
+		errs = append(errs, errors.New(msg))
+	}
+	return errs
+}
+
+func (c *Client) Walk(input filesapi.WalkInput, vistorFunction filesapi.FileVisitFunction) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := c.rpc.Walk(ctx, &remotepb.WalkRequest{Path: input.Path.Path, StartAfter: input.StartAfter})
+	if err != nil {
+		return storeError(input.Path.Path, err)
+	}
+	for i := 0; ; i++ {
+		oi, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return storeError(input.Path.Path, err)
+		}
+		if input.Progress != nil {
+			if err := input.Progress(filesapi.ProgressData{Index: i, Max: -1, Value: oi.GetPath()}); err != nil {
+				return err
+			}
+		}
+		if err := vistorFunction(oi.GetPath(), newFileInfo(oi)); err != nil {
+			return err
+		}
+	}
+}
+
+// converts the status errors of the server back to store errors
+func storeError(path string, err error) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch st.Code() {
+	case codes.NotFound:
+		return filesapi.NewFileNotFoundError(path)
+	case codes.AlreadyExists:
+		return fmt.Errorf("%w: %s", filesapi.ErrObjectExists, st.Message())
+	case codes.PermissionDenied:
+		return fmt.Errorf("%w: %s", fs.ErrPermission, st.Message())
+	case codes.Canceled:
+		return fmt.Errorf("%w: %s", context.Canceled, st.Message())
+	case codes.DeadlineExceeded:
+		return fmt.Errorf("%w: %s", context.DeadlineExceeded, st.Message())
+	}
+	return err
+}
+
+var _ filesapi.FileStore = (*Client)(nil)
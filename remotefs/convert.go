@@ -0,0 +1,82 @@
+package remotefs
+
+import (
+	"io/fs"
+	"path"
+	"time"
+
+	"github.com/usace/filesapi/remotefs/remotepb"
+)
+
+// fileInfo is the fs.FileInfo of objects reported by a remote store
+type fileInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+	isDir   bool
+}
+
+func (fi *fileInfo) Name() string {
+	return fi.name
+}
+
+func (fi *fileInfo) Size() int64 {
+	return fi.size
+}
+
+func (fi *fileInfo) Mode() fs.FileMode {
+	if fi.isDir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+func (fi *fileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+
+func (fi *fileInfo) IsDir() bool {
+	return fi.isDir
+}
+
+func (fi *fileInfo) Sys() any {
+	return nil
+}
+
+func objectInfo(p string, info fs.FileInfo) *remotepb.ObjectInfo {
+	return &remotepb.ObjectInfo{
+		Path:            p,
+		Name:            info.Name(),
+		Size:            info.Size(),
+		ModTimeUnixNano: unixNano(info.ModTime()),
+		IsDir:           info.IsDir(),
+	}
+}
+
+func newFileInfo(oi *remotepb.ObjectInfo) *fileInfo {
+	name := oi.GetName()
+	if name == "" {
+		name = path.Base(oi.GetPath())
+	}
+	return &fileInfo{
+		name:    name,
+		size:    oi.GetSize(),
+		modTime: fromUnixNano(oi.GetModTimeUnixNano()),
+		isDir:   oi.GetIsDir(),
+	}
+}
+
+// zero times are sent as 0 rather than the (overflowing) nanoseconds of year 1
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
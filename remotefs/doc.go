@@ -0,0 +1,17 @@
+// Package remotefs serves a filesapi.FileStore over gRPC, and provides a Client that
+// itself implements FileStore, so an application can use stores hosted by another
+// process (i.e. a service holding the bucket credentials).
+//
+// The service is defined in remotepb/filestore.proto.  Object contents are streamed in
+// chunks by GetObject, PutObject, and Walk.  Multipart upload sessions are not part of
+// the service; PutObject streams objects of any size and the server stores large
+// objects with multipart uploads.
+//
+// The package is its own module so the core filesapi module does not depend on
+// google.golang.org/grpc.  The generated remotepb package is checked in; regenerate it
+// with protoc, protoc-gen-go, and protoc-gen-go-grpc after changing the service:
+//
+//	go generate ./...
+package remotefs
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative remotepb/filestore.proto
@@ -0,0 +1,102 @@
+package remotefs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/usace/filesapi"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var _ filesapi.FileStore = &Client{}
+
+// serves a block file store over an in memory connection
+func testClient(t *testing.T) *Client {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	Register(server, store)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return NewClient(conn)
+}
+
+func TestClient(t *testing.T) {
+	client := testClient(t)
+	dir := t.TempDir()
+	src := filepath.Join(dir, "a", "src.txt")
+	data := strings.Repeat("HELLO WORLD", 10000)
+
+	_, err := client.PutObject(filesapi.PutObjectInput{
+		Source: filesapi.ObjectSource{Reader: io.NopCloser(strings.NewReader(data))},
+		Dest:   filesapi.PathConfig{Path: src},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := client.GetObject(filesapi.GetObjectInput{Path: filesapi.PathConfig{Path: src}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil || string(got) != data {
+		t.Fatalf("unexpected object of %d bytes: %v", len(got), err)
+	}
+	info, err := client.GetObjectInfo(filesapi.PathConfig{Path: src})
+	if err != nil || info.Size() != int64(len(data)) || info.IsDir() {
+		t.Fatalf("unexpected object info %v: %v", info, err)
+	}
+
+	dest := filepath.Join(dir, "b", "dest.txt")
+	if err = client.CopyObject(filesapi.CopyObjectInput{Src: filesapi.PathConfig{Path: src}, Dest: filesapi.PathConfig{Path: dest}}); err != nil {
+		t.Fatal(err)
+	}
+	walked := []string{}
+	err = client.Walk(filesapi.WalkInput{Path: filesapi.PathConfig{Path: dir}}, func(path string, file os.FileInfo) error {
+		if !file.IsDir() {
+			walked = append(walked, strings.TrimPrefix(path, dir))
+		}
+		return nil
+	})
+	if err != nil || strings.Join(walked, ",") != "/a/src.txt,/b/dest.txt" {
+		t.Fatalf("unexpected walk %v: %v", walked, err)
+	}
+	listing, err := client.ListDir(filesapi.ListDirInput{Path: filesapi.PathConfig{Path: filepath.Join(dir, "b")}})
+	if err != nil || len(*listing) != 1 || (*listing)[0].Name != "dest.txt" {
+		t.Fatalf("unexpected listing %v: %v", listing, err)
+	}
+
+	if errs := client.DeleteObjects(filesapi.DeleteObjectInput{Paths: filesapi.PathConfig{Path: dest}}); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	_, err = client.GetObjectInfo(filesapi.PathConfig{Path: dest})
+	var notFound *filesapi.FileNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("expected a not found error for the deleted object, got %v", err)
+	}
+	if _, err = client.InitializeObjectUpload(filesapi.UploadConfig{ObjectPath: dest}); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expected multipart uploads to be unsupported, got %v", err)
+	}
+}
@@ -0,0 +1,496 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: remotepb/filestore.proto
+
+package remotepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	FileStore_ResourceName_FullMethodName  = "/filesapi.remote.v1.FileStore/ResourceName"
+	FileStore_GetObjectInfo_FullMethodName = "/filesapi.remote.v1.FileStore/GetObjectInfo"
+	FileStore_GetObject_FullMethodName     = "/filesapi.remote.v1.FileStore/GetObject"
+	FileStore_PutObject_FullMethodName     = "/filesapi.remote.v1.FileStore/PutObject"
+	FileStore_CopyObject_FullMethodName    = "/filesapi.remote.v1.FileStore/CopyObject"
+	FileStore_MoveObject_FullMethodName    = "/filesapi.remote.v1.FileStore/MoveObject"
+	FileStore_DeleteObjects_FullMethodName = "/filesapi.remote.v1.FileStore/DeleteObjects"
+	FileStore_ListDir_FullMethodName       = "/filesapi.remote.v1.FileStore/ListDir"
+	FileStore_Walk_FullMethodName          = "/filesapi.remote.v1.FileStore/Walk"
+)
+
+// FileStoreClient is the client API for FileStore service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type FileStoreClient interface {
+	ResourceName(ctx context.Context, in *ResourceNameRequest, opts ...grpc.CallOption) (*ResourceNameResponse, error)
+	GetObjectInfo(ctx context.Context, in *PathRequest, opts ...grpc.CallOption) (*ObjectInfo, error)
+	GetObject(ctx context.Context, in *GetObjectRequest, opts ...grpc.CallOption) (FileStore_GetObjectClient, error)
+	// the first message carries the header, and the following messages the object data
+	PutObject(ctx context.Context, opts ...grpc.CallOption) (FileStore_PutObjectClient, error)
+	CopyObject(ctx context.Context, in *CopyObjectRequest, opts ...grpc.CallOption) (*CopyObjectResponse, error)
+	MoveObject(ctx context.Context, in *MoveObjectRequest, opts ...grpc.CallOption) (*MoveObjectResponse, error)
+	DeleteObjects(ctx context.Context, in *DeleteObjectsRequest, opts ...grpc.CallOption) (*DeleteObjectsResponse, error)
+	ListDir(ctx context.Context, in *ListDirRequest, opts ...grpc.CallOption) (*ListDirResponse, error)
+	Walk(ctx context.Context, in *WalkRequest, opts ...grpc.CallOption) (FileStore_WalkClient, error)
+}
+
+type fileStoreClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewFileStoreClient(cc grpc.ClientConnInterface) FileStoreClient {
+	return &fileStoreClient{cc}
+}
+
+func (c *fileStoreClient) ResourceName(ctx context.Context, in *ResourceNameRequest, opts ...grpc.CallOption) (*ResourceNameResponse, error) {
+	out := new(ResourceNameResponse)
+	err := c.cc.Invoke(ctx, FileStore_ResourceName_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileStoreClient) GetObjectInfo(ctx context.Context, in *PathRequest, opts ...grpc.CallOption) (*ObjectInfo, error) {
+	out := new(ObjectInfo)
+	err := c.cc.Invoke(ctx, FileStore_GetObjectInfo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileStoreClient) GetObject(ctx context.Context, in *GetObjectRequest, opts ...grpc.CallOption) (FileStore_GetObjectClient, error) {
+	stream, err := c.cc.NewStream(ctx, &FileStore_ServiceDesc.Streams[0], FileStore_GetObject_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &fileStoreGetObjectClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type FileStore_GetObjectClient interface {
+	Recv() (*Chunk, error)
+	grpc.ClientStream
+}
+
+type fileStoreGetObjectClient struct {
+	grpc.ClientStream
+}
+
+func (x *fileStoreGetObjectClient) Recv() (*Chunk, error) {
+	m := new(Chunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *fileStoreClient) PutObject(ctx context.Context, opts ...grpc.CallOption) (FileStore_PutObjectClient, error) {
+	stream, err := c.cc.NewStream(ctx, &FileStore_ServiceDesc.Streams[1], FileStore_PutObject_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &fileStorePutObjectClient{stream}
+	return x, nil
+}
+
+type FileStore_PutObjectClient interface {
+	Send(*PutObjectRequest) error
+	CloseAndRecv() (*PutObjectResponse, error)
+	grpc.ClientStream
+}
+
+type fileStorePutObjectClient struct {
+	grpc.ClientStream
+}
+
+func (x *fileStorePutObjectClient) Send(m *PutObjectRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *fileStorePutObjectClient) CloseAndRecv() (*PutObjectResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(PutObjectResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *fileStoreClient) CopyObject(ctx context.Context, in *CopyObjectRequest, opts ...grpc.CallOption) (*CopyObjectResponse, error) {
+	out := new(CopyObjectResponse)
+	err := c.cc.Invoke(ctx, FileStore_CopyObject_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileStoreClient) MoveObject(ctx context.Context, in *MoveObjectRequest, opts ...grpc.CallOption) (*MoveObjectResponse, error) {
+	out := new(MoveObjectResponse)
+	err := c.cc.Invoke(ctx, FileStore_MoveObject_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileStoreClient) DeleteObjects(ctx context.Context, in *DeleteObjectsRequest, opts ...grpc.CallOption) (*DeleteObjectsResponse, error) {
+	out := new(DeleteObjectsResponse)
+	err := c.cc.Invoke(ctx, FileStore_DeleteObjects_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileStoreClient) ListDir(ctx context.Context, in *ListDirRequest, opts ...grpc.CallOption) (*ListDirResponse, error) {
+	out := new(ListDirResponse)
+	err := c.cc.Invoke(ctx, FileStore_ListDir_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *fileStoreClient) Walk(ctx context.Context, in *WalkRequest, opts ...grpc.CallOption) (FileStore_WalkClient, error) {
+	stream, err := c.cc.NewStream(ctx, &FileStore_ServiceDesc.Streams[2], FileStore_Walk_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &fileStoreWalkClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type FileStore_WalkClient interface {
+	Recv() (*ObjectInfo, error)
+	grpc.ClientStream
+}
+
+type fileStoreWalkClient struct {
+	grpc.ClientStream
+}
+
+func (x *fileStoreWalkClient) Recv() (*ObjectInfo, error) {
+	m := new(ObjectInfo)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// FileStoreServer is the server API for FileStore service.
+// All implementations must embed UnimplementedFileStoreServer
+// for forward compatibility
+type FileStoreServer interface {
+	ResourceName(context.Context, *ResourceNameRequest) (*ResourceNameResponse, error)
+	GetObjectInfo(context.Context, *PathRequest) (*ObjectInfo, error)
+	GetObject(*GetObjectRequest, FileStore_GetObjectServer) error
+	// the first message carries the header, and the following messages the object data
+	PutObject(FileStore_PutObjectServer) error
+	CopyObject(context.Context, *CopyObjectRequest) (*CopyObjectResponse, error)
+	MoveObject(context.Context, *MoveObjectRequest) (*MoveObjectResponse, error)
+	DeleteObjects(context.Context, *DeleteObjectsRequest) (*DeleteObjectsResponse, error)
+	ListDir(context.Context, *ListDirRequest) (*ListDirResponse, error)
+	Walk(*WalkRequest, FileStore_WalkServer) error
+	mustEmbedUnimplementedFileStoreServer()
+}
+
+// UnimplementedFileStoreServer must be embedded to have forward compatible implementations.
+type UnimplementedFileStoreServer struct {
+}
+
+func (UnimplementedFileStoreServer) ResourceName(context.Context, *ResourceNameRequest) (*ResourceNameResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResourceName not implemented")
+}
+func (UnimplementedFileStoreServer) GetObjectInfo(context.Context, *PathRequest) (*ObjectInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetObjectInfo not implemented")
+}
+func (UnimplementedFileStoreServer) GetObject(*GetObjectRequest, FileStore_GetObjectServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetObject not implemented")
+}
+func (UnimplementedFileStoreServer) PutObject(FileStore_PutObjectServer) error {
+	return status.Errorf(codes.Unimplemented, "method PutObject not implemented")
+}
+func (UnimplementedFileStoreServer) CopyObject(context.Context, *CopyObjectRequest) (*CopyObjectResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CopyObject not implemented")
+}
+func (UnimplementedFileStoreServer) MoveObject(context.Context, *MoveObjectRequest) (*MoveObjectResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MoveObject not implemented")
+}
+func (UnimplementedFileStoreServer) DeleteObjects(context.Context, *DeleteObjectsRequest) (*DeleteObjectsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteObjects not implemented")
+}
+func (UnimplementedFileStoreServer) ListDir(context.Context, *ListDirRequest) (*ListDirResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDir not implemented")
+}
+func (UnimplementedFileStoreServer) Walk(*WalkRequest, FileStore_WalkServer) error {
+	return status.Errorf(codes.Unimplemented, "method Walk not implemented")
+}
+func (UnimplementedFileStoreServer) mustEmbedUnimplementedFileStoreServer() {}
+
+// UnsafeFileStoreServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to FileStoreServer will
+// result in compilation errors.
+type UnsafeFileStoreServer interface {
+	mustEmbedUnimplementedFileStoreServer()
+}
+
+func RegisterFileStoreServer(s grpc.ServiceRegistrar, srv FileStoreServer) {
+	s.RegisterService(&FileStore_ServiceDesc, srv)
+}
+
+func _FileStore_ResourceName_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResourceNameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileStoreServer).ResourceName(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileStore_ResourceName_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileStoreServer).ResourceName(ctx, req.(*ResourceNameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileStore_GetObjectInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PathRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileStoreServer).GetObjectInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileStore_GetObjectInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileStoreServer).GetObjectInfo(ctx, req.(*PathRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileStore_GetObject_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetObjectRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FileStoreServer).GetObject(m, &fileStoreGetObjectServer{stream})
+}
+
+type FileStore_GetObjectServer interface {
+	Send(*Chunk) error
+	grpc.ServerStream
+}
+
+type fileStoreGetObjectServer struct {
+	grpc.ServerStream
+}
+
+func (x *fileStoreGetObjectServer) Send(m *Chunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _FileStore_PutObject_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(FileStoreServer).PutObject(&fileStorePutObjectServer{stream})
+}
+
+type FileStore_PutObjectServer interface {
+	SendAndClose(*PutObjectResponse) error
+	Recv() (*PutObjectRequest, error)
+	grpc.ServerStream
+}
+
+type fileStorePutObjectServer struct {
+	grpc.ServerStream
+}
+
+func (x *fileStorePutObjectServer) SendAndClose(m *PutObjectResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *fileStorePutObjectServer) Recv() (*PutObjectRequest, error) {
+	m := new(PutObjectRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _FileStore_CopyObject_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CopyObjectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileStoreServer).CopyObject(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileStore_CopyObject_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileStoreServer).CopyObject(ctx, req.(*CopyObjectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileStore_MoveObject_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MoveObjectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileStoreServer).MoveObject(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileStore_MoveObject_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileStoreServer).MoveObject(ctx, req.(*MoveObjectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileStore_DeleteObjects_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteObjectsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileStoreServer).DeleteObjects(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileStore_DeleteObjects_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileStoreServer).DeleteObjects(ctx, req.(*DeleteObjectsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileStore_ListDir_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDirRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileStoreServer).ListDir(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: FileStore_ListDir_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileStoreServer).ListDir(ctx, req.(*ListDirRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _FileStore_Walk_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WalkRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(FileStoreServer).Walk(m, &fileStoreWalkServer{stream})
+}
+
+type FileStore_WalkServer interface {
+	Send(*ObjectInfo) error
+	grpc.ServerStream
+}
+
+type fileStoreWalkServer struct {
+	grpc.ServerStream
+}
+
+func (x *fileStoreWalkServer) Send(m *ObjectInfo) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// FileStore_ServiceDesc is the grpc.ServiceDesc for FileStore service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var FileStore_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "filesapi.remote.v1.FileStore",
+	HandlerType: (*FileStoreServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ResourceName",
+			Handler:    _FileStore_ResourceName_Handler,
+		},
+		{
+			MethodName: "GetObjectInfo",
+			Handler:    _FileStore_GetObjectInfo_Handler,
+		},
+		{
+			MethodName: "CopyObject",
+			Handler:    _FileStore_CopyObject_Handler,
+		},
+		{
+			MethodName: "MoveObject",
+			Handler:    _FileStore_MoveObject_Handler,
+		},
+		{
+			MethodName: "DeleteObjects",
+			Handler:    _FileStore_DeleteObjects_Handler,
+		},
+		{
+			MethodName: "ListDir",
+			Handler:    _FileStore_ListDir_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetObject",
+			Handler:       _FileStore_GetObject_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "PutObject",
+			Handler:       _FileStore_PutObject_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Walk",
+			Handler:       _FileStore_Walk_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "remotepb/filestore.proto",
+}
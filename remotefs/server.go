@@ -0,0 +1,213 @@
+package remotefs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+
+	"github.com/usace/filesapi"
+	"github.com/usace/filesapi/remotefs/remotepb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// size of the data chunks streamed by the server and client
+const chunkSize int = 1024 * 1024
+
+// objects larger than this, or of unknown length, are stored with multipart uploads
+const multipartThreshold int64 = 64 * 1024 * 1024
+
+// Server implements the FileStore service over a store
+type Server struct {
+	remotepb.UnimplementedFileStoreServer
+	store filesapi.FileStore
+}
+
+func NewServer(store filesapi.FileStore) *Server {
+	return &Server{store: store}
+}
+
+// Register serves a store with a gRPC server
+func Register(s grpc.ServiceRegistrar, store filesapi.FileStore) {
+	remotepb.RegisterFileStoreServer(s, NewServer(store))
+}
+
+func (s *Server) ResourceName(ctx context.Context, req *remotepb.ResourceNameRequest) (*remotepb.ResourceNameResponse, error) {
+	return &remotepb.ResourceNameResponse{Name: s.store.ResourceName()}, nil
+}
+
+func (s *Server) GetObjectInfo(ctx context.Context, req *remotepb.PathRequest) (*remotepb.ObjectInfo, error) {
+	info, err := s.store.GetObjectInfo(filesapi.PathConfig{Path: req.GetPath()})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return objectInfo(req.GetPath(), info), nil
+}
+
+func (s *Server) GetObject(req *remotepb.GetObjectRequest, stream remotepb.FileStore_GetObjectServer) error {
+	reader, err := s.store.GetObject(filesapi.GetObjectInput{
+		Path:  filesapi.PathConfig{Path: req.GetPath()},
+		Range: req.GetRange(),
+	})
+	if err != nil {
+		return statusError(err)
+	}
+	defer reader.Close()
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(reader, buf)
+		if n > 0 {
+			if serr := stream.Send(&remotepb.Chunk{Data: buf[:n]}); serr != nil {
+				return serr
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return statusError(err)
+		}
+	}
+}
+
+func (s *Server) PutObject(stream remotepb.FileStore_PutObjectServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	header := first.GetHeader()
+	if header == nil {
+		return status.Error(codes.InvalidArgument, "put requires a header message")
+	}
+	input := filesapi.PutObjectInput{
+		Source:          filesapi.ObjectSource{Reader: &putReader{stream: stream}},
+		Dest:            filesapi.PathConfig{Path: header.GetPath()},
+		ContentType:     header.GetContentType(),
+		ContentEncoding: header.GetContentEncoding(),
+		CacheControl:    header.GetCacheControl(),
+		IfNoneMatch:     header.GetIfNoneMatch(),
+		Mutipart:        header.GetContentLength() < 0 || header.GetContentLength() > multipartThreshold,
+	}
+	if length := header.GetContentLength(); length >= 0 {
+		input.Source.ContentLength = &length
+	}
+	output, err := s.store.PutObject(input)
+	if err != nil {
+		return statusError(err)
+	}
+	return stream.SendAndClose(&remotepb.PutObjectResponse{Etag: output.ETag})
+}
+
+// reads the data messages of a put stream
+type putReader struct {
+	stream remotepb.FileStore_PutObjectServer
+	buf    []byte
+}
+
+func (pr *putReader) Read(p []byte) (int, error) {
+	for len(pr.buf) == 0 {
+		msg, err := pr.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		pr.buf = msg.GetData()
+	}
+	n := copy(p, pr.buf)
+	pr.buf = pr.buf[n:]
+	return n, nil
+}
+
+func (s *Server) CopyObject(ctx context.Context, req *remotepb.CopyObjectRequest) (*remotepb.CopyObjectResponse, error) {
+	err := s.store.CopyObject(filesapi.CopyObjectInput{
+		Src:  filesapi.PathConfig{Path: req.GetSrc()},
+		Dest: filesapi.PathConfig{Path: req.GetDest()},
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &remotepb.CopyObjectResponse{}, nil
+}
+
+func (s *Server) MoveObject(ctx context.Context, req *remotepb.MoveObjectRequest) (*remotepb.MoveObjectResponse, error) {
+	err := s.store.MoveObject(filesapi.MoveObjectInput{
+		Src:  filesapi.PathConfig{Path: req.GetSrc()},
+		Dest: filesapi.PathConfig{Path: req.GetDest()},
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	return &remotepb.MoveObjectResponse{}, nil
+}
+
+func (s *Server) DeleteObjects(ctx context.Context, req *remotepb.DeleteObjectsRequest) (*remotepb.DeleteObjectsResponse, error) {
+	resp := &remotepb.DeleteObjectsResponse{}
+	for _, err := range s.store.DeleteObjects(filesapi.DeleteObjectInput{Paths: filesapi.PathConfig{Paths: req.GetPaths()}}) {
+		if err != nil {
+			resp.Errors = append(resp.Errors, err.Error())
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) ListDir(ctx context.Context, req *remotepb.ListDirRequest) (*remotepb.ListDirResponse, error) {
+	results, err := s.store.ListDir(filesapi.ListDirInput{
+		Path:   filesapi.PathConfig{Path: req.GetPath()},
+		Page:   int(req.GetPage()),
+		Size:   req.GetSize(),
+		Filter: req.GetFilter(),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+	resp := &remotepb.ListDirResponse{Entries: make([]*remotepb.ListEntry, 0, len(*results))}
+	for _, r := range *results {
+		resp.Entries = append(resp.Entries, &remotepb.ListEntry{
+			Id:               int32(r.ID),
+			Name:             r.Name,
+			Size:             r.Size,
+			Path:             r.Path,
+			Type:             r.Type,
+			IsDir:            r.IsDir,
+			ModifiedUnixNano: unixNano(r.Modified),
+			ModifiedBy:       r.ModifiedBy,
+		})
+	}
+	return resp, nil
+}
+
+func (s *Server) Walk(req *remotepb.WalkRequest, stream remotepb.FileStore_WalkServer) error {
+	err := s.store.Walk(filesapi.WalkInput{
+		Path:       filesapi.PathConfig{Path: req.GetPath()},
+		StartAfter: req.GetStartAfter(),
+	}, func(path string, info fs.FileInfo) error {
+		return stream.Send(objectInfo(path, info))
+	})
+	return statusError(err)
+}
+
+// converts store errors to gRPC status errors the client converts back
+func statusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	var fnf *filesapi.FileNotFoundError
+	code := codes.Unknown
+	switch {
+	case errors.As(err, &fnf), errors.Is(err, fs.ErrNotExist):
+		code = codes.NotFound
+	case errors.Is(err, filesapi.ErrObjectExists):
+		code = codes.AlreadyExists
+	case errors.Is(err, filesapi.ErrReadOnlyStore), errors.Is(err, filesapi.ErrProtectedPath), errors.Is(err, fs.ErrPermission):
+		code = codes.PermissionDenied
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	}
+	return status.Error(code, err.Error())
+}
@@ -0,0 +1,272 @@
+package filesapi
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	defaultRestoreDays         int32         = 7
+	defaultRestoreBatchSize    int           = 100
+	defaultRestorePollInterval time.Duration = 15 * time.Minute
+)
+
+// ArchiveStatus is the restore state of an archived (i.e. Glacier class) object
+type ArchiveStatus struct {
+
+	//a restore request has been made and has not completed
+	InProgress bool
+
+	//a temporary copy of the object is available for reads
+	Restored bool
+}
+
+// ArchiveRestorer is implemented by stores that support restoring objects from archive storage
+type ArchiveRestorer interface {
+	RestoreObject(path PathConfig, days int32, tier string) error
+	RestoreStatus(path PathConfig) (ArchiveStatus, error)
+}
+
+// RestoreObject requests a temporary copy of an archived object for the given number of days.
+// Tier is one of Standard, Bulk, or Expedited.  Requests for objects that are already being
+// restored or are not archived are not errors
+func (s3fs *S3FS) RestoreObject(path PathConfig, days int32, tier string) error {
+	client, err := s3fs.client()
+	if err != nil {
+		return err
+	}
+	s3Path := strings.TrimPrefix(path.Path, "/")
+	if tier == "" {
+		tier = string(types.TierStandard)
+	}
+	_, err = client.RestoreObject(context.TODO(), &s3.RestoreObjectInput{
+		Bucket: &s3fs.config.S3Bucket,
+		Key:    &s3Path,
+		RestoreRequest: &types.RestoreRequest{
+			Days: &days,
+			GlacierJobParameters: &types.GlacierJobParameters{
+				Tier: types.Tier(tier),
+			},
+		},
+	})
+	var activeTier *types.ObjectAlreadyInActiveTierError
+	var apiErr smithy.APIError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &activeTier):
+		return nil
+	case errors.As(err, &apiErr) && apiErr.ErrorCode() == "RestoreAlreadyInProgress":
+		return nil
+	case errors.As(err, &noSuchKey):
+		return &FileNotFoundError{path.Path}
+	}
+	return err
+}
+
+// RestoreStatus reports the restore state of an object from its x-amz-restore header.
+// Objects that are not archived are reported as restored
+func (s3fs *S3FS) RestoreStatus(path PathConfig) (ArchiveStatus, error) {
+	client, err := s3fs.client()
+	if err != nil {
+		return ArchiveStatus{}, err
+	}
+	s3Path := strings.TrimPrefix(path.Path, "/")
+	output, err := client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+		Bucket: &s3fs.config.S3Bucket,
+		Key:    &s3Path,
+	})
+	if err != nil {
+		return ArchiveStatus{}, err
+	}
+	switch output.StorageClass {
+	case types.StorageClassGlacier, types.StorageClassDeepArchive:
+	default:
+		return ArchiveStatus{Restored: true}, nil
+	}
+	if output.Restore == nil {
+		return ArchiveStatus{}, nil
+	}
+	if strings.Contains(*output.Restore, `ongoing-request="true"`) {
+		return ArchiveStatus{InProgress: true}, nil
+	}
+	return ArchiveStatus{Restored: true}, nil
+}
+
+type ArchiveRestoreInput struct {
+
+	//store containing the archived objects.  Must implement ArchiveRestorer
+	Store FileStore
+
+	//paths of the archived objects to restore
+	Manifest []string
+
+	//number of days restored copies remain available.  Defaults to defaultRestoreDays
+	Days int32
+
+	//retrieval tier: Standard, Bulk, or Expedited.  Defaults to Standard
+	Tier string
+
+	//number of restore requests issued before checking for completed restores.
+	//Defaults to defaultRestoreBatchSize
+	BatchSize int
+
+	//interval between restore status checks.  Defaults to defaultRestorePollInterval
+	PollInterval time.Duration
+
+	//optional prefix in Store that restored objects are copied into.
+	//copies are written with the default storage class so they remain readable
+	//after the restore expires
+	DestPrefix string
+
+	//optional local directory that restored objects are downloaded into
+	LocalDir string
+
+	//optional progress function.  Value is the path of the completed object
+	Progress ProgressFunction
+}
+
+type ArchiveRestoreOutput struct {
+
+	//paths that were restored and copied or downloaded
+	Completed []string
+
+	//paths that failed with their error
+	Failed map[string]error
+}
+
+// ReadManifest reads a newline delimited list of object paths.  Blank lines are ignored
+func ReadManifest(r io.Reader) ([]string, error) {
+	paths := []string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			paths = append(paths, line)
+		}
+	}
+	return paths, scanner.Err()
+}
+
+// RestoreArchive coordinates an archive retrieval.  Restore requests for the manifest
+// are issued in batches, restores are polled until they complete, then each restored
+// object is copied to DestPrefix and/or downloaded to LocalDir.
+// Per object failures are reported in the output; the returned error is reserved for
+// configuration errors and context cancellation
+func RestoreArchive(ctx context.Context, input ArchiveRestoreInput) (*ArchiveRestoreOutput, error) {
+	restorer, ok := input.Store.(ArchiveRestorer)
+	if !ok {
+		return nil, errors.New("store does not support archive restores")
+	}
+	days := input.Days
+	if days <= 0 {
+		days = defaultRestoreDays
+	}
+	batchSize := input.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultRestoreBatchSize
+	}
+	pollInterval := input.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultRestorePollInterval
+	}
+
+	output := &ArchiveRestoreOutput{Completed: []string{}, Failed: map[string]error{}}
+	pending := []string{}
+	for start := 0; start < len(input.Manifest); start += batchSize {
+		end := start + batchSize
+		if end > len(input.Manifest) {
+			end = len(input.Manifest)
+		}
+		for _, path := range input.Manifest[start:end] {
+			if err := restorer.RestoreObject(PathConfig{Path: path}, days, input.Tier); err != nil {
+				output.Failed[path] = err
+				continue
+			}
+			pending = append(pending, path)
+		}
+		//retrieve anything that completed while the remaining batches are requested
+		var err error
+		if pending, err = retrieveRestored(ctx, restorer, pending, input, output); err != nil {
+			return output, err
+		}
+	}
+
+	for len(pending) > 0 {
+		timer := time.NewTimer(pollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return output, ctx.Err()
+		case <-timer.C:
+		}
+		var err error
+		if pending, err = retrieveRestored(ctx, restorer, pending, input, output); err != nil {
+			return output, err
+		}
+	}
+	return output, nil
+}
+
+// copies or downloads every restored path and returns the paths still being restored
+func retrieveRestored(ctx context.Context, restorer ArchiveRestorer, pending []string, input ArchiveRestoreInput, output *ArchiveRestoreOutput) ([]string, error) {
+	remaining := []string{}
+	for i, path := range pending {
+		if err := ctx.Err(); err != nil {
+			return append(remaining, pending[i:]...), err
+		}
+		status, err := restorer.RestoreStatus(PathConfig{Path: path})
+		if err != nil {
+			output.Failed[path] = err
+			continue
+		}
+		if !status.Restored {
+			remaining = append(remaining, path)
+			continue
+		}
+		if err = retrieveObject(input, path); err != nil {
+			output.Failed[path] = err
+			continue
+		}
+		output.Completed = append(output.Completed, path)
+		err = reportProgress(input.Progress, ProgressData{
+			Index: len(output.Completed) - 1,
+			Max:   len(input.Manifest),
+			Value: path,
+		})
+		if err != nil {
+			return remaining, err
+		}
+	}
+	return remaining, nil
+}
+
+func retrieveObject(input ArchiveRestoreInput, path string) error {
+	rel := strings.TrimPrefix(path, "/")
+	if input.DestPrefix != "" {
+		err := input.Store.CopyObject(CopyObjectInput{
+			Src:  PathConfig{Path: path},
+			Dest: PathConfig{Path: strings.TrimSuffix(input.DestPrefix, "/") + "/" + rel},
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to copy restored object: %w", err)
+		}
+	}
+	if input.LocalDir != "" {
+		_, err := DownloadObject(input.Store, PathConfig{Path: path}, filepath.Join(input.LocalDir, filepath.FromSlash(rel)), DownloadOptions{Resume: true})
+		if err != nil {
+			return fmt.Errorf("Failed to download restored object: %w", err)
+		}
+	}
+	return nil
+}
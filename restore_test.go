@@ -0,0 +1,74 @@
+package filesapi
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+// archiveFS simulates archive storage over a BlockFS.  Each object
+// is restored after a fixed number of status checks
+type archiveFS struct {
+	*BlockFS
+	checks   map[string]int
+	requests map[string]int
+}
+
+func (a *archiveFS) RestoreObject(path PathConfig, days int32, tier string) error {
+	a.requests[path.Path]++
+	return nil
+}
+
+func (a *archiveFS) RestoreStatus(path PathConfig) (ArchiveStatus, error) {
+	a.checks[path.Path]++
+	if a.checks[path.Path] < 2 {
+		return ArchiveStatus{InProgress: true}, nil
+	}
+	return ArchiveStatus{Restored: true}, nil
+}
+
+func TestRestoreArchive(t *testing.T) {
+	dir := t.TempDir()
+	store := &archiveFS{
+		BlockFS:  &BlockFS{Config: BlockFSConfig{ChunkSize: 100}},
+		checks:   map[string]int{},
+		requests: map[string]int{},
+	}
+	manifest, err := ReadManifest(strings.NewReader(dir + "/archive/a.dss\n\n" + dir + "/archive/b.dss\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range manifest {
+		_, err := store.PutObject(PutObjectInput{
+			Source: ObjectSource{Data: []byte(testObjectString)},
+			Dest:   PathConfig{Path: path},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	output, err := RestoreArchive(context.Background(), ArchiveRestoreInput{
+		Store:        store,
+		Manifest:     append(manifest, dir+"/archive/missing.dss"),
+		BatchSize:    1,
+		PollInterval: 1,
+		LocalDir:     dir + "/local",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(output.Completed) != 2 || len(output.Failed) != 1 {
+		t.Fatalf("expected two restored objects and one failure, got %+v", output)
+	}
+	data, err := os.ReadFile(dir + "/local" + manifest[0])
+	if err != nil || string(data) != testObjectString {
+		t.Fatalf("restored object was not downloaded: %s", err)
+	}
+	for _, path := range manifest {
+		if store.requests[path] != 1 {
+			t.Fatalf("expected a single restore request for %s", path)
+		}
+	}
+}
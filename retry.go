@@ -0,0 +1,140 @@
+package filesapi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+)
+
+// RetryPolicy is an untyped Retryer used to share retry settings across
+// Retryers of any type.  See NewRetryer
+type RetryPolicy = Retryer[any]
+
+var (
+	//retries transient AWS errors (throttling, 5xx, connection resets) with the AWS standard backoff
+	S3Standard = RetryPolicy{MaxAttempts: 3, MaxBackoff: 20, R: 2, Retryable: IsRetryableError}
+
+	//retries all errors except not-found and cancellation errors
+	Aggressive = RetryPolicy{MaxAttempts: 10, MaxBackoff: 60, R: 2, Retryable: isNonTerminalError}
+
+	//sends a request once
+	NoRetry = RetryPolicy{MaxAttempts: 0}
+)
+
+type Retryer[T any] struct {
+
+	//Max retry attempts
+	MaxAttempts int
+
+	//Max backoff in seconds
+	MaxBackoff float64
+
+	//base value for exponential backoff (usually 2)
+	//https://docs.aws.amazon.com/sdkref/latest/guide/feature-retry-behavior.html
+	R float64
+
+	//optional classifier for errors that should be retried.
+	//if nil, all errors are retried
+	Retryable func(err error) bool
+
+	//optional callback invoked after a failed attempt, before sleeping for the next one
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// Creates a Retryer from a RetryPolicy
+func NewRetryer[T any](policy RetryPolicy) Retryer[T] {
+	return Retryer[T]{
+		MaxAttempts: policy.MaxAttempts,
+		MaxBackoff:  policy.MaxBackoff,
+		R:           policy.R,
+		Retryable:   policy.Retryable,
+		OnRetry:     policy.OnRetry,
+	}
+}
+
+// Send function for platform agnostic retry with exponential backoff and jitter
+// based on : https://docs.aws.amazon.com/sdkref/latest/guide/feature-retry-behavior.html
+func (r Retryer[T]) Send(sendFunction func() (T, error)) (T, error) {
+	return r.SendWithContext(context.Background(), func(ctx context.Context) (T, error) {
+		return sendFunction()
+	})
+}
+
+// SendWithContext retries sendFunction until it succeeds, returns a non-retryable error,
+// exhausts MaxAttempts, or ctx is done.  The context is passed through to each attempt.
+func (r Retryer[T]) SendWithContext(ctx context.Context, sendFunction func(ctx context.Context) (T, error)) (T, error) {
+	attempts := 0
+	for {
+		t, err := sendFunction(ctx)
+		if err == nil || attempts >= r.MaxAttempts {
+			return t, err
+		}
+		var nre *nonRetryableError
+		if errors.As(err, &nre) || (r.Retryable != nil && !r.Retryable(err)) {
+			return t, err
+		}
+		secondsToSleep := math.Min(jitter()*math.Pow(r.R, float64(attempts)), r.MaxBackoff)
+		delay := time.Duration(secondsToSleep * float64(time.Second))
+		attempts++
+		if r.OnRetry != nil {
+			r.OnRetry(attempts, err, delay)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return t, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// nonRetryableError marks an error that a Retryer must not retry
+// regardless of the retry policy
+type nonRetryableError struct {
+	err error
+}
+
+func (e *nonRetryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *nonRetryableError) Unwrap() error {
+	return e.err
+}
+
+// IsRetryableError reports whether the AWS SDK classifies err as transient
+// (throttling, 5xx responses, connection and timeout errors)
+func IsRetryableError(err error) bool {
+	if !isNonTerminalError(err) {
+		return false
+	}
+	return retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary
+}
+
+func isNonTerminalError(err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case errors.Is(err, ErrOperationAborted), errors.As(err, &fileNotFoundError):
+		return false
+	}
+	return true
+}
+
+// returns a crypto random float in [0,1)
+func jitter() float64 {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return 1
+	}
+	return float64(binary.BigEndian.Uint64(b[:])>>11) / (1 << 53)
+}
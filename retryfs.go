@@ -0,0 +1,158 @@
+package filesapi
+
+import (
+	"io"
+	"io/fs"
+	"os"
+)
+
+// RetryFS is a FileStore decorator that retries failed calls on the wrapped store
+// using a RetryPolicy.  Retries are idempotency aware:
+//   - reads, listings, copies, and deletes are retried freely
+//   - moves are retried, and a retry finding the object already moved by an earlier attempt succeeds
+//   - puts and chunk writes are only retried when the source can be replayed
+//   - upload initialization is never retried since each attempt creates a new upload session
+//   - walks resume from the last visited object rather than starting over
+type RetryFS struct {
+	FileStore
+	Policy RetryPolicy
+}
+
+func NewRetryFS(store FileStore, policy RetryPolicy) *RetryFS {
+	return &RetryFS{
+		FileStore: store,
+		Policy:    policy,
+	}
+}
+
+// returns the per call retry policy override if there is one
+func (rfs *RetryFS) policy(options *CallOptions) RetryPolicy {
+	if options != nil && options.RetryPolicy != nil {
+		return *options.RetryPolicy
+	}
+	return rfs.Policy
+}
+
+func (rfs *RetryFS) ListDir(input ListDirInput) (*[]FileStoreResultObject, error) {
+	return NewRetryer[*[]FileStoreResultObject](rfs.policy(input.Options)).Send(func() (*[]FileStoreResultObject, error) {
+		return rfs.FileStore.ListDir(input)
+	})
+}
+
+func (rfs *RetryFS) GetDir(path PathConfig) (*[]FileStoreResultObject, error) {
+	return NewRetryer[*[]FileStoreResultObject](rfs.Policy).Send(func() (*[]FileStoreResultObject, error) {
+		return rfs.FileStore.GetDir(path)
+	})
+}
+
+func (rfs *RetryFS) GetObjectInfo(path PathConfig) (fs.FileInfo, error) {
+	return NewRetryer[fs.FileInfo](rfs.Policy).Send(func() (fs.FileInfo, error) {
+		return rfs.FileStore.GetObjectInfo(path)
+	})
+}
+
+func (rfs *RetryFS) GetObject(input GetObjectInput) (io.ReadCloser, error) {
+	return NewRetryer[io.ReadCloser](rfs.policy(input.Options)).Send(func() (io.ReadCloser, error) {
+		return rfs.FileStore.GetObject(input)
+	})
+}
+
+func (rfs *RetryFS) PutObject(input PutObjectInput) (*FileOperationOutput, error) {
+	if !input.Source.Replayable() {
+		return rfs.FileStore.PutObject(input)
+	}
+	return NewRetryer[*FileOperationOutput](rfs.policy(input.Options)).Send(func() (*FileOperationOutput, error) {
+		return rfs.FileStore.PutObject(input)
+	})
+}
+
+func (rfs *RetryFS) CopyObject(input CopyObjectInput) error {
+	_, err := NewRetryer[any](rfs.Policy).Send(func() (any, error) {
+		return nil, rfs.FileStore.CopyObject(input)
+	})
+	return err
+}
+
+func (rfs *RetryFS) MoveObject(input MoveObjectInput) error {
+	attempt := 0
+	_, err := NewRetryer[any](rfs.policy(input.Options)).Send(func() (any, error) {
+		attempt++
+		err := rfs.FileStore.MoveObject(input)
+		if err != nil && attempt > 1 && rfs.moved(input) {
+			return nil, nil
+		}
+		return nil, err
+	})
+	return err
+}
+
+// true when every source is missing and every destination exists
+func (rfs *RetryFS) moved(input MoveObjectInput) bool {
+	for _, src := range input.Src.All() {
+		if exists, err := ObjectExists(rfs.FileStore, src); err != nil || exists {
+			return false
+		}
+	}
+	for _, dest := range input.Dest.All() {
+		if exists, err := ObjectExists(rfs.FileStore, dest); err != nil || !exists {
+			return false
+		}
+	}
+	return true
+}
+
+func (rfs *RetryFS) WriteChunk(u UploadConfig) (UploadResult, error) {
+	return NewRetryer[UploadResult](rfs.Policy).Send(func() (UploadResult, error) {
+		return rfs.FileStore.WriteChunk(u)
+	})
+}
+
+func (rfs *RetryFS) CompleteObjectUpload(u CompletedObjectUploadConfig) error {
+	_, err := NewRetryer[any](rfs.Policy).Send(func() (any, error) {
+		return nil, rfs.FileStore.CompleteObjectUpload(u)
+	})
+	return err
+}
+
+// Deletes are retried while any path fails to delete.
+// Errors from the final attempt are returned
+func (rfs *RetryFS) DeleteObjects(input DeleteObjectInput) []error {
+	errs, _ := NewRetryer[[]error](rfs.Policy).Send(func() ([]error, error) {
+		errs := rfs.FileStore.DeleteObjects(input)
+		return errs, firstError(errs)
+	})
+	return errs
+}
+
+// Walks are resumed from the last object handed to the visitor function.  The last object
+// may be a BlockFS directory, in which case the resumed walk descends into it.
+// Errors returned by the visitor function are not retried
+func (rfs *RetryFS) Walk(input WalkInput, vistorFunction FileVisitFunction) error {
+	var visitErr error
+	_, err := NewRetryer[any](rfs.Policy).Send(func() (any, error) {
+		err := rfs.FileStore.Walk(input, func(path string, file os.FileInfo) error {
+			visitErr = vistorFunction(path, file)
+			if visitErr == nil {
+				input.StartAfter = path
+			}
+			return visitErr
+		})
+		if visitErr != nil {
+			return nil, &nonRetryableError{visitErr}
+		}
+		return nil, err
+	})
+	if visitErr != nil {
+		return visitErr
+	}
+	return err
+}
+
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
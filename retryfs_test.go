@@ -0,0 +1,171 @@
+package filesapi
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var errFlaky error = errors.New("flaky store error")
+
+// flakyFS fails the first failures calls to GetObjectInfo and PutObject
+type flakyFS struct {
+	BlockFS
+	failures int
+	calls    int
+}
+
+func (f *flakyFS) GetObjectInfo(path PathConfig) (fs.FileInfo, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, errFlaky
+	}
+	return f.BlockFS.GetObjectInfo(path)
+}
+
+func (f *flakyFS) PutObject(poi PutObjectInput) (*FileOperationOutput, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		//consume the source the way a failed upload would
+		if reader, err := poi.Source.GetReader(); err == nil {
+			io.Copy(io.Discard, reader)
+			poi.Source.closeReader(reader)
+		}
+		return nil, errFlaky
+	}
+	return f.BlockFS.PutObject(poi)
+}
+
+func TestRetryFSRetriesReads(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.txt")
+	if err := os.WriteFile(path, []byte(testObjectString), 0644); err != nil {
+		t.Fatal(err)
+	}
+	flaky := &flakyFS{failures: 2}
+	rfs := NewRetryFS(flaky, RetryPolicy{MaxAttempts: 3, MaxBackoff: 0.01, R: 2})
+	info, err := rfs.GetObjectInfo(PathConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size() != int64(len(testObjectString)) || flaky.calls != 3 {
+		t.Fatalf("expected success after 3 calls, got %d calls", flaky.calls)
+	}
+}
+
+func TestRetryFSDoesNotReplayReaders(t *testing.T) {
+	dir := t.TempDir()
+	flaky := &flakyFS{failures: 1}
+	rfs := NewRetryFS(flaky, RetryPolicy{MaxAttempts: 3, MaxBackoff: 0.01, R: 2})
+	_, err := rfs.PutObject(PutObjectInput{
+		Source: ObjectSource{Reader: strings.NewReader(testObjectString)},
+		Dest:   PathConfig{Path: filepath.Join(dir, "reader.txt")},
+	})
+	if !errors.Is(err, errFlaky) || flaky.calls != 1 {
+		t.Fatalf("expected a single failed put for a reader source, got %d calls: %v", flaky.calls, err)
+	}
+
+	flaky.calls = 0
+	_, err = rfs.PutObject(PutObjectInput{
+		Source: ObjectSource{Data: []byte(testObjectString)},
+		Dest:   PathConfig{Path: filepath.Join(dir, "data.txt")},
+	})
+	if err != nil || flaky.calls != 2 {
+		t.Fatalf("expected a retried put for a byte slice source, got %d calls: %v", flaky.calls, err)
+	}
+}
+
+func TestRetryFSReplaysOpenSource(t *testing.T) {
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "open.txt")
+	flaky := &flakyFS{failures: 2}
+	rfs := NewRetryFS(flaky, RetryPolicy{MaxAttempts: 3, MaxBackoff: 0.01, R: 2})
+	opens := 0
+	_, err := rfs.PutObject(PutObjectInput{
+		Source: ObjectSource{Open: func() (io.ReadCloser, error) {
+			opens++
+			return io.NopCloser(strings.NewReader(testObjectString)), nil
+		}},
+		Dest: PathConfig{Path: dest},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != testObjectString || opens != 3 {
+		t.Fatalf("expected %s from 3 opens, got %s from %d opens", testObjectString, data, opens)
+	}
+}
+
+func TestRetryFSCallOptions(t *testing.T) {
+	dir := t.TempDir()
+	flaky := &flakyFS{failures: 1}
+	rfs := NewRetryFS(flaky, RetryPolicy{MaxAttempts: 3, MaxBackoff: 0.01, R: 2})
+	input := PutObjectInput{
+		Source:  ObjectSource{Data: []byte(testObjectString)},
+		Dest:    PathConfig{Path: filepath.Join(dir, "interactive.txt")},
+		Options: &CallOptions{RetryPolicy: &NoRetry},
+	}
+	if _, err := rfs.PutObject(input); !errors.Is(err, errFlaky) || flaky.calls != 1 {
+		t.Fatalf("expected the per call policy to disable retries, got %v after %d calls", err, flaky.calls)
+	}
+	input.Options = nil
+	if _, err := rfs.PutObject(input); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// flakyWalkFS fails a walk once, right after failAfter is visited
+type flakyWalkFS struct {
+	BlockFS
+	failAfter string
+	failed    bool
+}
+
+func (f *flakyWalkFS) Walk(input WalkInput, visitor FileVisitFunction) error {
+	return f.BlockFS.Walk(input, func(path string, file os.FileInfo) error {
+		if err := visitor(path, file); err != nil {
+			return err
+		}
+		if path == f.failAfter && !f.failed {
+			f.failed = true
+			return errFlaky
+		}
+		return nil
+	})
+}
+
+func TestRetryFSResumesWalk(t *testing.T) {
+	dir := t.TempDir()
+	for _, p := range []string{"a/1.txt", "a/2.txt", "b.txt"} {
+		fp := filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(fp), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fp, []byte(testObjectString), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	flaky := &flakyWalkFS{failAfter: filepath.Join(dir, "a")}
+	rfs := NewRetryFS(flaky, RetryPolicy{MaxAttempts: 3, MaxBackoff: 0.01, R: 2})
+	visited := []string{}
+	err := rfs.Walk(WalkInput{Path: PathConfig{Path: dir}}, func(path string, file os.FileInfo) error {
+		visited = append(visited, strings.TrimPrefix(path, dir))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//the retried walk resumes inside the directory it failed after
+	expected := []string{"", "/a", "/a/1.txt", "/a/2.txt", "/b.txt"}
+	if !flaky.failed || strings.Join(visited, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected the resumed walk to visit %v once each, got %v", expected, visited)
+	}
+}
@@ -0,0 +1,167 @@
+package filesapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// the S3 client of a store, shared by the stores derived from it with Clone and With.
+// Lazily connected stores create the client on first use.  A failed connection is not
+// cached, so a backend that is temporarily unreachable is retried by the next request
+type s3Connection struct {
+	mu      sync.Mutex
+	client  *s3.Client
+	connect func(ctx context.Context) (*s3.Client, error)
+}
+
+func (c *s3Connection) get(ctx context.Context) (*s3.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != nil {
+		return c.client, nil
+	}
+	client, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.client = client
+	return client, nil
+}
+
+// Connect resolves the store credentials and endpoint and creates the S3 client.
+// Stores configured with LazyConnect connect on first use; Connect forces the connection
+// so applications can check a backend is reachable.  Connecting a connected store does nothing
+func (s3fs *S3FS) Connect() error {
+	_, err := s3fs.client()
+	return err
+}
+
+func (s3fs *S3FS) client() (*s3.Client, error) {
+	if s3fs.conn == nil {
+		return nil, errors.New("S3 store has no connection")
+	}
+	return s3fs.conn.get(context.TODO())
+}
+
+// creates the S3 client for an S3 configuration.  Credential chains and region detection
+// make requests, so this is deferred until first use when LazyConnect is set
+func (sc *S3FSConfig) newClient(ctx context.Context) (*s3.Client, error) {
+	loadOptions := []func(*config.LoadOptions) error{}
+	if sc.AwsOptions != nil {
+		loadOptions = append(loadOptions, sc.AwsOptions...)
+	}
+	if sc.S3Region == "" && sc.AutoDetectRegion {
+		sc.S3Region = defaultDetectionRegion
+	}
+	loadOptions = append(loadOptions, config.WithRegion(sc.S3Region))
+	clientLoadOptions, s3Options := sc.ClientOptions.options(sc.ComplianceMode)
+	loadOptions = append(loadOptions, clientLoadOptions...)
+	/////AWS RETRY OPTION
+	/*
+		loadOptions = append(loadOptions, config.WithRetryer(func() aws.Retryer {
+			return retry.AddWithMaxBackoffDelay(retry.NewStandard(), time.Second*5)
+		}))
+	*/
+	////
+	switch cred := sc.Credentials.(type) {
+	case S3FS_Static:
+		loadOptions = append(loadOptions, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(cred.S3Id, cred.S3Key, ""),
+		))
+	case S3FS_Attached:
+		//if attached credentials are used and cred.Profile=="", then the AWS default credential chain is invoked
+		//otherwise add the profile.
+		if cred.Profile != "" {
+			loadOptions = append(loadOptions, config.WithSharedConfigProfile(cred.Profile))
+		}
+	case S3FS_Role:
+		return nil, configError("Credentials", "assumed role credentials are not supported")
+	case S3FS_Anonymous:
+		loadOptions = append(loadOptions, config.WithCredentialsProvider(aws.AnonymousCredentials{}))
+	case S3FS_CredentialChain:
+		chain, err := newChainCredentialsProvider(ctx, cred, sc.S3Region)
+		if err != nil {
+			return nil, err
+		}
+		loadOptions = append(loadOptions, config.WithCredentialsProvider(chain.cache))
+		s3Options = append(s3Options, func(o *s3.Options) {
+			o.APIOptions = append(o.APIOptions, chain.failoverMiddleware)
+		})
+
+	default:
+		return nil, configError("Credentials", "has unsupported credential type %T", sc.Credentials)
+	}
+
+	cfg, err := config.LoadDefaultConfig(
+		ctx,
+		loadOptions...,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s3Client := s3.NewFromConfig(cfg, s3Options...)
+	if sc.AutoDetectRegion {
+		region, err := manager.GetBucketRegion(ctx, s3Client, sc.S3Bucket)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to detect the region of bucket %s: %w", sc.S3Bucket, err)
+		}
+		if sc.ComplianceMode && !fipsRegions[region] {
+			return nil, fmt.Errorf("%w: bucket region %s has no FIPS endpoint", ErrComplianceUnsupported, region)
+		}
+		if region != cfg.Region {
+			sc.S3Region = region
+			s3Options = append(s3Options, func(o *s3.Options) { o.Region = region })
+			s3Client = s3.NewFromConfig(cfg, s3Options...)
+		}
+	}
+	return s3Client, nil
+}
+
+// creates the S3 client for a Minio configuration
+func (mc *MinioFSConfig) newClient(ctx context.Context) (*s3.Client, error) {
+	loadOptions := []func(*config.LoadOptions) error{}
+	if mc.AwsOptions != nil {
+		loadOptions = append(loadOptions, mc.AwsOptions...)
+	}
+	loadOptions = append(loadOptions, config.WithRegion(mc.S3Region))
+	clientLoadOptions, s3Options := mc.ClientOptions.options(mc.ComplianceMode)
+	loadOptions = append(loadOptions, clientLoadOptions...)
+
+	resolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...any) (aws.Endpoint, error) {
+		return aws.Endpoint{
+			PartitionID:       "aws",
+			URL:               mc.HostAddress,
+			SigningRegion:     mc.S3Region,
+			HostnameImmutable: true,
+		}, nil
+	})
+
+	var creds S3FS_Static
+	var ok bool
+	if creds, ok = mc.Credentials.(S3FS_Static); !ok {
+		return nil, configError("Credentials", "Minio stores require static credentials, got %T", mc.Credentials)
+	}
+
+	loadOptions = append(
+		loadOptions, config.WithRegion(mc.S3Region),
+		config.WithEndpointResolverWithOptions(resolver),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(creds.S3Id, creds.S3Key, "")),
+	)
+
+	cfg, err := config.LoadDefaultConfig(ctx, loadOptions...)
+	if err != nil {
+		return nil, err
+	}
+	s3Client := s3.NewFromConfig(cfg, s3Options...)
+	return s3Client, nil
+}
@@ -0,0 +1,99 @@
+package filesapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestLazyConnect(t *testing.T) {
+	//no credentials in the chain are usable, so connecting fails
+	unreachable := S3FSConfig{
+		S3Region:    "us-east-1",
+		S3Bucket:    "models",
+		Credentials: S3FS_CredentialChain{S3FS_Attached{Profile: "filesapi-missing-profile"}},
+		LazyConnect: true,
+	}
+	store, err := NewFileStore(unreachable)
+	if err != nil {
+		t.Fatalf("expected a lazy store to be constructed without connecting, got %v", err)
+	}
+	if err := store.(*S3FS).Connect(); err == nil {
+		t.Fatal("expected Connect to fail")
+	}
+	if _, err := store.GetObject(GetObjectInput{Path: PathConfig{Path: "/a.txt"}}); err == nil {
+		t.Fatal("expected GetObject to fail when the store cannot connect")
+	}
+	unreachable.LazyConnect = false
+	if _, err := NewFileStore(unreachable); err == nil {
+		t.Fatal("expected an eager store to fail at construction")
+	}
+
+	//invalid configurations are rejected even when the connection is deferred
+	invalid := unreachable
+	invalid.LazyConnect = true
+	invalid.S3Bucket = ""
+	if _, err := NewFileStore(invalid); !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("expected an invalid config error, got %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data"))
+	}))
+	defer server.Close()
+	store, err = NewFileStore(MinioFSConfig{
+		S3FSConfig: S3FSConfig{
+			S3Region:    "us-east-1",
+			S3Bucket:    "bucket",
+			Credentials: S3FS_Static{S3Id: "id", S3Key: "key"},
+			LazyConnect: true,
+		},
+		HostAddress: server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s3fs := store.(*S3FS)
+	clone := s3fs.Clone(S3FSOptions{MaxKeys: 10})
+	if s3fs.conn.client != nil {
+		t.Fatal("expected the client to be created on first use")
+	}
+	reader, err := clone.GetObject(GetObjectInput{Path: PathConfig{Path: "/a.txt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if data, _ := io.ReadAll(reader); string(data) != "data" {
+		t.Fatalf("unexpected object content %q", data)
+	}
+	if s3fs.GetClient() != clone.GetClient() || s3fs.GetClient() == nil {
+		t.Fatal("expected derived stores to share the connection")
+	}
+}
+
+func TestConnectionRetry(t *testing.T) {
+	attempts := 0
+	conn := &s3Connection{connect: func(ctx context.Context) (*s3.Client, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, errors.New("unreachable")
+		}
+		return s3.New(s3.Options{Region: "us-east-1"}), nil
+	}}
+	if _, err := conn.get(context.Background()); err == nil {
+		t.Fatal("expected the first connection to fail")
+	}
+	first, err := conn.get(context.Background())
+	if err != nil {
+		t.Fatalf("expected a failed connection to be retried, got %v", err)
+	}
+	second, _ := conn.get(context.Background())
+	if first != second || attempts != 2 {
+		t.Fatalf("expected the connected client to be reused, got %d attempts", attempts)
+	}
+}
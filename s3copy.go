@@ -0,0 +1,89 @@
+package filesapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+)
+
+// returned by server side part copies when the endpoint does not implement UploadPartCopy
+var errPartCopyUnsupported = errors.New("UploadPartCopy is not supported by the endpoint")
+
+const (
+	partCopyUnknown int32 = iota
+	partCopySupported
+	partCopyUnsupported
+)
+
+// partCopySupport caches whether an endpoint supports UploadPartCopy.  Some S3 compatible
+// appliances do not, so support is probed by the first multipart copy and shared by
+// every store derived from the same client
+type partCopySupport struct {
+	state int32
+}
+
+func (p *partCopySupport) unsupported() bool {
+	return p != nil && atomic.LoadInt32(&p.state) == partCopyUnsupported
+}
+
+func (p *partCopySupport) set(supported bool) {
+	if p == nil {
+		return
+	}
+	state := partCopyUnsupported
+	if supported {
+		state = partCopySupported
+	}
+	atomic.StoreInt32(&p.state, state)
+}
+
+// true for errors returned by endpoints that do not implement an operation
+func isNotImplementedError(err error) bool {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "NotImplemented", "XNotImplemented", "NotSupported":
+			return true
+		}
+	}
+	var responseErr *awshttp.ResponseError
+	return errors.As(err, &responseErr) && responseErr.HTTPStatusCode() == http.StatusNotImplemented
+}
+
+// copies an object by streaming it through the client.  The multipart uploader
+// bounds memory to its part size times its concurrency regardless of object size.
+// The get and put are bounded by whatever remains of the copy timeout
+func (s3fs *S3FS) streamCopy(ctx context.Context, coi CopyObjectInput) error {
+	var opts *CallOptions
+	if deadline, ok := ctx.Deadline(); ok {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return context.DeadlineExceeded
+		}
+		opts = &CallOptions{Timeout: remaining}
+	}
+	reader, err := s3fs.GetObject(GetObjectInput{Path: coi.Src, Options: opts})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	_, err = s3fs.PutObject(PutObjectInput{
+		Source:   ObjectSource{Reader: reader},
+		Dest:     coi.Dest,
+		Mutipart: true,
+		Options:  opts,
+	})
+	if err != nil {
+		return err
+	}
+	return reportProgress(coi.Progress, ProgressData{
+		Index: 0,
+		Max:   1,
+		Value: coi.Dest.Path,
+	})
+}
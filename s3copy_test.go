@@ -0,0 +1,212 @@
+package filesapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// a minimal S3 endpoint that does not implement UploadPartCopy
+type noPartCopyServer struct {
+	mu        sync.Mutex
+	objects   map[string][]byte
+	parts     map[int][]byte
+	partCopy  int
+	completed int
+	aborted   []string
+	headers   map[string]http.Header
+}
+
+func (s *noPartCopyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	query := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+	switch {
+	case r.Method == http.MethodGet && query.Has("attributes"):
+		fmt.Fprintf(w, "<GetObjectAttributesResponse><ObjectSize>%d</ObjectSize></GetObjectAttributesResponse>", len(s.objects[key]))
+	case r.Method == http.MethodGet:
+		w.Write(s.objects[key])
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		s.parts = map[int][]byte{}
+		if s.headers != nil {
+			s.headers[key] = r.Header.Clone()
+		}
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>%s</Key><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>", key)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		s.partCopy++
+		w.WriteHeader(http.StatusNotImplemented)
+		fmt.Fprint(w, "<Error><Code>NotImplemented</Code><Message>not implemented</Message></Error>")
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		n, _ := strconv.Atoi(query.Get("partNumber"))
+		s.parts[n] = body
+		w.Header().Set("ETag", fmt.Sprintf("\"part-%d\"", n))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		numbers := []int{}
+		for n := range s.parts {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
+		assembled := []byte{}
+		for _, n := range numbers {
+			assembled = append(assembled, s.parts[n]...)
+		}
+		s.objects[key] = assembled
+		s.completed++
+		fmt.Fprint(w, "<CompleteMultipartUploadResult><ETag>\"complete\"</ETag></CompleteMultipartUploadResult>")
+	case r.Method == http.MethodDelete:
+		if query.Has("uploadId") {
+			s.aborted = append(s.aborted, key)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		s.objects[key] = body
+		if s.headers != nil {
+			s.headers[key] = r.Header.Clone()
+		}
+		w.Header().Set("ETag", "\"put\"")
+	}
+}
+
+func TestConcatFallsBackWithoutPartCopy(t *testing.T) {
+	source := bytes.Repeat([]byte("0123456789"), min_multipart_part_size/10+1000)
+	backend := &noPartCopyServer{objects: map[string][]byte{"export/part-0": source}}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+	store, err := NewFileStore(MinioFSConfig{
+		S3FSConfig: S3FSConfig{
+			S3Region:    "us-east-1",
+			S3Bucket:    "bucket",
+			Credentials: S3FS_Static{S3Id: "id", S3Key: "key"},
+		},
+		HostAddress: server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s3fs := store.(*S3FS)
+
+	err = ConcatObjects(s3fs, PathConfig{Path: "/export/merged"}, []PathConfig{{Path: "/export/part-0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(backend.objects["export/merged"], source) || backend.partCopy != 1 {
+		t.Fatalf("expected a streamed concat after one part copy attempt, got %d bytes and %d attempts", len(backend.objects["export/merged"]), backend.partCopy)
+	}
+	if !s3fs.partCopy.unsupported() {
+		t.Fatal("expected part copy support to be cached as unsupported")
+	}
+
+	//later copies skip the probe and are shared by derived stores
+	err = ConcatObjects(s3fs.Clone(S3FSOptions{}), PathConfig{Path: "/export/merged-2"}, []PathConfig{{Path: "/export/part-0"}})
+	if err != nil || backend.partCopy != 1 || !bytes.Equal(backend.objects["export/merged-2"], source) {
+		t.Fatalf("expected the cached capability to skip part copies: %v", err)
+	}
+}
+
+func TestBuildCopySourceRange(t *testing.T) {
+	tests := []struct {
+		start, partSize, size int64
+		expected              string
+	}{
+		{0, 10, 25, "bytes=0-9"},
+		{20, 10, 25, "bytes=20-24"},
+		//a final part ending exactly at the object size
+		{10, 10, 20, "bytes=10-19"},
+		{19, 10, 20, "bytes=19-19"},
+	}
+	for _, test := range tests {
+		if r := buildCopySourceRange(test.start, test.partSize, test.size); r != test.expected {
+			t.Fatalf("expected %s for %v, got %s", test.expected, test, r)
+		}
+	}
+	if source := buildCopySource("bucket", "data/flow 1+2/a#b.csv"); source != "bucket/data/flow%201%2B2/a%23b.csv" {
+		t.Fatalf("unexpected copy source %s", source)
+	}
+	if size := copyPartSize(10); size != max_copy_chunk_size {
+		t.Fatalf("expected the minimum part size, got %d", size)
+	}
+	large := int64(max_copy_chunk_size)*max_upload_parts + 1
+	if size := copyPartSize(large); (large+size-1)/size > max_upload_parts {
+		t.Fatalf("part size %d exceeds %d parts", size, max_upload_parts)
+	}
+}
+
+func TestCopyPartsToAbortsUpload(t *testing.T) {
+	backend := &noPartCopyServer{objects: map[string][]byte{"data/source": []byte("source")}}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+	store, err := NewFileStore(MinioFSConfig{
+		S3FSConfig: S3FSConfig{
+			S3Region:    "us-east-1",
+			S3Bucket:    "bucket",
+			Credentials: S3FS_Static{S3Id: "id", S3Key: "key"},
+		},
+		HostAddress: server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.(*S3FS).copyPartsTo(context.Background(), PathConfig{Path: "/data/source"}, PathConfig{Path: "/data/dest"}, 6, nil)
+	if !errors.Is(err, errPartCopyUnsupported) {
+		t.Fatalf("expected the part copy error to be returned, got %v", err)
+	}
+	if len(backend.aborted) != 1 || backend.aborted[0] != "data/dest" {
+		t.Fatalf("expected the upload for data/dest to be aborted, got %v", backend.aborted)
+	}
+}
+
+func TestS3MoveObject(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		requests = append(requests, r.Method+" "+key+" "+r.Header.Get("X-Amz-Copy-Source"))
+		switch {
+		case r.Method == http.MethodGet && r.URL.Query().Has("attributes"):
+			fmt.Fprint(w, "<GetObjectAttributesResponse><ObjectSize>11</ObjectSize></GetObjectAttributesResponse>")
+		case r.Method == http.MethodPut:
+			fmt.Fprint(w, "<CopyObjectResult><ETag>\"etag\"</ETag></CopyObjectResult>")
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+	store, err := NewFileStore(MinioFSConfig{
+		S3FSConfig: S3FSConfig{
+			S3Region:    "us-east-1",
+			S3Bucket:    "bucket",
+			Credentials: S3FS_Static{S3Id: "id", S3Key: "key"},
+		},
+		HostAddress: server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.MoveObject(MoveObjectInput{Src: PathConfig{Path: "/data/a.txt"}, Dest: PathConfig{Path: "/archive/a.txt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied, deleted := false, false
+	for _, r := range requests {
+		copied = copied || r == "PUT archive/a.txt bucket/data/a.txt"
+		deleted = deleted || (copied && r == "DELETE data/a.txt ")
+	}
+	if !copied || !deleted {
+		t.Fatalf("expected a copy followed by a delete of the source, got %v", requests)
+	}
+
+	requests = nil
+	if err := store.MoveObject(MoveObjectInput{Src: PathConfig{Path: "/data/a.txt"}, Dest: PathConfig{Path: "data/a.txt"}}); err != nil || len(requests) != 0 {
+		t.Fatalf("expected moving an object onto itself to do nothing, got %v %v", err, requests)
+	}
+}
@@ -0,0 +1,183 @@
+package filesapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// S3FS_CredentialChain is a prioritized list of S3FS_Static and S3FS_Attached credentials.
+// The first credentials that can be retrieved are used, and the store falls back to
+// the next credentials in the chain when requests are rejected as unauthenticated
+// (i.e. an expired token or a revoked key).  This lets a single configuration work on
+// a laptop (static keys or a profile), on EC2 (the attached instance role), and on premises
+type S3FS_CredentialChain []any
+
+// S3 error codes indicating the request credentials were rejected
+var authFailureCodes = map[string]bool{
+	"InvalidAccessKeyId":    true,
+	"SignatureDoesNotMatch": true,
+	"ExpiredToken":          true,
+	"InvalidToken":          true,
+	"TokenRefreshRequired":  true,
+}
+
+type chainCredentialsProvider struct {
+	mu        sync.Mutex
+	providers []aws.CredentialsProvider
+	current   int
+	cache     *aws.CredentialsCache
+}
+
+func newChainCredentialsProvider(ctx context.Context, chain S3FS_CredentialChain, region string) (*chainCredentialsProvider, error) {
+	if len(chain) == 0 {
+		return nil, errors.New("Empty S3 credential chain")
+	}
+	ccp := &chainCredentialsProvider{}
+	for _, c := range chain {
+		switch cred := c.(type) {
+		case S3FS_Static:
+			ccp.providers = append(ccp.providers, credentials.NewStaticCredentialsProvider(cred.S3Id, cred.S3Key, ""))
+		case S3FS_Attached:
+			loadOptions := []func(*config.LoadOptions) error{config.WithRegion(region)}
+			if cred.Profile != "" {
+				loadOptions = append(loadOptions, config.WithSharedConfigProfile(cred.Profile))
+			}
+			cfg, err := config.LoadDefaultConfig(ctx, loadOptions...)
+			if err != nil {
+				//unusable profiles are skipped so later credentials in the chain can be tried
+				continue
+			}
+			ccp.providers = append(ccp.providers, cfg.Credentials)
+		default:
+			return nil, fmt.Errorf("Unsupported credentials in S3 credential chain: %T", c)
+		}
+	}
+	if len(ccp.providers) == 0 {
+		return nil, errors.New("No usable credentials in S3 credential chain")
+	}
+	ccp.cache = aws.NewCredentialsCache(ccp)
+
+	//fail fast at construction if none of the credentials can be retrieved
+	if _, err := ccp.cache.Retrieve(ctx); err != nil {
+		return nil, err
+	}
+	return ccp, nil
+}
+
+// Retrieve returns credentials from the current provider, moving down the chain past providers that fail
+func (ccp *chainCredentialsProvider) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	ccp.mu.Lock()
+	defer ccp.mu.Unlock()
+	var lastErr error
+	for i := ccp.current; i < len(ccp.providers); i++ {
+		creds, err := ccp.providers[i].Retrieve(ctx)
+		if err == nil {
+			ccp.current = i
+			return creds, nil
+		}
+		lastErr = err
+	}
+	return aws.Credentials{}, fmt.Errorf("Unable to retrieve credentials from the S3 credential chain: %w", lastErr)
+}
+
+// moves to the next provider if the failed provider is still current.
+// returns false when the chain is exhausted
+func (ccp *chainCredentialsProvider) failover(failed int) bool {
+	ccp.mu.Lock()
+	defer ccp.mu.Unlock()
+	if ccp.current != failed {
+		//another request already failed over
+		return true
+	}
+	if ccp.current+1 >= len(ccp.providers) {
+		return false
+	}
+	ccp.current++
+	ccp.cache.Invalidate()
+	return true
+}
+
+func (ccp *chainCredentialsProvider) active() int {
+	ccp.mu.Lock()
+	defer ccp.mu.Unlock()
+	return ccp.current
+}
+
+// failoverMiddleware resends requests rejected as unauthenticated with the next credentials in the chain
+func (ccp *chainCredentialsProvider) failoverMiddleware(stack *middleware.Stack) error {
+	return stack.Finalize.Add(middleware.FinalizeMiddlewareFunc("filesapiCredentialFailover",
+		func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+			for {
+				current := ccp.active()
+				out, metadata, err := next.HandleFinalize(ctx, in)
+				var apiErr smithy.APIError
+				if err == nil || !errors.As(err, &apiErr) || !authFailureCodes[apiErr.ErrorCode()] {
+					return out, metadata, err
+				}
+				if !ccp.failover(current) {
+					return out, metadata, err
+				}
+				if req, ok := in.Request.(*smithyhttp.Request); ok {
+					if rerr := req.RewindStream(); rerr != nil {
+						return out, metadata, err
+					}
+				}
+			}
+		}), middleware.Before)
+}
+
+// ErrConfigConflict is returned by NewFileStore when AwsOptions supplied by the caller
+// conflict with options filesapi sets itself from the store configuration
+var ErrConfigConflict = errors.New("conflicting store configuration")
+
+// validateAwsOptions checks the caller supplied AwsOptions against the region, credentials,
+// endpoint resolver, and http client that filesapi sets from the store configuration, rather
+// than letting the last applied option silently win.  A caller supplied region is adopted
+// when S3Region is empty.  setsEndpoint indicates the store sets its own endpoint resolver (Minio)
+func (sc *S3FSConfig) validateAwsOptions(setsEndpoint bool) error {
+	opts := config.LoadOptions{}
+	for _, fn := range sc.AwsOptions {
+		if err := fn(&opts); err != nil {
+			return err
+		}
+	}
+
+	if opts.Region != "" {
+		if sc.S3Region == "" {
+			sc.S3Region = opts.Region
+		} else if opts.Region != sc.S3Region {
+			return fmt.Errorf("%w: AwsOptions region %s differs from S3Region %s", ErrConfigConflict, opts.Region, sc.S3Region)
+		}
+	}
+
+	switch cred := sc.Credentials.(type) {
+	case S3FS_Attached:
+		if cred.Profile != "" && opts.Credentials != nil {
+			return fmt.Errorf("%w: AwsOptions credentials would override the %s profile credentials", ErrConfigConflict, cred.Profile)
+		}
+		if cred.Profile != "" && opts.SharedConfigProfile != "" && opts.SharedConfigProfile != cred.Profile {
+			return fmt.Errorf("%w: AwsOptions profile %s differs from the %s profile credentials", ErrConfigConflict, opts.SharedConfigProfile, cred.Profile)
+		}
+	default:
+		if opts.Credentials != nil {
+			return fmt.Errorf("%w: AwsOptions credentials conflict with the %T store credentials", ErrConfigConflict, sc.Credentials)
+		}
+	}
+
+	if setsEndpoint && (opts.EndpointResolver != nil || opts.EndpointResolverWithOptions != nil) {
+		return fmt.Errorf("%w: AwsOptions endpoint resolver conflicts with the store HostAddress", ErrConfigConflict)
+	}
+	if sc.ClientOptions != nil && opts.HTTPClient != nil {
+		return fmt.Errorf("%w: AwsOptions http client conflicts with ClientOptions", ErrConfigConflict)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package filesapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+func TestCredentialChain(t *testing.T) {
+	ccp, err := newChainCredentialsProvider(context.Background(), S3FS_CredentialChain{
+		S3FS_Static{S3Id: "first", S3Key: "secret"},
+		S3FS_Static{S3Id: "second", S3Key: "secret"},
+	}, "us-east-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds, err := ccp.cache.Retrieve(context.Background())
+	if err != nil || creds.AccessKeyID != "first" {
+		t.Fatalf("expected the first credentials, got %s %v", creds.AccessKeyID, err)
+	}
+
+	if !ccp.failover(0) {
+		t.Fatal("expected failover to the second credentials")
+	}
+	creds, err = ccp.cache.Retrieve(context.Background())
+	if err != nil || creds.AccessKeyID != "second" {
+		t.Fatalf("expected the second credentials after failover, got %s %v", creds.AccessKeyID, err)
+	}
+	if ccp.failover(1) {
+		t.Fatal("expected the chain to be exhausted")
+	}
+
+	//providers that cannot be retrieved are skipped
+	ccp.providers = []aws.CredentialsProvider{
+		aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{}, errors.New("no instance role")
+		}),
+		ccp.providers[1],
+	}
+	ccp.current = 0
+	creds, err = ccp.Retrieve(context.Background())
+	if err != nil || creds.AccessKeyID != "second" {
+		t.Fatalf("expected the failing provider to be skipped, got %s %v", creds.AccessKeyID, err)
+	}
+
+	_, err = newChainCredentialsProvider(context.Background(), S3FS_CredentialChain{S3FS_Anonymous{}}, "us-east-1")
+	if err == nil {
+		t.Fatal("expected anonymous credentials to be rejected in a chain")
+	}
+}
+
+func TestValidateAwsOptions(t *testing.T) {
+	sc := S3FSConfig{
+		Credentials: S3FS_Static{S3Id: "id", S3Key: "key"},
+		AwsOptions:  []func(*config.LoadOptions) error{config.WithRegion("us-west-2")},
+	}
+	if err := sc.validateAwsOptions(false); err != nil {
+		t.Fatal(err)
+	}
+	if sc.S3Region != "us-west-2" {
+		t.Fatal("expected the AwsOptions region to be adopted when S3Region is empty")
+	}
+
+	sc.S3Region = "us-east-1"
+	if err := sc.validateAwsOptions(false); !errors.Is(err, ErrConfigConflict) {
+		t.Fatalf("expected a region conflict, got %v", err)
+	}
+
+	sc = S3FSConfig{
+		S3Region:    "us-east-1",
+		Credentials: S3FS_Static{S3Id: "id", S3Key: "key"},
+		AwsOptions: []func(*config.LoadOptions) error{
+			config.WithCredentialsProvider(aws.AnonymousCredentials{}),
+		},
+	}
+	if err := sc.validateAwsOptions(false); !errors.Is(err, ErrConfigConflict) {
+		t.Fatalf("expected a credentials conflict, got %v", err)
+	}
+
+	//callers may supply credentials when the store defers to the default credential chain
+	sc.Credentials = S3FS_Attached{}
+	if err := sc.validateAwsOptions(false); err != nil {
+		t.Fatal(err)
+	}
+}
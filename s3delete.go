@@ -0,0 +1,304 @@
+package filesapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// default number of concurrent DeleteObjects requests made by DeletePrefix
+const defaultDeleteConcurrency int = 4
+
+type DeletePrefixInput struct {
+	Prefix PathConfig
+
+	//maximum concurrent DeleteObjects requests.  Defaults to defaultDeleteConcurrency
+	Concurrency int
+
+	//retry policy for failed DeleteObjects requests and for keys that fail with a retryable
+	//error code (i.e. SlowDown).  Only the failed keys are resent.  Defaults to S3Standard
+	RetryPolicy *RetryPolicy
+
+	//reports each deleted key.  Returning an error stops the delete
+	Progress ProgressFunction
+
+	//deletes every version and delete marker under the prefix (ListObjectVersions driven)
+	//so storage on versioned buckets is reclaimed.  Without it versioned buckets keep
+	//old versions behind a new delete marker
+	AllVersions bool
+}
+
+// DeleteFailure is a key that could not be deleted
+type DeleteFailure struct {
+	Key string
+
+	//set when deleting all versions
+	VersionId string
+
+	Code    string
+	Message string
+
+	//lock state when the key is protected by object lock
+	Lock *ObjectLockedError
+}
+
+func (df DeleteFailure) Error() string {
+	if df.VersionId != "" {
+		return fmt.Sprintf("%s (version %s): %s: %s", df.Key, df.VersionId, df.Code, df.Message)
+	}
+	return fmt.Sprintf("%s: %s: %s", df.Key, df.Code, df.Message)
+}
+
+// Unwrap returns the ObjectLockedError of keys protected by object lock, so failures
+// match ErrObjectLocked
+func (df DeleteFailure) Unwrap() error {
+	if df.Lock == nil {
+		return nil
+	}
+	return df.Lock
+}
+
+// DeleteReport summarizes a prefix delete
+type DeleteReport struct {
+	Deleted int
+
+	//keys that could not be deleted once retries were exhausted
+	Failed []DeleteFailure
+}
+
+// returns an error for each failed key
+func (dr *DeleteReport) Errors() []error {
+	errs := make([]error, len(dr.Failed))
+	for i, f := range dr.Failed {
+		errs[i] = f
+	}
+	return errs
+}
+
+// DeletePrefix deletes every object under a prefix.  Listing pages are deleted in batches by
+// up to Concurrency concurrent DeleteObjects requests, and keys that fail with a retryable error
+// are retried with backoff.  The report lists any keys that could not be deleted.  The error
+// is only set when listing fails or Progress stops the delete
+func (s3fs *S3FS) DeletePrefix(input DeletePrefixInput) (*DeleteReport, error) {
+	return s3fs.deletePrefixWithContext(context.TODO(), input)
+}
+
+// deletes a prefix under a parent context so the delete of an object list can share
+// the timeout of the calling DeleteObjects
+func (s3fs *S3FS) deletePrefixWithContext(parent context.Context, input DeletePrefixInput) (*DeleteReport, error) {
+	concurrency := input.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultDeleteConcurrency
+	}
+	policy := S3Standard
+	if input.RetryPolicy != nil {
+		policy = *input.RetryPolicy
+	}
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	report := &DeleteReport{}
+	var mu sync.Mutex
+	var progressErr error
+	batches := make(chan []types.ObjectIdentifier)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				deleted, failed, _ := s3fs.deleteBatch(ctx, batch, policy)
+				mu.Lock()
+				report.Failed = append(report.Failed, failed...)
+				for _, key := range deleted {
+					if progressErr == nil {
+						progressErr = reportProgress(input.Progress, ProgressData{
+							Index: report.Deleted,
+							Max:   -1,
+							Value: key,
+						})
+						if progressErr != nil {
+							cancel()
+						}
+					}
+					report.Deleted++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	prefix := strings.TrimPrefix(input.Prefix.Path, "/")
+	var listErr error
+	if input.AllVersions {
+		listErr = s3fs.listVersionDeleteBatches(ctx, prefix, batches)
+	} else {
+		listErr = s3fs.listDeleteBatches(ctx, prefix, batches)
+	}
+	close(batches)
+	wg.Wait()
+	if progressErr != nil {
+		return report, progressErr
+	}
+	return report, listErr
+}
+
+// lists a prefix one page at a time and sends each page as a delete batch.  Each page is
+// listed after the last key of the previous page rather than with a continuation token,
+// so listing stays valid while the objects already listed are deleted
+func (s3fs *S3FS) listDeleteBatches(ctx context.Context, prefix string, batches chan<- []types.ObjectIdentifier) error {
+	maxKeys := int32(maxDeleteBatchSize)
+	query := &s3.ListObjectsV2Input{
+		Bucket:  &s3fs.config.S3Bucket,
+		Prefix:  &prefix,
+		MaxKeys: &maxKeys,
+	}
+	for {
+		resp, err := s3fs.listObjects(ctx, query)
+		if err != nil {
+			return err
+		}
+		if len(resp.Contents) == 0 {
+			return nil
+		}
+		batch := make([]types.ObjectIdentifier, 0, len(resp.Contents))
+		for _, content := range resp.Contents {
+			batch = append(batch, types.ObjectIdentifier{Key: content.Key})
+		}
+		select {
+		case batches <- batch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if resp.IsTruncated == nil || !*resp.IsTruncated {
+			return nil
+		}
+		query.StartAfter = resp.Contents[len(resp.Contents)-1].Key
+	}
+}
+
+// lists every version and delete marker under a prefix one page at a time and sends each
+// page as a delete batch.  Key and version markers stay valid while listed versions are deleted
+func (s3fs *S3FS) listVersionDeleteBatches(ctx context.Context, prefix string, batches chan<- []types.ObjectIdentifier) error {
+	client, err := s3fs.client()
+	if err != nil {
+		return err
+	}
+	maxKeys := int32(maxDeleteBatchSize)
+	query := &s3.ListObjectVersionsInput{
+		Bucket:  &s3fs.config.S3Bucket,
+		Prefix:  &prefix,
+		MaxKeys: &maxKeys,
+	}
+	for {
+		resp, err := client.ListObjectVersions(ctx, query)
+		if err != nil {
+			return err
+		}
+		batch := make([]types.ObjectIdentifier, 0, len(resp.Versions)+len(resp.DeleteMarkers))
+		for _, version := range resp.Versions {
+			batch = append(batch, types.ObjectIdentifier{Key: version.Key, VersionId: version.VersionId})
+		}
+		for _, marker := range resp.DeleteMarkers {
+			batch = append(batch, types.ObjectIdentifier{Key: marker.Key, VersionId: marker.VersionId})
+		}
+		if len(batch) > 0 {
+			select {
+			case batches <- batch:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		if resp.IsTruncated == nil || !*resp.IsTruncated {
+			return nil
+		}
+		query.KeyMarker = resp.NextKeyMarker
+		query.VersionIdMarker = resp.NextVersionIdMarker
+	}
+}
+
+// identifies a key or key version within a delete batch
+func objectIdentity(key *string, versionId *string) string {
+	return aws.ToString(key) + "\x00" + aws.ToString(versionId)
+}
+
+// deletes a batch of keys, resending only the keys that fail with an error the policy
+// retries.  Returns the deleted keys, the keys that could not be deleted, and the last
+// request error if the batch could not be sent
+func (s3fs *S3FS) deleteBatch(ctx context.Context, keys []types.ObjectIdentifier, policy RetryPolicy) ([]string, []DeleteFailure, error) {
+	deleted := []string{}
+	failed := []DeleteFailure{}
+	pending := keys
+	retryer := NewRetryer[any](policy)
+	_, err := retryer.SendWithContext(ctx, func(ctx context.Context) (any, error) {
+		out, err := s3fs.deleteObjectsImpl(ctx, &s3.DeleteObjectsInput{
+			Bucket: &s3fs.config.S3Bucket,
+			Delete: &types.Delete{
+				Objects: pending,
+				Quiet:   Ref(true),
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		retry := []types.ObjectIdentifier{}
+		var retryErr error
+		failedKeys := map[string]bool{}
+		for _, e := range out.Errors {
+			failedKeys[objectIdentity(e.Key, e.VersionId)] = true
+			keyErr := &smithy.GenericAPIError{Code: aws.ToString(e.Code), Message: aws.ToString(e.Message)}
+			if policy.Retryable == nil || policy.Retryable(keyErr) {
+				retry = append(retry, types.ObjectIdentifier{Key: e.Key, VersionId: e.VersionId})
+				retryErr = keyErr
+			} else {
+				failure := DeleteFailure{
+					Key:       aws.ToString(e.Key),
+					VersionId: aws.ToString(e.VersionId),
+					Code:      keyErr.Code,
+					Message:   keyErr.Message,
+				}
+				if isObjectLockError(keyErr.Code, keyErr.Message) {
+					failure.Lock = s3fs.objectLockedError(ctx, failure.Key, failure.VersionId, keyErr)
+				}
+				failed = append(failed, failure)
+			}
+		}
+		//quiet responses only list the keys that failed
+		for _, obj := range pending {
+			if !failedKeys[objectIdentity(obj.Key, obj.VersionId)] {
+				deleted = append(deleted, aws.ToString(obj.Key))
+			}
+		}
+		pending = retry
+		if len(pending) == 0 {
+			return nil, nil
+		}
+		return nil, retryErr
+	})
+	if err != nil {
+		code, message := "", ""
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) {
+			code, message = apiErr.ErrorCode(), apiErr.ErrorMessage()
+		}
+		for _, obj := range pending {
+			failure := DeleteFailure{
+				Key:       aws.ToString(obj.Key),
+				VersionId: aws.ToString(obj.VersionId),
+				Code:      code,
+				Message:   err.Error(),
+			}
+			if isObjectLockError(code, message) {
+				failure.Lock = s3fs.objectLockedError(ctx, failure.Key, failure.VersionId, err)
+			}
+			failed = append(failed, failure)
+		}
+	}
+	return deleted, failed, err
+}
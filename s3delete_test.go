@@ -0,0 +1,222 @@
+package filesapi
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// a minimal S3 endpoint that lists, puts and deletes objects.  Keys in throttled fail
+// with SlowDown on their first delete and keys in denied always fail.  Versions
+// are keyed as key#versionId and are delete markers when the value is true
+type deleteServer struct {
+	mu        sync.Mutex
+	keys      map[string]bool
+	versions  map[string]bool
+	throttled map[string]bool
+	denied    map[string]bool
+	requests  int
+}
+
+func (s *deleteServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	query := r.URL.Query()
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	switch {
+	case r.Method == http.MethodHead:
+		if !s.keys[key] {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case r.Method == http.MethodGet && query.Has("attributes"):
+		if !s.keys[key] {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, "<Error><Code>NoSuchKey</Code><Message>missing</Message></Error>")
+			return
+		}
+		fmt.Fprint(w, "<GetObjectAttributesResponse><ObjectSize>1</ObjectSize></GetObjectAttributesResponse>")
+	case r.Method == http.MethodPut:
+		io.Copy(io.Discard, r.Body)
+		s.keys[key] = true
+		w.Header().Set("ETag", "\"put\"")
+	case r.Method == http.MethodGet && query.Get("list-type") == "2":
+		maxKeys, _ := strconv.Atoi(query.Get("max-keys"))
+		keys := []string{}
+		for key := range s.keys {
+			if strings.HasPrefix(key, query.Get("prefix")) && key > query.Get("start-after") {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		truncated := len(keys) > maxKeys
+		if truncated {
+			keys = keys[:maxKeys]
+		}
+		fmt.Fprintf(w, "<ListBucketResult><Name>bucket</Name><KeyCount>%d</KeyCount><IsTruncated>%t</IsTruncated>", len(keys), truncated)
+		for _, key := range keys {
+			fmt.Fprintf(w, "<Contents><Key>%s</Key><Size>1</Size></Contents>", key)
+		}
+		fmt.Fprint(w, "</ListBucketResult>")
+	case r.Method == http.MethodGet && query.Has("versions"):
+		maxKeys, _ := strconv.Atoi(query.Get("max-keys"))
+		marker := ""
+		if query.Get("key-marker") != "" {
+			marker = query.Get("key-marker") + "#" + query.Get("version-id-marker")
+		}
+		entries := []string{}
+		for entry := range s.versions {
+			if strings.HasPrefix(entry, query.Get("prefix")) && entry > marker {
+				entries = append(entries, entry)
+			}
+		}
+		sort.Strings(entries)
+		truncated := len(entries) > maxKeys
+		if truncated {
+			entries = entries[:maxKeys]
+		}
+		fmt.Fprintf(w, "<ListVersionsResult><Name>bucket</Name><IsTruncated>%t</IsTruncated>", truncated)
+		for _, entry := range entries {
+			key, version, _ := strings.Cut(entry, "#")
+			element := "Version"
+			if s.versions[entry] {
+				element = "DeleteMarker"
+			}
+			fmt.Fprintf(w, "<%s><Key>%s</Key><VersionId>%s</VersionId></%s>", element, key, version, element)
+		}
+		if truncated {
+			key, version, _ := strings.Cut(entries[len(entries)-1], "#")
+			fmt.Fprintf(w, "<NextKeyMarker>%s</NextKeyMarker><NextVersionIdMarker>%s</NextVersionIdMarker>", key, version)
+		}
+		fmt.Fprint(w, "</ListVersionsResult>")
+	case r.Method == http.MethodPost && query.Has("delete"):
+		s.requests++
+		body, _ := io.ReadAll(r.Body)
+		var del struct {
+			Objects []struct {
+				Key       string
+				VersionId string
+			} `xml:"Object"`
+		}
+		xml.Unmarshal(body, &del)
+		fmt.Fprint(w, "<DeleteResult>")
+		for _, obj := range del.Objects {
+			switch {
+			case s.denied[obj.Key]:
+				fmt.Fprintf(w, "<Error><Key>%s</Key><Code>AccessDenied</Code><Message>denied</Message></Error>", obj.Key)
+			case s.throttled[obj.Key]:
+				delete(s.throttled, obj.Key)
+				fmt.Fprintf(w, "<Error><Key>%s</Key><VersionId>%s</VersionId><Code>SlowDown</Code><Message>slow down</Message></Error>", obj.Key, obj.VersionId)
+			case obj.VersionId != "":
+				delete(s.versions, obj.Key+"#"+obj.VersionId)
+			default:
+				delete(s.keys, obj.Key)
+			}
+		}
+		fmt.Fprint(w, "</DeleteResult>")
+	}
+}
+
+func TestDeletePrefix(t *testing.T) {
+	backend := &deleteServer{
+		keys:      map[string]bool{"keep/file": true, "data/locked": true},
+		throttled: map[string]bool{},
+		denied:    map[string]bool{"data/locked": true},
+	}
+	for i := 0; i < 2500; i++ {
+		key := fmt.Sprintf("data/file-%04d", i)
+		backend.keys[key] = true
+		if i%10 == 0 {
+			backend.throttled[key] = true
+		}
+	}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+	store, err := NewFileStore(MinioFSConfig{
+		S3FSConfig: S3FSConfig{
+			S3Region:    "us-east-1",
+			S3Bucket:    "bucket",
+			Credentials: S3FS_Static{S3Id: "id", S3Key: "key"},
+		},
+		HostAddress: server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := RetryPolicy{MaxAttempts: 3, MaxBackoff: 0.01, R: 2, Retryable: IsRetryableError}
+	progress := 0
+	report, err := store.(*S3FS).DeletePrefix(DeletePrefixInput{
+		Prefix:      PathConfig{Path: "/data/"},
+		Concurrency: 3,
+		RetryPolicy: &policy,
+		Progress: func(pd ProgressData) error {
+			progress++
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Deleted != 2500 || progress != 2500 {
+		t.Fatalf("expected 2500 deleted keys, got %d with %d progress calls", report.Deleted, progress)
+	}
+	if len(report.Failed) != 1 || report.Failed[0].Key != "data/locked" || report.Failed[0].Code != "AccessDenied" {
+		t.Fatalf("expected only data/locked to fail, got %v", report.Failed)
+	}
+	if len(backend.keys) != 2 || !backend.keys["keep/file"] {
+		t.Fatalf("unexpected remaining keys %v", backend.keys)
+	}
+	//3 pages plus a retry of the throttled keys in each
+	if backend.requests != 6 {
+		t.Fatalf("expected 6 delete requests, got %d", backend.requests)
+	}
+}
+
+func TestDeletePrefixAllVersions(t *testing.T) {
+	backend := &deleteServer{
+		keys:      map[string]bool{},
+		versions:  map[string]bool{"keep/file#v1": false},
+		throttled: map[string]bool{"data/file-0001": true},
+		denied:    map[string]bool{},
+	}
+	for i := 0; i < 600; i++ {
+		key := fmt.Sprintf("data/file-%04d", i)
+		backend.versions[key+"#v1"] = false
+		backend.versions[key+"#v2"] = false
+		backend.versions[key+"#v3"] = true
+	}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+	store, err := NewFileStore(MinioFSConfig{
+		S3FSConfig: S3FSConfig{
+			S3Region:    "us-east-1",
+			S3Bucket:    "bucket",
+			Credentials: S3FS_Static{S3Id: "id", S3Key: "key"},
+		},
+		HostAddress: server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := RetryPolicy{MaxAttempts: 3, MaxBackoff: 0.01, R: 2, Retryable: IsRetryableError}
+	report, err := store.(*S3FS).DeletePrefix(DeletePrefixInput{
+		Prefix:      PathConfig{Path: "/data/"},
+		RetryPolicy: &policy,
+		AllVersions: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Deleted != 1800 || len(report.Failed) != 0 {
+		t.Fatalf("expected 1800 deleted versions and markers, got %d with failures %v", report.Deleted, report.Failed)
+	}
+	if len(backend.versions) != 1 || len(backend.throttled) != 0 {
+		t.Fatalf("unexpected remaining versions %v", backend.versions)
+	}
+}
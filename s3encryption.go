@@ -0,0 +1,121 @@
+package filesapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// KMSKeyRule encrypts the objects written under a key prefix with a specific KMS key,
+// so datasets sharing a bucket can be protected by their own keys
+type KMSKeyRule struct {
+
+	//key prefix the rule applies to (i.e. projects/levee-study/).  When rules overlap
+	//the longest matching prefix is used
+	Prefix string
+
+	//KMS key ARN, key id or alias
+	KeyId string
+
+	//optional encryption context.  The same context must be allowed by the key policy,
+	//and it is recorded in CloudTrail with each use of the key
+	EncryptionContext map[string]string
+
+	//uses an S3 bucket key, reducing the KMS requests made for the prefix
+	BucketKeyEnabled bool
+}
+
+// checks the KMS key rules of a configuration
+func (sc *S3FSConfig) validateKMSKeys() error {
+	prefixes := map[string]bool{}
+	for _, rule := range sc.KMSKeys {
+		if rule.KeyId == "" {
+			return fmt.Errorf("%w: KMS key rule for prefix %q has no KeyId", ErrConfigConflict, rule.Prefix)
+		}
+		prefix := strings.TrimPrefix(rule.Prefix, "/")
+		if prefixes[prefix] {
+			return fmt.Errorf("%w: multiple KMS key rules for prefix %q", ErrConfigConflict, rule.Prefix)
+		}
+		prefixes[prefix] = true
+	}
+	return nil
+}
+
+// server side encryption settings applied to a write
+type kmsEncryption struct {
+	keyId      *string
+	context    *string
+	bucketKey  *bool
+	encryption types.ServerSideEncryption
+}
+
+// returns the encryption for the rule with the longest prefix matching a key,
+// or nil when no rule matches and the bucket default encryption applies
+func (s3fs *S3FS) kmsEncryption(key string) (*kmsEncryption, error) {
+	var match *KMSKeyRule
+	for i, rule := range s3fs.config.KMSKeys {
+		prefix := strings.TrimPrefix(rule.Prefix, "/")
+		if strings.HasPrefix(key, prefix) && (match == nil || len(prefix) > len(strings.TrimPrefix(match.Prefix, "/"))) {
+			match = &s3fs.config.KMSKeys[i]
+		}
+	}
+	if match == nil {
+		return nil, nil
+	}
+	enc := &kmsEncryption{
+		keyId:      &match.KeyId,
+		encryption: types.ServerSideEncryptionAwsKms,
+	}
+	if match.BucketKeyEnabled {
+		enc.bucketKey = Ref(true)
+	}
+	if len(match.EncryptionContext) > 0 {
+		//the context header is base64 encoded json
+		data, err := json.Marshal(match.EncryptionContext)
+		if err != nil {
+			return nil, err
+		}
+		enc.context = Ref(base64.StdEncoding.EncodeToString(data))
+	}
+	return enc, nil
+}
+
+func (s3fs *S3FS) encryptPut(input *s3.PutObjectInput) error {
+	enc, err := s3fs.kmsEncryption(*input.Key)
+	if enc == nil || err != nil {
+		return err
+	}
+	input.ServerSideEncryption = enc.encryption
+	input.SSEKMSKeyId = enc.keyId
+	input.SSEKMSEncryptionContext = enc.context
+	input.BucketKeyEnabled = enc.bucketKey
+	return nil
+}
+
+func (s3fs *S3FS) encryptMultipart(input *s3.CreateMultipartUploadInput) error {
+	enc, err := s3fs.kmsEncryption(*input.Key)
+	if enc == nil || err != nil {
+		return err
+	}
+	input.ServerSideEncryption = enc.encryption
+	input.SSEKMSKeyId = enc.keyId
+	input.SSEKMSEncryptionContext = enc.context
+	input.BucketKeyEnabled = enc.bucketKey
+	return nil
+}
+
+func (s3fs *S3FS) encryptCopy(input *s3.CopyObjectInput) error {
+	enc, err := s3fs.kmsEncryption(*input.Key)
+	if enc == nil || err != nil {
+		return err
+	}
+	input.ServerSideEncryption = enc.encryption
+	input.SSEKMSKeyId = enc.keyId
+	input.SSEKMSEncryptionContext = enc.context
+	input.BucketKeyEnabled = enc.bucketKey
+	return nil
+}
@@ -0,0 +1,64 @@
+package filesapi
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestKMSKeyRules(t *testing.T) {
+	backend := &noPartCopyServer{objects: map[string][]byte{}, headers: map[string]http.Header{}}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+	config := MinioFSConfig{
+		S3FSConfig: S3FSConfig{
+			S3Region:    "us-east-1",
+			S3Bucket:    "bucket",
+			Credentials: S3FS_Static{S3Id: "id", S3Key: "key"},
+			KMSKeys: []KMSKeyRule{
+				{Prefix: "/projects/levee/", KeyId: "levee-key", EncryptionContext: map[string]string{"project": "levee"}},
+				{Prefix: "projects/levee/restricted/", KeyId: "restricted-key", BucketKeyEnabled: true},
+			},
+		},
+		HostAddress: server.URL,
+	}
+	store, err := NewFileStore(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"projects/levee/a.csv", "projects/levee/restricted/b.csv", "other/c.csv"} {
+		_, err = store.PutObject(PutObjectInput{Source: ObjectSource{Data: []byte("data")}, Dest: PathConfig{Path: "/" + key}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = store.InitializeObjectUpload(UploadConfig{ObjectPath: "/projects/levee/large.bin"}); err != nil {
+		t.Fatal(err)
+	}
+
+	levee := backend.headers["projects/levee/a.csv"]
+	if levee.Get("X-Amz-Server-Side-Encryption") != "aws:kms" || levee.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id") != "levee-key" {
+		t.Fatalf("expected the levee key, got %v", levee)
+	}
+	context, _ := base64.StdEncoding.DecodeString(levee.Get("X-Amz-Server-Side-Encryption-Context"))
+	if string(context) != `{"project":"levee"}` {
+		t.Fatalf("unexpected encryption context %s", context)
+	}
+	restricted := backend.headers["projects/levee/restricted/b.csv"]
+	if restricted.Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id") != "restricted-key" || restricted.Get("X-Amz-Server-Side-Encryption-Bucket-Key-Enabled") != "true" {
+		t.Fatalf("expected the longest prefix rule, got %v", restricted)
+	}
+	if backend.headers["other/c.csv"].Get("X-Amz-Server-Side-Encryption") != "" {
+		t.Fatal("expected objects outside the rules to use the bucket default encryption")
+	}
+	if backend.headers["projects/levee/large.bin"].Get("X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id") != "levee-key" {
+		t.Fatal("expected multipart uploads to use the prefix key")
+	}
+
+	config.KMSKeys = []KMSKeyRule{{Prefix: "projects/"}}
+	if _, err = NewFileStore(config); !errors.Is(err, ErrConfigConflict) {
+		t.Fatalf("expected a rule without a key to be rejected, got %v", err)
+	}
+}
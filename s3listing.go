@@ -0,0 +1,125 @@
+package filesapi
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	defaultListConcurrency int           = 8
+	maxListAttempts        int           = 8
+	listBaseBackoff        time.Duration = 100 * time.Millisecond
+	listMaxBackoff         time.Duration = 20 * time.Second
+)
+
+// listThrottle adapts the number of concurrent list requests made by an S3FS.
+// The limit is halved each time S3 responds with SlowDown and grows back by
+// roughly one request per round of successful requests (AIMD).  While throttled
+// each page request is also delayed by a jittered pacing interval so concurrent
+// walkers do not retry in lock step
+type listThrottle struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	max     int
+	limit   float64
+	active  int
+	penalty time.Duration
+}
+
+func newListThrottle(max int) *listThrottle {
+	if max <= 0 {
+		max = defaultListConcurrency
+	}
+	lt := &listThrottle{max: max, limit: float64(max)}
+	lt.cond = sync.NewCond(&lt.mu)
+	return lt
+}
+
+func (lt *listThrottle) acquire() {
+	if lt == nil {
+		return
+	}
+	lt.mu.Lock()
+	for lt.active >= int(lt.limit) {
+		lt.cond.Wait()
+	}
+	lt.active++
+	penalty := lt.penalty
+	lt.mu.Unlock()
+	if penalty > 0 {
+		time.Sleep(time.Duration(jitter() * float64(penalty)))
+	}
+}
+
+func (lt *listThrottle) release(throttled bool) {
+	if lt == nil {
+		return
+	}
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+	lt.active--
+	if throttled {
+		lt.limit = math.Max(1, lt.limit/2)
+		lt.penalty = lt.penalty * 2
+		if lt.penalty < listBaseBackoff {
+			lt.penalty = listBaseBackoff
+		}
+		if lt.penalty > listMaxBackoff {
+			lt.penalty = listMaxBackoff
+		}
+	} else {
+		lt.limit = math.Min(float64(lt.max), lt.limit+1/lt.limit)
+		lt.penalty = lt.penalty / 2
+		if lt.penalty < time.Millisecond {
+			lt.penalty = 0
+		}
+	}
+	lt.cond.Broadcast()
+}
+
+// listObjects sends a ListObjectsV2 request through the store's list throttle,
+// backing off and retrying when S3 responds with SlowDown
+func (s3fs *S3FS) listObjects(ctx context.Context, params *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	client, err := s3fs.client()
+	if err != nil {
+		return nil, err
+	}
+	for attempt := 1; ; attempt++ {
+		s3fs.lister.acquire()
+		resp, err := client.ListObjectsV2(ctx, params)
+		throttled := isSlowDownError(err)
+		s3fs.lister.release(throttled)
+		if !throttled || attempt >= maxListAttempts {
+			return resp, err
+		}
+		backoff := math.Min(float64(listBaseBackoff)*math.Pow(2, float64(attempt)), float64(listMaxBackoff))
+		timer := time.NewTimer(time.Duration(jitter() * backoff))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reports whether err is an S3 request rate throttling response
+func isSlowDownError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "SlowDown" {
+		return true
+	}
+	var responseErr *awshttp.ResponseError
+	return errors.As(err, &responseErr) && responseErr.HTTPStatusCode() == http.StatusServiceUnavailable
+}
@@ -0,0 +1,36 @@
+package filesapi
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/smithy-go"
+)
+
+func TestListThrottle(t *testing.T) {
+	lt := newListThrottle(8)
+	lt.acquire()
+	lt.release(true)
+	lt.acquire()
+	lt.release(true)
+	if lt.limit != 2 {
+		t.Fatalf("expected the limit to halve on each SlowDown, got %v", lt.limit)
+	}
+	if lt.penalty != 2*listBaseBackoff {
+		t.Fatalf("expected pacing to double on each SlowDown, got %s", lt.penalty)
+	}
+	for i := 0; i < 100; i++ {
+		lt.active++
+		lt.release(false)
+	}
+	if lt.limit != 8 || lt.penalty != 0 {
+		t.Fatalf("expected the throttle to recover, got limit %v penalty %s", lt.limit, lt.penalty)
+	}
+
+	if !isSlowDownError(&smithy.GenericAPIError{Code: "SlowDown"}) {
+		t.Fatal("expected SlowDown to be classified as throttling")
+	}
+	if isSlowDownError(errors.New("access denied")) {
+		t.Fatal("unexpected throttling classification")
+	}
+}
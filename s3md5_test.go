@@ -0,0 +1,87 @@
+package filesapi
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestContentMD5(t *testing.T) {
+	reader := bytes.NewReader([]byte(testObjectString))
+	sum, err := contentMD5(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//md5 of HELLO WORLD
+	if sum != "Nh+t8ccS6BLRmMTKtXEqeQ==" {
+		t.Fatalf("unexpected Content-MD5 %s", sum)
+	}
+	if pos, _ := reader.Seek(0, io.SeekCurrent); pos != 0 {
+		t.Fatalf("expected the reader to be rewound to 0, got %d", pos)
+	}
+
+	//readers are hashed from, and returned to, their current position
+	reader.Seek(6, io.SeekStart)
+	sum, _ = contentMD5(reader)
+	expected := md5.Sum([]byte("WORLD"))
+	if sum != base64.StdEncoding.EncodeToString(expected[:]) {
+		t.Fatalf("unexpected Content-MD5 from an offset %s", sum)
+	}
+	if pos, _ := reader.Seek(0, io.SeekCurrent); pos != 6 {
+		t.Fatalf("expected the reader to be returned to 6, got %d", pos)
+	}
+}
+
+func TestS3ContentMD5Headers(t *testing.T) {
+	headers := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("attributes") {
+			fmt.Fprintf(w, "<GetObjectAttributesResponse><ObjectSize>%d</ObjectSize></GetObjectAttributesResponse>", len(testObjectString))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		sum := md5.Sum(body)
+		if r.Header.Get("Content-MD5") != base64.StdEncoding.EncodeToString(sum[:]) {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, "<Error><Code>InvalidDigest</Code><Message>missing or invalid Content-MD5</Message></Error>")
+			return
+		}
+		headers[r.Method] = r.Header.Get("Content-MD5")
+		if r.URL.Query().Has("delete") {
+			io.WriteString(w, "<DeleteResult></DeleteResult>")
+			return
+		}
+		w.Header().Set("ETag", "\"put\"")
+	}))
+	defer server.Close()
+	store, err := NewFileStore(MinioFSConfig{
+		S3FSConfig: S3FSConfig{
+			S3Region:    "us-east-1",
+			S3Bucket:    "bucket",
+			Credentials: S3FS_Static{S3Id: "id", S3Key: "key"},
+			ContentMD5:  true,
+		},
+		HostAddress: server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = store.PutObject(PutObjectInput{Source: ObjectSource{Data: []byte(testObjectString)}, Dest: PathConfig{Path: "/a.txt"}}); err != nil {
+		t.Fatal(err)
+	}
+	if headers[http.MethodPut] != "Nh+t8ccS6BLRmMTKtXEqeQ==" {
+		t.Fatalf("unexpected put Content-MD5 %q", headers[http.MethodPut])
+	}
+	if errs := store.DeleteObjects(DeleteObjectInput{Paths: PathConfig{Paths: []string{"/a.txt", "/b.txt"}}}); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if strings.TrimSpace(headers[http.MethodPost]) == "" {
+		t.Fatal("expected the delete request to carry a Content-MD5 header")
+	}
+}
@@ -0,0 +1,97 @@
+package filesapi
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestS3ClientOptions(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    *S3ClientOptions
+		compliance bool
+
+		//expected results.  No client means no http client option
+		client          bool
+		keepAlives      bool
+		maxIdlePerHost  int
+		idleTimeout     time.Duration
+		headerTimeout   time.Duration
+		tls12           bool
+		unsignedPayload bool
+		fips            bool
+	}{
+		{name: "defaults", options: nil},
+		{name: "compliance defaults", options: nil, compliance: true, client: true, keepAlives: true, tls12: true, fips: true},
+		{
+			name: "tuned",
+			options: &S3ClientOptions{
+				DisableKeepAlives:     true,
+				MaxIdleConnsPerHost:   64,
+				IdleConnTimeout:       time.Minute,
+				ResponseHeaderTimeout: 10 * time.Second,
+				DisablePayloadSigning: true,
+			},
+			client:          true,
+			maxIdlePerHost:  64,
+			idleTimeout:     time.Minute,
+			headerTimeout:   10 * time.Second,
+			unsignedPayload: true,
+		},
+		{name: "tuned compliance", options: &S3ClientOptions{MaxIdleConnsPerHost: 8}, compliance: true, client: true, keepAlives: true, maxIdlePerHost: 8, tls12: true, fips: true},
+	}
+	for _, test := range tests {
+		loadOptions, s3Options := test.options.options(test.compliance)
+
+		lo := config.LoadOptions{}
+		for _, option := range loadOptions {
+			if err := option(&lo); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !test.client {
+			if lo.HTTPClient != nil || len(s3Options) != 0 {
+				t.Fatalf("%s: expected no client options", test.name)
+			}
+			continue
+		}
+		client, ok := lo.HTTPClient.(*awshttp.BuildableClient)
+		if !ok {
+			t.Fatalf("%s: expected a buildable http client, got %T", test.name, lo.HTTPClient)
+		}
+		tr := client.GetTransport()
+		if tr.DisableKeepAlives == test.keepAlives {
+			t.Fatalf("%s: unexpected keep-alives %v", test.name, !tr.DisableKeepAlives)
+		}
+		if test.maxIdlePerHost > 0 && tr.MaxIdleConnsPerHost != test.maxIdlePerHost {
+			t.Fatalf("%s: unexpected idle connections per host %d", test.name, tr.MaxIdleConnsPerHost)
+		}
+		if test.idleTimeout > 0 && tr.IdleConnTimeout != test.idleTimeout {
+			t.Fatalf("%s: unexpected idle timeout %v", test.name, tr.IdleConnTimeout)
+		}
+		if test.headerTimeout > 0 && tr.ResponseHeaderTimeout != test.headerTimeout {
+			t.Fatalf("%s: unexpected response header timeout %v", test.name, tr.ResponseHeaderTimeout)
+		}
+		if test.tls12 && (tr.TLSClientConfig == nil || tr.TLSClientConfig.MinVersion < tls.VersionTLS12) {
+			t.Fatalf("%s: unexpected tls minimum %v", test.name, tr.TLSClientConfig)
+		}
+
+		so := s3.Options{}
+		for _, option := range s3Options {
+			option(&so)
+		}
+		if (len(so.APIOptions) == 1) != test.unsignedPayload {
+			t.Fatalf("%s: unexpected api options %d", test.name, len(so.APIOptions))
+		}
+		fips := so.EndpointOptions.UseFIPSEndpoint == aws.FIPSEndpointStateEnabled
+		if fips != test.fips || so.DisableMultiRegionAccessPoints != test.fips {
+			t.Fatalf("%s: unexpected endpoint options %v", test.name, so.EndpointOptions)
+		}
+	}
+}
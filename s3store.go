@@ -3,26 +3,47 @@ package filesapi
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"log"
+	"mime"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
 )
 
 const max_copy_chunk_size = 5 * 1024 * 1024
+
+// maximum number of parts in an S3 multipart upload
+const max_upload_parts = 10000
+
+// region used to locate a bucket when AutoDetectRegion is set without an S3Region
+const defaultDetectionRegion = "us-east-1"
 const max_put_object_copy_size = 5000 * 1024 * 1024
 
+// maximum number of keys in a single DeleteObjects request
+const maxDeleteBatchSize = 1000
+
 var noSuchKey *types.NoSuchKey
 
 type S3AttributesFileInfo struct {
@@ -46,7 +67,10 @@ func (obj *S3AttributesFileInfo) Mode() os.FileMode {
 }
 
 func (obj *S3AttributesFileInfo) ModTime() time.Time {
-	return time.Now()
+	if obj.GetObjectAttributesOutput == nil || obj.LastModified == nil {
+		return time.Time{}
+	}
+	return *obj.LastModified
 }
 
 // if the object has attributes, then it is a "file"
@@ -62,6 +86,51 @@ func (obj *S3AttributesFileInfo) Sys() interface{} {
 	return nil
 }
 
+// cancels a call context when the object body is closed
+type cancelReadCloser struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelReadCloser) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// applies the content headers requested for a put
+func (poi PutObjectInput) applyContentHeaders(input *s3.PutObjectInput) {
+	if poi.ContentEncoding != "" {
+		input.ContentEncoding = &poi.ContentEncoding
+	}
+	if poi.ContentType != "" {
+		input.ContentType = &poi.ContentType
+	}
+	if poi.CacheControl != "" {
+		input.CacheControl = &poi.CacheControl
+	}
+}
+
+// applies storage class and metadata overrides to a put
+func (co *CallOptions) applyPut(input *s3.PutObjectInput) {
+	if co == nil {
+		return
+	}
+	if co.StorageClass != "" {
+		input.StorageClass = types.StorageClass(co.StorageClass)
+	}
+	if len(co.Metadata) > 0 {
+		input.Metadata = co.Metadata
+	}
+	if len(co.Tags) > 0 {
+		values := url.Values{}
+		for k, v := range co.Tags {
+			values.Set(k, v)
+		}
+		tagging := values.Encode()
+		input.Tagging = &tagging
+	}
+}
+
 type S3FileInfo struct {
 	s3 *types.Object
 }
@@ -106,6 +175,10 @@ type S3FS_Static struct {
 	S3Key string
 }
 
+// S3FS_Anonymous sends unsigned requests for reading public buckets
+// (i.e. NOAA and USGS open data) without any configured credentials
+type S3FS_Anonymous struct{}
+
 type S3FSConfig struct {
 	S3Region    string
 	S3Bucket    string
@@ -113,6 +186,109 @@ type S3FSConfig struct {
 	MaxKeys     int32
 	Credentials any
 	AwsOptions  []func(*config.LoadOptions) error
+
+	//optional http client and request tuning for high throughput workloads
+	ClientOptions *S3ClientOptions
+
+	//sends a Content-MD5 header with single part PutObject and DeleteObjects requests.
+	//required by some S3-compatible appliances.  Put sources must be seekable
+	//(byte slices, files, ReaderAt sources, or io.ReadSeeker readers) to be hashed
+	ContentMD5 bool
+
+	//restricts the store to FIPS endpoints, TLS 1.2 or later, and SigV4 signed requests
+	//for systems under federal security baselines.  NewFileStore returns an
+	//ErrComplianceUnsupported error when the configuration cannot satisfy the mode
+	ComplianceMode bool
+
+	//looks up the bucket region when the store connects and uses it in place of
+	//S3Region, avoiding opaque 301 redirect errors when S3Region is wrong or empty.
+	//S3Region is updated with the detected region.  Ignored by Minio stores
+	AutoDetectRegion bool
+
+	//maximum concurrent list requests made by ListDir and Walk across the store.
+	//the limit adapts downward when S3 responds with SlowDown.  Defaults to defaultListConcurrency
+	ListConcurrency int
+
+	//optional per prefix KMS keys applied to every object written by the store.
+	//Objects outside the rule prefixes use the bucket default encryption
+	KMSKeys []KMSKeyRule
+
+	//defers credential resolution, region detection, and client creation until the store
+	//is first used, so stores can be constructed while a backend is unreachable.
+	//The configuration is still validated by NewFileStore.  See S3FS.Connect
+	LazyConnect bool
+}
+
+// S3ClientOptions tunes the http transport used by the S3 client.
+// Zero values keep the AWS SDK defaults
+type S3ClientOptions struct {
+
+	//disables http keep-alives so each request uses a new connection
+	DisableKeepAlives bool
+
+	//maximum idle (keep-alive) connections across all hosts
+	MaxIdleConns int
+
+	//maximum idle (keep-alive) connections per host.
+	//raise this for highly concurrent transfers against a single bucket
+	MaxIdleConnsPerHost int
+
+	//how long an idle connection remains open
+	IdleConnTimeout time.Duration
+
+	//time to wait for response headers after a request is written
+	ResponseHeaderTimeout time.Duration
+
+	//sends UNSIGNED-PAYLOAD rather than hashing request bodies for the signature.
+	//avoids reading each upload body twice.  Only use over https
+	DisablePayloadSigning bool
+}
+
+// returns the aws config load options and s3 client options for the client options.
+// compliance mode requires TLS 1.2 or later, FIPS endpoints, and SigV4 signing
+func (co *S3ClientOptions) options(compliance bool) ([]func(*config.LoadOptions) error, []func(*s3.Options)) {
+	if co == nil {
+		if !compliance {
+			return nil, nil
+		}
+		co = &S3ClientOptions{}
+	}
+	httpClient := awshttp.NewBuildableClient().WithTransportOptions(func(tr *http.Transport) {
+		tr.DisableKeepAlives = co.DisableKeepAlives
+		if co.MaxIdleConns > 0 {
+			tr.MaxIdleConns = co.MaxIdleConns
+		}
+		if co.MaxIdleConnsPerHost > 0 {
+			tr.MaxIdleConnsPerHost = co.MaxIdleConnsPerHost
+		}
+		if co.IdleConnTimeout > 0 {
+			tr.IdleConnTimeout = co.IdleConnTimeout
+		}
+		if co.ResponseHeaderTimeout > 0 {
+			tr.ResponseHeaderTimeout = co.ResponseHeaderTimeout
+		}
+		if compliance {
+			if tr.TLSClientConfig == nil {
+				tr.TLSClientConfig = &tls.Config{}
+			}
+			tr.TLSClientConfig.MinVersion = tls.VersionTLS12
+		}
+	})
+	loadOptions := []func(*config.LoadOptions) error{config.WithHTTPClient(httpClient)}
+	s3Options := []func(*s3.Options){}
+	if co.DisablePayloadSigning {
+		s3Options = append(s3Options, func(o *s3.Options) {
+			o.APIOptions = append(o.APIOptions, v4.SwapComputePayloadSHA256ForUnsignedPayloadMiddleware)
+		})
+	}
+	if compliance {
+		s3Options = append(s3Options, func(o *s3.Options) {
+			o.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+			//multi-region access points require SigV4a
+			o.DisableMultiRegionAccessPoints = true
+		})
+	}
+	return loadOptions, s3Options
 }
 
 type MinioFSConfig struct {
@@ -121,15 +297,67 @@ type MinioFSConfig struct {
 }
 
 type S3FS struct {
-	s3client                 *s3.Client
-	config                   *S3FSConfig
-	delimiter                string
-	maxKeys                  int32
-	ignoreContinuationOnWalk bool //internal use only
+	conn      *s3Connection
+	config    *S3FSConfig
+	delimiter string
+	maxKeys   int32
+	lister    *listThrottle
+	logger    *log.Logger
+	partCopy  *partCopySupport
+}
+
+// S3FSOptions are the per store defaults that can be overridden on a derived store.
+// Zero values keep the settings of the store being derived from
+type S3FSOptions struct {
+	Delimiter string
+	MaxKeys   int32
+
+	//scopes every path of a store returned by With to a key prefix
+	Prefix string
+
+	//logger for store diagnostics.  Defaults to the standard logger
+	Logger *log.Logger
+}
+
+// Clone returns a copy of the store with the option overrides applied.  The copy shares the
+// underlying S3 client, configuration, and list throttle, so it is cheap to create per request.
+// Prefix is ignored; use With to scope a store to a prefix
+func (s3fs *S3FS) Clone(opts S3FSOptions) *S3FS {
+	clone := *s3fs
+	if opts.Delimiter != "" {
+		clone.delimiter = opts.Delimiter
+	}
+	if opts.MaxKeys > 0 {
+		clone.maxKeys = opts.MaxKeys
+	}
+	if opts.Logger != nil {
+		clone.logger = opts.Logger
+	}
+	return &clone
+}
+
+// With returns a derived store sharing the underlying S3 client, with the option overrides applied
+// and, when opts.Prefix is set, every path scoped beneath the prefix (see PrefixFS)
+func (s3fs *S3FS) With(opts S3FSOptions) FileStore {
+	clone := s3fs.Clone(opts)
+	if opts.Prefix == "" {
+		return clone
+	}
+	return NewPrefixFS(clone, opts.Prefix)
 }
 
+func (s3fs *S3FS) getLogger() *log.Logger {
+	if s3fs.logger == nil {
+		return log.Default()
+	}
+	return s3fs.logger
+}
+
+// GetClient returns the S3 client of the store.  A lazily connected store connects first,
+// and GetClient returns nil if the connection fails; use Connect to get the error
 func (s3fs *S3FS) GetClient() *s3.Client {
-	return s3fs.s3client
+	client, _ := s3fs.client()
+	return client
 }
 
 func (s3fs *S3FS) GetConfig() *S3FSConfig {
@@ -141,6 +369,18 @@ func (s3fs *S3FS) ResourceName() string {
 }
 
 func (s3fs *S3FS) GetObjectInfo(path PathConfig) (fs.FileInfo, error) {
+	return s3fs.objectInfo(context.TODO(), path)
+}
+
+// gets object attributes under the context of the calling operation
+func (s3fs *S3FS) objectInfo(ctx context.Context, path PathConfig) (fs.FileInfo, error) {
+	client, err := s3fs.client()
+	if err != nil {
+		return nil, err
+	}
+	if all := path.All(); len(all) > 1 {
+		return resourceInfo(all, s3fs.GetObjectInfo)
+	}
 	s3Path := strings.TrimPrefix(path.Path, "/")
 	params := &s3.GetObjectAttributesInput{
 		Bucket: &s3fs.config.S3Bucket,
@@ -151,7 +391,7 @@ func (s3fs *S3FS) GetObjectInfo(path PathConfig) (fs.FileInfo, error) {
 		},
 	}
 
-	resp, err := s3fs.s3client.GetObjectAttributes(context.TODO(), params)
+	resp, err := client.GetObjectAttributes(ctx, params)
 	if errors.As(err, &noSuchKey) {
 		err = &FileNotFoundError{path.Path}
 	}
@@ -173,14 +413,16 @@ func (s3fs *S3FS) ListDir(input ListDirInput) (*[]FileStoreResultObject, error)
 		ContinuationToken: continuationToken,
 	}
 
+	ctx, cancel := input.Options.context()
+	defer cancel()
 	var err error
 	if input.Filter == "" && input.Size <= DEFAULTMAXKEYS {
-		prefixes, objects, err = s3fs.getPage(input, params)
+		prefixes, objects, err = s3fs.getPage(ctx, input, params)
 	} else {
-		prefixes, objects, err = s3fs.getAllUpToMax(input, params)
+		prefixes, objects, err = s3fs.getAllUpToMax(ctx, input, params)
 	}
 	if err != nil {
-		return nil, fmt.Errorf("Unable to get page: %s\n", err)
+		return nil, fmt.Errorf("Unable to get page: %w\n", err)
 	}
 
 	result := []FileStoreResultObject{}
@@ -217,7 +459,7 @@ func (s3fs *S3FS) ListDir(input ListDirInput) (*[]FileStoreResultObject, error)
 	return &result, nil
 }
 
-func (s3fs *S3FS) getAllUpToMax(input ListDirInput, params *s3.ListObjectsV2Input) ([]types.CommonPrefix, []types.Object, error) {
+func (s3fs *S3FS) getAllUpToMax(ctx context.Context, input ListDirInput, params *s3.ListObjectsV2Input) ([]types.CommonPrefix, []types.Object, error) {
 	shouldContinue := true
 	if input.Size > 0 && input.Size < DEFAULTMAXKEYS {
 		params.MaxKeys = &input.Size
@@ -229,9 +471,9 @@ func (s3fs *S3FS) getAllUpToMax(input ListDirInput, params *s3.ListObjectsV2Inpu
 
 	for shouldContinue {
 		params.ContinuationToken = continuationToken
-		resp, err := s3fs.s3client.ListObjectsV2(context.TODO(), params)
+		resp, err := s3fs.listObjects(ctx, params)
 		if err != nil {
-			log.Printf("failed to list objects in the bucket - %v", err)
+			s3fs.getLogger().Printf("failed to list objects in the bucket - %v", err)
 			return nil, nil, err
 		}
 		if input.Filter != "" {
@@ -269,28 +511,32 @@ func (s3fs *S3FS) getAllUpToMax(input ListDirInput, params *s3.ListObjectsV2Inpu
 
 // Uses the AWS Pagenator to get a single page of unfiltered results
 // for a given page number and page size
-func (s3fs *S3FS) getPage(input ListDirInput, params *s3.ListObjectsV2Input) ([]types.CommonPrefix, []types.Object, error) {
+func (s3fs *S3FS) getPage(ctx context.Context, input ListDirInput, params *s3.ListObjectsV2Input) ([]types.CommonPrefix, []types.Object, error) {
+	client, err := s3fs.client()
+	if err != nil {
+		return nil, nil, err
+	}
 	currentPage := 0
 	if input.Size > 0 {
 		params.MaxKeys = &input.Size
 	}
 	prefixes := []types.CommonPrefix{}
 	objects := []types.Object{}
-	paginator := s3.NewListObjectsV2Paginator(s3fs.s3client, params)
+	paginator := s3.NewListObjectsV2Paginator(client, params)
 	for paginator.HasMorePages() {
 		if currentPage == input.Page {
-			page, err := paginator.NextPage(context.TODO())
+			page, err := paginator.NextPage(ctx)
 			if err != nil {
-				return nil, nil, fmt.Errorf("unable to get page, %v", err)
+				return nil, nil, fmt.Errorf("unable to get page, %w", err)
 			}
 			prefixes = append(prefixes, page.CommonPrefixes...)
 			objects = append(objects, page.Contents...)
 			break
 		}
 		currentPage++
-		_, err := paginator.NextPage(context.TODO())
+		_, err := paginator.NextPage(ctx)
 		if err != nil {
-			return nil, nil, fmt.Errorf("unable to get page, %v", err)
+			return nil, nil, fmt.Errorf("unable to get page, %w", err)
 		}
 	}
 	return prefixes, objects, nil
@@ -315,9 +561,9 @@ func (s3fs *S3FS) GetDir(path PathConfig) (*[]FileStoreResultObject, error) {
 			ContinuationToken: continuationToken,
 		}
 
-		resp, err := s3fs.s3client.ListObjectsV2(context.TODO(), params)
+		resp, err := s3fs.listObjects(context.TODO(), params)
 		if err != nil {
-			log.Printf("failed to list objects in the bucket - %v", err)
+			s3fs.getLogger().Printf("failed to list objects in the bucket - %v", err)
 			return nil, err
 		}
 		prefixes = append(prefixes, resp.CommonPrefixes...)
@@ -364,36 +610,62 @@ func (s3fs *S3FS) GetDir(path PathConfig) (*[]FileStoreResultObject, error) {
 }
 
 func (s3fs *S3FS) GetObject(goi GetObjectInput) (io.ReadCloser, error) {
+	client, err := s3fs.client()
+	if err != nil {
+		return nil, err
+	}
 	s3Path := strings.TrimPrefix(goi.Path.Path, "/")
 	input := &s3.GetObjectInput{
 		Bucket: &s3fs.config.S3Bucket,
 		Key:    &s3Path,
 		Range:  &goi.Range,
 	}
-	output, err := s3fs.s3client.GetObject(context.TODO(), input)
+	ctx, cancel := goi.Options.context()
+	output, err := client.GetObject(ctx, input)
 	if err != nil {
+		cancel()
 		if errors.As(err, &noSuchKey) {
 			err = &FileNotFoundError{goi.Path.Path}
 		}
 		return nil, err
 	}
-	return output.Body, nil
+	//the call timeout also covers reading the body
+	body := &cancelReadCloser{output.Body, cancel}
+	if goi.Decompress && goi.Range == "" && output.ContentEncoding != nil && strings.EqualFold(*output.ContentEncoding, gzipEncoding) {
+		return newGzipReadCloser(body)
+	}
+	return body, nil
 }
 
 func (s3fs *S3FS) PutObject(poi PutObjectInput) (*FileOperationOutput, error) {
+	client, err := s3fs.client()
+	if err != nil {
+		return nil, err
+	}
 	s3Path := strings.TrimPrefix(poi.Dest.Path, "/")
 	reader, err := poi.Source.GetReader()
 	if err != nil {
 		return nil, fmt.Errorf("Unable to get the Source Reader: %s\n", err)
 	}
-	//defer reader.Close()
+	defer poi.Source.closeReader(reader)
+	if poi.Mutipart && poi.IfNoneMatch {
+		return nil, errors.New("Conditional puts are not supported for multipart uploads")
+	}
+	ctx, cancel := poi.Options.context()
+	defer cancel()
 	if poi.Mutipart {
-		uploader := manager.NewUploader(s3fs.s3client)
-		s3output, err := uploader.Upload(context.TODO(), &s3.PutObjectInput{
+		uploader := manager.NewUploader(client)
+		input := &s3.PutObjectInput{
 			Bucket: &s3fs.config.S3Bucket,
 			Key:    &s3Path,
 			Body:   reader,
-		})
+		}
+		poi.applyContentHeaders(input)
+		poi.Options.applyPut(input)
+		if err = s3fs.encryptPut(input); err != nil {
+			return nil, err
+		}
+		s3output, err := uploader.Upload(ctx, input)
 		if err != nil {
 			return nil, err
 		}
@@ -408,7 +680,30 @@ func (s3fs *S3FS) PutObject(poi PutObjectInput) (*FileOperationOutput, error) {
 			ContentLength: poi.Source.ContentLength,
 			Key:           &s3Path,
 		}
-		s3output, err := s3fs.s3client.PutObject(context.TODO(), input)
+		poi.applyContentHeaders(input)
+		poi.Options.applyPut(input)
+		if err = s3fs.encryptPut(input); err != nil {
+			return nil, err
+		}
+		if s3fs.config.ContentMD5 {
+			if seeker, ok := reader.(io.ReadSeeker); ok {
+				contentMd5, err := contentMD5(seeker)
+				if err != nil {
+					return nil, fmt.Errorf("Unable to compute Content-MD5: %s\n", err)
+				}
+				input.ContentMD5 = &contentMd5
+			}
+		}
+		putOptions := []func(*s3.Options){}
+		if poi.IfNoneMatch {
+			putOptions = append(putOptions, func(o *s3.Options) {
+				o.APIOptions = append(o.APIOptions, smithyhttp.AddHeaderValue("If-None-Match", "*"))
+			})
+		}
+		s3output, err := client.PutObject(ctx, input, putOptions...)
+		if poi.IfNoneMatch && isPreconditionFailed(err) {
+			return nil, fmt.Errorf("%w: %s", ErrObjectExists, poi.Dest.Path)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -422,8 +717,9 @@ func (s3fs *S3FS) PutObject(poi PutObjectInput) (*FileOperationOutput, error) {
 
 func (s3fs *S3FS) DeleteObjects(doi DeleteObjectInput) []error {
 
-	objects := make([]types.ObjectIdentifier, 0, len(doi.Paths.Paths))
-	for _, p := range doi.Paths.Paths {
+	paths := doi.Paths.All()
+	objects := make([]types.ObjectIdentifier, 0, len(paths))
+	for _, p := range paths {
 		p := p
 		s3Path := strings.TrimPrefix(p, "/")
 		object := types.ObjectIdentifier{
@@ -440,207 +736,313 @@ func (s3fs *S3FS) DeleteObjects(doi DeleteObjectInput) []error {
 		},
 	}
 
-	return s3fs.deleteListImpl(input, doi.Progress)
+	ctx, cancel := doi.Options.context()
+	defer cancel()
+	return s3fs.deleteListImpl(ctx, input, doi.Progress)
 
 }
 
-func (s3fs *S3FS) deleteListImpl(input *s3.DeleteObjectsInput, pf ProgressFunction) []error {
+// deletes the requested objects in batches, deleting the contents of any path that is
+// not an object as a prefix.  Deletion state is kept on the call stack so concurrent
+// deletes and walks on the same S3FS do not interfere
+func (s3fs *S3FS) deleteListImpl(ctx context.Context, input *s3.DeleteObjectsInput, pf ProgressFunction) []error {
 	errs := []error{}
-	s3fs.ignoreContinuationOnWalk = true
-	defer func() {
-		s3fs.ignoreContinuationOnWalk = false
-	}()
-	maxDelBufferSize := 1000
 	delBuffer := []types.ObjectIdentifier{}
 	count := 0
 	for _, obj := range input.Delete.Objects {
-		info, err1 := s3fs.GetObjectInfo(PathConfig{Path: *obj.Key})
+		info, err1 := s3fs.objectInfo(ctx, PathConfig{Path: *obj.Key})
 		if err1 != nil {
 			//if we get a filenotfound error, then attempt to traverse it as a path
 			//otherwise quit
-			if err1, ok := err1.(*FileNotFoundError); !ok {
-				log.Printf("Error getting delete object info for %s: %s\n", *obj.Key, err1)
+			if _, ok := err1.(*FileNotFoundError); !ok {
+				s3fs.getLogger().Printf("Error getting delete object info for %s: %s\n", *obj.Key, err1)
 				return []error{err1}
 			}
 		}
 		if info.IsDir() {
-			s3fs.Walk(WalkInput{Path: PathConfig{Path: *obj.Key}, Progress: pf}, func(path string, file os.FileInfo) error {
-				key := file.Name()
-				delBuffer = append(delBuffer, types.ObjectIdentifier{Key: &key})
-				if len(delBuffer) >= maxDelBufferSize {
-					err := s3fs.flushDeletes(delBuffer)
-					if err != nil {
-						log.Printf("Error in batch delete operation: %s\n", err)
-					}
-				}
-				count++
-				return nil
-			})
-		} else {
-			delBuffer = append(delBuffer, types.ObjectIdentifier{Key: obj.Key})
+			perrs, err := s3fs.deletePrefix(ctx, *obj.Key, pf, &count)
+			errs = append(errs, perrs...)
+			if err != nil {
+				return append(errs, err)
+			}
+			continue
 		}
-
-		//flush any remaining deletes
-		err := s3fs.flushDeletes(delBuffer)
+		delBuffer = append(delBuffer, types.ObjectIdentifier{Key: obj.Key})
+		err := reportProgress(pf, ProgressData{
+			Index: count,
+			Max:   -1,
+			Value: *obj.Key,
+		})
 		if err != nil {
-			log.Printf("Error in batch delete operation: %s\n", err)
+			return append(errs, err)
+		}
+		count++
+		if len(delBuffer) >= maxDeleteBatchSize {
+			errs = append(errs, s3fs.flushDeletes(ctx, delBuffer)...)
+			delBuffer = []types.ObjectIdentifier{}
 		}
 	}
+
+	//flush any remaining deletes
+	if len(delBuffer) > 0 {
+		errs = append(errs, s3fs.flushDeletes(ctx, delBuffer)...)
+	}
 	return errs
 }
 
-func (s3fs *S3FS) flushDeletes(delBuffer []types.ObjectIdentifier) []error {
+// deletes every object under a prefix with DeletePrefix, continuing the progress count
+// of the calling delete
+func (s3fs *S3FS) deletePrefix(ctx context.Context, prefix string, pf ProgressFunction, count *int) ([]error, error) {
+	offset := *count
+	report, err := s3fs.deletePrefixWithContext(ctx, DeletePrefixInput{
+		Prefix: PathConfig{Path: prefix},
+		Progress: func(pd ProgressData) error {
+			pd.Index += offset
+			return reportProgress(pf, pd)
+		},
+	})
+	*count += report.Deleted
+	return report.Errors(), err
+}
+
+// deletes a batch of keys, retrying keys that fail with a retryable error
+func (s3fs *S3FS) flushDeletes(ctx context.Context, delBuffer []types.ObjectIdentifier) []error {
 	if len(delBuffer) == 0 {
 		return []error{errors.New("nothing to delete")}
 	}
-	input := &s3.DeleteObjectsInput{
-		Bucket: &s3fs.config.S3Bucket,
-		Delete: &types.Delete{
-			Objects: delBuffer,
-		},
-	}
-	out, err := s3fs.deleteObjectsImpl(input)
+	_, failed, err := s3fs.deleteBatch(ctx, delBuffer, S3Standard)
 	if err != nil {
 		return []error{err}
 	}
-
-	errs := make([]error, len(out.Errors))
-	for i, e := range out.Errors {
-		if e.Key != nil && e.Code != nil && e.Message != nil {
-			errs[i] = fmt.Errorf("%s: %s: %s", *e.Key, *e.Code, *e.Message)
-		} else {
-			errs[i] = errors.New("Unknown AWS delete error")
-		}
-	}
-	return errs
+	return (&DeleteReport{Failed: failed}).Errors()
 }
 
-func (s3fs *S3FS) deleteObjectsImpl(input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
-	result, err := s3fs.s3client.DeleteObjects(context.TODO(), input)
+func (s3fs *S3FS) deleteObjectsImpl(ctx context.Context, input *s3.DeleteObjectsInput) (*s3.DeleteObjectsOutput, error) {
+	client, err := s3fs.client()
+	if err != nil {
+		return nil, err
+	}
+	optFns := []func(*s3.Options){}
+	if s3fs.config.ContentMD5 {
+		optFns = append(optFns, s3.WithAPIOptions(smithyhttp.AddContentChecksumMiddleware))
+	}
+	result, err := client.DeleteObjects(ctx, input, optFns...)
 	return result, err
 }
 
 func (s3fs *S3FS) CopyObject(coi CopyObjectInput) error {
-	info, err := s3fs.GetObjectInfo(coi.Src)
+	client, err := s3fs.client()
+	if err != nil {
+		return err
+	}
+	if len(coi.Src.All()) > 1 || len(coi.Dest.All()) > 1 {
+		return copyEach(coi, s3fs.CopyObject)
+	}
+	ctx, cancel := coi.Options.context()
+	defer cancel()
+	info, err := s3fs.objectInfo(ctx, coi.Src)
 	if err != nil {
 		return err
 	}
-
 	var fileSize int64 = info.Size()
 	if fileSize < max_put_object_copy_size {
-		source := fmt.Sprintf("%s/%s", s3fs.ResourceName(), strings.TrimPrefix(coi.Src.Path, "/"))
+		source := buildCopySource(s3fs.ResourceName(), strings.TrimPrefix(coi.Src.Path, "/"))
 		dest := strings.TrimPrefix(coi.Dest.Path, "/")
 		input := s3.CopyObjectInput{
 			Bucket:     &s3fs.config.S3Bucket,
 			CopySource: &source,
 			Key:        &dest,
 		}
-		_, err = s3fs.s3client.CopyObject(context.TODO(), &input)
+		if err = s3fs.encryptCopy(&input); err != nil {
+			return err
+		}
+		_, err = client.CopyObject(ctx, &input)
+		if err == nil {
+			err = reportProgress(coi.Progress, ProgressData{
+				Index: 0,
+				Max:   1,
+				Value: source,
+			})
+		}
+	} else if s3fs.partCopy.unsupported() {
+		err = s3fs.streamCopy(ctx, coi)
 	} else {
-		s3fs.copyPartsTo(coi.Src, coi.Dest, fileSize)
+		err = s3fs.copyPartsTo(ctx, coi.Src, coi.Dest, fileSize, coi.Progress)
+		if errors.Is(err, errPartCopyUnsupported) {
+			s3fs.getLogger().Printf("Falling back to a streamed copy of %s\n", coi.Src.Path)
+			err = s3fs.streamCopy(ctx, coi)
+		}
 	}
-	return err
+	if err != nil || !coi.Verify {
+		return err
+	}
+	return s3fs.verifyCopy(ctx, coi, info, fileSize < max_put_object_copy_size)
+}
+
+// MoveObject copies the object, with a multipart copy for objects over 5GB, and deletes the source
+// once the copy succeeds.  Object versions, and tags of multipart copies, are not carried over
+func (s3fs *S3FS) MoveObject(moi MoveObjectInput) error {
+	if len(moi.Src.All()) > 1 || len(moi.Dest.All()) > 1 {
+		return moveEach(moi, s3fs.MoveObject)
+	}
+	src := strings.TrimPrefix(moi.Src.Path, "/")
+	if src == strings.TrimPrefix(moi.Dest.Path, "/") {
+		return nil
+	}
+	if err := s3fs.CopyObject(moi.copyInput()); err != nil {
+		return err
+	}
+	client, err := s3fs.client()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := moi.Options.context()
+	defer cancel()
+	_, err = client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: &s3fs.config.S3Bucket,
+		Key:    &src,
+	})
+	if err != nil {
+		return fmt.Errorf("Copied %s to %s but unable to delete the source: %w", moi.Src.Path, moi.Dest.Path, err)
+	}
+	return nil
+}
+
+// compares the size of a copy with its source.  Single request copies of single part
+// objects preserve the source ETag, so the ETags are compared as well
+func (s3fs *S3FS) verifyCopy(ctx context.Context, coi CopyObjectInput, srcInfo fs.FileInfo, singleRequest bool) error {
+	destInfo, err := s3fs.objectInfo(ctx, coi.Dest)
+	if err != nil {
+		return err
+	}
+	if srcInfo.Size() != destInfo.Size() {
+		return &CopyMismatchError{
+			Src:      coi.Src.Path,
+			Dest:     coi.Dest.Path,
+			Property: "size",
+			Expected: strconv.FormatInt(srcInfo.Size(), 10),
+			Actual:   strconv.FormatInt(destInfo.Size(), 10),
+		}
+	}
+	srcEtag := attributesEtag(srcInfo)
+	destEtag := attributesEtag(destInfo)
+	if singleRequest && srcEtag != "" && !strings.Contains(srcEtag, "-") && srcEtag != destEtag {
+		return &CopyMismatchError{Src: coi.Src.Path, Dest: coi.Dest.Path, Property: "etag", Expected: srcEtag, Actual: destEtag}
+	}
+	return nil
+}
+
+func attributesEtag(info fs.FileInfo) string {
+	if attrs, ok := info.(*S3AttributesFileInfo); ok && attrs.GetObjectAttributesOutput != nil && attrs.ETag != nil {
+		return strings.Trim(*attrs.ETag, "\"")
+	}
+	return ""
 }
 
-func (s3fs *S3FS) copyPartsTo(sourcePath PathConfig, destPath PathConfig, fileSize int64) error {
-	source := fmt.Sprintf("%s/%s", s3fs.ResourceName(), strings.TrimPrefix(sourcePath.Path, "/"))
+// copies an object with UploadPartCopy.  Any failure aborts the upload so no parts are left behind
+func (s3fs *S3FS) copyPartsTo(ctx context.Context, sourcePath PathConfig, destPath PathConfig, fileSize int64, pf ProgressFunction) error {
+	client, err := s3fs.client()
+	if err != nil {
+		return err
+	}
+	source := buildCopySource(s3fs.ResourceName(), strings.TrimPrefix(sourcePath.Path, "/"))
 	dest := strings.TrimPrefix(destPath.Path, "/")
 
-	/*
-		ctx, cancelFn := context.WithTimeout(context.TODO(), 10*time.Minute)
-		defer cancelFn()
-	*/
 	//struct for starting a multipart upload
 	destInput := s3.CreateMultipartUploadInput{
 		Bucket: &s3fs.config.S3Bucket,
 		Key:    &dest,
 	}
-	var uploadId string
-	createOutput, err := s3fs.s3client.CreateMultipartUpload(context.TODO(), &destInput)
-	if err != nil {
+	if err := s3fs.encryptMultipart(&destInput); err != nil {
 		return err
 	}
-	if createOutput != nil {
-		if createOutput.UploadId != nil {
-			uploadId = *createOutput.UploadId
-		}
+	createOutput, err := client.CreateMultipartUpload(ctx, &destInput)
+	if err != nil {
+		return err
 	}
-	if uploadId == "" {
+	if createOutput == nil || createOutput.UploadId == nil || *createOutput.UploadId == "" {
 		return errors.New("No upload id found in start upload request")
 	}
+	uploadId := *createOutput.UploadId
+	abort := func(err error) error {
+		s3fs.getLogger().Printf("Attempting to abort upload %s\n", uploadId)
+		//uses a fresh context so cancelled copies are still cleaned up.  Ignoring any errors with aborting the copy
+		client.AbortMultipartUpload(context.TODO(), &s3.AbortMultipartUploadInput{
+			Bucket:   &s3fs.config.S3Bucket,
+			Key:      &dest,
+			UploadId: &uploadId,
+		})
+		return err
+	}
 
-	var i int64
-	var partNumber int32 = 1
-	//copySource := fmt.Sprintf("%s/%s", s3fs.config.S3Bucket, source)
-
-	parts := make([]types.CompletedPart, 0)
-	numUploads := fileSize / max_copy_chunk_size
-	log.Printf("Will attempt upload in %d number of parts to %s", numUploads, dest)
+	partSize := copyPartSize(fileSize)
+	numParts := int((fileSize + partSize - 1) / partSize)
+	parts := make([]types.CompletedPart, 0, numParts)
+	s3fs.getLogger().Printf("Will attempt copy in %d parts to %s", numParts, dest)
 
-	for i = 0; i < fileSize; i += max_copy_chunk_size {
-		copyRange := buildCopySourceRange(i, fileSize)
-		partInput := s3.UploadPartCopyInput{
+	for i := 0; i < numParts; i++ {
+		partNumber := int32(i + 1)
+		copyRange := buildCopySourceRange(int64(i)*partSize, partSize, fileSize)
+		partResp, err := client.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
 			Bucket:          &s3fs.config.S3Bucket,
 			CopySource:      &source,
 			CopySourceRange: &copyRange,
 			Key:             &dest,
 			PartNumber:      &partNumber,
 			UploadId:        &uploadId,
-		}
-
-		partResp, err := s3fs.s3client.UploadPartCopy(context.TODO(), &partInput)
-
+		})
 		if err != nil {
-			log.Println("Attempting to abort upload")
-			abortIn := s3.AbortMultipartUploadInput{
-				UploadId: &uploadId,
+			if partNumber == 1 && isNotImplementedError(err) {
+				s3fs.partCopy.set(false)
+				return abort(fmt.Errorf("%w: %s", errPartCopyUnsupported, err))
 			}
-			//ignoring any errors with aborting the copy
-			s3fs.s3client.AbortMultipartUpload(context.TODO(), &abortIn)
-			return fmt.Errorf("Error uploading part %d : %w", partNumber, err)
+			return abort(fmt.Errorf("Error copying part %d : %w", partNumber, err))
+		}
+		s3fs.partCopy.set(true)
+		if partResp == nil || partResp.CopyPartResult == nil || partResp.CopyPartResult.ETag == nil {
+			return abort(fmt.Errorf("Error copying part %d : missing ETag in response", partNumber))
 		}
 
 		//copy etag and part number from response as it is needed for completion
-		if partResp != nil {
-			partNum := partNumber
-			etag := strings.Trim(*partResp.CopyPartResult.ETag, "\"")
-			cPart := types.CompletedPart{
-				ETag:       &etag,
-				PartNumber: &partNum,
-			}
-			parts = append(parts, cPart)
-			log.Printf("Successfully upload part %d of %s\n", partNumber, uploadId)
+		etag := strings.Trim(*partResp.CopyPartResult.ETag, "\"")
+		parts = append(parts, types.CompletedPart{
+			ETag:       &etag,
+			PartNumber: &partNumber,
+		})
+		err = reportProgress(pf, ProgressData{
+			Index: i,
+			Max:   numParts,
+			Value: copyRange,
+		})
+		if err != nil {
+			s3fs.getLogger().Println("Copy aborted")
+			return abort(err)
 		}
-		partNumber++
 		if partNumber%50 == 0 {
-			log.Printf("Completed part %d of %d to %s\n", partNumber, numUploads, dest)
+			s3fs.getLogger().Printf("Completed part %d of %d to %s\n", partNumber, numParts, dest)
 		}
 	}
-	//create struct for completing the upload
-	mpu := types.CompletedMultipartUpload{
-		Parts: parts,
-	}
 
 	//complete actual upload
 	//does not actually copy if the complete command is not received
-	complete := s3.CompleteMultipartUploadInput{
+	_, err = client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
 		Bucket:          &s3fs.config.S3Bucket,
 		Key:             &dest,
 		UploadId:        &uploadId,
-		MultipartUpload: &mpu,
-	}
-	compOutput, err := s3fs.s3client.CompleteMultipartUpload(context.TODO(), &complete)
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
 	if err != nil {
-		return fmt.Errorf("Error completing upload: %w", err)
-	}
-	if compOutput != nil {
-		log.Println("Finished copy")
+		return abort(fmt.Errorf("Error completing upload: %w", err))
 	}
+	s3fs.getLogger().Println("Finished copy")
 	return nil
-
 }
 
 func (s3fs *S3FS) InitializeObjectUpload(u UploadConfig) (UploadResult, error) {
+	client, err := s3fs.client()
+	if err != nil {
+		return UploadResult{}, err
+	}
 	output := UploadResult{}
 	s3path := u.ObjectPath //@TODO incomoplete
 	s3path = strings.TrimPrefix(s3path, "/")
@@ -648,8 +1050,15 @@ func (s3fs *S3FS) InitializeObjectUpload(u UploadConfig) (UploadResult, error) {
 		Bucket: &s3fs.config.S3Bucket,
 		Key:    &s3path,
 	}
+	if u.Checksums {
+		//parts record SHA256 checksums, so completed uploads can be verified without reading them back
+		input.ChecksumAlgorithm = types.ChecksumAlgorithmSha256
+	}
+	if err := s3fs.encryptMultipart(input); err != nil {
+		return output, err
+	}
 
-	resp, err := s3fs.s3client.CreateMultipartUpload(context.TODO(), input)
+	resp, err := client.CreateMultipartUpload(context.TODO(), input)
 	if err != nil {
 		return output, err
 	}
@@ -658,6 +1067,10 @@ func (s3fs *S3FS) InitializeObjectUpload(u UploadConfig) (UploadResult, error) {
 }
 
 func (s3fs *S3FS) WriteChunk(u UploadConfig) (UploadResult, error) {
+	client, err := s3fs.client()
+	if err != nil {
+		return UploadResult{}, err
+	}
 	s3path := u.ObjectPath //@TODO incomplete
 	s3path = strings.TrimPrefix(s3path, "/")
 	partNumber := u.ChunkId + 1 //aws chunks are 1 to n, our chunks are 0 referenced
@@ -669,7 +1082,11 @@ func (s3fs *S3FS) WriteChunk(u UploadConfig) (UploadResult, error) {
 		UploadId:      &u.UploadId,
 		ContentLength: Ref(int64(len(u.Data))),
 	}
-	result, err := s3fs.s3client.UploadPart(context.TODO(), partInput)
+	if u.Checksums {
+		sum := sha256.Sum256(u.Data)
+		partInput.ChecksumSHA256 = Ref(base64.StdEncoding.EncodeToString(sum[:]))
+	}
+	result, err := client.UploadPart(context.TODO(), partInput)
 
 	if err != nil {
 		return UploadResult{}, err
@@ -682,15 +1099,36 @@ func (s3fs *S3FS) WriteChunk(u UploadConfig) (UploadResult, error) {
 }
 
 func (s3fs *S3FS) CompleteObjectUpload(u CompletedObjectUploadConfig) error {
+	client, err := s3fs.client()
+	if err != nil {
+		return err
+	}
 	s3path := u.ObjectPath //@TODO incomplete
 	s3path = strings.TrimPrefix(s3path, "/")
+
+	verify := u.ExpectedHash != "" || len(u.PartHashes) > 0
+	checksums := map[int32]string{}
+	if verify {
+		//uploads created with a checksum algorithm must be completed with the part checksums
+		uploaded, err := s3fs.ListUploadParts(UploadConfig{ObjectPath: u.ObjectPath, UploadId: u.UploadId})
+		if err != nil {
+			return err
+		}
+		for _, part := range uploaded {
+			checksums[part.ChunkId] = part.Checksum
+		}
+	}
 	cp := []types.CompletedPart{}
 	for i, cuId := range u.ChunkUploadIds {
 		etag := cuId
-		cp = append(cp, types.CompletedPart{
+		part := types.CompletedPart{
 			ETag:       &etag,
 			PartNumber: Ref(int32(i + 1)),
-		})
+		}
+		if checksum, err := hex.DecodeString(checksums[int32(i)]); err == nil && len(checksum) > 0 {
+			part.ChecksumSHA256 = Ref(base64.StdEncoding.EncodeToString(checksum))
+		}
+		cp = append(cp, part)
 	}
 	input := &s3.CompleteMultipartUploadInput{
 		Bucket:   &s3fs.config.S3Bucket,
@@ -700,11 +1138,140 @@ func (s3fs *S3FS) CompleteObjectUpload(u CompletedObjectUploadConfig) error {
 			Parts: cp,
 		},
 	}
-	result, err := s3fs.s3client.CompleteMultipartUpload(context.TODO(), input)
-	fmt.Print(result)
+	_, err = client.CompleteMultipartUpload(context.TODO(), input)
+	if err != nil || !verify {
+		return err
+	}
+	verified := false
+	if len(u.PartHashes) > 0 {
+		verified, err = s3fs.verifyPartChecksums(client, s3path, u.PartHashes)
+	}
+	if err == nil && !verified && u.ExpectedHash != "" {
+		//the object has no part checksums to compare, so it is read back
+		err = verifyObjectHash(s3fs, u.ObjectPath, u.HashAlgorithm, u.ExpectedHash)
+	}
+	var mismatch *HashMismatchError
+	if errors.As(err, &mismatch) {
+		_, derr := client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+			Bucket: &s3fs.config.S3Bucket,
+			Key:    &s3path,
+		})
+		if derr != nil {
+			s3fs.getLogger().Printf("Failed to delete corrupt upload %s: %s\n", s3path, derr)
+		}
+	}
 	return err
 }
 
+// compares the part checksums S3 records for a completed multipart object with the expected
+// hex encoded SHA256 part hashes.  verified is false when the object has no part checksums
+func (s3fs *S3FS) verifyPartChecksums(client *s3.Client, s3path string, expected []string) (bool, error) {
+	input := &s3.GetObjectAttributesInput{
+		Bucket:           &s3fs.config.S3Bucket,
+		Key:              &s3path,
+		ObjectAttributes: []types.ObjectAttributes{types.ObjectAttributesObjectParts, types.ObjectAttributesChecksum},
+	}
+	actual := []string{}
+	for {
+		output, err := client.GetObjectAttributes(context.TODO(), input)
+		if err != nil {
+			return false, err
+		}
+		if output.ObjectParts == nil {
+			return false, nil
+		}
+		for _, part := range output.ObjectParts.Parts {
+			if part.ChecksumSHA256 == nil {
+				return false, nil
+			}
+			actual = append(actual, checksumHex(part.ChecksumSHA256))
+		}
+		if output.ObjectParts.IsTruncated == nil || !*output.ObjectParts.IsTruncated {
+			break
+		}
+		input.PartNumberMarker = output.ObjectParts.NextPartNumberMarker
+	}
+	if len(actual) == 0 {
+		return false, nil
+	}
+	if len(actual) != len(expected) {
+		return true, &HashMismatchError{
+			Path:     s3path,
+			Expected: fmt.Sprintf("%d parts", len(expected)),
+			Actual:   fmt.Sprintf("%d parts", len(actual)),
+		}
+	}
+	for i, hash := range expected {
+		if !strings.EqualFold(hash, actual[i]) {
+			return true, &HashMismatchError{Path: fmt.Sprintf("%s part %d", s3path, i+1), Expected: hash, Actual: actual[i]}
+		}
+	}
+	return true, nil
+}
+
+// converts a base64 S3 checksum to hex
+func checksumHex(checksum *string) string {
+	if checksum == nil {
+		return ""
+	}
+	sum, err := base64.StdEncoding.DecodeString(*checksum)
+	if err != nil {
+		return ""
+	}
+	return hex.EncodeToString(sum)
+}
+
+func (s3fs *S3FS) AbortObjectUpload(u UploadConfig) error {
+	client, err := s3fs.client()
+	if err != nil {
+		return err
+	}
+	s3path := strings.TrimPrefix(u.ObjectPath, "/")
+	input := &s3.AbortMultipartUploadInput{
+		Bucket:   &s3fs.config.S3Bucket,
+		Key:      &s3path,
+		UploadId: &u.UploadId,
+	}
+	_, err = client.AbortMultipartUpload(context.TODO(), input)
+	return err
+}
+
+func (s3fs *S3FS) ListUploadParts(u UploadConfig) ([]UploadPart, error) {
+	client, err := s3fs.client()
+	if err != nil {
+		return nil, err
+	}
+	s3path := strings.TrimPrefix(u.ObjectPath, "/")
+	input := &s3.ListPartsInput{
+		Bucket:   &s3fs.config.S3Bucket,
+		Key:      &s3path,
+		UploadId: &u.UploadId,
+	}
+	parts := []UploadPart{}
+	paginator := s3.NewListPartsPaginator(client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range page.Parts {
+			part := UploadPart{}
+			if p.PartNumber != nil {
+				part.ChunkId = *p.PartNumber - 1 //aws chunks are 1 to n, our chunks are 0 referenced
+			}
+			if p.Size != nil {
+				part.Size = *p.Size
+			}
+			if p.ETag != nil {
+				part.ETag = *p.ETag
+			}
+			part.Checksum = checksumHex(p.ChecksumSHA256)
+			parts = append(parts, part)
+		}
+	}
+	return parts, nil
+}
+
 func (s3fs *S3FS) Walk(input WalkInput, vistorFunction FileVisitFunction) error {
 	s3Path := strings.TrimPrefix(input.Path.Path, "/")
 	s3delim := ""
@@ -714,11 +1281,16 @@ func (s3fs *S3FS) Walk(input WalkInput, vistorFunction FileVisitFunction) error
 		Delimiter: &s3delim,
 		MaxKeys:   &s3fs.maxKeys,
 	}
+	if input.StartAfter != "" {
+		//s3 walks in lexicographic key order, so the checkpoint maps directly to StartAfter
+		startAfter := strings.TrimPrefix(input.StartAfter, "/")
+		query.StartAfter = &startAfter
+	}
 
 	truncatedListing := true
 	count := 0
 	for truncatedListing {
-		resp, err := s3fs.s3client.ListObjectsV2(context.TODO(), query)
+		resp, err := s3fs.listObjects(context.TODO(), query)
 		if err != nil {
 			return err
 		}
@@ -727,19 +1299,18 @@ func (s3fs *S3FS) Walk(input WalkInput, vistorFunction FileVisitFunction) error
 			fileInfo := &S3FileInfo{&obj}
 			err = vistorFunction("/"+*obj.Key, fileInfo)
 			if err != nil {
-				log.Printf("Visitor Function error: %s\n", err)
+				s3fs.getLogger().Printf("Visitor Function error: %s\n", err)
 			}
-			if input.Progress != nil {
-				input.Progress(ProgressData{
-					Index: count,
-					Max:   -1,
-					Value: fileInfo,
-				})
+			err = reportProgress(input.Progress, ProgressData{
+				Index: count,
+				Max:   -1,
+				Value: fileInfo,
+			})
+			if err != nil {
+				return err
 			}
 		}
-		if !s3fs.ignoreContinuationOnWalk {
-			query.ContinuationToken = resp.NextContinuationToken
-		}
+		query.ContinuationToken = resp.NextContinuationToken
 		if resp.IsTruncated == nil {
 			truncatedListing = false
 		} else {
@@ -755,14 +1326,40 @@ these functions are not part of the filestore interface and are unique to the S3
 */
 
 func (s3fs *S3FS) GetPresignedUrl(path PathConfig, days int) (string, error) {
-	s3Path := strings.TrimPrefix(path.Path, "/")
-	presignClient := s3.NewPresignClient(s3fs.s3client)
+	return s3fs.PresignGetObject(PresignGetInput{
+		Path:    path,
+		Expires: time.Duration(24*days) * time.Hour,
+	})
+}
+
+type PresignGetInput struct {
+	Path PathConfig
+
+	//lifetime of the url
+	Expires time.Duration
+
+	//optional file name.  Downloads of the url are saved as an attachment with this name
+	DownloadName string
+}
+
+// PresignGetObject creates a presigned download url for an object
+func (s3fs *S3FS) PresignGetObject(pgi PresignGetInput) (string, error) {
+	client, err := s3fs.client()
+	if err != nil {
+		return "", err
+	}
+	s3Path := strings.TrimPrefix(pgi.Path.Path, "/")
+	presignClient := s3.NewPresignClient(client)
 	input := &s3.GetObjectInput{
 		Bucket: &s3fs.config.S3Bucket,
 		Key:    &s3Path,
 	}
+	if pgi.DownloadName != "" {
+		disposition := mime.FormatMediaType("attachment", map[string]string{"filename": pgi.DownloadName})
+		input.ResponseContentDisposition = &disposition
+	}
 	request, err := presignClient.PresignGetObject(context.TODO(), input, func(opts *s3.PresignOptions) {
-		opts.Expires = time.Duration(time.Duration(24*days) * time.Hour)
+		opts.Expires = pgi.Expires
 	})
 
 	if err != nil {
@@ -772,6 +1369,10 @@ func (s3fs *S3FS) GetPresignedUrl(path PathConfig, days int) (string, error) {
 }
 
 func (s3fs *S3FS) SetObjectPublic(path PathConfig) (string, error) {
+	client, err := s3fs.client()
+	if err != nil {
+		return "", err
+	}
 	s3Path := strings.TrimPrefix(path.Path, "/")
 	acl := types.ObjectCannedACLPublicRead
 	input := &s3.PutObjectAclInput{
@@ -779,21 +1380,59 @@ func (s3fs *S3FS) SetObjectPublic(path PathConfig) (string, error) {
 		Key:    &s3Path,
 		ACL:    acl,
 	}
-	aclResp, err := s3fs.s3client.PutObjectAcl(context.TODO(), input)
+	aclResp, err := client.PutObjectAcl(context.TODO(), input)
 	if err != nil {
-		log.Printf("Failed to add public-read ACL on %s\n", s3Path)
-		log.Println(aclResp)
+		s3fs.getLogger().Printf("Failed to add public-read ACL on %s\n", s3Path)
+		s3fs.getLogger().Println(aclResp)
 	}
 	url := fmt.Sprintf("https://%s.s3.amazonaws.com/%s", s3fs.config.S3Bucket, s3Path)
-	log.Println(url)
+	s3fs.getLogger().Println(url)
 	return url, err
 }
 
 /////util functions
 
-func buildCopySourceRange(start int64, objectSize int64) string {
-	end := start + max_copy_chunk_size - 1
-	if end > objectSize {
+// reports whether a conditional request was rejected because its precondition failed
+func isPreconditionFailed(err error) bool {
+	var responseErr *awshttp.ResponseError
+	if errors.As(err, &responseErr) {
+		return responseErr.HTTPStatusCode() == http.StatusPreconditionFailed
+	}
+	return false
+}
+
+// computes the base64 encoded md5 of a seekable reader and
+// returns the reader to its starting position
+func contentMD5(reader io.ReadSeeker) (string, error) {
+	start, err := reader.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return "", err
+	}
+	h := md5.New()
+	if _, err = io.Copy(h, reader); err != nil {
+		return "", err
+	}
+	if _, err = reader.Seek(start, io.SeekStart); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// returns the CopySource of an object, escaping each key segment so keys with spaces,
+// plus signs, or other reserved characters are copied from the right object.  S3 decodes
+// a plus sign in the source as a space, so plus signs are escaped as well
+func buildCopySource(bucket string, key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = strings.ReplaceAll(url.PathEscape(segment), "+", "%2B")
+	}
+	return bucket + "/" + strings.Join(segments, "/")
+}
+
+// returns the inclusive byte range of the part starting at start.  The last part ends at objectSize-1
+func buildCopySourceRange(start int64, partSize int64, objectSize int64) string {
+	end := start + partSize - 1
+	if end > objectSize-1 {
 		end = objectSize - 1
 	}
 	startRange := strconv.FormatInt(start, 10)
@@ -801,6 +1440,16 @@ func buildCopySourceRange(start int64, objectSize int64) string {
 	return "bytes=" + startRange + "-" + stopRange
 }
 
+// returns the part size for a multipart copy.  Parts grow past max_copy_chunk_size
+// when needed to stay within the S3 limit of max_upload_parts parts
+func copyPartSize(objectSize int64) int64 {
+	partSize := int64(max_copy_chunk_size)
+	if objectSize > partSize*max_upload_parts {
+		partSize = (objectSize + max_upload_parts - 1) / max_upload_parts
+	}
+	return partSize
+}
+
 /*
  create prrfix/object slices
  while shouldcontinue
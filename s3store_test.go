@@ -4,9 +4,14 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
 )
 
 var testout string = `&[{0 10  filestore_tests/10/  true 0001-01-01 00:00:00 +0000 UTC } {1 2D Unsteady Flow Hydraulics  filestore_tests/2D Unsteady Flow Hydraulics/  true 0001-01-01 00:00:00 +0000 UTC } {2 filestore_tests 0 filestore_tests  false 2023-10-06 21:02:48 +0000 UTC } {3 Archive.zip 73501923 filestore_tests .zip false 2023-10-18 19:56:14 +0000 UTC } {4 Archive2.zip 73501923 filestore_tests .zip false 2023-10-19 13:05:36 +0000 UTC } {5 image1.jpg 177142 filestore_tests .jpg false 2023-10-06 21:07:11 +0000 UTC }]`
@@ -63,6 +68,85 @@ func TestStaticCreds(t *testing.T) {
 	fmt.Println(dirs)
 }
 
+// routes an S3 store to a test server in place of the AWS endpoint
+func testEndpoint(url string) []func(*config.LoadOptions) error {
+	resolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...any) (aws.Endpoint, error) {
+		return aws.Endpoint{URL: url, SigningRegion: region, HostnameImmutable: true}, nil
+	})
+	return []func(*config.LoadOptions) error{config.WithEndpointResolverWithOptions(resolver)}
+}
+
+func TestAnonymousCreds(t *testing.T) {
+	signed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signed = signed || r.Header.Get("Authorization") != ""
+		fmt.Fprint(w, "<ListBucketResult><Name>noaa-ghcn-pds</Name><IsTruncated>false</IsTruncated>"+
+			"<Contents><Key>readme.txt</Key><Size>12</Size><LastModified>2023-10-06T21:02:48Z</LastModified></Contents>"+
+			"<CommonPrefixes><Prefix>csv/</Prefix></CommonPrefixes></ListBucketResult>")
+	}))
+	defer server.Close()
+	config := S3FSConfig{
+		Credentials: S3FS_Anonymous{},
+		S3Region:    "us-east-1",
+		S3Bucket:    "noaa-ghcn-pds",
+		AwsOptions:  testEndpoint(server.URL),
+	}
+
+	fs, err := NewFileStore(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirs, err := fs.ListDir(ListDirInput{Path: PathConfig{Path: "/"}, Size: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*dirs) != 2 || (*dirs)[0].Name != "csv" || !(*dirs)[0].IsDir || (*dirs)[1].Name != "readme.txt" || (*dirs)[1].Size != "12" {
+		t.Fatalf("unexpected listing %+v", *dirs)
+	}
+	if signed {
+		t.Fatal("expected anonymous requests to be unsigned")
+	}
+}
+
+func TestAutoDetectRegion(t *testing.T) {
+	var regions []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//the region is the third element of the credential scope
+		if scope := strings.Split(r.Header.Get("Authorization"), "/"); len(scope) > 2 {
+			regions = append(regions, scope[2])
+		}
+		w.Header().Set("X-Amz-Bucket-Region", "us-east-1")
+		if r.Method == http.MethodHead {
+			return
+		}
+		fmt.Fprint(w, "<ListBucketResult><Name>noaa-ghcn-pds</Name><IsTruncated>false</IsTruncated></ListBucketResult>")
+	}))
+	defer server.Close()
+	config := S3FSConfig{
+		Credentials:      S3FS_Static{S3Id: "id", S3Key: "key"},
+		S3Region:         "us-west-2",
+		S3Bucket:         "noaa-ghcn-pds",
+		AutoDetectRegion: true,
+		AwsOptions:       testEndpoint(server.URL),
+	}
+
+	fs, err := NewFileStore(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	region := fs.(*S3FS).GetConfig().S3Region
+	if region != "us-east-1" {
+		t.Fatalf("expected the bucket region to be detected, got %s", region)
+	}
+	_, err = fs.ListDir(ListDirInput{Path: PathConfig{Path: "/"}, Size: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(regions) == 0 || regions[len(regions)-1] != "us-east-1" {
+		t.Fatalf("expected requests to be signed for the detected region, got %v", regions)
+	}
+}
+
 func TestListDir(t *testing.T) {
 	config := S3FSConfig{
 		Credentials: S3FS_Attached{
@@ -226,11 +310,12 @@ func TestGetObjectSlice(t *testing.T) {
 
 func TestResourceName(t *testing.T) {
 	config := S3FSConfig{
-		Credentials: S3FS_Attached{
-			Profile: testProfile,
+		Credentials: S3FS_Static{
+			S3Id:  "id",
+			S3Key: "key",
 		},
-		S3Region: os.Getenv("AWS_REGION"),
-		S3Bucket: os.Getenv("AWS_BUCKET"),
+		S3Region: "us-east-1",
+		S3Bucket: "resource-bucket",
 	}
 
 	fs, err := NewFileStore(config)
@@ -238,7 +323,7 @@ func TestResourceName(t *testing.T) {
 		t.Fatal(err)
 	}
 	name := fs.ResourceName()
-	testname := os.Getenv("AWS_BUCKET")
+	testname := "resource-bucket"
 	if name != testname {
 		t.Fatalf(`Failed Test GetObject, got %s expected %s\n`, name, testname)
 	}
@@ -0,0 +1,134 @@
+package filesapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// serves the first half of the test object then stalls until the client goes away
+func stallingServer(t *testing.T, closed chan<- struct{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && !r.URL.Query().Has("attributes") && !r.URL.Query().Has("list-type") {
+			w.Header().Set("Content-Length", strconv.Itoa(len(testObjectString)))
+			w.WriteHeader(http.StatusOK)
+			io.WriteString(w, testObjectString[:5])
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-r.Context().Done():
+			if closed != nil {
+				closed <- struct{}{}
+			}
+		case <-time.After(10 * time.Second):
+			t.Error("request was not cancelled")
+		}
+	}))
+}
+
+func stallingStore(t *testing.T, server *httptest.Server) FileStore {
+	store, err := NewFileStore(MinioFSConfig{
+		S3FSConfig: S3FSConfig{
+			S3Region:    "us-east-1",
+			S3Bucket:    "bucket",
+			Credentials: S3FS_Static{S3Id: "id", S3Key: "key"},
+		},
+		HostAddress: server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestS3GetObjectBodyTimeout(t *testing.T) {
+	server := stallingServer(t, nil)
+	defer server.Close()
+	store := stallingStore(t, server)
+
+	reader, err := store.GetObject(GetObjectInput{
+		Path:    PathConfig{Path: "/a.txt"},
+		Options: &CallOptions{Timeout: 200 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	start := time.Now()
+	data, err := io.ReadAll(reader)
+	if err == nil {
+		t.Fatalf("expected the body read to time out, read %q", data)
+	}
+	if string(data) != testObjectString[:5] {
+		t.Fatalf("expected the bytes sent before the stall, got %q", data)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("body read was not bounded by the call timeout: %s", elapsed)
+	}
+}
+
+func TestS3GetObjectCloseCancels(t *testing.T) {
+	closed := make(chan struct{}, 1)
+	server := stallingServer(t, closed)
+	defer server.Close()
+	store := stallingStore(t, server)
+
+	reader, err := store.GetObject(GetObjectInput{Path: PathConfig{Path: "/a.txt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 5)
+	if _, err = io.ReadFull(reader, buf); err != nil {
+		t.Fatal(err)
+	}
+	reader.Close()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("closing the body did not cancel the request")
+	}
+}
+
+func TestS3CallTimeout(t *testing.T) {
+	server := stallingServer(t, nil)
+	defer server.Close()
+	store := stallingStore(t, server)
+	options := &CallOptions{Timeout: 200 * time.Millisecond}
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"delete", func() error {
+			errs := store.DeleteObjects(DeleteObjectInput{Paths: PathConfig{Path: "/a.txt"}, Options: options})
+			if len(errs) == 0 {
+				return nil
+			}
+			return errs[0]
+		}},
+		{"copy", func() error {
+			return store.CopyObject(CopyObjectInput{Src: PathConfig{Path: "/a.txt"}, Dest: PathConfig{Path: "/b.txt"}, Options: options})
+		}},
+		{"list", func() error {
+			_, err := store.ListDir(ListDirInput{Path: PathConfig{Path: "/"}, Options: options})
+			return err
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			start := time.Now()
+			err := test.call()
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("expected a deadline exceeded error, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Fatalf("call was not bounded by the timeout: %s", elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package filesapi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// default number of objects updated concurrently by UpdatePrefix
+const defaultUpdateConcurrency int = 8
+
+// ObjectChanges are merged into the existing tags and metadata of an object.
+// A key with an empty value removes the tag or metadata entry
+type ObjectChanges struct {
+	Tags     map[string]string
+	Metadata map[string]string
+
+	//S3 storage class (i.e. STANDARD_IA, GLACIER_IR).  Empty keeps the current class
+	StorageClass string
+}
+
+func (oc ObjectChanges) copyRequired() bool {
+	return len(oc.Metadata) > 0 || oc.StorageClass != ""
+}
+
+type UpdatePrefixInput struct {
+	Prefix  PathConfig
+	Changes ObjectChanges
+
+	//optional filter.  Only objects whose key it accepts are updated
+	Filter func(key string) bool
+
+	//maximum concurrent object updates.  Defaults to defaultUpdateConcurrency
+	Concurrency int
+
+	//reports each updated key.  Returning an error stops the update
+	Progress ProgressFunction
+}
+
+// UpdateFailure is an object that could not be updated
+type UpdateFailure struct {
+	Key string
+	Err error
+}
+
+// UpdateReport summarizes a prefix update
+type UpdateReport struct {
+	Updated int
+	Failed  []UpdateFailure
+}
+
+// UpdatePrefix applies tag, metadata and storage class changes to every object under a prefix
+// (i.e. to retroactively classify a dataset).  Metadata and storage class changes copy each
+// object onto itself with replace directives, keeping its content headers and encryption.
+// Tag only changes use PutObjectTagging.  Copies are limited to objects up to 5GB, and each
+// copy adds a version on versioned buckets.  The error is only set when listing fails or
+// Progress stops the update
+func (s3fs *S3FS) UpdatePrefix(input UpdatePrefixInput) (*UpdateReport, error) {
+	concurrency := input.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultUpdateConcurrency
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+
+	report := &UpdateReport{}
+	var mu sync.Mutex
+	var progressErr error
+	objects := make(chan types.Object)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for object := range objects {
+				key := aws.ToString(object.Key)
+				err := s3fs.updateObject(ctx, key, aws.ToInt64(object.Size), input.Changes)
+				mu.Lock()
+				if err != nil {
+					report.Failed = append(report.Failed, UpdateFailure{Key: key, Err: err})
+				} else if progressErr == nil {
+					progressErr = reportProgress(input.Progress, ProgressData{
+						Index: report.Updated,
+						Max:   -1,
+						Value: key,
+					})
+					if progressErr != nil {
+						cancel()
+					}
+					report.Updated++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	listErr := func() error {
+		prefix := strings.TrimPrefix(input.Prefix.Path, "/")
+		query := &s3.ListObjectsV2Input{
+			Bucket: &s3fs.config.S3Bucket,
+			Prefix: &prefix,
+		}
+		for {
+			resp, err := s3fs.listObjects(ctx, query)
+			if err != nil {
+				return err
+			}
+			for _, object := range resp.Contents {
+				if input.Filter != nil && !input.Filter(aws.ToString(object.Key)) {
+					continue
+				}
+				select {
+				case objects <- object:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			if resp.IsTruncated == nil || !*resp.IsTruncated {
+				return nil
+			}
+			query.ContinuationToken = resp.NextContinuationToken
+		}
+	}()
+	close(objects)
+	wg.Wait()
+	if progressErr != nil {
+		return report, progressErr
+	}
+	return report, listErr
+}
+
+func (s3fs *S3FS) updateObject(ctx context.Context, key string, size int64, changes ObjectChanges) error {
+	client, err := s3fs.client()
+	if err != nil {
+		return err
+	}
+	if !changes.copyRequired() {
+		if len(changes.Tags) == 0 {
+			return nil
+		}
+		tags, err := s3fs.mergeTags(ctx, key, changes.Tags)
+		if err != nil {
+			return err
+		}
+		tagSet := make([]types.Tag, 0, len(tags))
+		for k, v := range tags {
+			k, v := k, v
+			tagSet = append(tagSet, types.Tag{Key: &k, Value: &v})
+		}
+		_, err = client.PutObjectTagging(ctx, &s3.PutObjectTaggingInput{
+			Bucket:  &s3fs.config.S3Bucket,
+			Key:     &key,
+			Tagging: &types.Tagging{TagSet: tagSet},
+		})
+		return err
+	}
+
+	if size >= max_put_object_copy_size {
+		return fmt.Errorf("Unable to update %s: objects over 5GB cannot be copied in place", key)
+	}
+	head, err := client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: &s3fs.config.S3Bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return err
+	}
+	source := buildCopySource(s3fs.ResourceName(), key)
+	input := &s3.CopyObjectInput{
+		Bucket:               &s3fs.config.S3Bucket,
+		Key:                  &key,
+		CopySource:           &source,
+		MetadataDirective:    types.MetadataDirectiveReplace,
+		Metadata:             mergeChanges(head.Metadata, changes.Metadata),
+		CacheControl:         head.CacheControl,
+		ContentDisposition:   head.ContentDisposition,
+		ContentEncoding:      head.ContentEncoding,
+		ContentLanguage:      head.ContentLanguage,
+		ContentType:          head.ContentType,
+		ServerSideEncryption: head.ServerSideEncryption,
+		SSEKMSKeyId:          head.SSEKMSKeyId,
+		StorageClass:         types.StorageClass(head.StorageClass),
+	}
+	if changes.StorageClass != "" {
+		input.StorageClass = types.StorageClass(changes.StorageClass)
+	}
+	//prefix KMS keys take precedence over the current encryption, and supply the encryption context
+	if err = s3fs.encryptCopy(input); err != nil {
+		return err
+	}
+	if len(changes.Tags) > 0 {
+		tags, err := s3fs.mergeTags(ctx, key, changes.Tags)
+		if err != nil {
+			return err
+		}
+		values := url.Values{}
+		for k, v := range tags {
+			values.Set(k, v)
+		}
+		tagging := values.Encode()
+		input.TaggingDirective = types.TaggingDirectiveReplace
+		input.Tagging = &tagging
+	}
+	_, err = client.CopyObject(ctx, input)
+	return err
+}
+
+func (s3fs *S3FS) mergeTags(ctx context.Context, key string, changes map[string]string) (map[string]string, error) {
+	client, err := s3fs.client()
+	if err != nil {
+		return map[string]string{}, err
+	}
+	output, err := client.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+		Bucket: &s3fs.config.S3Bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		return nil, err
+	}
+	tags := map[string]string{}
+	for _, tag := range output.TagSet {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return mergeChanges(tags, changes), nil
+}
+
+// merges changes into a copy of values.  Empty change values remove the key
+func mergeChanges(values map[string]string, changes map[string]string) map[string]string {
+	merged := make(map[string]string, len(values)+len(changes))
+	for k, v := range values {
+		merged[k] = v
+	}
+	for k, v := range changes {
+		if v == "" {
+			delete(merged, k)
+		} else {
+			merged[k] = v
+		}
+	}
+	return merged
+}
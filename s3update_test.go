@@ -0,0 +1,182 @@
+package filesapi
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type updateObject struct {
+	metadata    map[string]string
+	tags        map[string]string
+	class       string
+	contentType string
+}
+
+// a minimal S3 endpoint that supports listing, tagging and copying objects onto themselves
+type updateServer struct {
+	mu      sync.Mutex
+	objects map[string]*updateObject
+	copies  int
+}
+
+func (s *updateServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+	query := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+	object := s.objects[key]
+	switch {
+	case r.Method == http.MethodGet && query.Get("list-type") == "2":
+		keys := []string{}
+		for k := range s.objects {
+			if strings.HasPrefix(k, query.Get("prefix")) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		fmt.Fprintf(w, "<ListBucketResult><Name>bucket</Name><KeyCount>%d</KeyCount><IsTruncated>false</IsTruncated>", len(keys))
+		for _, k := range keys {
+			fmt.Fprintf(w, "<Contents><Key>%s</Key><Size>1</Size></Contents>", k)
+		}
+		fmt.Fprint(w, "</ListBucketResult>")
+	case r.Method == http.MethodHead:
+		for k, v := range object.metadata {
+			w.Header().Set("x-amz-meta-"+k, v)
+		}
+		w.Header().Set("Content-Type", object.contentType)
+		if object.class != "" {
+			w.Header().Set("x-amz-storage-class", object.class)
+		}
+	case r.Method == http.MethodGet && query.Has("tagging"):
+		fmt.Fprint(w, "<Tagging><TagSet>")
+		for k, v := range object.tags {
+			fmt.Fprintf(w, "<Tag><Key>%s</Key><Value>%s</Value></Tag>", k, v)
+		}
+		fmt.Fprint(w, "</TagSet></Tagging>")
+	case r.Method == http.MethodPut && query.Has("tagging"):
+		var tagging struct {
+			Tags []struct {
+				Key   string
+				Value string
+			} `xml:"TagSet>Tag"`
+		}
+		xml.Unmarshal(body, &tagging)
+		object.tags = map[string]string{}
+		for _, tag := range tagging.Tags {
+			object.tags[tag.Key] = tag.Value
+		}
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		s.copies++
+		//copy sources are url encoded, so unescaped spaces or plus signs name another object
+		source := r.Header.Get("X-Amz-Copy-Source")
+		if unescaped, err := url.PathUnescape(source); err != nil || unescaped != "bucket/"+key || strings.ContainsAny(source, " +") {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, "<Error><Code>NoSuchKey</Code><Message>no such copy source</Message></Error>")
+			return
+		}
+		if r.Header.Get("X-Amz-Metadata-Directive") != "REPLACE" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, "<Error><Code>InvalidRequest</Code><Message>copy to itself</Message></Error>")
+			return
+		}
+		object.metadata = map[string]string{}
+		for k := range r.Header {
+			if strings.HasPrefix(k, "X-Amz-Meta-") {
+				object.metadata[strings.ToLower(strings.TrimPrefix(k, "X-Amz-Meta-"))] = r.Header.Get(k)
+			}
+		}
+		object.class = r.Header.Get("X-Amz-Storage-Class")
+		object.contentType = r.Header.Get("Content-Type")
+		if r.Header.Get("X-Amz-Tagging-Directive") == "REPLACE" {
+			values, _ := url.ParseQuery(r.Header.Get("X-Amz-Tagging"))
+			object.tags = map[string]string{}
+			for k := range values {
+				object.tags[k] = values.Get(k)
+			}
+		}
+		fmt.Fprint(w, "<CopyObjectResult><ETag>\"copy\"</ETag></CopyObjectResult>")
+	}
+}
+
+func TestUpdatePrefix(t *testing.T) {
+	backend := &updateServer{objects: map[string]*updateObject{}}
+	for _, key := range []string{"data/a.csv", "data/b 1+2.csv", "data/c.txt", "other/d.csv"} {
+		backend.objects[key] = &updateObject{
+			metadata:    map[string]string{"owner": "hydrology", "draft": "true"},
+			tags:        map[string]string{"project": "levee"},
+			contentType: "text/csv",
+		}
+	}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+	store, err := NewFileStore(MinioFSConfig{
+		S3FSConfig: S3FSConfig{
+			S3Region:    "us-east-1",
+			S3Bucket:    "bucket",
+			Credentials: S3FS_Static{S3Id: "id", S3Key: "key"},
+		},
+		HostAddress: server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s3fs := store.(*S3FS)
+
+	updated := []string{}
+	report, err := s3fs.UpdatePrefix(UpdatePrefixInput{
+		Prefix: PathConfig{Path: "/data/"},
+		Changes: ObjectChanges{
+			Tags:         map[string]string{"classification": "public"},
+			Metadata:     map[string]string{"reviewed": "2026", "draft": ""},
+			StorageClass: "STANDARD_IA",
+		},
+		Filter: func(key string) bool {
+			return strings.HasSuffix(key, ".csv")
+		},
+		Concurrency: 2,
+		Progress: func(pd ProgressData) error {
+			updated = append(updated, pd.Value.(string))
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Updated != 2 || len(report.Failed) != 0 || len(updated) != 2 || backend.copies != 2 {
+		t.Fatalf("expected 2 updated objects, got %+v after %d copies", report, backend.copies)
+	}
+	a := backend.objects["data/a.csv"]
+	if a.metadata["owner"] != "hydrology" || a.metadata["reviewed"] != "2026" || a.metadata["draft"] != "" {
+		t.Fatalf("unexpected metadata %v", a.metadata)
+	}
+	if a.tags["project"] != "levee" || a.tags["classification"] != "public" {
+		t.Fatalf("unexpected tags %v", a.tags)
+	}
+	if a.class != "STANDARD_IA" || a.contentType != "text/csv" {
+		t.Fatalf("unexpected storage class %s or content type %s", a.class, a.contentType)
+	}
+	if c := backend.objects["data/c.txt"]; c.class != "" || len(c.tags) != 1 {
+		t.Fatal("expected filtered objects to be unchanged")
+	}
+
+	//tag only changes do not copy
+	report, err = s3fs.UpdatePrefix(UpdatePrefixInput{
+		Prefix:  PathConfig{Path: "/other"},
+		Changes: ObjectChanges{Tags: map[string]string{"project": ""}},
+	})
+	if err != nil || report.Updated != 1 {
+		t.Fatalf("unexpected tag update %+v %v", report, err)
+	}
+	if d := backend.objects["other/d.csv"]; len(d.tags) != 0 || backend.copies != 2 {
+		t.Fatalf("expected tags to be removed without a copy, got %v", d.tags)
+	}
+}
@@ -0,0 +1,167 @@
+package filesapi
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// a minimal S3 endpoint for multipart uploads that records part checksums
+type checksumUploadServer struct {
+	mu        sync.Mutex
+	object    []byte
+	parts     map[int][]byte
+	checksums map[int]string
+	completes []string
+	gets      int
+	lists     int
+	deleted   bool
+}
+
+func (s *checksumUploadServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	query := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+	numbers := []int{}
+	for n := range s.parts {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+	partsXml := func() string {
+		var sb strings.Builder
+		for _, n := range numbers {
+			fmt.Fprintf(&sb, "<Part><PartNumber>%d</PartNumber><ETag>\"part-%d\"</ETag><Size>%d</Size>", n, n, len(s.parts[n]))
+			if s.checksums[n] != "" {
+				fmt.Fprintf(&sb, "<ChecksumSHA256>%s</ChecksumSHA256>", s.checksums[n])
+			}
+			sb.WriteString("</Part>")
+		}
+		return sb.String()
+	}
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		s.parts = map[int][]byte{}
+		s.checksums = map[int]string{}
+		fmt.Fprint(w, "<InitiateMultipartUploadResult><Bucket>bucket</Bucket><Key>a.bin</Key><UploadId>upload-1</UploadId></InitiateMultipartUploadResult>")
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		n, _ := strconv.Atoi(query.Get("partNumber"))
+		s.parts[n] = body
+		s.checksums[n] = r.Header.Get("X-Amz-Checksum-Sha256")
+		w.Header().Set("ETag", fmt.Sprintf("\"part-%d\"", n))
+	case r.Method == http.MethodGet && query.Has("uploadId"):
+		s.lists++
+		fmt.Fprintf(w, "<ListPartsResult><Bucket>bucket</Bucket><Key>a.bin</Key><UploadId>upload-1</UploadId><IsTruncated>false</IsTruncated>%s</ListPartsResult>", partsXml())
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		s.completes = append(s.completes, string(body))
+		s.object = nil
+		for _, n := range numbers {
+			s.object = append(s.object, s.parts[n]...)
+		}
+		fmt.Fprint(w, "<CompleteMultipartUploadResult><ETag>\"complete\"</ETag></CompleteMultipartUploadResult>")
+	case r.Method == http.MethodGet && query.Has("attributes"):
+		fmt.Fprintf(w, "<GetObjectAttributesResponse><ObjectSize>%d</ObjectSize><ObjectParts><IsTruncated>false</IsTruncated><PartsCount>%d</PartsCount>%s</ObjectParts></GetObjectAttributesResponse>",
+			len(s.object), len(numbers), partsXml())
+	case r.Method == http.MethodGet:
+		s.gets++
+		w.Write(s.object)
+	case r.Method == http.MethodDelete:
+		s.deleted = true
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3CompleteObjectUploadVerifiesParts(t *testing.T) {
+	parts := [][]byte{[]byte("HELLO "), []byte("WORLD")}
+	partHashes := []string{}
+	for _, part := range parts {
+		sum := sha256.Sum256(part)
+		partHashes = append(partHashes, hex.EncodeToString(sum[:]))
+	}
+	objectSum := sha256.Sum256([]byte("HELLO WORLD"))
+
+	upload := func(server *checksumUploadServer, checksums bool, complete CompletedObjectUploadConfig) error {
+		httpServer := httptest.NewServer(server)
+		defer httpServer.Close()
+		store, err := NewFileStore(MinioFSConfig{
+			S3FSConfig: S3FSConfig{
+				S3Region:    "us-east-1",
+				S3Bucket:    "bucket",
+				Credentials: S3FS_Static{S3Id: "id", S3Key: "key"},
+			},
+			HostAddress: httpServer.URL,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := store.InitializeObjectUpload(UploadConfig{ObjectPath: "/a.bin", Checksums: checksums})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, part := range parts {
+			chunk, err := store.WriteChunk(UploadConfig{ObjectPath: "/a.bin", UploadId: result.ID, ChunkId: int32(i), Data: part, Checksums: checksums})
+			if err != nil {
+				t.Fatal(err)
+			}
+			complete.ChunkUploadIds = append(complete.ChunkUploadIds, chunk.ID)
+		}
+		complete.ObjectPath = "/a.bin"
+		complete.UploadId = result.ID
+		return store.CompleteObjectUpload(complete)
+	}
+
+	//part checksums are sent with each part and the completed upload, and verified without a read
+	server := &checksumUploadServer{}
+	if err := upload(server, true, CompletedObjectUploadConfig{PartHashes: partHashes, ExpectedHash: hex.EncodeToString(objectSum[:])}); err != nil {
+		t.Fatal(err)
+	}
+	first := sha256.Sum256(parts[0])
+	if server.checksums[1] != base64.StdEncoding.EncodeToString(first[:]) {
+		t.Fatalf("expected the part checksum to be sent, got %q", server.checksums[1])
+	}
+	if !strings.Contains(server.completes[0], "<ChecksumSHA256>"+server.checksums[2]+"</ChecksumSHA256>") {
+		t.Fatalf("expected the completed parts to carry their checksums, got %s", server.completes[0])
+	}
+	if server.gets != 0 || server.deleted {
+		t.Fatalf("expected the upload to be verified without reading it back, got %d reads", server.gets)
+	}
+
+	//mismatched parts are deleted
+	server = &checksumUploadServer{}
+	err := upload(server, true, CompletedObjectUploadConfig{PartHashes: []string{partHashes[0], partHashes[0]}})
+	var mismatch *HashMismatchError
+	if !errors.As(err, &mismatch) || mismatch.Actual != partHashes[1] || !server.deleted {
+		t.Fatalf("expected a deleted part mismatch, got %v", err)
+	}
+
+	//objects without part checksums fall back to reading the object
+	server = &checksumUploadServer{}
+	if err = upload(server, false, CompletedObjectUploadConfig{PartHashes: partHashes, ExpectedHash: hex.EncodeToString(objectSum[:])}); err != nil {
+		t.Fatal(err)
+	}
+	if server.gets != 1 {
+		t.Fatalf("expected the object to be read back once, got %d reads", server.gets)
+	}
+
+	//checksums are opt in, and unverified uploads complete without listing their parts
+	server = &checksumUploadServer{}
+	if err = upload(server, false, CompletedObjectUploadConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if server.checksums[1] != "" || server.lists != 0 || strings.Contains(server.completes[0], "ChecksumSHA256") {
+		t.Fatalf("expected an upload without checksums, got %d part listings: %s", server.lists, server.completes[0])
+	}
+	if string(server.object) != "HELLO WORLD" {
+		t.Fatalf("unexpected object %q", server.object)
+	}
+}
@@ -0,0 +1,202 @@
+package filesapi
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type SampleFormat int
+
+const (
+	//detect the format from the object extension
+	SampleAuto SampleFormat = iota
+	SampleCSV
+	SampleParquet
+)
+
+const (
+	defaultSampleRows      int    = 10
+	defaultSampleMaxBytes  int64  = 1024 * 1024
+	initialSampleReadBytes int64  = 64 * 1024
+	parquetMagic           string = "PAR1"
+	parquetTrailerSize     int64  = 8
+)
+
+var ErrUnsupportedSampleFormat = errors.New("unsupported sample format")
+
+type SampleInput struct {
+	Path PathConfig
+
+	//object format.  Defaults to detecting the format from the path extension
+	Format SampleFormat
+
+	//number of csv data rows to return.  Defaults to defaultSampleRows
+	Rows int
+
+	//maximum number of bytes read from the object.  Defaults to defaultSampleMaxBytes
+	MaxBytes int64
+}
+
+type ObjectSample struct {
+	Format SampleFormat
+	Size   int64
+
+	//csv header and leading data rows
+	Header []string
+	Rows   [][]string
+
+	//raw thrift encoded parquet FileMetaData read from the object footer.
+	//decode it with a parquet library to get the schema and row group statistics
+	ParquetFooter []byte
+
+	//number of bytes read from the object
+	BytesRead int64
+
+	//true if fewer rows than requested were returned because MaxBytes was reached
+	Truncated bool
+}
+
+// SampleObject previews a large CSV or Parquet object using ranged GETs rather than a
+// full download.  CSV objects return the header and first rows, Parquet objects return
+// the footer metadata.  Intended for preview endpoints in data browsers
+func SampleObject(store FileStore, input SampleInput) (*ObjectSample, error) {
+	if input.Rows <= 0 {
+		input.Rows = defaultSampleRows
+	}
+	if input.MaxBytes <= 0 {
+		input.MaxBytes = defaultSampleMaxBytes
+	}
+	format := input.Format
+	if format == SampleAuto {
+		format = detectSampleFormat(input.Path.Path)
+	}
+	info, err := store.GetObjectInfo(input.Path)
+	if err != nil {
+		return nil, err
+	}
+	sample := &ObjectSample{Format: format, Size: info.Size()}
+	switch format {
+	case SampleCSV:
+		err = sampleCsv(store, input, sample)
+	case SampleParquet:
+		err = sampleParquet(store, input, sample)
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnsupportedSampleFormat, input.Path.Path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sample, nil
+}
+
+func detectSampleFormat(path string) SampleFormat {
+	lower := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(lower, ".parquet"), strings.HasSuffix(lower, ".parq"):
+		return SampleParquet
+	case strings.HasSuffix(lower, ".csv"), strings.HasSuffix(lower, ".tsv"):
+		return SampleCSV
+	}
+	return SampleAuto
+}
+
+// reads growing windows from the start of the object until enough complete rows are available
+func sampleCsv(store FileStore, input SampleInput, sample *ObjectSample) error {
+	window := initialSampleReadBytes
+	for {
+		if window > input.MaxBytes {
+			window = input.MaxBytes
+		}
+		if window > sample.Size {
+			window = sample.Size
+		}
+		data, err := readObjectRange(store, input.Path, 0, window)
+		if err != nil {
+			return err
+		}
+		sample.BytesRead = int64(len(data))
+		complete := window >= sample.Size
+		if !complete {
+			//drop the trailing partial line
+			data = data[:bytes.LastIndexByte(data, '\n')+1]
+		}
+		records := parseCsvSample(data, input.Path.Path, input.Rows+1)
+		if len(records) > input.Rows || complete || window >= input.MaxBytes {
+			if len(records) > 0 {
+				sample.Header = records[0]
+				sample.Rows = records[1:]
+			}
+			sample.Truncated = len(records) <= input.Rows && !complete
+			return nil
+		}
+		window *= 2
+	}
+}
+
+// parses up to max records, stopping at the first malformed record.
+// a window may end inside a quoted field, so parse errors end the sample rather than fail it
+func parseCsvSample(data []byte, path string, max int) [][]string {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if strings.HasSuffix(strings.ToLower(path), ".tsv") {
+		reader.Comma = '\t'
+	}
+	records := [][]string{}
+	for len(records) < max {
+		record, err := reader.Read()
+		if err != nil {
+			break
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// reads the parquet trailer (footer length and magic) then the footer itself
+func sampleParquet(store FileStore, input SampleInput, sample *ObjectSample) error {
+	if sample.Size < parquetTrailerSize+int64(len(parquetMagic)) {
+		return fmt.Errorf("%s is too small to be a parquet file", input.Path.Path)
+	}
+	trailer, err := readObjectRange(store, input.Path, sample.Size-parquetTrailerSize, parquetTrailerSize)
+	if err != nil {
+		return err
+	}
+	if len(trailer) != int(parquetTrailerSize) || string(trailer[4:]) != parquetMagic {
+		return fmt.Errorf("%s is not a parquet file", input.Path.Path)
+	}
+	footerSize := int64(binary.LittleEndian.Uint32(trailer[:4]))
+	if footerSize > sample.Size-parquetTrailerSize-int64(len(parquetMagic)) {
+		return fmt.Errorf("invalid parquet footer length %d in %s", footerSize, input.Path.Path)
+	}
+	if footerSize+parquetTrailerSize > input.MaxBytes {
+		return fmt.Errorf("parquet footer of %d bytes exceeds the sample limit of %d bytes", footerSize, input.MaxBytes)
+	}
+	footer, err := readObjectRange(store, input.Path, sample.Size-parquetTrailerSize-footerSize, footerSize)
+	if err != nil {
+		return err
+	}
+	sample.ParquetFooter = footer
+	sample.BytesRead = int64(len(trailer) + len(footer))
+	return nil
+}
+
+func readObjectRange(store FileStore, path PathConfig, offset int64, length int64) ([]byte, error) {
+	if length <= 0 {
+		return []byte{}, nil
+	}
+	reader, err := store.GetObject(GetObjectInput{
+		Path:  path,
+		Range: fmt.Sprintf("bytes=%d-%d", offset, offset+length-1),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(io.LimitReader(reader, length))
+}
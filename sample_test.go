@@ -0,0 +1,70 @@
+package filesapi
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSampleObject(t *testing.T) {
+	dir := t.TempDir()
+	block := &BlockFS{}
+
+	var csvData strings.Builder
+	csvData.WriteString("station,stage\n")
+	for i := 0; i < 1000; i++ {
+		csvData.WriteString(fmt.Sprintf("gage-%d,%d.5\n", i, i))
+	}
+	csvPath := PathConfig{Path: dir + "/gages.csv"}
+	if _, err := block.PutObject(PutObjectInput{Source: ObjectSource{Data: []byte(csvData.String())}, Dest: csvPath}); err != nil {
+		t.Fatal(err)
+	}
+	sample, err := SampleObject(block, SampleInput{Path: csvPath, Rows: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sample.Format != SampleCSV || strings.Join(sample.Header, ",") != "station,stage" {
+		t.Fatalf("unexpected csv header %+v", sample)
+	}
+	if len(sample.Rows) != 3 {
+		t.Fatalf("expected 3 rows, got %v", sample.Rows)
+	}
+	for i, row := range sample.Rows {
+		if strings.Join(row, ",") != fmt.Sprintf("gage-%d,%d.5", i, i) {
+			t.Fatalf("unexpected row %d: %v", i, row)
+		}
+	}
+
+	//the byte limit ends the sample early
+	sample, err = SampleObject(block, SampleInput{Path: csvPath, Rows: 500, MaxBytes: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sample.Truncated || sample.BytesRead > 100 || len(sample.Rows) == 0 {
+		t.Fatalf("expected a truncated csv sample %+v", sample)
+	}
+
+	footer := []byte("thrift-file-metadata")
+	parquet := []byte(parquetMagic + "column-chunks")
+	parquet = append(parquet, footer...)
+	trailer := make([]byte, 4)
+	binary.LittleEndian.PutUint32(trailer, uint32(len(footer)))
+	parquet = append(parquet, trailer...)
+	parquet = append(parquet, parquetMagic...)
+	parquetPath := PathConfig{Path: dir + "/gages.parquet"}
+	if _, err = block.PutObject(PutObjectInput{Source: ObjectSource{Data: parquet}, Dest: parquetPath}); err != nil {
+		t.Fatal(err)
+	}
+	sample, err = SampleObject(block, SampleInput{Path: parquetPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(sample.ParquetFooter) != string(footer) {
+		t.Fatalf("unexpected parquet footer %q", sample.ParquetFooter)
+	}
+
+	if _, err = SampleObject(block, SampleInput{Path: csvPath, Format: SampleParquet}); err == nil {
+		t.Fatal("expected an error sampling a csv object as parquet")
+	}
+}
@@ -0,0 +1,354 @@
+package filesapi
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+type TransferSchedulerConfig struct {
+
+	//maximum concurrent transfers across all stores sharing the scheduler.
+	//zero is unlimited
+	MaxConcurrent int
+
+	//maximum transfer rate in bytes per second across all transfers.
+	//zero is unlimited
+	BytesPerSecond int64
+
+	//optional time source
+	Clock Clock
+}
+
+// TransferPriority orders transfers competing for scheduler slots and bandwidth
+type TransferPriority int
+
+const (
+	//user initiated transfers.  Interactive transfers are granted slots
+	//and bandwidth ahead of any waiting batch transfers
+	PriorityInteractive TransferPriority = iota
+
+	//background transfers such as bulk syncs
+	PriorityBatch
+)
+
+// how long a batch transfer backs off while interactive transfers are waiting on bandwidth
+const batchYieldInterval = 10 * time.Millisecond
+
+// TransferScheduler enforces process wide concurrency and bandwidth budgets
+// for uploads, downloads, and copies.  Share one scheduler between the stores
+// used by background jobs (see ScheduledFS) to throttle them relative to
+// interactive traffic.  Interactive transfers preempt batch transfers
+// when slots or bandwidth are contended.  TransferScheduler is safe for concurrent use
+type TransferScheduler struct {
+	config TransferSchedulerConfig
+
+	mu         sync.Mutex
+	active     int
+	waiters    [2][]chan struct{}
+	tokens     float64
+	lastRefill time.Time
+
+	//number of interactive transfers currently waiting on bandwidth
+	interactiveWaiting int
+}
+
+func NewTransferScheduler(config TransferSchedulerConfig) *TransferScheduler {
+	ts := &TransferScheduler{config: config}
+	ts.tokens = float64(config.BytesPerSecond)
+	ts.lastRefill = config.Clock.Now()
+	return ts
+}
+
+// Acquire blocks until an interactive transfer slot is available.  The returned function releases the slot
+func (ts *TransferScheduler) Acquire(ctx context.Context) (func(), error) {
+	return ts.AcquirePriority(ctx, PriorityInteractive)
+}
+
+// AcquirePriority blocks until a transfer slot is available.  Released slots are
+// handed to waiting interactive transfers before waiting batch transfers.
+// The returned function releases the slot
+func (ts *TransferScheduler) AcquirePriority(ctx context.Context, priority TransferPriority) (func(), error) {
+	if ts.config.MaxConcurrent <= 0 {
+		return func() {}, nil
+	}
+	priority = priority.normalize()
+	ts.mu.Lock()
+	if ts.active < ts.config.MaxConcurrent && len(ts.waiters[PriorityInteractive]) == 0 &&
+		(priority == PriorityInteractive || len(ts.waiters[PriorityBatch]) == 0) {
+		ts.active++
+		ts.mu.Unlock()
+		return ts.releaseFunc(), nil
+	}
+	ready := make(chan struct{})
+	ts.waiters[priority] = append(ts.waiters[priority], ready)
+	ts.mu.Unlock()
+
+	select {
+	case <-ready:
+		return ts.releaseFunc(), nil
+	case <-ctx.Done():
+		ts.mu.Lock()
+		defer ts.mu.Unlock()
+		for i, w := range ts.waiters[priority] {
+			if w == ready {
+				ts.waiters[priority] = append(ts.waiters[priority][:i], ts.waiters[priority][i+1:]...)
+				return nil, ctx.Err()
+			}
+		}
+		//the slot was granted while the context was being cancelled
+		ts.releaseLocked()
+		return nil, ctx.Err()
+	}
+}
+
+func (ts *TransferScheduler) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ts.mu.Lock()
+			ts.releaseLocked()
+			ts.mu.Unlock()
+		})
+	}
+}
+
+// hands the released slot to the next waiter in priority order.  Caller must hold ts.mu
+func (ts *TransferScheduler) releaseLocked() {
+	for p := range ts.waiters {
+		if len(ts.waiters[p]) > 0 {
+			next := ts.waiters[p][0]
+			ts.waiters[p] = ts.waiters[p][1:]
+			close(next)
+			return
+		}
+	}
+	ts.active--
+}
+
+// WaitBytes blocks until n interactive bytes may be transferred under the bandwidth budget
+func (ts *TransferScheduler) WaitBytes(ctx context.Context, n int) error {
+	return ts.WaitBytesPriority(ctx, n, PriorityInteractive)
+}
+
+// WaitBytesPriority blocks until n bytes may be transferred under the bandwidth budget.
+// Batch transfers yield while any interactive transfer is waiting on bandwidth
+func (ts *TransferScheduler) WaitBytesPriority(ctx context.Context, n int, priority TransferPriority) error {
+	if ts.config.BytesPerSecond <= 0 {
+		return nil
+	}
+	priority = priority.normalize()
+	if priority == PriorityInteractive {
+		ts.mu.Lock()
+		ts.interactiveWaiting++
+		ts.mu.Unlock()
+		defer func() {
+			ts.mu.Lock()
+			ts.interactiveWaiting--
+			ts.mu.Unlock()
+		}()
+	}
+	remaining := float64(n)
+	burst := float64(ts.config.BytesPerSecond)
+	for remaining > 0 {
+		request := remaining
+		if request > burst {
+			request = burst
+		}
+		ts.mu.Lock()
+		now := ts.config.Clock.Now()
+		ts.tokens += now.Sub(ts.lastRefill).Seconds() * burst
+		if ts.tokens > burst {
+			ts.tokens = burst
+		}
+		ts.lastRefill = now
+		var wait time.Duration
+		if priority == PriorityBatch && ts.interactiveWaiting > 0 {
+			wait = batchYieldInterval
+		} else if ts.tokens >= request {
+			ts.tokens -= request
+			remaining -= request
+			ts.mu.Unlock()
+			continue
+		} else {
+			wait = time.Duration((request - ts.tokens) / burst * float64(time.Second))
+		}
+		ts.mu.Unlock()
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return nil
+}
+
+// Reader returns a reader throttled by the scheduler bandwidth budget at interactive priority
+func (ts *TransferScheduler) Reader(ctx context.Context, r io.Reader) io.Reader {
+	return ts.ReaderPriority(ctx, r, PriorityInteractive)
+}
+
+// ReaderPriority returns a reader throttled by the scheduler bandwidth budget
+func (ts *TransferScheduler) ReaderPriority(ctx context.Context, r io.Reader, priority TransferPriority) io.Reader {
+	if ts.config.BytesPerSecond <= 0 {
+		return r
+	}
+	return &throttledReader{ctx: ctx, reader: r, scheduler: ts, priority: priority}
+}
+
+func (p TransferPriority) normalize() TransferPriority {
+	if p == PriorityBatch {
+		return PriorityBatch
+	}
+	return PriorityInteractive
+}
+
+type throttledReader struct {
+	ctx       context.Context
+	reader    io.Reader
+	scheduler *TransferScheduler
+	priority  TransferPriority
+}
+
+func (tr *throttledReader) Read(p []byte) (int, error) {
+	n, err := tr.reader.Read(p)
+	if n > 0 {
+		if werr := tr.scheduler.WaitBytesPriority(tr.ctx, n, tr.priority); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// releases a transfer slot when the reader is closed
+type scheduledReadCloser struct {
+	io.Reader
+	body    io.ReadCloser
+	release func()
+}
+
+func (s *scheduledReadCloser) Close() error {
+	s.release()
+	return s.body.Close()
+}
+
+// ScheduledFS is a FileStore decorator that runs transfers through a TransferScheduler.
+// Downloads hold a transfer slot until the returned reader is closed
+type ScheduledFS struct {
+	FileStore
+	Scheduler *TransferScheduler
+
+	//priority of transfers made through this store.  Defaults to PriorityInteractive
+	Priority TransferPriority
+}
+
+func NewScheduledFS(store FileStore, scheduler *TransferScheduler) *ScheduledFS {
+	return &ScheduledFS{
+		FileStore: store,
+		Scheduler: scheduler,
+	}
+}
+
+// WithPriority returns a copy of the store that schedules its transfers at the given priority.
+// Use it to give bulk sync jobs a batch view of the same underlying store client
+func (sfs *ScheduledFS) WithPriority(priority TransferPriority) *ScheduledFS {
+	return &ScheduledFS{
+		FileStore: sfs.FileStore,
+		Scheduler: sfs.Scheduler,
+		Priority:  priority,
+	}
+}
+
+func (sfs *ScheduledFS) GetObject(input GetObjectInput) (io.ReadCloser, error) {
+	ctx := context.Background()
+	release, err := sfs.Scheduler.AcquirePriority(ctx, sfs.Priority)
+	if err != nil {
+		return nil, err
+	}
+	body, err := sfs.FileStore.GetObject(input)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return &scheduledReadCloser{
+		Reader:  sfs.Scheduler.ReaderPriority(ctx, body, sfs.Priority),
+		body:    body,
+		release: release,
+	}, nil
+}
+
+func (sfs *ScheduledFS) PutObject(input PutObjectInput) (*FileOperationOutput, error) {
+	ctx := context.Background()
+	release, err := sfs.Scheduler.AcquirePriority(ctx, sfs.Priority)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	input.Source = sfs.throttledSource(ctx, input.Source)
+	return sfs.FileStore.PutObject(input)
+}
+
+func (sfs *ScheduledFS) CopyObject(input CopyObjectInput) error {
+	release, err := sfs.Scheduler.AcquirePriority(context.Background(), sfs.Priority)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return sfs.FileStore.CopyObject(input)
+}
+
+func (sfs *ScheduledFS) MoveObject(input MoveObjectInput) error {
+	release, err := sfs.Scheduler.AcquirePriority(context.Background(), sfs.Priority)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return sfs.FileStore.MoveObject(input)
+}
+
+func (sfs *ScheduledFS) WriteChunk(u UploadConfig) (UploadResult, error) {
+	ctx := context.Background()
+	release, err := sfs.Scheduler.AcquirePriority(ctx, sfs.Priority)
+	if err != nil {
+		return UploadResult{}, err
+	}
+	defer release()
+	if err = sfs.Scheduler.WaitBytesPriority(ctx, len(u.Data), sfs.Priority); err != nil {
+		return UploadResult{}, err
+	}
+	return sfs.FileStore.WriteChunk(u)
+}
+
+// wraps an object source in a throttled reader.  Replayable sources
+// remain replayable by throttling each reader they open
+func (sfs *ScheduledFS) throttledSource(ctx context.Context, source ObjectSource) ObjectSource {
+	if sfs.Scheduler.config.BytesPerSecond <= 0 {
+		return source
+	}
+	if !source.Replayable() {
+		reader, err := source.GetReader()
+		if err != nil {
+			return source
+		}
+		source.Reader = sfs.Scheduler.ReaderPriority(ctx, reader, sfs.Priority)
+		return source
+	}
+	original := source
+	return ObjectSource{
+		ContentLength: source.ContentLength,
+		Open: func() (io.ReadCloser, error) {
+			reader, err := original.GetReader()
+			if err != nil {
+				return nil, err
+			}
+			closer, ok := reader.(io.Closer)
+			if !ok {
+				closer = io.NopCloser(nil)
+			}
+			return &readCloser{sfs.Scheduler.ReaderPriority(ctx, reader, sfs.Priority), closer}, nil
+		},
+	}
+}
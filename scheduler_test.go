@@ -0,0 +1,89 @@
+package filesapi
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestScheduledFS(t *testing.T) {
+	scheduler := NewTransferScheduler(TransferSchedulerConfig{
+		MaxConcurrent:  1,
+		BytesPerSecond: 1000,
+	})
+	store := NewScheduledFS(&BlockFS{Config: BlockFSConfig{ChunkSize: 100}}, scheduler)
+	path := t.TempDir() + "/data.bin"
+
+	data := bytes.Repeat([]byte("a"), 1500)
+	_, err := store.PutObject(PutObjectInput{
+		Source: ObjectSource{Data: data},
+		Dest:   PathConfig{Path: path},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	reader, err := store.GetObject(GetObjectInput{Path: PathConfig{Path: path}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the single slot is held until the reader is closed
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := scheduler.Acquire(ctx); err == nil {
+		t.Fatal("expected acquire to block while a download is open")
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader.Close()
+	if !bytes.Equal(body, data) {
+		t.Fatal("downloaded data does not match")
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Fatalf("expected transfers to be throttled, took %s", elapsed)
+	}
+
+	release, err := scheduler.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+}
+
+func TestTransferSchedulerPriority(t *testing.T) {
+	scheduler := NewTransferScheduler(TransferSchedulerConfig{MaxConcurrent: 1})
+	release, err := scheduler.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := make(chan TransferPriority, 2)
+	acquire := func(priority TransferPriority) {
+		r, err := scheduler.AcquirePriority(context.Background(), priority)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		order <- priority
+		r()
+	}
+	go acquire(PriorityBatch)
+	time.Sleep(20 * time.Millisecond)
+	go acquire(PriorityInteractive)
+	time.Sleep(20 * time.Millisecond)
+
+	release()
+	if first := <-order; first != PriorityInteractive {
+		t.Fatal("expected the interactive transfer to preempt the waiting batch transfer")
+	}
+	if second := <-order; second != PriorityBatch {
+		t.Fatal("expected the batch transfer to run after the interactive transfer")
+	}
+}
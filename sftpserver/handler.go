@@ -0,0 +1,329 @@
+// Package sftpserver exposes a filesapi.FileStore to SFTP clients, so legacy tools that
+// only speak SFTP can read and write S3 buckets and block file systems.
+//
+// SFTP requests are mapped onto the store: ls and stat list the store, get reads objects
+// with ranged requests, put stages the upload in a temporary file and stores it when the
+// client closes the file, and rm, mkdir, rmdir, and rename delete, create, and copy objects.
+// Links and attribute changes are not supported.
+//
+// The SSH server and SFTP protocol come from golang.org/x/crypto/ssh and github.com/pkg/sftp.
+// The package is its own module, so applications that do not serve SFTP do not depend on them
+package sftpserver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/usace/filesapi"
+)
+
+// files larger than this are written to object stores with multipart uploads
+const multipartThreshold int64 = 64 * 1024 * 1024
+
+var errUnsupported = errors.New("operation not supported by the store")
+
+type Config struct {
+
+	//store exposed to clients
+	Store filesapi.FileStore
+
+	//store path shown to clients as the root directory
+	Root string
+
+	//reject writes, removals, and renames
+	ReadOnly bool
+}
+
+// handler maps the SFTP request methods onto a store
+type handler struct {
+	config Config
+	fsys   *filesapi.IOFS
+}
+
+func newHandler(config Config) (*handler, error) {
+	if config.Store == nil {
+		return nil, errors.New("sftp server requires a store")
+	}
+	return &handler{config: config, fsys: filesapi.NewIOFS(config.Store, config.Root)}, nil
+}
+
+// store path of a client path
+func (h *handler) storePath(p string) string {
+	return path.Join("/", h.config.Root, path.Clean("/"+p))
+}
+
+// io/fs name of a client path
+func ioName(p string) string {
+	n := strings.Trim(path.Clean("/"+p), "/")
+	if n == "" {
+		return "."
+	}
+	return n
+}
+
+func (h *handler) stat(p string) (fs.FileInfo, error) {
+	return h.fsys.Stat(ioName(p))
+}
+
+func (h *handler) writable(op string, p string) error {
+	if h.config.ReadOnly {
+		return &fs.PathError{Op: op, Path: p, Err: fs.ErrPermission}
+	}
+	return nil
+}
+
+// read opens an object for ranged reads
+func (h *handler) read(p string) (io.ReaderAt, error) {
+	info, err := h.stat(p)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &fs.PathError{Op: "open", Path: p, Err: errors.New("is a directory")}
+	}
+	reader, err := filesapi.NewObjectReadSeeker(h.config.Store, filesapi.PathConfig{Path: h.storePath(p)})
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: p, Err: fs.ErrNotExist}
+	}
+	return reader, nil
+}
+
+// write opens an object for writing.  Without truncate the existing content is kept,
+// so clients can resume uploads
+func (h *handler) write(p string, truncate bool) (io.WriterAt, error) {
+	if err := h.writable("open", p); err != nil {
+		return nil, err
+	}
+	info, err := h.stat(p)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	exists := err == nil
+	if exists && info.IsDir() {
+		return nil, &fs.PathError{Op: "open", Path: p, Err: errors.New("is a directory")}
+	}
+	tmp, err := os.CreateTemp("", "filesapi-sftp-*")
+	if err != nil {
+		return nil, err
+	}
+	f := &stagedFile{tmp: tmp, store: h.config.Store, path: h.storePath(p)}
+	if exists && !truncate {
+		if err := f.load(); err != nil {
+			f.discard()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// cmd runs the Setstat, Rename, Rmdir, Mkdir, and Remove methods
+func (h *handler) cmd(method string, p string, target string) error {
+	switch method {
+	case "Setstat":
+		//permissions and times are managed by the store
+		return nil
+	case "Mkdir":
+		if err := h.writable("mkdir", p); err != nil {
+			return err
+		}
+		if _, err := h.stat(p); err == nil {
+			return &fs.PathError{Op: "mkdir", Path: p, Err: fs.ErrExist}
+		}
+		return filesapi.EnsureDir(h.config.Store, h.storePath(p))
+	case "Remove":
+		if err := h.writable("remove", p); err != nil {
+			return err
+		}
+		info, err := h.stat(p)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return &fs.PathError{Op: "remove", Path: p, Err: errors.New("is a directory")}
+		}
+		return h.deleteObject(h.storePath(p))
+	case "Rmdir":
+		if err := h.writable("rmdir", p); err != nil {
+			return err
+		}
+		return h.rmdir(p)
+	case "Rename", "PosixRename":
+		if err := h.writable("rename", p); err != nil {
+			return err
+		}
+		return h.rename(p, target)
+	}
+	return fmt.Errorf("%w: %s", errUnsupported, method)
+}
+
+func (h *handler) rmdir(p string) error {
+	if ioName(p) == "." {
+		return &fs.PathError{Op: "rmdir", Path: p, Err: fs.ErrPermission}
+	}
+	entries, err := h.fsys.ReadDir(ioName(p))
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		//object store folder markers are listed as an entry of their own folder
+		if e.Name() != path.Base(p) || e.IsDir() {
+			return &fs.PathError{Op: "rmdir", Path: p, Err: errors.New("directory not empty")}
+		}
+	}
+	return filesapi.RemoveDir(h.config.Store, h.storePath(p))
+}
+
+// rename moves a file, or copies every object beneath a directory to the target and removes the source
+func (h *handler) rename(p string, target string) error {
+	info, err := h.stat(p)
+	if err != nil {
+		return err
+	}
+	if ioName(p) == "." || ioName(target) == "." {
+		return &fs.PathError{Op: "rename", Path: p, Err: fs.ErrPermission}
+	}
+	if _, err := h.stat(target); err == nil {
+		return &fs.PathError{Op: "rename", Path: target, Err: fs.ErrExist}
+	}
+	src, dest := h.storePath(p), h.storePath(target)
+	if !info.IsDir() {
+		return h.move(src, dest)
+	}
+	if strings.HasPrefix(dest+"/", src+"/") {
+		return &fs.PathError{Op: "rename", Path: target, Err: errors.New("cannot move a directory into itself")}
+	}
+	files, err := filesapi.ListFiles(h.config.Store, src)
+	if err != nil {
+		return err
+	}
+	if err := filesapi.EnsureDir(h.config.Store, dest); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := h.copy(f, dest+strings.TrimPrefix(f, src)); err != nil {
+			return err
+		}
+	}
+	return filesapi.RemoveDir(h.config.Store, src)
+}
+
+func (h *handler) copy(src string, dest string) error {
+	if err := filesapi.EnsureDir(h.config.Store, path.Dir(dest)); err != nil {
+		return err
+	}
+	return h.config.Store.CopyObject(filesapi.CopyObjectInput{
+		Src:  filesapi.PathConfig{Path: src},
+		Dest: filesapi.PathConfig{Path: dest},
+	})
+}
+
+func (h *handler) move(src string, dest string) error {
+	if err := filesapi.EnsureDir(h.config.Store, path.Dir(dest)); err != nil {
+		return err
+	}
+	return h.config.Store.MoveObject(filesapi.MoveObjectInput{
+		Src:  filesapi.PathConfig{Path: src},
+		Dest: filesapi.PathConfig{Path: dest},
+	})
+}
+
+func (h *handler) deleteObject(p string) error {
+	for _, err := range h.config.Store.DeleteObjects(filesapi.DeleteObjectInput{Paths: filesapi.PathConfig{Path: p}}) {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// list runs the List and Stat methods
+func (h *handler) list(method string, p string) (listerAt, error) {
+	switch method {
+	case "List":
+		entries, err := h.fsys.ReadDir(ioName(p))
+		if err != nil {
+			return nil, err
+		}
+		infos := make(listerAt, 0, len(entries))
+		for _, e := range entries {
+			info, err := e.Info()
+			if err != nil {
+				return nil, err
+			}
+			infos = append(infos, info)
+		}
+		return infos, nil
+	case "Stat":
+		info, err := h.stat(p)
+		if err != nil {
+			return nil, err
+		}
+		return listerAt{info}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", errUnsupported, method)
+}
+
+// listerAt pages a directory listing to the client
+type listerAt []fs.FileInfo
+
+func (l listerAt) ListAt(infos []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(infos, l[offset:])
+	if n < len(infos) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// an upload staged in a temporary file, since clients write blocks at any offset and
+// stores only write whole objects.  The object is stored when the file is closed
+type stagedFile struct {
+	tmp   *os.File
+	store filesapi.FileStore
+	path  string
+}
+
+// copies the existing object into the staging file
+func (sf *stagedFile) load() error {
+	reader, err := sf.store.GetObject(filesapi.GetObjectInput{Path: filesapi.PathConfig{Path: sf.path}})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	_, err = io.Copy(sf.tmp, reader)
+	return err
+}
+
+func (sf *stagedFile) WriteAt(p []byte, off int64) (int, error) {
+	return sf.tmp.WriteAt(p, off)
+}
+
+func (sf *stagedFile) Close() error {
+	defer sf.discard()
+	info, err := sf.tmp.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := sf.tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	size := info.Size()
+	_, err = sf.store.PutObject(filesapi.PutObjectInput{
+		Source:   filesapi.ObjectSource{Reader: sf.tmp, ContentLength: &size},
+		Dest:     filesapi.PathConfig{Path: sf.path},
+		Mutipart: size > multipartThreshold,
+	})
+	return err
+}
+
+func (sf *stagedFile) discard() {
+	sf.tmp.Close()
+	os.Remove(sf.tmp.Name())
+}
@@ -0,0 +1,105 @@
+package sftpserver
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/usace/filesapi"
+)
+
+func TestHandler(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	h, err := newHandler(Config{Store: store, Root: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.cmd("Mkdir", "/runs", ""); err != nil {
+		t.Fatal(err)
+	}
+	put := func(p string, truncate bool, off int64, data string) {
+		w, err := h.write(p, truncate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.WriteAt([]byte(data), off); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.(io.Closer).Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	get := func(p string) string {
+		r, err := h.read(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.(io.Closer).Close()
+		data, err := io.ReadAll(io.NewSectionReader(r, 0, 1<<20))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	put("/runs/out.dss", true, 0, "block one|")
+	//resumed upload keeps the existing content
+	put("/runs/out.dss", false, 10, "block two")
+	if got := get("/runs/out.dss"); got != "block one|block two" {
+		t.Fatalf("unexpected content %q", got)
+	}
+
+	list, err := h.list("List", "/runs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	infos := make([]os.FileInfo, 10)
+	n, err := list.ListAt(infos, 0)
+	if err != io.EOF || n != 1 || infos[0].Name() != "out.dss" || infos[0].Size() != 19 {
+		t.Fatalf("unexpected listing %d %v", n, err)
+	}
+	if _, err := h.list("Stat", "/runs/missing.dss"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected a not exist error, got %v", err)
+	}
+	if _, err := h.list("Readlink", "/runs/out.dss"); !errors.Is(err, errUnsupported) {
+		t.Fatalf("expected an unsupported error, got %v", err)
+	}
+
+	if err := h.cmd("Rmdir", "/runs", ""); err == nil {
+		t.Fatal("expected removing a non empty directory to fail")
+	}
+	if err := h.cmd("Rename", "/runs", "/archive"); err != nil {
+		t.Fatal(err)
+	}
+	if got := get("/archive/out.dss"); got != "block one|block two" {
+		t.Fatalf("unexpected renamed content %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(root, "runs")); !os.IsNotExist(err) {
+		t.Fatalf("expected the source directory to be removed, got %v", err)
+	}
+	if err := h.cmd("Remove", "/archive/out.dss", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.cmd("Rmdir", "/archive", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "archive")); !os.IsNotExist(err) {
+		t.Fatalf("expected the directory to be removed, got %v", err)
+	}
+	if err := h.cmd("Symlink", "/a", "/b"); !errors.Is(err, errUnsupported) {
+		t.Fatalf("expected an unsupported error, got %v", err)
+	}
+
+	readOnly, _ := newHandler(Config{Store: store, Root: root, ReadOnly: true})
+	if _, err := readOnly.write("/new.txt", true); !errors.Is(err, fs.ErrPermission) {
+		t.Fatalf("expected a permission error, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package sftpserver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// Handlers returns the SFTP request handlers of a store, for use with sftp.NewRequestServer
+func Handlers(config Config) (sftp.Handlers, error) {
+	h, err := newHandler(config)
+	if err != nil {
+		return sftp.Handlers{}, err
+	}
+	sh := sftpHandler{h}
+	return sftp.Handlers{FileGet: sh, FilePut: sh, FileCmd: sh, FileList: sh}, nil
+}
+
+// Serve answers SFTP requests on a channel until the client disconnects
+func Serve(channel io.ReadWriteCloser, config Config) error {
+	handlers, err := Handlers(config)
+	if err != nil {
+		return err
+	}
+	server := sftp.NewRequestServer(channel, handlers)
+	defer server.Close()
+	if err := server.Serve(); err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// HandleChannel accepts an SSH session channel and serves the sftp subsystem on it.
+// Call it for each new channel of an authenticated ssh.ServerConn
+func HandleChannel(newChannel ssh.NewChannel, config Config) error {
+	if newChannel.ChannelType() != "session" {
+		newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+		return fmt.Errorf("unsupported channel type %s", newChannel.ChannelType())
+	}
+	channel, requests, err := newChannel.Accept()
+	if err != nil {
+		return err
+	}
+	go func(in <-chan *ssh.Request) {
+		for req := range in {
+			//the payload is the length prefixed subsystem name
+			ok := req.Type == "subsystem" && len(req.Payload) > 4 && string(req.Payload[4:]) == "sftp"
+			req.Reply(ok, nil)
+		}
+	}(requests)
+	return Serve(channel, config)
+}
+
+type sftpHandler struct {
+	*handler
+}
+
+func (sh sftpHandler) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	reader, err := sh.read(r.Filepath)
+	return reader, sftpError(err)
+}
+
+func (sh sftpHandler) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	writer, err := sh.write(r.Filepath, r.Pflags().Trunc)
+	return writer, sftpError(err)
+}
+
+func (sh sftpHandler) Filecmd(r *sftp.Request) error {
+	return sftpError(sh.cmd(r.Method, r.Filepath, r.Target))
+}
+
+func (sh sftpHandler) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	lister, err := sh.list(r.Method, r.Filepath)
+	if err != nil {
+		//a nil listerAt in the interface would not compare equal to nil
+		return nil, sftpError(err)
+	}
+	return lister, nil
+}
+
+func sftpError(err error) error {
+	if errors.Is(err, errUnsupported) {
+		return sftp.ErrSSHFxOpUnsupported
+	}
+	return err
+}
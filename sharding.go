@@ -0,0 +1,107 @@
+package filesapi
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strings"
+)
+
+const defaultShardCount int = 16
+
+// KeySharder spreads keys across hashed prefixes so high volume writes
+// (i.e. telemetry) are not limited by S3's per-prefix request rates.
+// A key "gage1/2024/reading.csv" under Prefix "telemetry" is written to
+// "telemetry/0b/gage1/2024/reading.csv" where "0b" is the key's shard.
+// The shard is derived from the key alone, so readers locate an object
+// with ShardKey and listings recover the original keys with UnshardKey or WalkShards
+type KeySharder struct {
+
+	//prefix that the shard prefixes are created under
+	Prefix string
+
+	//number of shard prefixes.  Defaults to defaultShardCount.
+	//changing the shard count remaps existing keys
+	Shards int
+}
+
+func (ks KeySharder) shards() int {
+	if ks.Shards <= 0 {
+		return defaultShardCount
+	}
+	return ks.Shards
+}
+
+func (ks KeySharder) shardName(shard int) string {
+	width := len(fmt.Sprintf("%x", ks.shards()-1))
+	return fmt.Sprintf("%0*x", width, shard)
+}
+
+func (ks KeySharder) shardPrefix(shard int) string {
+	return strings.TrimSuffix(ks.Prefix, "/") + "/" + ks.shardName(shard)
+}
+
+func (ks KeySharder) shardOf(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(ks.shards()))
+}
+
+// ShardKey returns the sharded path for key
+func (ks KeySharder) ShardKey(key string) string {
+	key = strings.TrimPrefix(key, "/")
+	return ks.shardPrefix(ks.shardOf(key)) + "/" + key
+}
+
+// UnshardKey reverses ShardKey.  It returns false if the path is not a sharded key of this sharder
+func (ks KeySharder) UnshardKey(path string) (string, bool) {
+	prefix := strings.TrimPrefix(strings.TrimSuffix(ks.Prefix, "/"), "/")
+	rest := strings.TrimPrefix(path, "/")
+	if prefix != "" {
+		if !strings.HasPrefix(rest, prefix+"/") {
+			return "", false
+		}
+		rest = rest[len(prefix)+1:]
+	}
+	slash := strings.Index(rest, "/")
+	if slash < 0 {
+		return "", false
+	}
+	key := rest[slash+1:]
+	if key == "" || rest[:slash] != ks.shardName(ks.shardOf(key)) {
+		return "", false
+	}
+	return key, true
+}
+
+// ShardPrefixes returns every shard prefix.  Listings of sharded data fan out across them
+func (ks KeySharder) ShardPrefixes() []string {
+	prefixes := make([]string, ks.shards())
+	for i := range prefixes {
+		prefixes[i] = ks.shardPrefix(i) + "/"
+	}
+	return prefixes
+}
+
+// WalkShards walks every shard prefix and visits each object with its original (unsharded) key.
+// Objects under the prefix that do not belong to the sharder are skipped
+func WalkShards(store FileStore, ks KeySharder, vistorFunction FileVisitFunction) error {
+	for _, prefix := range ks.ShardPrefixes() {
+		err := store.Walk(WalkInput{Path: PathConfig{Path: prefix}}, func(path string, file os.FileInfo) error {
+			if file.IsDir() {
+				return nil
+			}
+			key, ok := ks.UnshardKey(path)
+			if !ok {
+				return nil
+			}
+			return vistorFunction(key, file)
+		})
+		//shards that have not been written to do not exist in block file stores
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package filesapi
+
+import (
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestKeySharder(t *testing.T) {
+	dir := t.TempDir()
+	ks := KeySharder{Prefix: dir + "/telemetry", Shards: 4}
+	store := &BlockFS{Config: BlockFSConfig{ChunkSize: 100}}
+
+	keys := []string{"gage1/a.csv", "gage1/b.csv", "gage2/a.csv", "gage3/c.csv", "gage4/d.csv"}
+	shards := map[string]bool{}
+	for _, key := range keys {
+		sharded := ks.ShardKey(key)
+		shards[strings.TrimPrefix(sharded, ks.Prefix)[:3]] = true
+		unsharded, ok := ks.UnshardKey(sharded)
+		if !ok || unsharded != key {
+			t.Fatalf("expected %s to unshard to %s, got %s", sharded, key, unsharded)
+		}
+		_, err := store.PutObject(PutObjectInput{
+			Source: ObjectSource{Data: []byte(key)},
+			Dest:   PathConfig{Path: sharded},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(shards) < 2 {
+		t.Fatal("expected keys to be spread across shards")
+	}
+	if _, ok := ks.UnshardKey(dir + "/telemetry/zz/gage1/a.csv"); ok {
+		t.Fatal("expected a path in the wrong shard to be rejected")
+	}
+
+	walked := []string{}
+	err := WalkShards(store, ks, func(path string, file os.FileInfo) error {
+		walked = append(walked, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(walked)
+	if strings.Join(walked, ",") != strings.Join(keys, ",") {
+		t.Fatalf("expected walked keys %v, got %v", keys, walked)
+	}
+}
@@ -0,0 +1,167 @@
+package filesapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"path"
+	"strings"
+)
+
+type SplitPart struct {
+
+	//part name relative to the manifest
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// SplitManifest describes an object split into parts by SplitObject
+type SplitManifest struct {
+	Source   string      `json:"source"`
+	Size     int64       `json:"size"`
+	SHA256   string      `json:"sha256"`
+	PartSize int64       `json:"partSize"`
+	Parts    []SplitPart `json:"parts"`
+}
+
+// SplitObject splits src into parts of at most partSize bytes under destPrefix, for
+// moving large objects through systems with per-file size limits.  Parts are named
+// <name>.part0000, <name>.part0001, ... and a <name>.manifest.json manifest records
+// the size and checksum of each part and of the whole object.  Use JoinObject to reassemble
+func SplitObject(store FileStore, src PathConfig, partSize int64, destPrefix string) (*SplitManifest, error) {
+	if partSize <= 0 {
+		return nil, errors.New("split part size must be greater than zero")
+	}
+	info, err := store.GetObjectInfo(src)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := store.GetObject(GetObjectInput{Path: src})
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	name := path.Base(src.Path)
+	prefix := strings.TrimSuffix(destPrefix, "/") + "/"
+	manifest := &SplitManifest{Source: src.Path, Size: info.Size(), PartSize: partSize, Parts: []SplitPart{}}
+	whole := sha256.New()
+	body := io.TeeReader(reader, whole)
+	for offset := int64(0); offset < info.Size() || len(manifest.Parts) == 0; offset += partSize {
+		size := info.Size() - offset
+		if size > partSize {
+			size = partSize
+		}
+		part := SplitPart{Name: fmt.Sprintf("%s.part%04d", name, len(manifest.Parts)), Size: size}
+		h := sha256.New()
+		_, err = store.PutObject(PutObjectInput{
+			Source: ObjectSource{Reader: io.TeeReader(io.LimitReader(body, size), h), ContentLength: &size},
+			Dest:   PathConfig{Path: prefix + part.Name},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("Failed to write part %s: %w", part.Name, err)
+		}
+		part.SHA256 = hex.EncodeToString(h.Sum(nil))
+		manifest.Parts = append(manifest.Parts, part)
+	}
+	manifest.SHA256 = hex.EncodeToString(whole.Sum(nil))
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	_, err = store.PutObject(PutObjectInput{
+		Source: ObjectSource{Data: data},
+		Dest:   PathConfig{Path: prefix + name + ".manifest.json"},
+	})
+	return manifest, err
+}
+
+// JoinObject reassembles the parts listed in a SplitObject manifest into dest.
+// Each part and the joined object are verified against the manifest checksums;
+// dest is deleted if verification fails
+func JoinObject(store FileStore, manifestPath PathConfig, dest PathConfig) (*SplitManifest, error) {
+	reader, err := store.GetObject(GetObjectInput{Path: manifestPath})
+	if err != nil {
+		return nil, err
+	}
+	manifest := &SplitManifest{}
+	err = json.NewDecoder(reader).Decode(manifest)
+	reader.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	dir := manifestPath.Path[:strings.LastIndex(manifestPath.Path, "/")+1]
+	readers := []io.Reader{}
+	for _, part := range manifest.Parts {
+		readers = append(readers, &verifyingReader{
+			reader:   &lazyObjectReader{store: store, path: PathConfig{Path: dir + part.Name}},
+			hash:     sha256.New(),
+			path:     dir + part.Name,
+			expected: part.SHA256,
+			size:     part.Size,
+		})
+	}
+	joined := &verifyingReader{
+		reader:   io.MultiReader(readers...),
+		hash:     sha256.New(),
+		path:     dest.Path,
+		expected: manifest.SHA256,
+		size:     manifest.Size,
+	}
+	_, err = store.PutObject(PutObjectInput{
+		Source:   ObjectSource{Reader: joined},
+		Dest:     dest,
+		Mutipart: true,
+	})
+	for _, r := range readers {
+		r.(*verifyingReader).reader.(*lazyObjectReader).close()
+	}
+	if err == nil {
+		err = joined.err
+	}
+	if err != nil {
+		store.DeleteObjects(DeleteObjectInput{Paths: PathConfig{Paths: []string{dest.Path}}})
+		return manifest, err
+	}
+	return manifest, nil
+}
+
+// verifies the size and sha256 of a stream when it reaches EOF
+type verifyingReader struct {
+	reader   io.Reader
+	hash     hash.Hash
+	path     string
+	expected string
+	size     int64
+	read     int64
+	err      error
+}
+
+func (vr *verifyingReader) Read(p []byte) (int, error) {
+	if vr.err != nil {
+		return 0, vr.err
+	}
+	n, err := vr.reader.Read(p)
+	vr.hash.Write(p[:n])
+	vr.read += int64(n)
+	if err == io.EOF {
+		actual := hex.EncodeToString(vr.hash.Sum(nil))
+		switch {
+		case vr.read != vr.size:
+			vr.err = fmt.Errorf("size mismatch for %s: expected %d, got %d", vr.path, vr.size, vr.read)
+		case !strings.EqualFold(actual, vr.expected):
+			vr.err = &HashMismatchError{Path: vr.path, Expected: vr.expected, Actual: actual}
+		}
+		if vr.err != nil {
+			return n, vr.err
+		}
+	}
+	return n, err
+}
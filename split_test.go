@@ -0,0 +1,52 @@
+package filesapi
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestSplitAndJoinObject(t *testing.T) {
+	dir := t.TempDir()
+	block := &BlockFS{}
+	src := PathConfig{Path: dir + "/data/model.dss"}
+	content := "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	if _, err := block.PutObject(PutObjectInput{Source: ObjectSource{Data: []byte(content)}, Dest: src}); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := SplitObject(block, src, 10, dir+"/parts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Parts) != 4 || manifest.Parts[3].Size != 6 || manifest.Size != int64(len(content)) {
+		t.Fatalf("unexpected manifest %+v", manifest)
+	}
+	data, err := os.ReadFile(dir + "/parts/model.dss.part0001")
+	if err != nil || string(data) != "ABCDEFGHIJ" {
+		t.Fatalf("unexpected part %q %v", data, err)
+	}
+
+	manifestPath := PathConfig{Path: dir + "/parts/model.dss.manifest.json"}
+	dest := PathConfig{Path: dir + "/joined/model.dss"}
+	if _, err = JoinObject(block, manifestPath, dest); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(dest.Path)
+	if err != nil || string(data) != content {
+		t.Fatalf("unexpected joined object %q %v", data, err)
+	}
+
+	//a corrupted part fails verification and removes the partial object
+	if err = os.WriteFile(dir+"/parts/model.dss.part0002", []byte("KLMNOPQRSx"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = JoinObject(block, manifestPath, dest)
+	var mismatch *HashMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("expected a hash mismatch, got %v", err)
+	}
+	if _, err = os.Stat(dest.Path); !os.IsNotExist(err) {
+		t.Fatal("expected the corrupted join to be removed")
+	}
+}
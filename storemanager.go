@@ -0,0 +1,137 @@
+package filesapi
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// StoreConfigFunction resolves the NewFileStore configuration (i.e. S3FSConfig)
+// for a store key.  It is called each time a store is constructed, so credentials
+// fetched by the function are refreshed whenever a store is rebuilt
+type StoreConfigFunction func(key string) (any, error)
+
+type StoreManagerConfig struct {
+
+	//resolves the store configuration for a key
+	ConfigFunc StoreConfigFunction
+
+	//stores unused for longer than the idle TTL are evicted.  Zero disables idle eviction
+	IdleTTL time.Duration
+
+	//stores older than MaxAge are rebuilt on next use, refreshing their credentials.
+	//Zero disables rebuilding
+	MaxAge time.Duration
+
+	//optional time source used for expiration
+	Clock Clock
+}
+
+type managedStore struct {
+	ready    chan struct{}
+	store    FileStore
+	err      error
+	created  time.Time
+	lastUsed time.Time
+}
+
+// StoreManager lazily constructs and caches FileStores keyed by an application
+// defined key (i.e. tenant/bucket/profile) so services serving many buckets
+// share a single client per store rather than constructing one per request.
+// StoreManager is safe for concurrent use
+type StoreManager struct {
+	config StoreManagerConfig
+	mu     sync.Mutex
+	stores map[string]*managedStore
+}
+
+func NewStoreManager(config StoreManagerConfig) (*StoreManager, error) {
+	if config.ConfigFunc == nil {
+		return nil, errors.New("store manager requires a ConfigFunc")
+	}
+	return &StoreManager{
+		config: config,
+		stores: map[string]*managedStore{},
+	}, nil
+}
+
+// Get returns the store for key, constructing it on first use or
+// when the cached store has exceeded MaxAge
+func (sm *StoreManager) Get(key string) (FileStore, error) {
+	now := sm.config.Clock.Now()
+	sm.mu.Lock()
+	ms, ok := sm.stores[key]
+	if ok && sm.expired(ms, now) {
+		delete(sm.stores, key)
+		ok = false
+	}
+	if !ok {
+		ms = &managedStore{ready: make(chan struct{}), created: now}
+		sm.stores[key] = ms
+		sm.mu.Unlock()
+		ms.store, ms.err = sm.build(key)
+		close(ms.ready)
+		sm.mu.Lock()
+		if ms.err != nil && sm.stores[key] == ms {
+			//failed constructions are not cached
+			delete(sm.stores, key)
+		}
+	}
+	ms.lastUsed = now
+	sm.mu.Unlock()
+	<-ms.ready
+	return ms.store, ms.err
+}
+
+// Evict removes a store from the cache.  The next Get rebuilds it
+func (sm *StoreManager) Evict(key string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	delete(sm.stores, key)
+}
+
+// EvictExpired removes all idle or aged out stores and returns the number evicted
+func (sm *StoreManager) EvictExpired() int {
+	now := sm.config.Clock.Now()
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	count := 0
+	for key, ms := range sm.stores {
+		if sm.expired(ms, now) {
+			delete(sm.stores, key)
+			count++
+		}
+	}
+	return count
+}
+
+// Len returns the number of cached stores
+func (sm *StoreManager) Len() int {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return len(sm.stores)
+}
+
+func (sm *StoreManager) expired(ms *managedStore, now time.Time) bool {
+	select {
+	case <-ms.ready:
+	default:
+		//stores under construction never expire
+		return false
+	}
+	if sm.config.IdleTTL > 0 && now.Sub(ms.lastUsed) > sm.config.IdleTTL {
+		return true
+	}
+	if sm.config.MaxAge > 0 && now.Sub(ms.created) > sm.config.MaxAge {
+		return true
+	}
+	return false
+}
+
+func (sm *StoreManager) build(key string) (FileStore, error) {
+	config, err := sm.config.ConfigFunc(key)
+	if err != nil {
+		return nil, err
+	}
+	return NewFileStore(config)
+}
@@ -0,0 +1,59 @@
+package filesapi
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStoreManager(t *testing.T) {
+	now := time.Now()
+	builds := map[string]int{}
+	var mu sync.Mutex
+	sm, err := NewStoreManager(StoreManagerConfig{
+		ConfigFunc: func(key string) (any, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			builds[key]++
+			if key == "bad" {
+				return nil, errors.New("unknown tenant")
+			}
+			return BlockFSConfig{}, nil
+		},
+		IdleTTL: time.Minute,
+		MaxAge:  time.Hour,
+		Clock:   func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := sm.Get("tenant1"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if builds["tenant1"] != 1 {
+		t.Fatalf("expected a single construction, got %d", builds["tenant1"])
+	}
+
+	if _, err = sm.Get("bad"); err == nil || sm.Len() != 1 {
+		t.Fatalf("expected a failed construction not to be cached")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if n := sm.EvictExpired(); n != 1 || sm.Len() != 0 {
+		t.Fatalf("expected the idle store to be evicted, evicted %d", n)
+	}
+	sm.Get("tenant1")
+	if builds["tenant1"] != 2 {
+		t.Fatalf("expected the evicted store to be rebuilt, got %d builds", builds["tenant1"])
+	}
+}
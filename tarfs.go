@@ -0,0 +1,207 @@
+package filesapi
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+)
+
+// TarFS is a read only FileStore over the regular file entries of a .tar or .tar.gz archive
+// stored in another FileStore.  Tar archives have no central directory, so the archive is
+// scanned once, on first use, to build an index of its entries.  Entries of uncompressed
+// archives are then read directly with ranged GetObject requests.  Compressed archives
+// cannot be read at an offset, so reading an entry decompresses the archive up to the entry.
+// Entry paths are relative to the archive root (i.e. /data/a.csv), and every write operation
+// returns ErrReadOnlyStore
+type TarFS struct {
+	readOnlyStore
+	store       FileStore
+	archivePath PathConfig
+	object      *ObjectReadSeeker
+	gzipped     bool
+
+	mu      sync.Mutex
+	entries entryIndex
+	offsets map[string]int64
+}
+
+// NewTarFS opens a tar archive in a store.  Gzip compressed archives are detected from their content
+func NewTarFS(store FileStore, archivePath PathConfig) (*TarFS, error) {
+	object, err := NewObjectReadSeeker(store, archivePath)
+	if err != nil {
+		return nil, err
+	}
+	magic := make([]byte, 2)
+	if _, err = object.ReadAt(magic, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return &TarFS{
+		store:       store,
+		archivePath: archivePath,
+		object:      object,
+		gzipped:     magic[0] == 0x1f && magic[1] == 0x8b,
+	}, nil
+}
+
+func (tfs *TarFS) ResourceName() string {
+	return tfs.store.ResourceName() + tfs.archivePath.Path
+}
+
+// returns the archive index, scanning the archive the first time it is called
+func (tfs *TarFS) index() (entryIndex, error) {
+	tfs.mu.Lock()
+	defer tfs.mu.Unlock()
+	if tfs.entries != nil {
+		return tfs.entries, nil
+	}
+	var tr *tar.Reader
+	var position func() int64
+	if tfs.gzipped {
+		archive, err := tfs.openGzip()
+		if err != nil {
+			return nil, err
+		}
+		defer archive.Close()
+		tr = tar.NewReader(archive)
+	} else {
+		//tar readers seek past entry data, so only the headers are read
+		section := io.NewSectionReader(newBlockReaderAt(tfs.object), 0, tfs.object.Info().Size())
+		tr = tar.NewReader(section)
+		position = func() int64 {
+			offset, _ := section.Seek(0, io.SeekCurrent)
+			return offset
+		}
+	}
+	entries := []storeEntry{}
+	offsets := map[string]int64{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read tar archive %s: %w", tfs.archivePath.Path, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := tarEntryPath(header)
+		entries = append(entries, storeEntry{path: name, info: header.FileInfo()})
+		if position != nil {
+			offsets[name] = position()
+		}
+	}
+	tfs.entries = newEntryIndex(entries)
+	tfs.offsets = offsets
+	return tfs.entries, nil
+}
+
+// entry path relative to the archive root.  Archives created from "." prefix their entries with ./
+func tarEntryPath(header *tar.Header) string {
+	return strings.TrimPrefix(strings.TrimPrefix(header.Name, "./"), "/")
+}
+
+func (tfs *TarFS) openGzip() (io.ReadCloser, error) {
+	body, err := tfs.store.GetObject(GetObjectInput{Path: tfs.archivePath})
+	if err != nil {
+		return nil, err
+	}
+	return newGzipReadCloser(body)
+}
+
+func (tfs *TarFS) GetObjectInfo(pc PathConfig) (fs.FileInfo, error) {
+	if all := pc.All(); len(all) > 1 {
+		return resourceInfo(all, tfs.GetObjectInfo)
+	}
+	index, err := tfs.index()
+	if err != nil {
+		return nil, err
+	}
+	return index.info(pc.Path)
+}
+
+func (tfs *TarFS) GetObject(goi GetObjectInput) (io.ReadCloser, error) {
+	index, err := tfs.index()
+	if err != nil {
+		return nil, err
+	}
+	i, ok := index.find(goi.Path.Path)
+	if !ok {
+		return nil, &FileNotFoundError{goi.Path.Path}
+	}
+	size := index[i].info.Size()
+	start, end := int64(0), size-1
+	if goi.Range != "" {
+		readRange, err := parseRange(goi.Range)
+		if err != nil {
+			return nil, err
+		}
+		if readRange.End < readRange.Start {
+			return nil, fmt.Errorf("invalid range: %s", goi.Range)
+		}
+		start = readRange.Start
+		if readRange.End < end {
+			end = readRange.End
+		}
+	}
+	if start > end {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	if !tfs.gzipped {
+		offset := tfs.offsets[index[i].path]
+		return tfs.store.GetObject(GetObjectInput{
+			Path:    tfs.archivePath,
+			Range:   fmt.Sprintf("bytes=%d-%d", offset+start, offset+end),
+			Options: goi.Options,
+		})
+	}
+
+	archive, err := tfs.openGzip()
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(archive)
+	for {
+		header, err := tr.Next()
+		if err != nil {
+			archive.Close()
+			if err == io.EOF {
+				err = &FileNotFoundError{goi.Path.Path}
+			}
+			return nil, err
+		}
+		if tarEntryPath(header) != index[i].path {
+			continue
+		}
+		if _, err = io.CopyN(io.Discard, tr, start); err != nil {
+			archive.Close()
+			return nil, err
+		}
+		return &readCloser{io.LimitReader(tr, end-start+1), archive}, nil
+	}
+}
+
+// ListDir lists the entries immediately beneath a directory
+func (tfs *TarFS) ListDir(input ListDirInput) (*[]FileStoreResultObject, error) {
+	index, err := tfs.index()
+	if err != nil {
+		return nil, err
+	}
+	return index.listDir(input), nil
+}
+
+func (tfs *TarFS) GetDir(path PathConfig) (*[]FileStoreResultObject, error) {
+	return tfs.ListDir(ListDirInput{Path: path})
+}
+
+// Walk visits the entries beneath a path in lexicographic order
+func (tfs *TarFS) Walk(input WalkInput, vistorFunction FileVisitFunction) error {
+	index, err := tfs.index()
+	if err != nil {
+		return err
+	}
+	return index.walk(input, vistorFunction)
+}
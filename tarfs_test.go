@@ -0,0 +1,79 @@
+package filesapi
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTarFS(t *testing.T) {
+	dir := t.TempDir()
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	tw.WriteHeader(&tar.Header{Name: "./data/", Typeflag: tar.TypeDir, Mode: 0755})
+	entries := map[string]string{
+		"./data/a.csv":        strings.Repeat("station,stage\n", 100),
+		"./data/nested/b.csv": "b",
+		"./readme.txt":        testObjectString,
+	}
+	for _, name := range []string{"./data/a.csv", "./data/nested/b.csv", "./readme.txt"} {
+		tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(entries[name]))})
+		tw.Write([]byte(entries[name]))
+	}
+	tw.Close()
+	gz := new(bytes.Buffer)
+	gw := gzip.NewWriter(gz)
+	gw.Write(buf.Bytes())
+	gw.Close()
+	os.WriteFile(dir+"/archive.tar", buf.Bytes(), 0644)
+	os.WriteFile(dir+"/archive.tar.gz", gz.Bytes(), 0644)
+
+	for _, archive := range []string{"/archive.tar", "/archive.tar.gz"} {
+		var store FileStore = &BlockFS{}
+		if archive == "/archive.tar" {
+			//uncompressed archives are only read with ranged requests
+			store = &rangedOnlyFS{FileStore: store}
+		}
+		tfs, err := NewTarFS(store, PathConfig{Path: dir + archive})
+		if err != nil {
+			t.Fatal(err)
+		}
+		read := func(path string, rng string) string {
+			reader, err := tfs.GetObject(GetObjectInput{Path: PathConfig{Path: path}, Range: rng})
+			if err != nil {
+				t.Fatalf("%s: %s", archive, err)
+			}
+			defer reader.Close()
+			data, _ := io.ReadAll(reader)
+			return string(data)
+		}
+		if data := read("/data/a.csv", ""); data != entries["./data/a.csv"] {
+			t.Fatalf("%s: unexpected entry %q", archive, data)
+		}
+		if data := read("/readme.txt", "bytes=6-10"); data != testObjectString[6:11] {
+			t.Fatalf("%s: unexpected range %q", archive, data)
+		}
+		list, err := tfs.ListDir(ListDirInput{Path: PathConfig{Path: "/data"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(*list) != 2 || (*list)[0].Name != "a.csv" || (*list)[0].Size != "1400" || !(*list)[1].IsDir {
+			t.Fatalf("%s: unexpected listing %v", archive, *list)
+		}
+		walked := []string{}
+		err = tfs.Walk(WalkInput{Path: PathConfig{Path: "/"}}, func(path string, file os.FileInfo) error {
+			walked = append(walked, path)
+			return nil
+		})
+		if err != nil || strings.Join(walked, ",") != "/data/a.csv,/data/nested/b.csv,/readme.txt" {
+			t.Fatalf("%s: unexpected walk %v %v", archive, walked, err)
+		}
+		if _, err = tfs.GetObject(GetObjectInput{Path: PathConfig{Path: "/missing"}}); err == nil {
+			t.Fatalf("%s: expected a missing entry to fail", archive)
+		}
+	}
+}
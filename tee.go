@@ -0,0 +1,104 @@
+package filesapi
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+const teeBufferSize int = 1024 * 1024
+
+var errTeeDestinationClosed = errors.New("tee destination closed before the source was fully read")
+
+type TeeDestination struct {
+	Store FileStore
+	Path  PathConfig
+}
+
+type TeeUploadInput struct {
+
+	//the source is read once and streamed to every destination
+	Source ObjectSource
+
+	Destinations []TeeDestination
+}
+
+// TeeResult is the outcome of the upload to a single destination
+type TeeResult struct {
+	Destination TeeDestination
+	Output      *FileOperationOutput
+	Err         error
+}
+
+// TeeUpload streams one source to multiple destinations at the same time
+// (i.e. an S3 primary and a local archive) without buffering the whole object.
+// The source is read in chunks and fanned out through a pipe per destination.
+// A failed destination is dropped while the others continue, so each destination
+// reports its own result.  The returned error is only set when the source cannot be read
+func TeeUpload(input TeeUploadInput) ([]TeeResult, error) {
+	results := make([]TeeResult, len(input.Destinations))
+	src, err := input.Source.GetReader()
+	if err != nil {
+		return results, err
+	}
+	defer input.Source.closeReader(src)
+
+	writers := make([]*io.PipeWriter, len(input.Destinations))
+	var wg sync.WaitGroup
+	for i, dest := range input.Destinations {
+		pr, pw := io.Pipe()
+		writers[i] = pw
+		results[i].Destination = dest
+		wg.Add(1)
+		go func(i int, dest TeeDestination, pr *io.PipeReader) {
+			defer wg.Done()
+			output, err := dest.Store.PutObject(PutObjectInput{
+				Source:   ObjectSource{Reader: pr},
+				Dest:     dest.Path,
+				Mutipart: true,
+			})
+			results[i].Output = output
+			results[i].Err = err
+			//unblock the fan out if the store stopped reading early
+			if err == nil {
+				err = errTeeDestinationClosed
+			}
+			pr.CloseWithError(err)
+		}(i, dest, pr)
+	}
+
+	var srcErr error
+	buf := make([]byte, teeBufferSize)
+	for {
+		n, rerr := src.Read(buf)
+		if n > 0 {
+			for i, w := range writers {
+				if w == nil {
+					continue
+				}
+				if _, werr := w.Write(buf[:n]); werr != nil {
+					//the destination failed; its error is reported by its upload
+					writers[i] = nil
+				}
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			srcErr = rerr
+			break
+		}
+	}
+	for _, w := range writers {
+		if w != nil {
+			if srcErr != nil {
+				w.CloseWithError(srcErr)
+			} else {
+				w.Close()
+			}
+		}
+	}
+	wg.Wait()
+	return results, srcErr
+}
@@ -0,0 +1,51 @@
+package filesapi
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
+
+type failingPutFS struct {
+	*BlockFS
+}
+
+func (f *failingPutFS) PutObject(input PutObjectInput) (*FileOperationOutput, error) {
+	buf := make([]byte, 10)
+	input.Source.Reader.Read(buf)
+	return nil, errors.New("destination unavailable")
+}
+
+func TestTeeUpload(t *testing.T) {
+	dir := t.TempDir()
+	store := &BlockFS{Config: BlockFSConfig{ChunkSize: 100}}
+	data := bytes.Repeat([]byte("0123456789"), teeBufferSize/5)
+
+	results, err := TeeUpload(TeeUploadInput{
+		Source: ObjectSource{Reader: bytes.NewReader(data)},
+		Destinations: []TeeDestination{
+			{Store: store, Path: PathConfig{Path: dir + "/primary/model.bin"}},
+			{Store: &failingPutFS{store}, Path: PathConfig{Path: dir + "/broken/model.bin"}},
+			{Store: store, Path: PathConfig{Path: dir + "/archive/model.bin"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Err != nil || results[2].Err != nil {
+		t.Fatalf("expected the healthy destinations to succeed: %v %v", results[0].Err, results[2].Err)
+	}
+	if results[1].Err == nil {
+		t.Fatal("expected the failing destination to report its error")
+	}
+	for _, path := range []string{dir + "/primary/model.bin", dir + "/archive/model.bin"} {
+		written, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(written, data) {
+			t.Fatalf("%s does not match the source", path)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+// Package tus implements a tus.io (https://tus.io/protocols/resumable-upload) server
+// backed by the multipart upload methods of a filesapi.FileStore.
+//
+// Supported extensions: creation, termination
+package tus
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/usace/filesapi"
+)
+
+const (
+	TusVersion    string = "1.0.0"
+	TusExtensions string = "creation,termination"
+
+	offsetContentType string = "application/offset+octet-stream"
+
+	//matches the default BlockFSConfig chunk size
+	DefaultChunkSize int64 = 10 * 1024 * 1024
+)
+
+type Config struct {
+
+	//store receiving the uploads
+	Store filesapi.FileStore
+
+	//public url path the handler is mounted at.  Used to build upload Location headers
+	BasePath string
+
+	//store directory that uploads are written into when PathFunc is not provided
+	UploadDir string
+
+	//optional function that determines the store path for a new upload
+	//from the request and the decoded Upload-Metadata
+	PathFunc func(r *http.Request, metadata map[string]string) (string, error)
+
+	//size of chunks written to the store.  Must be at least 5MB for S3
+	//and must match BlockFSConfig.ChunkSize for block file stores
+	ChunkSize int64
+
+	//optional maximum upload size in bytes
+	MaxSize int64
+
+	//optional source of tus upload ids
+	IdGenerator filesapi.IdGenerator
+}
+
+type upload struct {
+	mu         sync.Mutex
+	path       string
+	uploadId   string
+	length     int64
+	flushed    int64
+	chunkId    int32
+	buffer     bytes.Buffer
+	etags      []string
+	isComplete bool
+}
+
+func (u *upload) offset() int64 {
+	return u.flushed + int64(u.buffer.Len())
+}
+
+type Handler struct {
+	config  Config
+	mu      sync.Mutex
+	uploads map[string]*upload
+}
+
+func NewHandler(config Config) (*Handler, error) {
+	if config.Store == nil {
+		return nil, errors.New("tus handler requires a store")
+	}
+	if config.ChunkSize <= 0 {
+		config.ChunkSize = DefaultChunkSize
+	}
+	if !strings.HasSuffix(config.BasePath, "/") {
+		config.BasePath = config.BasePath + "/"
+	}
+	return &Handler{
+		config:  config,
+		uploads: map[string]*upload{},
+	}, nil
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", TusVersion)
+	if r.Method == http.MethodOptions {
+		h.options(w)
+		return
+	}
+	if r.Header.Get("Tus-Resumable") != TusVersion {
+		w.Header().Set("Tus-Version", TusVersion)
+		http.Error(w, "unsupported tus version", http.StatusPreconditionFailed)
+		return
+	}
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, strings.TrimSuffix(h.config.BasePath, "/")), "/")
+	switch {
+	case r.Method == http.MethodPost && id == "":
+		h.create(w, r)
+	case r.Method == http.MethodHead && id != "":
+		h.head(w, id)
+	case r.Method == http.MethodPatch && id != "":
+		h.patch(w, r, id)
+	case r.Method == http.MethodDelete && id != "":
+		h.terminate(w, id)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) options(w http.ResponseWriter) {
+	w.Header().Set("Tus-Version", TusVersion)
+	w.Header().Set("Tus-Extension", TusExtensions)
+	if h.config.MaxSize > 0 {
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.config.MaxSize, 10))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		http.Error(w, "invalid Upload-Length", http.StatusBadRequest)
+		return
+	}
+	if h.config.MaxSize > 0 && length > h.config.MaxSize {
+		http.Error(w, "upload exceeds the maximum size", http.StatusRequestEntityTooLarge)
+		return
+	}
+	metadata, err := parseMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	path, err := h.uploadPath(r, metadata)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := h.config.Store.InitializeObjectUpload(filesapi.UploadConfig{ObjectPath: path})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("unable to initialize upload: %s", err), http.StatusInternalServerError)
+		return
+	}
+	id := h.config.IdGenerator.NewId()
+	h.mu.Lock()
+	h.uploads[id] = &upload{
+		path:     path,
+		uploadId: result.ID,
+		length:   length,
+	}
+	h.mu.Unlock()
+	w.Header().Set("Location", h.config.BasePath+id)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h *Handler) head(w http.ResponseWriter, id string) {
+	u := h.getUpload(id)
+	if u == nil {
+		http.NotFound(w, nil)
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.offset(), 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(u.length, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) patch(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Header.Get("Content-Type") != offsetContentType {
+		http.Error(w, "invalid Content-Type", http.StatusUnsupportedMediaType)
+		return
+	}
+	u := h.getUpload(id)
+	if u == nil {
+		http.NotFound(w, nil)
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset != u.offset() {
+		http.Error(w, "Upload-Offset does not match the current offset", http.StatusConflict)
+		return
+	}
+	//read no more than the remaining upload length
+	body := io.LimitReader(r.Body, u.length-u.offset())
+	buf := make([]byte, 32*1024)
+	for {
+		n, rerr := body.Read(buf)
+		if n > 0 {
+			u.buffer.Write(buf[:n])
+			for int64(u.buffer.Len()) >= h.config.ChunkSize {
+				if err = h.flush(u, h.config.ChunkSize); err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			}
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			//the client may resume from the last accepted offset
+			break
+		}
+	}
+	if u.offset() == u.length && !u.isComplete {
+		if err = h.complete(u); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		h.mu.Lock()
+		delete(h.uploads, id)
+		h.mu.Unlock()
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.offset(), 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) terminate(w http.ResponseWriter, id string) {
+	h.mu.Lock()
+	u, ok := h.uploads[id]
+	delete(h.uploads, id)
+	h.mu.Unlock()
+	if !ok {
+		http.NotFound(w, nil)
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	err := h.config.Store.AbortObjectUpload(filesapi.UploadConfig{
+		ObjectPath: u.path,
+		UploadId:   u.uploadId,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writes up to size bytes of buffered data to the store as the next chunk
+func (h *Handler) flush(u *upload, size int64) error {
+	data := make([]byte, size)
+	n, _ := u.buffer.Read(data)
+	result, err := h.config.Store.WriteChunk(filesapi.UploadConfig{
+		ObjectPath: u.path,
+		ChunkId:    u.chunkId,
+		UploadId:   u.uploadId,
+		Data:       data[:n],
+	})
+	if err != nil {
+		//restore the buffer so the chunk can be retried by the next request
+		remaining := u.buffer.Bytes()
+		u.buffer = bytes.Buffer{}
+		u.buffer.Write(data[:n])
+		u.buffer.Write(remaining)
+		return fmt.Errorf("unable to write chunk %d: %s", u.chunkId, err)
+	}
+	u.etags = append(u.etags, result.ID)
+	u.flushed += int64(n)
+	u.chunkId++
+	return nil
+}
+
+func (h *Handler) complete(u *upload) error {
+	if u.buffer.Len() > 0 || u.chunkId == 0 {
+		if err := h.flush(u, int64(u.buffer.Len())); err != nil {
+			return err
+		}
+	}
+	err := h.config.Store.CompleteObjectUpload(filesapi.CompletedObjectUploadConfig{
+		UploadId:       u.uploadId,
+		ObjectPath:     u.path,
+		ChunkUploadIds: u.etags,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to complete upload: %s", err)
+	}
+	u.isComplete = true
+	return nil
+}
+
+func (h *Handler) getUpload(id string) *upload {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.uploads[id]
+}
+
+func (h *Handler) uploadPath(r *http.Request, metadata map[string]string) (string, error) {
+	if h.config.PathFunc != nil {
+		return h.config.PathFunc(r, metadata)
+	}
+	filename := metadata["filename"]
+	if filename == "" {
+		return "", errors.New("Upload-Metadata must include a filename")
+	}
+	pp := filesapi.PathParts{Parts: []string{h.config.UploadDir, filename}}
+	return pp.ToFilePath(), nil
+}
+
+// decodes the tus Upload-Metadata header: comma separated "key base64value" pairs
+func parseMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	if header == "" {
+		return metadata, nil
+	}
+	for _, pair := range strings.Split(header, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), " ", 2)
+		if kv[0] == "" {
+			return nil, errors.New("invalid Upload-Metadata")
+		}
+		if len(kv) == 1 {
+			metadata[kv[0]] = ""
+			continue
+		}
+		value, err := base64.StdEncoding.DecodeString(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid Upload-Metadata value for %s", kv[0])
+		}
+		metadata[kv[0]] = string(value)
+	}
+	return metadata, nil
+}
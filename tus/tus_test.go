@@ -0,0 +1,107 @@
+package tus
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/usace/filesapi"
+)
+
+func TestTusUpload(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{ChunkSize: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	handler, err := NewHandler(Config{
+		Store:     store,
+		BasePath:  "/files",
+		UploadDir: dir,
+		ChunkSize: 5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	data := "HELLO WORLD, HELLO TUS"
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/files", nil)
+	req.Header.Set("Tus-Resumable", TusVersion)
+	req.Header.Set("Upload-Length", strconv.Itoa(len(data)))
+	req.Header.Set("Upload-Metadata", "filename "+base64.StdEncoding.EncodeToString([]byte("tus.txt")))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201 from create, got %d", resp.StatusCode)
+	}
+	location := resp.Header.Get("Location")
+
+	offset := 0
+	for _, part := range []string{data[:7], data[7:]} {
+		req, _ = http.NewRequest(http.MethodPatch, server.URL+location, strings.NewReader(part))
+		req.Header.Set("Tus-Resumable", TusVersion)
+		req.Header.Set("Content-Type", offsetContentType)
+		req.Header.Set("Upload-Offset", strconv.Itoa(offset))
+		resp, err = http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("expected 204 from patch, got %d", resp.StatusCode)
+		}
+		offset += len(part)
+		if resp.Header.Get("Upload-Offset") != strconv.Itoa(offset) {
+			t.Fatalf("expected offset %d, got %s", offset, resp.Header.Get("Upload-Offset"))
+		}
+	}
+
+	out, err := os.ReadFile(filepath.Join(dir, "tus.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != data {
+		t.Fatalf("expected %s, got %s", data, out)
+	}
+}
+
+func TestTusOffsetConflict(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := NewHandler(Config{Store: store, BasePath: "/files", UploadDir: t.TempDir()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/files", nil)
+	req.Header.Set("Tus-Resumable", TusVersion)
+	req.Header.Set("Upload-Length", "10")
+	req.Header.Set("Upload-Metadata", "filename "+base64.StdEncoding.EncodeToString([]byte("conflict.txt")))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ = http.NewRequest(http.MethodPatch, server.URL+resp.Header.Get("Location"), strings.NewReader("12345"))
+	req.Header.Set("Tus-Resumable", TusVersion)
+	req.Header.Set("Content-Type", offsetContentType)
+	req.Header.Set("Upload-Offset", "3")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusConflict {
+		t.Fatalf("expected 409 for a mismatched offset, got %d", resp.StatusCode)
+	}
+}
@@ -8,54 +8,27 @@ import (
 	"errors"
 	"fmt"
 	"math"
-	"math/rand"
 	"net/url"
 	"os"
 	"regexp"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 const (
-	signatureQueryName  string = "X-Amz-Signature"
-	expirationQueryName string = "X-Amz-Expiration"
-	credentialQueryName string = "X-Amz-Credential"
-	timeQueryName       string = "X-Amz-Date"
-	timeFormat          string = "20060102T150405Z"
-	maxExpiration       int    = 86400 * 30 //30 days
+	signatureQueryName      string = "X-Amz-Signature"
+	expirationQueryName     string = "X-Amz-Expiration"
+	credentialQueryName     string = "X-Amz-Credential"
+	timeQueryName           string = "X-Amz-Date"
+	timeFormat              string = "20060102T150405Z"
+	maxExpiration           int    = 86400 * 30 //30 days
+	defaultCountConcurrency int    = 4
 )
 
 var fileNotFoundError *FileNotFoundError
 
-type Retryer[T any] struct {
-
-	//Max retry attempts
-	MaxAttempts int
-
-	//Max backoff in seconds
-	MaxBackoff float64
-
-	//base value for exponential backoff (usually 2)
-	//https://docs.aws.amazon.com/sdkref/latest/guide/feature-retry-behavior.html
-	R float64
-}
-
-// Send function for platform agnostic retry with exponential backoff and jitter
-// based on : https://docs.aws.amazon.com/sdkref/latest/guide/feature-retry-behavior.html
-func (r Retryer[T]) Send(sendFunction func() (T, error)) (T, error) {
-	attempts := 0
-	for {
-		t, err := sendFunction()
-		if err == nil || attempts > r.MaxAttempts {
-			return t, err
-		}
-		b := rand.Float64() //@TODO should probably use crypto random.....
-		secondsToSleep := math.Min(b*math.Pow(r.R, float64(attempts)), r.MaxBackoff)
-		time.Sleep(time.Second * time.Duration(secondsToSleep))
-		attempts++
-	}
-}
-
 type CountInput struct {
 
 	//the filestore that will be walked
@@ -66,6 +39,10 @@ type CountInput struct {
 
 	//an optional regular expression pattern for counting specific occurences of files
 	Pattern string
+
+	//optional number of concurrent walkers used by CountAndSize.
+	//defaults to defaultCountConcurrency
+	Concurrency int
 }
 
 // Counts the number of files matching an optional pattern.
@@ -100,6 +77,131 @@ func Count(ci CountInput) (int64, error) {
 	return count, nil
 }
 
+type CountResult struct {
+	Count        int64
+	TotalSize    int64
+	LargestPath  string
+	LargestSize  int64
+	SmallestPath string
+	SmallestSize int64
+}
+
+func (cr *CountResult) add(path string, size int64) {
+	if cr.Count == 0 || size > cr.LargestSize {
+		cr.LargestPath = path
+		cr.LargestSize = size
+	}
+	if cr.Count == 0 || size < cr.SmallestSize {
+		cr.SmallestPath = path
+		cr.SmallestSize = size
+	}
+	cr.Count++
+	cr.TotalSize += size
+}
+
+func (cr *CountResult) merge(other CountResult) {
+	if other.Count == 0 {
+		return
+	}
+	if cr.Count == 0 || other.LargestSize > cr.LargestSize {
+		cr.LargestPath = other.LargestPath
+		cr.LargestSize = other.LargestSize
+	}
+	if cr.Count == 0 || other.SmallestSize < cr.SmallestSize {
+		cr.SmallestPath = other.SmallestPath
+		cr.SmallestSize = other.SmallestSize
+	}
+	cr.Count += other.Count
+	cr.TotalSize += other.TotalSize
+}
+
+// Counts the number of files matching an optional pattern and accumulates
+// total, largest, and smallest object sizes in a single pass.
+// Each child directory of the dirpath is walked concurrently.
+func CountAndSize(ci CountInput) (CountResult, error) {
+	result := CountResult{}
+	var r *regexp.Regexp
+	if ci.Pattern != "" {
+		var err error
+		r, err = regexp.Compile(ci.Pattern)
+		if err != nil {
+			return result, fmt.Errorf("Failed to compile file search pattern: %s\n", err)
+		}
+	}
+	concurrency := ci.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultCountConcurrency
+	}
+
+	dirPath := ci.DirPath.Path
+	if !strings.HasSuffix(dirPath, "/") {
+		dirPath = dirPath + "/"
+	}
+	children, err := ci.FileStore.ListDir(ListDirInput{
+		Path: PathConfig{Path: dirPath},
+		Size: math.MaxInt32,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	dirs := make(chan string, len(*children))
+	for _, child := range *children {
+		path := listingPath(dirPath, child)
+		if child.IsDir {
+			dirs <- path
+			continue
+		}
+		if r != nil && !r.MatchString(path) {
+			continue
+		}
+		size, _ := strconv.ParseInt(child.Size, 10, 64)
+		result.add(path, size)
+	}
+	close(dirs)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := []error{}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for dir := range dirs {
+				dirResult := CountResult{}
+				err := ci.FileStore.Walk(WalkInput{Path: PathConfig{Path: dir}}, func(path string, file os.FileInfo) error {
+					if file.IsDir() || (r != nil && !r.MatchString(path)) {
+						return nil
+					}
+					dirResult.add(path, file.Size())
+					return nil
+				})
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, err)
+				}
+				result.merge(dirResult)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		return result, fmt.Errorf("%d of %d directory walks failed. first error: %w", len(errs), len(*children), errs[0])
+	}
+	return result, nil
+}
+
+// builds the full path of a ListDir result.
+// S3 directory results carry their full prefix in Path while
+// all other results carry the parent directory
+func listingPath(dir string, obj FileStoreResultObject) string {
+	if obj.IsDir && strings.TrimPrefix(obj.Path, "/") != strings.TrimPrefix(dir, "/") {
+		return obj.Path
+	}
+	return strings.TrimSuffix(obj.Path, "/") + "/" + obj.Name
+}
+
 type PresignInputOptions struct {
 
 	//full uri, including query params, to sign or verify
@@ -113,6 +215,9 @@ type PresignInputOptions struct {
 
 	//X-Amz-Credential
 	Credential string
+
+	//optional time source for signing and verification timestamps
+	Clock Clock
 }
 
 // Signs a uri object.  Object should be a full uri with query parameters.
@@ -128,7 +233,7 @@ func PresignObject(options PresignInputOptions) (string, error) {
 		return "", err
 	}
 	qp := uri.Query()
-	qp.Add(timeQueryName, time.Now().UTC().Format(timeFormat))
+	qp.Add(timeQueryName, options.Clock.Now().UTC().Format(timeFormat))
 	qp.Add(expirationQueryName, strconv.Itoa(options.Expiration))
 	qp.Add(credentialQueryName, b64.StdEncoding.EncodeToString([]byte(options.Credential)))
 	uri.RawQuery = qp.Encode()
@@ -157,7 +262,7 @@ func VerifySignedObject(options PresignInputOptions) bool {
 		return false
 	}
 	sigok := verifySignature(uri, options.SigningKey)
-	timeok := verifyExpiration(uri.Query())
+	timeok := verifyExpiration(uri.Query(), options.Clock.Now())
 	return sigok && timeok
 }
 
@@ -181,7 +286,7 @@ func verifySignature(uri *url.URL, key []byte) bool {
 	return bytes.Equal(signature, expectedSignature)
 }
 
-func verifyExpiration(qp url.Values) bool {
+func verifyExpiration(qp url.Values, now time.Time) bool {
 	t, err := time.Parse(timeFormat, qp.Get(timeQueryName))
 	if err != nil {
 		return false
@@ -191,13 +296,8 @@ func verifyExpiration(qp url.Values) bool {
 		return false
 	}
 	t = t.Add(time.Second * time.Duration(d))
-	return t.After(time.Now().UTC())
-
-}
+	return t.After(now.UTC())
 
-func FileExists(fs FileStore, path string) bool {
-	_, err := fs.GetObjectInfo(PathConfig{Path: path})
-	return !errors.As(err, &fileNotFoundError)
 }
 
 func Ref[T any](t T) *T {
@@ -1,8 +1,13 @@
 package filesapi
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 var testKey []byte = []byte("asdfasdfasdfasdfasdfasdfasdfasdf")
@@ -33,3 +38,115 @@ func TestValidateUrl(t *testing.T) {
 		t.Fatal("NOT VALID")
 	}
 }
+
+func TestCountAndSize(t *testing.T) {
+	fs, err := NewFileStore(BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	files := map[string]int{"a.txt": 10, "b/c.txt": 5, "b/d/e.csv": 20, "f/g.txt": 1}
+	for p, size := range files {
+		fp := filepath.Join(dir, p)
+		if err = os.MkdirAll(filepath.Dir(fp), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(fp, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result, err := CountAndSize(CountInput{FileStore: fs, DirPath: PathConfig{Path: dir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Count != 4 || result.TotalSize != 36 {
+		t.Fatalf("expected 4 files and 36 bytes, got %d files and %d bytes", result.Count, result.TotalSize)
+	}
+	if result.LargestSize != 20 || filepath.Base(result.LargestPath) != "e.csv" {
+		t.Fatalf("unexpected largest object %s (%d)", result.LargestPath, result.LargestSize)
+	}
+	if result.SmallestSize != 1 || filepath.Base(result.SmallestPath) != "g.txt" {
+		t.Fatalf("unexpected smallest object %s (%d)", result.SmallestPath, result.SmallestSize)
+	}
+
+	result, err = CountAndSize(CountInput{FileStore: fs, DirPath: PathConfig{Path: dir}, Pattern: `\.txt$`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Count != 3 {
+		t.Fatalf("expected 3 .txt files, got %d", result.Count)
+	}
+}
+
+func TestRetryerPolicies(t *testing.T) {
+	attempts := 0
+	retryer := NewRetryer[int](RetryPolicy{MaxAttempts: 3, MaxBackoff: 0.01, R: 2})
+	v, err := retryer.Send(func() (int, error) {
+		attempts++
+		if attempts < 3 {
+			return 0, errors.New("transient")
+		}
+		return attempts, nil
+	})
+	if err != nil || v != 3 {
+		t.Fatalf("expected success on the third attempt, got %d: %v", v, err)
+	}
+
+	attempts = 0
+	retryer = NewRetryer[int](Aggressive)
+	_, err = retryer.Send(func() (int, error) {
+		attempts++
+		return 0, &FileNotFoundError{"missing"}
+	})
+	if err == nil || attempts != 1 {
+		t.Fatalf("expected not found errors to fail without retrying, got %d attempts", attempts)
+	}
+
+	attempts = 0
+	retryer = NewRetryer[int](NoRetry)
+	_, err = retryer.Send(func() (int, error) {
+		attempts++
+		return 0, errors.New("transient")
+	})
+	if err == nil || attempts != 1 {
+		t.Fatalf("expected a single attempt, got %d", attempts)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	retryer = NewRetryer[int](RetryPolicy{MaxAttempts: 5, MaxBackoff: 10, R: 2,
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			cancel()
+		},
+	})
+	_, err = retryer.SendWithContext(ctx, func(ctx context.Context) (int, error) {
+		return 0, errors.New("transient")
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled retry, got %v", err)
+	}
+}
+
+func TestPresignClock(t *testing.T) {
+	signedAt := time.Date(2023, 11, 2, 21, 21, 14, 0, time.UTC)
+	now := signedAt
+	options := PresignInputOptions{
+		Uri:        "https://test.com/path1/path2?param1=1234",
+		SigningKey: testKey,
+		Expiration: 60,
+		Clock:      func() time.Time { return now },
+	}
+	signedurl, err := PresignObject(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options.Uri = signedurl
+	now = signedAt.Add(30 * time.Second)
+	if !VerifySignedObject(options) {
+		t.Fatal("expected the signed url to be valid before expiration")
+	}
+	options.Uri = signedurl
+	now = signedAt.Add(2 * time.Minute)
+	if VerifySignedObject(options) {
+		t.Fatal("expected the signed url to be expired")
+	}
+}
@@ -0,0 +1,87 @@
+package filesapi
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type EntryType int
+
+const (
+	EntryFile EntryType = iota
+	EntryDir
+)
+
+// WalkEntry describes an object visited by WalkEntries
+type WalkEntry struct {
+
+	//full path as reported by the store
+	Path string
+
+	//slash separated path relative to the walk root.  Empty for the root itself
+	RelativePath string
+
+	//number of path elements below the walk root.  Objects directly under the root have depth 1
+	Depth int
+
+	Type EntryType
+	Info os.FileInfo
+}
+
+func (we WalkEntry) IsDir() bool {
+	return we.Type == EntryDir
+}
+
+type WalkEntryFunction func(entry WalkEntry) error
+
+// WalkEntries walks a store like FileStore.Walk, handing the visitor a WalkEntry with the
+// store relative path and depth worked out the same way for every backend.
+// A visitor error stops the walk and is returned on all backends
+func WalkEntries(store FileStore, input WalkInput, visitor WalkEntryFunction) error {
+	root := normalizeWalkPath(input.Path.Path)
+	var visitErr error
+	progress := input.Progress
+	input.Progress = func(pd ProgressData) error {
+		//stores that ignore visitor errors stop on progress errors
+		if visitErr != nil {
+			return visitErr
+		}
+		return reportProgress(progress, pd)
+	}
+	err := store.Walk(input, func(path string, file os.FileInfo) error {
+		if visitErr != nil {
+			return visitErr
+		}
+		entry := WalkEntry{
+			Path: path,
+			Info: file,
+		}
+		if file.IsDir() {
+			entry.Type = EntryDir
+		}
+		rel := normalizeWalkPath(path)
+		if root != "" {
+			if rel == root {
+				rel = ""
+			} else {
+				rel = strings.TrimPrefix(rel, root+"/")
+			}
+		}
+		entry.RelativePath = rel
+		if rel != "" {
+			entry.Depth = strings.Count(rel, "/") + 1
+		}
+		visitErr = visitor(entry)
+		return visitErr
+	})
+	if visitErr != nil {
+		return visitErr
+	}
+	return err
+}
+
+// converts a block or object store path to a slash separated path without leading or trailing slashes
+func normalizeWalkPath(path string) string {
+	return strings.Trim(filepath.ToSlash(path), "/")
+}
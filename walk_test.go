@@ -0,0 +1,54 @@
+package filesapi
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWalkEntries(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "model/outputs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"model/run.json", "model/outputs/flow.dss"} {
+		if err := os.WriteFile(filepath.Join(dir, f), []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries := map[string]WalkEntry{}
+	err := WalkEntries(&BlockFS{}, WalkInput{Path: PathConfig{Path: dir + "/"}}, func(entry WalkEntry) error {
+		entries[entry.RelativePath] = entry
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, ok := entries[""]
+	if !ok || !root.IsDir() || root.Depth != 0 {
+		t.Fatalf("unexpected root entry %+v", root)
+	}
+	if e := entries["model/outputs"]; !e.IsDir() || e.Depth != 2 {
+		t.Fatalf("unexpected directory entry %+v", e)
+	}
+	if e := entries["model/outputs/flow.dss"]; e.Type != EntryFile || e.Depth != 3 || e.Path != filepath.Join(dir, "model/outputs/flow.dss") {
+		t.Fatalf("unexpected file entry %+v", e)
+	}
+
+	//s3 style keys with a leading slash
+	store := &markerFS{BlockFS: &BlockFS{}}
+	store.objects = append(store.objects, testS3Object("model/outputs/flow.dss", 4), testS3Object("model/run.json", 4))
+	stop := errors.New("stop")
+	visited := 0
+	err = WalkEntries(store, WalkInput{Path: PathConfig{Path: "model"}}, func(entry WalkEntry) error {
+		visited++
+		if entry.RelativePath != "outputs/flow.dss" || entry.Depth != 2 {
+			t.Fatalf("unexpected s3 entry %+v", entry)
+		}
+		return stop
+	})
+	if !errors.Is(err, stop) || visited != 1 {
+		t.Fatalf("expected the visitor error to stop the walk: %v %d", err, visited)
+	}
+}
@@ -0,0 +1,323 @@
+// Package webdavfs serves a filesapi.FileStore over WebDAV, so any store can be mounted by
+// Windows Explorer or Finder.
+//
+// The WebDAV protocol comes from golang.org/x/net/webdav.  The package is its own module,
+// so applications that do not serve WebDAV do not depend on it
+package webdavfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/usace/filesapi"
+)
+
+// files larger than this are written to object stores with multipart uploads
+const davMultipartThreshold int64 = 64 * 1024 * 1024
+
+var errIsDirectory = errors.New("is a directory")
+
+// davFile is the webdav.File interface
+type davFile interface {
+	http.File
+	io.Writer
+}
+
+// davFileSystem implements the methods of golang.org/x/net/webdav.FileSystem over the
+// objects beneath a store root (see FileSystem).  Reads are served with ranged
+// requests through filesapi.IOFS.  Writes are staged in a temporary file and stored
+// when the file is closed, since stores cannot modify part of an object
+type davFileSystem struct {
+	store filesapi.FileStore
+	root  string
+	fsys  *filesapi.IOFS
+}
+
+func newDavFileSystem(store filesapi.FileStore, root string) *davFileSystem {
+	return &davFileSystem{store: store, root: root, fsys: filesapi.NewIOFS(store, root)}
+}
+
+// store path of a webdav name (i.e. /folder/file.txt)
+func (dfs *davFileSystem) storePath(name string) string {
+	return path.Join("/", dfs.root, name)
+}
+
+// io/fs name of a webdav name
+func davIOName(name string) string {
+	n := strings.Trim(path.Clean("/"+name), "/")
+	if n == "" {
+		return "."
+	}
+	return n
+}
+
+func (dfs *davFileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	return dfs.fsys.Stat(davIOName(name))
+}
+
+// requires the parent directory of a name to exist
+func (dfs *davFileSystem) checkParent(ctx context.Context, name string) error {
+	parent := path.Dir(path.Clean("/" + name))
+	if parent == "/" {
+		return nil
+	}
+	info, err := dfs.Stat(ctx, parent)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return &os.PathError{Op: "open", Path: parent, Err: errors.New("not a directory")}
+	}
+	return nil
+}
+
+func (dfs *davFileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	if _, err := dfs.Stat(ctx, name); err == nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+	if err := dfs.checkParent(ctx, name); err != nil {
+		return err
+	}
+	return filesapi.EnsureDir(dfs.store, dfs.storePath(name))
+}
+
+func (dfs *davFileSystem) openFile(ctx context.Context, name string, flag int, perm os.FileMode) (davFile, error) {
+	write := flag&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) != 0
+	info, err := dfs.Stat(ctx, name)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	exists := err == nil
+	if !write {
+		if !exists {
+			return nil, err
+		}
+		f, err := dfs.fsys.Open(davIOName(name))
+		if err != nil {
+			return nil, err
+		}
+		return &davReadFile{f}, nil
+	}
+
+	switch {
+	case exists && info.IsDir():
+		return nil, &os.PathError{Op: "open", Path: name, Err: errIsDirectory}
+	case exists && flag&os.O_CREATE != 0 && flag&os.O_EXCL != 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	case !exists && flag&os.O_CREATE == 0:
+		return nil, err
+	case !exists:
+		if err := dfs.checkParent(ctx, name); err != nil {
+			return nil, err
+		}
+	}
+	tmp, err := os.CreateTemp("", "filesapi-webdav-*")
+	if err != nil {
+		return nil, err
+	}
+	wf := &davWriteFile{tmp: tmp, dfs: dfs, name: name, dirty: !exists}
+	if exists && flag&os.O_TRUNC == 0 {
+		//keep the existing content for partial updates
+		if err := wf.load(); err != nil {
+			wf.discard()
+			return nil, err
+		}
+		if flag&os.O_APPEND != 0 {
+			tmp.Seek(0, io.SeekEnd)
+		}
+	} else if exists {
+		wf.dirty = true
+	}
+	return wf, nil
+}
+
+func (dfs *davFileSystem) RemoveAll(ctx context.Context, name string) error {
+	if davIOName(name) == "." {
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrPermission}
+	}
+	info, err := dfs.Stat(ctx, name)
+	if err != nil {
+		return err
+	}
+	storePath := dfs.storePath(name)
+	if !info.IsDir() {
+		return firstError(dfs.store.DeleteObjects(filesapi.DeleteObjectInput{Paths: filesapi.PathConfig{Path: storePath}}))
+	}
+	return filesapi.RemoveDir(dfs.store, storePath)
+}
+
+// Rename moves a file, or copies every object beneath a directory and removes the source
+func (dfs *davFileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	info, err := dfs.Stat(ctx, oldName)
+	if err != nil {
+		return err
+	}
+	if davIOName(oldName) == "." || davIOName(newName) == "." {
+		return &os.PathError{Op: "rename", Path: oldName, Err: os.ErrPermission}
+	}
+	if err := dfs.checkParent(ctx, newName); err != nil {
+		return err
+	}
+	oldPath, newPath := dfs.storePath(oldName), dfs.storePath(newName)
+	if !info.IsDir() {
+		return dfs.store.MoveObject(filesapi.MoveObjectInput{
+			Src:  filesapi.PathConfig{Path: oldPath},
+			Dest: filesapi.PathConfig{Path: newPath},
+		})
+	}
+	if strings.HasPrefix(newPath+"/", oldPath+"/") {
+		return &os.PathError{Op: "rename", Path: newName, Err: errors.New("cannot move a directory into itself")}
+	}
+	if err := filesapi.EnsureDir(dfs.store, newPath); err != nil {
+		return err
+	}
+	files, err := filesapi.ListFiles(dfs.store, oldPath)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		dest := newPath + strings.TrimPrefix(f, oldPath)
+		if err := filesapi.EnsureDir(dfs.store, path.Dir(dest)); err != nil {
+			return err
+		}
+		err := dfs.store.CopyObject(filesapi.CopyObjectInput{
+			Src:  filesapi.PathConfig{Path: f},
+			Dest: filesapi.PathConfig{Path: dest},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return dfs.RemoveAll(ctx, oldName)
+}
+
+// a file or directory opened for reading
+type davReadFile struct {
+	fs.File
+}
+
+func (f *davReadFile) Seek(offset int64, whence int) (int64, error) {
+	seeker, ok := f.File.(io.Seeker)
+	if !ok {
+		return 0, errIsDirectory
+	}
+	return seeker.Seek(offset, whence)
+}
+
+func (f *davReadFile) Readdir(count int) ([]fs.FileInfo, error) {
+	dir, ok := f.File.(fs.ReadDirFile)
+	if !ok {
+		return nil, errors.New("not a directory")
+	}
+	entries, err := dir.ReadDir(count)
+	infos := make([]fs.FileInfo, 0, len(entries))
+	for _, e := range entries {
+		info, ierr := e.Info()
+		if ierr != nil {
+			return infos, ierr
+		}
+		infos = append(infos, info)
+	}
+	return infos, err
+}
+
+func (f *davReadFile) Write(p []byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+// a file opened for writing, staged in a temporary file until it is closed
+type davWriteFile struct {
+	tmp   *os.File
+	dfs   *davFileSystem
+	name  string
+	dirty bool
+}
+
+// copies the existing object into the staging file
+func (f *davWriteFile) load() error {
+	reader, err := f.dfs.store.GetObject(filesapi.GetObjectInput{Path: filesapi.PathConfig{Path: f.dfs.storePath(f.name)}})
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	if _, err = io.Copy(f.tmp, reader); err != nil {
+		return err
+	}
+	_, err = f.tmp.Seek(0, io.SeekStart)
+	return err
+}
+
+func (f *davWriteFile) Read(p []byte) (int, error) {
+	return f.tmp.Read(p)
+}
+
+func (f *davWriteFile) Seek(offset int64, whence int) (int64, error) {
+	return f.tmp.Seek(offset, whence)
+}
+
+func (f *davWriteFile) Write(p []byte) (int, error) {
+	f.dirty = true
+	return f.tmp.Write(p)
+}
+
+func (f *davWriteFile) Readdir(count int) ([]fs.FileInfo, error) {
+	return nil, errors.New("not a directory")
+}
+
+func (f *davWriteFile) Stat() (fs.FileInfo, error) {
+	info, err := f.tmp.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return &davFileInfo{FileInfo: info, name: path.Base(f.name)}, nil
+}
+
+// Close stores the staged content when the file was created or written
+func (f *davWriteFile) Close() error {
+	defer f.discard()
+	if !f.dirty {
+		return nil
+	}
+	size, err := f.tmp.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if _, err = f.tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err = f.dfs.store.PutObject(filesapi.PutObjectInput{
+		Source:   filesapi.ObjectSource{Reader: f.tmp, ContentLength: &size},
+		Dest:     filesapi.PathConfig{Path: f.dfs.storePath(f.name)},
+		Mutipart: size > davMultipartThreshold,
+	})
+	return err
+}
+
+func (f *davWriteFile) discard() {
+	f.tmp.Close()
+	os.Remove(f.tmp.Name())
+}
+
+type davFileInfo struct {
+	fs.FileInfo
+	name string
+}
+
+func (dfi *davFileInfo) Name() string {
+	return dfi.name
+}
+
+func firstError(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
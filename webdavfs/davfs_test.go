@@ -0,0 +1,120 @@
+package webdavfs
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/usace/filesapi"
+)
+
+func TestDavFileSystem(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	dfs := newDavFileSystem(store, root)
+	ctx := context.Background()
+
+	if err := dfs.Mkdir(ctx, "/model", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := dfs.Mkdir(ctx, "/model", 0755); !os.IsExist(err) {
+		t.Fatalf("expected an exists error, got %v", err)
+	}
+	if err := dfs.Mkdir(ctx, "/missing/child", 0755); !os.IsNotExist(err) {
+		t.Fatalf("expected a missing parent error, got %v", err)
+	}
+
+	write := func(name string, flag int, data string) {
+		f, err := dfs.openFile(ctx, name, flag, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(f, data); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		f, err := dfs.openFile(ctx, name, os.O_RDONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		data, err := io.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	write("/model/run.log", os.O_RDWR|os.O_CREATE|os.O_TRUNC, "started\n")
+	if got := read("/model/run.log"); got != "started\n" {
+		t.Fatalf("unexpected content %q", got)
+	}
+	write("/model/run.log", os.O_WRONLY|os.O_APPEND, "finished\n")
+	if got := read("/model/run.log"); got != "started\nfinished\n" {
+		t.Fatalf("unexpected appended content %q", got)
+	}
+	if _, err := dfs.openFile(ctx, "/model/run.log", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644); !os.IsExist(err) {
+		t.Fatalf("expected an exists error, got %v", err)
+	}
+	if _, err := dfs.openFile(ctx, "/model/new.txt", os.O_WRONLY, 0644); !os.IsNotExist(err) {
+		t.Fatalf("expected a not exist error, got %v", err)
+	}
+
+	f, err := dfs.openFile(ctx, "/model/run.log", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(8, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	tail, _ := io.ReadAll(f)
+	f.Close()
+	if string(tail) != "finished\n" {
+		t.Fatalf("unexpected content after seek %q", tail)
+	}
+
+	dir, err := dfs.openFile(ctx, "/", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	infos, err := dir.Readdir(0)
+	dir.Close()
+	if err != nil || len(infos) != 1 || infos[0].Name() != "model" || !infos[0].IsDir() {
+		t.Fatalf("unexpected root listing %v %v", infos, err)
+	}
+
+	if err := dfs.Rename(ctx, "/model", "/archive"); err != nil {
+		t.Fatal(err)
+	}
+	if got := read("/archive/run.log"); got != "started\nfinished\n" {
+		t.Fatalf("unexpected renamed content %q", got)
+	}
+	if _, err := dfs.Stat(ctx, "/model"); !os.IsNotExist(err) {
+		t.Fatalf("expected the old directory to be removed, got %v", err)
+	}
+	if err := dfs.Rename(ctx, "/archive/run.log", "/run.log"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "run.log")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dfs.RemoveAll(ctx, "/archive"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "archive")); !os.IsNotExist(err) {
+		t.Fatalf("expected the directory to be removed, got %v", err)
+	}
+	if err := dfs.RemoveAll(ctx, "/"); err == nil {
+		t.Fatal("expected removing the root to fail")
+	}
+}
@@ -0,0 +1,39 @@
+package webdavfs
+
+import (
+	"context"
+	"os"
+
+	"github.com/usace/filesapi"
+	"golang.org/x/net/webdav"
+)
+
+// FileSystem adapts the objects beneath a store root to a webdav.FileSystem, so any
+// store can be mounted by Windows Explorer or Finder.  Written files are stored when they
+// are closed
+func FileSystem(store filesapi.FileStore, root string) webdav.FileSystem {
+	return webdavFileSystem{newDavFileSystem(store, root)}
+}
+
+// NewHandler serves the objects beneath a store root over WebDAV at prefix, with
+// in memory locks
+func NewHandler(store filesapi.FileStore, root string, prefix string) *webdav.Handler {
+	return &webdav.Handler{
+		Prefix:     prefix,
+		FileSystem: FileSystem(store, root),
+		LockSystem: webdav.NewMemLS(),
+	}
+}
+
+type webdavFileSystem struct {
+	*davFileSystem
+}
+
+func (wfs webdavFileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	f, err := wfs.openFile(ctx, name, flag, perm)
+	if err != nil {
+		//a nil *davFile in the interface would not compare equal to nil
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,45 @@
+package webdavfs
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/usace/filesapi"
+)
+
+func TestFileSystem(t *testing.T) {
+	store, err := filesapi.NewFileStore(filesapi.BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	wfs := FileSystem(store, root)
+	ctx := context.Background()
+
+	f, err := wfs.OpenFile(ctx, "/a.txt", os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("HELLO WORLD"))
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f, err = wfs.OpenFile(ctx, "/a.txt", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(f)
+	f.Close()
+	if string(data) != "HELLO WORLD" {
+		t.Fatalf("expected HELLO WORLD, got %q", data)
+	}
+	if _, err = wfs.OpenFile(ctx, "/missing.txt", os.O_RDONLY, 0); !os.IsNotExist(err) {
+		t.Fatalf("expected a not exist error, got %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(root, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,156 @@
+package filesapi
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrWriterClosed is returned by writes to, and repeated closes of, a closed object writer
+var ErrWriterClosed = errors.New("object writer is closed")
+
+// WriterOpener is implemented by stores that can stream an object as it is written
+type WriterOpener interface {
+	OpenWriter(path PathConfig) (io.WriteCloser, error)
+}
+
+// WriterAborter is implemented by the writers returned by OpenWriter.  Abort discards the
+// object instead of storing the bytes written so far
+type WriterAborter interface {
+	Abort(err error) error
+}
+
+// OpenWriter returns a writer that streams into the object at path as bytes are written,
+// so producers (i.e. CSV or GeoTIFF generators) never buffer the whole object.  Close stores
+// the object and returns any error writing it.  Producers that fail part way should call
+// Abort (see WriterAborter), so a partial object is never stored.
+//
+// Stores implementing WriterOpener open the writer themselves.  Other stores (including
+// decorators) stream the writes into a multipart put
+func OpenWriter(store FileStore, path PathConfig) (io.WriteCloser, error) {
+	if wo, ok := store.(WriterOpener); ok {
+		return wo.OpenWriter(path)
+	}
+	return newPipeWriter(store, path), nil
+}
+
+// OpenWriter writes a temporary file beside the object, which is renamed over the object
+// when the writer is closed
+func (b *BlockFS) OpenWriter(path PathConfig) (io.WriteCloser, error) {
+	dir := filepath.Dir(path.Path)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path.Path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	if err = f.Chmod(0644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &fileWriter{file: f, path: path.Path}, nil
+}
+
+// OpenWriter streams the writes into a multipart upload.  Parts are uploaded as they fill,
+// and an aborted writer aborts the upload
+func (s3fs *S3FS) OpenWriter(path PathConfig) (io.WriteCloser, error) {
+	return newPipeWriter(s3fs, path), nil
+}
+
+// fileWriter writes a temporary file that replaces the object on Close
+type fileWriter struct {
+	file   *os.File
+	path   string
+	closed bool
+}
+
+func (fw *fileWriter) Write(p []byte) (int, error) {
+	if fw.closed {
+		return 0, ErrWriterClosed
+	}
+	return fw.file.Write(p)
+}
+
+func (fw *fileWriter) Close() error {
+	if fw.closed {
+		return ErrWriterClosed
+	}
+	fw.closed = true
+	err := fw.file.Close()
+	if err == nil {
+		err = os.Rename(fw.file.Name(), fw.path)
+	}
+	if err != nil {
+		os.Remove(fw.file.Name())
+	}
+	return err
+}
+
+func (fw *fileWriter) Abort(err error) error {
+	if fw.closed {
+		return ErrWriterClosed
+	}
+	fw.closed = true
+	fw.file.Close()
+	return os.Remove(fw.file.Name())
+}
+
+// pipeWriter streams writes into a multipart put running in another goroutine
+type pipeWriter struct {
+	pw     *io.PipeWriter
+	done   chan struct{}
+	err    error
+	closed bool
+}
+
+func newPipeWriter(store FileStore, path PathConfig) *pipeWriter {
+	pr, pw := io.Pipe()
+	w := &pipeWriter{pw: pw, done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+		_, w.err = store.PutObject(PutObjectInput{
+			Source:   ObjectSource{Reader: pr},
+			Dest:     path,
+			Mutipart: true,
+		})
+		//unblocks writes once the put fails or stops reading
+		pr.CloseWithError(w.err)
+	}()
+	return w
+}
+
+// writes fail with the error of the put when it fails before the writer is closed
+func (w *pipeWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, ErrWriterClosed
+	}
+	return w.pw.Write(p)
+}
+
+func (w *pipeWriter) Close() error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+	w.closed = true
+	w.pw.Close()
+	<-w.done
+	return w.err
+}
+
+// Abort fails the put with err, so S3 multipart uploads are aborted.  Stores that write
+// objects as they read (i.e. decorated block file stores) may leave a partial object
+func (w *pipeWriter) Abort(err error) error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+	w.closed = true
+	if err == nil {
+		err = errors.New("object writer aborted")
+	}
+	w.pw.CloseWithError(err)
+	<-w.done
+	return nil
+}
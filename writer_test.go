@@ -0,0 +1,108 @@
+package filesapi
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenWriterBlockFS(t *testing.T) {
+	store, err := NewFileStore(BlockFSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "exports", "table.csv")
+	w, err := OpenWriter(store, PathConfig{Path: dest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(w, "id,value\n")
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Fatal("expected the object to be written on close")
+	}
+	io.WriteString(w, "1,2\n")
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "id,value\n1,2\n" {
+		t.Fatalf("unexpected object %q", data)
+	}
+	if _, err = w.Write([]byte("late")); !errors.Is(err, ErrWriterClosed) {
+		t.Fatalf("expected ErrWriterClosed, got %v", err)
+	}
+
+	w, _ = OpenWriter(store, PathConfig{Path: dest})
+	io.WriteString(w, "partial")
+	if err = w.(WriterAborter).Abort(errors.New("generator failed")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "id,value\n1,2\n" {
+		t.Fatalf("expected an aborted writer to leave the object unchanged, got %q", data)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(dest)); len(entries) != 1 {
+		t.Fatalf("expected the temporary file to be removed, got %d entries", len(entries))
+	}
+
+	//decorated stores stream into a put
+	decorated := struct{ FileStore }{store}
+	w, _ = OpenWriter(decorated, PathConfig{Path: dest})
+	io.WriteString(w, "streamed")
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(dest); string(data) != "streamed" {
+		t.Fatalf("unexpected streamed object %q", data)
+	}
+}
+
+func TestOpenWriterS3(t *testing.T) {
+	backend := &noPartCopyServer{objects: map[string][]byte{}}
+	server := httptest.NewServer(backend)
+	defer server.Close()
+	store, err := NewFileStore(MinioFSConfig{
+		S3FSConfig: S3FSConfig{
+			S3Region:    "us-east-1",
+			S3Bucket:    "bucket",
+			Credentials: S3FS_Static{S3Id: "id", S3Key: "key"},
+		},
+		HostAddress: server.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := bytes.Repeat([]byte("0123456789"), min_multipart_part_size/10+1000)
+	w, err := OpenWriter(store, PathConfig{Path: "/exports/raster.tif"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for chunk := source; len(chunk) > 0; {
+		n := 64 * 1024
+		if n > len(chunk) {
+			n = len(chunk)
+		}
+		if _, err = w.Write(chunk[:n]); err != nil {
+			t.Fatal(err)
+		}
+		chunk = chunk[n:]
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(backend.objects["exports/raster.tif"], source) || backend.completed != 1 {
+		t.Fatalf("expected a multipart upload of the written bytes, got %d bytes", len(backend.objects["exports/raster.tif"]))
+	}
+
+	w, _ = OpenWriter(store, PathConfig{Path: "/exports/aborted.tif"})
+	w.Write(source)
+	if err = w.(WriterAborter).Abort(errors.New("generator failed")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := backend.objects["exports/aborted.tif"]; ok || len(backend.aborted) != 1 {
+		t.Fatalf("expected the aborted upload not to be stored, got %v aborts", backend.aborted)
+	}
+}
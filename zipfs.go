@@ -0,0 +1,117 @@
+package filesapi
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// ZipFS is a read only FileStore over the entries of a zip archive stored in another FileStore.
+// The archive is read with ranged GetObject requests: opening the store reads the central
+// directory at the end of the archive, and reading an entry only reads that entry.  Entry paths
+// are relative to the archive root (i.e. /data/a.csv), and every write operation returns
+// ErrReadOnlyStore
+type ZipFS struct {
+	readOnlyStore
+	resourceName string
+	entries      entryIndex
+	files        map[string]*zip.File
+	reader       io.ReaderAt
+}
+
+// NewZipFS opens a zip archive in a store and reads its central directory
+func NewZipFS(store FileStore, archivePath PathConfig) (*ZipFS, error) {
+	object, err := NewObjectReadSeeker(store, archivePath)
+	if err != nil {
+		return nil, err
+	}
+	reader := newBlockReaderAt(object)
+	archive, err := zip.NewReader(reader, object.Info().Size())
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read zip archive %s: %w", archivePath.Path, err)
+	}
+	entries := []storeEntry{}
+	files := map[string]*zip.File{}
+	for _, f := range archive.File {
+		if !strings.HasSuffix(f.Name, "/") {
+			entries = append(entries, storeEntry{path: f.Name, info: f.FileInfo()})
+			files[strings.TrimPrefix(f.Name, "/")] = f
+		}
+	}
+	return &ZipFS{
+		resourceName: store.ResourceName() + archivePath.Path,
+		entries:      newEntryIndex(entries),
+		files:        files,
+		reader:       reader,
+	}, nil
+}
+
+func (zfs *ZipFS) ResourceName() string {
+	return zfs.resourceName
+}
+
+func (zfs *ZipFS) GetObjectInfo(pc PathConfig) (fs.FileInfo, error) {
+	if all := pc.All(); len(all) > 1 {
+		return resourceInfo(all, zfs.GetObjectInfo)
+	}
+	return zfs.entries.info(pc.Path)
+}
+
+// GetObject reads an entry.  Ranges of stored (uncompressed) entries are read directly
+// from the archive.  Ranges of compressed entries are decompressed from the entry start
+func (zfs *ZipFS) GetObject(goi GetObjectInput) (io.ReadCloser, error) {
+	f, ok := zfs.files[strings.TrimPrefix(goi.Path.Path, "/")]
+	if !ok {
+		return nil, &FileNotFoundError{goi.Path.Path}
+	}
+	if goi.Range == "" {
+		return f.Open()
+	}
+	readRange, err := parseRange(goi.Range)
+	if err != nil {
+		return nil, err
+	}
+	if readRange.End < readRange.Start {
+		return nil, fmt.Errorf("invalid range: %s", goi.Range)
+	}
+	length := readRange.End - readRange.Start + 1
+	if f.Method == zip.Store {
+		offset, err := f.DataOffset()
+		if err != nil {
+			return nil, err
+		}
+		size := int64(f.UncompressedSize64)
+		if readRange.Start >= size {
+			return io.NopCloser(strings.NewReader("")), nil
+		}
+		if readRange.Start+length > size {
+			length = size - readRange.Start
+		}
+		return io.NopCloser(io.NewSectionReader(zfs.reader, offset+readRange.Start, length)), nil
+	}
+	entry, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = io.CopyN(io.Discard, entry, readRange.Start); err != nil && err != io.EOF {
+		entry.Close()
+		return nil, err
+	}
+	return &readCloser{io.LimitReader(entry, length), entry}, nil
+}
+
+// ListDir lists the entries immediately beneath a directory
+func (zfs *ZipFS) ListDir(input ListDirInput) (*[]FileStoreResultObject, error) {
+	return zfs.entries.listDir(input), nil
+}
+
+func (zfs *ZipFS) GetDir(path PathConfig) (*[]FileStoreResultObject, error) {
+	return zfs.ListDir(ListDirInput{Path: path})
+}
+
+// Walk visits the entries beneath a path in lexicographic order
+func (zfs *ZipFS) Walk(input WalkInput, vistorFunction FileVisitFunction) error {
+	return zfs.entries.walk(input, vistorFunction)
+}
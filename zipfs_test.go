@@ -0,0 +1,112 @@
+package filesapi
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// fails unranged reads so tests can check an archive is never fully downloaded
+type rangedOnlyFS struct {
+	FileStore
+	reads int
+}
+
+func (rfs *rangedOnlyFS) GetObject(goi GetObjectInput) (io.ReadCloser, error) {
+	if goi.Range == "" {
+		return nil, errors.New("unranged read")
+	}
+	rfs.reads++
+	return rfs.FileStore.GetObject(goi)
+}
+
+func TestZipFS(t *testing.T) {
+	dir := t.TempDir()
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	entries := []struct {
+		name   string
+		method uint16
+		data   string
+	}{
+		{"readme.txt", zip.Store, testObjectString},
+		{"data/a.csv", zip.Deflate, strings.Repeat("station,stage\n", 100)},
+		{"data/nested/b.csv", zip.Store, "b"},
+	}
+	for _, e := range entries {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: e.name, Method: e.method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(e.data))
+	}
+	zw.Close()
+	store := &rangedOnlyFS{FileStore: &BlockFS{}}
+	archive := PathConfig{Path: dir + "/archive.zip"}
+	if err := os.WriteFile(archive.Path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	zfs, err := NewZipFS(store, archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := func(path string, rng string) string {
+		reader, err := zfs.GetObject(GetObjectInput{Path: PathConfig{Path: path}, Range: rng})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	if data := read("/data/a.csv", ""); data != entries[1].data {
+		t.Fatalf("unexpected deflated entry: %q", data)
+	}
+	if data := read("/readme.txt", "bytes=6-10"); data != testObjectString[6:11] {
+		t.Fatalf("unexpected stored range: %q", data)
+	}
+	if data := read("/data/a.csv", "bytes=14-18"); data != "stati" {
+		t.Fatalf("unexpected deflated range: %q", data)
+	}
+	if store.reads != 1 {
+		t.Fatalf("expected the small archive to be read in a single block, got %d reads", store.reads)
+	}
+
+	list, err := zfs.ListDir(ListDirInput{Path: PathConfig{Path: "/data"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*list) != 2 || (*list)[0].Name != "a.csv" || !(*list)[1].IsDir {
+		t.Fatalf("unexpected listing: %v", *list)
+	}
+	info, err := zfs.GetObjectInfo(PathConfig{Path: "/data/nested"})
+	if err != nil || !info.IsDir() {
+		t.Fatalf("expected a directory, got %v %v", info, err)
+	}
+	if _, err = zfs.GetObject(GetObjectInput{Path: PathConfig{Path: "/missing.txt"}}); !errors.As(err, &fileNotFoundError) {
+		t.Fatalf("expected a FileNotFoundError, got %v", err)
+	}
+
+	walked := []string{}
+	err = zfs.Walk(WalkInput{Path: PathConfig{Path: "/"}}, func(path string, file os.FileInfo) error {
+		walked = append(walked, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(walked, ",") != "/data/a.csv,/data/nested/b.csv,/readme.txt" {
+		t.Fatalf("unexpected walk: %v", walked)
+	}
+	if errs := zfs.DeleteObjects(DeleteObjectInput{Paths: PathConfig{Path: "/readme.txt"}}); !errors.Is(errs[0], ErrReadOnlyStore) {
+		t.Fatalf("expected ErrReadOnlyStore, got %v", errs)
+	}
+}